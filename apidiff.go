@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// API diff tools of the api_diff_tool option, which decide how an exit status is read.
+const (
+	apiDiffGriffe  = "griffe"
+	apiDiffAbidiff = "abidiff"
+)
+
+// apiDiffTools lists the supported api_diff_tool values.
+var apiDiffTools = []string{apiDiffGriffe, apiDiffAbidiff}
+
+// apiDiffCommands are the default commands of the tools. griffe compares the package with the
+// git tag of the previous release; abidiff needs the libraries to compare, so it has no default.
+var apiDiffCommands = map[string][]string{
+	apiDiffGriffe: {"griffe", "check", "{package}", "--against", "v{previous_version}"},
+}
+
+// abidiff exit status bits; the others mean the comparison failed.
+const (
+	abidiffChange       = 4
+	abidiffIncompatible = 8
+)
+
+// apiDiffReport is the result of comparing the public API with the previous release, reported in
+// outputs.
+type apiDiffReport struct {
+	Tool            string   `json:"tool"`
+	Command         []string `json:"command"`
+	Version         string   `json:"version"`
+	PreviousVersion string   `json:"previous_version"`
+	// Breaking is set when the tool reported removed or incompatible public symbols
+	Breaking  bool   `json:"breaking"`
+	MajorBump bool   `json:"major_bump"`
+	Report    string `json:"report"`
+}
+
+// apiDiffCommand returns the configured api_diff_command or the command of the tool.
+func apiDiffCommand(cfg Config) []string {
+	if len(cfg.APIDiffCommand) > 0 {
+		return cfg.APIDiffCommand
+	}
+	return apiDiffCommands[cfg.APIDiffTool]
+}
+
+// apiDiffBreaking reads the exit status of the tool: whether it reported breaking changes, or an
+// error when the comparison itself failed.
+func apiDiffBreaking(tool string, err error) (bool, error) {
+	if err == nil {
+		return false, nil
+	}
+	var exit interface{ ExitCode() int }
+	if !errors.As(err, &exit) {
+		return false, err
+	}
+	code := exit.ExitCode()
+	switch tool {
+	case apiDiffAbidiff:
+		if code&^(abidiffChange|abidiffIncompatible) != 0 {
+			return false, err
+		}
+		// Compatible ABI changes such as added symbols are not breaking
+		return code&abidiffIncompatible != 0, nil
+	default:
+		if code != 1 {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// majorBump reports whether version raises the major version of previous, the only bump
+// allowed to remove public symbols.
+func majorBump(previous, version string) bool {
+	prev, ok := parsePEP440(previous)
+	if !ok {
+		return false
+	}
+	next, ok := parsePEP440(version)
+	if !ok {
+		return false
+	}
+	major := func(v pep440Version) int {
+		if len(v.release) == 0 {
+			return 0
+		}
+		return v.release[0]
+	}
+	return next.epoch > prev.epoch || (next.epoch == prev.epoch && major(next) > major(prev))
+}
+
+// preflightAPIDiff runs the API diff tool between the previous release on the index and the new
+// build, and warns or blocks when a non-major version removes public symbols. A first release
+// has nothing to compare with.
+func (p *PyPIPlugin) preflightAPIDiff(ctx context.Context, cfg Config, result *preflightResult) *plugin.ExecuteResponse {
+	skip := func(format string, args ...any) *plugin.ExecuteResponse {
+		msg := fmt.Sprintf(format, args...)
+		if cfg.APIDiff == checkFail {
+			return &plugin.ExecuteResponse{Success: false, Error: "API diff failed: " + msg}
+		}
+		result.warn("API diff skipped: %s", msg)
+		return nil
+	}
+	project, version := distProjectVersion(result.files)
+	if project == "" {
+		return nil
+	}
+	previous, err := p.previousRelease(ctx, cfg, project, version)
+	if err != nil {
+		return skip("%v", err)
+	}
+	if previous == "" {
+		return nil
+	}
+
+	vars := map[string]string{
+		"project":          project,
+		"package":          strings.ReplaceAll(normalizeProjectName(project), "-", "_"),
+		"version":          version,
+		"previous_version": previous,
+	}
+	for _, f := range result.files {
+		if strings.HasSuffix(f, ".whl") {
+			if names, err := wheelImportNames(f); err == nil && len(names) > 0 {
+				vars["package"] = names[0]
+				break
+			}
+		}
+	}
+	argv, err := renderCommandTemplate("api_diff_command", apiDiffCommand(cfg), vars)
+	if err != nil {
+		return skip("%v", err)
+	}
+	output, err := p.getExecutor().Run(ctx, argv[0], argv[1:]...)
+	if errors.Is(err, exec.ErrNotFound) {
+		return skip("%s is not installed", argv[0])
+	}
+	breaking, err := apiDiffBreaking(cfg.APIDiffTool, err)
+	if err != nil {
+		return skip("%s: %v", strings.Join(argv, " "), err)
+	}
+
+	report := &apiDiffReport{
+		Tool:            cfg.APIDiffTool,
+		Command:         argv,
+		Version:         version,
+		PreviousVersion: previous,
+		Breaking:        breaking,
+		MajorBump:       majorBump(previous, version),
+	}
+	report.Report, _ = truncateOutput(string(output), cfg.MaxOutputBytes)
+	result.outputs["api_diff"] = report
+	if !breaking || report.MajorBump {
+		return nil
+	}
+
+	msg := fmt.Sprintf("%s %s removes or changes public API of %s without a major version bump; see the api_diff report", project, version, previous)
+	if cfg.APIDiff == checkFail {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   "API diff failed: " + msg,
+			Outputs: map[string]any{"api_diff": report},
+		}
+	}
+	result.warn("%s", msg)
+	return nil
+}
+
+// validateAPIDiffConfig validates the api_diff options.
+func validateAPIDiffConfig(cfg Config) error {
+	if cfg.APIDiff == "" || cfg.APIDiff == checkOff {
+		if len(cfg.APIDiffCommand) > 0 {
+			return fmt.Errorf("api_diff_command requires api_diff")
+		}
+		return nil
+	}
+	if !containsString(checkModes, cfg.APIDiff) {
+		return fmt.Errorf("api_diff must be one of: %s", strings.Join(checkModes, ", "))
+	}
+	if !containsString(apiDiffTools, cfg.APIDiffTool) {
+		return fmt.Errorf("api_diff_tool must be one of: %s", strings.Join(apiDiffTools, ", "))
+	}
+	if len(apiDiffCommand(cfg)) == 0 {
+		return fmt.Errorf("api_diff_tool %s requires api_diff_command", cfg.APIDiffTool)
+	}
+	if cfg.JSONAPIURL == "" {
+		return fmt.Errorf("api_diff requires json_api_url for repositories other than PyPI and TestPyPI")
+	}
+	if err := validateJSONAPIURL(cfg); err != nil {
+		return err
+	}
+	_, err := renderCommandTemplate("api_diff_command", apiDiffCommand(cfg), map[string]string{"project": "", "package": "", "version": "", "previous_version": ""})
+	return err
+}
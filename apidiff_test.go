@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// exitError is a command failure with an exit status.
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitError) ExitCode() int { return int(e) }
+
+// newTestReleasesAPI serves the releases of mypkg on the JSON API.
+func newTestReleasesAPI(t *testing.T, versions ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pypi/mypkg/json" {
+			http.NotFound(w, r)
+			return
+		}
+		releases := make([]string, 0, len(versions))
+		for _, v := range versions {
+			releases = append(releases, fmt.Sprintf(`%q: [{"yanked": false}]`, v))
+		}
+		_, _ = fmt.Fprintf(w, `{"releases": {%s}}`, strings.Join(releases, ","))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAPIDiffBreaking(t *testing.T) {
+	tests := []struct {
+		tool     string
+		err      error
+		breaking bool
+		wantErr  bool
+	}{
+		{apiDiffGriffe, nil, false, false},
+		{apiDiffGriffe, exitError(1), true, false},
+		{apiDiffGriffe, exitError(2), false, true},
+		{apiDiffGriffe, fmt.Errorf("signal: killed"), false, true},
+		{apiDiffAbidiff, exitError(abidiffChange), false, false},
+		{apiDiffAbidiff, exitError(abidiffChange | abidiffIncompatible), true, false},
+		{apiDiffAbidiff, exitError(1), false, true},
+	}
+	for _, tt := range tests {
+		breaking, err := apiDiffBreaking(tt.tool, tt.err)
+		if breaking != tt.breaking || (err != nil) != tt.wantErr {
+			t.Errorf("apiDiffBreaking(%s, %v) = %v, %v; want %v, error %v", tt.tool, tt.err, breaking, err, tt.breaking, tt.wantErr)
+		}
+	}
+}
+
+func TestMajorBump(t *testing.T) {
+	tests := []struct {
+		previous, version string
+		want              bool
+	}{
+		{"1.4.2", "2.0.0", true},
+		{"1.4.2", "1.5.0", false},
+		{"1.4.2", "2.0.0rc1", true},
+		{"0.9", "0.10", false},
+		{"2024.1", "1!0.1", true},
+		{"1.4.2", "nightly", false},
+	}
+	for _, tt := range tests {
+		if got := majorBump(tt.previous, tt.version); got != tt.want {
+			t.Errorf("majorBump(%q, %q) = %v, want %v", tt.previous, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteAPIDiff(t *testing.T) {
+	server := newTestReleasesAPI(t, "1.0.0", "1.1.0")
+	tests := []struct {
+		name     string
+		mode     string
+		version  string
+		exit     error
+		wantOK   bool
+		wantWarn bool
+	}{
+		{"compatible", checkFail, "1.2.0", nil, true, false},
+		{"breaking minor warns", checkWarn, "1.2.0", exitError(1), true, true},
+		{"breaking minor fails", checkFail, "1.2.0", exitError(1), false, false},
+		{"breaking major", checkFail, "2.0.0", exitError(1), true, false},
+		{"tool error warns", checkWarn, "1.2.0", exitError(2), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeDistFiles(t)
+			writeTestWheel(t, filepath.Join("dist", "mypkg-"+tt.version+"-py3-none-any.whl"), map[string]string{
+				"mypkg/__init__.py":                           "",
+				"mypkg-" + tt.version + ".dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: " + tt.version + "\n",
+			})
+			executor := &MockCommandExecutor{ReturnOut: []byte("mypkg/api.py:10: connect: Public object was removed"), ReturnError: tt.exit}
+			p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: executor}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"username":     "deployer",
+					"password":     "secret",
+					"repository":   "http://localhost:8080/",
+					"api_diff":     tt.mode,
+					"json_api_url": server.URL + "/pypi",
+				},
+				DryRun: true,
+			})
+			if err != nil || resp.Success != tt.wantOK {
+				t.Fatalf("expected success %v, got %v %+v", tt.wantOK, err, resp)
+			}
+			if len(executor.RunCalls) != 1 || strings.Join(executor.RunCalls[0].Args, " ") != "check mypkg --against v1.1.0" {
+				t.Fatalf("unexpected griffe calls %+v", executor.RunCalls)
+			}
+			if warnings := fmt.Sprint(resp.Outputs["warnings"]); (strings.Contains(warnings, "API diff skipped") || strings.Contains(warnings, "without a major version bump")) != tt.wantWarn {
+				t.Errorf("expected warning %v, got %s", tt.wantWarn, warnings)
+			}
+			if report, ok := resp.Outputs["api_diff"].(*apiDiffReport); tt.exit != exitError(2) && (!ok || report.PreviousVersion != "1.1.0" || report.Breaking != (tt.exit != nil)) {
+				t.Errorf("unexpected api_diff %+v", resp.Outputs["api_diff"])
+			}
+		})
+	}
+}
+
+func TestExecuteAPIDiffFirstRelease(t *testing.T) {
+	server := newTestReleasesAPI(t)
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	executor := &MockCommandExecutor{}
+	p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: executor}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":     "deployer",
+			"password":     "secret",
+			"repository":   "http://localhost:8080/",
+			"api_diff":     checkFail,
+			"json_api_url": server.URL + "/pypi",
+		},
+		DryRun: true,
+	})
+	if err != nil || !resp.Success || len(executor.RunCalls) != 0 || resp.Outputs["api_diff"] != nil {
+		t.Fatalf("expected a first release not to be compared, got %v %+v", err, resp)
+	}
+}
+
+func TestValidateAPIDiffConfig(t *testing.T) {
+	pypi := "https://upload.pypi.org/legacy/"
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"off", Config{APIDiff: checkOff}, false},
+		{"griffe", Config{APIDiff: checkWarn, APIDiffTool: apiDiffGriffe, JSONAPIURL: defaultJSONAPI(pypi)}, false},
+		{"abidiff without command", Config{APIDiff: checkWarn, APIDiffTool: apiDiffAbidiff, JSONAPIURL: defaultJSONAPI(pypi)}, true},
+		{"abidiff", Config{APIDiff: checkFail, APIDiffTool: apiDiffAbidiff, APIDiffCommand: []string{"./abi-check.sh", "{previous_version}", "{version}"}, JSONAPIURL: defaultJSONAPI(pypi)}, false},
+		{"unknown variable", Config{APIDiff: checkWarn, APIDiffTool: apiDiffGriffe, APIDiffCommand: []string{"griffe", "check", "{tag}"}, JSONAPIURL: defaultJSONAPI(pypi)}, true},
+		{"unknown tool", Config{APIDiff: checkWarn, APIDiffTool: "japicmp", JSONAPIURL: defaultJSONAPI(pypi)}, true},
+		{"no json api", Config{APIDiff: checkWarn, APIDiffTool: apiDiffGriffe, Repository: "http://localhost:8080/"}, true},
+		{"command without api_diff", Config{APIDiff: checkOff, APIDiffCommand: []string{"griffe"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateAPIDiffConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateAPIDiffConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Authentication methods of an artifactory block.
+const (
+	artifactoryAuthBasic       = "basic"
+	artifactoryAuthAccessToken = "access_token"
+	artifactoryAuthAPIKey      = "api_key"
+)
+
+// artifactoryAuthMethods lists the accepted values of artifactory.auth.
+var artifactoryAuthMethods = []string{artifactoryAuthBasic, artifactoryAuthAccessToken, artifactoryAuthAPIKey}
+
+// artifactoryAPIKeyHeader carries Artifactory API keys.
+const artifactoryAPIKeyHeader = "X-JFrog-Art-Api"
+
+// Artifactory identifies a PyPI repository of a JFrog Artifactory instance. Its upload URL is
+// derived from the instance URL and repository key, and the password is sent as the access
+// token or API key Artifactory expects.
+type Artifactory struct {
+	// BaseURL is the Artifactory URL, such as https://acme.jfrog.io/artifactory
+	BaseURL string
+	// RepoKey is the key of a local repository, or of a virtual repository with a default
+	// deployment repository
+	RepoKey string
+	// Auth is basic, access_token or api_key (default basic)
+	Auth string
+}
+
+// parseArtifactory parses the artifactory block, or returns nil when it is absent.
+func parseArtifactory(raw any) *Artifactory {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil
+	}
+	a := &Artifactory{Auth: artifactoryAuthBasic}
+	a.BaseURL, _ = m["base_url"].(string)
+	a.RepoKey, _ = m["repo_key"].(string)
+	if v, ok := m["auth"].(string); ok && v != "" {
+		a.Auth = strings.ToLower(v)
+	}
+	return a
+}
+
+// repositoryURL returns the PyPI upload endpoint of the repository.
+func (a *Artifactory) repositoryURL() string {
+	return strings.TrimSuffix(a.BaseURL, "/") + "/api/pypi/" + url.PathEscape(a.RepoKey)
+}
+
+// authScheme returns the auth_scheme and auth_header sending the password the way auth asks:
+// access tokens as bearer tokens and API keys in the X-JFrog-Art-Api header.
+func (a *Artifactory) authScheme() (scheme, header string) {
+	switch a.Auth {
+	case artifactoryAuthAccessToken:
+		return authSchemeBearer, defaultAuthHeader
+	case artifactoryAuthAPIKey:
+		return authSchemeNone, artifactoryAPIKeyHeader
+	}
+	return authSchemeBasic, defaultAuthHeader
+}
+
+// artifactoryRemediations recognize the error bodies of Artifactory, such as
+// {"errors": [{"status": 403, "message": "..."}]}. They are tried before the built-in rules
+// when an artifactory block is configured.
+var artifactoryRemediations = []builtinRemediation{
+	{"artifactory_bad_credentials", regexp.MustCompile(`(?i)bad credentials|props authentication token not found`).MatchString,
+		"Artifactory rejected the credentials; check that the access token or API key has not expired or been revoked, and that artifactory.auth matches it (access_token for tokens, api_key for API keys)"},
+	{"artifactory_api_key_disabled", regexp.MustCompile(`(?i)api key.*(disabled|deprecated|revoked)`).MatchString,
+		"API keys are disabled on this Artifactory instance; create an access token and set artifactory.auth to access_token"},
+	{"artifactory_overwrite", regexp.MustCompile(`(?i)overwrite artifact|needs DELETE permission`).MatchString,
+		"The version is already in the Artifactory repository and replacing it needs the Delete/Overwrite permission; bump the version, or set skip_existing"},
+	{"artifactory_deploy_permission", regexp.MustCompile(`(?i)not enough permissions|needs DEPLOY|not permitted to deploy`).MatchString,
+		"The Artifactory user lacks the Deploy/Cache permission on the repository; ask an Artifactory administrator to grant it in a permission target covering artifactory.repo_key"},
+	{"artifactory_no_deployment_repo", regexp.MustCompile(`(?i)default deployment repo`).MatchString,
+		"The virtual repository has no default deployment repository; set one in Artifactory, or set artifactory.repo_key to a local repository"},
+	{"artifactory_remote_repo", regexp.MustCompile(`(?i)remote repo(sitory)?[^\n]{0,80}(deploy|upload)|(deploy|upload)[^\n]{0,80}remote repo`).MatchString,
+		"Artifacts cannot be deployed to a remote repository; set artifactory.repo_key to a local repository, or a virtual repository with a default deployment repository"},
+	{"artifactory_repo_not_found", regexp.MustCompile(`(?i)\brepo(sitory)?\b[^\n]{0,80}\b(not found|does not exist|doesn't exist)`).MatchString,
+		"Artifactory has no repository with this key; check artifactory.repo_key, which is case sensitive, and that the repository has the PyPI package type"},
+}
+
+// validateArtifactoryConfig validates the artifactory block.
+func validateArtifactoryConfig(cfg Config) error {
+	a := cfg.Artifactory
+	if a == nil {
+		return nil
+	}
+	switch {
+	case a.BaseURL == "" || a.RepoKey == "":
+		return fmt.Errorf("artifactory requires base_url and repo_key")
+	case !containsString(artifactoryAuthMethods, a.Auth):
+		return fmt.Errorf("artifactory.auth must be one of: %s", strings.Join(artifactoryAuthMethods, ", "))
+	case cfg.CodeArtifact != nil || cfg.GCPArtifactRegistry || cfg.AzureArtifacts != nil:
+		return fmt.Errorf("artifactory cannot be combined with codeartifact, gcp_artifact_registry or azure_artifacts")
+	case cfg.Token != "" || cfg.TrustedPublishing || cfg.DeviceAuth:
+		return fmt.Errorf("artifactory cannot be combined with token, trusted_publishing or device_auth; set the access token or API key as password")
+	}
+	if u, err := url.Parse(a.BaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("artifactory.base_url must be an http or https URL, got %q", a.BaseURL)
+	}
+	if scheme, header := a.authScheme(); cfg.AuthScheme != scheme || !strings.EqualFold(cfg.AuthHeader, header) {
+		return fmt.Errorf("artifactory.auth %s sets auth_scheme and auth_header; remove them", a.Auth)
+	}
+	return nil
+}
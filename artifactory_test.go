@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestParseArtifactory(t *testing.T) {
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"artifactory": map[string]any{"base_url": "https://acme.jfrog.io/artifactory/", "repo_key": "pypi-local", "auth": "api_key"},
+	})
+	if cfg.Repository != "https://acme.jfrog.io/artifactory/api/pypi/pypi-local" {
+		t.Errorf("unexpected repository %s", cfg.Repository)
+	}
+	if cfg.AuthScheme != authSchemeNone || cfg.AuthHeader != artifactoryAPIKeyHeader {
+		t.Errorf("expected the API key header, got %s %s", cfg.AuthScheme, cfg.AuthHeader)
+	}
+
+	cfg = p.parseConfig(map[string]any{
+		"artifactory": map[string]any{"base_url": "https://acme.jfrog.io/artifactory", "repo_key": "pypi-local", "auth": "access_token"},
+	})
+	if cfg.AuthScheme != authSchemeBearer || cfg.AuthHeader != defaultAuthHeader {
+		t.Errorf("expected a bearer token, got %s %s", cfg.AuthScheme, cfg.AuthHeader)
+	}
+	if cfg := p.parseConfig(map[string]any{}); cfg.Artifactory != nil {
+		t.Errorf("expected no artifactory without the block, got %+v", cfg.Artifactory)
+	}
+}
+
+func TestExecuteArtifactory(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+
+	permitted := true
+	var uploads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.URL.Path != "/artifactory/api/pypi/pypi-local" || r.Header.Get(artifactoryAPIKeyHeader) != "AKCp8-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"errors": [{"status": 401, "message": "Bad credentials"}]}`)
+			return
+		}
+		if !permitted {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"errors": [{"status": 403, "message": "Not enough permissions to deploy artifact 'mypkg/1.0.0/mypkg-1.0.0-py3-none-any.whl' (user: 'ci' needs DEPLOY permission)."}]}`)
+			return
+		}
+		uploads = append(uploads, r.URL.Path)
+	}))
+	defer server.Close()
+
+	execute := func(key string) *plugin.ExecuteResponse {
+		t.Helper()
+		mockExecutor := &MockCommandExecutor{}
+		p := &PyPIPlugin{cmdExecutor: mockExecutor, httpClient: server.Client()}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"password":    key,
+				"artifactory": map[string]any{"base_url": server.URL + "/artifactory", "repo_key": "pypi-local", "auth": "api_key"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(mockExecutor.RunCalls) != 0 {
+			t.Errorf("twine must not run for API key headers, got %d calls", len(mockExecutor.RunCalls))
+		}
+		return resp
+	}
+
+	resp := execute("AKCp8-key")
+	if !resp.Success || len(uploads) != 1 {
+		t.Fatalf("expected the upload to succeed, got %+v %v", resp, uploads)
+	}
+
+	resp = execute("expired")
+	if fix, _ := resp.Outputs["remediation"].(*remediation); resp.Success || fix == nil || fix.Rule != "artifactory_bad_credentials" {
+		t.Errorf("expected the bad credentials remediation, got %+v", resp)
+	}
+	permitted = false
+	resp = execute("AKCp8-key")
+	if resp.Success || !strings.HasPrefix(resp.Error, "The Artifactory user lacks the Deploy/Cache permission") || !strings.Contains(resp.Error, "needs DEPLOY permission") {
+		t.Errorf("expected the deploy permission advice in front of the error, got %q", resp.Error)
+	}
+}
+
+func TestArtifactoryRemediations(t *testing.T) {
+	cfg := Config{Artifactory: &Artifactory{BaseURL: "https://acme.jfrog.io/artifactory", RepoKey: "pypi-local"}}
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{`HTTPError: 403 Forbidden {"errors": [{"status": 403, "message": "Not enough permissions to overwrite artifact 'pypi-local:mypkg/1.0.0/mypkg-1.0.0.tar.gz' (user 'ci' needs DELETE permission)."}]}`, "artifactory_overwrite"},
+		{`HTTPError: 400 Bad Request {"errors": [{"status": 400, "message": "Virtual repo 'pypi' has no default deployment repository"}]}`, "artifactory_no_deployment_repo"},
+		{`HTTPError: 404 Not Found {"errors": [{"status": 404, "message": "Repository 'PyPI-local' not found"}]}`, "artifactory_repo_not_found"},
+		{`HTTPError: 400 Bad Request {"errors": [{"status": 400, "message": "Cannot deploy to remote repository 'pypi-remote'"}]}`, "artifactory_remote_repo"},
+		{"HTTPError: 400 Bad Request\nFile already exists", "file_exists"},
+	}
+	for _, tt := range tests {
+		var got string
+		if fix := suggestRemediation(cfg, tt.output); fix != nil {
+			got = fix.Rule
+		}
+		if got != tt.want {
+			t.Errorf("suggestRemediation(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+	if fix := suggestRemediation(Config{}, "Bad credentials"); fix != nil {
+		t.Errorf("expected the Artifactory rules to need an artifactory block, got %+v", fix)
+	}
+}
+
+func TestValidateArtifactoryConfig(t *testing.T) {
+	valid := Config{
+		AuthScheme:  authSchemeBearer,
+		AuthHeader:  defaultAuthHeader,
+		Artifactory: &Artifactory{BaseURL: "https://acme.jfrog.io/artifactory", RepoKey: "pypi-local", Auth: artifactoryAuthAccessToken},
+	}
+	if err := validateArtifactoryConfig(valid); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := validateArtifactoryConfig(Config{}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, modify := range []func(*Config){
+		func(c *Config) { c.Artifactory.RepoKey = "" },
+		func(c *Config) { c.Artifactory.BaseURL = "acme.jfrog.io/artifactory" },
+		func(c *Config) { c.Artifactory.Auth = "oauth" },
+		func(c *Config) { c.AuthHeader = "X-Api-Key" },
+		func(c *Config) { c.Token = "pypi-token" },
+		func(c *Config) { c.CodeArtifact = &CodeArtifact{Domain: "acme"} },
+	} {
+		cfg := valid
+		settings := *valid.Artifactory
+		cfg.Artifactory = &settings
+		modify(&cfg)
+		if err := validateArtifactoryConfig(cfg); err == nil {
+			t.Errorf("expected an error for %+v %+v", cfg, cfg.Artifactory)
+		}
+	}
+}
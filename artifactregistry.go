@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// artifactRegistryUsername is the user Artifact Registry expects with an access token as
+	// the password.
+	artifactRegistryUsername = "oauth2accesstoken"
+	// artifactRegistryHostSuffix ends the host of Artifact Registry Python repositories,
+	// <region>-python.pkg.dev.
+	artifactRegistryHostSuffix = "-python.pkg.dev"
+	// gcpCloudPlatformScope is the OAuth scope requested for service account credentials.
+	gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	// defaultGCPTokenURI is the Google OAuth token endpoint of credential files without token_uri.
+	defaultGCPTokenURI = "https://oauth2.googleapis.com/token"
+	// gcpJWTBearerGrant is the grant type exchanging a signed service account JWT for a token.
+	gcpJWTBearerGrant = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	// gcpAssertionLifetime is the lifetime of the JWT asserting a service account, the maximum
+	// Google accepts.
+	gcpAssertionLifetime = time.Hour
+)
+
+// gcpCredentialsFile is a Google Cloud credentials file: a service account key or the
+// authorized user credentials written by gcloud auth application-default login.
+type gcpCredentialsFile struct {
+	Type string `json:"type"`
+	// Service account keys
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	// Authorized users
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+
+	TokenURI string `json:"token_uri"`
+}
+
+// gcpTokenSource obtains Google Cloud access tokens for Artifact Registry uploads from
+// Application Default Credentials, or from a service account key when credentialsFile is set.
+type gcpTokenSource struct {
+	p               *PyPIPlugin
+	credentialsFile string
+	now             func() time.Time
+}
+
+// fetch obtains an access token. Credentials are looked up as Google's client libraries do:
+// the configured key file, then GOOGLE_APPLICATION_CREDENTIALS, then the file written by
+// gcloud auth application-default login, then the service account of a Google Cloud runner.
+func (s *gcpTokenSource) fetch(ctx context.Context) (credential, error) {
+	path, err := gcpCredentialsPath(s.credentialsFile)
+	if err != nil {
+		return credential{}, err
+	}
+
+	var token string
+	var lifetime time.Duration
+	if path != "" {
+		token, lifetime, err = s.fileToken(ctx, path)
+	} else {
+		token, lifetime, err = s.p.gcpMetadataToken(ctx)
+		if err != nil {
+			err = fmt.Errorf("no Google Cloud credentials found (set gcp_credentials_file or GOOGLE_APPLICATION_CREDENTIALS, run gcloud auth application-default login, or run with a service account on Google Cloud): %w", err)
+		}
+	}
+	if err != nil {
+		return credential{}, err
+	}
+	cred := credential{Username: artifactRegistryUsername, Password: token}
+	if lifetime > 0 {
+		cred.ExpiresAt = s.now().Add(lifetime)
+	}
+	return cred, nil
+}
+
+// gcpCredentialsPath returns the credentials file to use, or "" when none is found and the
+// metadata server is asked instead.
+func gcpCredentialsPath(configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return path, nil
+	}
+	dir := os.Getenv("CLOUDSDK_CONFIG")
+	if dir == "" {
+		if runtime.GOOS == "windows" {
+			dir = filepath.Join(os.Getenv("APPDATA"), "gcloud")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config", "gcloud")
+		}
+	}
+	if dir == "" {
+		return "", nil
+	}
+	path := filepath.Join(dir, "application_default_credentials.json")
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// fileToken exchanges the credentials of a credentials file for an access token.
+func (s *gcpTokenSource) fileToken(ctx context.Context, path string) (string, time.Duration, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- the configured credentials file
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read Google Cloud credentials: %w", err)
+	}
+	var creds gcpCredentialsFile
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return "", 0, fmt.Errorf("invalid Google Cloud credentials in %s: %w", path, err)
+	}
+	tokenURI := creds.TokenURI
+	if tokenURI == "" {
+		tokenURI = defaultGCPTokenURI
+	}
+
+	var form url.Values
+	switch creds.Type {
+	case "service_account":
+		assertion, err := gcpServiceAccountAssertion(creds, tokenURI, s.now())
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid service account key in %s: %w", path, err)
+		}
+		form = url.Values{"grant_type": {gcpJWTBearerGrant}, "assertion": {assertion}}
+	case "authorized_user":
+		if creds.RefreshToken == "" || creds.ClientID == "" {
+			return "", 0, fmt.Errorf("invalid Google Cloud credentials in %s: client_id and refresh_token are required", path)
+		}
+		form = url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		}
+	default:
+		return "", 0, fmt.Errorf("unsupported Google Cloud credentials type %q in %s (use a service account key or gcloud auth application-default login)", creds.Type, path)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := s.p.postOAuthForm(ctx, tokenURI, form, &token); err != nil {
+		return "", 0, fmt.Errorf("google token request failed: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", 0, fmt.Errorf("google token request returned no token")
+	}
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, nil
+}
+
+// gcpServiceAccountAssertion returns the RS256 JWT with which a service account requests an
+// access token.
+func gcpServiceAccountAssertion(creds gcpCredentialsFile, audience string, now time.Time) (string, error) {
+	if creds.ClientEmail == "" || creds.PrivateKey == "" {
+		return "", fmt.Errorf("client_email and private_key are required")
+	}
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("private_key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return "", fmt.Errorf("invalid private_key: %w", err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("private_key must be an RSA key")
+	}
+
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if creds.PrivateKeyID != "" {
+		header["kid"] = creds.PrivateKeyID
+	}
+	claims := map[string]any{
+		"iss":   creds.ClientEmail,
+		"scope": gcpCloudPlatformScope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(gcpAssertionLifetime).Unix(),
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// validateArtifactRegistryConfig validates gcp_artifact_registry and gcp_credentials_file.
+func validateArtifactRegistryConfig(cfg Config) error {
+	if !cfg.GCPArtifactRegistry {
+		if cfg.GCPCredentialsFile != "" {
+			return fmt.Errorf("gcp_credentials_file requires gcp_artifact_registry")
+		}
+		return nil
+	}
+	u, err := url.Parse(cfg.Repository)
+	if err != nil || u.Scheme != "https" || !strings.HasSuffix(u.Hostname(), artifactRegistryHostSuffix) {
+		return fmt.Errorf("gcp_artifact_registry requires an https://<region>%s/<project>/<repository>/ repository, got %q", artifactRegistryHostSuffix, cfg.Repository)
+	}
+	switch {
+	case len(cfg.TokenCommand) > 0 || cfg.Token != "" || cfg.TrustedPublishing || cfg.DeviceAuth:
+		return fmt.Errorf("gcp_artifact_registry cannot be combined with token, token_command, trusted_publishing or device_auth")
+	case cfg.CodeArtifact != nil:
+		return fmt.Errorf("gcp_artifact_registry cannot be combined with codeartifact")
+	case len(cfg.CredentialOverrides) > 0 || len(cfg.CustomCommand) > 0:
+		return fmt.Errorf("gcp_artifact_registry cannot be combined with credential_overrides or custom_command")
+	case cfg.SpiffeWorkloadAPI || !usesBasicAuth(cfg):
+		return fmt.Errorf("gcp_artifact_registry uploads with basic auth and cannot be combined with auth_scheme or spiffe_workload_api")
+	}
+	return nil
+}
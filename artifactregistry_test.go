@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeGCPCredentials writes a credentials file and returns its path.
+func writeGCPCredentials(t *testing.T, creds map[string]string) string {
+	t.Helper()
+	data, err := json.Marshal(creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunTwineUploadsWithArtifactRegistry(t *testing.T) {
+	distPath := writeDistFiles(t, "pkg-1.0.0.tar.gz", "pkg-1.0.0-py3-none-any.whl")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	// Tokens expire within the refresh margin, so each file gets a new one
+	fetches := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != gcpJWTBearerGrant {
+			http.Error(w, `{"error": "unsupported_grant_type"}`, http.StatusBadRequest)
+			return
+		}
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if len(parts) != 3 {
+			http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims struct {
+			Iss   string `json:"iss"`
+			Scope string `json:"scope"`
+			Aud   string `json:"aud"`
+		}
+		_ = json.Unmarshal(payload, &claims)
+		if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig) != nil || !strings.Contains(string(header), `"kid":"key-1"`) ||
+			claims.Iss != "publisher@acme.iam.gserviceaccount.com" || claims.Scope != gcpCloudPlatformScope || claims.Aud != server.URL+"/token" {
+			http.Error(w, `{"error": "invalid_grant", "error_description": "Invalid JWT Signature."}`, http.StatusBadRequest)
+			return
+		}
+		fetches++
+		_, _ = fmt.Fprintf(w, `{"access_token": "ya29.token-%d", "expires_in": 60, "token_type": "Bearer"}`, fetches)
+	}))
+	defer server.Close()
+
+	keyFile := writeGCPCredentials(t, map[string]string{
+		"type":           "service_account",
+		"client_email":   "publisher@acme.iam.gserviceaccount.com",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      server.URL + "/token",
+	})
+	mockExecutor := &MockCommandExecutor{ReturnOut: []byte("Uploading\n")}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor}
+	cfg := p.parseConfig(map[string]any{
+		"repository":            "https://europe-west1-python.pkg.dev/acme/python-internal/",
+		"dist_path":             distPath,
+		"gcp_artifact_registry": true,
+		"gcp_credentials_file":  keyFile,
+	})
+	if err := validateArtifactRegistryConfig(cfg); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	run, err := p.runTwineUploads(context.Background(), cfg, mockExecutor, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetches != 2 || run.tokenRefreshes != 1 {
+		t.Errorf("expected the token to be refreshed before the second file, got %d fetches and %d refreshes", fetches, run.tokenRefreshes)
+	}
+	var envs []string
+	for _, call := range mockExecutor.RunCalls {
+		envs = append(envs, strings.Join(call.Env, " "))
+	}
+	if want := "TWINE_USERNAME=oauth2accesstoken TWINE_PASSWORD=ya29.token-1,TWINE_USERNAME=oauth2accesstoken TWINE_PASSWORD=ya29.token-2"; strings.Join(envs, ",") != want {
+		t.Errorf("unexpected twine credentials %v", envs)
+	}
+}
+
+func TestGCPTokenSourceAuthorizedUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "1//refresh" || r.FormValue("client_id") != "client.apps.googleusercontent.com" {
+			http.Error(w, `{"error": "invalid_grant", "error_description": "Token has been expired or revoked."}`, http.StatusBadRequest)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"access_token": "ya29.user", "expires_in": 3599}`)
+	}))
+	defer server.Close()
+
+	// The file written by gcloud auth application-default login is found through CLOUDSDK_CONFIG
+	dir := t.TempDir()
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("CLOUDSDK_CONFIG", dir)
+	data, _ := json.Marshal(map[string]string{
+		"type":          "authorized_user",
+		"client_id":     "client.apps.googleusercontent.com",
+		"client_secret": "secret",
+		"refresh_token": "1//refresh",
+		"token_uri":     server.URL,
+	})
+	if err := os.WriteFile(filepath.Join(dir, "application_default_credentials.json"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	source := &gcpTokenSource{p: &PyPIPlugin{}, now: func() time.Time { return now }}
+	cred, err := source.fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Username != artifactRegistryUsername || cred.Password != "ya29.user" || !cred.ExpiresAt.Equal(now.Add(3599*time.Second)) {
+		t.Errorf("unexpected credential %+v", cred)
+	}
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeGCPCredentials(t, map[string]string{
+		"type":          "authorized_user",
+		"client_id":     "client.apps.googleusercontent.com",
+		"refresh_token": "1//revoked",
+		"token_uri":     server.URL,
+	}))
+	if _, err := source.fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "Token has been expired or revoked") {
+		t.Errorf("expected GOOGLE_APPLICATION_CREDENTIALS to take precedence and its error to be reported, got %v", err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeGCPCredentials(t, map[string]string{"type": "external_account"}))
+	if _, err := source.fetch(context.Background()); err == nil || !strings.Contains(err.Error(), `unsupported Google Cloud credentials type "external_account"`) {
+		t.Errorf("expected an unsupported credentials type to be rejected, got %v", err)
+	}
+}
+
+func TestGCPTokenSourceMetadataServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Path != "/computeMetadata/v1/instance/service-accounts/default/token" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"access_token": "ya29.metadata", "expires_in": 1800}`)
+	}))
+	defer server.Close()
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("CLOUDSDK_CONFIG", t.TempDir())
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	now := time.Now()
+	source := &gcpTokenSource{p: &PyPIPlugin{}, now: func() time.Time { return now }}
+	cred, err := source.fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Password != "ya29.metadata" || !cred.ExpiresAt.Equal(now.Add(30*time.Minute)) {
+		t.Errorf("unexpected credential %+v", cred)
+	}
+}
+
+func TestValidateArtifactRegistryConfig(t *testing.T) {
+	valid := Config{Repository: "https://us-central1-python.pkg.dev/acme/python/", GCPArtifactRegistry: true}
+	if err := validateArtifactRegistryConfig(valid); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := validateArtifactRegistryConfig(Config{Repository: "https://upload.pypi.org/legacy/"}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, modify := range []func(*Config){
+		func(c *Config) { c.Repository = "https://upload.pypi.org/legacy/" },
+		func(c *Config) { c.Repository = "http://us-central1-python.pkg.dev/acme/python/" },
+		func(c *Config) { c.GCPArtifactRegistry, c.GCPCredentialsFile = false, "key.json" },
+		func(c *Config) { c.TokenCommand = []string{"gcloud", "auth", "print-access-token"} },
+		func(c *Config) { c.CodeArtifact = &CodeArtifact{Domain: "acme"} },
+		func(c *Config) { c.AuthScheme = authSchemeSigV4 },
+	} {
+		cfg := valid
+		modify(&cfg)
+		if err := validateArtifactRegistryConfig(cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
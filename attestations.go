@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// attestationSuffix is appended to a distribution's path for its PEP 740 publish attestation,
+// where twine --attestations looks for it.
+const attestationSuffix = ".publish.attestation"
+
+// defaultAttestationCommand signs distributions with pypi-attestations, which writes a Sigstore
+// attestation next to each file using the ambient OIDC identity of the CI job.
+var defaultAttestationCommand = []string{"python3", "-m", "pypi_attestations", "sign"}
+
+// distAttestation is the attestation of one distribution, reported in outputs.
+type distAttestation struct {
+	File        string `json:"file"`
+	SHA256      string `json:"sha256"`
+	Attestation string `json:"attestation"`
+	// Generated is false for attestations that existed before the publish
+	Generated bool `json:"generated"`
+}
+
+// attestationPath returns the attestation file of a distribution.
+func attestationPath(file string) string {
+	return file + attestationSuffix
+}
+
+// isAttestationFile reports whether a dist_path match is an attestation rather than a
+// distribution.
+func isAttestationFile(path string) bool {
+	return strings.HasSuffix(path, attestationSuffix)
+}
+
+// attestDistFiles makes sure every file has an attestation for its current content: files
+// without one are signed with attestation_command, and existing attestations, for example from
+// an earlier pipeline step, are kept when they match. A stale attestation fails the publish, as
+// the index would reject it.
+func (p *PyPIPlugin) attestDistFiles(ctx context.Context, cfg Config, files []string) ([]distAttestation, []plugin.Artifact, error) {
+	var unsigned []string
+	for _, f := range files {
+		if _, err := os.Stat(attestationPath(f)); os.IsNotExist(err) {
+			unsigned = append(unsigned, f)
+		}
+	}
+	if len(unsigned) > 0 {
+		args := append(append([]string{}, cfg.AttestationCommand[1:]...), unsigned...)
+		output, err := p.getExecutor().Run(ctx, cfg.AttestationCommand[0], args...)
+		if err != nil {
+			detail, _ := truncateOutput(strings.TrimSpace(string(output)), defaultMaxErrorBodyBytes)
+			return nil, nil, fmt.Errorf("failed to generate attestations: %w: %s", err, detail)
+		}
+	}
+
+	attestations := make([]distAttestation, 0, len(files))
+	artifacts := make([]plugin.Artifact, 0, len(files))
+	for _, f := range files {
+		path := attestationPath(f)
+		_, digest, _, err := fileDigests(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := checkAttestation(path, filepath.Base(f), digest); err != nil {
+			return nil, nil, err
+		}
+		_, attDigest, size, err := fileDigests(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		attestations = append(attestations, distAttestation{
+			File:        filepath.Base(f),
+			SHA256:      digest,
+			Attestation: filepath.ToSlash(path),
+			Generated:   containsString(unsigned, f),
+		})
+		artifacts = append(artifacts, plugin.Artifact{
+			Name:     filepath.Base(path),
+			Path:     path,
+			Type:     "file",
+			Size:     size,
+			Checksum: "sha256:" + attDigest,
+		})
+	}
+	return attestations, artifacts, nil
+}
+
+// checkAttestation checks that the in-toto statement of an attestation names the distribution
+// and its SHA-256 digest.
+func checkAttestation(path, name, sha256Digest string) error {
+	data, err := os.ReadFile(path) // #nosec G304 -- next to a distribution matched by the validated dist_path
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no attestation was generated for %s", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the attestation of %s: %w", name, err)
+	}
+	var attestation struct {
+		Envelope struct {
+			Statement string `json:"statement"`
+		} `json:"envelope"`
+	}
+	if err := json.Unmarshal(data, &attestation); err != nil {
+		return fmt.Errorf("invalid attestation %s: %w", filepath.Base(path), err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(attestation.Envelope.Statement)
+	if err != nil {
+		return fmt.Errorf("invalid attestation %s: statement is not base64: %w", filepath.Base(path), err)
+	}
+	var statement struct {
+		Subject []struct {
+			Name   string            `json:"name"`
+			Digest map[string]string `json:"digest"`
+		} `json:"subject"`
+	}
+	if err := json.Unmarshal(raw, &statement); err != nil {
+		return fmt.Errorf("invalid attestation %s: %w", filepath.Base(path), err)
+	}
+	for _, s := range statement.Subject {
+		if s.Name == name && strings.EqualFold(s.Digest["sha256"], sha256Digest) {
+			return nil
+		}
+	}
+	return fmt.Errorf("attestation %s does not match the current %s; remove it to sign the file again", filepath.Base(path), name)
+}
+
+// attestationsField returns the attestations form field of the legacy upload API: a JSON
+// array holding the attestation of the distribution, as twine sends it.
+func attestationsField(path string) (string, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- checked by attestDistFiles
+	if err != nil {
+		return "", err
+	}
+	var attestation json.RawMessage
+	if err := json.Unmarshal(data, &attestation); err != nil {
+		return "", fmt.Errorf("invalid attestation %s: %w", filepath.Base(path), err)
+	}
+	field, err := json.Marshal([]json.RawMessage{attestation})
+	return string(field), err
+}
+
+// validateAttestationConfig validates the attestations option. PyPI only accepts attestations
+// uploaded with a Trusted Publishing token, as it verifies them against the publisher.
+func validateAttestationConfig(cfg Config) error {
+	if !cfg.Attestations {
+		return nil
+	}
+	if !cfg.TrustedPublishing {
+		return fmt.Errorf("attestations are only accepted from Trusted Publishing; set trusted_publishing")
+	}
+	if len(cfg.CustomCommand) > 0 {
+		return fmt.Errorf("attestations cannot be uploaded by custom_command")
+	}
+	if len(cfg.AttestationCommand) == 0 {
+		return fmt.Errorf("attestation_command must not be empty")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestAttestation writes an attestation whose statement names file with digest.
+func writeTestAttestation(t *testing.T, file, digest string) {
+	t.Helper()
+	statement, _ := json.Marshal(map[string]any{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"subject":       []map[string]any{{"name": filepath.Base(file), "digest": map[string]string{"sha256": digest}}},
+		"predicateType": "https://docs.pypi.org/attestations/publish/v1",
+	})
+	attestation, _ := json.Marshal(map[string]any{
+		"version":               1,
+		"verification_material": map[string]any{"certificate": "MIIC", "transparency_entries": []any{}},
+		"envelope":              map[string]string{"statement": base64.StdEncoding.EncodeToString(statement), "signature": "c2ln"},
+	})
+	if err := os.WriteFile(attestationPath(file), attestation, 0o600); err != nil {
+		t.Fatalf("failed to write attestation: %v", err)
+	}
+}
+
+// attestingExecutor writes a valid attestation for each file it is asked to sign.
+func attestingExecutor(t *testing.T) *MockCommandExecutor {
+	return &MockCommandExecutor{RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		for _, f := range args[3:] {
+			_, digest, _, _ := fileDigests(f)
+			writeTestAttestation(t, f, digest)
+		}
+		return nil, nil
+	}}
+}
+
+func TestAttestDistFiles(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz", "mypkg-1.0.0-py3-none-any.whl")
+	sdist, wheel := filepath.Join("dist", "mypkg-1.0.0.tar.gz"), filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl")
+	_, digest, _, _ := fileDigests(sdist)
+	writeTestAttestation(t, sdist, digest)
+
+	executor := attestingExecutor(t)
+	p := &PyPIPlugin{cmdExecutor: executor}
+	cfg := Config{Attestations: true, AttestationCommand: defaultAttestationCommand}
+	attestations, artifacts, err := p.attestDistFiles(context.Background(), cfg, []string{sdist, wheel})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only the file without an attestation is signed
+	if len(executor.RunCalls) != 1 || strings.Join(executor.RunCalls[0].Args, " ") != "-m pypi_attestations sign "+wheel {
+		t.Errorf("unexpected signing commands %+v", executor.RunCalls)
+	}
+	if len(attestations) != 2 || attestations[0].Generated || !attestations[1].Generated || attestations[1].Attestation != "dist/mypkg-1.0.0-py3-none-any.whl.publish.attestation" {
+		t.Errorf("unexpected attestations %+v", attestations)
+	}
+	if len(artifacts) != 2 || artifacts[1].Name != "mypkg-1.0.0-py3-none-any.whl.publish.attestation" {
+		t.Errorf("unexpected artifacts %+v", artifacts)
+	}
+
+	// The attestation files are not distributions
+	if files, _ := expandDistGlob("dist/*"); len(files) != 2 {
+		t.Errorf("expected attestations to be left out of dist_path, got %v", files)
+	}
+}
+
+func TestAttestDistFilesRejectsStaleAttestation(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	sdist := filepath.Join("dist", "mypkg-1.0.0.tar.gz")
+	writeTestAttestation(t, sdist, strings.Repeat("0", 64))
+
+	p := &PyPIPlugin{cmdExecutor: attestingExecutor(t)}
+	cfg := Config{Attestations: true, AttestationCommand: defaultAttestationCommand}
+	if _, _, err := p.attestDistFiles(context.Background(), cfg, []string{sdist}); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected a stale attestation to be rejected, got %v", err)
+	}
+
+	_ = os.Remove(attestationPath(sdist))
+	p.cmdExecutor = &MockCommandExecutor{ReturnOut: []byte("No ambient identity found"), ReturnError: errors.New("exit status 1")}
+	if _, _, err := p.attestDistFiles(context.Background(), cfg, []string{sdist}); err == nil || !strings.Contains(err.Error(), "No ambient identity found") {
+		t.Errorf("expected the signing failure, got %v", err)
+	}
+}
+
+func TestMultipartUploadBodyAttestations(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	sdist := filepath.Join("dist", "mypkg-1.0.0.tar.gz")
+	_, digest, _, _ := fileDigests(sdist)
+	writeTestAttestation(t, sdist, digest)
+
+	body, contentType := multipartUploadBody(distribution{Path: sdist, Name: "mypkg", Version: "1.0.0", AttestationPath: attestationPath(sdist)}, "md5", digest)
+	_, params, _ := mime.ParseMediaType(contentType)
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("no attestations field: %v", err)
+		}
+		if part.FormName() != "attestations" {
+			continue
+		}
+		data, _ := io.ReadAll(part)
+		var attestations []map[string]any
+		if err := json.Unmarshal(data, &attestations); err != nil || len(attestations) != 1 || attestations[0]["version"] != float64(1) {
+			t.Errorf("expected a JSON array with the attestation, got %s", data)
+		}
+		return
+	}
+}
+
+func TestAttestationConfig(t *testing.T) {
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{"attestations": true, "trusted_publishing": true})
+	if !cfg.Attestations || strings.Join(cfg.AttestationCommand, " ") != "python3 -m pypi_attestations sign" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if err := validateAttestationConfig(cfg); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if args := p.buildTwineArgsForFiles(Config{Attestations: true, ExtraArgs: []string{"--attestations"}}, []string{"dist/a.whl"}); strings.Count(strings.Join(args, " "), "--attestations") != 1 {
+		t.Errorf("expected --attestations once, got %v", args)
+	}
+
+	for _, bad := range []Config{
+		{Attestations: true, AttestationCommand: defaultAttestationCommand},
+		{Attestations: true, TrustedPublishing: true, AttestationCommand: defaultAttestationCommand, CustomCommand: []string{"upload"}},
+		{Attestations: true, TrustedPublishing: true},
+	} {
+		if err := validateAttestationConfig(bad); err == nil {
+			t.Errorf("expected %+v to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// defaultAuditTagPrefix is the prefix of release tags compared by the release audit.
+const defaultAuditTagPrefix = "v"
+
+// Pre-release and post-release spellings normalized by PEP 440.
+var (
+	versionSpellingPattern = regexp.MustCompile(`[-_.]?(alpha|beta|preview|pre|c|rc|a|b|post|rev|r|dev)[-_.]?([0-9]*)`)
+	versionSpellings       = map[string]string{
+		"alpha": "a", "beta": "b", "c": "rc", "pre": "rc", "preview": "rc",
+		"rev": "post", "r": "post",
+	}
+)
+
+// auditsReleases reports whether the run audits published versions instead of uploading.
+func auditsReleases(cfg Config) bool {
+	return cfg.ReleaseAudit != "" && cfg.ReleaseAudit != checkOff
+}
+
+// normalizeVersion returns the PEP 440 normal form of common version spellings, so that tag
+// v1.0.0-rc.1 matches the published 1.0.0rc1.
+func normalizeVersion(version string) string {
+	v := strings.ToLower(strings.TrimSpace(version))
+	v = strings.TrimPrefix(v, "v")
+	return versionSpellingPattern.ReplaceAllStringFunc(v, func(m string) string {
+		parts := versionSpellingPattern.FindStringSubmatch(m)
+		label, number := parts[1], parts[2]
+		if spelled, ok := versionSpellings[label]; ok {
+			label = spelled
+		}
+		if number == "" {
+			number = "0"
+		}
+		if label == "post" || label == "dev" {
+			return "." + label + number
+		}
+		return label + number
+	})
+}
+
+// versionFromFilename returns the version of a wheel or sdist file name of project, or "".
+func versionFromFilename(project, filename string) string {
+	if strings.HasSuffix(filename, ".whl") {
+		parts := strings.Split(filename, "-")
+		if len(parts) < 5 {
+			return ""
+		}
+		return parts[1]
+	}
+	for _, ext := range []string{".tar.gz", ".zip", ".tar.bz2", ".tgz"} {
+		if base, ok := strings.CutSuffix(filename, ext); ok {
+			i := strings.LastIndex(base, "-")
+			if i <= 0 || normalizeProjectName(base[:i]) != normalizeProjectName(project) {
+				return ""
+			}
+			return base[i+1:]
+		}
+	}
+	return ""
+}
+
+// releaseTags returns the git tags starting with prefix, keyed by the normalized version they
+// name. Tags whose remainder is not a version are ignored.
+func (p *PyPIPlugin) releaseTags(ctx context.Context, prefix string) (map[string]string, error) {
+	output, err := p.getExecutor().Run(ctx, "git", "tag", "--list", prefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list git tags: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	tags := map[string]string{}
+	for _, tag := range strings.Fields(string(output)) {
+		version := strings.TrimPrefix(tag, prefix)
+		if version == "" || version[0] < '0' || version[0] > '9' {
+			continue
+		}
+		tags[normalizeVersion(version)] = tag
+	}
+	return tags, nil
+}
+
+// auditReleases compares the release tags of the repository with the versions published on the
+// index and reports drift: tags that were never published and published versions without a tag.
+func (p *PyPIPlugin) auditReleases(ctx context.Context, cfg Config) *plugin.ExecuteResponse {
+	project := cfg.AuditProject
+	if project == "" {
+		files, _ := expandDistGlob(cfg.DistPath)
+		for _, f := range files {
+			if meta, err := readDistMetadata(f); err == nil {
+				project = meta.Name
+				break
+			}
+		}
+	}
+	if project == "" {
+		return &plugin.ExecuteResponse{Success: false, Error: "release audit requires audit_project or a distribution with readable metadata"}
+	}
+
+	tags, err := p.releaseTags(ctx, cfg.AuditTagPrefix)
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("release audit failed: %v", err)}
+	}
+	files, err := p.indexFiles(ctx, cfg.IndexURL, project)
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("release audit failed: %v", err)}
+	}
+	published := map[string]string{}
+	for name := range files {
+		if version := versionFromFilename(project, name); version != "" {
+			published[normalizeVersion(version)] = version
+		}
+	}
+
+	unpublished := []string{}
+	for version, tag := range tags {
+		if _, ok := published[version]; !ok {
+			unpublished = append(unpublished, tag)
+		}
+	}
+	untagged := []string{}
+	for normalized, version := range published {
+		if _, ok := tags[normalized]; !ok {
+			untagged = append(untagged, version)
+		}
+	}
+	sort.Strings(unpublished)
+	sort.Strings(untagged)
+
+	outputs := map[string]any{
+		"project":            project,
+		"index_url":          cfg.IndexURL,
+		"tags":               len(tags),
+		"published_versions": len(published),
+		"unpublished_tags":   unpublished,
+		"untagged_versions":  untagged,
+		"plugin_build":       currentBuild().String(),
+	}
+	if len(unpublished) == 0 && len(untagged) == 0 {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("All %d release tags of %s are published on the index", len(tags), project),
+			Outputs: outputs,
+		}
+	}
+
+	var drift []string
+	if len(unpublished) > 0 {
+		drift = append(drift, fmt.Sprintf("%d tag(s) never published: %s", len(unpublished), strings.Join(unpublished, ", ")))
+	}
+	if len(untagged) > 0 {
+		drift = append(drift, fmt.Sprintf("%d published version(s) without a tag: %s", len(untagged), strings.Join(untagged, ", ")))
+	}
+	msg := fmt.Sprintf("release drift for %s: %s", project, strings.Join(drift, "; "))
+	if cfg.ReleaseAudit == checkFail {
+		return &plugin.ExecuteResponse{Success: false, Error: msg, Outputs: outputs}
+	}
+	outputs["warnings"] = []string{msg}
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Audited %d release tags of %s with drift", len(tags), project),
+		Outputs: outputs,
+	}
+}
+
+// validateAuditConfig validates the release audit options.
+func validateAuditConfig(cfg Config) error {
+	if !auditsReleases(cfg) {
+		return nil
+	}
+	if !containsString(checkModes, cfg.ReleaseAudit) {
+		return fmt.Errorf("release_audit must be one of: %s", strings.Join(checkModes, ", "))
+	}
+	if cfg.IndexURL == "" {
+		return fmt.Errorf("release_audit requires index_url for repositories other than PyPI and TestPyPI")
+	}
+	if strings.ContainsAny(cfg.AuditTagPrefix, "*?[\\ ") {
+		return fmt.Errorf("audit_tag_prefix must not contain wildcards or spaces")
+	}
+	return nil
+}
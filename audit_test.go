@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestNormalizeVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.0.0":         "1.0.0",
+		"v1.0.0":        "1.0.0",
+		"1.0.0-rc.1":    "1.0.0rc1",
+		"1.0.0RC1":      "1.0.0rc1",
+		"1.0.0-alpha.2": "1.0.0a2",
+		"1.0.0-beta":    "1.0.0b0",
+		"1.0.0.post1":   "1.0.0.post1",
+		"1.0.0-r2":      "1.0.0.post2",
+		"1.0.0-dev.3":   "1.0.0.dev3",
+		"2.0c1":         "2.0rc1",
+	}
+	for version, want := range tests {
+		if got := normalizeVersion(version); got != want {
+			t.Errorf("normalizeVersion(%q) = %q, want %q", version, got, want)
+		}
+	}
+}
+
+func TestVersionFromFilename(t *testing.T) {
+	tests := map[string]string{
+		"my_pkg-1.0.0-py3-none-any.whl":           "1.0.0",
+		"my_pkg-1.0.0rc1-1-cp312-cp312-linux.whl": "1.0.0rc1",
+		"my-pkg-1.0.0.tar.gz":                     "1.0.0",
+		"my_pkg-2.0.zip":                          "2.0",
+		"other-1.0.0.tar.gz":                      "",
+		"my_pkg-1.0.0.exe":                        "",
+		"my_pkg.whl":                              "",
+	}
+	for filename, want := range tests {
+		if got := versionFromFilename("my-pkg", filename); got != want {
+			t.Errorf("versionFromFilename(%q) = %q, want %q", filename, got, want)
+		}
+	}
+}
+
+func TestExecuteReleaseAudit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/simple/mypkg/" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<a href="mypkg-1.0.0.tar.gz">mypkg-1.0.0.tar.gz</a>
+<a href="mypkg-1.0.0-py3-none-any.whl">mypkg-1.0.0-py3-none-any.whl</a>
+<a href="mypkg-1.1.0rc1.tar.gz">mypkg-1.1.0rc1.tar.gz</a>
+<a href="mypkg-0.9.0.tar.gz">mypkg-0.9.0.tar.gz</a>`))
+	}))
+	defer server.Close()
+
+	var gitArgs []string
+	p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name != "git" {
+				t.Errorf("unexpected command %s %v", name, args)
+			}
+			gitArgs = args
+			return []byte("v1.0.0\nv1.1.0-rc.1\nv1.2.0\nvnext\n"), nil
+		},
+	}}
+	config := map[string]any{
+		"repository":    "http://localhost:8080/",
+		"index_url":     server.URL + "/simple/",
+		"release_audit": "warn",
+		"audit_project": "mypkg",
+	}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the audit to report drift as a warning, got %v %+v", err, resp)
+	}
+	if !reflect.DeepEqual(gitArgs, []string{"tag", "--list", "v*"}) {
+		t.Errorf("unexpected git arguments %v", gitArgs)
+	}
+	if got := resp.Outputs["unpublished_tags"]; !reflect.DeepEqual(got, []string{"v1.2.0"}) {
+		t.Errorf("unpublished_tags = %v", got)
+	}
+	if got := resp.Outputs["untagged_versions"]; !reflect.DeepEqual(got, []string{"0.9.0"}) {
+		t.Errorf("untagged_versions = %v", got)
+	}
+	if warnings, _ := resp.Outputs["warnings"].([]string); len(warnings) != 1 || !strings.Contains(warnings[0], "v1.2.0") {
+		t.Errorf("unexpected warnings %v", resp.Outputs["warnings"])
+	}
+
+	config["release_audit"] = "fail"
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "1 tag(s) never published: v1.2.0") {
+		t.Fatalf("expected the audit to fail on drift, got %v %+v", err, resp)
+	}
+}
+
+func TestValidateAuditConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"off", Config{ReleaseAudit: checkOff}, false},
+		{"audit", Config{ReleaseAudit: checkWarn, IndexURL: "https://pypi.org/simple/", AuditTagPrefix: "v"}, false},
+		{"without index", Config{ReleaseAudit: checkFail, AuditTagPrefix: "v"}, true},
+		{"wildcard prefix", Config{ReleaseAudit: checkWarn, IndexURL: "https://pypi.org/simple/", AuditTagPrefix: "release-*"}, true},
+		{"invalid mode", Config{ReleaseAudit: "strict"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateAuditConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateAuditConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
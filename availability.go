@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Availability wait defaults.
+const (
+	defaultAvailabilityTimeout      = 5 * time.Minute
+	defaultAvailabilityPollInterval = 5 * time.Second
+)
+
+// availabilityReport is the result of waiting for the uploaded files on the index, reported in
+// outputs.
+type availabilityReport struct {
+	IndexURL string   `json:"index_url"`
+	Project  string   `json:"project"`
+	Files    []string `json:"files"`
+	// WaitedMs is how long the files took to be listed after the upload
+	WaitedMs int64 `json:"waited_ms"`
+}
+
+// awaitAvailability polls the index until it lists every uploaded file, so that release steps
+// installing the new version do not race the CDN of the index.
+func (p *PyPIPlugin) awaitAvailability(ctx context.Context, cfg Config, files []string) (*availabilityReport, error) {
+	report := &availabilityReport{IndexURL: cfg.IndexURL, Files: make([]string, 0, len(files))}
+	for _, f := range files {
+		report.Files = append(report.Files, filepath.Base(f))
+		if report.Project == "" {
+			if meta, err := readDistMetadata(f); err == nil {
+				report.Project = meta.Name
+			}
+		}
+	}
+	if report.Project == "" {
+		return report, fmt.Errorf("the project name could not be read from the distribution metadata")
+	}
+
+	start := time.Now()
+	err := p.waitForIndexFiles(ctx, cfg.IndexURL, report.Project, report.Files, cfg.AvailabilityTimeout, cfg.AvailabilityPollInterval)
+	report.WaitedMs = time.Since(start).Milliseconds()
+	return report, err
+}
+
+// validateAvailabilityConfig validates the wait_for_availability options.
+func validateAvailabilityConfig(cfg Config) error {
+	if !cfg.WaitForAvailability {
+		return nil
+	}
+	if cfg.IndexURL == "" {
+		return fmt.Errorf("wait_for_availability needs index_url for repositories other than PyPI and TestPyPI")
+	}
+	if cfg.AvailabilityTimeout <= 0 {
+		return fmt.Errorf("availability_timeout must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestExecuteWaitsForAvailability(t *testing.T) {
+	writeReleaseDists(t)
+	var polls, listAfter atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/simple/mypkg/" {
+			http.NotFound(w, r)
+			return
+		}
+		// The CDN serves the page without the new release for the first polls
+		if polls.Add(1) <= listAfter.Load() {
+			http.NotFound(w, r)
+			return
+		}
+		for _, f := range []string{"mypkg-1.0.0-py3-none-any.whl", "mypkg-1.0.0.tar.gz"} {
+			_, _ = fmt.Fprintf(w, `<a href="/files/%s">%s</a>`, f, f)
+		}
+	}))
+	defer server.Close()
+
+	execute := func(timeout string) *plugin.ExecuteResponse {
+		t.Helper()
+		p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{ReturnOut: []byte("ok")}}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"username":                   "__token__",
+				"password":                   "pypi-token",
+				"repository":                 "http://localhost:8080/",
+				"index_url":                  server.URL + "/simple/",
+				"wait_for_availability":      true,
+				"availability_timeout":       timeout,
+				"availability_poll_interval": "10ms",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	listAfter.Store(2)
+	resp := execute("5s")
+	if !resp.Success {
+		t.Fatalf("expected success, got %+v", resp)
+	}
+	report, _ := resp.Outputs["availability"].(*availabilityReport)
+	if report == nil || report.Project != "mypkg" || len(report.Files) != 2 || polls.Load() != 3 {
+		t.Errorf("unexpected availability %+v after %d polls", report, polls.Load())
+	}
+
+	polls.Store(0)
+	listAfter.Store(1000)
+	resp = execute("50ms")
+	if resp.Success || !strings.Contains(resp.Error, "uploaded, but the release is not available yet") {
+		t.Errorf("expected the wait to time out, got %+v", resp)
+	}
+}
+
+func TestValidateAvailabilityConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"disabled", Config{}, false},
+		{"enabled", Config{WaitForAvailability: true, IndexURL: "https://pypi.org/simple/", AvailabilityTimeout: defaultAvailabilityTimeout}, false},
+		{"no index", Config{WaitForAvailability: true, AvailabilityTimeout: defaultAvailabilityTimeout}, true},
+		{"zero timeout", Config{WaitForAvailability: true, IndexURL: "https://pypi.org/simple/"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateAvailabilityConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateAvailabilityConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
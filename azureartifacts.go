@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Authentication methods of an azure_artifacts block.
+const (
+	azureArtifactsAuthPAT       = "pat"
+	azureArtifactsAuthFederated = "federated"
+)
+
+const (
+	// azureArtifactsUsername is sent with the token; Azure Artifacts ignores the username but
+	// twine requires one.
+	azureArtifactsUsername = "azure"
+	// defaultAzureArtifactsPATEnv holds the personal access token of PAT authentication.
+	defaultAzureArtifactsPATEnv = "AZURE_DEVOPS_PAT"
+	// azureDevOpsScope is the Entra ID scope of Azure DevOps, which federated credentials request.
+	azureDevOpsScope = "499b84ac-1321-427f-aa17-267ca6975798/.default"
+	// azureArtifactsHost serves the package feeds of Azure DevOps organizations.
+	azureArtifactsHost = "pkgs.dev.azure.com"
+)
+
+// azureArtifactsAuthMethods lists the accepted values of azure_artifacts.auth.
+var azureArtifactsAuthMethods = []string{azureArtifactsAuthPAT, azureArtifactsAuthFederated}
+
+// AzureArtifacts identifies an Azure Artifacts Python feed. Its upload URL is derived from the
+// organization, project and feed, and uploads authenticate with a personal access token or
+// with an Entra ID token obtained from federated credentials.
+type AzureArtifacts struct {
+	// Organization is the Azure DevOps organization owning the feed
+	Organization string
+	// Project is the project of a project-scoped feed (empty for organization-scoped feeds)
+	Project string
+	// Feed is the feed name
+	Feed string
+	// Auth is pat or federated (default pat)
+	Auth string
+	// PATEnv is the environment variable holding the personal access token (default AZURE_DEVOPS_PAT)
+	PATEnv string
+}
+
+// parseAzureArtifacts parses the azure_artifacts block, or returns nil when it is absent.
+func parseAzureArtifacts(raw any) *AzureArtifacts {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil
+	}
+	a := &AzureArtifacts{Auth: azureArtifactsAuthPAT, PATEnv: defaultAzureArtifactsPATEnv}
+	a.Organization, _ = m["organization"].(string)
+	a.Project, _ = m["project"].(string)
+	a.Feed, _ = m["feed"].(string)
+	if v, ok := m["auth"].(string); ok && v != "" {
+		a.Auth = v
+	}
+	if v, ok := m["pat_env"].(string); ok && v != "" {
+		a.PATEnv = v
+	}
+	return a
+}
+
+// feedURL returns the base URL of the feed's PyPI endpoints.
+func (a *AzureArtifacts) feedURL() string {
+	base := "https://" + azureArtifactsHost + "/" + url.PathEscape(a.Organization) + "/"
+	if a.Project != "" {
+		base += url.PathEscape(a.Project) + "/"
+	}
+	return base + "_packaging/" + url.PathEscape(a.Feed) + "/pypi/"
+}
+
+// repositoryURL returns the upload endpoint of the feed.
+func (a *AzureArtifacts) repositoryURL() string {
+	return a.feedURL() + "upload/"
+}
+
+// azureDevOpsTokenSource obtains Entra ID access tokens of Azure DevOps for federated
+// azure_artifacts uploads.
+type azureDevOpsTokenSource struct {
+	p   *PyPIPlugin
+	cfg Config
+	now func() time.Time
+}
+
+// fetch requests an access token with the credentials of the AZURE_CLIENT_ID application.
+func (s *azureDevOpsTokenSource) fetch(ctx context.Context) (credential, error) {
+	token, lifetime, err := s.p.azureAccessToken(ctx, s.cfg, azureDevOpsScope)
+	if err != nil {
+		return credential{}, err
+	}
+	cred := credential{Username: azureArtifactsUsername, Password: token}
+	if lifetime > 0 {
+		cred.ExpiresAt = s.now().Add(lifetime)
+	}
+	return cred, nil
+}
+
+// usesAzureArtifactsFederation reports whether uploads authenticate to Azure Artifacts with
+// federated credentials.
+func usesAzureArtifactsFederation(cfg Config) bool {
+	return cfg.AzureArtifacts != nil && cfg.AzureArtifacts.Auth == azureArtifactsAuthFederated
+}
+
+// validateAzureArtifactsConfig validates the azure_artifacts block.
+func validateAzureArtifactsConfig(cfg Config) error {
+	a := cfg.AzureArtifacts
+	if a == nil {
+		return nil
+	}
+	switch {
+	case a.Organization == "" || a.Feed == "":
+		return fmt.Errorf("azure_artifacts requires organization and feed")
+	case !containsString(azureArtifactsAuthMethods, a.Auth):
+		return fmt.Errorf("azure_artifacts.auth must be one of: %s", strings.Join(azureArtifactsAuthMethods, ", "))
+	case len(cfg.TokenCommand) > 0 || cfg.Token != "" || cfg.TrustedPublishing || cfg.DeviceAuth:
+		return fmt.Errorf("azure_artifacts cannot be combined with token, token_command, trusted_publishing or device_auth")
+	case cfg.CodeArtifact != nil || cfg.GCPArtifactRegistry:
+		return fmt.Errorf("azure_artifacts cannot be combined with codeartifact or gcp_artifact_registry")
+	case len(cfg.CredentialOverrides) > 0 || len(cfg.CustomCommand) > 0:
+		return fmt.Errorf("azure_artifacts cannot be combined with credential_overrides or custom_command")
+	case cfg.SpiffeWorkloadAPI || !usesBasicAuth(cfg):
+		return fmt.Errorf("azure_artifacts uploads with basic auth and cannot be combined with auth_scheme or spiffe_workload_api")
+	}
+	if a.Auth == azureArtifactsAuthFederated {
+		if os.Getenv("AZURE_TENANT_ID") == "" || os.Getenv("AZURE_CLIENT_ID") == "" {
+			return fmt.Errorf("azure_artifacts with federated auth requires AZURE_TENANT_ID and AZURE_CLIENT_ID")
+		}
+		return nil
+	}
+	if cfg.Password == "" {
+		return fmt.Errorf("azure_artifacts requires a personal access token in %s (or password)", a.PATEnv)
+	}
+	return nil
+}
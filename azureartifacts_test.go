@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseAzureArtifacts(t *testing.T) {
+	t.Setenv("AZURE_DEVOPS_PAT", "pat-123")
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"username":        "ignored",
+		"azure_artifacts": map[string]any{"organization": "acme", "project": "Platform Team", "feed": "python-internal"},
+	})
+	if cfg.AzureArtifacts == nil || cfg.AzureArtifacts.Auth != azureArtifactsAuthPAT {
+		t.Fatalf("unexpected azure_artifacts %+v", cfg.AzureArtifacts)
+	}
+	if want := "https://pkgs.dev.azure.com/acme/Platform%20Team/_packaging/python-internal/pypi/upload/"; cfg.Repository != want {
+		t.Errorf("repository = %s, want %s", cfg.Repository, want)
+	}
+	if cfg.Username != azureArtifactsUsername || cfg.Password != "pat-123" {
+		t.Errorf("expected the PAT to be the password, got %s/%s", cfg.Username, cfg.Password)
+	}
+
+	// Organization-scoped feeds have no project, and pat_env names another variable
+	t.Setenv("SYSTEM_ACCESSTOKEN", "pipeline-token")
+	cfg = p.parseConfig(map[string]any{
+		"azure_artifacts": map[string]any{"organization": "acme", "feed": "python", "pat_env": "SYSTEM_ACCESSTOKEN"},
+	})
+	if cfg.Repository != "https://pkgs.dev.azure.com/acme/_packaging/python/pypi/upload/" || cfg.Password != "pipeline-token" {
+		t.Errorf("unexpected organization feed %s %s", cfg.Repository, cfg.Password)
+	}
+
+	cfg = p.parseConfig(map[string]any{
+		"password":        "configured",
+		"repository":      "https://pkgs.example.com/acme/_packaging/python/pypi/upload/",
+		"azure_artifacts": map[string]any{"organization": "acme", "feed": "python", "auth": "federated"},
+	})
+	if !strings.HasPrefix(cfg.Repository, "https://pkgs.example.com/") || cfg.Password != "configured" {
+		t.Errorf("expected federated auth to leave the repository and password alone, got %s %s", cfg.Repository, cfg.Password)
+	}
+	if cfg := p.parseConfig(map[string]any{}); cfg.AzureArtifacts != nil {
+		t.Errorf("expected no azure_artifacts without the block, got %+v", cfg.AzureArtifacts)
+	}
+}
+
+func TestRunTwineUploadsWithAzureArtifactsFederation(t *testing.T) {
+	distPath := writeDistFiles(t, "pkg-1.0.0.tar.gz", "pkg-1.0.0-py3-none-any.whl")
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("federated-jwt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens expire within the refresh margin, so each file gets a new one
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tenant/oauth2/v2.0/token" || r.FormValue("scope") != azureDevOpsScope ||
+			r.FormValue("client_id") != "client" || r.FormValue("client_assertion") != "federated-jwt" {
+			http.Error(w, `{"error": "invalid_client", "error_description": "AADSTS70021: No matching federated identity record found."}`, http.StatusBadRequest)
+			return
+		}
+		fetches++
+		_, _ = fmt.Fprintf(w, `{"access_token": "entra-token-%d", "expires_in": 60}`, fetches)
+	}))
+	defer server.Close()
+	t.Setenv("AZURE_AUTHORITY_HOST", server.URL)
+	t.Setenv("AZURE_TENANT_ID", "tenant")
+	t.Setenv("AZURE_CLIENT_ID", "client")
+	t.Setenv("AZURE_CLIENT_SECRET", "")
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", tokenFile)
+
+	mockExecutor := &MockCommandExecutor{ReturnOut: []byte("Uploading\n")}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor}
+	cfg := p.parseConfig(map[string]any{
+		"dist_path":       distPath,
+		"azure_artifacts": map[string]any{"organization": "acme", "feed": "python", "auth": "federated"},
+	})
+	if err := validateAzureArtifactsConfig(cfg); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	run, err := p.runTwineUploads(context.Background(), cfg, mockExecutor, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetches != 2 || run.tokenRefreshes != 1 {
+		t.Errorf("expected the token to be refreshed before the second file, got %d fetches and %d refreshes", fetches, run.tokenRefreshes)
+	}
+	var envs []string
+	for _, call := range mockExecutor.RunCalls {
+		envs = append(envs, strings.Join(call.Env, " "))
+	}
+	if want := "TWINE_USERNAME=azure TWINE_PASSWORD=entra-token-1,TWINE_USERNAME=azure TWINE_PASSWORD=entra-token-2"; strings.Join(envs, ",") != want {
+		t.Errorf("unexpected twine credentials %v", envs)
+	}
+
+	t.Setenv("AZURE_CLIENT_ID", "other")
+	if _, err := p.runTwineUploads(context.Background(), cfg, mockExecutor, nil); err == nil || !strings.Contains(err.Error(), "No matching federated identity record found") {
+		t.Errorf("expected the Entra ID error, got %v", err)
+	}
+}
+
+func TestValidateAzureArtifactsConfig(t *testing.T) {
+	t.Setenv("AZURE_TENANT_ID", "tenant")
+	t.Setenv("AZURE_CLIENT_ID", "client")
+	valid := Config{
+		Repository:     "https://pkgs.dev.azure.com/acme/_packaging/python/pypi/upload/",
+		Username:       azureArtifactsUsername,
+		Password:       "pat-123",
+		AzureArtifacts: &AzureArtifacts{Organization: "acme", Feed: "python", Auth: azureArtifactsAuthPAT, PATEnv: defaultAzureArtifactsPATEnv},
+	}
+	if err := validateAzureArtifactsConfig(valid); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := validateAzureArtifactsConfig(Config{}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	federated := valid
+	federated.Password = ""
+	federated.AzureArtifacts = &AzureArtifacts{Organization: "acme", Feed: "python", Auth: azureArtifactsAuthFederated}
+	if err := validateAzureArtifactsConfig(federated); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	for _, modify := range []func(*Config){
+		func(c *Config) { c.AzureArtifacts.Feed = "" },
+		func(c *Config) { c.AzureArtifacts.Auth = "oauth" },
+		func(c *Config) { c.Password = "" },
+		func(c *Config) { c.Token = "pypi-token" },
+		func(c *Config) { c.GCPArtifactRegistry = true },
+		func(c *Config) { c.AuthScheme = authSchemeSigV4 },
+	} {
+		cfg := valid
+		settings := *valid.AzureArtifacts
+		cfg.AzureArtifacts = &settings
+		modify(&cfg)
+		if err := validateAzureArtifactsConfig(cfg); err == nil {
+			t.Errorf("expected an error for %+v %+v", cfg, cfg.AzureArtifacts)
+		}
+	}
+	t.Setenv("AZURE_CLIENT_ID", "")
+	if err := validateAzureArtifactsConfig(federated); err == nil || !strings.Contains(err.Error(), "AZURE_TENANT_ID and AZURE_CLIENT_ID") {
+		t.Errorf("expected federated auth without an application to be rejected, got %v", err)
+	}
+}
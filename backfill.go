@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// checkBackfill guards the publish of a historical version: every distribution must carry the
+// backfilled version, and the index must not have any file of that version yet.
+func (p *PyPIPlugin) checkBackfill(ctx context.Context, cfg Config, result *preflightResult) *plugin.ExecuteResponse {
+	fail := func(format string, args ...any) *plugin.ExecuteResponse {
+		return &plugin.ExecuteResponse{Success: false, Error: "backfill refused: " + fmt.Sprintf(format, args...)}
+	}
+	if len(result.files) == 0 {
+		return fail("no distributions in %s", cfg.BackfillDistPath)
+	}
+
+	project := ""
+	for _, f := range result.files {
+		meta, err := readDistMetadata(f)
+		if err != nil {
+			return fail("cannot read the version of %s: %v", f, err)
+		}
+		if normalizeVersion(meta.Version) != normalizeVersion(cfg.BackfillVersion) {
+			return fail("%s is version %s, not %s", f, meta.Version, cfg.BackfillVersion)
+		}
+		if project == "" {
+			project = meta.Name
+		} else if normalizeProjectName(meta.Name) != normalizeProjectName(project) {
+			return fail("%s belongs to %s, not %s", f, meta.Name, project)
+		}
+	}
+	result.outputs["backfill"] = map[string]any{"version": cfg.BackfillVersion, "project": project, "dist_path": cfg.BackfillDistPath}
+
+	if cfg.IndexURL == "" {
+		result.warn("backfill of %s %s not checked against the index: index_url is not set", project, cfg.BackfillVersion)
+		return nil
+	}
+	files, err := p.indexFiles(ctx, cfg.IndexURL, project)
+	if err != nil {
+		return fail("cannot check whether %s %s is already published: %v", project, cfg.BackfillVersion, err)
+	}
+	var published []string
+	for name := range files {
+		if version := versionFromFilename(project, name); version != "" && normalizeVersion(version) == normalizeVersion(cfg.BackfillVersion) {
+			published = append(published, name)
+		}
+	}
+	if len(published) > 0 {
+		sort.Strings(published)
+		return fail("%s %s is already published (%s)", project, cfg.BackfillVersion, strings.Join(published, ", "))
+	}
+	return nil
+}
+
+// validateBackfillConfig validates the backfill options. A backfill publishes outside the
+// release flow, so it must be allowed explicitly and confirmed by repeating the version.
+func validateBackfillConfig(cfg Config) error {
+	if cfg.BackfillVersion == "" {
+		if cfg.BackfillDistPath != "" || cfg.BackfillConfirm != "" {
+			return fmt.Errorf("backfill_dist_path and backfill_confirm require backfill_version")
+		}
+		return nil
+	}
+	if !cfg.AllowBackfill {
+		return fmt.Errorf("backfill_version requires allow_backfill: true")
+	}
+	if cfg.BackfillConfirm != cfg.BackfillVersion {
+		return fmt.Errorf("backfill_confirm must repeat backfill_version %q", cfg.BackfillVersion)
+	}
+	if cfg.BackfillDistPath == "" {
+		return fmt.Errorf("backfill_version requires backfill_dist_path")
+	}
+	if err := validateDistPath(cfg.BackfillDistPath); err != nil {
+		return fmt.Errorf("invalid backfill_dist_path: %w", err)
+	}
+	if strings.Contains(cfg.BackfillDistPath, "*") {
+		return fmt.Errorf("backfill_dist_path must be a directory, not a glob")
+	}
+	if cfg.Benchmark || cfg.NexusStagingReleaseTag != "" || auditsReleases(cfg) || cfg.ResumeQueued {
+		return fmt.Errorf("backfill_version cannot be combined with benchmark, nexus_staging_release_tag, release_audit or resume_queued")
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func newBackfillTest(t *testing.T, index string) (*PyPIPlugin, map[string]any, *[][]string) {
+	t.Helper()
+	writeDistFiles(t)
+	if err := os.MkdirAll(filepath.Join("backfill", "0.9.0"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	writeTestWheel(t, filepath.Join("backfill", "0.9.0", "mypkg-0.9.0-py3-none-any.whl"), map[string]string{
+		"mypkg-0.9.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 0.9.0\n",
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(index))
+	}))
+	t.Cleanup(server.Close)
+
+	calls := &[][]string{}
+	p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			*calls = append(*calls, args)
+			return nil, nil
+		},
+	}}
+	config := map[string]any{
+		"username":           "__token__",
+		"password":           "pypi-token",
+		"repository":         "http://localhost:8080/",
+		"index_url":          server.URL + "/simple/",
+		"allow_backfill":     true,
+		"backfill_version":   "0.9.0",
+		"backfill_confirm":   "0.9.0",
+		"backfill_dist_path": "backfill/0.9.0",
+	}
+	return p, config, calls
+}
+
+func TestExecuteBackfill(t *testing.T) {
+	p, config, calls := newBackfillTest(t, `<a href="mypkg-1.0.0.tar.gz">mypkg-1.0.0.tar.gz</a>`)
+	releaseCtx := plugin.ReleaseContext{Version: "1.1.0"}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config, Context: releaseCtx})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the backfill to succeed, got %v %+v", err, resp)
+	}
+	if resp.Message != "Backfilled version 0.9.0 to http://localhost:8080/" || resp.Outputs["version"] != "0.9.0" {
+		t.Errorf("unexpected response %q %v", resp.Message, resp.Outputs["version"])
+	}
+	if len(*calls) != 1 || !strings.Contains(strings.Join((*calls)[0], " "), "backfill/0.9.0/mypkg-0.9.0-py3-none-any.whl") {
+		t.Errorf("expected the backfill artifacts to be uploaded, got %v", *calls)
+	}
+}
+
+func TestExecuteBackfillRefused(t *testing.T) {
+	tests := []struct {
+		name   string
+		index  string
+		modify func(config map[string]any)
+		want   string
+	}{
+		{"already published", `<a href="mypkg-0.9.0.tar.gz">mypkg-0.9.0.tar.gz</a>`, nil, "mypkg 0.9.0 is already published (mypkg-0.9.0.tar.gz)"},
+		{"version mismatch", "", func(config map[string]any) {
+			config["backfill_version"], config["backfill_confirm"] = "0.8.0", "0.8.0"
+		}, "is version 0.9.0, not 0.8.0"},
+		{"not allowed", "", func(config map[string]any) { delete(config, "allow_backfill") }, "requires allow_backfill"},
+		{"not confirmed", "", func(config map[string]any) { config["backfill_confirm"] = "0.9" }, "backfill_confirm must repeat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, config, calls := newBackfillTest(t, tt.index)
+			if tt.modify != nil {
+				tt.modify(config)
+			}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+			if err != nil || resp.Success || !strings.Contains(resp.Error, tt.want) {
+				t.Fatalf("expected %q, got %v %+v", tt.want, err, resp)
+			}
+			if len(*calls) != 0 {
+				t.Errorf("expected no upload, got %v", *calls)
+			}
+		})
+	}
+}
+
+func TestValidateBackfillConfig(t *testing.T) {
+	valid := Config{AllowBackfill: true, BackfillVersion: "0.9.0", BackfillConfirm: "0.9.0", BackfillDistPath: "backfill/0.9.0"}
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr bool
+	}{
+		{"valid", func(cfg *Config) {}, false},
+		{"unset", func(cfg *Config) { *cfg = Config{} }, false},
+		{"options without version", func(cfg *Config) { *cfg = Config{BackfillDistPath: "backfill"} }, true},
+		{"missing directory", func(cfg *Config) { cfg.BackfillDistPath = "" }, true},
+		{"glob", func(cfg *Config) { cfg.BackfillDistPath = "backfill/*" }, true},
+		{"absolute directory", func(cfg *Config) { cfg.BackfillDistPath = "/tmp/backfill" }, true},
+		{"with benchmark", func(cfg *Config) { cfg.Benchmark = true }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			if err := validateBackfillConfig(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateBackfillConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Batch mode limits.
+const (
+	defaultBatchConcurrency = 4
+	maxBatchConcurrency     = 32
+)
+
+// batchOnlyKeys are top-level options that configure the batch itself and are not inherited
+// by the package configs.
+var batchOnlyKeys = []string{"packages", "repositories", "batch_concurrency", "mirror_consistency_check"}
+
+// batchPackageKeys are per-package options that describe the package within the batch and
+// are not part of its publish config.
+var batchPackageKeys = []string{"name", "priority", "depends_on"}
+
+// batchPackage is one package of a release train.
+type batchPackage struct {
+	Name   string
+	Config map[string]any
+	// Priority orders packages that are ready at the same time; higher goes first
+	Priority int
+	// DependsOn names packages that must be published and available on the index first
+	DependsOn []string
+}
+
+// batchPackageResult is the outcome of publishing one package in batch mode.
+type batchPackageResult struct {
+	Name       string         `json:"name"`
+	Success    bool           `json:"success"`
+	Message    string         `json:"message,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	StartedAt  string         `json:"started_at,omitempty"`
+	FinishedAt string         `json:"finished_at,omitempty"`
+	DurationMs int64          `json:"duration_ms"`
+	Outputs    map[string]any `json:"outputs,omitempty"`
+}
+
+// finish records that publishing the package started at start and finished now.
+func (r *batchPackageResult) finish(start time.Time) {
+	end := time.Now()
+	r.StartedAt = formatTimestamp(start)
+	r.FinishedAt = formatTimestamp(end)
+	r.DurationMs = end.Sub(start).Milliseconds()
+}
+
+// isBatchConfig reports whether the config describes a release train.
+func isBatchConfig(raw map[string]any) bool {
+	_, ok := raw["packages"]
+	return ok
+}
+
+// batchPackages returns the package configs of a batch, each merged over the shared
+// top-level options. Package names default to the package's dist path.
+func batchPackages(raw map[string]any) ([]batchPackage, error) {
+	if isFanOutConfig(raw) {
+		return nil, fmt.Errorf("packages cannot be combined with repositories; set the repository of each package instead")
+	}
+	return mergeBatchItems(raw, "packages", "dist_path", nil)
+}
+
+// mergeBatchItems merges each object of the key list over the top-level options, except those
+// in private, which every item must set itself. Item names default to their nameKey option.
+func mergeBatchItems(raw map[string]any, key, nameKey string, private []string) ([]batchPackage, error) {
+	items, ok := raw[key].([]any)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("%s must be a non-empty list of configs", key)
+	}
+
+	shared := make(map[string]any, len(raw))
+	for k, v := range raw {
+		shared[k] = v
+	}
+	for _, k := range append(batchOnlyKeys, private...) {
+		delete(shared, k)
+	}
+
+	packages := make([]batchPackage, 0, len(items))
+	seen := map[string]bool{}
+	for i, item := range items {
+		overrides, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] must be an object", key, i)
+		}
+		if isBatchConfig(overrides) || isFanOutConfig(overrides) {
+			return nil, fmt.Errorf("%s[%d]: packages and repositories cannot be nested", key, i)
+		}
+
+		merged := make(map[string]any, len(shared)+len(overrides))
+		for k, v := range shared {
+			merged[k] = v
+		}
+		for k, v := range overrides {
+			if !containsString(batchPackageKeys, k) {
+				merged[k] = v
+			}
+		}
+
+		name, _ := overrides["name"].(string)
+		if name == "" {
+			name, _ = merged[nameKey].(string)
+		}
+		if name == "" {
+			name = fmt.Sprintf("%s[%d]", key, i)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s[%d]: duplicate name %q", key, i, name)
+		}
+		seen[name] = true
+
+		parser := helpers.NewConfigParser(overrides)
+		packages = append(packages, batchPackage{
+			Name:      name,
+			Config:    merged,
+			Priority:  parser.GetInt("priority", 0),
+			DependsOn: parser.GetStringSlice("depends_on", nil),
+		})
+	}
+
+	if err := validateBatchGraph(packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// validateBatchGraph checks that dependencies name packages of the batch and form no cycle.
+func validateBatchGraph(packages []batchPackage) error {
+	index := make(map[string]int, len(packages))
+	for i, pkg := range packages {
+		index[pkg.Name] = i
+	}
+	for _, pkg := range packages {
+		for _, dep := range pkg.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return fmt.Errorf("package %q depends on unknown package %q", pkg.Name, dep)
+			}
+		}
+	}
+
+	// Depth-first search for back edges
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(packages))
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, packages[i].Name), " -> "))
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		for _, dep := range packages[i].DependsOn {
+			if err := visit(index[dep], append(path, packages[i].Name)); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		return nil
+	}
+	for i := range packages {
+		if err := visit(i, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchConcurrency returns the configured number of packages published at once.
+func batchConcurrency(raw map[string]any) (int, error) {
+	n := helpers.NewConfigParser(raw).GetInt("batch_concurrency", defaultBatchConcurrency)
+	if n < 1 || n > maxBatchConcurrency {
+		return 0, fmt.Errorf("batch_concurrency must be between 1 and %d", maxBatchConcurrency)
+	}
+	return n, nil
+}
+
+// runBatch publishes every package of a release train, or the release to every repository of a
+// fan-out, with bounded concurrency and aggregates the results. A failing package does not stop
+// the others.
+func (p *PyPIPlugin) runBatch(ctx context.Context, req plugin.ExecuteRequest) *plugin.ExecuteResponse {
+	raw, err := p.decryptConfig(ctx, req.Config)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to decrypt config: %v", err),
+		}
+	}
+
+	packages, err := batchTargets(raw)
+	if err != nil {
+		return invalidBatchResponse(err)
+	}
+	concurrency, err := batchConcurrency(raw)
+	if err != nil {
+		return invalidBatchResponse(err)
+	}
+	mirrorCheck, err := mirrorConsistencyMode(raw)
+	if err != nil {
+		return invalidBatchResponse(err)
+	}
+
+	// Server errors and uploaded digests count across packages, so an unhealthy repository
+	// aborts the whole train and a file matched by several packages is uploaded to each
+	// repository once
+	start := time.Now()
+	session := newPublishSession(p.parseConfig(raw))
+	resp, configs, results := p.publishBatch(ctx, req, packages, concurrency, session)
+
+	// Fanned-out project versions must be served with identical bytes by every target
+	if !req.DryRun && mirrorCheck != checkOff {
+		applyMirrorConsistency(resp, p.checkMirrorConsistency(ctx, configs, results), mirrorCheck)
+	}
+	if isFanOutConfig(raw) {
+		fanOutResponse(resp, req.DryRun)
+	}
+	if volume := session.volume.snapshot(); len(volume) > 0 {
+		resp.Outputs["upload_volume"] = volume
+	}
+	addTimingOutputs(resp.Outputs, start, time.Now())
+	session.log.annotate(resp)
+	return resp
+}
+
+// invalidBatchResponse reports a batch configuration error.
+func invalidBatchResponse(err error) *plugin.ExecuteResponse {
+	return &plugin.ExecuteResponse{
+		Success: false,
+		Error:   fmt.Sprintf("invalid batch configuration: %v", err),
+	}
+}
+
+// publishBatch publishes the packages with at most concurrency uploads in flight. A package
+// starts once its dependencies have been published; among ready packages higher priority
+// goes first, then config order. Packages whose dependencies failed are skipped.
+func (p *PyPIPlugin) publishBatch(ctx context.Context, req plugin.ExecuteRequest, packages []batchPackage, concurrency int, session *publishSession) (*plugin.ExecuteResponse, []Config, []batchPackageResult) {
+	results := make([]batchPackageResult, len(packages))
+	artifacts := make([][]plugin.Artifact, len(packages))
+	configs := make([]Config, len(packages))
+	index := make(map[string]int, len(packages))
+	for i, pkg := range packages {
+		configs[i] = p.parseConfig(pkg.Config)
+		index[pkg.Name] = i
+	}
+
+	const (
+		pending = iota
+		running
+		finished
+	)
+	state := make([]int, len(packages))
+	done := make(chan int)
+	inFlight, remaining := 0, len(packages)
+
+	for remaining > 0 {
+		// Start ready packages in priority order while slots are free
+		for _, i := range readyPackages(packages, state, index, pending, finished) {
+			if blocker := failedDependency(packages[i], results, index); blocker != "" {
+				results[i] = batchPackageResult{
+					Name:  packages[i].Name,
+					Error: fmt.Sprintf("skipped: dependency %s failed", blocker),
+				}
+				state[i] = finished
+				remaining--
+				continue
+			}
+			if inFlight == concurrency {
+				break
+			}
+
+			state[i] = running
+			inFlight++
+			go func(i int) {
+				results[i], artifacts[i] = p.publishBatchPackage(ctx, req, packages, configs, index, i, session)
+				done <- i
+			}(i)
+		}
+		if inFlight == 0 {
+			// Skipping packages can make new ones ready without anything running
+			continue
+		}
+
+		i := <-done
+		state[i] = finished
+		inFlight--
+		remaining--
+	}
+
+	resp := batchResponse(results, artifacts, req.DryRun)
+	if repos := session.breaker.unhealthy(); len(repos) > 0 {
+		resp.Outputs["unhealthy_repositories"] = repos
+	}
+	return resp, configs, results
+}
+
+// readyPackages returns the pending packages whose dependencies have all finished, ordered
+// by priority (highest first) and then config order.
+func readyPackages(packages []batchPackage, state []int, index map[string]int, pending, finished int) []int {
+	var ready []int
+	for i, pkg := range packages {
+		if state[i] != pending {
+			continue
+		}
+		ok := true
+		for _, dep := range pkg.DependsOn {
+			if state[index[dep]] != finished {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			ready = append(ready, i)
+		}
+	}
+	sort.SliceStable(ready, func(a, b int) bool { return packages[ready[a]].Priority > packages[ready[b]].Priority })
+	return ready
+}
+
+// indexOfPackage returns the position of the named package, or -1.
+func indexOfPackage(packages []batchPackage, name string) int {
+	for i, pkg := range packages {
+		if pkg.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// failedDependency returns the name of a dependency that was not published, or "".
+func failedDependency(pkg batchPackage, results []batchPackageResult, index map[string]int) string {
+	for _, dep := range pkg.DependsOn {
+		if !results[index[dep]].Success {
+			return dep
+		}
+	}
+	return ""
+}
+
+// publishBatchPackage waits until the package's dependencies are installable from the index
+// and then publishes it.
+func (p *PyPIPlugin) publishBatchPackage(ctx context.Context, req plugin.ExecuteRequest, packages []batchPackage, configs []Config, index map[string]int, i int, session *publishSession) (batchPackageResult, []plugin.Artifact) {
+	pkg, cfg := packages[i], configs[i]
+	start := time.Now()
+	result := batchPackageResult{Name: pkg.Name}
+
+	if !req.DryRun {
+		for _, dep := range pkg.DependsOn {
+			if err := p.waitForBatchDependency(ctx, configs[index[dep]], dep, cfg); err != nil {
+				result.Error = fmt.Sprintf("dependency %s not available: %v", dep, err)
+				result.finish(start)
+				return result, nil
+			}
+		}
+	}
+
+	resp, err := p.uploadPackage(ctx, cfg, req.Context, req.DryRun, session)
+	result.finish(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Success = resp.Success
+	result.Message = resp.Message
+	result.Error = resp.Error
+	result.Outputs = resp.Outputs
+	return result, resp.Artifacts
+}
+
+// waitForBatchDependency polls the dependency's index until its distribution files are listed,
+// using the dependent's wait settings.
+func (p *PyPIPlugin) waitForBatchDependency(ctx context.Context, depCfg Config, depName string, cfg Config) error {
+	files, err := expandDistGlob(depCfg.DistPath)
+	if err != nil || len(files) == 0 {
+		return fmt.Errorf("no distribution files found for %s", depName)
+	}
+
+	project := depName
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+		if meta, err := readDistMetadata(f); err == nil {
+			project = meta.Name
+		}
+	}
+
+	if depCfg.IndexURL == "" {
+		return fmt.Errorf("index_url is required to wait for %s", depName)
+	}
+	return p.waitForIndexFiles(ctx, depCfg.IndexURL, project, names, cfg.DependencyWaitTimeout, cfg.DependencyPollInterval)
+}
+
+// batchResponse aggregates package results into the hook response.
+func batchResponse(results []batchPackageResult, artifacts [][]plugin.Artifact, dryRun bool) *plugin.ExecuteResponse {
+	var failed []string
+	published := 0
+	for _, r := range results {
+		if r.Success {
+			published++
+		} else {
+			failed = append(failed, r.Name)
+		}
+	}
+
+	resp := &plugin.ExecuteResponse{
+		Success: len(failed) == 0,
+		Outputs: map[string]any{
+			"packages":  results,
+			"published": published,
+			"failed":    len(failed),
+		},
+	}
+	for _, a := range artifacts {
+		resp.Artifacts = append(resp.Artifacts, a...)
+	}
+
+	verb := "Published"
+	if dryRun {
+		verb = "Would publish"
+	}
+	resp.Message = fmt.Sprintf("%s %d of %d packages", verb, published, len(results))
+	if len(failed) > 0 {
+		resp.Outputs["failed_packages"] = failed
+		resp.Error = fmt.Sprintf("%d of %d packages failed: %v", len(failed), len(results), failed)
+	}
+	return resp
+}
+
+// validateBatch validates the batch options and each merged package or repository config,
+// prefixing their errors with their position.
+func (p *PyPIPlugin) validateBatch(ctx context.Context, raw map[string]any) (*plugin.ValidateResponse, error) {
+	vb := helpers.NewValidationBuilder()
+	if _, err := batchConcurrency(raw); err != nil {
+		vb.AddError("batch_concurrency", err.Error())
+	}
+	vb.ValidateOneOf(raw, "mirror_consistency_check", checkModes)
+
+	key := batchListKey(raw)
+	packages, err := batchTargets(raw)
+	if err != nil {
+		vb.AddError(key, err.Error())
+		return vb.Build(), nil
+	}
+
+	var warnings []plugin.ValidationError
+	for i, pkg := range packages {
+		for _, dep := range pkg.DependsOn {
+			if depCfg := p.parseConfig(packages[indexOfPackage(packages, dep)].Config); depCfg.IndexURL == "" {
+				vb.AddError(fmt.Sprintf("%s[%d].depends_on", key, i),
+					fmt.Sprintf("index_url is required for %s to wait for it to be published", dep))
+			}
+		}
+
+		resp, err := p.Validate(ctx, pkg.Config)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range resp.Errors {
+			field := fmt.Sprintf("%s[%d].%s", key, i, e.Field)
+			if e.Code == validationWarningCode {
+				warnings = append(warnings, plugin.ValidationError{Field: field, Message: e.Message, Code: e.Code})
+				continue
+			}
+			vb.AddErrorWithCode(field, e.Message, e.Code)
+		}
+	}
+
+	resp := vb.Build()
+	resp.Errors = append(resp.Errors, warnings...)
+	return resp, nil
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// writeBatchDists creates one directory with a distribution per package name and returns
+// the package configs for them.
+func writeBatchDists(t *testing.T, names ...string) []any {
+	t.Helper()
+	writeDistFiles(t)
+
+	packages := make([]any, 0, len(names))
+	for _, name := range names {
+		if err := os.Mkdir(name, 0o750); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(name, name+"-1.0.tar.gz"), []byte(name), 0o600); err != nil {
+			t.Fatalf("failed to write dist: %v", err)
+		}
+		packages = append(packages, map[string]any{"name": name, "dist_path": name + "/*"})
+	}
+	return packages
+}
+
+func TestBatchPackages(t *testing.T) {
+	raw := map[string]any{
+		"username":          "shared-user",
+		"password":          "shared-pass",
+		"batch_concurrency": 2,
+		"packages": []any{
+			map[string]any{"name": "core", "dist_path": "core/dist/*"},
+			map[string]any{"dist_path": "cli/dist/*", "password": "cli-pass"},
+		},
+	}
+
+	packages, err := batchPackages(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(packages) != 2 {
+		t.Fatalf("expected 2 packages, got %d", len(packages))
+	}
+	if packages[0].Name != "core" || packages[1].Name != "cli/dist/*" {
+		t.Errorf("unexpected names: %q, %q", packages[0].Name, packages[1].Name)
+	}
+	if packages[0].Config["password"] != "shared-pass" || packages[1].Config["password"] != "cli-pass" {
+		t.Errorf("package options must override shared ones: %v", packages)
+	}
+	for _, pkg := range packages {
+		for _, key := range []string{"packages", "batch_concurrency", "name"} {
+			if _, ok := pkg.Config[key]; ok {
+				t.Errorf("%s: %s must not be inherited", pkg.Name, key)
+			}
+		}
+	}
+
+	for _, bad := range []map[string]any{
+		{"packages": []any{}},
+		{"packages": []any{"core"}},
+		{"packages": []any{map[string]any{"name": "a"}, map[string]any{"name": "a"}}},
+		{"packages": []any{map[string]any{"packages": []any{}}}},
+	} {
+		if _, err := batchPackages(bad); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestExecuteBatch(t *testing.T) {
+	packages := writeBatchDists(t, "core", "cli", "extras")
+
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if filepath.Dir(args[len(args)-1]) == "cli" {
+				return []byte("HTTPError: 400 Bad Request"), errors.New("exit status 1")
+			}
+			return []byte("uploaded " + args[len(args)-1]), nil
+		},
+	}}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":   "__token__",
+			"password":   "pypi-token",
+			"repository": "http://localhost:8080/",
+			"packages":   packages,
+		},
+		Context: plugin.ReleaseContext{Version: "1.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Success {
+		t.Error("expected batch failure when a package fails")
+	}
+	if resp.Outputs["published"] != 2 || resp.Outputs["failed"] != 1 {
+		t.Errorf("unexpected counts: published=%v failed=%v", resp.Outputs["published"], resp.Outputs["failed"])
+	}
+	if !strings.Contains(resp.Error, "cli") || resp.Message != "Published 2 of 3 packages" {
+		t.Errorf("unexpected message/error: %q / %q", resp.Message, resp.Error)
+	}
+
+	results, _ := resp.Outputs["packages"].([]batchPackageResult)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %v", resp.Outputs["packages"])
+	}
+	for i, name := range []string{"core", "cli", "extras"} {
+		if results[i].Name != name {
+			t.Errorf("results[%d] = %s, want %s (config order)", i, results[i].Name, name)
+		}
+		if results[i].Success != (name != "cli") {
+			t.Errorf("%s: success = %v", name, results[i].Success)
+		}
+	}
+	if results[0].Outputs["output"] != "uploaded "+filepath.Join("core", "core-1.0.tar.gz") {
+		t.Errorf("expected per-package outputs, got %v", results[0].Outputs)
+	}
+}
+
+func TestExecuteBatchConcurrencyLimit(t *testing.T) {
+	packages := writeBatchDists(t, "a", "b", "c", "d", "e")
+
+	var inFlight, peak int32
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			return []byte("ok"), nil
+		},
+	}}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":          "__token__",
+			"password":          "pypi-token",
+			"repository":        "http://localhost:8080/",
+			"batch_concurrency": 2,
+			"packages":          packages,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got: %s", resp.Error)
+	}
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent uploads, saw %d", peak)
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	p := &PyPIPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{
+		"username":          "__token__",
+		"password":          "pypi-token",
+		"batch_concurrency": 100,
+		"packages": []any{
+			map[string]any{"name": "ok", "dist_path": "ok/*"},
+			map[string]any{"name": "bad", "dist_path": "../escape/*"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Valid {
+		t.Fatal("expected invalid batch config")
+	}
+
+	fields := map[string]bool{}
+	for _, e := range resp.Errors {
+		fields[e.Field] = true
+	}
+	for _, want := range []string{"batch_concurrency", "packages[1].dist_path"} {
+		if !fields[want] {
+			t.Errorf("expected error for %s, got %+v", want, resp.Errors)
+		}
+	}
+	if fields["packages[0].dist_path"] {
+		t.Errorf("unexpected error for valid package: %+v", resp.Errors)
+	}
+}
+
+func TestBatchResponseDryRun(t *testing.T) {
+	resp := batchResponse([]batchPackageResult{{Name: "a", Success: true}}, nil, true)
+	if !resp.Success || resp.Message != fmt.Sprintf("Would publish %d of %d packages", 1, 1) {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if _, ok := resp.Outputs["failed_packages"]; ok {
+		t.Error("failed_packages must be omitted when nothing failed")
+	}
+}
+
+func TestBatchPackagesDependencies(t *testing.T) {
+	packages, err := batchPackages(map[string]any{
+		"packages": []any{
+			map[string]any{"name": "core", "priority": 5},
+			map[string]any{"name": "cli", "depends_on": []any{"core"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if packages[0].Priority != 5 || len(packages[1].DependsOn) != 1 {
+		t.Errorf("unexpected packages: %+v", packages)
+	}
+	for _, pkg := range packages {
+		for _, key := range []string{"priority", "depends_on"} {
+			if _, ok := pkg.Config[key]; ok {
+				t.Errorf("%s: %s must not be part of the publish config", pkg.Name, key)
+			}
+		}
+	}
+
+	for name, list := range map[string][]any{
+		"unknown": {map[string]any{"name": "a", "depends_on": []any{"b"}}},
+		"cycle": {
+			map[string]any{"name": "a", "depends_on": []any{"b"}},
+			map[string]any{"name": "b", "depends_on": []any{"a"}},
+		},
+		"self": {map[string]any{"name": "a", "depends_on": []any{"a"}}},
+	} {
+		if _, err := batchPackages(map[string]any{"packages": list}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestExecuteBatchOrdering(t *testing.T) {
+	packages := writeBatchDists(t, "low", "high", "dependent", "base")
+	packages[1].(map[string]any)["priority"] = 10
+	packages[2].(map[string]any)["depends_on"] = []any{"base"}
+
+	index := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<a href="base-1.0.tar.gz">base-1.0.tar.gz</a>`))
+	}))
+	defer index.Close()
+
+	exec := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("ok"), nil
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: exec, httpClient: index.Client()}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":          "__token__",
+			"password":          "pypi-token",
+			"repository":        "http://localhost:8080/",
+			"index_url":         index.URL + "/simple/",
+			"batch_concurrency": 1,
+			"packages":          packages,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got: %s", resp.Error)
+	}
+
+	var order []string
+	for _, call := range exec.RunCalls {
+		for _, arg := range call.Args {
+			if strings.HasSuffix(arg, "-1.0.tar.gz") {
+				order = append(order, filepath.Dir(arg))
+			}
+		}
+	}
+	if want := "high,low,base,dependent"; strings.Join(order, ",") != want {
+		t.Errorf("upload order = %v, want %s", order, want)
+	}
+}
+
+func TestExecuteBatchWaitsForDependencies(t *testing.T) {
+	packages := writeBatchDists(t, "core", "cli")
+	packages[1].(map[string]any)["depends_on"] = []any{"core"}
+
+	var uploaded atomic.Bool
+	var pollsAfterUpload int32
+	index := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/simple/core/" || !uploaded.Load() {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&pollsAfterUpload, 1)
+		_, _ = w.Write([]byte(`<a href="core-1.0.tar.gz">core-1.0.tar.gz</a>`))
+	}))
+	defer index.Close()
+
+	p := &PyPIPlugin{
+		httpClient: index.Client(),
+		cmdExecutor: &MockCommandExecutor{
+			RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				switch filepath.Dir(args[len(args)-1]) {
+				case "core":
+					uploaded.Store(true)
+				case "cli":
+					if atomic.LoadInt32(&pollsAfterUpload) == 0 {
+						t.Error("cli uploaded before core was available on the index")
+					}
+				}
+				return []byte("ok"), nil
+			},
+		},
+	}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":                 "__token__",
+			"password":                 "pypi-token",
+			"repository":               "http://localhost:8080/",
+			"index_url":                index.URL + "/simple/",
+			"dependency_poll_interval": "1ms",
+			"packages":                 packages,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got: %s", resp.Error)
+	}
+}
+
+func TestExecuteBatchSkipsDependentsOfFailures(t *testing.T) {
+	packages := writeBatchDists(t, "core", "cli", "plugin")
+	packages[1].(map[string]any)["depends_on"] = []any{"core"}
+	packages[2].(map[string]any)["depends_on"] = []any{"cli"}
+
+	exec := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("HTTPError: 400 Bad Request"), errors.New("exit status 1")
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: exec}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":   "__token__",
+			"password":   "pypi-token",
+			"repository": "http://localhost:8080/",
+			"index_url":  "http://localhost:8080/simple/",
+			"packages":   packages,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(exec.RunCalls) != 1 {
+		t.Errorf("expected only core to be uploaded, got %d calls", len(exec.RunCalls))
+	}
+	results, _ := resp.Outputs["packages"].([]batchPackageResult)
+	if len(results) != 3 || results[1].Error != "skipped: dependency core failed" || results[2].Error != "skipped: dependency cli failed" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if resp.Outputs["failed"] != 3 {
+		t.Errorf("expected 3 failed packages, got %v", resp.Outputs["failed"])
+	}
+}
+
+func TestValidateBatchDependencyIndex(t *testing.T) {
+	p := &PyPIPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{
+		"username":   "__token__",
+		"password":   "pypi-token",
+		"repository": "http://localhost:8080/",
+		"packages": []any{
+			map[string]any{"name": "core", "dist_path": "core/*"},
+			map[string]any{"name": "cli", "dist_path": "cli/*", "depends_on": []any{"core"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Valid || len(resp.Errors) != 1 || resp.Errors[0].Field != "packages[1].depends_on" {
+		t.Errorf("expected index_url error for the dependent, got %+v", resp.Errors)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Benchmark defaults.
+const (
+	defaultBenchmarkIterations = 5
+	defaultBenchmarkSize       = 1 << 20 // 1 MiB
+	defaultBenchmarkPackage    = "relicta-pypi-benchmark"
+	maxBenchmarkIterations     = 100
+	maxBenchmarkSize           = 100 << 20 // 100 MiB
+)
+
+// productionUploadHost is the PyPI host that benchmark uploads must never target.
+const productionUploadHost = "upload.pypi.org"
+
+// benchmarkSample is the measurement of a single synthetic upload.
+type benchmarkSample struct {
+	latency time.Duration
+	bytes   int64
+}
+
+// runBenchmark uploads synthetic throwaway distributions to the configured staging index
+// and reports latency and throughput percentiles. The real release artifacts are not published.
+func (p *PyPIPlugin) runBenchmark(ctx context.Context, cfg Config, dryRun bool) (*plugin.ExecuteResponse, error) {
+	if dryRun {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Would benchmark %d synthetic uploads of %d bytes to %s", cfg.BenchmarkIterations, cfg.BenchmarkSize, cfg.Repository),
+			Outputs: map[string]any{
+				"repository": cfg.Repository,
+				"iterations": cfg.BenchmarkIterations,
+				"file_size":  cfg.BenchmarkSize,
+				"package":    cfg.BenchmarkPackage,
+			},
+		}, nil
+	}
+
+	workDir, err := os.MkdirTemp("", "relicta-pypi-benchmark-")
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to create benchmark directory: %v", err),
+		}, nil
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	uploader := newNativeUploader(p.uploadHTTPClient(cfg), cfg)
+	base := time.Now().Unix()
+
+	var samples []benchmarkSample
+	var failures []string
+	for i := 0; i < cfg.BenchmarkIterations; i++ {
+		version := fmt.Sprintf("0.0.%d.dev%d", base, i)
+		dist, err := writeSyntheticSdist(workDir, cfg.BenchmarkPackage, version, cfg.BenchmarkSize)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to create synthetic distribution: %v", err),
+			}, nil
+		}
+
+		start := time.Now()
+		n, err := uploader.upload(ctx, dist)
+		elapsed := time.Since(start)
+		_ = os.Remove(dist.Path)
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("benchmark cancelled: %v", ctx.Err()),
+				}, nil
+			}
+			failures = append(failures, err.Error())
+			continue
+		}
+		samples = append(samples, benchmarkSample{latency: elapsed, bytes: n})
+	}
+
+	if len(samples) == 0 {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("all %d benchmark uploads failed: %s", cfg.BenchmarkIterations, strings.Join(failures, "; ")),
+		}, nil
+	}
+
+	outputs := benchmarkOutputs(samples)
+	outputs["repository"] = cfg.Repository
+	outputs["iterations"] = cfg.BenchmarkIterations
+	outputs["file_size"] = cfg.BenchmarkSize
+	outputs["failures"] = len(failures)
+
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Benchmarked %d uploads to %s: p50 latency %dms, p50 throughput %d B/s",
+			len(samples), cfg.Repository, outputs["latency_p50_ms"], outputs["throughput_p50_bps"]),
+		Outputs: outputs,
+	}, nil
+}
+
+// benchmarkOutputs computes latency and throughput percentiles from samples.
+func benchmarkOutputs(samples []benchmarkSample) map[string]any {
+	latencies := make([]float64, len(samples))
+	throughputs := make([]float64, len(samples))
+	for i, s := range samples {
+		latencies[i] = float64(s.latency.Milliseconds())
+		secs := s.latency.Seconds()
+		if secs > 0 {
+			throughputs[i] = float64(s.bytes) / secs
+		}
+	}
+	sort.Float64s(latencies)
+	sort.Float64s(throughputs)
+
+	outputs := map[string]any{
+		"latency_min_ms":     int64(latencies[0]),
+		"latency_max_ms":     int64(latencies[len(latencies)-1]),
+		"throughput_min_bps": int64(throughputs[0]),
+		"throughput_max_bps": int64(throughputs[len(throughputs)-1]),
+	}
+	for _, pct := range []int{50, 90, 99} {
+		outputs[fmt.Sprintf("latency_p%d_ms", pct)] = int64(percentile(latencies, pct))
+		outputs[fmt.Sprintf("throughput_p%d_bps", pct)] = int64(percentile(throughputs, pct))
+	}
+	return outputs
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, pct int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (pct*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// writeSyntheticSdist writes a throwaway sdist with size bytes of random payload.
+func writeSyntheticSdist(dir, name, version string, size int64) (distribution, error) {
+	normalized := strings.ReplaceAll(name, "-", "_")
+	root := fmt.Sprintf("%s-%s", normalized, version)
+	path := filepath.Join(dir, root+".tar.gz")
+
+	f, err := os.Create(path)
+	if err != nil {
+		return distribution{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	pkgInfo := fmt.Sprintf("Metadata-Version: 2.1\nName: %s\nVersion: %s\nSummary: Synthetic Relicta upload benchmark package\n", name, version)
+	if err := writeTarFile(tw, root+"/PKG-INFO", int64(len(pkgInfo)), strings.NewReader(pkgInfo)); err != nil {
+		return distribution{}, err
+	}
+	if err := writeTarFile(tw, root+"/payload.bin", size, io.LimitReader(rand.Reader, size)); err != nil {
+		return distribution{}, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return distribution{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return distribution{}, err
+	}
+
+	return distribution{
+		Path:            path,
+		Name:            name,
+		Version:         version,
+		Filetype:        "sdist",
+		PyVersion:       "source",
+		MetadataVersion: "2.1",
+		Metadata:        &packageMetadata{MetadataVersion: "2.1", Name: name, Version: version, Summary: "Synthetic Relicta upload benchmark package"},
+	}, nil
+}
+
+// writeTarFile writes a regular file entry to tw.
+func writeTarFile(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+// validateBenchmarkConfig validates the benchmark options.
+func validateBenchmarkConfig(cfg Config) error {
+	if !cfg.Benchmark {
+		return nil
+	}
+
+	if cfg.BenchmarkIterations < 1 || cfg.BenchmarkIterations > maxBenchmarkIterations {
+		return fmt.Errorf("benchmark_iterations must be between 1 and %d", maxBenchmarkIterations)
+	}
+	if cfg.BenchmarkSize < 1 || cfg.BenchmarkSize > maxBenchmarkSize {
+		return fmt.Errorf("benchmark_size must be between 1 and %d bytes", maxBenchmarkSize)
+	}
+	if cfg.BenchmarkPackage == "" {
+		return fmt.Errorf("benchmark_package cannot be empty")
+	}
+	if !projectNamePattern.MatchString(cfg.BenchmarkPackage) {
+		return fmt.Errorf("benchmark_package is not a valid project name")
+	}
+
+	parsed, err := url.Parse(cfg.Repository)
+	if err == nil && strings.EqualFold(parsed.Hostname(), productionUploadHost) {
+		return fmt.Errorf("benchmark uploads must target a staging index, not %s", productionUploadHost)
+	}
+
+	return nil
+}
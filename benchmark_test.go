@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	tests := []struct {
+		pct      int
+		expected float64
+	}{
+		{50, 5},
+		{90, 9},
+		{99, 10},
+		{100, 10},
+		{0, 1},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(values, tt.pct); got != tt.expected {
+			t.Errorf("percentile(%d) = %v, expected %v", tt.pct, got, tt.expected)
+		}
+	}
+
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile of empty slice = %v, expected 0", got)
+	}
+}
+
+func TestBenchmarkOutputs(t *testing.T) {
+	samples := []benchmarkSample{
+		{latency: 100 * time.Millisecond, bytes: 1000},
+		{latency: 300 * time.Millisecond, bytes: 1000},
+		{latency: 200 * time.Millisecond, bytes: 1000},
+	}
+
+	outputs := benchmarkOutputs(samples)
+
+	expected := map[string]int64{
+		"latency_min_ms":     100,
+		"latency_max_ms":     300,
+		"latency_p50_ms":     200,
+		"latency_p99_ms":     300,
+		"throughput_max_bps": 10000,
+		"throughput_min_bps": 3333,
+	}
+	for key, want := range expected {
+		if got := outputs[key]; got != want {
+			t.Errorf("%s: expected %v, got %v", key, want, got)
+		}
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+	var uploads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("failed to parse upload: %v", err)
+		}
+		if name := r.FormValue("name"); name != "my-bench" {
+			t.Errorf("expected benchmark package name, got '%s'", name)
+		}
+		if !strings.Contains(r.FormValue("version"), ".dev") {
+			t.Errorf("expected dev version, got '%s'", r.FormValue("version"))
+		}
+		uploads.Add(1)
+	}))
+	defer server.Close()
+
+	mockExecutor := &MockCommandExecutor{}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor, httpClient: server.Client()}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":             "testuser",
+			"password":             "testpass",
+			"repository":           server.URL,
+			"benchmark":            true,
+			"benchmark_iterations": 3,
+			"benchmark_size":       2048,
+			"benchmark_package":    "my-bench",
+		},
+		Context: plugin.ReleaseContext{Version: "v1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !resp.Success {
+		t.Fatalf("expected success, got error: %s", resp.Error)
+	}
+	if uploads.Load() != 3 {
+		t.Errorf("expected 3 uploads, got %d", uploads.Load())
+	}
+	if len(mockExecutor.RunCalls) != 0 {
+		t.Errorf("expected twine not to be run, got %d calls", len(mockExecutor.RunCalls))
+	}
+	for _, key := range []string{"latency_p50_ms", "latency_p90_ms", "latency_p99_ms", "throughput_p50_bps"} {
+		if _, ok := resp.Outputs[key]; !ok {
+			t.Errorf("expected output key '%s'", key)
+		}
+	}
+	if resp.Outputs["failures"] != 0 {
+		t.Errorf("expected 0 failures, got %v", resp.Outputs["failures"])
+	}
+}
+
+func TestRunBenchmarkAllFail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	cfg := p.parseConfig(map[string]any{
+		"username":             "testuser",
+		"password":             "testpass",
+		"repository":           server.URL,
+		"benchmark":            true,
+		"benchmark_iterations": 2,
+		"benchmark_size":       16,
+	})
+
+	resp, err := p.runBenchmark(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success {
+		t.Error("expected failure when all uploads fail")
+	}
+	if !strings.Contains(resp.Error, "all 2 benchmark uploads failed") {
+		t.Errorf("unexpected error: %s", resp.Error)
+	}
+}
+
+func TestRunBenchmarkDryRun(t *testing.T) {
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"repository": "http://localhost:8080/",
+		"benchmark":  true,
+	})
+
+	resp, err := p.runBenchmark(context.Background(), cfg, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Errorf("expected success, got error: %s", resp.Error)
+	}
+	if !strings.Contains(resp.Message, "Would benchmark 5 synthetic uploads") {
+		t.Errorf("unexpected message: %s", resp.Message)
+	}
+}
+
+func TestValidateBenchmarkConfig(t *testing.T) {
+	base := Config{
+		Repository:          "https://test.pypi.org/legacy/",
+		Benchmark:           true,
+		BenchmarkIterations: defaultBenchmarkIterations,
+		BenchmarkSize:       defaultBenchmarkSize,
+		BenchmarkPackage:    defaultBenchmarkPackage,
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(*Config)
+		errContains string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"disabled ignores options", func(c *Config) { c.Benchmark = false; c.BenchmarkIterations = 0 }, ""},
+		{"production index", func(c *Config) { c.Repository = "https://upload.pypi.org/legacy/" }, "staging index"},
+		{"too many iterations", func(c *Config) { c.BenchmarkIterations = 1000 }, "benchmark_iterations"},
+		{"zero size", func(c *Config) { c.BenchmarkSize = 0 }, "benchmark_size"},
+		{"empty package", func(c *Config) { c.BenchmarkPackage = "" }, "benchmark_package"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			tt.modify(&cfg)
+			err := validateBenchmarkConfig(cfg)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing '%s', got %v", tt.errContains, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Build backends of the build option.
+const (
+	buildBackendBuild  = "build"
+	buildBackendPoetry = "poetry"
+	buildBackendHatch  = "hatch"
+	buildBackendFlit   = "flit"
+	buildBackendUV     = "uv"
+)
+
+// buildBackends lists the supported build_backend values.
+var buildBackends = []string{buildBackendBuild, buildBackendPoetry, buildBackendHatch, buildBackendFlit, buildBackendUV}
+
+// buildBackendCommands are the commands building sdist and wheel into {out_dir}. flit cannot
+// choose its output directory and always builds into dist.
+var buildBackendCommands = map[string][]string{
+	buildBackendBuild:  {"python", "-m", "build", "--outdir", "{out_dir}"},
+	buildBackendPoetry: {"poetry", "build", "--output", "{out_dir}"},
+	buildBackendHatch:  {"hatch", "build", "{out_dir}"},
+	buildBackendFlit:   {"flit", "build"},
+	buildBackendUV:     {"uv", "build", "--out-dir", "{out_dir}"},
+}
+
+// pretendVersionEnv makes setuptools-scm and hatch-vcs build the release version before its tag
+// exists.
+const pretendVersionEnv = "SETUPTOOLS_SCM_PRETEND_VERSION"
+
+// buildOutDir returns the directory holding the dist_path distributions, which the build writes
+// to.
+func buildOutDir(distPath string) (string, error) {
+	dir := path.Dir(toSlashPath(distPath))
+	if strings.ContainsAny(dir, "*?[") {
+		return "", fmt.Errorf("the build needs a dist_path whose directory has no wildcards, got %s", distPath)
+	}
+	return dir, nil
+}
+
+// buildCommand returns the configured build_command or the command of the build backend.
+func buildCommand(cfg Config) []string {
+	if len(cfg.BuildCommand) > 0 {
+		return cfg.BuildCommand
+	}
+	return buildBackendCommands[cfg.BuildBackend]
+}
+
+// buildPackage builds the distributions on the pre-publish hook, so the post-publish hook
+// uploads them from dist_path. Stale files matching dist_path are removed first, and the
+// distributions built must carry the release version.
+func (p *PyPIPlugin) buildPackage(ctx context.Context, cfg Config, releaseCtx plugin.ReleaseContext, dryRun bool) *plugin.ExecuteResponse {
+	version, err := releaseVersion(cfg, releaseCtx.Version)
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: err.Error()}
+	}
+	outDir, err := buildOutDir(cfg.DistPath)
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: err.Error()}
+	}
+	argv, err := renderCommandTemplate("build_command", buildCommand(cfg), map[string]string{"version": version, "out_dir": filepath.FromSlash(outDir)})
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: err.Error()}
+	}
+	outputs := map[string]any{
+		"build_command": argv,
+		"dist_path":     cfg.DistPath,
+		"version":       version,
+		"plugin_build":  currentBuild().String(),
+	}
+	if len(cfg.BuildCommand) == 0 {
+		outputs["build_backend"] = cfg.BuildBackend
+	}
+	if dryRun {
+		return &plugin.ExecuteResponse{Success: true, Message: fmt.Sprintf("Would build the package with %s", strings.Join(argv, " ")), Outputs: outputs}
+	}
+
+	stale, _ := expandDistGlob(cfg.DistPath)
+	for _, f := range stale {
+		if err := os.Remove(f); err != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("failed to remove stale distribution %s: %v", f, err)}
+		}
+	}
+	if len(stale) > 0 {
+		outputs["removed_files"] = stale
+	}
+
+	var env []string
+	if version != "" {
+		env = append(env, pretendVersionEnv+"="+version)
+	}
+	start := time.Now()
+	output, err := p.getExecutor().RunWithEnv(ctx, env, argv[0], argv[1:]...)
+	outputs["build_duration_ms"] = time.Since(start).Milliseconds()
+	outputs["output"], _ = truncateOutput(string(output), cfg.MaxOutputBytes)
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("build failed: %v", err), Outputs: outputs}
+	}
+
+	files, _ := expandDistGlob(cfg.DistPath)
+	if len(files) == 0 {
+		return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("the build produced no distributions matching %s", cfg.DistPath), Outputs: outputs}
+	}
+	outputs["built_files"] = files
+	for _, f := range files {
+		meta, err := readDistMetadata(f)
+		if err != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("cannot read the metadata of built %s: %v", f, err), Outputs: outputs}
+		}
+		if version != "" && normalizeVersion(meta.Version) != normalizeVersion(version) {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("built %s is version %s, but the release is %s; update the project version or derive it from %s", f, meta.Version, version, pretendVersionEnv),
+				Outputs: outputs,
+			}
+		}
+	}
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Built %d distribution(s) into %s", len(files), outDir),
+		Outputs: outputs,
+	}
+}
+
+// validateBuildConfig validates the build options.
+func validateBuildConfig(cfg Config) error {
+	if !cfg.Build {
+		if len(cfg.BuildCommand) > 0 {
+			return fmt.Errorf("build_command requires build")
+		}
+		return nil
+	}
+	if !containsString(buildBackends, cfg.BuildBackend) {
+		return fmt.Errorf("build_backend must be one of: %s", strings.Join(buildBackends, ", "))
+	}
+	outDir, err := buildOutDir(cfg.DistPath)
+	if err != nil {
+		return err
+	}
+	if len(cfg.BuildCommand) == 0 && cfg.BuildBackend == buildBackendFlit && outDir != "dist" {
+		return fmt.Errorf("flit always builds into dist; set dist_path to dist/* or use build_command")
+	}
+	_, err = renderCommandTemplate("build_command", buildCommand(cfg), map[string]string{"version": "", "out_dir": ""})
+	return err
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestBuildOutDir(t *testing.T) {
+	tests := []struct {
+		distPath string
+		want     string
+		wantErr  bool
+	}{
+		{"dist/*", "dist", false},
+		{"build/output/*.whl", "build/output", false},
+		{"*.tar.gz", ".", false},
+		{"packages/*/dist/*", "", true},
+	}
+	for _, tt := range tests {
+		got, err := buildOutDir(tt.distPath)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("buildOutDir(%q) = %q, %v; want %q, error %v", tt.distPath, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// fakeBuild returns an executor building a wheel of version into the --outdir of python -m build.
+func fakeBuild(t *testing.T, version string) *MockCommandExecutor {
+	return &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			writeTestWheel(t, filepath.Join(args[3], "mypkg-"+version+"-py3-none-any.whl"), map[string]string{
+				"mypkg-" + version + ".dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: " + version + "\n",
+			})
+			return []byte("Successfully built mypkg-" + version + "-py3-none-any.whl\n"), nil
+		},
+	}
+}
+
+func TestExecuteBuild(t *testing.T) {
+	writeDistFiles(t, "mypkg-0.9.0.tar.gz")
+	executor := fakeBuild(t, "1.0.0")
+	p := &PyPIPlugin{cmdExecutor: executor}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  map[string]any{"build": true},
+		Context: plugin.ReleaseContext{Version: "v1.0.0"},
+	})
+	if err != nil || !resp.Success || resp.Message != "Built 1 distribution(s) into dist" {
+		t.Fatalf("expected a build, got %v %+v", err, resp)
+	}
+	call := executor.RunCalls[0]
+	if call.Name != "python" || strings.Join(call.Args, " ") != "-m build --outdir dist" || fmt.Sprint(call.Env) != "[SETUPTOOLS_SCM_PRETEND_VERSION=1.0.0]" {
+		t.Errorf("unexpected build call %+v", call)
+	}
+	if _, err := os.Stat(filepath.Join("dist", "mypkg-0.9.0.tar.gz")); !os.IsNotExist(err) {
+		t.Error("expected the stale distribution to be removed")
+	}
+	if fmt.Sprint(resp.Outputs["built_files"]) != "["+filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl")+"]" {
+		t.Errorf("unexpected built_files %v", resp.Outputs["built_files"])
+	}
+}
+
+func TestExecuteBuildVersionMismatch(t *testing.T) {
+	writeDistFiles(t)
+	p := &PyPIPlugin{cmdExecutor: fakeBuild(t, "0.9.0")}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  map[string]any{"build": true},
+		Context: plugin.ReleaseContext{Version: "v1.0.0"},
+	})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "is version 0.9.0, but the release is 1.0.0") {
+		t.Fatalf("expected a version mismatch, got %v %+v", err, resp)
+	}
+}
+
+func TestExecuteBuildBackends(t *testing.T) {
+	tests := []struct {
+		config map[string]any
+		want   string
+	}{
+		{map[string]any{"build_backend": "uv"}, "uv build --out-dir dist"},
+		{map[string]any{"build_backend": "hatch"}, "hatch build dist"},
+		{map[string]any{"build_backend": "poetry", "dist_path": "out/*.whl"}, "poetry build --output out"},
+		{map[string]any{"build_backend": "flit"}, "flit build"},
+		{map[string]any{"build_command": []any{"pdm", "build", "-d", "{out_dir}"}}, "pdm build -d dist"},
+	}
+	for _, tt := range tests {
+		tt.config["build"] = true
+		executor := &MockCommandExecutor{}
+		p := &PyPIPlugin{cmdExecutor: executor}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPrePublish, Config: tt.config, DryRun: true})
+		if err != nil || !resp.Success || strings.Join(resp.Outputs["build_command"].([]string), " ") != tt.want || len(executor.RunCalls) != 0 {
+			t.Errorf("%v: expected %q without running it, got %v %+v", tt.config, tt.want, err, resp)
+		}
+	}
+}
+
+func TestExecuteBuildDisabled(t *testing.T) {
+	executor := &MockCommandExecutor{}
+	p := &PyPIPlugin{cmdExecutor: executor}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPrePublish, Config: map[string]any{}})
+	if err != nil || !resp.Success || !strings.Contains(resp.Message, "not handled") || len(executor.RunCalls) != 0 {
+		t.Fatalf("expected the hook to be skipped, got %v %+v", err, resp)
+	}
+}
+
+func TestValidateBuildConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"disabled", Config{}, false},
+		{"build", Config{Build: true, BuildBackend: buildBackendBuild, DistPath: "dist/*"}, false},
+		{"flit", Config{Build: true, BuildBackend: buildBackendFlit, DistPath: "dist/*"}, false},
+		{"flit elsewhere", Config{Build: true, BuildBackend: buildBackendFlit, DistPath: "out/*"}, true},
+		{"unknown backend", Config{Build: true, BuildBackend: "setup.py", DistPath: "dist/*"}, true},
+		{"wildcard directory", Config{Build: true, BuildBackend: buildBackendBuild, DistPath: "*/dist/*"}, true},
+		{"command without build", Config{BuildCommand: []string{"make", "dist"}}, true},
+		{"unknown variable", Config{Build: true, BuildBackend: buildBackendBuild, DistPath: "dist/*", BuildCommand: []string{"make", "{dist_path}"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateBuildConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateBuildConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
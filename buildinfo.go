@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Version is the plugin version. Release builds override it with -ldflags "-X main.Version=<tag>".
+var Version = "2.0.0"
+
+// buildIdentity describes the running plugin binary so release logs show exactly which build ran.
+type buildIdentity struct {
+	Version   string
+	OS        string
+	Arch      string
+	GoVersion string
+	// Static reports whether the binary was built without cgo and is therefore
+	// independent of the host libc (glibc or musl).
+	Static bool
+}
+
+// currentBuild returns the identity of the running binary.
+func currentBuild() buildIdentity {
+	id := buildIdentity{
+		Version:   strings.TrimPrefix(Version, "v"),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		GoVersion: runtime.Version(),
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "CGO_ENABLED" {
+				id.Static = s.Value == "0"
+			}
+		}
+	}
+
+	return id
+}
+
+// String formats the identity as "<version> <os>/<arch> (<linkage>, <go version>)".
+func (b buildIdentity) String() string {
+	linkage := "dynamic"
+	if b.Static {
+		linkage = "static"
+	}
+	return fmt.Sprintf("%s %s/%s (%s, %s)", b.Version, b.OS, b.Arch, linkage, b.GoVersion)
+}
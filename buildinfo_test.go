@@ -0,0 +1,45 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCurrentBuild(t *testing.T) {
+	id := currentBuild()
+
+	if id.OS != runtime.GOOS || id.Arch != runtime.GOARCH {
+		t.Errorf("expected %s/%s, got %s/%s", runtime.GOOS, runtime.GOARCH, id.OS, id.Arch)
+	}
+	if strings.HasPrefix(id.Version, "v") {
+		t.Errorf("expected version without v prefix, got '%s'", id.Version)
+	}
+}
+
+func TestBuildIdentityString(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       buildIdentity
+		expected string
+	}{
+		{
+			name:     "static",
+			id:       buildIdentity{Version: "2.1.0", OS: "linux", Arch: "arm64", GoVersion: "go1.22.7", Static: true},
+			expected: "2.1.0 linux/arm64 (static, go1.22.7)",
+		},
+		{
+			name:     "dynamic",
+			id:       buildIdentity{Version: "2.1.0", OS: "darwin", Arch: "amd64", GoVersion: "go1.22.7"},
+			expected: "2.1.0 darwin/amd64 (dynamic, go1.22.7)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.id.String(); got != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Rollout channels recorded in the canary tag attributes.
+const (
+	rolloutCanary = "canary"
+	rolloutStable = "stable"
+)
+
+// canaryRollout is the rollout of a release on an internal index, recorded as the attributes of
+// a Nexus tag associated with its components. Installers and mirrors of the organization read
+// the tag to decide which consumers get the release.
+type canaryRollout struct {
+	Tag        string `json:"tag"`
+	Project    string `json:"project"`
+	Version    string `json:"version"`
+	Channel    string `json:"channel"`
+	Percentage int    `json:"percentage"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// canaryTag returns the rollout tag of project and version. It is stable, so later runs find it.
+func canaryTag(project, version string) string {
+	return fmt.Sprintf("pypi-%s-%s-rollout", normalizeProjectName(project), versionSlug(version))
+}
+
+// attributes returns the tag attributes recording the rollout.
+func (r *canaryRollout) attributes() map[string]string {
+	return map[string]string{
+		"project":            r.Project,
+		"version":            r.Version,
+		"channel":            r.Channel,
+		"rollout_percentage": strconv.Itoa(r.Percentage),
+		"updated_at":         r.UpdatedAt,
+	}
+}
+
+// tagCanary creates the rollout tag of the uploaded files as a canary at cfg.CanaryPercentage and
+// associates it with their components.
+func (p *PyPIPlugin) tagCanary(ctx context.Context, cfg Config, files []string) (*canaryRollout, error) {
+	project, version := distProjectVersion(files)
+	if project == "" {
+		return nil, fmt.Errorf("canary: no distribution with readable metadata")
+	}
+	nexus, err := newNexusStaging(cfg, canaryTag(project, version))
+	if err != nil {
+		return nil, err
+	}
+	rollout := &canaryRollout{
+		Tag:        nexus.Tag,
+		Project:    project,
+		Version:    version,
+		Channel:    rolloutCanary,
+		Percentage: cfg.CanaryPercentage,
+		UpdatedAt:  formatTimestamp(time.Now()),
+	}
+	tag := map[string]any{"name": rollout.Tag, "attributes": rollout.attributes()}
+	if err := p.sendJSON(ctx, "nexus", http.MethodPost, nexus.api("tags", nil), nexus.headers, tag, nil); err != nil {
+		return nil, fmt.Errorf("failed to create canary tag %s: %w", rollout.Tag, err)
+	}
+	query := url.Values{
+		"repository": {nexus.Repository},
+		"format":     {"pypi"},
+		"name":       {normalizeProjectName(project)},
+		"version":    {version},
+	}
+	if err := p.sendJSON(ctx, "nexus", http.MethodPost, nexus.api("tags/associate/"+url.PathEscape(rollout.Tag), query), nexus.headers, nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to tag the canary components: %w", err)
+	}
+	return rollout, nil
+}
+
+// promoteCanary updates the rollout tag of an earlier canary upload, without uploading anything:
+// to cfg.CanaryPercentage, or to stable when it is unset or 100.
+func (p *PyPIPlugin) promoteCanary(ctx context.Context, cfg Config, dryRun bool) *plugin.ExecuteResponse {
+	nexus, err := newNexusStaging(cfg, cfg.CanaryPromoteTag)
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: err.Error()}
+	}
+	var tag struct {
+		Name       string            `json:"name"`
+		Attributes map[string]string `json:"attributes"`
+	}
+	if err := p.sendJSON(ctx, "nexus", http.MethodGet, nexus.api("tags/"+url.PathEscape(nexus.Tag), nil), nexus.headers, nil, &tag); err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("failed to read canary tag %s: %v", nexus.Tag, err)}
+	}
+	switch tag.Attributes["channel"] {
+	case rolloutCanary:
+	case rolloutStable:
+		return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("%s %s is already promoted to stable", tag.Attributes["project"], tag.Attributes["version"])}
+	default:
+		return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("tag %s is not a canary rollout", nexus.Tag)}
+	}
+
+	rollout := &canaryRollout{
+		Tag:        nexus.Tag,
+		Project:    tag.Attributes["project"],
+		Version:    tag.Attributes["version"],
+		Channel:    rolloutCanary,
+		Percentage: cfg.CanaryPercentage,
+		UpdatedAt:  formatTimestamp(time.Now()),
+	}
+	if rollout.Percentage == 0 || rollout.Percentage == 100 {
+		rollout.Channel, rollout.Percentage = rolloutStable, 100
+	}
+	message := fmt.Sprintf("%s %s to %d%%", rollout.Project, rollout.Version, rollout.Percentage)
+	if rollout.Channel == rolloutStable {
+		message = fmt.Sprintf("%s %s to stable", rollout.Project, rollout.Version)
+	}
+	outputs := map[string]any{
+		"repository":   cfg.Repository,
+		"canary":       rollout,
+		"plugin_build": currentBuild().String(),
+	}
+	if dryRun {
+		return &plugin.ExecuteResponse{Success: true, Message: "Would promote " + message, Outputs: outputs}
+	}
+
+	update := map[string]any{"attributes": rollout.attributes()}
+	if err := p.sendJSON(ctx, "nexus", http.MethodPut, nexus.api("tags/"+url.PathEscape(nexus.Tag), nil), nexus.headers, update, nil); err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("failed to update canary tag %s: %v", nexus.Tag, err), Outputs: outputs}
+	}
+	return &plugin.ExecuteResponse{Success: true, Message: "Promoted " + message, Outputs: outputs}
+}
+
+// validateCanaryConfig validates the canary options.
+func validateCanaryConfig(cfg Config) error {
+	if cfg.CanaryPercentage < 0 || cfg.CanaryPercentage > 100 {
+		return fmt.Errorf("canary_percentage must be between 1 and 100")
+	}
+	if cfg.CanaryPercentage == 0 && cfg.CanaryPromoteTag == "" {
+		return nil
+	}
+	if cfg.CanaryPromoteTag == "" {
+		if cfg.CanaryPercentage == 100 {
+			return fmt.Errorf("canary_percentage must be below 100 for a canary upload; promote it to stable with canary_promote_tag")
+		}
+		if cfg.NexusStagingDestination != "" {
+			return fmt.Errorf("canary_percentage cannot be combined with nexus_staging_destination")
+		}
+	} else if !nexusRepositoryNamePattern.MatchString(cfg.CanaryPromoteTag) {
+		return fmt.Errorf("invalid canary_promote_tag %q", cfg.CanaryPromoteTag)
+	}
+	if _, err := newNexusStaging(cfg, ""); err != nil {
+		return fmt.Errorf("canary rollouts are recorded in Nexus tags: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// fakeNexusTags stores the Nexus tags and their attributes.
+type fakeNexusTags struct {
+	mu         sync.Mutex
+	tags       map[string]map[string]string
+	associated []string
+}
+
+func (n *fakeNexusTags) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	endpoint := strings.TrimPrefix(r.URL.Path, "/service/rest/v1/")
+	var tag struct {
+		Name       string            `json:"name"`
+		Attributes map[string]string `json:"attributes"`
+	}
+	switch name := strings.TrimPrefix(endpoint, "tags/"); {
+	case r.Method == http.MethodPost && endpoint == "tags":
+		_ = json.NewDecoder(r.Body).Decode(&tag)
+		n.tags[tag.Name] = tag.Attributes
+	case r.Method == http.MethodPost && strings.HasPrefix(endpoint, "tags/associate/"):
+		n.associated = append(n.associated, strings.TrimPrefix(endpoint, "tags/associate/")+" "+r.URL.Query().Get("name")+" "+r.URL.Query().Get("version"))
+	case r.Method == http.MethodGet && n.tags[name] != nil:
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": name, "attributes": n.tags[name]})
+		return
+	case r.Method == http.MethodPut && n.tags[name] != nil:
+		_ = json.NewDecoder(r.Body).Decode(&tag)
+		n.tags[name] = tag.Attributes
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	_, _ = w.Write([]byte(`{}`))
+}
+
+func TestExecuteCanaryRollout(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	nexus := &fakeNexusTags{tags: map[string]map[string]string{}}
+	server := httptest.NewServer(nexus)
+	defer server.Close()
+	uploads := 0
+	p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			uploads++
+			return nil, nil
+		},
+	}}
+	config := map[string]any{
+		"username":          "deployer",
+		"password":          "secret",
+		"repository":        server.URL + "/repository/pypi-internal/",
+		"canary_percentage": 5,
+	}
+	execute := func() *plugin.ExecuteResponse {
+		t.Helper()
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := execute()
+	if !resp.Success || resp.Message != "Uploaded mypkg 1.0.0 to "+server.URL+"/repository/pypi-internal/ as a canary for 5% of consumers" {
+		t.Fatalf("expected a canary upload, got %+v", resp)
+	}
+	rollout, _ := resp.Outputs["canary"].(*canaryRollout)
+	if rollout == nil || rollout.Tag != "pypi-mypkg-1.0.0-rollout" || rollout.Channel != rolloutCanary {
+		t.Fatalf("unexpected canary output %+v", resp.Outputs["canary"])
+	}
+	if attrs := nexus.tags[rollout.Tag]; attrs["channel"] != "canary" || attrs["rollout_percentage"] != "5" || attrs["version"] != "1.0.0" {
+		t.Errorf("unexpected tag attributes %v", attrs)
+	}
+	if len(nexus.associated) != 1 || nexus.associated[0] != "pypi-mypkg-1.0.0-rollout mypkg 1.0.0" {
+		t.Errorf("unexpected associations %v", nexus.associated)
+	}
+
+	config["canary_promote_tag"] = rollout.Tag
+	config["canary_percentage"] = 50
+	if resp := execute(); !resp.Success || resp.Message != "Promoted mypkg 1.0.0 to 50%" || nexus.tags[rollout.Tag]["rollout_percentage"] != "50" {
+		t.Fatalf("expected the rollout to widen, got %+v %v", resp, nexus.tags[rollout.Tag])
+	}
+
+	delete(config, "canary_percentage")
+	if resp := execute(); !resp.Success || resp.Message != "Promoted mypkg 1.0.0 to stable" || nexus.tags[rollout.Tag]["channel"] != "stable" {
+		t.Fatalf("expected the rollout to be promoted, got %+v %v", resp, nexus.tags[rollout.Tag])
+	}
+	if resp := execute(); resp.Success || !strings.Contains(resp.Error, "already promoted to stable") {
+		t.Fatalf("expected a second promotion to be refused, got %+v", resp)
+	}
+	if uploads != 1 {
+		t.Errorf("expected promotions not to upload, got %d uploads", uploads)
+	}
+}
+
+func TestValidateCanaryConfig(t *testing.T) {
+	canary := Config{Repository: "http://localhost:8081/repository/pypi-internal/", CanaryPercentage: 10}
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr bool
+	}{
+		{"canary", func(cfg *Config) {}, false},
+		{"disabled", func(cfg *Config) { cfg.CanaryPercentage = 0 }, false},
+		{"promote", func(cfg *Config) { cfg.CanaryPromoteTag, cfg.CanaryPercentage = "pypi-mypkg-1.0.0-rollout", 0 }, false},
+		{"promote to 100", func(cfg *Config) { cfg.CanaryPromoteTag, cfg.CanaryPercentage = "pypi-mypkg-1.0.0-rollout", 100 }, false},
+		{"upload at 100", func(cfg *Config) { cfg.CanaryPercentage = 100 }, true},
+		{"negative", func(cfg *Config) { cfg.CanaryPercentage = -1 }, true},
+		{"above 100", func(cfg *Config) { cfg.CanaryPercentage = 101 }, true},
+		{"invalid tag", func(cfg *Config) { cfg.CanaryPromoteTag = "bad tag" }, true},
+		{"not nexus", func(cfg *Config) { cfg.Repository = "https://upload.pypi.org/legacy/" }, true},
+		{"with staging", func(cfg *Config) { cfg.NexusStagingDestination = "pypi-releases" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := canary
+			tt.modify(&cfg)
+			if err := validateCanaryConfig(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateCanaryConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
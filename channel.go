@@ -0,0 +1,108 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Release channels reported in the channel output.
+const (
+	channelStable  = "stable"
+	channelRC      = "rc"
+	channelBeta    = "beta"
+	channelAlpha   = "alpha"
+	channelDev     = "dev"
+	channelPost    = "post"
+	channelUnknown = "unknown"
+)
+
+// pep440Pattern matches a version in the normal form returned by normalizeVersion.
+var pep440Pattern = regexp.MustCompile(`^(?:[0-9]+!)?[0-9]+(?:\.[0-9]+)*(?:(a|b|rc)[0-9]+)?(\.post[0-9]+)?(\.dev[0-9]+)?(?:\+[a-z0-9.]+)?$`)
+
+// releaseChannel classifies version by its PEP 440 release segments. A development release is
+// dev even when it is also a pre-release, and a post-release of a pre-release stays a
+// pre-release. Versions that are not PEP 440 are unknown.
+func releaseChannel(version string) string {
+	m := pep440Pattern.FindStringSubmatch(normalizeVersion(version))
+	switch {
+	case m == nil:
+		return channelUnknown
+	case m[3] != "":
+		return channelDev
+	case m[1] == "rc":
+		return channelRC
+	case m[1] == "b":
+		return channelBeta
+	case m[1] == "a":
+		return channelAlpha
+	case m[2] != "":
+		return channelPost
+	}
+	return channelStable
+}
+
+// pep440PartsPattern captures the epoch, release, pre-release, post and dev segments of a version
+// in normal form.
+var pep440PartsPattern = regexp.MustCompile(`^(?:([0-9]+)!)?([0-9]+(?:\.[0-9]+)*)(?:(a|b|rc)([0-9]+))?(?:\.post([0-9]+))?(?:\.dev([0-9]+))?(?:\+[a-z0-9.]+)?$`)
+
+// pep440Version is a parsed PEP 440 version, without its local segment.
+type pep440Version struct {
+	epoch   int
+	release []int
+	// phase orders a dev release of a final version (-4) before its alpha (-3), beta (-2) and
+	// release candidate (-1) pre-releases, which come before the final version (0)
+	phase, pre, post, dev int
+}
+
+// parsePEP440 parses version, or returns false when it is not PEP 440.
+func parsePEP440(version string) (pep440Version, bool) {
+	m := pep440PartsPattern.FindStringSubmatch(normalizeVersion(version))
+	if m == nil {
+		return pep440Version{}, false
+	}
+	num := func(s string, missing int) int {
+		if n, err := strconv.Atoi(s); err == nil {
+			return n
+		}
+		return missing
+	}
+	v := pep440Version{
+		epoch: num(m[1], 0),
+		phase: map[string]int{"a": -3, "b": -2, "rc": -1}[m[3]],
+		pre:   num(m[4], 0),
+		post:  num(m[5], -1),
+		dev:   num(m[6], math.MaxInt),
+	}
+	if m[3] == "" && m[5] == "" && m[6] != "" {
+		v.phase = -4
+	}
+	for _, part := range strings.Split(m[2], ".") {
+		v.release = append(v.release, num(part, 0))
+	}
+	// Trailing zeros do not count: 1.0 == 1.0.0
+	for len(v.release) > 1 && v.release[len(v.release)-1] == 0 {
+		v.release = v.release[:len(v.release)-1]
+	}
+	return v, true
+}
+
+// compareVersions orders two PEP 440 versions, returning -1, 0 or 1. ok is false when either
+// version is not PEP 440.
+func compareVersions(a, b string) (int, bool) {
+	va, okA := parsePEP440(a)
+	vb, okB := parsePEP440(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	if c := cmp.Compare(va.epoch, vb.epoch); c != 0 {
+		return c, true
+	}
+	if c := slices.Compare(va.release, vb.release); c != 0 {
+		return c, true
+	}
+	return slices.Compare([]int{va.phase, va.pre, va.post, va.dev}, []int{vb.phase, vb.pre, vb.post, vb.dev}), true
+}
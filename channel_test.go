@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestReleaseChannel(t *testing.T) {
+	tests := map[string]string{
+		"1.0.0":           channelStable,
+		"v2.1":            channelStable,
+		"1!2.0+local.1":   channelStable,
+		"1.0.0rc1":        channelRC,
+		"1.0.0-rc.2":      channelRC,
+		"1.0.0c1":         channelRC,
+		"1.0.0b2":         channelBeta,
+		"1.0.0-beta.1":    channelBeta,
+		"1.0.0a1":         channelAlpha,
+		"1.0.0-alpha":     channelAlpha,
+		"1.0.0.dev3":      channelDev,
+		"1.0.0rc1.dev1":   channelDev,
+		"1.0.0.post1":     channelPost,
+		"1.0.0a1.post1":   channelAlpha,
+		"":                channelUnknown,
+		"release-2024-01": channelUnknown,
+	}
+	for version, want := range tests {
+		if got := releaseChannel(version); got != want {
+			t.Errorf("releaseChannel(%q) = %q, want %q", version, got, want)
+		}
+	}
+}
+
+func TestExecuteChannelOutput(t *testing.T) {
+	writeDistFiles(t, "mypkg-2.0.0rc1.tar.gz")
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"username": "__token__", "password": "pypi-token"},
+		Context: plugin.ReleaseContext{Version: "v2.0.0-rc.1"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+	if resp.Outputs["channel"] != channelRC {
+		t.Errorf("channel = %v, want %s", resp.Outputs["channel"], channelRC)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	// Each version sorts before the next one
+	ordered := []string{"1.0.dev1", "1.0a1.dev1", "1.0a1", "1.0b2", "1.0rc1", "1.0", "1.0.post1.dev1", "1.0.post1", "1.0.1", "1.2", "1.10", "1!0.1"}
+	for i := 0; i+1 < len(ordered); i++ {
+		if c, ok := compareVersions(ordered[i], ordered[i+1]); !ok || c != -1 {
+			t.Errorf("compareVersions(%q, %q) = %d, %v; want -1", ordered[i], ordered[i+1], c, ok)
+		}
+		if c, ok := compareVersions(ordered[i+1], ordered[i]); !ok || c != 1 {
+			t.Errorf("compareVersions(%q, %q) = %d, %v; want 1", ordered[i+1], ordered[i], c, ok)
+		}
+	}
+	for _, pair := range [][2]string{{"1.0", "1.0.0"}, {"v1.0.0-rc.1", "1.0rc1"}, {"1.0+local", "1.0"}} {
+		if c, ok := compareVersions(pair[0], pair[1]); !ok || c != 0 {
+			t.Errorf("compareVersions(%q, %q) = %d, %v; want 0", pair[0], pair[1], c, ok)
+		}
+	}
+	if _, ok := compareVersions("release-2024", "1.0"); ok {
+		t.Error("expected a version that is not PEP 440 not to compare")
+	}
+}
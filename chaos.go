@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Failure injection modes accepted by the inject_failure option.
+const (
+	failureTimeout = "timeout"
+	failureHTTP500 = "http500"
+	failurePartial = "partial"
+)
+
+// failureModes lists the supported inject_failure values.
+var failureModes = []string{failureTimeout, failureHTTP500, failurePartial}
+
+// faultInjectingExecutor simulates publish failures for pipeline testing.
+// It never runs the requested command, so nothing is uploaded while a failure is injected.
+type faultInjectingExecutor struct {
+	mode       string
+	repository string
+	distPath   string
+}
+
+// newFaultInjectingExecutor creates an executor that fails according to cfg.InjectFailure.
+func newFaultInjectingExecutor(cfg Config) *faultInjectingExecutor {
+	return &faultInjectingExecutor{
+		mode:       cfg.InjectFailure,
+		repository: cfg.Repository,
+		distPath:   cfg.DistPath,
+	}
+}
+
+// RunWithEnv ignores the environment and behaves like Run.
+func (e *faultInjectingExecutor) RunWithEnv(ctx context.Context, _ []string, name string, args ...string) ([]byte, error) {
+	return e.Run(ctx, name, args...)
+}
+
+// Output behaves like Run.
+func (e *faultInjectingExecutor) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return e.Run(ctx, name, args...)
+}
+
+// Run returns the simulated output and error for the configured failure mode.
+func (e *faultInjectingExecutor) Run(_ context.Context, _ string, _ ...string) ([]byte, error) {
+	var out strings.Builder
+	fmt.Fprintf(&out, "Uploading distributions to %s\n", e.repository)
+
+	switch e.mode {
+	case failureTimeout:
+		return []byte(out.String()), fmt.Errorf("injected failure: %w", context.DeadlineExceeded)
+	case failureHTTP500:
+		fmt.Fprintf(&out, "ERROR    HTTPError: 500 Internal Server Error from %s\n", e.repository)
+		return []byte(out.String()), errors.New("injected failure: exit status 1")
+	case failurePartial:
+		uploaded, failed := e.partialFiles()
+		fmt.Fprintf(&out, "Uploading %s\n100%%\n", uploaded)
+		fmt.Fprintf(&out, "Uploading %s\n", failed)
+		fmt.Fprintf(&out, "ERROR    HTTPError: 500 Internal Server Error from %s\n", e.repository)
+		return []byte(out.String()), errors.New("injected failure: exit status 1")
+	default:
+		return nil, fmt.Errorf("unknown failure injection mode: %s", e.mode)
+	}
+}
+
+// partialFiles picks the file names reported as uploaded and failed in partial mode.
+// Real distribution names are used when the dist path matches at least two files.
+func (e *faultInjectingExecutor) partialFiles() (string, string) {
+	matches, err := expandDistGlob(e.distPath)
+	if err == nil && len(matches) >= 2 {
+		return filepath.Base(matches[0]), filepath.Base(matches[1])
+	}
+	return "package.tar.gz", "package.whl"
+}
+
+// validateInjectFailure validates the inject_failure option.
+func validateInjectFailure(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, m := range failureModes {
+		if mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("inject_failure must be one of: %s", strings.Join(failureModes, ", "))
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestFaultInjectingExecutor(t *testing.T) {
+	tests := []struct {
+		name           string
+		mode           string
+		expectDeadline bool
+		expectOutput   string
+	}{
+		{
+			name:           "timeout",
+			mode:           failureTimeout,
+			expectDeadline: true,
+			expectOutput:   "Uploading distributions to http://localhost:8080/",
+		},
+		{
+			name:         "http500",
+			mode:         failureHTTP500,
+			expectOutput: "HTTPError: 500 Internal Server Error",
+		},
+		{
+			name:         "partial",
+			mode:         failurePartial,
+			expectOutput: "Uploading package.tar.gz\n100%",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newFaultInjectingExecutor(Config{
+				InjectFailure: tt.mode,
+				Repository:    "http://localhost:8080/",
+				DistPath:      "nonexistent-dist/*",
+			})
+
+			out, err := e.Run(context.Background(), "twine", "upload")
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := errors.Is(err, context.DeadlineExceeded); got != tt.expectDeadline {
+				t.Errorf("errors.Is(err, DeadlineExceeded) = %v, expected %v", got, tt.expectDeadline)
+			}
+			if !strings.Contains(string(out), tt.expectOutput) {
+				t.Errorf("expected output to contain '%s', got '%s'", tt.expectOutput, string(out))
+			}
+		})
+	}
+}
+
+func TestExecuteInjectFailure(t *testing.T) {
+	for _, mode := range failureModes {
+		t.Run(mode, func(t *testing.T) {
+			writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+			mockExecutor := &MockCommandExecutor{}
+			p := &PyPIPlugin{cmdExecutor: mockExecutor}
+
+			req := plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"username":       "testuser",
+					"password":       "testpass",
+					"repository":     "http://localhost:8080/",
+					"inject_failure": mode,
+				},
+				Context: plugin.ReleaseContext{Version: "v1.0.0"},
+			}
+
+			resp, err := p.Execute(context.Background(), req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp.Success {
+				t.Error("expected injected failure to fail the upload")
+			}
+			if !strings.Contains(resp.Error, "twine upload failed") {
+				t.Errorf("expected error to contain 'twine upload failed', got '%s'", resp.Error)
+			}
+			if resp.Outputs["injected_failure"] != mode {
+				t.Errorf("expected injected_failure output '%s', got '%v'", mode, resp.Outputs["injected_failure"])
+			}
+			if len(mockExecutor.RunCalls) != 0 {
+				t.Errorf("expected twine not to be run, got %d calls", len(mockExecutor.RunCalls))
+			}
+		})
+	}
+}
+
+func TestExecuteInjectFailureDryRun(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	p := &PyPIPlugin{}
+
+	req := plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":       "testuser",
+			"password":       "testpass",
+			"repository":     "http://localhost:8080/",
+			"inject_failure": failureHTTP500,
+		},
+		Context: plugin.ReleaseContext{Version: "v1.0.0"},
+		DryRun:  true,
+	}
+
+	resp, err := p.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !resp.Success {
+		t.Errorf("expected dry run to succeed, got error: %s", resp.Error)
+	}
+	if resp.Outputs["inject_failure"] != failureHTTP500 {
+		t.Errorf("expected inject_failure output '%s', got '%v'", failureHTTP500, resp.Outputs["inject_failure"])
+	}
+}
+
+func TestValidateInjectFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"timeout", "timeout", false},
+		{"http500", "http500", false},
+		{"partial", "partial", false},
+		{"unknown", "http404", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateInjectFailure(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateInjectFailure(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("Validate rejects unknown mode", func(t *testing.T) {
+		p := &PyPIPlugin{}
+		resp, err := p.Validate(context.Background(), map[string]any{
+			"username":       "testuser",
+			"password":       "testpass",
+			"repository":     "http://localhost:8080/",
+			"inject_failure": "http404",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Valid {
+			t.Error("expected invalid config")
+		}
+	})
+}
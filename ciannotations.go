@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// circleCIResultsDir receives the JUnit results of publishes under CircleCI, for a
+// store_test_results step of its parent directory.
+const circleCIResultsDir = "test-results/pypi-publish"
+
+// annotateCI surfaces a publish in the UI of Buildkite or CircleCI, detected by the variables
+// their agents set. Buildkite shows the job summary as a build annotation and keeps the version
+// as build meta-data; CircleCI, which has no annotation API, shows each file as a test result.
+func (p *PyPIPlugin) annotateCI(ctx context.Context, cfg Config, resp *plugin.ExecuteResponse, outcome uploadOutcome, version string, dryRun bool) error {
+	if !cfg.CIAnnotations || len(outcome.files) == 0 {
+		return nil
+	}
+	switch {
+	case os.Getenv("BUILDKITE") == "true":
+		return p.annotateBuildkite(ctx, cfg, resp, outcome, version, dryRun)
+	case os.Getenv("CIRCLECI") == "true":
+		return writeCircleCIResults(cfg, resp, outcome, dryRun)
+	default:
+		return nil
+	}
+}
+
+// annotationProject returns the project of a publish as named in annotations.
+func annotationProject(outcome uploadOutcome) string {
+	if project, _ := distProjectVersion(outcome.files); project != "" {
+		return normalizeProjectName(project)
+	}
+	return normalizeProjectName(strings.SplitN(filepath.Base(outcome.files[0]), "-", 2)[0])
+}
+
+// annotateBuildkite adds the job summary of the publish as a build annotation, one per project
+// so that the packages of a batch do not replace each other's, and records the published
+// version as pypi:<project>:version meta-data for later steps.
+func (p *PyPIPlugin) annotateBuildkite(ctx context.Context, cfg Config, resp *plugin.ExecuteResponse, outcome uploadOutcome, version string, dryRun bool) error {
+	project := annotationProject(outcome)
+	style := "success"
+	switch {
+	case dryRun:
+		style = "info"
+	case !resp.Success:
+		style = "error"
+	}
+	body := jobSummary(cfg, resp, outcome, version, dryRun)
+	if output, err := p.getExecutor().Run(ctx, "buildkite-agent", "annotate", "--style", style, "--context", "pypi-publish-"+project, body); err != nil {
+		return fmt.Errorf("buildkite-agent annotate failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	if !resp.Success || dryRun || version == "" {
+		return nil
+	}
+	if output, err := p.getExecutor().Run(ctx, "buildkite-agent", "meta-data", "set", "pypi:"+project+":version", version); err != nil {
+		return fmt.Errorf("buildkite-agent meta-data set failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// junitTestSuite is the JUnit XML report of a publish.
+type junitTestSuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is one distribution file of a publish.
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeCircleCIResults writes the files of a publish as JUnit test cases to
+// circleCIResultsDir/<project>.xml: uploaded and existing files pass, a failed file carries the
+// error, and files not uploaded, as in dry runs, are skipped.
+func writeCircleCIResults(cfg Config, resp *plugin.ExecuteResponse, outcome uploadOutcome, dryRun bool) error {
+	project := annotationProject(outcome)
+	suite := junitTestSuite{Name: "pypi-publish " + repositoryName(cfg.Repository)}
+	for _, file := range fileOutputs(cfg, outcome) {
+		c := junitTestCase{ClassName: project, Name: file.Name}
+		switch file.Status {
+		case fileFailed:
+			c.Failure = &junitFailure{Message: "upload failed", Text: resp.Error}
+			suite.Failures++
+		case filePending, fileStaged:
+			message := "not uploaded"
+			if dryRun {
+				message = "dry run"
+			} else if file.Status == fileStaged {
+				message = "staged"
+			}
+			c.Skipped = &junitSkipped{Message: message}
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Tests = len(suite.Cases)
+
+	data, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.FromSlash(circleCIResultsDir), 0o750); err != nil {
+		return fmt.Errorf("failed to create %s: %w", circleCIResultsDir, err)
+	}
+	path := filepath.Join(filepath.FromSlash(circleCIResultsDir), project+".xml")
+	if err := os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.ToSlash(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestExecuteBuildkiteAnnotation(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	t.Setenv("BUILDKITE", "true")
+
+	executor := &MockCommandExecutor{ReturnOut: []byte("Uploading mypkg-1.0.0.tar.gz\n")}
+	p := &PyPIPlugin{cmdExecutor: executor}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"username": "__token__", "password": "pypi-token", "repository": "http://localhost:8080/"},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the upload to succeed, got %+v, %v", resp, err)
+	}
+
+	var annotate, metadata []string
+	for _, call := range executor.RunCalls {
+		if call.Name == "buildkite-agent" && call.Args[0] == "annotate" {
+			annotate = call.Args
+		}
+		if call.Name == "buildkite-agent" && call.Args[0] == "meta-data" {
+			metadata = call.Args
+		}
+	}
+	if len(annotate) != 6 || strings.Join(annotate[:5], " ") != "annotate --style success --context pypi-publish-mypkg" || !strings.Contains(annotate[5], "| `mypkg-1.0.0.tar.gz` |") {
+		t.Errorf("unexpected annotation %q", annotate)
+	}
+	if strings.Join(metadata, " ") != "meta-data set pypi:mypkg:version 1.0.0" {
+		t.Errorf("unexpected meta-data %q", metadata)
+	}
+
+	// An agent failure is reported without failing the publish
+	p.cmdExecutor = &MockCommandExecutor{RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "buildkite-agent" {
+			return []byte("agent access token missing"), errors.New("exit status 1")
+		}
+		return []byte("Uploading mypkg-1.0.0.tar.gz\n"), nil
+	}}
+	resp, _ = p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"username": "__token__", "password": "pypi-token", "repository": "http://localhost:8080/"},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if msg, _ := resp.Outputs["ci_annotations_error"].(string); !resp.Success || !strings.Contains(msg, "agent access token missing") {
+		t.Errorf("expected a reported annotation failure, got %+v", resp)
+	}
+}
+
+func TestCircleCIResults(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz", "mypkg-1.0.0-py3-none-any.whl")
+	t.Setenv("CIRCLECI", "true")
+
+	cfg := Config{Repository: "http://localhost:8080/", CIAnnotations: true}
+	outcome := uploadOutcome{
+		files:     []string{filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), filepath.Join("dist", "mypkg-1.0.0.tar.gz")},
+		attempted: true,
+		failed:    true,
+		output:    "Uploading mypkg-1.0.0-py3-none-any.whl\nUploading mypkg-1.0.0.tar.gz\n",
+	}
+	resp := &plugin.ExecuteResponse{Error: "twine upload failed: 400 <Bad Request>"}
+	if err := (&PyPIPlugin{}).annotateCI(context.Background(), cfg, resp, outcome, "1.0.0", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join("test-results", "pypi-publish", "mypkg.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<testsuite name="pypi-publish localhost" tests="2" failures="1" skipped="0">`,
+		`<testcase classname="mypkg" name="mypkg-1.0.0-py3-none-any.whl"></testcase>`,
+		`<failure message="upload failed">twine upload failed: 400 &lt;Bad Request&gt;</failure>`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("results lack %s:\n%s", want, data)
+		}
+	}
+
+	// Disabled, nothing is written
+	_ = os.RemoveAll("test-results")
+	cfg.CIAnnotations = false
+	if err := (&PyPIPlugin{}).annotateCI(context.Background(), cfg, resp, outcome, "1.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("test-results"); !os.IsNotExist(err) {
+		t.Errorf("expected no results with ci_annotations disabled, got %v", err)
+	}
+}
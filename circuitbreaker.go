@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Circuit breaker defaults.
+const (
+	defaultCircuitBreakerThreshold = 3
+	maxCircuitBreakerThreshold     = 100
+)
+
+// errRepositoryUnhealthy is returned for uploads skipped because the circuit is open.
+var errRepositoryUnhealthy = errors.New("repository unhealthy")
+
+// serverErrorPattern matches the HTTP 5xx errors reported by twine.
+var serverErrorPattern = regexp.MustCompile(`HTTPError: 5\d\d\b`)
+
+// isServerError reports whether upload output indicates a server-side (5xx) failure.
+func isServerError(output string) bool {
+	return serverErrorPattern.MatchString(output)
+}
+
+// circuitBreaker tracks server errors across the uploads of a run. A repository's circuit
+// opens after threshold consecutive server errors from it, and the global circuit opens after
+// globalThreshold server errors across all repositories. Uploads through an open circuit fail
+// immediately. A zero threshold disables that circuit.
+type circuitBreaker struct {
+	threshold       int
+	globalThreshold int
+
+	mu          sync.Mutex
+	consecutive map[string]int
+	open        map[string]bool
+	total       int
+}
+
+// newCircuitBreaker creates a breaker with the thresholds of cfg.
+func newCircuitBreaker(cfg Config) *circuitBreaker {
+	return &circuitBreaker{
+		threshold:       cfg.CircuitBreakerThreshold,
+		globalThreshold: cfg.CircuitBreakerGlobalThreshold,
+		consecutive:     make(map[string]int),
+		open:            make(map[string]bool),
+	}
+}
+
+// allow returns an error wrapping errRepositoryUnhealthy if uploads to repository must be skipped.
+func (b *circuitBreaker) allow(repository string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.globalThreshold > 0 && b.total >= b.globalThreshold {
+		return fmt.Errorf("%w: %d server errors across all repositories, remaining uploads aborted", errRepositoryUnhealthy, b.total)
+	}
+	if b.open[repository] {
+		return fmt.Errorf("%w: %s returned %d consecutive server errors, remaining uploads aborted", errRepositoryUnhealthy, repository, b.consecutive[repository])
+	}
+	return nil
+}
+
+// record updates the circuits with the outcome of an upload to repository.
+func (b *circuitBreaker) record(repository, output string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case err == nil:
+		b.consecutive[repository] = 0
+	case isServerError(output):
+		b.consecutive[repository]++
+		b.total++
+		if b.threshold > 0 && b.consecutive[repository] >= b.threshold {
+			b.open[repository] = true
+		}
+	}
+}
+
+// unhealthy returns the repositories whose circuit is open, sorted. When the global circuit
+// is open every repository that returned a server error is included.
+func (b *circuitBreaker) unhealthy() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	globalOpen := b.globalThreshold > 0 && b.total >= b.globalThreshold
+
+	var repos []string
+	for repo, n := range b.consecutive {
+		if b.open[repo] || (globalOpen && n > 0) {
+			repos = append(repos, repo)
+		}
+	}
+	sort.Strings(repos)
+	return repos
+}
+
+// wrap returns an executor whose commands upload to repository through the breaker.
+func (b *circuitBreaker) wrap(executor CommandExecutor, repository string) CommandExecutor {
+	return &breakerExecutor{inner: executor, breaker: b, repository: repository}
+}
+
+// breakerExecutor runs upload commands unless the repository's circuit is open.
+type breakerExecutor struct {
+	inner      CommandExecutor
+	breaker    *circuitBreaker
+	repository string
+}
+
+// Run runs the command and records its outcome, or fails fast while the circuit is open.
+func (e *breakerExecutor) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return e.RunWithEnv(ctx, nil, name, args...)
+}
+
+// RunWithEnv is Run with additional environment variables.
+func (e *breakerExecutor) RunWithEnv(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	if err := e.breaker.allow(e.repository); err != nil {
+		return nil, err
+	}
+	out, err := e.inner.RunWithEnv(ctx, env, name, args...)
+	e.breaker.record(e.repository, string(out), err)
+	return out, err
+}
+
+// Output is Run returning standard output only.
+func (e *breakerExecutor) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if err := e.breaker.allow(e.repository); err != nil {
+		return nil, err
+	}
+	out, err := commandOutput(ctx, e.inner, name, args...)
+	e.breaker.record(e.repository, string(out), err)
+	return out, err
+}
+
+// validateCircuitBreakerConfig validates the circuit breaker thresholds.
+func validateCircuitBreakerConfig(cfg Config) error {
+	if cfg.CircuitBreakerThreshold < 0 || cfg.CircuitBreakerThreshold > maxCircuitBreakerThreshold {
+		return fmt.Errorf("circuit_breaker_threshold must be between 0 and %d", maxCircuitBreakerThreshold)
+	}
+	if cfg.CircuitBreakerGlobalThreshold < 0 || cfg.CircuitBreakerGlobalThreshold > maxCircuitBreakerThreshold {
+		return fmt.Errorf("circuit_breaker_global_threshold must be between 0 and %d", maxCircuitBreakerThreshold)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(Config{CircuitBreakerThreshold: 2})
+	fail := errors.New("exit status 1")
+	const repo = "https://pypi.example.com/"
+
+	b.record(repo, "HTTPError: 503 Service Unavailable", fail)
+	b.record(repo, "", nil)
+	b.record(repo, "HTTPError: 502 Bad Gateway", fail)
+	if err := b.allow(repo); err != nil {
+		t.Fatalf("a success must reset the consecutive count: %v", err)
+	}
+
+	b.record(repo, "HTTPError: 400 Bad Request", fail)
+	b.record(repo, "HTTPError: 500 Internal Server Error", fail)
+	err := b.allow(repo)
+	if !errors.Is(err, errRepositoryUnhealthy) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+	if b.allow("https://other.example.com/") != nil {
+		t.Error("other repositories must not be affected")
+	}
+	if got := b.unhealthy(); !reflect.DeepEqual(got, []string{repo}) {
+		t.Errorf("unhealthy() = %v", got)
+	}
+}
+
+func TestCircuitBreakerGlobal(t *testing.T) {
+	b := newCircuitBreaker(Config{CircuitBreakerGlobalThreshold: 2})
+	fail := errors.New("exit status 1")
+
+	b.record("https://a.example.com/", "HTTPError: 500 Internal Server Error", fail)
+	if b.allow("https://c.example.com/") != nil {
+		t.Fatal("global circuit opened too early")
+	}
+	b.record("https://b.example.com/", "HTTPError: 504 Gateway Timeout", fail)
+	if err := b.allow("https://c.example.com/"); !errors.Is(err, errRepositoryUnhealthy) {
+		t.Errorf("expected global circuit to be open, got %v", err)
+	}
+	if got := b.unhealthy(); len(got) != 2 {
+		t.Errorf("expected both failing repositories reported, got %v", got)
+	}
+}
+
+func TestExecuteBatchCircuitBreaker(t *testing.T) {
+	packages := writeBatchDists(t, "a", "b", "c", "d", "e")
+
+	exec := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("ERROR    HTTPError: 503 Service Unavailable"), errors.New("exit status 1")
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: exec}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":          "__token__",
+			"password":          "pypi-token",
+			"repository":        "http://localhost:8080/",
+			"batch_concurrency": 1,
+			"packages":          packages,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(exec.RunCalls) != defaultCircuitBreakerThreshold {
+		t.Errorf("expected %d upload attempts before the circuit opened, got %d", defaultCircuitBreakerThreshold, len(exec.RunCalls))
+	}
+	results, _ := resp.Outputs["packages"].([]batchPackageResult)
+	if len(results) != 5 || !strings.HasPrefix(results[4].Error, "repository unhealthy") {
+		t.Errorf("expected remaining packages to be aborted, got %+v", results)
+	}
+	if results[4].Outputs["repository_status"] != "unhealthy" {
+		t.Errorf("expected unhealthy repository status, got %v", results[4].Outputs)
+	}
+	if got := resp.Outputs["unhealthy_repositories"]; !reflect.DeepEqual(got, []string{"http://localhost:8080/"}) {
+		t.Errorf("unexpected unhealthy_repositories: %v", got)
+	}
+}
+
+func TestValidateCircuitBreakerConfig(t *testing.T) {
+	p := &PyPIPlugin{}
+	for _, raw := range []map[string]any{
+		{"circuit_breaker_threshold": -1},
+		{"circuit_breaker_global_threshold": 1000},
+	} {
+		if err := validateCircuitBreakerConfig(p.parseConfig(raw)); err == nil {
+			t.Errorf("expected error for %v", raw)
+		}
+	}
+	if err := validateCircuitBreakerConfig(p.parseConfig(map[string]any{"circuit_breaker_threshold": 0})); err != nil {
+		t.Errorf("disabling the circuit breaker must be valid: %v", err)
+	}
+}
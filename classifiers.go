@@ -0,0 +1,281 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// knownPythonMinors are the Python 3 minor versions considered for version classifiers.
+var knownPythonMinors = []int{6, 7, 8, 9, 10, 11, 12, 13, 14}
+
+// frameworkClassifiers maps normalized dependency names to the framework classifier they imply.
+var frameworkClassifiers = map[string]string{
+	"aiohttp":    "Framework :: aiohttp",
+	"celery":     "Framework :: Celery",
+	"dash":       "Framework :: Dash",
+	"django":     "Framework :: Django",
+	"fastapi":    "Framework :: FastAPI",
+	"flask":      "Framework :: Flask",
+	"ipython":    "Framework :: IPython",
+	"jupyterlab": "Framework :: Jupyter :: JupyterLab",
+	"matplotlib": "Framework :: Matplotlib",
+	"pydantic":   "Framework :: Pydantic",
+	"pyramid":    "Framework :: Pyramid",
+	"scrapy":     "Framework :: Scrapy",
+	"sphinx":     "Framework :: Sphinx",
+	"sqlalchemy": "Framework :: SQLAlchemy",
+	"streamlit":  "Framework :: Streamlit",
+	"trio":       "Framework :: Trio",
+	"twisted":    "Framework :: Twisted",
+	"wagtail":    "Framework :: Wagtail",
+}
+
+// entryPointFrameworks maps entry point groups to the framework classifier they imply.
+var entryPointFrameworks = map[string]string{
+	"pytest11":         "Framework :: Pytest",
+	"flake8.extension": "Framework :: Flake8",
+	"sphinx.builders":  "Framework :: Sphinx :: Extension",
+	"mkdocs.plugins":   "Framework :: MkDocs",
+	"napari.manifest":  "Framework :: napari",
+	"tox":              "Framework :: tox",
+}
+
+// distInsights is what classifier suggestions are derived from.
+type distInsights struct {
+	meta        *packageMetadata
+	entryPoints map[string][]entryPoint
+	typed       bool
+}
+
+// inspectDists reads the metadata of the first readable distribution, preferring wheels,
+// together with the wheel's entry points and PEP 561 marker.
+func inspectDists(files []string) (*distInsights, error) {
+	ordered := make([]string, 0, len(files))
+	for _, f := range files {
+		if strings.HasSuffix(f, ".whl") {
+			ordered = append(ordered, f)
+		}
+	}
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".whl") {
+			ordered = append(ordered, f)
+		}
+	}
+
+	var lastErr error
+	for _, f := range ordered {
+		meta, err := readDistMetadata(f)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		insights := &distInsights{meta: meta, entryPoints: map[string][]entryPoint{}}
+		if strings.HasSuffix(f, ".whl") {
+			if eps, err := wheelEntryPoints(f); err == nil {
+				insights.entryPoints = eps
+			}
+			insights.typed = wheelHasPyTyped(f)
+		}
+		return insights, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no distribution files found")
+	}
+	return nil, lastErr
+}
+
+// wheelHasPyTyped reports whether a wheel ships a py.typed marker.
+func wheelHasPyTyped(wheelPath string) bool {
+	zr, err := zip.OpenReader(wheelPath)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = zr.Close() }()
+	for _, f := range zr.File {
+		if f.Name == "py.typed" || strings.HasSuffix(f.Name, "/py.typed") {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestClassifiers returns trove classifiers implied by the metadata that the
+// distribution does not declare yet.
+func suggestClassifiers(insights *distInsights) []string {
+	meta := insights.meta
+	declared := map[string]bool{}
+	for _, c := range meta.Classifiers {
+		declared[c] = true
+	}
+	// A classifier counts as declared when it or a more specific child is present
+	hasClassifier := func(c string) bool {
+		if declared[c] {
+			return true
+		}
+		for d := range declared {
+			if strings.HasPrefix(d, c+" :: ") {
+				return true
+			}
+		}
+		return false
+	}
+
+	suggested := map[string]bool{}
+	suggest := func(c string) {
+		if c != "" && !hasClassifier(c) {
+			suggested[c] = true
+		}
+	}
+
+	// Python versions
+	if minors, ok := supportedPythonMinors(meta.RequiresPython); ok && len(minors) > 0 {
+		suggest("Programming Language :: Python :: 3")
+		suggest("Programming Language :: Python :: 3 :: Only")
+		for _, minor := range minors {
+			suggest(fmt.Sprintf("Programming Language :: Python :: 3.%d", minor))
+		}
+	}
+
+	// License classifiers are deprecated once a License-Expression is declared (PEP 639)
+	if meta.LicenseExpression == "" {
+		if c := licenseClassifier(metadataLicense(meta)); c != "" && !hasClassifier("License") {
+			suggest(c)
+		}
+	}
+
+	// Frameworks from dependencies and entry points
+	for _, line := range meta.RequiresDist {
+		r, ok := parseRequirement(line)
+		if !ok || strings.Contains(r.Marker, "extra") {
+			continue
+		}
+		suggest(frameworkClassifiers[normalizeProjectName(r.Name)])
+	}
+	for group := range insights.entryPoints {
+		suggest(entryPointFrameworks[group])
+	}
+
+	if insights.typed {
+		suggest("Typing :: Typed")
+	}
+
+	result := make([]string, 0, len(suggested))
+	for c := range suggested {
+		result = append(result, c)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// classifierSuggestions returns the classifiers suggested for the distributions, or none when
+// their metadata cannot be read.
+func classifierSuggestions(files []string) []string {
+	insights, err := inspectDists(files)
+	if err != nil {
+		return nil
+	}
+	return suggestClassifiers(insights)
+}
+
+// licenseClassifier returns the trove classifier for an SPDX license identifier.
+func licenseClassifier(id string) string {
+	best := ""
+	for classifier, spdx := range classifierLicenses {
+		// Several classifiers can map to one identifier; prefer the shortest, canonical one
+		if spdx == id && (best == "" || len(classifier) < len(best)) {
+			best = classifier
+		}
+	}
+	return best
+}
+
+// supportedPythonMinors evaluates a Requires-Python specifier against the known Python 3
+// minor versions. It returns false when the specifier cannot be interpreted or allows Python 2.
+func supportedPythonMinors(spec string) ([]int, bool) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, false
+	}
+
+	type clause struct {
+		op    string
+		major int
+		minor int
+		// majorOnly is set for "==3.*" style clauses that match a whole major version
+		majorOnly bool
+	}
+	var clauses []clause
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		op := strings.TrimRight(part[:len(part)-len(strings.TrimLeft(part, "<>=!~"))], " ")
+		version := strings.TrimSpace(strings.TrimLeft(part, "<>=!~"))
+		wildcard := strings.HasSuffix(version, ".*")
+		fields := strings.Split(strings.TrimSuffix(version, ".*"), ".")
+		major, err := strconv.Atoi(fields[0])
+		if err != nil || op == "" {
+			return nil, false
+		}
+		minor := 0
+		if len(fields) > 1 {
+			if minor, err = strconv.Atoi(fields[1]); err != nil {
+				return nil, false
+			}
+		}
+		clauses = append(clauses, clause{op: op, major: major, minor: minor, majorOnly: wildcard && len(fields) == 1})
+	}
+
+	cmp := func(major, minor int, c clause) int {
+		if major != c.major || c.majorOnly {
+			return major - c.major
+		}
+		return minor - c.minor
+	}
+	allows := func(major, minor int) bool {
+		for _, c := range clauses {
+			d := cmp(major, minor, c)
+			switch c.op {
+			case ">=", "~=":
+				if d < 0 {
+					return false
+				}
+			case ">":
+				if d <= 0 {
+					return false
+				}
+			case "<":
+				if d >= 0 {
+					return false
+				}
+			case "<=":
+				if d > 0 {
+					return false
+				}
+			case "==":
+				if d != 0 {
+					return false
+				}
+			case "!=":
+				if d == 0 {
+					return false
+				}
+			default:
+				return false
+			}
+		}
+		return true
+	}
+
+	if allows(2, 7) {
+		return nil, false
+	}
+	var minors []int
+	for _, minor := range knownPythonMinors {
+		if allows(3, minor) {
+			minors = append(minors, minor)
+		}
+	}
+	return minors, true
+}
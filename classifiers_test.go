@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestSupportedPythonMinors(t *testing.T) {
+	tests := []struct {
+		spec   string
+		want   []int
+		wantOK bool
+	}{
+		{">=3.10", []int{10, 11, 12, 13, 14}, true},
+		{">=3.8, <3.11", []int{8, 9, 10}, true},
+		{">3.11", []int{12, 13, 14}, true},
+		{"~=3.12", []int{12, 13, 14}, true},
+		{">=3.9,!=3.10.*,<=3.11", []int{9, 11}, true},
+		{"==3.*", []int{6, 7, 8, 9, 10, 11, 12, 13, 14}, true},
+		{">=2.7", nil, false},
+		{"", nil, false},
+		{"latest", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, ok := supportedPythonMinors(tt.spec)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("supportedPythonMinors(%q) = %v, %v; want %v, %v", tt.spec, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSuggestClassifiers(t *testing.T) {
+	insights := &distInsights{
+		meta: &packageMetadata{
+			RequiresPython: ">=3.12",
+			License:        "MIT",
+			RequiresDist:   []string{"Django>=4.2", "pytest; extra == \"test\""},
+			Classifiers: []string{
+				"Programming Language :: Python :: 3.12",
+				"Framework :: Django :: 4.2",
+			},
+		},
+		entryPoints: map[string][]entryPoint{"pytest11": {{Name: "p", Target: "p"}}},
+		typed:       true,
+	}
+
+	want := []string{
+		"Framework :: Pytest",
+		"License :: OSI Approved :: MIT License",
+		"Programming Language :: Python :: 3",
+		"Programming Language :: Python :: 3 :: Only",
+		"Programming Language :: Python :: 3.13",
+		"Programming Language :: Python :: 3.14",
+		"Typing :: Typed",
+	}
+	if got := suggestClassifiers(insights); !reflect.DeepEqual(got, want) {
+		t.Errorf("suggestClassifiers() = %v, want %v", got, want)
+	}
+
+	// PEP 639 license expressions replace license classifiers
+	insights.meta.LicenseExpression = "MIT"
+	for _, c := range suggestClassifiers(insights) {
+		if c == "License :: OSI Approved :: MIT License" {
+			t.Error("license classifier must not be suggested alongside License-Expression")
+		}
+	}
+}
+
+func TestClassifierSuggestionsInDryRunAndValidate(t *testing.T) {
+	distPath := writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "app-1.0-py3-none-any.whl"), map[string]string{
+		"app/__init__.py": "",
+		"app/py.typed":    "",
+		"app-1.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: app\nVersion: 1.0\nRequires-Python: >=3.13\n" +
+			"Classifier: Programming Language :: Python :: 3 :: Only\nClassifier: Programming Language :: Python :: 3.14\n",
+	})
+	config := map[string]any{
+		"username":   "__token__",
+		"password":   "pypi-token",
+		"repository": "http://localhost:8080/",
+		"dist_path":  distPath,
+	}
+	p := &PyPIPlugin{}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config, DryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"Programming Language :: Python :: 3.13", "Typing :: Typed"}
+	if got := resp.Outputs["suggested_classifiers"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("suggested_classifiers = %v, want %v", got, want)
+	}
+	if warnings, _ := resp.Outputs["warnings"].([]string); len(warnings) != 2 {
+		t.Errorf("expected 2 warnings, got %v", resp.Outputs["warnings"])
+	}
+
+	vresp, err := p.Validate(context.Background(), config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !vresp.Valid {
+		t.Errorf("suggestions must not invalidate the config: %+v", vresp.Errors)
+	}
+	if len(vresp.Errors) != 2 || vresp.Errors[0].Code != validationWarningCode || vresp.Errors[0].Field != "classifiers" {
+		t.Errorf("expected classifier warnings, got %+v", vresp.Errors)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// clientCertSource provides the client certificate presented in TLS handshakes.
+type clientCertSource interface {
+	certificate() (*tls.Certificate, error)
+}
+
+// usesClientCert reports whether uploads authenticate with a client certificate.
+func usesClientCert(cfg Config) bool {
+	return cfg.ClientCert != "" || cfg.SpiffeWorkloadAPI
+}
+
+// newClientCertSource returns the configured client certificate source, or nil.
+func newClientCertSource(cfg Config) clientCertSource {
+	switch {
+	case cfg.SpiffeWorkloadAPI:
+		return newSpiffeSource(cfg)
+	case cfg.ClientCert != "":
+		return newCertReloader(cfg.ClientCert, cfg.ClientKey)
+	default:
+		return nil
+	}
+}
+
+// certReloader serves a client certificate from PEM files and loads it again whenever the
+// files change or the loaded certificate has expired, so certificates rotated by cert-manager
+// or SPIFFE agents during a long publish are picked up by the next TLS handshake.
+type certReloader struct {
+	certPath string
+	// keyPath is the private key file, or certPath when the key is bundled with the certificate
+	keyPath string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	stamp   string
+	reloads int
+	now     func() time.Time
+}
+
+// newCertReloader creates a reloader for the certificate (and key) files.
+func newCertReloader(certPath, keyPath string) *certReloader {
+	if keyPath == "" {
+		keyPath = certPath
+	}
+	return &certReloader{certPath: certPath, keyPath: keyPath, now: time.Now}
+}
+
+// fileStamp identifies the current version of the files by size and modification time.
+func (r *certReloader) fileStamp() (string, error) {
+	var stamp string
+	for _, path := range []string{r.certPath, r.keyPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read client certificate: %w", err)
+		}
+		stamp += fmt.Sprintf("%d:%d;", info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp, nil
+}
+
+// certificate returns the current client certificate, reloading it if needed.
+func (r *certReloader) certificate() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stamp, err := r.fileStamp()
+	if err != nil {
+		return nil, err
+	}
+	if r.cert != nil && stamp == r.stamp && (r.cert.Leaf == nil || r.now().Before(r.cert.Leaf.NotAfter)) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	if cert.Leaf == nil && len(cert.Certificate) > 0 {
+		// Go < 1.23 does not populate Leaf, which the expiry check relies on
+		cert.Leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+	}
+	if r.cert != nil {
+		r.reloads++
+	}
+	r.cert, r.stamp = &cert, stamp
+	return r.cert, nil
+}
+
+// reloadCount returns how many times a changed certificate was loaded.
+func (r *certReloader) reloadCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloads
+}
+
+// uploadHTTPClient returns the HTTP client for uploads, applying the upload timeouts and
+// transport options and presenting the configured client certificate if any.
+func (p *PyPIPlugin) uploadHTTPClient(cfg Config) *http.Client {
+	client := clientWithTransportOptions(clientWithTimeouts(p.getHTTPClient(), cfg), cfg)
+	source := newClientCertSource(cfg)
+	if source == nil {
+		return client
+	}
+	return clientWithCertificate(client, source)
+}
+
+// serverTrustSource is implemented by client certificate sources that also supply the trust
+// anchors of the servers, such as SPIFFE trust bundles.
+type serverTrustSource interface {
+	configureServerTrust(config *tls.Config)
+}
+
+// clientWithCertificate returns a copy of client presenting the source's certificate, and
+// verifying servers with the source when it supplies trust anchors.
+func clientWithCertificate(client *http.Client, source clientCertSource) *http.Client {
+	base, ok := client.Transport.(*http.Transport)
+	if !ok || base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	transport.TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return source.certificate()
+	}
+	if trust, ok := source.(serverTrustSource); ok {
+		trust.configureServerTrust(transport.TLSClientConfig)
+	}
+
+	withCert := *client
+	withCert.Transport = transport
+	return &withCert
+}
+
+// twineClientCert returns the --client-cert file for a twine invocation. twine expects the key
+// and certificate in one file, so separate files and SPIFFE SVIDs are written to a temporary
+// file that the returned cleanup removes. Reading the certificate per invocation picks up
+// rotated certificates.
+func twineClientCert(cfg Config) (string, func(), error) {
+	if cfg.SpiffeWorkloadAPI {
+		cert, err := newSpiffeSource(cfg).certificate()
+		if err != nil {
+			return "", nil, err
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to encode SVID key: %w", err)
+		}
+		bundle := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+		for _, der := range cert.Certificate {
+			bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+		}
+		return writeTempPEM(bundle)
+	}
+	if cfg.ClientKey == "" {
+		return cfg.ClientCert, func() {}, nil
+	}
+
+	cert, err := os.ReadFile(cfg.ClientCert)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	key, err := os.ReadFile(cfg.ClientKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read client key: %w", err)
+	}
+
+	return writeTempPEM(append(append(key, '\n'), cert...))
+}
+
+// writeTempPEM writes a key and certificate bundle to a private temporary file.
+func writeTempPEM(bundle []byte) (string, func(), error) {
+	f, err := os.CreateTemp("", "relicta-pypi-client-*.pem")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create client certificate file: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(f.Name()) }
+	_, err = f.Write(bundle)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write client certificate file: %w", err)
+	}
+	return f.Name(), cleanup, nil
+}
+
+// validateClientCertConfig validates the client certificate options.
+func validateClientCertConfig(cfg Config) error {
+	if cfg.ClientKey != "" && cfg.ClientCert == "" {
+		return fmt.Errorf("client_key requires client_cert")
+	}
+	return nil
+}
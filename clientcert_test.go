@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestClientCert writes a self-signed certificate and its key as PEM files and returns
+// their paths.
+func writeTestClientCert(t *testing.T, dir, commonName string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	// Make the rotation visible even on filesystems with coarse timestamps
+	stamp := time.Now().Add(time.Duration(len(commonName)) * time.Second)
+	_ = os.Chtimes(certPath, stamp, stamp)
+	return certPath, keyPath
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestClientCert(t, dir, "first")
+	r := newCertReloader(certPath, keyPath)
+
+	cert, err := r.certificate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cert.Leaf.Subject.CommonName != "first" {
+		t.Errorf("unexpected certificate: %s", cert.Leaf.Subject.CommonName)
+	}
+	if again, _ := r.certificate(); again != cert || r.reloadCount() != 0 {
+		t.Error("unchanged files must not be reloaded")
+	}
+
+	writeTestClientCert(t, dir, "rotated")
+	cert, err = r.certificate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cert.Leaf.Subject.CommonName != "rotated" || r.reloadCount() != 1 {
+		t.Errorf("expected rotated certificate, got %s after %d reloads", cert.Leaf.Subject.CommonName, r.reloadCount())
+	}
+}
+
+func TestNativeUploadRotatedClientCert(t *testing.T) {
+	writeDistFiles(t)
+	for _, name := range []string{"mypkg-1.0.0-py3-none-any.whl", "mypkg-1.0.0-py2-none-any.whl"} {
+		writeTestWheel(t, filepath.Join("dist", name), map[string]string{
+			"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+		})
+	}
+	certDir := t.TempDir()
+	certPath, keyPath := writeTestClientCert(t, certDir, "first")
+
+	var subjects []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subjects = append(subjects, r.TLS.PeerCertificates[0].Subject.CommonName)
+		_, _ = io.Copy(io.Discard, r.Body)
+		// Force a new handshake for the next file
+		w.Header().Set("Connection", "close")
+		if len(subjects) == 1 {
+			writeTestClientCert(t, certDir, "rotated")
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	cfg := p.parseConfig(map[string]any{
+		"repository":  server.URL,
+		"password":    "token",
+		"auth_scheme": "bearer",
+		"client_cert": certPath,
+		"client_key":  keyPath,
+	})
+	if _, err := p.runNativeUploads(context.Background(), cfg, newPublishSession(cfg), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(subjects, ",") != "first,rotated" {
+		t.Errorf("expected the rotated certificate for the second file, got %v", subjects)
+	}
+}
+
+func TestRunTwineUploadsClientCert(t *testing.T) {
+	distPath := writeDistFiles(t, "pkg-1.0.0.tar.gz", "pkg-1.0.0-py3-none-any.whl")
+	certPath, keyPath := writeTestClientCert(t, t.TempDir(), "client")
+
+	var bundles []string
+	mockExecutor := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			for i, arg := range args {
+				if arg == "--client-cert" {
+					data, err := os.ReadFile(args[i+1])
+					if err != nil {
+						t.Errorf("client certificate file missing during upload: %v", err)
+					}
+					bundles = append(bundles, args[i+1])
+					if !strings.Contains(string(data), "PRIVATE KEY") || !strings.Contains(string(data), "CERTIFICATE") {
+						t.Errorf("expected key and certificate in one file, got:\n%s", data)
+					}
+				}
+			}
+			return []byte("ok\n"), nil
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor}
+	cfg := p.parseConfig(map[string]any{
+		"repository":  "http://localhost:8080/",
+		"dist_path":   distPath,
+		"client_cert": certPath,
+		"client_key":  keyPath,
+	})
+
+	if _, err := p.runTwineUploads(context.Background(), cfg, mockExecutor, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bundles) != 2 {
+		t.Fatalf("expected one twine call per file with a client certificate, got %d", len(bundles))
+	}
+	for _, b := range bundles {
+		if _, err := os.Stat(b); !os.IsNotExist(err) {
+			t.Errorf("combined certificate file %s must be removed after the upload", b)
+		}
+	}
+}
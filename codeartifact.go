@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CodeArtifact uploads authenticate as this user with an authorization token as the password.
+const codeArtifactUsername = "aws"
+
+// codeArtifactService is the SigV4 signing name of the CodeArtifact API.
+const codeArtifactService = "codeartifact"
+
+// awsAccountIDPattern matches the 12-digit AWS account ID owning a CodeArtifact domain.
+var awsAccountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+// CodeArtifact identifies an AWS CodeArtifact repository. Its upload URL is derived from the
+// domain, and authorization tokens are obtained with the runner's AWS credentials, replacing
+// a scripted aws codeartifact get-authorization-token.
+type CodeArtifact struct {
+	// Domain is the CodeArtifact domain of the repository
+	Domain string
+	// DomainOwner is the AWS account ID owning the domain
+	DomainOwner string
+	// Region is the region of the domain (defaults to aws_region)
+	Region string
+	// Repository is the repository name within the domain
+	Repository string
+	// Endpoint overrides the CodeArtifact API endpoint, such as a VPC endpoint
+	Endpoint string
+}
+
+// parseCodeArtifact parses the codeartifact block, or returns nil when it is absent.
+func parseCodeArtifact(raw any) *CodeArtifact {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil
+	}
+	c := &CodeArtifact{}
+	c.Domain, _ = m["domain"].(string)
+	c.Region, _ = m["region"].(string)
+	c.Repository, _ = m["repository"].(string)
+	c.Endpoint, _ = m["endpoint"].(string)
+	// An unquoted account ID is decoded as a number
+	switch owner := m["domain_owner"].(type) {
+	case string:
+		c.DomainOwner = owner
+	case float64:
+		c.DomainOwner = strconv.FormatFloat(owner, 'f', 0, 64)
+	case int:
+		c.DomainOwner = strconv.Itoa(owner)
+	case int64:
+		c.DomainOwner = strconv.FormatInt(owner, 10)
+	}
+	return c
+}
+
+// repositoryURL returns the PyPI upload endpoint of the repository.
+func (c *CodeArtifact) repositoryURL() string {
+	return fmt.Sprintf("https://%s-%s.d.codeartifact.%s.amazonaws.com/pypi/%s/", c.Domain, c.DomainOwner, c.Region, url.PathEscape(c.Repository))
+}
+
+// apiEndpoint returns the CodeArtifact API endpoint of the domain's region.
+func (c *CodeArtifact) apiEndpoint() string {
+	if c.Endpoint != "" {
+		return strings.TrimSuffix(c.Endpoint, "/")
+	}
+	return "https://codeartifact." + c.Region + ".amazonaws.com"
+}
+
+// codeArtifactTokenSource obtains CodeArtifact authorization tokens through the
+// GetAuthorizationToken API, signed with the runner's AWS credentials.
+type codeArtifactTokenSource struct {
+	client   *http.Client
+	settings *CodeArtifact
+	now      func() time.Time
+}
+
+// fetch requests an authorization token of the domain. Its expiry is the one reported by
+// CodeArtifact, bounded by the lifetime of the runner's AWS session.
+func (s *codeArtifactTokenSource) fetch(ctx context.Context) (credential, error) {
+	creds, err := loadAWSCredentials()
+	if err != nil {
+		return credential{}, err
+	}
+	query := url.Values{"domain": {s.settings.Domain}, "domain-owner": {s.settings.DomainOwner}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.settings.apiEndpoint()+"/v1/authorization-token?"+query.Encode(), nil)
+	if err != nil {
+		return credential{}, fmt.Errorf("failed to create codeartifact request: %w", err)
+	}
+	emptyHash := sha256.Sum256(nil)
+	signSigV4(req, hex.EncodeToString(emptyHash[:]), creds, s.settings.Region, codeArtifactService, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return credential{}, fmt.Errorf("codeartifact request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIntegrationResponseSize))
+	if err != nil {
+		return credential{}, fmt.Errorf("codeartifact request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := truncateOutput(strings.TrimSpace(string(body)), defaultMaxErrorBodyBytes)
+		return credential{}, fmt.Errorf("codeartifact request failed: %s: %s", resp.Status, detail)
+	}
+	var result struct {
+		AuthorizationToken string `json:"authorizationToken"`
+		// Expiration is in epoch seconds
+		Expiration float64 `json:"expiration"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.AuthorizationToken == "" {
+		return credential{}, fmt.Errorf("invalid codeartifact response")
+	}
+	cred := credential{Username: codeArtifactUsername, Password: result.AuthorizationToken}
+	if result.Expiration > 0 {
+		seconds, fraction := math.Modf(result.Expiration)
+		cred.ExpiresAt = time.Unix(int64(seconds), int64(fraction*1e9))
+	}
+	return cred, nil
+}
+
+// validateCodeArtifactConfig validates the codeartifact block.
+func validateCodeArtifactConfig(cfg Config) error {
+	c := cfg.CodeArtifact
+	if c == nil {
+		return nil
+	}
+	switch {
+	case c.Domain == "" || c.DomainOwner == "" || c.Repository == "":
+		return fmt.Errorf("codeartifact requires domain, domain_owner and repository")
+	case !awsAccountIDPattern.MatchString(c.DomainOwner):
+		return fmt.Errorf("codeartifact.domain_owner must be a 12-digit AWS account ID, got %q", c.DomainOwner)
+	case c.Region == "":
+		return fmt.Errorf("codeartifact requires region (or set aws_region or AWS_REGION)")
+	case len(cfg.TokenCommand) > 0 || cfg.Token != "" || cfg.TrustedPublishing || cfg.DeviceAuth:
+		return fmt.Errorf("codeartifact cannot be combined with token, token_command, trusted_publishing or device_auth")
+	case len(cfg.CredentialOverrides) > 0 || len(cfg.CustomCommand) > 0:
+		return fmt.Errorf("codeartifact cannot be combined with credential_overrides or custom_command")
+	case cfg.SpiffeWorkloadAPI || !usesBasicAuth(cfg):
+		return fmt.Errorf("codeartifact uploads with basic auth and cannot be combined with auth_scheme or spiffe_workload_api")
+	}
+	if c.Endpoint != "" {
+		if err := validateRepositoryURL(c.Endpoint); err != nil {
+			return fmt.Errorf("invalid codeartifact.endpoint: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCodeArtifact(t *testing.T) {
+	t.Setenv("AWS_REGION", "eu-west-1")
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"codeartifact": map[string]any{"domain": "acme", "domain_owner": float64(123456789012), "repository": "python-internal"},
+	})
+	if cfg.CodeArtifact == nil || cfg.CodeArtifact.DomainOwner != "123456789012" || cfg.CodeArtifact.Region != "eu-west-1" {
+		t.Fatalf("unexpected codeartifact %+v", cfg.CodeArtifact)
+	}
+	if want := "https://acme-123456789012.d.codeartifact.eu-west-1.amazonaws.com/pypi/python-internal/"; cfg.Repository != want {
+		t.Errorf("repository = %s, want %s", cfg.Repository, want)
+	}
+
+	cfg = p.parseConfig(map[string]any{
+		"repository":   "https://vpce-1234.d.codeartifact.eu-west-1.vpce.amazonaws.com/pypi/python-internal/",
+		"codeartifact": map[string]any{"domain": "acme", "domain_owner": "123456789012", "region": "us-east-2", "repository": "python-internal"},
+	})
+	if !strings.HasPrefix(cfg.Repository, "https://vpce-1234.") || cfg.CodeArtifact.Region != "us-east-2" {
+		t.Errorf("expected an explicit repository and region to be kept, got %s %+v", cfg.Repository, cfg.CodeArtifact)
+	}
+	if cfg := p.parseConfig(map[string]any{}); cfg.CodeArtifact != nil {
+		t.Errorf("expected no codeartifact without the block, got %+v", cfg.CodeArtifact)
+	}
+}
+
+func TestRunTwineUploadsWithCodeArtifact(t *testing.T) {
+	distPath := writeDistFiles(t, "pkg-1.0.0.tar.gz", "pkg-1.0.0-py3-none-any.whl")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	// Tokens expire within the refresh margin, so each file gets a new one
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/authorization-token" ||
+			r.URL.Query().Get("domain") != "acme" || r.URL.Query().Get("domain-owner") != "123456789012" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/us-east-1/codeartifact/aws4_request") {
+			http.Error(w, "unsigned request", http.StatusForbidden)
+			return
+		}
+		fetches++
+		_, _ = fmt.Fprintf(w, `{"authorizationToken": "ca-token-%d", "expiration": %d}`, fetches, time.Now().Add(time.Minute).Unix())
+	}))
+	defer server.Close()
+
+	mockExecutor := &MockCommandExecutor{ReturnOut: []byte("Uploading\n")}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor}
+	cfg := p.parseConfig(map[string]any{
+		"repository": "http://localhost:8080/",
+		"dist_path":  distPath,
+		"codeartifact": map[string]any{
+			"domain":       "acme",
+			"domain_owner": "123456789012",
+			"region":       "us-east-1",
+			"repository":   "python-internal",
+			"endpoint":     server.URL,
+		},
+	})
+	if err := p.validateConfig(cfg); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	run, err := p.runTwineUploads(context.Background(), cfg, mockExecutor, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetches != 2 || run.tokenRefreshes != 1 {
+		t.Errorf("expected the token to be refreshed before the second file, got %d fetches and %d refreshes", fetches, run.tokenRefreshes)
+	}
+	var envs []string
+	for _, call := range mockExecutor.RunCalls {
+		envs = append(envs, strings.Join(call.Env, " "))
+	}
+	if want := "TWINE_USERNAME=aws TWINE_PASSWORD=ca-token-1,TWINE_USERNAME=aws TWINE_PASSWORD=ca-token-2"; strings.Join(envs, ",") != want {
+		t.Errorf("unexpected twine credentials %v", envs)
+	}
+}
+
+func TestCodeArtifactTokenSourceError(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "User is not authorized to perform: codeartifact:GetAuthorizationToken"}`, http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	source := &codeArtifactTokenSource{
+		client:   server.Client(),
+		settings: &CodeArtifact{Domain: "acme", DomainOwner: "123456789012", Region: "us-east-1", Repository: "python", Endpoint: server.URL},
+		now:      time.Now,
+	}
+	if _, err := source.fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "not authorized to perform: codeartifact:GetAuthorizationToken") {
+		t.Errorf("expected the API error, got %v", err)
+	}
+}
+
+func TestValidateCodeArtifactConfig(t *testing.T) {
+	valid := Config{
+		Repository:   "https://acme-123456789012.d.codeartifact.us-east-1.amazonaws.com/pypi/python/",
+		CodeArtifact: &CodeArtifact{Domain: "acme", DomainOwner: "123456789012", Region: "us-east-1", Repository: "python"},
+	}
+	if err := validateCodeArtifactConfig(valid); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := validateCodeArtifactConfig(Config{}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, modify := range []func(*Config){
+		func(c *Config) { c.CodeArtifact.Domain = "" },
+		func(c *Config) { c.CodeArtifact.DomainOwner = "acme-prod" },
+		func(c *Config) { c.CodeArtifact.Region = "" },
+		func(c *Config) { c.TokenCommand = []string{"aws", "codeartifact", "get-authorization-token"} },
+		func(c *Config) { c.TrustedPublishing = true },
+		func(c *Config) { c.AuthScheme = authSchemeSigV4 },
+		func(c *Config) { c.CodeArtifact.Endpoint = "http://codeartifact.example.com" },
+	} {
+		cfg := valid
+		settings := *valid.CodeArtifact
+		cfg.CodeArtifact = &settings
+		modify(&cfg)
+		if err := validateCodeArtifactConfig(cfg); err == nil {
+			t.Errorf("expected an error for %+v %+v", cfg, cfg.CodeArtifact)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits of the concurrency option.
+const (
+	defaultConcurrency = 1
+	maxConcurrency     = 16
+)
+
+// nativeUploadResult is the upload of one file by runNativeUploadsParallel.
+type nativeUploadResult struct {
+	started bool
+	run     uploadRun
+	skipped bool
+	err     error
+}
+
+// runNativeUploadsParallel uploads files with up to concurrency workers. Once a file fails or
+// the session is interrupted, no further files are started and the files in flight finish. The
+// output lists the files in their order rather than as they finish, and failed files are named
+// in run.failed, as several can fail at once.
+func (p *PyPIPlugin) runNativeUploadsParallel(ctx context.Context, cfg Config, session *publishSession, creds *refreshingCredentials, client *http.Client, uploads []nativeUpload, run *uploadRun, output *strings.Builder) error {
+	results := make([]nativeUploadResult, len(uploads))
+	next := make(chan int)
+	var mu sync.Mutex
+	failed := false
+	stopped := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		interrupted, _ := session.interrupt.state()
+		return failed || interrupted || ctx.Err() != nil
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(cfg.Concurrency, len(uploads)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				// A file handed over while waiting for a worker is not started after a stop
+				if stopped() {
+					continue
+				}
+				result := nativeUploadResult{started: true}
+				start := time.Now()
+				result.run.notices, result.err = p.uploadNative(ctx, cfg, session, creds, client, uploads[i])
+				result.run.recordTiming([]string{uploads[i].file}, start)
+				if result.err != nil && cfg.SkipExisting && isAlreadyExists(result.err.Error()) {
+					result.skipped, result.err = true, nil
+				}
+				mu.Lock()
+				results[i] = result
+				failed = failed || result.err != nil
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range uploads {
+		if stopped() {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var errs []error
+	var remaining []string
+	for i, result := range results {
+		if !result.started {
+			remaining = append(remaining, uploads[i].file)
+			continue
+		}
+		name := filepath.Base(uploads[i].file)
+		run.merge(result.run)
+		switch {
+		case result.skipped:
+			fmt.Fprintf(output, "Skipping %s because it appears to already exist\n", name)
+		case result.err != nil:
+			fmt.Fprintf(output, "Uploading %s\nERROR    %v\n", name, result.err)
+			run.failed = append(run.failed, uploads[i].file)
+			errs = append(errs, fmt.Errorf("%s: %w", name, result.err))
+		default:
+			fmt.Fprintf(output, "Uploading %s\n", name)
+		}
+	}
+	// An interrupt leaves the failed files and those not started to upload again, as
+	// uploadEach does
+	if interrupted, aborted := session.interrupt.state(); interrupted {
+		remaining = append(append([]string{}, run.failed...), remaining...)
+		return &interruptedUpload{uploaded: len(uploads) - len(remaining), remaining: remaining, aborted: aborted}
+	}
+	// A cancelled upload that left files out failed even when no upload in flight did
+	if len(errs) == 0 && len(remaining) > 0 {
+		return ctx.Err()
+	}
+	return errors.Join(errs...)
+}
+
+// validateConcurrency validates the concurrency option. Parallel uploads need the built-in
+// uploader, and continue_on_error uploads one file at a time.
+func validateConcurrency(cfg Config) error {
+	if cfg.Concurrency < 1 || cfg.Concurrency > maxConcurrency {
+		return fmt.Errorf("concurrency must be between 1 and %d", maxConcurrency)
+	}
+	if cfg.Concurrency == 1 {
+		return nil
+	}
+	if !usesNativeUploader(cfg) {
+		return fmt.Errorf("concurrency requires the built-in uploader; set upload_backend: native")
+	}
+	if cfg.ContinueOnError {
+		return fmt.Errorf("concurrency cannot be combined with continue_on_error, which uploads one file at a time")
+	}
+	return nil
+}
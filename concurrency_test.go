@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// writePlatformWheels writes a wheel of mypkg 1.0.0 for each platform tag, with different
+// contents so that they are not deduplicated.
+func writePlatformWheels(t *testing.T, platforms ...string) {
+	t.Helper()
+	writeDistFiles(t)
+	for _, platform := range platforms {
+		writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-cp312-cp312-"+platform+".whl"), map[string]string{
+			"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+			"mypkg/_platform.txt":            platform,
+		})
+	}
+}
+
+func TestExecuteParallelNativeUploads(t *testing.T) {
+	writePlatformWheels(t, "linux_x86_64", "linux_aarch64", "macosx_11_0_arm64", "win_amd64")
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	var uploaded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		_, header, err := r.FormFile("content")
+		if err != nil {
+			t.Errorf("upload without content: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		uploaded = append(uploaded, header.Filename)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{}, httpClient: server.Client()}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":       "__token__",
+			"password":       "pypi-token",
+			"repository":     server.URL,
+			"upload_backend": "native",
+			"concurrency":    4,
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+	if len(uploaded) != 4 || maxInFlight < 2 {
+		t.Errorf("expected 4 parallel uploads, got %v with at most %d at once", uploaded, maxInFlight)
+	}
+	// The output lists the files in order, whichever finished first
+	output, _ := resp.Outputs["output"].(string)
+	if want := "Uploading mypkg-1.0.0-cp312-cp312-linux_aarch64.whl\nUploading mypkg-1.0.0-cp312-cp312-linux_x86_64.whl\nUploading mypkg-1.0.0-cp312-cp312-macosx_11_0_arm64.whl\nUploading mypkg-1.0.0-cp312-cp312-win_amd64.whl\n"; !strings.HasSuffix(output, want) {
+		t.Errorf("unexpected output %q", output)
+	}
+	if timings, _ := resp.Outputs["upload_timings"].([]uploadTiming); len(timings) != 4 {
+		t.Errorf("expected a timing per file, got %v", resp.Outputs["upload_timings"])
+	}
+}
+
+func TestExecuteParallelNativeUploadFailure(t *testing.T) {
+	writePlatformWheels(t, "linux_aarch64", "linux_x86_64", "win_amd64")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, header, _ := r.FormFile("content"); strings.Contains(header.Filename, "linux_aarch64") {
+			http.Error(w, "Invalid platform tag", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{}, httpClient: server.Client()}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":       "__token__",
+			"password":       "pypi-token",
+			"repository":     server.URL,
+			"upload_backend": "native",
+			"concurrency":    2,
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "mypkg-1.0.0-cp312-cp312-linux_aarch64.whl: ") {
+		t.Fatalf("expected the failed file in the error, got %v %+v", err, resp)
+	}
+	// Files in flight finish, so only the rejected file failed
+	for _, file := range resp.Outputs["file_results"].([]fileOutput) {
+		if failed := file.Status == fileFailed; failed != strings.Contains(file.Name, "linux_aarch64") {
+			t.Errorf("unexpected status %s of %s", file.Status, file.Name)
+		}
+	}
+}
+
+func TestExecuteParallelNativeUploadInterrupt(t *testing.T) {
+	writePlatformWheels(t, "linux_aarch64", "linux_x86_64", "macosx_11_0_arm64", "win_amd64")
+
+	prompts := &syncWriter{w: &bytes.Buffer{}}
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{}, promptOutput: prompts, interrupts: make(chan os.Signal, 1)}
+	var mu sync.Mutex
+	requests := 0
+	bothInFlight := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		first := requests == 1
+		if requests == 2 {
+			close(bothInFlight)
+		}
+		mu.Unlock()
+		if !first {
+			return
+		}
+		// Ctrl-C while both workers upload a file
+		<-bothInFlight
+		p.interrupts <- os.Interrupt
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+			prompts.mu.Lock()
+			printed := strings.Contains(prompts.w.(*bytes.Buffer).String(), "Interrupted:")
+			prompts.mu.Unlock()
+			if printed {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("interrupt not reported")
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	p.httpClient = server.Client()
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":       "__token__",
+			"password":       "pypi-token",
+			"repository":     server.URL,
+			"upload_backend": "native",
+			"concurrency":    2,
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The files in flight finish and no further file is started
+	if requests != 2 || resp.Success || !strings.Contains(resp.Error, "upload interrupted after 2 of 4 file(s)") {
+		t.Fatalf("expected the upload to stop after the files in flight, got %d uploads and %q", requests, resp.Error)
+	}
+	remaining, _ := resp.Outputs["remaining_files"].([]string)
+	if resp.Outputs["interrupted"] != true || len(remaining) != 2 || !strings.HasSuffix(remaining[0], "macosx_11_0_arm64.whl") || !strings.HasSuffix(remaining[1], "win_amd64.whl") {
+		t.Errorf("expected the files not started to remain, got %v", resp.Outputs)
+	}
+}
+
+func TestValidateConcurrency(t *testing.T) {
+	p := &PyPIPlugin{}
+	if cfg := p.parseConfig(map[string]any{}); cfg.Concurrency != 1 || validateConcurrency(cfg) != nil {
+		t.Errorf("expected sequential uploads by default, got %d", cfg.Concurrency)
+	}
+	native := Config{UploadBackend: uploadBackendNative}
+	for _, bad := range []Config{
+		{UploadBackend: uploadBackendNative, Concurrency: 0},
+		{UploadBackend: uploadBackendNative, Concurrency: maxConcurrency + 1},
+		{UploadBackend: uploadBackendAuto, Concurrency: 4},
+		{UploadBackend: uploadBackendNative, Concurrency: 4, ContinueOnError: true},
+	} {
+		if err := validateConcurrency(bad); err == nil {
+			t.Errorf("expected %+v to be rejected", bad)
+		}
+	}
+	native.Concurrency = 8
+	if err := validateConcurrency(native); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
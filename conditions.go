@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// publishSkipReason returns why the release should not be uploaded according to
+// publish_branches, skip_prereleases and only_if_paths_changed, or "" to publish it.
+func (p *PyPIPlugin) publishSkipReason(ctx context.Context, cfg Config, releaseCtx plugin.ReleaseContext, version string) (string, error) {
+	if len(cfg.PublishBranches) > 0 {
+		if releaseCtx.Branch == "" {
+			return fmt.Sprintf("the release names no branch, and publish_branches only allows %s", strings.Join(cfg.PublishBranches, ", ")), nil
+		}
+		if !branchAllowed(cfg.PublishBranches, releaseCtx.Branch) {
+			return fmt.Sprintf("branch %s is not in publish_branches (%s)", releaseCtx.Branch, strings.Join(cfg.PublishBranches, ", ")), nil
+		}
+	}
+
+	if cfg.SkipPrereleases {
+		switch channel := releaseChannel(version); channel {
+		case channelAlpha, channelBeta, channelRC, channelDev:
+			return fmt.Sprintf("%s is a pre-release (%s) and skip_prereleases is set", version, channel), nil
+		}
+	}
+
+	if len(cfg.OnlyIfPathsChanged) > 0 {
+		// The first release has nothing to compare with and is always published
+		if releaseCtx.PreviousVersion == "" {
+			return "", nil
+		}
+		changed, base, err := p.changedFiles(ctx, releaseCtx)
+		if err != nil {
+			return "", err
+		}
+		for _, file := range changed {
+			if matchesAnyPathPattern(cfg.OnlyIfPathsChanged, file) {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("no file matching only_if_paths_changed (%s) changed since %s", strings.Join(cfg.OnlyIfPathsChanged, ", "), base), nil
+	}
+	return "", nil
+}
+
+// branchAllowed reports whether branch matches one of the publish_branches patterns, such as
+// main or release/*.
+func branchAllowed(patterns []string, branch string) bool {
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// changedFiles lists the files changed between the tag of the previous release and the
+// released commit, with paths relative to the root of the git repository. The tag is named
+// like the release's own tag, with the previous version in place of the new one.
+func (p *PyPIPlugin) changedFiles(ctx context.Context, releaseCtx plugin.ReleaseContext) ([]string, string, error) {
+	prefix := "v"
+	if releaseCtx.TagName != "" && strings.HasSuffix(releaseCtx.TagName, releaseCtx.Version) {
+		prefix = strings.TrimSuffix(releaseCtx.TagName, releaseCtx.Version)
+	}
+	base := prefix + releaseCtx.PreviousVersion
+	head := releaseCtx.CommitSHA
+	if head == "" {
+		head = "HEAD"
+	}
+	// -z keeps paths with spaces or special characters unquoted, separated by NUL
+	output, err := commandOutput(ctx, p.getExecutor(), "git", "diff", "-z", "--name-only", base, head, "--")
+	if err != nil {
+		return nil, "", fmt.Errorf("only_if_paths_changed: failed to list the files changed since %s: %w; the checkout needs the tags and history of both releases", base, err)
+	}
+	var files []string
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, base, nil
+}
+
+// pathPatternRegexp compiles an only_if_paths_changed pattern: * and ? stay within a path
+// segment, ** matches any number of directories, and a pattern ending in / matches everything
+// below that directory.
+func pathPatternRegexp(pattern string) (*regexp.Regexp, error) {
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// matchesAnyPathPattern reports whether file matches one of the only_if_paths_changed patterns.
+func matchesAnyPathPattern(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		if re, err := pathPatternRegexp(pattern); err == nil && re.MatchString(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePublishBranches validates the publish_branches option.
+func validatePublishBranches(cfg Config) error {
+	for _, pattern := range cfg.PublishBranches {
+		if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("invalid publish_branches pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// validateOnlyIfPathsChanged validates the only_if_paths_changed option.
+func validateOnlyIfPathsChanged(cfg Config) error {
+	for _, pattern := range cfg.OnlyIfPathsChanged {
+		if strings.TrimSpace(pattern) == "" || strings.HasPrefix(pattern, "/") || escapesWorkDir(pattern) {
+			return fmt.Errorf("invalid only_if_paths_changed pattern %q: use a path relative to the repository root", pattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestExecuteSkipsByPublishConditions(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0rc1.tar.gz")
+	for name, tc := range map[string]struct {
+		config map[string]any
+		ctx    plugin.ReleaseContext
+		reason string
+	}{
+		"branch": {
+			config: map[string]any{"publish_branches": []any{"main", "release/*"}},
+			ctx:    plugin.ReleaseContext{Version: "1.0.0", Branch: "feature/x"},
+			reason: "branch feature/x is not in publish_branches (main, release/*)",
+		},
+		"no branch": {
+			config: map[string]any{"publish_branches": []any{"main"}},
+			ctx:    plugin.ReleaseContext{Version: "1.0.0"},
+			reason: "the release names no branch",
+		},
+		"prerelease": {
+			config: map[string]any{"skip_prereleases": true, "publish_branches": []any{"release/*"}},
+			ctx:    plugin.ReleaseContext{Version: "1.0.0rc1", Branch: "refs/heads/release/1.0"},
+			reason: "1.0.0rc1 is a pre-release (rc)",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			executor := &MockCommandExecutor{}
+			p := &PyPIPlugin{cmdExecutor: executor}
+			tc.config["repository"] = "http://localhost:8080/"
+			tc.config["username"], tc.config["password"] = "__token__", "pypi-token"
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: tc.config, Context: tc.ctx})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected a skipped success, got %v %+v", err, resp)
+			}
+			if resp.Outputs["skipped"] != true || !strings.HasPrefix(resp.Outputs["skip_reason"].(string), tc.reason) {
+				t.Errorf("unexpected outputs %v", resp.Outputs)
+			}
+			if len(executor.RunCalls) != 0 {
+				t.Errorf("expected no upload, got %+v", executor.RunCalls)
+			}
+		})
+	}
+}
+
+func TestPublishSkipReasonPathsChanged(t *testing.T) {
+	cfg := Config{OnlyIfPathsChanged: []string{"packages/core/", "**/pyproject.toml"}}
+	releaseCtx := plugin.ReleaseContext{Version: "1.1.0", PreviousVersion: "1.0.0", TagName: "core-v1.1.0", CommitSHA: "abc123"}
+
+	// Warnings on stderr are not file names
+	executor := &MockCommandExecutor{ReturnOut: []byte("docs/index.md\x00packages/web/app.py\x00"), ReturnStderr: []byte("warning: inexact rename detection was skipped\n")}
+	p := &PyPIPlugin{cmdExecutor: executor}
+	reason, err := p.publishSkipReason(context.Background(), cfg, releaseCtx, "1.1.0")
+	if err != nil || reason != "no file matching only_if_paths_changed (packages/core/, **/pyproject.toml) changed since core-v1.0.0" {
+		t.Errorf("unexpected result %q %v", reason, err)
+	}
+	if len(executor.RunCalls) != 1 || strings.Join(executor.RunCalls[0].Args, " ") != "diff -z --name-only core-v1.0.0 abc123 --" {
+		t.Errorf("unexpected git commands %+v", executor.RunCalls)
+	}
+
+	p.cmdExecutor = &MockCommandExecutor{ReturnOut: []byte("docs/index.md\x00packages/web/pyproject.toml\x00")}
+	if reason, err := p.publishSkipReason(context.Background(), cfg, releaseCtx, "1.1.0"); err != nil || reason != "" {
+		t.Errorf("expected a publish, got %q %v", reason, err)
+	}
+
+	// Paths with spaces are not split
+	spaced := Config{OnlyIfPathsChanged: []string{"packages/core lib/"}}
+	p.cmdExecutor = &MockCommandExecutor{ReturnOut: []byte("packages/core lib/mod.py\x00")}
+	if reason, err := p.publishSkipReason(context.Background(), spaced, releaseCtx, "1.1.0"); err != nil || reason != "" {
+		t.Errorf("expected a publish for a path with spaces, got %q %v", reason, err)
+	}
+
+	// The first release is published without consulting git
+	first := &MockCommandExecutor{}
+	p.cmdExecutor = first
+	if reason, err := p.publishSkipReason(context.Background(), cfg, plugin.ReleaseContext{Version: "1.0.0"}, "1.0.0"); err != nil || reason != "" || len(first.RunCalls) != 0 {
+		t.Errorf("expected the first release to publish, got %q %v %+v", reason, err, first.RunCalls)
+	}
+
+	p.cmdExecutor = &MockCommandExecutor{ReturnError: errors.New("exit status 128: fatal: bad revision 'core-v1.0.0'")}
+	if _, err := p.publishSkipReason(context.Background(), cfg, releaseCtx, "1.1.0"); err == nil || !strings.Contains(err.Error(), "bad revision") {
+		t.Errorf("expected the git failure, got %v", err)
+	}
+}
+
+func TestPathPatterns(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"src/", "src/pkg/mod.py", true},
+		{"src/", "tests/src/mod.py", false},
+		{"src/*.py", "src/mod.py", true},
+		{"src/*.py", "src/pkg/mod.py", false},
+		{"src/**/*.py", "src/mod.py", true},
+		{"src/**/*.py", "src/a/b/mod.py", true},
+		{"**/pyproject.toml", "pyproject.toml", true},
+		{"**/pyproject.toml", "packages/core/pyproject.toml", true},
+		{"setup.cfg", "setup.cfg", true},
+		{"setup.cfg", "setupxcfg", false},
+		{"v?.txt", "v1.txt", true},
+	} {
+		if got := matchesAnyPathPattern([]string{tc.pattern}, tc.file); got != tc.want {
+			t.Errorf("%s matching %s = %v, want %v", tc.pattern, tc.file, got, tc.want)
+		}
+	}
+}
+
+func TestPublishConditionsConfig(t *testing.T) {
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{"publish_branches": []any{"main"}, "skip_prereleases": true, "only_if_paths_changed": []any{"src/"}})
+	if len(cfg.PublishBranches) != 1 || !cfg.SkipPrereleases || len(cfg.OnlyIfPathsChanged) != 1 {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if err := validatePublishBranches(Config{PublishBranches: []string{"release/["}}); err == nil {
+		t.Error("expected a malformed branch pattern to be rejected")
+	}
+	for _, pattern := range []string{"", "/src", "../other"} {
+		if err := validateOnlyIfPathsChanged(Config{OnlyIfPathsChanged: []string{pattern}}); err == nil {
+			t.Errorf("expected %q to be rejected", pattern)
+		}
+	}
+}
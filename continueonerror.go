@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// partialFailure is the error of a continue_on_error upload in which some files failed while
+// the others were uploaded.
+type partialFailure struct {
+	failed []string
+	errs   []error
+	total  int
+}
+
+// Error implements error.
+func (e *partialFailure) Error() string {
+	details := make([]string, len(e.failed))
+	for i, f := range e.failed {
+		details[i] = fmt.Sprintf("%s: %v", filepath.Base(f), e.errs[i])
+	}
+	return fmt.Sprintf("%d of %d file(s) failed to upload: %s", len(e.failed), e.total, strings.Join(details, "; "))
+}
+
+// Unwrap returns the errors of the failed files, so errors.Is finds, for example, an unhealthy
+// repository behind them.
+func (e *partialFailure) Unwrap() []error {
+	return e.errs
+}
+
+// validateContinueOnError validates the continue_on_error option. A custom command uploads all
+// files in one invocation, which cannot continue after one of them.
+func validateContinueOnError(cfg Config) error {
+	if cfg.ContinueOnError && len(cfg.CustomCommand) > 0 {
+		return fmt.Errorf("continue_on_error cannot be combined with custom_command, which uploads all files at once")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// failingTwine fails the upload of the named file, printing twine's output for each file.
+func failingTwine(failing string) *MockCommandExecutor {
+	return &MockCommandExecutor{RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		var out strings.Builder
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "dist") {
+				continue
+			}
+			fmt.Fprintf(&out, "Uploading %s\n", filepath.Base(arg))
+			if filepath.Base(arg) == failing {
+				out.WriteString("ERROR    HTTPError: 400 Bad Request\n")
+				return []byte(out.String()), errors.New("exit status 1")
+			}
+		}
+		return []byte(out.String()), nil
+	}}
+}
+
+func fileResultStatuses(t *testing.T, resp *plugin.ExecuteResponse) string {
+	t.Helper()
+	results, ok := resp.Outputs["file_results"].([]fileOutput)
+	if !ok {
+		t.Fatalf("no file_results in %v", resp.Outputs)
+	}
+	statuses := make([]string, len(results))
+	for i, r := range results {
+		statuses[i] = r.Name + "=" + r.Status
+	}
+	return strings.Join(statuses, " ")
+}
+
+func TestExecuteContinueOnError(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz", "mypkg-1.0.0-py3-none-any.whl", "mypkg-1.0.0-py3-none-win_amd64.whl")
+	config := map[string]any{
+		"username":   "__token__",
+		"password":   "pypi-token",
+		"repository": "http://localhost:8080/",
+	}
+
+	// By default the upload stops at the failed file
+	p := &PyPIPlugin{cmdExecutor: failingTwine("mypkg-1.0.0-py3-none-win_amd64.whl")}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config, Context: plugin.ReleaseContext{Version: "1.0.0"}})
+	if err != nil || resp.Success {
+		t.Fatalf("expected the upload to fail, got %+v, %v", resp, err)
+	}
+	if got, want := fileResultStatuses(t, resp), "mypkg-1.0.0-py3-none-any.whl=uploaded mypkg-1.0.0-py3-none-win_amd64.whl=failed mypkg-1.0.0.tar.gz=pending"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// continue_on_error uploads the files after it
+	config["continue_on_error"] = true
+	executor := failingTwine("mypkg-1.0.0-py3-none-win_amd64.whl")
+	p = &PyPIPlugin{cmdExecutor: executor}
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config, Context: plugin.ReleaseContext{Version: "1.0.0"}})
+	if err != nil || resp.Success {
+		t.Fatalf("expected the publish to fail, got %+v, %v", resp, err)
+	}
+	if len(executor.RunCalls) != 3 {
+		t.Errorf("expected one twine upload per file, got %d", len(executor.RunCalls))
+	}
+	if !strings.Contains(resp.Error, "1 of 3 file(s) failed to upload: mypkg-1.0.0-py3-none-win_amd64.whl: exit status 1") {
+		t.Errorf("unexpected error %s", resp.Error)
+	}
+	if got, want := fileResultStatuses(t, resp), "mypkg-1.0.0-py3-none-any.whl=uploaded mypkg-1.0.0-py3-none-win_amd64.whl=failed mypkg-1.0.0.tar.gz=uploaded"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestUploadEachContinueOnError(t *testing.T) {
+	files := []string{"dist/a.whl", "dist/b.whl", "dist/c.tar.gz"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var uploaded []string
+	var guard *interruptGuard
+	run, err := guard.uploadEach(ctx, files, true, func(files []string) (uploadRun, error) {
+		if files[0] == "dist/a.whl" {
+			return uploadRun{}, errors.New("400 Bad Request")
+		}
+		uploaded = append(uploaded, files[0])
+		return uploadRun{}, nil
+	})
+	var partial *partialFailure
+	if !errors.As(err, &partial) || len(partial.failed) != 1 || partial.total != 3 || len(uploaded) != 2 {
+		t.Fatalf("expected a partial failure after uploading the rest, got %v, %v", err, uploaded)
+	}
+	if len(run.failed) != 1 || run.failed[0] != "dist/a.whl" {
+		t.Errorf("unexpected failed files %v", run.failed)
+	}
+
+	// A cancelled upload does not go on
+	_, err = guard.uploadEach(ctx, files, true, func(files []string) (uploadRun, error) {
+		cancel()
+		return uploadRun{}, context.Canceled
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation, got %v", err)
+	}
+
+	if err := validateContinueOnError(Config{ContinueOnError: true, CustomCommand: []string{"upload"}}); err == nil {
+		t.Error("expected continue_on_error with custom_command to be rejected")
+	}
+}
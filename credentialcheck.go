@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// invisibleRunes are characters that copy-pasted secrets pick up and that never belong to a
+// token: zero-width spaces and joiners, the byte order mark and word joiners.
+var invisibleRunes = []rune{'\u200b', '\u200c', '\u200d', '\u2060', '\ufeff'}
+
+// cleanCredential removes the artifacts CI setups commonly add to secrets: surrounding
+// whitespace such as the trailing newline of `echo`, surrounding quotes from YAML or shell
+// quoting, and invisible characters. It returns the cleaned value and a warning per fix,
+// naming the field but never the value. Credentials that are rejected by the index for these
+// reasons otherwise surface as an unexplained 403.
+func cleanCredential(field, value string) (string, []string) {
+	if value == "" {
+		return value, nil
+	}
+	var warnings []string
+
+	if trimmed := strings.TrimSpace(value); trimmed != value {
+		what := "surrounding whitespace"
+		if strings.HasSuffix(value, "\n") {
+			what = "a trailing newline (as printed by echo)"
+		}
+		warnings = append(warnings, fmt.Sprintf("%s had %s, which was removed", field, what))
+		value = trimmed
+	}
+
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		warnings = append(warnings, fmt.Sprintf("%s was wrapped in quotes, which were removed", field))
+		value = value[1 : len(value)-1]
+	}
+
+	if stripped := strings.Map(func(r rune) rune {
+		for _, invisible := range invisibleRunes {
+			if r == invisible {
+				return -1
+			}
+		}
+		return r
+	}, value); stripped != value {
+		warnings = append(warnings, fmt.Sprintf("%s contained invisible characters (zero-width space or byte order mark), which were removed", field))
+		value = stripped
+	}
+
+	for _, r := range value {
+		if r > unicode.MaxASCII && !unicode.IsControl(r) {
+			warnings = append(warnings, fmt.Sprintf("%s contains non-ASCII characters; check that it was copied correctly", field))
+			break
+		}
+	}
+	return value, warnings
+}
+
+// cleanCredentials cleans the credentials of cfg in place and records the fixes in
+// cfg.CredentialWarnings.
+func cleanCredentials(cfg *Config) {
+	var warnings []string
+	clean := func(field string, value *string) {
+		var w []string
+		*value, w = cleanCredential(field, *value)
+		warnings = append(warnings, w...)
+	}
+
+	clean("username", &cfg.Username)
+	// A token fills in the password, so it is cleaned once and reported under its own name
+	if cfg.Token != "" && cfg.Password == cfg.Token {
+		clean("token", &cfg.Token)
+		cfg.Password = cfg.Token
+	} else {
+		clean("password", &cfg.Password)
+	}
+	for i := range cfg.CredentialOverrides {
+		clean(fmt.Sprintf("credential_overrides[%d].username", i), &cfg.CredentialOverrides[i].Username)
+		clean(fmt.Sprintf("credential_overrides[%d].password", i), &cfg.CredentialOverrides[i].Password)
+	}
+
+	// PyPI API tokens always start with pypi-, so a different value is usually the account
+	// password or a token for another index
+	if cfg.Username == "__token__" && cfg.Password != "" && !strings.HasPrefix(cfg.Password, "pypi-") && isPyPIRepository(cfg.Repository) {
+		warnings = append(warnings, "password does not look like a PyPI API token (tokens start with pypi-)")
+	}
+	// PyPI only accepts API tokens with the __token__ username
+	if cfg.Username != "" && cfg.Username != defaultTokenUsername && strings.HasPrefix(cfg.Password, "pypi-") {
+		warnings = append(warnings, fmt.Sprintf("password looks like a PyPI API token, but the username is %q instead of __token__; set token instead of username and password", cfg.Username))
+	}
+	cfg.CredentialWarnings = warnings
+}
+
+// isPyPIRepository reports whether rawURL is the upload endpoint of PyPI or TestPyPI.
+func isPyPIRepository(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == "upload.pypi.org" || host == "test.pypi.org"
+}
+
+// validateCredentialEncoding rejects credentials that still contain control characters after
+// cleaning, as they cannot be sent in an HTTP header.
+func validateCredentialEncoding(cfg Config) error {
+	fields := [][2]string{{"username", cfg.Username}, {"password", cfg.Password}}
+	for i, o := range cfg.CredentialOverrides {
+		fields = append(fields,
+			[2]string{fmt.Sprintf("credential_overrides[%d].username", i), o.Username},
+			[2]string{fmt.Sprintf("credential_overrides[%d].password", i), o.Password})
+	}
+	for _, f := range fields {
+		if strings.IndexFunc(f[1], unicode.IsControl) >= 0 {
+			return fmt.Errorf("%s contains control characters such as an embedded newline", f[0])
+		}
+	}
+	return nil
+}
+
+// validateTokenConfig rejects a token combined with credentials it would conflict with.
+func validateTokenConfig(cfg Config) error {
+	if cfg.Token == "" {
+		return nil
+	}
+	switch {
+	case cfg.Password != cfg.Token:
+		return fmt.Errorf("token cannot be combined with password")
+	case cfg.Username != defaultTokenUsername:
+		return fmt.Errorf("token is sent with the username __token__; remove username %q", cfg.Username)
+	case len(cfg.TokenCommand) > 0:
+		return fmt.Errorf("token cannot be combined with token_command")
+	case cfg.TrustedPublishing:
+		return fmt.Errorf("token cannot be combined with trusted_publishing")
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestCleanCredential(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		want     string
+		warnings int
+	}{
+		{"clean", "pypi-AgEIcHlwaS5vcmc", "pypi-AgEIcHlwaS5vcmc", 0},
+		{"echo newline", "pypi-token\n", "pypi-token", 1},
+		{"windows newline", "pypi-token\r\n", "pypi-token", 1},
+		{"quoted", `"pypi-token"`, "pypi-token", 1},
+		{"quoted with newline", "'pypi-token'\n", "pypi-token", 2},
+		{"zero-width space", "pypi-\u200btoken", "pypi-token", 1},
+		{"byte order mark", "\ufeffpypi-token", "pypi-token", 1},
+		{"non-ASCII", "pässword", "pässword", 1},
+		{"unbalanced quote", `pass"`, `pass"`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings := cleanCredential("password", tt.value)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("expected %d warnings, got %q", tt.warnings, warnings)
+			}
+			for _, w := range warnings {
+				if strings.Contains(w, "token") {
+					t.Errorf("warning leaks the value: %q", w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseConfigCleansCredentials(t *testing.T) {
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"username": "__token__",
+		"password": "not-a-token\n",
+	})
+	if cfg.Password != "not-a-token" {
+		t.Errorf("expected the newline to be removed, got %q", cfg.Password)
+	}
+	if len(cfg.CredentialWarnings) != 2 || !strings.Contains(cfg.CredentialWarnings[1], "pypi-") {
+		t.Errorf("expected newline and token format warnings, got %q", cfg.CredentialWarnings)
+	}
+
+	if err := validateCredentialEncoding(Config{Username: "user", Password: "line1\nline2"}); err == nil {
+		t.Error("expected an embedded newline to be rejected")
+	}
+}
+
+func TestExecuteReportsCredentialWarnings(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	p := &PyPIPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:   plugin.HookPostPublish,
+		DryRun: true,
+		Config: map[string]any{
+			"username":   "user",
+			"password":   "secret\n",
+			"repository": "http://localhost:8080/",
+		},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected result: %v %+v", err, resp)
+	}
+	warnings, _ := resp.Outputs["warnings"].([]string)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "trailing newline") {
+		t.Errorf("expected a trailing newline warning, got %v", resp.Outputs["warnings"])
+	}
+}
+
+func TestParseConfigToken(t *testing.T) {
+	t.Setenv("PYPI_USERNAME", "alice")
+	t.Setenv("PYPI_PASSWORD", "")
+	t.Setenv("PYPI_TOKEN", "")
+	tests := []struct {
+		name    string
+		env     string
+		config  map[string]any
+		want    [2]string
+		wantErr string
+	}{
+		{"config token", "", map[string]any{"token": "pypi-config \n"}, [2]string{"__token__", "pypi-config"}, ""},
+		{"env token", "pypi-env", map[string]any{}, [2]string{"__token__", "pypi-env"}, ""},
+		{"config token over env token", "pypi-env", map[string]any{"token": "pypi-config"}, [2]string{"__token__", "pypi-config"}, ""},
+		{"config password over env token", "pypi-env", map[string]any{"username": "__token__", "password": "pypi-password"}, [2]string{"__token__", "pypi-password"}, ""},
+		{"token and password", "", map[string]any{"token": "pypi-config", "password": "secret"}, [2]string{"__token__", "secret"}, "token cannot be combined with password"},
+		{"token and username", "", map[string]any{"token": "pypi-config", "username": "bob"}, [2]string{"bob", "pypi-config"}, "remove username"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PYPI_TOKEN", tt.env)
+			cfg := (&PyPIPlugin{}).parseConfig(tt.config)
+			if got := [2]string{cfg.Username, cfg.Password}; got != tt.want {
+				t.Errorf("credentials = %q, want %q", got, tt.want)
+			}
+			err := validateTokenConfig(cfg)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("validateTokenConfig() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateWarnsTokenWithUsername(t *testing.T) {
+	t.Setenv("PYPI_TOKEN", "")
+	p := &PyPIPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{"username": "alice", "password": "pypi-AgEIcHlwaS5vcmc"})
+	if err != nil || !resp.Valid {
+		t.Fatalf("expected a valid config, got %v %+v", err, resp)
+	}
+	found := false
+	for _, e := range resp.Errors {
+		found = found || strings.Contains(e.Message, "instead of __token__")
+	}
+	if !found {
+		t.Errorf("expected a warning about the username, got %+v", resp.Errors)
+	}
+}
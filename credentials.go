@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token refresh defaults.
+const (
+	defaultTokenRefreshMargin = 5 * time.Minute
+	defaultTokenUsername      = "__token__"
+)
+
+// credential is a username/password pair with an optional expiry.
+type credential struct {
+	Username string
+	Password string
+	// ExpiresAt is zero when the expiry is unknown.
+	ExpiresAt time.Time
+}
+
+// tokenSource obtains a fresh short-lived credential.
+type tokenSource interface {
+	fetch(ctx context.Context) (credential, error)
+}
+
+// refreshingCredentials caches a credential from a tokenSource and fetches a new one
+// when the cached credential is about to expire or has been rejected by the index.
+type refreshingCredentials struct {
+	source tokenSource
+	margin time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	current credential
+	valid   bool
+	fetches int
+}
+
+// newRefreshingCredentials creates a credential cache that refreshes margin before expiry.
+func newRefreshingCredentials(source tokenSource, margin time.Duration) *refreshingCredentials {
+	return &refreshingCredentials{
+		source: source,
+		margin: margin,
+		now:    time.Now,
+	}
+}
+
+// get returns a credential that is not within the refresh margin of its expiry.
+func (r *refreshingCredentials) get(ctx context.Context) (credential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.valid && (r.current.ExpiresAt.IsZero() || r.now().Add(r.margin).Before(r.current.ExpiresAt)) {
+		return r.current, nil
+	}
+
+	cred, err := r.source.fetch(ctx)
+	if err != nil {
+		return credential{}, fmt.Errorf("failed to obtain token: %w", err)
+	}
+	r.fetches++
+	r.current = cred
+	r.valid = true
+	return cred, nil
+}
+
+// invalidate forces the next get to fetch a new credential, e.g. after a 401 response.
+func (r *refreshingCredentials) invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.valid = false
+}
+
+// refreshCount returns how many times the credential was replaced after the initial fetch.
+func (r *refreshingCredentials) refreshCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fetches == 0 {
+		return 0
+	}
+	return r.fetches - 1
+}
+
+// commandTokenSource obtains tokens by running a command such as
+// "aws codeartifact get-authorization-token" or "gcloud auth print-access-token".
+type commandTokenSource struct {
+	executor CommandExecutor
+	command  []string
+	username string
+	lifetime time.Duration
+	now      func() time.Time
+}
+
+// fetch runs the token command and determines the token expiry.
+// JSON output may carry the expiry explicitly; otherwise it is read from a JWT "exp" claim
+// or derived from the configured token lifetime.
+func (s *commandTokenSource) fetch(ctx context.Context) (credential, error) {
+	output, err := commandOutput(ctx, s.executor, s.command[0], s.command[1:]...)
+	if err != nil {
+		return credential{}, fmt.Errorf("token command failed: %w", err)
+	}
+
+	token, expiresAt, err := parseTokenOutput(output, s.now())
+	if err != nil {
+		return credential{}, err
+	}
+	if expiresAt.IsZero() && s.lifetime > 0 {
+		expiresAt = s.now().Add(s.lifetime)
+	}
+
+	return credential{Username: s.username, Password: token, ExpiresAt: expiresAt}, nil
+}
+
+// tokenCommandOutput is the JSON shape accepted from token commands.
+type tokenCommandOutput struct {
+	Token              string `json:"token"`
+	AuthorizationToken string `json:"authorizationToken"`
+	AccessToken        string `json:"access_token"`
+	Expiration         string `json:"expiration"`
+	ExpiresAt          string `json:"expires_at"`
+	ExpiresIn          int64  `json:"expires_in"`
+}
+
+// parseTokenOutput extracts the token and its expiry (zero if unknown) from command output.
+func parseTokenOutput(output []byte, now time.Time) (string, time.Time, error) {
+	trimmed := strings.TrimSpace(string(output))
+	if trimmed == "" {
+		return "", time.Time{}, fmt.Errorf("token command produced no output")
+	}
+
+	if strings.HasPrefix(trimmed, "{") {
+		var parsed tokenCommandOutput
+		if err := json.Unmarshal([]byte(trimmed), &parsed); err != nil {
+			return "", time.Time{}, fmt.Errorf("token command output is not valid JSON: %w", err)
+		}
+
+		token := firstNonEmpty(parsed.Token, parsed.AuthorizationToken, parsed.AccessToken)
+		if token == "" {
+			return "", time.Time{}, fmt.Errorf("token command output has no token field")
+		}
+
+		var expiresAt time.Time
+		switch {
+		case parsed.ExpiresIn > 0:
+			expiresAt = now.Add(time.Duration(parsed.ExpiresIn) * time.Second)
+		case firstNonEmpty(parsed.Expiration, parsed.ExpiresAt) != "":
+			t, err := time.Parse(time.RFC3339, firstNonEmpty(parsed.Expiration, parsed.ExpiresAt))
+			if err != nil {
+				return "", time.Time{}, fmt.Errorf("invalid token expiration: %w", err)
+			}
+			expiresAt = t
+		default:
+			expiresAt = jwtExpiry(token)
+		}
+		return token, expiresAt, nil
+	}
+
+	return trimmed, jwtExpiry(trimmed), nil
+}
+
+// jwtExpiry returns the "exp" claim of a JWT, or the zero time if token is not a JWT.
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// firstNonEmpty returns the first non-empty string.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// isAuthFailure reports whether upload output indicates rejected credentials.
+func isAuthFailure(output string) bool {
+	return strings.Contains(output, "401 Unauthorized") || strings.Contains(output, "403 Forbidden") ||
+		strings.Contains(output, "Invalid or non-existent authentication")
+}
+
+// twoFactorAdvice replaces the upload error when PyPI rejects a password because the account
+// has two-factor authentication enabled.
+const twoFactorAdvice = "PyPI rejected the password because the account has two-factor authentication enabled, " +
+	"and uploading with a username and password is no longer supported. Create an API token and " +
+	"configure username __token__ with the token as password, or publish with Trusted Publishing"
+
+// isTwoFactorFailure reports whether upload output contains PyPI's error for password uploads
+// to accounts with two-factor authentication ("User x has two factor auth enabled, an API
+// Token or Trusted Publisher must be used to upload in place of password").
+func isTwoFactorFailure(output string) bool {
+	lower := strings.ToLower(output)
+	return strings.Contains(lower, "two factor auth enabled") || strings.Contains(lower, "two-factor authentication enabled") ||
+		strings.Contains(lower, "must be used to upload in place of password")
+}
+
+// CredentialOverride uploads files whose name matches Pattern with separate credentials,
+// for organizations that split upload permissions (e.g. sdists and wheels) across tokens.
+type CredentialOverride struct {
+	// Pattern is a glob matched against the distribution file name (e.g. "*.tar.gz").
+	Pattern string
+	// Username for the matching files.
+	Username string
+	// Password or API token for the matching files.
+	Password string
+	// PasswordEnv names an environment variable holding the password, used when Password is empty.
+	PasswordEnv string
+}
+
+// resolvedPassword returns the configured password or the value of PasswordEnv.
+func (o CredentialOverride) resolvedPassword() string {
+	if o.Password != "" {
+		return o.Password
+	}
+	if o.PasswordEnv != "" {
+		return os.Getenv(o.PasswordEnv)
+	}
+	return ""
+}
+
+// matchCredentialOverride returns the index of the first override matching name, or -1.
+func matchCredentialOverride(overrides []CredentialOverride, name string) int {
+	for i, o := range overrides {
+		if ok, _ := filepath.Match(o.Pattern, name); ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseCredentialOverrides parses the credential_overrides config list.
+func parseCredentialOverrides(raw any) []CredentialOverride {
+	items, ok := raw.([]any)
+	if !ok {
+		return nil
+	}
+
+	overrides := make([]CredentialOverride, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		o := CredentialOverride{}
+		o.Pattern, _ = m["pattern"].(string)
+		o.Username, _ = m["username"].(string)
+		o.Password, _ = m["password"].(string)
+		o.PasswordEnv, _ = m["password_env"].(string)
+		overrides = append(overrides, o)
+	}
+	return overrides
+}
+
+// validateCredentialOverrides validates the credential_overrides entries.
+func validateCredentialOverrides(overrides []CredentialOverride) error {
+	for i, o := range overrides {
+		if o.Pattern == "" {
+			return fmt.Errorf("credential_overrides[%d]: pattern is required", i)
+		}
+		if _, err := filepath.Match(o.Pattern, ""); err != nil {
+			return fmt.Errorf("credential_overrides[%d]: invalid pattern: %w", i, err)
+		}
+		if strings.ContainsAny(o.Pattern, `/\`) {
+			return fmt.Errorf("credential_overrides[%d]: pattern must match file names, not paths", i)
+		}
+		if o.Username == "" {
+			return fmt.Errorf("credential_overrides[%d]: username is required", i)
+		}
+		if o.resolvedPassword() == "" {
+			return fmt.Errorf("credential_overrides[%d]: password or password_env is required", i)
+		}
+	}
+	return nil
+}
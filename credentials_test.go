@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// fakeTokenSource returns numbered tokens expiring ttl after the fake clock.
+type fakeTokenSource struct {
+	now   func() time.Time
+	ttl   time.Duration
+	calls int
+	err   error
+}
+
+func (s *fakeTokenSource) fetch(_ context.Context) (credential, error) {
+	if s.err != nil {
+		return credential{}, s.err
+	}
+	s.calls++
+	return credential{
+		Username:  "__token__",
+		Password:  fmt.Sprintf("token-%d", s.calls),
+		ExpiresAt: s.now().Add(s.ttl),
+	}, nil
+}
+
+func TestRefreshingCredentials(t *testing.T) {
+	clock := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := func() time.Time { return clock }
+	source := &fakeTokenSource{now: now, ttl: 15 * time.Minute}
+
+	creds := newRefreshingCredentials(source, 5*time.Minute)
+	creds.now = now
+	ctx := context.Background()
+
+	cred, err := creds.get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cred.Password != "token-1" {
+		t.Errorf("expected token-1, got %s", cred.Password)
+	}
+
+	// Still well before the refresh margin
+	clock = clock.Add(5 * time.Minute)
+	if cred, _ = creds.get(ctx); cred.Password != "token-1" {
+		t.Errorf("expected cached token-1, got %s", cred.Password)
+	}
+
+	// Within the refresh margin of expiry
+	clock = clock.Add(6 * time.Minute)
+	if cred, _ = creds.get(ctx); cred.Password != "token-2" {
+		t.Errorf("expected refreshed token-2, got %s", cred.Password)
+	}
+
+	creds.invalidate()
+	if cred, _ = creds.get(ctx); cred.Password != "token-3" {
+		t.Errorf("expected token-3 after invalidate, got %s", cred.Password)
+	}
+
+	if creds.refreshCount() != 2 {
+		t.Errorf("expected 2 refreshes, got %d", creds.refreshCount())
+	}
+}
+
+func TestRefreshingCredentialsError(t *testing.T) {
+	source := &fakeTokenSource{now: time.Now, err: errors.New("boom")}
+	creds := newRefreshingCredentials(source, time.Minute)
+
+	if _, err := creds.get(context.Background()); err == nil {
+		t.Error("expected error from failing source")
+	}
+}
+
+func TestParseTokenOutput(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	exp := now.Add(time.Hour).Unix()
+	jwt := "eyJhbGciOiJIUzI1NiJ9." +
+		base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp))) +
+		".signature"
+
+	tests := []struct {
+		name          string
+		output        string
+		expectToken   string
+		expectExpiry  time.Time
+		expectErr     bool
+		expectNoToken bool
+	}{
+		{
+			name:        "plain token",
+			output:      "pypi-abc123\n",
+			expectToken: "pypi-abc123",
+		},
+		{
+			name:         "jwt",
+			output:       jwt,
+			expectToken:  jwt,
+			expectExpiry: time.Unix(exp, 0),
+		},
+		{
+			name:         "json expires_in",
+			output:       `{"access_token": "ya29.token", "expires_in": 3599}`,
+			expectToken:  "ya29.token",
+			expectExpiry: now.Add(3599 * time.Second),
+		},
+		{
+			name:         "codeartifact json",
+			output:       `{"authorizationToken": "ca-token", "expiration": "2025-01-01T13:00:00Z"}`,
+			expectToken:  "ca-token",
+			expectExpiry: time.Date(2025, 1, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "json without expiry",
+			output:      `{"token": "pypi-xyz"}`,
+			expectToken: "pypi-xyz",
+		},
+		{
+			name:      "empty output",
+			output:    "  \n",
+			expectErr: true,
+		},
+		{
+			name:      "json without token",
+			output:    `{"expires_in": 10}`,
+			expectErr: true,
+		},
+		{
+			name:      "invalid expiration",
+			output:    `{"token": "t", "expiration": "tomorrow"}`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, expiry, err := parseTokenOutput([]byte(tt.output), now)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token != tt.expectToken {
+				t.Errorf("expected token '%s', got '%s'", tt.expectToken, token)
+			}
+			if !expiry.Equal(tt.expectExpiry) {
+				t.Errorf("expected expiry %v, got %v", tt.expectExpiry, expiry)
+			}
+		})
+	}
+}
+
+func TestCommandTokenSourceLifetime(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	source := &commandTokenSource{
+		executor: &MockCommandExecutor{ReturnOut: []byte("plain-token\n")},
+		command:  []string{"print-token"},
+		username: "aws",
+		lifetime: 12 * time.Hour,
+		now:      func() time.Time { return now },
+	}
+
+	cred, err := source.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cred.Username != "aws" || cred.Password != "plain-token" {
+		t.Errorf("unexpected credential: %+v", cred)
+	}
+	if !cred.ExpiresAt.Equal(now.Add(12 * time.Hour)) {
+		t.Errorf("expected lifetime-based expiry, got %v", cred.ExpiresAt)
+	}
+}
+
+func TestValidateCredentialOverrides(t *testing.T) {
+	tests := []struct {
+		name        string
+		overrides   []CredentialOverride
+		errContains string
+	}{
+		{"none", nil, ""},
+		{"valid", []CredentialOverride{{Pattern: "*.whl", Username: "__token__", Password: "t"}}, ""},
+		{"missing pattern", []CredentialOverride{{Username: "u", Password: "p"}}, "pattern is required"},
+		{"bad pattern", []CredentialOverride{{Pattern: "[", Username: "u", Password: "p"}}, "invalid pattern"},
+		{"path pattern", []CredentialOverride{{Pattern: "dist/*.whl", Username: "u", Password: "p"}}, "file names"},
+		{"missing username", []CredentialOverride{{Pattern: "*.whl", Password: "p"}}, "username is required"},
+		{"missing password", []CredentialOverride{{Pattern: "*.whl", Username: "u", PasswordEnv: "UNSET_TEST_TOKEN"}}, "password or password_env"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCredentialOverrides(tt.overrides)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing '%s', got %v", tt.errContains, err)
+			}
+		})
+	}
+}
+
+func TestParseCredentialOverrides(t *testing.T) {
+	overrides := parseCredentialOverrides([]any{
+		map[string]any{"pattern": "*.tar.gz", "username": "sdist", "password": "p1"},
+		"not-a-map",
+		map[string]any{"pattern": "*.whl", "username": "wheel", "password_env": "WHEEL_TOKEN"},
+	})
+
+	if len(overrides) != 2 {
+		t.Fatalf("expected 2 overrides, got %d", len(overrides))
+	}
+	if overrides[0].Username != "sdist" || overrides[0].Password != "p1" {
+		t.Errorf("unexpected first override: %+v", overrides[0])
+	}
+	if overrides[1].PasswordEnv != "WHEEL_TOKEN" {
+		t.Errorf("unexpected second override: %+v", overrides[1])
+	}
+}
+
+// twoFactorOutput is twine's output when PyPI rejects a password of a 2FA-enabled account.
+const twoFactorOutput = `Uploading mypkg-1.0.0.tar.gz
+ERROR    HTTPError: 401 Unauthorized from https://upload.pypi.org/legacy/
+         User release-bot has two factor auth enabled, an API Token or Trusted Publisher must be used to upload in place of password.
+`
+
+func TestExecuteTwoFactorFailure(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte(twoFactorOutput), errors.New("exit status 1")
+		},
+	}}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":   "release-bot",
+			"password":   "hunter22",
+			"repository": "http://localhost:8080/",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.HasPrefix(resp.Error, twoFactorAdvice) {
+		t.Errorf("expected the two-factor advice, got %q", resp.Error)
+	}
+	if resp.Outputs["auth_failure"] != "two_factor_required" {
+		t.Errorf("unexpected outputs %v", resp.Outputs)
+	}
+	if isTwoFactorFailure("HTTPError: 403 Forbidden") {
+		t.Error("expected other auth failures not to match")
+	}
+}
+
+func TestCommandTokenSourceIgnoresStderr(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	source := &commandTokenSource{
+		executor: &MockCommandExecutor{
+			ReturnOut:    []byte(`{"token": "json-token", "expires_in": 3600}` + "\n"),
+			ReturnStderr: []byte("WARNING: Could not open the configuration file: [/root/.config/gcloud/configurations/config_default].\n"),
+		},
+		command:  []string{"gcloud", "auth", "print-access-token"},
+		username: "oauth2accesstoken",
+		now:      func() time.Time { return now },
+	}
+
+	cred, err := source.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cred.Password != "json-token" || !cred.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected credential: %+v", cred)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// customCommandVarPattern matches the {name} and {env.NAME} variables of custom_command.
+var customCommandVarPattern = regexp.MustCompile(`\{([a-z_]+(?:\.[A-Za-z_][A-Za-z0-9_]*)?)\}`)
+
+// customCommandFiles is the variable expanding to one argument per distribution file.
+const customCommandFiles = "{files}"
+
+// customCommandVars are the variables substituted within arguments of custom_command.
+var customCommandVars = []string{"repository", "dist_path", "version", "username", "password"}
+
+// renderCustomCommand substitutes the variables of cfg.CustomCommand. Each template argument
+// stays a single argument: substituted values are not split, quoted or expanded again, and
+// no shell is involved. An argument that is exactly {files} expands to the distribution
+// files. With redact set, credentials are replaced so the command can be reported.
+func renderCustomCommand(cfg Config, version string, files []string, redact bool) ([]string, error) {
+	var rendered []string
+	for i, arg := range cfg.CustomCommand {
+		if arg == customCommandFiles {
+			if i == 0 {
+				return nil, fmt.Errorf("custom_command must start with the executable")
+			}
+			rendered = append(rendered, files...)
+			continue
+		}
+
+		var err error
+		value := customCommandVarPattern.ReplaceAllStringFunc(arg, func(match string) string {
+			name := match[1 : len(match)-1]
+			v, secret, varErr := customCommandValue(cfg, version, name)
+			if varErr != nil && err == nil {
+				err = varErr
+			}
+			if secret && redact {
+				return redactedValue
+			}
+			return v
+		})
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && value != arg {
+			return nil, fmt.Errorf("the custom_command executable must not contain variables")
+		}
+		rendered = append(rendered, value)
+	}
+	return rendered, nil
+}
+
+// renderCommandTemplate substitutes vars in the arguments of the command configured in option,
+// such as the build commands. Like custom_command, each argument stays a single argument, no
+// shell is involved, and the executable must not contain variables.
+func renderCommandTemplate(option string, command []string, vars map[string]string) ([]string, error) {
+	rendered := make([]string, 0, len(command))
+	for i, arg := range command {
+		var err error
+		value := customCommandVarPattern.ReplaceAllStringFunc(arg, func(match string) string {
+			name := match[1 : len(match)-1]
+			v, ok := vars[name]
+			if !ok && err == nil {
+				names := make([]string, 0, len(vars))
+				for n := range vars {
+					names = append(names, n)
+				}
+				sort.Strings(names)
+				err = fmt.Errorf("unknown %s variable {%s} (use %s)", option, name, "{"+strings.Join(names, "}, {")+"}")
+			}
+			return v
+		})
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && value != arg {
+			return nil, fmt.Errorf("the %s executable must not contain variables", option)
+		}
+		rendered = append(rendered, value)
+	}
+	return rendered, nil
+}
+
+// customCommandValue returns the value of a custom_command variable and whether it is secret.
+func customCommandValue(cfg Config, version, name string) (string, bool, error) {
+	if env, ok := strings.CutPrefix(name, "env."); ok {
+		v, set := os.LookupEnv(env)
+		if !set {
+			return "", true, fmt.Errorf("custom_command references {%s} but %s is not set", name, env)
+		}
+		return v, true, nil
+	}
+	switch name {
+	case "repository":
+		return cfg.Repository, false, nil
+	case "dist_path":
+		return cfg.DistPath, false, nil
+	case "version":
+		return version, false, nil
+	case "username":
+		if cfg.Username == "" {
+			return "", false, fmt.Errorf("custom_command references {username} but no username is configured")
+		}
+		return cfg.Username, false, nil
+	case "password":
+		if cfg.Password == "" {
+			return "", true, fmt.Errorf("custom_command references {password} but no password is configured")
+		}
+		return cfg.Password, true, nil
+	case "files":
+		return "", false, fmt.Errorf("{files} must be a custom_command argument of its own")
+	default:
+		return "", false, fmt.Errorf("unknown custom_command variable {%s} (use %s, {files} or {env.NAME})",
+			name, "{"+strings.Join(customCommandVars, "}, {")+"}")
+	}
+}
+
+// customCommandSecrets returns the values of the environment variables custom_command
+// references, so they can be redacted like passwords.
+func customCommandSecrets(cfg Config) []string {
+	var secrets []string
+	for _, arg := range cfg.CustomCommand {
+		for _, m := range customCommandVarPattern.FindAllStringSubmatch(arg, -1) {
+			if env, ok := strings.CutPrefix(m[1], "env."); ok {
+				secrets = append(secrets, os.Getenv(env))
+			}
+		}
+	}
+	return secrets
+}
+
+// runCustomCommand uploads files with the configured custom command instead of twine.
+func (p *PyPIPlugin) runCustomCommand(ctx context.Context, cfg Config, executor CommandExecutor, version string, files []string) (uploadRun, error) {
+	if len(files) == 0 {
+		return uploadRun{}, fmt.Errorf("no distribution files match %s", cfg.DistPath)
+	}
+	argv, err := renderCustomCommand(cfg, version, files, false)
+	if err != nil {
+		return uploadRun{}, err
+	}
+	start := time.Now()
+	output, err := executor.Run(ctx, argv[0], argv[1:]...)
+	run := uploadRun{output: string(output)}
+	run.recordTiming(files, start)
+	return run, err
+}
+
+// validateCustomCommand validates the custom_command template. The files always come from
+// dist_path, so the template must pass them with {files}, and options that only apply to
+// twine or the built-in uploader are rejected.
+func validateCustomCommand(cfg Config) error {
+	if len(cfg.CustomCommand) == 0 {
+		return nil
+	}
+	if strings.TrimSpace(cfg.CustomCommand[0]) == "" {
+		return fmt.Errorf("custom_command must start with the executable")
+	}
+	if !containsString(cfg.CustomCommand, customCommandFiles) {
+		return fmt.Errorf("custom_command must pass the distribution files with a {files} argument")
+	}
+	switch {
+	case len(cfg.ExtraArgs) > 0:
+		return fmt.Errorf("extra_args cannot be combined with custom_command; add the arguments to the template")
+	case len(cfg.TokenCommand) > 0:
+		return fmt.Errorf("token_command cannot be combined with custom_command")
+	case len(cfg.CredentialOverrides) > 0:
+		return fmt.Errorf("credential_overrides cannot be combined with custom_command")
+	case usesNativeUploader(cfg) || usesClientCert(cfg):
+		return fmt.Errorf("custom_command cannot be combined with auth, client certificate or connection options of the built-in uploader")
+	}
+	_, err := renderCustomCommand(cfg, "", nil, true)
+	return err
+}
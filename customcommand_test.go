@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestRenderCustomCommand(t *testing.T) {
+	t.Setenv("ACME_TOKEN", "acme-secret; rm -rf /")
+	cfg := Config{
+		Repository:    "https://pkgs.example.com/upload",
+		Username:      "ci",
+		Password:      "pass",
+		CustomCommand: []string{"acme-pkg", "push", "--to={repository}", "--token", "{env.ACME_TOKEN}", "--tag", "v{version}", "{files}"},
+	}
+	files := []string{"dist/a.whl", "dist/a.tar.gz"}
+
+	got, err := renderCustomCommand(cfg, "1.2.0", files, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"acme-pkg", "push", "--to=https://pkgs.example.com/upload", "--token", "acme-secret; rm -rf /", "--tag", "v1.2.0", "dist/a.whl", "dist/a.tar.gz"}
+	if strings.Join(got, "\x00") != strings.Join(want, "\x00") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	redacted, _ := renderCustomCommand(cfg, "1.2.0", files, true)
+	if redacted[4] != redactedValue {
+		t.Errorf("expected the env value to be redacted, got %q", redacted)
+	}
+}
+
+func TestValidateCustomCommand(t *testing.T) {
+	t.Setenv("ACME_TOKEN", "token")
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{"unset", Config{}, ""},
+		{"valid", Config{CustomCommand: []string{"acme-pkg", "push", "{repository}", "{env.ACME_TOKEN}", "{files}"}}, ""},
+		{"missing files", Config{CustomCommand: []string{"acme-pkg", "push", "dist/*"}}, "{files}"},
+		{"embedded files", Config{CustomCommand: []string{"acme-pkg", "--files={files}", "{files}"}}, "of its own"},
+		{"unknown variable", Config{CustomCommand: []string{"acme-pkg", "{token}", "{files}"}}, "unknown custom_command variable"},
+		{"unset env", Config{CustomCommand: []string{"acme-pkg", "{env.ACME_MISSING}", "{files}"}}, "ACME_MISSING is not set"},
+		{"missing password", Config{CustomCommand: []string{"acme-pkg", "{password}", "{files}"}}, "no password"},
+		{"templated executable", Config{CustomCommand: []string{"{env.ACME_TOKEN}", "{files}"}}, "executable"},
+		{"extra args", Config{CustomCommand: []string{"acme-pkg", "{files}"}, ExtraArgs: []string{"--verbose"}}, "extra_args"},
+		{"native auth", Config{CustomCommand: []string{"acme-pkg", "{files}"}, AuthScheme: authSchemeBearer}, "built-in uploader"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCustomCommand(tt.cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExecuteCustomCommand(t *testing.T) {
+	pattern := writeDistFiles(t, "mypkg-1.0.0.tar.gz", "mypkg-1.0.0-py3-none-any.whl")
+	t.Setenv("ACME_TOKEN", "acme-token")
+
+	var gotName string
+	var gotArgs []string
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			gotName, gotArgs = name, args
+			return []byte("pushed 2 files"), nil
+		},
+	}}
+	config := map[string]any{
+		"repository":     "http://localhost:8080/upload",
+		"dist_path":      pattern,
+		"custom_command": []any{"acme-pkg", "push", "--token", "{env.ACME_TOKEN}", "{files}"},
+	}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config, DryRun: true})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected dry run result: %v %+v", err, resp)
+	}
+	if command, _ := resp.Outputs["custom_command"].([]string); len(command) != 6 || command[3] != redactedValue {
+		t.Errorf("expected the redacted command in the dry run, got %v", resp.Outputs["custom_command"])
+	}
+	if gotName != "" {
+		t.Error("expected the dry run not to run the command")
+	}
+
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected result: %v %+v", err, resp)
+	}
+	if gotName != "acme-pkg" || len(gotArgs) != 5 || gotArgs[2] != "acme-token" {
+		t.Errorf("unexpected command %s %q", gotName, gotArgs)
+	}
+	for _, file := range gotArgs[3:] {
+		if filepath.Dir(file) != "dist" {
+			t.Errorf("unexpected file argument %q", file)
+		}
+	}
+	if resp.Outputs["output"] != "pushed 2 files" {
+		t.Errorf("unexpected output %v", resp.Outputs["output"])
+	}
+}
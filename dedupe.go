@@ -0,0 +1,64 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// duplicateFile is a distribution skipped because a file with the same content is uploaded
+// in the same run.
+type duplicateFile struct {
+	Path        string `json:"path"`
+	DuplicateOf string `json:"duplicate_of"`
+	SHA256      string `json:"sha256"`
+}
+
+// uploadedDigests records the SHA-256 digests of the files uploaded to each repository in a
+// run, shared by all packages of a batch.
+type uploadedDigests struct {
+	mu    sync.Mutex
+	paths map[[2]string]string
+}
+
+// newUploadedDigests creates an empty digest registry.
+func newUploadedDigests() *uploadedDigests {
+	return &uploadedDigests{paths: make(map[[2]string]string)}
+}
+
+// claim records path as the upload of digest to repository. If the digest was claimed for the
+// repository before, it returns the path that claimed it and false.
+func (d *uploadedDigests) claim(repository, digest, path string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := [2]string{repository, digest}
+	if prev, ok := d.paths[key]; ok {
+		return prev, false
+	}
+	d.paths[key] = path
+	return path, true
+}
+
+// dedupeDistFiles drops files whose content was already claimed for repository in this run,
+// either earlier in files or by another package. Uploads of the same file to different
+// repositories are kept. Files that cannot be read are kept so the upload reports them.
+func dedupeDistFiles(files []string, repository string, digests *uploadedDigests) ([]string, []duplicateFile) {
+	unique := make([]string, 0, len(files))
+	var duplicates []duplicateFile
+	for _, f := range files {
+		_, digest, _, err := fileDigests(f)
+		if err != nil {
+			unique = append(unique, f)
+			continue
+		}
+		if prev, ok := digests.claim(repository, digest, f); !ok {
+			duplicates = append(duplicates, duplicateFile{
+				Path:        filepath.ToSlash(f),
+				DuplicateOf: filepath.ToSlash(prev),
+				SHA256:      digest,
+			})
+			continue
+		}
+		unique = append(unique, f)
+	}
+	return unique, duplicates
+}
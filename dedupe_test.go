@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestDedupeDistFiles(t *testing.T) {
+	writeDistFiles(t, "pkg-1.0.tar.gz", "pkg-1.0-py3-none-any.whl")
+	if err := os.WriteFile(filepath.Join("dist", "pkg-1.0.copy.tar.gz"), []byte("pkg-1.0.tar.gz"), 0o600); err != nil {
+		t.Fatalf("failed to write copy: %v", err)
+	}
+	files, err := expandDistGlob("dist/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	digests := newUploadedDigests()
+	unique, duplicates := dedupeDistFiles(files, "https://pypi.example.com/", digests)
+	if len(unique) != 2 || len(duplicates) != 1 {
+		t.Fatalf("expected 2 unique files and 1 duplicate, got %v / %+v", unique, duplicates)
+	}
+	if duplicates[0].Path != "dist/pkg-1.0.tar.gz" || duplicates[0].DuplicateOf != "dist/pkg-1.0.copy.tar.gz" || duplicates[0].SHA256 == "" {
+		t.Errorf("unexpected duplicate: %+v", duplicates[0])
+	}
+
+	// A second package matching the same files uploads nothing
+	unique, duplicates = dedupeDistFiles(files, "https://pypi.example.com/", digests)
+	if len(unique) != 0 || len(duplicates) != 3 {
+		t.Errorf("expected every file to be a duplicate, got %v / %+v", unique, duplicates)
+	}
+
+	// Publishing the same files to another repository is not a duplicate
+	if unique, _ = dedupeDistFiles(files, "https://mirror.example.com/", digests); len(unique) != 2 {
+		t.Errorf("expected distinct files to be uploaded to the mirror, got %v", unique)
+	}
+}
+
+func TestExecuteDeduplicatesFiles(t *testing.T) {
+	writeDistFiles(t)
+	for _, dir := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join("dist", dir), 0o750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join("dist", dir, "pkg-1.0.tar.gz"), []byte("same bytes"), 0o600); err != nil {
+			t.Fatalf("failed to write dist: %v", err)
+		}
+	}
+
+	exec := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("ok"), nil
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: exec}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":   "__token__",
+			"password":   "pypi-token",
+			"repository": "http://localhost:8080/",
+			"dist_path":  "dist/*/*",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got: %s", resp.Error)
+	}
+
+	if len(exec.RunCalls) != 1 {
+		t.Fatalf("expected 1 twine call, got %d", len(exec.RunCalls))
+	}
+	args := exec.RunCalls[0].Args
+	if got := args[len(args)-1]; got != filepath.Join("dist", "a", "pkg-1.0.tar.gz") {
+		t.Errorf("expected only the first copy to be uploaded, got args %v", args)
+	}
+	warnings, _ := resp.Outputs["warnings"].([]string)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "dist/b/pkg-1.0.tar.gz") {
+		t.Errorf("expected duplicate warning, got %v", warnings)
+	}
+	duplicates, _ := resp.Outputs["duplicate_files"].([]duplicateFile)
+	if len(duplicates) != 1 || duplicates[0].DuplicateOf != "dist/a/pkg-1.0.tar.gz" {
+		t.Errorf("unexpected duplicate_files: %+v", duplicates)
+	}
+}
+
+func TestExecuteBatchDeduplicatesOverlappingPackages(t *testing.T) {
+	writeDistFiles(t, "pkg-1.0.tar.gz")
+
+	exec := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("ok"), nil
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: exec}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":          "__token__",
+			"password":          "pypi-token",
+			"repository":        "http://localhost:8080/",
+			"batch_concurrency": 1,
+			"packages": []any{
+				map[string]any{"name": "first", "dist_path": "dist/*"},
+				map[string]any{"name": "second", "dist_path": "dist/*.tar.gz"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got: %s", resp.Error)
+	}
+	if len(exec.RunCalls) != 1 {
+		t.Errorf("expected the shared file to be uploaded once, got %d twine calls", len(exec.RunCalls))
+	}
+
+	results, _ := resp.Outputs["packages"].([]batchPackageResult)
+	if len(results) != 2 || !reflect.DeepEqual(results[1].Outputs["duplicate_files"], []duplicateFile{{
+		Path:        "dist/pkg-1.0.tar.gz",
+		DuplicateOf: "dist/pkg-1.0.tar.gz",
+		SHA256:      sha256Hex("pkg-1.0.tar.gz"),
+	}}) {
+		t.Errorf("expected second package to report the duplicate, got %+v", results)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dependencyChange is a dependency whose constraint differs from the previous release.
+type dependencyChange struct {
+	Name     string `json:"name"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+}
+
+// dependencyDelta compares the Requires-Dist of the new distributions with the previous release
+// on the index, reported in outputs for the dependency changes of release notes.
+type dependencyDelta struct {
+	Project string `json:"project"`
+	Version string `json:"version"`
+	// PreviousVersion is empty for the first release, whose dependencies are all added
+	PreviousVersion string             `json:"previous_version,omitempty"`
+	Added           []string           `json:"added"`
+	Removed         []string           `json:"removed"`
+	Changed         []dependencyChange `json:"changed"`
+}
+
+// requirementKey identifies a dependency across releases: its name under its environment
+// marker, as a project may constrain a dependency differently per environment.
+func requirementKey(r requirement) string {
+	return normalizeProjectName(r.Name) + ";" + normalizeMarker(r.Marker)
+}
+
+// normalizeMarker removes the spacing and quoting differences of equivalent markers.
+func normalizeMarker(marker string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(marker), ""), "'", `"`)
+}
+
+// requirementConstraint returns the extras and specifier of r in a canonical order.
+func requirementConstraint(r requirement) string {
+	specifiers := strings.Split(r.Specifier, ",")
+	sort.Strings(specifiers)
+	extras := strings.Split(strings.Trim(strings.ReplaceAll(r.Extras, " ", ""), "[]"), ",")
+	sort.Strings(extras)
+	return strings.Join(extras, ",") + "|" + strings.Join(specifiers, ",")
+}
+
+// formatRequirement renders r as a requirement line.
+func formatRequirement(r requirement) string {
+	s := r.Name + strings.ReplaceAll(r.Extras, " ", "") + r.Specifier
+	if r.Marker != "" {
+		s += "; " + r.Marker
+	}
+	return s
+}
+
+// requirementsByKey parses Requires-Dist lines keyed by requirementKey; the first line of a key
+// wins.
+func requirementsByKey(lines []string) map[string]requirement {
+	reqs := map[string]requirement{}
+	for _, line := range lines {
+		if r, ok := parseRequirement(line); ok {
+			if _, seen := reqs[requirementKey(r)]; !seen {
+				reqs[requirementKey(r)] = r
+			}
+		}
+	}
+	return reqs
+}
+
+// diffDependencies returns the dependencies added, removed and changed from previous to current.
+func diffDependencies(previous, current []string) ([]string, []string, []dependencyChange) {
+	before, after := requirementsByKey(previous), requirementsByKey(current)
+	added, removed, changed := []string{}, []string{}, []dependencyChange{}
+	for _, key := range sortedRequirementKeys(after) {
+		r := after[key]
+		old, ok := before[key]
+		switch {
+		case !ok:
+			added = append(added, formatRequirement(r))
+		case requirementConstraint(old) != requirementConstraint(r):
+			changed = append(changed, dependencyChange{Name: r.Name, Previous: formatRequirement(old), Current: formatRequirement(r)})
+		}
+	}
+	for _, key := range sortedRequirementKeys(before) {
+		if _, ok := after[key]; !ok {
+			removed = append(removed, formatRequirement(before[key]))
+		}
+	}
+	return added, removed, changed
+}
+
+// sortedRequirementKeys returns the keys of reqs in order.
+func sortedRequirementKeys(reqs map[string]requirement) []string {
+	keys := make([]string, 0, len(reqs))
+	for k := range reqs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// previousRelease returns the highest version of project on the JSON API below version, or ""
+// when there is none. Releases whose files are all yanked are ignored, and a final release is
+// compared with the previous final release rather than its pre-releases.
+func (p *PyPIPlugin) previousRelease(ctx context.Context, cfg Config, project, version string) (string, error) {
+	var doc struct {
+		Releases map[string][]struct {
+			Yanked bool `json:"yanked"`
+		} `json:"releases"`
+	}
+	found, err := p.getJSONAPI(ctx, cfg.JSONAPIURL, project, &doc)
+	if err != nil || !found {
+		return "", err
+	}
+	final := releaseChannel(version) == channelStable || releaseChannel(version) == channelPost
+	previous := ""
+	for v, files := range doc.Releases {
+		available := false
+		for _, f := range files {
+			available = available || !f.Yanked
+		}
+		if !available {
+			continue
+		}
+		if channel := releaseChannel(v); final && channel != channelStable && channel != channelPost {
+			continue
+		}
+		if c, ok := compareVersions(v, version); !ok || c >= 0 {
+			continue
+		}
+		if c, _ := compareVersions(v, previous); previous == "" || c > 0 {
+			previous = v
+		}
+	}
+	return previous, nil
+}
+
+// reportDependencyChanges compares the dependencies of the distributions with those of the
+// previous release. The report is informational: when the index cannot be read it is skipped
+// with a warning.
+func (p *PyPIPlugin) reportDependencyChanges(ctx context.Context, cfg Config, result *preflightResult) {
+	project, version := distProjectVersion(result.files)
+	if project == "" {
+		return
+	}
+	var current []string
+	for _, f := range result.files {
+		if meta, err := readDistMetadata(f); err == nil {
+			current = append(current, meta.RequiresDist...)
+		}
+	}
+
+	previous, err := p.previousRelease(ctx, cfg, project, version)
+	if err != nil {
+		result.warn("dependency report skipped: %v", err)
+		return
+	}
+	var before []string
+	if previous != "" {
+		release, err := p.fetchJSONRelease(ctx, cfg.JSONAPIURL, project, previous)
+		if err == nil && release == nil {
+			err = fmt.Errorf("not found")
+		}
+		if err != nil {
+			result.warn("dependency report skipped: cannot read %s %s: %v", project, previous, err)
+			return
+		}
+		before = release.Info.RequiresDist
+	}
+
+	delta := &dependencyDelta{Project: project, Version: version, PreviousVersion: previous}
+	delta.Added, delta.Removed, delta.Changed = diffDependencies(before, current)
+	result.outputs["dependency_changes"] = delta
+}
+
+// validateDependencyReportConfig validates the dependency_report options.
+func validateDependencyReportConfig(cfg Config) error {
+	if !cfg.DependencyReport {
+		return nil
+	}
+	if cfg.JSONAPIURL == "" {
+		return fmt.Errorf("dependency_report requires json_api_url for repositories other than PyPI and TestPyPI")
+	}
+	return validateJSONAPIURL(cfg)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestDiffDependencies(t *testing.T) {
+	previous := []string{
+		"requests (>=2.28)",
+		"click>=8,<9",
+		"tomli>=1.1; python_version < '3.11'",
+		"rich[jupyter]>=12",
+	}
+	current := []string{
+		"requests>=2.31",
+		"click<9,>=8",
+		"tomli>=1.1 ; python_version<\"3.11\"",
+		"rich>=12",
+		"httpx>=0.27; extra == 'http'",
+	}
+	added, removed, changed := diffDependencies(previous, current)
+	if fmt.Sprint(added) != "[httpx>=0.27; extra == 'http']" {
+		t.Errorf("unexpected added %v", added)
+	}
+	if len(removed) != 0 {
+		t.Errorf("unexpected removed %v", removed)
+	}
+	if fmt.Sprint(changed) != "[{requests requests>=2.28 requests>=2.31} {rich rich[jupyter]>=12 rich>=12}]" {
+		t.Errorf("unexpected changed %v", changed)
+	}
+
+	added, removed, _ = diffDependencies(current, nil)
+	if len(added) != 0 || len(removed) != 5 {
+		t.Errorf("expected every dependency to be removed, got %v %v", added, removed)
+	}
+}
+
+func TestExecuteDependencyReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pypi/mypkg/json":
+			_, _ = w.Write([]byte(`{"releases": {
+				"1.0.0": [{"yanked": false}],
+				"1.1.0": [{"yanked": false}],
+				"1.2.0": [{"yanked": true}],
+				"2.0.0rc1": [{"yanked": false}],
+				"2.0.0": [],
+				"2.1.0": [{"yanked": false}]
+			}}`))
+		case "/pypi/mypkg/1.1.0/json":
+			_, _ = w.Write([]byte(`{"info": {"requires_dist": ["requests>=2.28", "six"]}, "urls": []}`))
+		case "/pypi/mypkg/2.0.0rc1/json":
+			_, _ = w.Write([]byte(`{"info": {"requires_dist": ["requests>=2.31"]}, "urls": []}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		version  string
+		previous string
+		want     string
+	}{
+		{"2.0.0", "1.1.0", "added [] removed [six] changed [{requests requests>=2.28 requests>=2.31}]"},
+		{"2.0.0rc2", "2.0.0rc1", "added [] removed [] changed []"},
+		{"0.9.0", "", "added [requests>=2.31] removed [] changed []"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			writeDistFiles(t)
+			writeTestWheel(t, filepath.Join("dist", "mypkg-"+tt.version+"-py3-none-any.whl"), map[string]string{
+				"mypkg-" + tt.version + ".dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: " + tt.version + "\nRequires-Dist: requests>=2.31\n",
+			})
+			p := &PyPIPlugin{httpClient: server.Client()}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"username":          "deployer",
+					"password":          "secret",
+					"repository":        "http://localhost:8080/",
+					"dependency_report": true,
+					"json_api_url":      server.URL + "/pypi",
+				},
+				DryRun: true,
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected success, got %v %+v", err, resp)
+			}
+			delta, _ := resp.Outputs["dependency_changes"].(*dependencyDelta)
+			if delta == nil {
+				t.Fatalf("expected dependency_changes, got %v", resp.Outputs)
+			}
+			got := fmt.Sprintf("added %v removed %v changed %v", delta.Added, delta.Removed, delta.Changed)
+			if delta.PreviousVersion != tt.previous || got != tt.want {
+				t.Errorf("got previous %q and %s; want %q and %s", delta.PreviousVersion, got, tt.previous, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteDependencyReportUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	p := &PyPIPlugin{httpClient: server.Client()}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":          "deployer",
+			"password":          "secret",
+			"repository":        "http://localhost:8080/",
+			"dependency_report": true,
+			"json_api_url":      server.URL + "/pypi",
+		},
+		DryRun: true,
+	})
+	if err != nil || !resp.Success || resp.Outputs["dependency_changes"] != nil {
+		t.Fatalf("expected the report to be skipped, got %v %+v", err, resp)
+	}
+	if warnings := fmt.Sprint(resp.Outputs["warnings"]); !strings.Contains(warnings, "dependency report skipped") {
+		t.Errorf("expected a warning, got %s", warnings)
+	}
+}
+
+func TestValidateDependencyReportConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"disabled", Config{}, false},
+		{"pypi", Config{DependencyReport: true, Repository: "https://upload.pypi.org/legacy/", JSONAPIURL: defaultJSONAPI("https://upload.pypi.org/legacy/")}, false},
+		{"no json api", Config{DependencyReport: true, Repository: "http://localhost:8080/", IndexURL: "http://localhost:8080/simple/"}, true},
+		{"invalid json api", Config{DependencyReport: true, JSONAPIURL: "ftp://localhost/pypi"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateDependencyReportConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateDependencyReportConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
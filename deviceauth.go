@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// OAuth 2.0 device authorization grant (RFC 8628).
+const (
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	// deviceAuthorizationPending and deviceSlowDown keep the client polling; any other error
+	// ends the flow
+	deviceAuthorizationPending = "authorization_pending"
+	deviceSlowDown             = "slow_down"
+	// deviceSlowDownIncrement is added to the poll interval each time the server asks for it
+	deviceSlowDownIncrement     = 5 * time.Second
+	defaultDevicePollInterval   = 5 * time.Second
+	defaultDeviceCodeExpiration = 15 * time.Minute
+)
+
+// deviceAuthorization describes the approved device login of a publish, reported in outputs.
+type deviceAuthorization struct {
+	VerificationURI string `json:"verification_uri"`
+	UserCode        string `json:"user_code"`
+	ApprovedAt      string `json:"approved_at"`
+	// ExpiresAt is when the access token expires, if the server said so
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// oauthError is the error body of an OAuth token or device authorization endpoint.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *oauthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// getPromptOutput returns where interactive prompts are written, defaulting to stderr, which
+// the release tool shows on the terminal.
+func (p *PyPIPlugin) getPromptOutput() io.Writer {
+	if p.promptOutput != nil {
+		return p.promptOutput
+	}
+	return os.Stderr
+}
+
+// postOAuthForm posts a form to an OAuth token or device authorization endpoint and decodes the JSON response into result.
+// An error response carrying an OAuth error code is returned as an *oauthError.
+func (p *PyPIPlugin) postOAuthForm(ctx context.Context, target string, form url.Values, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create OAuth request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.getHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("OAuth request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIntegrationResponseSize))
+	if err != nil {
+		return fmt.Errorf("OAuth request failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var oerr oauthError
+		if json.Unmarshal(body, &oerr) == nil && oerr.Code != "" {
+			return &oerr
+		}
+		detail, _ := truncateOutput(strings.TrimSpace(string(body)), defaultMaxErrorBodyBytes)
+		return fmt.Errorf("OAuth request failed: %s: %s", resp.Status, detail)
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("invalid OAuth response: %w", err)
+	}
+	return nil
+}
+
+// authorizeDevice obtains an access token with the device authorization grant: it prints the
+// verification URL and user code, then polls the token endpoint until the maintainer approves
+// the login in a browser, denies it, or the code expires. It needs a person at the terminal and
+// refuses to run in CI.
+func (p *PyPIPlugin) authorizeDevice(ctx context.Context, cfg Config) (string, *deviceAuthorization, error) {
+	if runningInCI() {
+		return "", nil, fmt.Errorf("device_auth needs a maintainer to approve the login and cannot run in CI; use trusted_publishing or a token there")
+	}
+
+	form := url.Values{"client_id": {cfg.DeviceClientID}}
+	if cfg.DeviceScope != "" {
+		form.Set("scope", cfg.DeviceScope)
+	}
+	var code struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+	if err := p.postOAuthForm(ctx, cfg.DeviceAuthURL, form, &code); err != nil {
+		return "", nil, fmt.Errorf("failed to start the device login: %w", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" || code.VerificationURI == "" {
+		return "", nil, fmt.Errorf("failed to start the device login: %s returned no device code", cfg.DeviceAuthURL)
+	}
+
+	expiration := defaultDeviceCodeExpiration
+	if code.ExpiresIn > 0 {
+		expiration = time.Duration(code.ExpiresIn) * time.Second
+	}
+	interval := cfg.DevicePollInterval
+	if server := time.Duration(code.Interval) * time.Second; server > interval {
+		interval = server
+	}
+
+	out := p.getPromptOutput()
+	fmt.Fprintf(out, "To authorize the upload to %s, open %s and enter the code %s\n", cfg.Repository, code.VerificationURI, code.UserCode)
+	if code.VerificationURIComplete != "" {
+		fmt.Fprintf(out, "or open %s\n", code.VerificationURIComplete)
+	}
+	fmt.Fprintf(out, "Waiting for approval (the code expires in %s)...\n", expiration)
+
+	poll := url.Values{
+		"grant_type":  {deviceCodeGrantType},
+		"device_code": {code.DeviceCode},
+		"client_id":   {cfg.DeviceClientID},
+	}
+	deadline := time.Now().Add(expiration)
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+		}
+		err := p.postOAuthForm(ctx, cfg.DeviceTokenURL, poll, &token)
+		if err == nil {
+			if token.AccessToken == "" {
+				return "", nil, fmt.Errorf("device login failed: %s returned no access token", cfg.DeviceTokenURL)
+			}
+			fmt.Fprintln(out, "Upload authorized.")
+			now := time.Now()
+			authorization := &deviceAuthorization{
+				VerificationURI: code.VerificationURI,
+				UserCode:        code.UserCode,
+				ApprovedAt:      formatTimestamp(now),
+			}
+			if token.ExpiresIn > 0 {
+				authorization.ExpiresAt = formatTimestamp(now.Add(time.Duration(token.ExpiresIn) * time.Second))
+			}
+			return token.AccessToken, authorization, nil
+		}
+
+		oerr, ok := err.(*oauthError)
+		switch {
+		case ok && oerr.Code == deviceAuthorizationPending:
+		case ok && oerr.Code == deviceSlowDown:
+			interval += deviceSlowDownIncrement
+		case ok && oerr.Code == "access_denied":
+			return "", nil, fmt.Errorf("device login was denied")
+		case ok && oerr.Code == "expired_token":
+			return "", nil, fmt.Errorf("device login code %s expired before it was approved", code.UserCode)
+		default:
+			return "", nil, fmt.Errorf("device login failed: %w", err)
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return "", nil, fmt.Errorf("device login code %s was not approved within %s", code.UserCode, expiration)
+		}
+	}
+}
+
+// validateDeviceAuthConfig validates the device_auth options.
+func validateDeviceAuthConfig(cfg Config) error {
+	if !cfg.DeviceAuth {
+		if cfg.DeviceAuthURL != "" || cfg.DeviceTokenURL != "" || cfg.DeviceClientID != "" {
+			return fmt.Errorf("device_auth_url, device_token_url and device_client_id require device_auth")
+		}
+		return nil
+	}
+	switch {
+	case cfg.DeviceAuthURL == "" || cfg.DeviceTokenURL == "" || cfg.DeviceClientID == "":
+		return fmt.Errorf("device_auth requires device_auth_url, device_token_url and device_client_id")
+	case len(cfg.TokenCommand) > 0 || cfg.TrustedPublishing || len(cfg.CredentialOverrides) > 0:
+		return fmt.Errorf("device_auth cannot be combined with token_command, trusted_publishing or credential_overrides")
+	case cfg.SpiffeWorkloadAPI || cfg.AuthScheme == authSchemeSigV4:
+		return fmt.Errorf("device_auth cannot be combined with spiffe_workload_api or auth_scheme sigv4")
+	case cfg.DevicePollInterval <= 0:
+		return fmt.Errorf("device_poll_interval must be positive")
+	}
+	if err := validateRepositoryURL(cfg.DeviceAuthURL); err != nil {
+		return fmt.Errorf("invalid device_auth_url: %w", err)
+	}
+	if err := validateRepositoryURL(cfg.DeviceTokenURL); err != nil {
+		return fmt.Errorf("invalid device_token_url: %w", err)
+	}
+	return nil
+}
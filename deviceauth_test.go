@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// fakeDeviceAuth serves an OAuth device authorization and token endpoint. The token endpoint
+// reports the login pending for pending polls, then answers with result.
+func fakeDeviceAuth(t *testing.T, pending int32, result string) (*httptest.Server, *int32) {
+	t.Helper()
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("client_id") != "relicta" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/device":
+			_, _ = w.Write([]byte(`{"device_code": "dev-123", "user_code": "WDJB-MJHT", "verification_uri": "https://login.example.com/device", "expires_in": 600}`))
+		case "/token":
+			if r.PostForm.Get("grant_type") != deviceCodeGrantType || r.PostForm.Get("device_code") != "dev-123" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error": "invalid_grant"}`))
+				return
+			}
+			if atomic.AddInt32(&polls, 1) <= pending {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error": "authorization_pending"}`))
+				return
+			}
+			if result != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprintf(w, `{"error": %q}`, result)
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token": "oauth-access-token", "token_type": "Bearer", "expires_in": 3600}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &polls
+}
+
+func deviceAuthConfig(server *httptest.Server) map[string]any {
+	return map[string]any{
+		"repository":           "http://localhost:8080/legacy/",
+		"device_auth":          true,
+		"device_auth_url":      server.URL + "/device",
+		"device_token_url":     server.URL + "/token",
+		"device_client_id":     "relicta",
+		"device_poll_interval": "1ms",
+	}
+}
+
+func TestExecuteDeviceAuth(t *testing.T) {
+	t.Setenv("CI", "")
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	server, polls := fakeDeviceAuth(t, 2, "")
+	var prompt bytes.Buffer
+	executor := &MockCommandExecutor{}
+	p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: executor, promptOutput: &prompt}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: deviceAuthConfig(server)})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+	if !strings.Contains(prompt.String(), "open https://login.example.com/device and enter the code WDJB-MJHT") {
+		t.Errorf("expected the user code to be printed, got %q", prompt.String())
+	}
+	if *polls != 3 {
+		t.Errorf("expected 3 token polls, got %d", *polls)
+	}
+	if env := strings.Join(executor.RunCalls[0].Env, " "); env != "TWINE_USERNAME=__token__ TWINE_PASSWORD=oauth-access-token" {
+		t.Errorf("expected twine to upload with the access token, got %s", env)
+	}
+	authorization, _ := resp.Outputs["device_auth"].(*deviceAuthorization)
+	if authorization == nil || authorization.UserCode != "WDJB-MJHT" || authorization.ExpiresAt == "" {
+		t.Errorf("unexpected device_auth output %+v", resp.Outputs["device_auth"])
+	}
+}
+
+func TestExecuteDeviceAuthFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		ci     string
+		result string
+		want   string
+	}{
+		{"denied", "", "access_denied", "device login was denied"},
+		{"expired", "", "expired_token", "expired before it was approved"},
+		{"in ci", "true", "", "cannot run in CI"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CI", tt.ci)
+			writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+			server, _ := fakeDeviceAuth(t, 1, tt.result)
+			executor := &MockCommandExecutor{}
+			p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: executor, promptOutput: &bytes.Buffer{}}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: deviceAuthConfig(server)})
+			if err != nil || resp.Success || !strings.Contains(resp.Error, tt.want) {
+				t.Fatalf("expected %q, got %v %+v", tt.want, err, resp)
+			}
+			if len(executor.RunCalls) != 0 {
+				t.Error("expected no upload without an approved login")
+			}
+		})
+	}
+}
+
+func TestValidateDeviceAuthConfig(t *testing.T) {
+	device := Config{
+		DeviceAuth:         true,
+		DeviceAuthURL:      "http://localhost:9000/oauth/device",
+		DeviceTokenURL:     "http://localhost:9000/oauth/token",
+		DeviceClientID:     "relicta",
+		DevicePollInterval: defaultDevicePollInterval,
+	}
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr bool
+	}{
+		{"device auth", func(cfg *Config) {}, false},
+		{"bearer", func(cfg *Config) { cfg.AuthScheme = authSchemeBearer }, false},
+		{"disabled", func(cfg *Config) { *cfg = Config{} }, false},
+		{"options without device auth", func(cfg *Config) { cfg.DeviceAuth = false }, true},
+		{"no client", func(cfg *Config) { cfg.DeviceClientID = "" }, true},
+		{"no token endpoint", func(cfg *Config) { cfg.DeviceTokenURL = "" }, true},
+		{"invalid endpoint", func(cfg *Config) { cfg.DeviceAuthURL = "ftp://localhost/device" }, true},
+		{"trusted publishing", func(cfg *Config) { cfg.TrustedPublishing = true }, true},
+		{"token command", func(cfg *Config) { cfg.TokenCommand = []string{"vault", "read"} }, true},
+		{"sigv4", func(cfg *Config) { cfg.AuthScheme = authSchemeSigV4 }, true},
+		{"zero interval", func(cfg *Config) { cfg.DevicePollInterval = 0 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := device
+			tt.modify(&cfg)
+			if err := validateDeviceAuthConfig(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateDeviceAuthConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
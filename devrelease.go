@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Sources of the N of dev release versions (1.2.0.devN).
+const (
+	devNumberTimestamp = "timestamp"
+	devNumberRun       = "run"
+)
+
+// devNumbers lists the supported dev_number values.
+var devNumbers = []string{devNumberTimestamp, devNumberRun}
+
+// runNumberEnvs are the CI variables holding a run number that increases with every run, in
+// order of preference: GitHub Actions, GitLab CI, Jenkins and CircleCI.
+var runNumberEnvs = []string{"GITHUB_RUN_NUMBER", "CI_PIPELINE_IID", "BUILD_NUMBER", "CIRCLE_BUILD_NUM"}
+
+// devSegmentPattern matches a trailing PEP 440 dev segment in any accepted spelling.
+var devSegmentPattern = regexp.MustCompile(`(?i)[-_.]?dev[-_.]?[0-9]*$`)
+
+// devRelease describes a dev release publish, reported in outputs.
+type devRelease struct {
+	Version     string   `json:"version"`
+	BaseVersion string   `json:"base_version"`
+	Repository  string   `json:"repository"`
+	Built       bool     `json:"built"`
+	Files       []string `json:"files,omitempty"`
+	// dir holds the built or restamped distributions; it is removed after the publish
+	dir string
+}
+
+// devNumber returns the N of the dev release version: the UTC time of the run, or the CI run
+// number.
+func devNumber(cfg Config, now time.Time) (string, error) {
+	if cfg.DevNumber != devNumberRun {
+		return now.UTC().Format("20060102150405"), nil
+	}
+	for _, env := range runNumberEnvs {
+		if v := strings.TrimSpace(os.Getenv(env)); v != "" {
+			if strings.Trim(v, "0123456789") != "" {
+				return "", fmt.Errorf("%s is not a run number: %q", env, v)
+			}
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("dev_number run needs a CI run number in one of %s", strings.Join(runNumberEnvs, ", "))
+}
+
+// devVersion returns base as a dev release: any local or dev segment is replaced by .devN.
+func devVersion(base, number string) string {
+	return devSegmentPattern.ReplaceAllString(stripLocalVersion(base), "") + ".dev" + number
+}
+
+// prepareDevRelease determines the dev release version of the release version, or of the
+// distributions when the release has none. Outside dry runs it builds the distributions with
+// cfg.DevBuildCommand, or restamps copies of the dist_path distributions with the dev version,
+// into a temporary directory.
+func (p *PyPIPlugin) prepareDevRelease(ctx context.Context, cfg Config, version string, dryRun bool) (*devRelease, error) {
+	files, _ := expandDistGlob(cfg.DistPath)
+	if version == "" && len(files) > 0 {
+		if meta, err := readDistMetadata(files[0]); err == nil {
+			version = meta.Version
+		}
+	}
+	if version == "" {
+		return nil, fmt.Errorf("no release version to derive the dev version from, and no distributions in %s", cfg.DistPath)
+	}
+	number, err := devNumber(cfg, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	dev := &devRelease{
+		Version:     devVersion(version, number),
+		BaseVersion: version,
+		Repository:  cfg.DevRepository,
+		Built:       len(cfg.DevBuildCommand) > 0,
+	}
+	if dryRun {
+		return dev, nil
+	}
+
+	dir, err := os.MkdirTemp("", "relicta-pypi-dev-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a directory for the dev distributions: %w", err)
+	}
+	dev.dir = dir
+	if dev.Built {
+		dev.Files, err = p.buildDevRelease(ctx, cfg, dev.Version, dir)
+	} else {
+		dev.Files, err = restampDistFiles(files, dir, dev.Version)
+	}
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	return dev, nil
+}
+
+// buildDevRelease runs dev_build_command to build version into dir, and checks that every
+// distribution it produced carries that version.
+func (p *PyPIPlugin) buildDevRelease(ctx context.Context, cfg Config, version, dir string) ([]string, error) {
+	argv, err := renderCommandTemplate("dev_build_command", cfg.DevBuildCommand, map[string]string{"version": version, "out_dir": dir})
+	if err != nil {
+		return nil, err
+	}
+	if output, err := p.getExecutor().Run(ctx, argv[0], argv[1:]...); err != nil {
+		tail, _ := truncateOutput(strings.TrimSpace(string(output)), cfg.MaxErrorBodyBytes)
+		return nil, fmt.Errorf("dev_build_command failed: %w: %s", err, tail)
+	}
+	files, err := expandDistGlob(distDirGlob(dir))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("dev_build_command produced no distributions in {out_dir}")
+	}
+	for _, f := range files {
+		meta, err := readDistMetadata(f)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the version of %s: %w", filepath.Base(f), err)
+		}
+		if normalizeVersion(meta.Version) != normalizeVersion(version) {
+			return nil, fmt.Errorf("dev_build_command built %s as version %s, not %s; pass {version} to the build", filepath.Base(f), meta.Version, version)
+		}
+	}
+	return files, nil
+}
+
+// restampDistFiles writes copies of the distributions to dir with version in their file names,
+// metadata and wheel RECORD, and returns them in the order of files.
+func restampDistFiles(files []string, dir, version string) ([]string, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no distributions to restamp")
+	}
+	restamped := make([]string, 0, len(files))
+	for _, f := range files {
+		meta, err := readDistMetadata(f)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the version of %s: %w", f, err)
+		}
+		base := filepath.Base(f)
+		named := versionFromFilename(meta.Name, base)
+		if named == "" {
+			return nil, fmt.Errorf("cannot find the version in the file name of %s", f)
+		}
+		r := firstComponentReplacer("-"+named, "-"+version)
+		target := filepath.Join(dir, r(base))
+		switch {
+		case strings.HasSuffix(base, ".whl"):
+			err = rewriteWheel(f, target, r, version)
+		case strings.HasSuffix(base, ".tar.gz"):
+			err = rewriteSdist(f, target, r, version)
+		default:
+			err = fmt.Errorf("unsupported distribution format")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to restamp %s as %s: %w", f, version, err)
+		}
+		restamped = append(restamped, target)
+	}
+	return restamped, nil
+}
+
+// validateDevReleaseConfig validates the dev_release options.
+func validateDevReleaseConfig(cfg Config) error {
+	if !cfg.DevRelease {
+		if cfg.DevRepository != "" || len(cfg.DevBuildCommand) > 0 {
+			return fmt.Errorf("dev_repository and dev_build_command require dev_release")
+		}
+		return nil
+	}
+	if cfg.DevNumber != "" && !containsString(devNumbers, cfg.DevNumber) {
+		return fmt.Errorf("dev_number must be one of: %s", strings.Join(devNumbers, ", "))
+	}
+	if cfg.DevRepository == "" {
+		return fmt.Errorf("dev_release requires dev_repository")
+	}
+	if strings.TrimSuffix(cfg.DevRepository, "/") == strings.TrimSuffix(cfg.Repository, "/") {
+		return fmt.Errorf("dev_repository must be a separate index from repository")
+	}
+	if err := validateRepositoryURL(cfg.DevRepository); err != nil {
+		return fmt.Errorf("invalid dev_repository: %w", err)
+	}
+	if cfg.BackfillVersion != "" {
+		return fmt.Errorf("dev_release cannot be combined with backfill_version")
+	}
+	if len(cfg.DevBuildCommand) > 0 {
+		if _, err := renderCommandTemplate("dev_build_command", cfg.DevBuildCommand, map[string]string{"version": "", "out_dir": ""}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
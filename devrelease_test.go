@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestDevVersion(t *testing.T) {
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"1.3.0", "1.3.0.dev42"},
+		{"1.3.0+g1234", "1.3.0.dev42"},
+		{"1.3.0.dev7", "1.3.0.dev42"},
+		{"1.3.0-dev", "1.3.0.dev42"},
+		{"1!1.3.0rc1", "1!1.3.0rc1.dev42"},
+	}
+	for _, tt := range tests {
+		if got := devVersion(tt.base, "42"); got != tt.want {
+			t.Errorf("devVersion(%q) = %q, want %q", tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestDevNumber(t *testing.T) {
+	for _, env := range runNumberEnvs {
+		t.Setenv(env, "")
+	}
+	now := time.Date(2024, 3, 1, 13, 5, 9, 0, time.FixedZone("CET", 3600))
+	if got, err := devNumber(Config{DevNumber: devNumberTimestamp}, now); err != nil || got != "20240301120509" {
+		t.Errorf("unexpected timestamp %q %v", got, err)
+	}
+	if _, err := devNumber(Config{DevNumber: devNumberRun}, now); err == nil {
+		t.Error("expected an error without a run number")
+	}
+	t.Setenv("CI_PIPELINE_IID", "17")
+	if got, err := devNumber(Config{DevNumber: devNumberRun}, now); err != nil || got != "17" {
+		t.Errorf("unexpected run number %q %v", got, err)
+	}
+	t.Setenv("GITHUB_RUN_NUMBER", "x1")
+	if _, err := devNumber(Config{DevNumber: devNumberRun}, now); err == nil {
+		t.Error("expected a non-numeric run number to be rejected")
+	}
+}
+
+// uploadedDevFiles returns the base names and versions of the files twine was given.
+func uploadedDevFiles(t *testing.T, args []string) []string {
+	t.Helper()
+	var files []string
+	for _, arg := range args {
+		if strings.HasSuffix(arg, ".whl") || strings.HasSuffix(arg, ".tar.gz") {
+			files = append(files, arg)
+		}
+	}
+	var uploaded []string
+	for _, f := range files {
+		meta, err := readDistMetadata(f)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		uploaded = append(uploaded, filepath.Base(f)+"="+meta.Version)
+	}
+	return uploaded
+}
+
+func TestExecuteDevRelease(t *testing.T) {
+	t.Setenv("GITHUB_RUN_NUMBER", "42")
+	config := map[string]any{
+		"username":       "__token__",
+		"password":       "pypi-token",
+		"dev_release":    true,
+		"dev_repository": "http://localhost:8081/dev/",
+		"dev_number":     "run",
+	}
+
+	t.Run("restamp", func(t *testing.T) {
+		writeDistFiles(t)
+		writeTestWheel(t, filepath.Join("dist", "mypkg-1.3.0-py3-none-any.whl"), map[string]string{
+			"mypkg-1.3.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.3.0\n",
+			"mypkg-1.3.0.dist-info/RECORD":   "mypkg-1.3.0.dist-info/METADATA,sha256=old,44\nmypkg-1.3.0.dist-info/RECORD,,\n",
+		})
+		writeTestSdist(t, filepath.Join("dist", "mypkg-1.3.0.tar.gz"), map[string]string{
+			"mypkg-1.3.0/PKG-INFO": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.3.0\n",
+		})
+		var uploaded []string
+		var args []string
+		p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+			RunFunc: func(ctx context.Context, name string, a ...string) ([]byte, error) {
+				args = a
+				uploaded = uploadedDevFiles(t, a)
+				return nil, nil
+			},
+		}}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  config,
+			Context: plugin.ReleaseContext{Version: "v1.3.0"},
+		})
+		if err != nil || !resp.Success || resp.Outputs["version"] != "1.3.0.dev42" || resp.Outputs["channel"] != "dev" {
+			t.Fatalf("expected the dev release to be published, got %v %+v", err, resp)
+		}
+		if !strings.Contains(strings.Join(args, " "), "--repository-url http://localhost:8081/dev/") {
+			t.Errorf("expected the upload to go to the dev index, got %v", args)
+		}
+		want := []string{"mypkg-1.3.0.dev42-py3-none-any.whl=1.3.0.dev42", "mypkg-1.3.0.dev42.tar.gz=1.3.0.dev42"}
+		if fmt.Sprint(uploaded) != fmt.Sprint(want) {
+			t.Errorf("uploaded %v, want %v", uploaded, want)
+		}
+		if dev, _ := resp.Outputs["dev_release"].(*devRelease); dev == nil || dev.BaseVersion != "1.3.0" || dev.Built {
+			t.Errorf("unexpected dev_release output %+v", resp.Outputs["dev_release"])
+		}
+	})
+
+	t.Run("build command", func(t *testing.T) {
+		writeDistFiles(t)
+		built := map[string]any{"dev_build_command": []any{"python", "-m", "build", "--outdir", "{out_dir}", "-C=version={version}"}}
+		for k, v := range config {
+			built[k] = v
+		}
+		var calls []string
+		var uploaded []string
+		p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+			RunFunc: func(ctx context.Context, name string, a ...string) ([]byte, error) {
+				calls = append(calls, name)
+				if name == "python" {
+					version := strings.TrimPrefix(a[4], "-C=version=")
+					writeTestWheel(t, filepath.Join(a[3], "mypkg-"+version+"-py3-none-any.whl"), map[string]string{
+						"mypkg-" + version + ".dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: " + version + "\n",
+					})
+					return nil, nil
+				}
+				uploaded = uploadedDevFiles(t, a)
+				return nil, nil
+			},
+		}}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  built,
+			Context: plugin.ReleaseContext{Version: "v1.3.0"},
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("expected the built dev release to be published, got %v %+v", err, resp)
+		}
+		if len(calls) != 2 || calls[0] != "python" || fmt.Sprint(uploaded) != "[mypkg-1.3.0.dev42-py3-none-any.whl=1.3.0.dev42]" {
+			t.Errorf("unexpected calls %v and uploads %v", calls, uploaded)
+		}
+	})
+
+	t.Run("build ignoring the version", func(t *testing.T) {
+		writeDistFiles(t)
+		built := map[string]any{"dev_build_command": []any{"python", "-m", "build", "--outdir", "{out_dir}"}}
+		for k, v := range config {
+			built[k] = v
+		}
+		p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+			RunFunc: func(ctx context.Context, name string, a ...string) ([]byte, error) {
+				writeTestWheel(t, filepath.Join(a[3], "mypkg-1.3.0-py3-none-any.whl"), map[string]string{
+					"mypkg-1.3.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.3.0\n",
+				})
+				return nil, nil
+			},
+		}}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  built,
+			Context: plugin.ReleaseContext{Version: "v1.3.0"},
+		})
+		if err != nil || resp.Success || !strings.Contains(resp.Error, "as version 1.3.0, not 1.3.0.dev42") {
+			t.Fatalf("expected the mismatched build to be refused, got %v %+v", err, resp)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		writeDistFiles(t, "mypkg-1.3.0.tar.gz")
+		p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{}}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  config,
+			Context: plugin.ReleaseContext{Version: "v1.3.0"},
+			DryRun:  true,
+		})
+		if err != nil || !resp.Success || resp.Message != "Would publish dev release 1.3.0.dev42 to http://localhost:8081/dev/" {
+			t.Fatalf("unexpected dry run %v %+v", err, resp)
+		}
+	})
+}
+
+func TestValidateDevReleaseConfig(t *testing.T) {
+	dev := Config{DevRelease: true, DevRepository: "http://localhost:8081/dev/", Repository: "https://upload.pypi.org/legacy/", DevNumber: devNumberTimestamp}
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr bool
+	}{
+		{"valid", func(cfg *Config) {}, false},
+		{"build command", func(cfg *Config) { cfg.DevBuildCommand = []string{"python", "-m", "build", "--outdir", "{out_dir}"} }, false},
+		{"disabled", func(cfg *Config) { *cfg = Config{} }, false},
+		{"repository without dev_release", func(cfg *Config) { cfg.DevRelease = false }, true},
+		{"no dev repository", func(cfg *Config) { cfg.DevRepository = "" }, true},
+		{"same repository", func(cfg *Config) { cfg.DevRepository = "https://upload.pypi.org/legacy" }, true},
+		{"unknown number", func(cfg *Config) { cfg.DevNumber = "sha" }, true},
+		{"backfill", func(cfg *Config) { cfg.BackfillVersion = "1.0.0" }, true},
+		{"unknown variable", func(cfg *Config) { cfg.DevBuildCommand = []string{"hatch", "build", "{dist_path}"} }, true},
+		{"variable executable", func(cfg *Config) { cfg.DevBuildCommand = []string{"{out_dir}/build"} }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := dev
+			tt.modify(&cfg)
+			if err := validateDevReleaseConfig(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateDevReleaseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
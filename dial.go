@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// IP families accepted by the ip_family option.
+const (
+	// ipFamilyAuto races IPv6 and IPv4 connections (Happy Eyeballs, RFC 6555)
+	ipFamilyAuto = "auto"
+	ipFamilyIPv4 = "ipv4"
+	ipFamilyIPv6 = "ipv6"
+)
+
+// ipFamilies lists the supported ip_family values.
+var ipFamilies = []string{ipFamilyAuto, ipFamilyIPv4, ipFamilyIPv6}
+
+// unixRepositoryPrefix marks repositories served on a Unix domain socket, such as a local
+// sidecar proxy injecting credentials.
+const unixRepositoryPrefix = "http+unix://"
+
+// parseUnixRepository splits an http+unix:// repository into the socket path, given
+// percent-encoded as the host as in requests-unixsocket, and the URL requests are sent to.
+func parseUnixRepository(repository string) (socket, requestURL string, ok bool) {
+	rest, ok := strings.CutPrefix(repository, unixRepositoryPrefix)
+	if !ok {
+		return "", "", false
+	}
+	host, path := rest, "/"
+	if i := strings.Index(rest, "/"); i >= 0 {
+		host, path = rest[:i], rest[i:]
+	}
+	socket, err := url.PathUnescape(host)
+	if err != nil {
+		socket = ""
+	}
+	return socket, "http://localhost" + path, true
+}
+
+// uploadRequestURL returns the URL upload requests are sent to.
+func uploadRequestURL(repository string) string {
+	if _, requestURL, ok := parseUnixRepository(repository); ok {
+		return requestURL
+	}
+	return repository
+}
+
+// dialNetwork restricts a TCP network to the configured IP family.
+func dialNetwork(network, family string) string {
+	if network != "tcp" {
+		return network
+	}
+	switch family {
+	case ipFamilyIPv4:
+		return "tcp4"
+	case ipFamilyIPv6:
+		return "tcp6"
+	default:
+		return network
+	}
+}
+
+// uploadDialContext returns the dial function of upload connections. Unix socket repositories
+// are dialed directly; otherwise static hosts and the configured DNS servers resolve the
+// address and the IP family restricts the network. Connections time out when idle.
+func uploadDialContext(cfg Config) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}
+	if len(cfg.DNSServers) > 0 {
+		dialer.Resolver = dnsResolver(cfg.DNSServers)
+	}
+	idle, family, hosts := cfg.IdleTimeout, cfg.IPFamily, cfg.StaticHosts
+	socket, _, unix := parseUnixRepository(cfg.Repository)
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if unix {
+			network, addr = "unix", socket
+		} else if host, port, err := net.SplitHostPort(addr); err == nil {
+			if ip, ok := hosts[strings.ToLower(host)]; ok {
+				addr = net.JoinHostPort(ip, port)
+			}
+		}
+		conn, err := dialer.DialContext(ctx, dialNetwork(network, family), addr)
+		if err != nil || idle <= 0 {
+			return conn, err
+		}
+		return &idleTimeoutConn{Conn: conn, timeout: idle}, nil
+	}
+}
+
+// dnsResolver returns a resolver querying the given servers in order instead of the system
+// configuration, for split-horizon DNS.
+func dnsResolver(servers []string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			var err error
+			for _, server := range servers {
+				var conn net.Conn
+				if conn, err = d.DialContext(ctx, network, server); err == nil {
+					return conn, nil
+				}
+			}
+			return nil, err
+		},
+	}
+}
+
+// uploadLookupIP returns the hostname lookup matching upload connections: static hosts first,
+// then the configured DNS servers or the system resolver.
+func uploadLookupIP(cfg Config) func(host string) ([]net.IP, error) {
+	resolver := net.DefaultResolver
+	if len(cfg.DNSServers) > 0 {
+		resolver = dnsResolver(cfg.DNSServers)
+	}
+	return func(host string) ([]net.IP, error) {
+		if ip, ok := cfg.StaticHosts[strings.ToLower(host)]; ok {
+			if parsed := net.ParseIP(ip); parsed != nil {
+				return []net.IP{parsed}, nil
+			}
+		}
+		return resolver.LookupIP(context.Background(), "ip", host)
+	}
+}
+
+// normalizeDNSServer returns a DNS server address as ip:port, defaulting to port 53.
+func normalizeDNSServer(server string) (string, error) {
+	if ip := net.ParseIP(server); ip != nil {
+		return net.JoinHostPort(ip.String(), "53"), nil
+	}
+	host, port, err := net.SplitHostPort(server)
+	if err != nil || net.ParseIP(host) == nil || port == "" {
+		return "", fmt.Errorf("dns_servers entry %q must be an IP address with optional port", server)
+	}
+	return server, nil
+}
+
+// usesNativeUploader reports whether uploads use the native uploader, because upload_backend
+// selects it or because the upload needs it.
+func usesNativeUploader(cfg Config) bool {
+	return cfg.UploadBackend == uploadBackendNative || requiresNativeUploader(cfg)
+}
+
+// requiresNativeUploader reports whether uploads must use the native uploader, because of the
+// authentication or of connection options twine does not support.
+func requiresNativeUploader(cfg Config) bool {
+	_, _, unix := parseUnixRepository(cfg.Repository)
+	return usesNativeAuth(cfg) || (cfg.IPFamily != "" && cfg.IPFamily != ipFamilyAuto) ||
+		len(cfg.DNSServers) > 0 || len(cfg.StaticHosts) > 0 || unix ||
+		cfg.DisableCompression || len(cfg.ProxyHeaders) > 0 || cfg.SpiffeServerID != ""
+}
+
+// validateIPFamily validates the ip_family option.
+func validateIPFamily(cfg Config) error {
+	if cfg.IPFamily != "" && !containsString(ipFamilies, cfg.IPFamily) {
+		return fmt.Errorf("ip_family must be one of: %s", strings.Join(ipFamilies, ", "))
+	}
+	return nil
+}
+
+// validateResolverConfig validates the dns_servers and static_hosts options.
+func validateResolverConfig(cfg Config) error {
+	for _, server := range cfg.DNSServers {
+		if _, err := normalizeDNSServer(server); err != nil {
+			return err
+		}
+	}
+	for host, ip := range cfg.StaticHosts {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("static_hosts entry for %s must be an IP address, got %q", host, ip)
+		}
+	}
+	return nil
+}
+
+// validateUnixRepository validates an http+unix:// repository.
+func validateUnixRepository(repository string) error {
+	socket, _, _ := parseUnixRepository(repository)
+	if socket == "" {
+		return fmt.Errorf("http+unix repository must name the percent-encoded socket path, e.g. http+unix://%%2Frun%%2Fproxy.sock/legacy/")
+	}
+	if !filepath.IsAbs(socket) {
+		return fmt.Errorf("socket path %q must be absolute", socket)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDialNetwork(t *testing.T) {
+	tests := []struct {
+		network, family, want string
+	}{
+		{"tcp", ipFamilyAuto, "tcp"},
+		{"tcp", ipFamilyIPv4, "tcp4"},
+		{"tcp", ipFamilyIPv6, "tcp6"},
+		{"unix", ipFamilyIPv4, "unix"},
+	}
+	for _, tt := range tests {
+		if got := dialNetwork(tt.network, tt.family); got != tt.want {
+			t.Errorf("dialNetwork(%q, %q) = %q, want %q", tt.network, tt.family, got, tt.want)
+		}
+	}
+}
+
+func TestNativeUploadIPFamily(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	// httptest listens on the IPv4 loopback address
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	for family, wantErr := range map[string]bool{ipFamilyAuto: false, ipFamilyIPv4: false, ipFamilyIPv6: true} {
+		cfg := p.parseConfig(map[string]any{
+			"repository": server.URL,
+			"username":   "user",
+			"password":   "pass",
+			"ip_family":  family,
+		})
+		if got := usesNativeUploader(cfg); got != (family != ipFamilyAuto) {
+			t.Errorf("%s: usesNativeUploader = %v", family, got)
+		}
+		_, err := p.runNativeUploads(context.Background(), cfg, newPublishSession(cfg), nil)
+		if (err != nil) != wantErr {
+			t.Errorf("%s: upload error = %v, wantErr %v", family, err, wantErr)
+		}
+	}
+}
+
+// startTestDNSServer answers A queries for every name with 127.0.0.1 and returns its address.
+func startTestDNSServer(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := buf[:n]
+			// Header, then the question: name labels ending with 0, type and class
+			end := 12
+			for end < n && query[end] != 0 {
+				end += int(query[end]) + 1
+			}
+			end += 5
+			if end > n {
+				continue
+			}
+			isA := binary.BigEndian.Uint16(query[end-4:]) == 1
+
+			resp := append([]byte{}, query[:2]...)
+			resp = append(resp, 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0)
+			resp = append(resp, query[12:end]...)
+			if isA {
+				resp[7] = 1
+				resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 0, 0, 1)
+			}
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestNativeUploadResolver(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	var hosts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	configs := []map[string]any{
+		{"static_hosts": map[string]any{"PyPI.Internal.Test": "127.0.0.1"}},
+		{"dns_servers": []any{startTestDNSServer(t)}},
+	}
+	for _, extra := range configs {
+		raw := map[string]any{
+			"repository": "http://pypi.internal.test:" + port + "/",
+			"username":   "user",
+			"password":   "pass",
+		}
+		for k, v := range extra {
+			raw[k] = v
+		}
+		cfg := p.parseConfig(raw)
+		if !usesNativeUploader(cfg) {
+			t.Error("expected the native uploader with custom resolution")
+		}
+		if _, err := p.runNativeUploads(context.Background(), cfg, newPublishSession(cfg), nil); err != nil {
+			t.Errorf("%v: unexpected error: %v", extra, err)
+		}
+	}
+	if len(hosts) != 2 || hosts[0] != "pypi.internal.test:"+port {
+		t.Errorf("expected both uploads to keep the index hostname, got %v", hosts)
+	}
+}
+
+func TestValidateResolverConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"empty", Config{}, false},
+		{"servers", Config{DNSServers: []string{"10.0.0.2", "10.0.0.3:5353", "[fd00::53]:53"}}, false},
+		{"server hostname", Config{DNSServers: []string{"dns.example.com"}}, true},
+		{"static hosts", Config{StaticHosts: map[string]string{"pypi.internal": "10.1.2.3"}}, false},
+		{"static hostname", Config{StaticHosts: map[string]string{"pypi.internal": "lb.internal"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateResolverConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateResolverConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRepositoryURLStaticHosts(t *testing.T) {
+	cfg := Config{StaticHosts: map[string]string{"pypi.internal.test": "93.184.216.34"}}
+	if err := validateRepositoryURLWith("https://pypi.internal.test/legacy/", uploadLookupIP(cfg)); err != nil {
+		t.Errorf("expected the static host to be used for validation: %v", err)
+	}
+	if err := validateRepositoryURLWith("https://pypi.internal.test/legacy/", uploadLookupIP(Config{})); err == nil {
+		t.Error("expected the system resolver to fail for the internal hostname")
+	}
+}
+
+func TestNativeUploadUnixSocket(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	// Unix socket paths are limited in length, so avoid the long test temp dir
+	dir, err := os.MkdirTemp("", "proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	socket := filepath.Join(dir, "proxy.sock")
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	var path string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+	server.Listener = lis
+	server.Start()
+	defer server.Close()
+
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"repository": "http+unix://" + url.PathEscape(socket) + "/legacy/",
+		"username":   "user",
+		"password":   "pass",
+	})
+	if err := p.validateConfig(cfg); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	if !usesNativeUploader(cfg) {
+		t.Error("expected the native uploader for a unix socket repository")
+	}
+	if _, err := p.runNativeUploads(context.Background(), cfg, newPublishSession(cfg), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "/legacy/" {
+		t.Errorf("expected the upload at /legacy/, got %q", path)
+	}
+}
+
+func TestParseUnixRepository(t *testing.T) {
+	tests := []struct {
+		repository, socket, requestURL string
+		ok, wantErr                    bool
+	}{
+		{"http+unix://%2Frun%2Fproxy.sock/legacy/", "/run/proxy.sock", "http://localhost/legacy/", true, false},
+		{"http+unix://%2Frun%2Fproxy.sock", "/run/proxy.sock", "http://localhost/", true, false},
+		{"http+unix://proxy.sock/legacy/", "proxy.sock", "http://localhost/legacy/", true, true},
+		{"http+unix:///legacy/", "", "http://localhost/legacy/", true, true},
+		{"https://upload.pypi.org/legacy/", "", "", false, false},
+	}
+	for _, tt := range tests {
+		socket, requestURL, ok := parseUnixRepository(tt.repository)
+		if socket != tt.socket || requestURL != tt.requestURL || ok != tt.ok {
+			t.Errorf("parseUnixRepository(%q) = %q, %q, %v", tt.repository, socket, requestURL, ok)
+		}
+		if ok {
+			if err := validateUnixRepository(tt.repository); (err != nil) != tt.wantErr {
+				t.Errorf("validateUnixRepository(%q) error = %v, wantErr %v", tt.repository, err, tt.wantErr)
+			}
+		}
+	}
+}
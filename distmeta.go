@@ -0,0 +1,179 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"path"
+	"strings"
+)
+
+// maxMetadataSize bounds how much of a METADATA/PKG-INFO file is read.
+const maxMetadataSize = 10 << 20 // 10 MiB
+
+// packageMetadata is the core metadata of a distribution (METADATA or PKG-INFO).
+type packageMetadata struct {
+	MetadataVersion        string
+	Name                   string
+	Version                string
+	Summary                string
+	HomePage               string
+	DownloadURL            string
+	Author                 string
+	AuthorEmail            string
+	Maintainer             string
+	MaintainerEmail        string
+	License                string
+	LicenseExpression      string
+	Keywords               string
+	RequiresPython         string
+	DescriptionContentType string
+	Description            string
+	RequiresDist           []string
+	RequiresExternal       []string
+	ProvidesDist           []string
+	ObsoletesDist          []string
+	Classifiers            []string
+	ProvidesExtra          []string
+	ProjectURLs            []string
+	Platforms              []string
+	SupportedPlatforms     []string
+	LicenseFiles           []string
+	Dynamic                []string
+}
+
+// readDistMetadata reads the core metadata from a wheel or sdist.
+func readDistMetadata(distPath string) (*packageMetadata, error) {
+	var data []byte
+	var err error
+
+	switch {
+	case strings.HasSuffix(distPath, ".whl"):
+		data, err = readWheelFile(distPath, func(name string) bool {
+			dir, file := path.Split(name)
+			return file == "METADATA" && strings.HasSuffix(dir, ".dist-info/") && strings.Count(name, "/") == 1
+		})
+	case strings.HasSuffix(distPath, ".tar.gz"):
+		data, err = readSdistFile(distPath, func(name string) bool {
+			return path.Base(name) == "PKG-INFO" && strings.Count(strings.TrimPrefix(name, "./"), "/") == 1
+		})
+	default:
+		return nil, fmt.Errorf("unsupported distribution format: %s", path.Base(distPath))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMetadata(data)
+}
+
+// parseMetadata parses RFC 822 style core metadata.
+func parseMetadata(data []byte) (*packageMetadata, error) {
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(data) + "\n")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+
+	body, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata body: %w", err)
+	}
+
+	h := msg.Header
+	meta := &packageMetadata{
+		MetadataVersion:        h.Get("Metadata-Version"),
+		Name:                   h.Get("Name"),
+		Version:                h.Get("Version"),
+		Summary:                h.Get("Summary"),
+		HomePage:               h.Get("Home-Page"),
+		DownloadURL:            h.Get("Download-URL"),
+		Author:                 h.Get("Author"),
+		AuthorEmail:            h.Get("Author-Email"),
+		Maintainer:             h.Get("Maintainer"),
+		MaintainerEmail:        h.Get("Maintainer-Email"),
+		License:                h.Get("License"),
+		LicenseExpression:      h.Get("License-Expression"),
+		Keywords:               h.Get("Keywords"),
+		RequiresPython:         h.Get("Requires-Python"),
+		DescriptionContentType: h.Get("Description-Content-Type"),
+		Description:            strings.TrimSpace(string(body)),
+		RequiresDist:           h["Requires-Dist"],
+		RequiresExternal:       h["Requires-External"],
+		ProvidesDist:           h["Provides-Dist"],
+		ObsoletesDist:          h["Obsoletes-Dist"],
+		Classifiers:            h["Classifier"],
+		ProvidesExtra:          h["Provides-Extra"],
+		ProjectURLs:            h["Project-Url"],
+		Platforms:              h["Platform"],
+		SupportedPlatforms:     h["Supported-Platform"],
+		LicenseFiles:           h["License-File"],
+		Dynamic:                h["Dynamic"],
+	}
+	if meta.Description == "" {
+		meta.Description = h.Get("Description")
+	}
+	if meta.Name == "" || meta.Version == "" {
+		return nil, fmt.Errorf("invalid metadata: missing Name or Version")
+	}
+	return meta, nil
+}
+
+// readWheelFile returns the content of the first wheel member accepted by match.
+func readWheelFile(wheelPath string, match func(name string) bool) ([]byte, error) {
+	zr, err := zip.OpenReader(wheelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wheel: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	for _, f := range zr.File {
+		if !match(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		defer func() { _ = rc.Close() }()
+		return io.ReadAll(io.LimitReader(rc, maxMetadataSize))
+	}
+	return nil, errNotInArchive
+}
+
+// readSdistFile returns the content of the first sdist member accepted by match.
+func readSdistFile(sdistPath string, match func(name string) bool) ([]byte, error) {
+	f, err := os.Open(sdistPath) // #nosec G304 -- dist files come from the validated dist path
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sdist: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sdist: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, errNotInArchive
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sdist: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg && match(hdr.Name) {
+			return io.ReadAll(io.LimitReader(tr, maxMetadataSize))
+		}
+	}
+}
+
+// errNotInArchive is returned when an expected archive member is missing.
+var errNotInArchive = errors.New("metadata file not found in distribution")
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestWheel writes a wheel (zip) at path containing the given members.
+func writeTestWheel(t *testing.T, path string, members map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create wheel: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	zw := zip.NewWriter(f)
+	for _, name := range sortedKeys(members) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(members[name])); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to finish wheel: %v", err)
+	}
+}
+
+// writeTestSdist writes an sdist (tar.gz) at path containing the given members.
+func writeTestSdist(t *testing.T, path string, members map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create sdist: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, name := range sortedKeys(members) {
+		content := members[name]
+		if err := writeTarFile(tw, name, int64(len(content)), strings.NewReader(content)); err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to finish sdist: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to finish sdist: %v", err)
+	}
+}
+
+const testMetadata = `Metadata-Version: 2.1
+Name: example-pkg
+Version: 1.2.0
+Summary: An example package
+License: MIT
+Requires-Python: >=3.8
+Description-Content-Type: text/markdown
+Requires-Dist: requests (==2.19.0)
+Requires-Dist: click>=8.0 ; python_version >= "3.8"
+Provides-Extra: dev
+Classifier: License :: OSI Approved :: MIT License
+
+# Example
+
+Long description.
+`
+
+func TestReadDistMetadata(t *testing.T) {
+	dir := t.TempDir()
+	wheel := filepath.Join(dir, "example_pkg-1.2.0-py3-none-any.whl")
+	sdist := filepath.Join(dir, "example_pkg-1.2.0.tar.gz")
+	writeTestWheel(t, wheel, map[string]string{
+		"example_pkg/__init__.py":                 "",
+		"example_pkg-1.2.0.dist-info/METADATA":    testMetadata,
+		"example_pkg-1.2.0.dist-info/RECORD":      "",
+		"example_pkg/vendored.dist-info/METADATA": "Name: wrong\nVersion: 0\n",
+	})
+	writeTestSdist(t, sdist, map[string]string{
+		"example_pkg-1.2.0/PKG-INFO":       testMetadata,
+		"example_pkg-1.2.0/src/x/PKG-INFO": "Name: wrong\nVersion: 0\n",
+	})
+
+	for _, path := range []string{wheel, sdist} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			meta, err := readDistMetadata(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if meta.Name != "example-pkg" || meta.Version != "1.2.0" {
+				t.Errorf("unexpected name/version: %s %s", meta.Name, meta.Version)
+			}
+			if meta.License != "MIT" || meta.RequiresPython != ">=3.8" {
+				t.Errorf("unexpected license/requires-python: %q %q", meta.License, meta.RequiresPython)
+			}
+			if len(meta.RequiresDist) != 2 || meta.RequiresDist[0] != "requests (==2.19.0)" {
+				t.Errorf("unexpected Requires-Dist: %v", meta.RequiresDist)
+			}
+			if len(meta.Classifiers) != 1 || len(meta.ProvidesExtra) != 1 {
+				t.Errorf("unexpected classifiers/extras: %v %v", meta.Classifiers, meta.ProvidesExtra)
+			}
+			if !strings.HasPrefix(meta.Description, "# Example") {
+				t.Errorf("expected description from body, got %q", meta.Description)
+			}
+		})
+	}
+}
+
+func TestReadDistMetadataErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := filepath.Join(dir, "empty-1.0-py3-none-any.whl")
+	writeTestWheel(t, missing, map[string]string{"empty/__init__.py": ""})
+	if _, err := readDistMetadata(missing); !errors.Is(err, errNotInArchive) {
+		t.Errorf("expected errNotInArchive, got %v", err)
+	}
+
+	if _, err := readDistMetadata(filepath.Join(dir, "pkg.egg")); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+
+	invalid := filepath.Join(dir, "bad-1.0.tar.gz")
+	writeTestSdist(t, invalid, map[string]string{"bad-1.0/PKG-INFO": "Summary: no name\n"})
+	if _, err := readDistMetadata(invalid); err == nil {
+		t.Error("expected error for metadata without name")
+	}
+}
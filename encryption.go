@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// encryptedValuePrefix marks config values encrypted with encryptConfigValue.
+const encryptedValuePrefix = "enc:"
+
+// Environment variables that supply the config decryption key.
+const (
+	configKeyEnv     = "RELICTA_PYPI_CONFIG_KEY"
+	configKeyFileEnv = "RELICTA_PYPI_CONFIG_KEY_FILE"
+	// configKeyKMSEnv holds the key encrypted with an AWS KMS key, as the base64 CiphertextBlob
+	// of aws kms encrypt
+	configKeyKMSEnv = "RELICTA_PYPI_CONFIG_KEY_KMS"
+	// awsKMSEndpointEnv overrides the KMS endpoint, as for the AWS SDKs
+	awsKMSEndpointEnv = "AWS_ENDPOINT_URL_KMS"
+)
+
+// configKeySize is the AES-256 key size in bytes.
+const configKeySize = 32
+
+// decryptConfig returns a copy of raw with every "enc:" string value decrypted.
+// The config is returned unchanged when it contains no encrypted values.
+func (p *PyPIPlugin) decryptConfig(ctx context.Context, raw map[string]any) (map[string]any, error) {
+	if !hasEncryptedValues(raw) {
+		return raw, nil
+	}
+
+	key, err := p.configKey(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := decryptValues(key, "", raw)
+	if err != nil {
+		return nil, err
+	}
+	return decrypted.(map[string]any), nil
+}
+
+// configKey resolves the decryption key from the environment, a key file, a key encrypted with
+// AWS KMS, or config_key_command. The command is typically a secrets-manager CLI that prints the
+// base64 key.
+func (p *PyPIPlugin) configKey(ctx context.Context, raw map[string]any) ([]byte, error) {
+	if v := os.Getenv(configKeyEnv); v != "" {
+		return decodeConfigKey(v)
+	}
+
+	if path := os.Getenv(configKeyFileEnv); path != "" {
+		data, err := os.ReadFile(path) // #nosec G304 -- path is supplied by the operator
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config key file: %w", err)
+		}
+		return decodeConfigKey(string(data))
+	}
+
+	if blob := os.Getenv(configKeyKMSEnv); blob != "" {
+		return p.decryptKMSConfigKey(ctx, blob)
+	}
+
+	if command := helpers.NewConfigParser(raw).GetStringSlice("config_key_command", nil); len(command) > 0 {
+		output, err := commandOutput(ctx, p.getExecutor(), command[0], command[1:]...)
+		if err != nil {
+			return nil, fmt.Errorf("config_key_command failed: %w", err)
+		}
+		return decodeConfigKey(string(output))
+	}
+
+	return nil, fmt.Errorf("encrypted config values require a key (set %s, %s, %s or config_key_command)", configKeyEnv, configKeyFileEnv, configKeyKMSEnv)
+}
+
+// decryptKMSConfigKey decrypts the key encrypted with AWS KMS. The ciphertext names its KMS key,
+// so only the region of the key is needed, from AWS_REGION or AWS_DEFAULT_REGION.
+func (p *PyPIPlugin) decryptKMSConfigKey(ctx context.Context, blob string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimSpace(blob))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid base64", configKeyKMSEnv)
+	}
+	region := defaultAWSRegion()
+	if region == "" {
+		return nil, fmt.Errorf("%s needs the region of its KMS key in AWS_REGION", configKeyKMSEnv)
+	}
+	endpoint := os.Getenv(awsKMSEndpointEnv)
+	if endpoint == "" {
+		endpoint = "https://kms." + region + ".amazonaws.com/"
+	}
+	body, err := json.Marshal(map[string]any{"CiphertextBlob": ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := p.callAWSKMS(ctx, endpoint, region, "TrentService.Decrypt", body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the config key: %w", err)
+	}
+	var result struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("invalid KMS response: %w", err)
+	}
+	if len(result.Plaintext) != configKeySize {
+		return nil, fmt.Errorf("config key must be %d bytes, got %d", configKeySize, len(result.Plaintext))
+	}
+	return result.Plaintext, nil
+}
+
+// decodeConfigKey decodes a base64-encoded AES-256 key.
+func decodeConfigKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("config key is not valid base64")
+	}
+	if len(key) != configKeySize {
+		return nil, fmt.Errorf("config key must be %d bytes, got %d", configKeySize, len(key))
+	}
+	return key, nil
+}
+
+// hasEncryptedValues reports whether v contains any "enc:" string value.
+func hasEncryptedValues(v any) bool {
+	switch val := v.(type) {
+	case string:
+		return strings.HasPrefix(val, encryptedValuePrefix)
+	case map[string]any:
+		for _, item := range val {
+			if hasEncryptedValues(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if hasEncryptedValues(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decryptValues recursively decrypts "enc:" strings in v. field names the value in errors.
+func decryptValues(key []byte, field string, v any) (any, error) {
+	switch val := v.(type) {
+	case string:
+		if !strings.HasPrefix(val, encryptedValuePrefix) {
+			return val, nil
+		}
+		plaintext, err := decryptConfigValue(key, val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %w", field, err)
+		}
+		return plaintext, nil
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			name := k
+			if field != "" {
+				name = field + "." + k
+			}
+			decrypted, err := decryptValues(key, name, item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = decrypted
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			decrypted, err := decryptValues(key, fmt.Sprintf("%s[%d]", field, i), item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = decrypted
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// encryptConfigValue encrypts plaintext with AES-256-GCM and returns an "enc:" value.
+func encryptConfigValue(key []byte, plaintext string) (string, error) {
+	gcm, err := newConfigCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptConfigValue decrypts an "enc:" value produced by encryptConfigValue.
+func decryptConfigValue(key []byte, value string) (string, error) {
+	gcm, err := newConfigCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedValuePrefix))
+	if err != nil {
+		return "", fmt.Errorf("encrypted value is not valid base64")
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted value is too short")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+// newConfigCipher creates the AES-GCM cipher for config values.
+func newConfigCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// runEncryptValue implements the "encrypt-value" command: it reads a secret from stdin and
+// prints the encrypted config value using the key from RELICTA_PYPI_CONFIG_KEY(_FILE).
+func runEncryptValue(stdin io.Reader, stdout, stderr io.Writer) int {
+	p := &PyPIPlugin{}
+	key, err := p.configKey(context.Background(), nil)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		_, _ = fmt.Fprintf(stderr, "failed to read value: %v\n", err)
+		return 1
+	}
+
+	encrypted, err := encryptConfigValue(key, strings.TrimRight(line, "\r\n"))
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	_, _ = fmt.Fprintln(stdout, encrypted)
+	return 0
+}
+
+// runGenerateConfigKey implements the "generate-config-key" command, printing a new base64 key.
+func runGenerateConfigKey(stdout, stderr io.Writer) int {
+	key := make([]byte, configKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to generate key: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintln(stdout, base64.StdEncoding.EncodeToString(key))
+	return 0
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// testConfigKey returns a deterministic base64 AES-256 key for tests.
+func testConfigKey() (string, []byte) {
+	key := bytes.Repeat([]byte{0x42}, configKeySize)
+	return base64.StdEncoding.EncodeToString(key), key
+}
+
+func TestConfigValueRoundTrip(t *testing.T) {
+	_, key := testConfigKey()
+
+	encrypted, err := encryptConfigValue(key, "pypi-secret-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(encrypted, encryptedValuePrefix) {
+		t.Errorf("expected '%s' prefix, got '%s'", encryptedValuePrefix, encrypted)
+	}
+	if strings.Contains(encrypted, "pypi-secret-token") {
+		t.Error("encrypted value contains plaintext")
+	}
+
+	decrypted, err := decryptConfigValue(key, encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decrypted != "pypi-secret-token" {
+		t.Errorf("expected 'pypi-secret-token', got '%s'", decrypted)
+	}
+
+	wrongKey := bytes.Repeat([]byte{0x01}, configKeySize)
+	if _, err := decryptConfigValue(wrongKey, encrypted); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("expected wrong key error, got %v", err)
+	}
+}
+
+func TestDecodeConfigKey(t *testing.T) {
+	encoded, _ := testConfigKey()
+
+	tests := []struct {
+		name        string
+		input       string
+		errContains string
+	}{
+		{"valid", encoded, ""},
+		{"trailing newline", encoded + "\n", ""},
+		{"not base64", "not-base64!", "not valid base64"},
+		{"wrong size", base64.StdEncoding.EncodeToString([]byte("short")), "must be 32 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeConfigKey(tt.input)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing '%s', got %v", tt.errContains, err)
+			}
+		})
+	}
+}
+
+func TestDecryptConfig(t *testing.T) {
+	encoded, key := testConfigKey()
+	encPassword, _ := encryptConfigValue(key, "secret")
+	encNested, _ := encryptConfigValue(key, "nested-secret")
+
+	raw := map[string]any{
+		"username": "__token__",
+		"password": encPassword,
+		"nested":   map[string]any{"token": encNested},
+		"list":     []any{encNested, "plain"},
+	}
+
+	t.Run("key from env", func(t *testing.T) {
+		t.Setenv(configKeyEnv, encoded)
+		p := &PyPIPlugin{}
+
+		out, err := p.decryptConfig(context.Background(), raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out["password"] != "secret" {
+			t.Errorf("expected decrypted password, got '%v'", out["password"])
+		}
+		if out["nested"].(map[string]any)["token"] != "nested-secret" {
+			t.Errorf("expected decrypted nested value, got '%v'", out["nested"])
+		}
+		if list := out["list"].([]any); list[0] != "nested-secret" || list[1] != "plain" {
+			t.Errorf("unexpected list: %v", list)
+		}
+		if raw["password"] != encPassword {
+			t.Error("expected original config to be left untouched")
+		}
+	})
+
+	t.Run("key from file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "key")
+		if err := os.WriteFile(path, []byte(encoded+"\n"), 0o600); err != nil {
+			t.Fatalf("failed to write key: %v", err)
+		}
+		t.Setenv(configKeyEnv, "")
+		t.Setenv(configKeyFileEnv, path)
+		p := &PyPIPlugin{}
+
+		out, err := p.decryptConfig(context.Background(), raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out["password"] != "secret" {
+			t.Errorf("expected decrypted password, got '%v'", out["password"])
+		}
+	})
+
+	t.Run("key from command", func(t *testing.T) {
+		t.Setenv(configKeyEnv, "")
+		t.Setenv(configKeyFileEnv, "")
+		// CLIs print warnings to stderr, which must not end up in the key
+		mockExecutor := &MockCommandExecutor{ReturnOut: []byte(encoded + "\n"), ReturnStderr: []byte("WARNING: Python 3.8 is deprecated\n")}
+		p := &PyPIPlugin{cmdExecutor: mockExecutor}
+
+		withCommand := map[string]any{
+			"password":           encPassword,
+			"config_key_command": []any{"aws", "secretsmanager", "get-secret-value"},
+		}
+		out, err := p.decryptConfig(context.Background(), withCommand)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out["password"] != "secret" {
+			t.Errorf("expected decrypted password, got '%v'", out["password"])
+		}
+		if len(mockExecutor.RunCalls) != 1 || mockExecutor.RunCalls[0].Name != "aws" {
+			t.Errorf("expected key command to be run, got %v", mockExecutor.RunCalls)
+		}
+	})
+
+	t.Run("key from KMS", func(t *testing.T) {
+		t.Setenv(configKeyEnv, "")
+		t.Setenv(configKeyFileEnv, "")
+		t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+		t.Setenv("AWS_SESSION_TOKEN", "")
+		t.Setenv("AWS_REGION", "eu-west-1")
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				CiphertextBlob []byte `json:"CiphertextBlob"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || string(req.CiphertextBlob) != "wrapped-key" {
+				t.Errorf("unexpected request %v %q", err, req.CiphertextBlob)
+			}
+			if r.Header.Get("X-Amz-Target") != "TrentService.Decrypt" || !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request") {
+				t.Errorf("unexpected headers %v", r.Header)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"KeyId": "arn:aws:kms:eu-west-1:111122223333:key/1234abcd", "Plaintext": key})
+		}))
+		defer server.Close()
+		t.Setenv(awsKMSEndpointEnv, server.URL)
+		t.Setenv(configKeyKMSEnv, base64.StdEncoding.EncodeToString([]byte("wrapped-key")))
+
+		out, err := (&PyPIPlugin{}).decryptConfig(context.Background(), raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out["password"] != "secret" {
+			t.Errorf("expected decrypted password, got '%v'", out["password"])
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		t.Setenv(configKeyEnv, "")
+		t.Setenv(configKeyFileEnv, "")
+		p := &PyPIPlugin{}
+
+		if _, err := p.decryptConfig(context.Background(), raw); err == nil || !strings.Contains(err.Error(), "require a key") {
+			t.Errorf("expected missing key error, got %v", err)
+		}
+	})
+
+	t.Run("plain config needs no key", func(t *testing.T) {
+		t.Setenv(configKeyEnv, "")
+		t.Setenv(configKeyFileEnv, "")
+		p := &PyPIPlugin{}
+
+		plain := map[string]any{"username": "user", "password": "pass"}
+		out, err := p.decryptConfig(context.Background(), plain)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out["password"] != "pass" {
+			t.Errorf("expected config unchanged, got %v", out)
+		}
+	})
+}
+
+func TestExecuteWithEncryptedPassword(t *testing.T) {
+	encoded, key := testConfigKey()
+	t.Setenv(configKeyEnv, encoded)
+	encPassword, _ := encryptConfigValue(key, "decrypted-pass")
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+
+	mockExecutor := &MockCommandExecutor{ReturnOut: []byte("ok")}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":   "testuser",
+			"password":   encPassword,
+			"repository": "http://localhost:8080/",
+		},
+		Context: plugin.ReleaseContext{Version: "v1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got error: %s", resp.Error)
+	}
+
+	env := strings.Join(mockExecutor.RunCalls[0].Env, " ")
+	if !strings.Contains(env, "TWINE_PASSWORD=decrypted-pass") {
+		t.Errorf("expected decrypted password to be used, got env: %s", env)
+	}
+}
+
+func TestValidateWithUndecryptableValue(t *testing.T) {
+	t.Setenv(configKeyEnv, "")
+	t.Setenv(configKeyFileEnv, "")
+	p := &PyPIPlugin{}
+
+	resp, err := p.Validate(context.Background(), map[string]any{
+		"username": "testuser",
+		"password": "enc:AAAA",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Valid {
+		t.Error("expected invalid config")
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Field != "config" {
+		t.Errorf("expected single config error, got %v", resp.Errors)
+	}
+}
+
+func TestRunEncryptValue(t *testing.T) {
+	encoded, key := testConfigKey()
+	t.Setenv(configKeyEnv, encoded)
+
+	var stdout, stderr bytes.Buffer
+	code := runEncryptValue(strings.NewReader("my-token\n"), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	decrypted, err := decryptConfigValue(key, strings.TrimSpace(stdout.String()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decrypted != "my-token" {
+		t.Errorf("expected 'my-token', got '%s'", decrypted)
+	}
+}
+
+func TestRunGenerateConfigKey(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runGenerateConfigKey(&stdout, &stderr); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if _, err := decodeConfigKey(stdout.String()); err != nil {
+		t.Errorf("generated key is invalid: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Entry point groups that define executables.
+const (
+	consoleScriptsGroup = "console_scripts"
+	guiScriptsGroup     = "gui_scripts"
+)
+
+// entryPoint is a named object reference ("module:attr") in an entry point group.
+type entryPoint struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+}
+
+// wheelEntryPoints reads entry_points.txt from a wheel's .dist-info directory, keyed by group.
+// A wheel without entry points yields an empty map.
+func wheelEntryPoints(wheelPath string) (map[string][]entryPoint, error) {
+	data, err := readWheelFile(wheelPath, func(name string) bool {
+		dir, file := path.Split(name)
+		return file == "entry_points.txt" && strings.HasSuffix(dir, ".dist-info/") && strings.Count(name, "/") == 1
+	})
+	if errors.Is(err, errNotInArchive) {
+		return map[string][]entryPoint{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseEntryPoints(string(data)), nil
+}
+
+// parseEntryPoints parses the INI-style entry_points.txt format.
+func parseEntryPoints(data string) map[string][]entryPoint {
+	groups := map[string][]entryPoint{}
+	group := ""
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		name, target, ok := strings.Cut(line, "=")
+		if !ok || group == "" {
+			continue
+		}
+		groups[group] = append(groups[group], entryPoint{
+			Name:   strings.TrimSpace(name),
+			Target: strings.TrimSpace(target),
+		})
+	}
+	return groups
+}
+
+// mergeEntryPoints adds entry points to dst, skipping names already present in a group.
+// Wheels for different platforms normally declare the same entry points.
+func mergeEntryPoints(dst, src map[string][]entryPoint) {
+	for group, points := range src {
+		for _, ep := range points {
+			duplicate := false
+			for _, existing := range dst[group] {
+				if existing.Name == ep.Name {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				dst[group] = append(dst[group], ep)
+			}
+		}
+	}
+	for group := range dst {
+		sort.Slice(dst[group], func(i, j int) bool { return dst[group][i].Name < dst[group][j].Name })
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestParseEntryPoints(t *testing.T) {
+	data := `
+[console_scripts]
+mytool = mypkg.cli:main
+mytool-admin=mypkg.admin:main [admin]
+
+# comment
+[pytest11]
+myplugin = mypkg.pytest_plugin
+
+orphan = ignored:too
+`
+	groups := parseEntryPoints(data)
+
+	wantScripts := []entryPoint{
+		{Name: "mytool", Target: "mypkg.cli:main"},
+		{Name: "mytool-admin", Target: "mypkg.admin:main [admin]"},
+	}
+	if !reflect.DeepEqual(groups[consoleScriptsGroup], wantScripts) {
+		t.Errorf("console_scripts = %+v, want %+v", groups[consoleScriptsGroup], wantScripts)
+	}
+	if len(groups["pytest11"]) != 2 || groups["pytest11"][0].Target != "mypkg.pytest_plugin" {
+		t.Errorf("unexpected pytest11 group: %+v", groups["pytest11"])
+	}
+}
+
+func TestWheelEntryPointsMissing(t *testing.T) {
+	wheel := filepath.Join(t.TempDir(), "pkg-1.0-py3-none-any.whl")
+	writeTestWheel(t, wheel, map[string]string{"pkg/__init__.py": ""})
+
+	groups, err := wheelEntryPoints(wheel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("expected no entry points, got %+v", groups)
+	}
+}
+
+func TestExecuteReportsEntryPoints(t *testing.T) {
+	distPath := writeDistFiles(t)
+	entryPoints := "[console_scripts]\nmytool = mypkg.cli:main\n\n[gui_scripts]\nmytool-gui = mypkg.gui:run\n\n[mypkg.plugins]\nexample = mypkg.plugins.example\n"
+	for _, tag := range []string{"py3-none-any", "cp311-cp311-manylinux_2_17_x86_64"} {
+		writeTestWheel(t, filepath.Join("dist", "mypkg-1.0-"+tag+".whl"), map[string]string{
+			"mypkg/__init__.py":                    "",
+			"mypkg-1.0.dist-info/entry_points.txt": entryPoints,
+		})
+	}
+
+	p := &PyPIPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":   "__token__",
+			"password":   "pypi-token",
+			"repository": "http://localhost:8080/",
+			"dist_path":  distPath,
+		},
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := resp.Outputs["console_scripts"]; !reflect.DeepEqual(got, []entryPoint{{Name: "mytool", Target: "mypkg.cli:main"}}) {
+		t.Errorf("console_scripts = %+v", got)
+	}
+	if got := resp.Outputs["gui_scripts"]; !reflect.DeepEqual(got, []entryPoint{{Name: "mytool-gui", Target: "mypkg.gui:run"}}) {
+		t.Errorf("gui_scripts = %+v", got)
+	}
+	plugins, _ := resp.Outputs["entry_points"].(map[string][]entryPoint)
+	if len(plugins) != 1 || len(plugins["mypkg.plugins"]) != 1 {
+		t.Errorf("entry_points = %+v", resp.Outputs["entry_points"])
+	}
+}
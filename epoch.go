@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// splitEpoch splits the PEP 440 epoch from version: 1!2.0.0 is release 2.0.0 in epoch 1. A
+// version without an epoch is in epoch 0.
+func splitEpoch(version string) (int, string, error) {
+	epoch, release, ok := strings.Cut(version, "!")
+	if !ok {
+		return 0, version, nil
+	}
+	n, err := strconv.Atoi(epoch)
+	if err != nil || n < 0 || strings.HasPrefix(epoch, "+") {
+		return 0, "", fmt.Errorf("invalid epoch %q in version %s", epoch, version)
+	}
+	if release == "" || strings.Contains(release, "!") {
+		return 0, "", fmt.Errorf("invalid version %s", version)
+	}
+	return n, release, nil
+}
+
+// releaseVersion translates the release version into the version published on the index: the
+// tag prefix is dropped and VersionEpoch is added, since semantic versions cannot express an
+// epoch. A release version that carries an epoch must agree with VersionEpoch.
+func releaseVersion(cfg Config, raw string) (string, error) {
+	version := strings.TrimPrefix(raw, "v")
+	if version == "" {
+		return "", nil
+	}
+	epoch, release, err := splitEpoch(version)
+	if err != nil {
+		return "", err
+	}
+	if cfg.VersionEpoch == 0 {
+		return version, nil
+	}
+	if strings.Contains(version, "!") && epoch != cfg.VersionEpoch {
+		return "", fmt.Errorf("release version %s is in epoch %d, but version_epoch is %d", version, epoch, cfg.VersionEpoch)
+	}
+	return fmt.Sprintf("%d!%s", cfg.VersionEpoch, release), nil
+}
+
+// validateVersionEpoch validates the version_epoch option.
+func validateVersionEpoch(cfg Config) error {
+	if cfg.VersionEpoch < 0 {
+		return fmt.Errorf("version_epoch cannot be negative")
+	}
+	return nil
+}
+
+// checkDistEpochs verifies that every distribution is in the epoch of the release version and
+// that its file name carries the epoch of its metadata. A distribution built without the epoch
+// would be published as a version sorting before every release of the current epoch, and
+// indexes reject files whose name disagrees with their metadata.
+func checkDistEpochs(version string, files []string) error {
+	if version == "" {
+		return nil
+	}
+	want, _, err := splitEpoch(version)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		meta, err := readDistMetadata(f)
+		if err != nil {
+			continue
+		}
+		epoch, _, err := splitEpoch(meta.Version)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+		if epoch != want {
+			return fmt.Errorf("%s is version %s in epoch %d, but release %s is in epoch %d", f, meta.Version, epoch, version, want)
+		}
+		if named := versionFromFilename(meta.Name, filepath.Base(f)); named != "" {
+			if nameEpoch, _, err := splitEpoch(named); err != nil || nameEpoch != epoch {
+				return fmt.Errorf("file name of %s does not carry epoch %d of its metadata version %s", f, epoch, meta.Version)
+			}
+		}
+	}
+	return nil
+}
+
+// versionSlug returns version with the characters that are not safe in file and tag names,
+// such as the epoch separator, replaced by underscores.
+func versionSlug(version string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, version)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestReleaseVersion(t *testing.T) {
+	tests := []struct {
+		epoch   int
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{0, "v2.0.0", "2.0.0", false},
+		{0, "1!2.0.0", "1!2.0.0", false},
+		{1, "v2.0.0", "1!2.0.0", false},
+		{1, "1!2.0.0", "1!2.0.0", false},
+		{1, "", "", false},
+		{2, "1!2.0.0", "", true},
+		{0, "x!2.0.0", "", true},
+		{0, "1!", "", true},
+		{0, "1!2!3", "", true},
+	}
+	for _, tt := range tests {
+		got, err := releaseVersion(Config{VersionEpoch: tt.epoch}, tt.raw)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("releaseVersion(%d, %q) = %q, %v; want %q, error %v", tt.epoch, tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckDistEpochs(t *testing.T) {
+	writeDistFiles(t)
+	write := func(name, version string) string {
+		path := filepath.Join("dist", name)
+		writeTestWheel(t, path, map[string]string{
+			"mypkg.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: " + version + "\n",
+		})
+		return path
+	}
+	epoch := write("mypkg-1!2.0.0-py3-none-any.whl", "1!2.0.0")
+	plain := write("mypkg-2.0.0-py3-none-any.whl", "2.0.0")
+	misnamed := write("mypkg-2.0.0-py2-none-any.whl", "1!2.0.0")
+
+	tests := []struct {
+		version string
+		files   []string
+		want    string
+	}{
+		{"1!2.0.0", []string{epoch}, ""},
+		{"2.0.0", []string{plain}, ""},
+		{"", []string{plain}, ""},
+		{"1!2.0.0", []string{epoch, plain}, "is version 2.0.0 in epoch 0, but release 1!2.0.0 is in epoch 1"},
+		{"2.0.0", []string{epoch}, "in epoch 1, but release 2.0.0 is in epoch 0"},
+		{"1!2.0.0", []string{misnamed}, "does not carry epoch 1"},
+	}
+	for _, tt := range tests {
+		err := checkDistEpochs(tt.version, tt.files)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("checkDistEpochs(%q, %v) = %v, want %q", tt.version, tt.files, err, tt.want)
+		}
+	}
+}
+
+func TestExecuteVersionEpoch(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-2.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-2.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 2.0.0\n",
+	})
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{}}
+	config := map[string]any{"username": "__token__", "password": "pypi-token", "version_epoch": 1}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "v2.0.0"},
+		DryRun:  true,
+	})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "version epoch mismatch") {
+		t.Fatalf("expected the distribution without epoch to be refused, got %v %+v", err, resp)
+	}
+
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1!2.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1!2.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1!2.0.0\n",
+	})
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "v2.0.0"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success || resp.Outputs["version"] != "1!2.0.0" {
+		t.Fatalf("expected version 1!2.0.0, got %v %+v", err, resp)
+	}
+}
+
+func TestNexusStagingTagEncodesEpoch(t *testing.T) {
+	tag := nexusStagingTag("mypkg", "1!2.0.0+local", time.Date(2024, 3, 1, 13, 5, 9, 0, time.UTC))
+	if tag != "pypi-mypkg-1_2.0.0_local-20240301T130509Z" || !nexusRepositoryNamePattern.MatchString(tag) {
+		t.Errorf("unexpected tag %s", tag)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// maxJSONAPIResponseSize bounds the JSON API documents read; a project document lists every
+// release and file.
+const maxJSONAPIResponseSize = 32 << 20 // 32 MiB
+
+// onExistingSkip ends the publish successfully when the version is already published. The other
+// on_existing values are the check modes.
+const onExistingSkip = "skip"
+
+// onExistingPolicies lists the accepted on_existing values.
+var onExistingPolicies = []string{checkOff, onExistingSkip, checkWarn, checkFail}
+
+// knownJSONAPIs maps upload endpoints to the JSON API of their index.
+var knownJSONAPIs = map[string]string{
+	"https://upload.pypi.org/legacy/": "https://pypi.org/pypi",
+	"https://test.pypi.org/legacy/":   "https://test.pypi.org/pypi",
+}
+
+// defaultJSONAPI returns the JSON API of a well-known upload repository, or "".
+func defaultJSONAPI(repository string) string {
+	if !strings.HasSuffix(repository, "/") {
+		repository += "/"
+	}
+	return knownJSONAPIs[repository]
+}
+
+// publishedFiles returns the files of project's version on the index mapped to their SHA-256
+// digest, or "" when the index reports none. A version that is not published has no files. The
+// JSON API is asked when known; other indexes are read through their simple index.
+func (p *PyPIPlugin) publishedFiles(ctx context.Context, cfg Config, project, version string) (map[string]string, error) {
+	published := map[string]string{}
+	if cfg.JSONAPIURL != "" {
+		release, err := p.fetchJSONRelease(ctx, cfg.JSONAPIURL, project, version)
+		if err != nil {
+			return nil, err
+		}
+		if release == nil {
+			return published, nil
+		}
+		for _, u := range release.URLs {
+			published[u.Filename] = strings.ToLower(u.Digests.SHA256)
+		}
+		return published, nil
+	}
+	files, err := p.indexFiles(ctx, cfg.IndexURL, project)
+	if err != nil {
+		return nil, err
+	}
+	for name, digest := range files {
+		if v := versionFromFilename(project, name); v != "" && normalizeVersion(v) == normalizeVersion(version) {
+			published[name] = digest
+		}
+	}
+	return published, nil
+}
+
+// jsonRelease is the response of the JSON API for a release.
+type jsonRelease struct {
+	Info struct {
+		RequiresDist []string `json:"requires_dist"`
+	} `json:"info"`
+	URLs []struct {
+		Filename string `json:"filename"`
+		Digests  struct {
+			SHA256 string `json:"sha256"`
+		} `json:"digests"`
+	} `json:"urls"`
+}
+
+// fetchJSONRelease returns the release of project's version from the JSON API. A version that is
+// not published yields a nil release and no error.
+func (p *PyPIPlugin) fetchJSONRelease(ctx context.Context, apiURL, project, version string) (*jsonRelease, error) {
+	var release jsonRelease
+	found, err := p.getJSONAPI(ctx, apiURL, project+"/"+version, &release)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// getJSONAPI decodes the JSON API document at path, such as "<project>" or
+// "<project>/<version>", into result. It reports false without an error when the project or
+// version is not published.
+func (p *PyPIPlugin) getJSONAPI(ctx context.Context, apiURL, path string, result any) (bool, error) {
+	project, version, _ := strings.Cut(path, "/")
+	target := strings.TrimSuffix(apiURL, "/") + "/" + url.PathEscape(normalizeProjectName(project))
+	if version != "" {
+		target += "/" + url.PathEscape(version)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target+"/json", nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create json api request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	// Bypass CDN caches that would hide a freshly published version
+	req.Header.Set("Cache-Control", "max-age=0")
+
+	resp, err := p.getHTTPClient().Do(req)
+	if err != nil {
+		return false, fmt.Errorf("json api request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("json api request for %s failed: %s", strings.ReplaceAll(path, "/", " "), resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJSONAPIResponseSize)).Decode(result); err != nil {
+		return false, fmt.Errorf("invalid json api response: %w", err)
+	}
+	return true, nil
+}
+
+// checkExisting applies on_existing when the version is already published: skip ends the
+// publish successfully without uploading, warn records a warning, and fail blocks the publish.
+// A response is returned only when the publish ends here. An unreachable index only blocks the
+// publish in fail mode.
+func (p *PyPIPlugin) checkExisting(ctx context.Context, cfg Config, version string, result *preflightResult) *plugin.ExecuteResponse {
+	project, distVersion := distProjectVersion(result.files)
+	if project == "" {
+		return nil
+	}
+	if version == "" {
+		version = distVersion
+	}
+	files, err := p.publishedFiles(ctx, cfg, project, version)
+	if err != nil {
+		if cfg.OnExisting == checkFail {
+			return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("cannot check whether %s %s is already published: %v", project, version, err)}
+		}
+		result.warn("cannot check whether %s %s is already published: %v", project, version, err)
+		return nil
+	}
+	result.outputs["version_exists"] = len(files) > 0
+	if len(files) == 0 {
+		return nil
+	}
+	published := sortedKeys(files)
+	result.outputs["existing_files"] = published
+
+	switch cfg.OnExisting {
+	case checkWarn:
+		result.warn("%s %s is already published on %s; the upload may be rejected", project, version, cfg.Repository)
+		return nil
+	case checkFail:
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("%s %s is already published on %s (%s)", project, version, cfg.Repository, strings.Join(published, ", ")),
+		}
+	}
+
+	for _, f := range result.files {
+		if !containsString(published, filepath.Base(f)) {
+			result.warn("%s is not part of the published %s %s and was not uploaded", filepath.Base(f), project, version)
+		}
+	}
+	outputs := map[string]any{
+		"repository":   cfg.Repository,
+		"dist_path":    cfg.DistPath,
+		"version":      version,
+		"channel":      releaseChannel(version),
+		"skipped":      true,
+		"plugin_build": currentBuild().String(),
+	}
+	result.apply(outputs)
+	return &plugin.ExecuteResponse{
+		Success:   true,
+		Message:   fmt.Sprintf("%s %s is already published on %s; skipped the upload", project, version, cfg.Repository),
+		Outputs:   outputs,
+		Artifacts: result.artifacts,
+	}
+}
+
+// validateExistingConfig validates the on_existing options.
+func validateExistingConfig(cfg Config) error {
+	if cfg.OnExisting == "" || cfg.OnExisting == checkOff {
+		return nil
+	}
+	if !containsString(onExistingPolicies, cfg.OnExisting) {
+		return fmt.Errorf("on_existing must be one of: %s", strings.Join(onExistingPolicies, ", "))
+	}
+	if cfg.JSONAPIURL == "" && cfg.IndexURL == "" {
+		return fmt.Errorf("on_existing needs json_api_url or index_url for repositories other than PyPI and TestPyPI")
+	}
+	return validateJSONAPIURL(cfg)
+}
+
+// validateJSONAPIURL validates a json_api_url other than the default of the repository.
+func validateJSONAPIURL(cfg Config) error {
+	if cfg.JSONAPIURL != "" && cfg.JSONAPIURL != defaultJSONAPI(cfg.Repository) {
+		if err := validateRepositoryURL(cfg.JSONAPIURL); err != nil {
+			return fmt.Errorf("invalid json_api_url: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// newTestJSONAPI serves the JSON API with mypkg 1.0.0 published as an sdist, and the simple index
+// listing the same file.
+func newTestJSONAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pypi/mypkg/1.0.0/json":
+			_, _ = w.Write([]byte(`{"info": {"name": "mypkg", "version": "1.0.0"}, "urls": [{"filename": "mypkg-1.0.0.tar.gz"}]}`))
+		case "/simple/mypkg/":
+			_, _ = w.Write([]byte(`<a href="mypkg-1.0.0.tar.gz#sha256=abc">mypkg-1.0.0.tar.gz</a>`))
+		case "/broken/mypkg/1.0.0/json":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExecuteOnExisting(t *testing.T) {
+	server := newTestJSONAPI(t)
+	tests := []struct {
+		name       string
+		onExisting string
+		version    string
+		apiPath    string
+		wantUpload bool
+		wantOK     bool
+		want       string
+	}{
+		{"skip", onExistingSkip, "1.0.0", "/pypi", false, true, "mypkg 1.0.0 is already published on http://localhost:8080/; skipped the upload"},
+		{"warn", checkWarn, "1.0.0", "/pypi", true, true, ""},
+		{"fail", checkFail, "1.0.0", "/pypi", false, false, "mypkg 1.0.0 is already published on http://localhost:8080/ (mypkg-1.0.0.tar.gz)"},
+		{"not published", checkFail, "1.1.0", "/pypi", true, true, ""},
+		{"index unavailable", onExistingSkip, "1.0.0", "/broken", true, true, ""},
+		{"index unavailable in fail mode", checkFail, "1.0.0", "/broken", false, false, "cannot check whether mypkg 1.0.0 is already published"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeDistFiles(t)
+			writeTestWheel(t, filepath.Join("dist", "mypkg-"+tt.version+"-py3-none-any.whl"), map[string]string{
+				"mypkg-" + tt.version + ".dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: " + tt.version + "\n",
+			})
+			executor := &MockCommandExecutor{}
+			p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: executor}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"username":     "deployer",
+					"password":     "secret",
+					"repository":   "http://localhost:8080/",
+					"on_existing":  tt.onExisting,
+					"json_api_url": server.URL + tt.apiPath,
+				},
+				Context: plugin.ReleaseContext{Version: tt.version},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Success != tt.wantOK || (len(executor.RunCalls) > 0) != tt.wantUpload {
+				t.Fatalf("expected success %v and upload %v, got %d uploads and %+v", tt.wantOK, tt.wantUpload, len(executor.RunCalls), resp)
+			}
+			if got := resp.Message + resp.Error; tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecuteOnExistingSkipReportsUnpublishedFiles(t *testing.T) {
+	server := newTestJSONAPI(t)
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	executor := &MockCommandExecutor{}
+	p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: executor}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":    "deployer",
+			"password":    "secret",
+			"repository":  "http://localhost:8080/",
+			"index_url":   server.URL + "/simple/",
+			"on_existing": onExistingSkip,
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success || len(executor.RunCalls) != 0 {
+		t.Fatalf("expected the upload to be skipped through the simple index, got %v %+v", err, resp)
+	}
+	if fmt.Sprint(resp.Outputs["existing_files"]) != "[mypkg-1.0.0.tar.gz]" || resp.Outputs["skipped"] != true {
+		t.Errorf("unexpected outputs %v", resp.Outputs)
+	}
+	if warnings := fmt.Sprint(resp.Outputs["warnings"]); !strings.Contains(warnings, "mypkg-1.0.0-py3-none-any.whl is not part of the published mypkg 1.0.0") {
+		t.Errorf("expected a warning for the unpublished wheel, got %s", warnings)
+	}
+}
+
+func TestValidateExistingConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"off", Config{OnExisting: checkOff}, false},
+		{"pypi", Config{OnExisting: onExistingSkip, Repository: "https://upload.pypi.org/legacy/", JSONAPIURL: defaultJSONAPI("https://upload.pypi.org/legacy/")}, false},
+		{"simple index", Config{OnExisting: checkFail, Repository: "http://localhost:8080/", IndexURL: "http://localhost:8080/simple/"}, false},
+		{"no index", Config{OnExisting: checkWarn, Repository: "http://localhost:8080/"}, true},
+		{"unknown policy", Config{OnExisting: "ignore", IndexURL: "http://localhost:8080/simple/"}, true},
+		{"invalid json api", Config{OnExisting: checkFail, JSONAPIURL: "ftp://localhost/pypi"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateExistingConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateExistingConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reservedTwineFlags are the twine options the plugin sets itself. Overriding them through
+// extra_args would bypass the repository URL validation or leak credentials into the
+// configuration, so they are rejected, as are abbreviations twine's parser would expand to them.
+var reservedTwineFlags = []string{
+	"--username",
+	"--password",
+	"--repository",
+	"--repository-url",
+	"--config-file",
+	"--client-cert",
+}
+
+// twineSwitches are the twine options that take no value.
+var twineSwitches = []string{
+	"--sign",
+	"--skip-existing",
+	"--verbose",
+	"--disable-progress-bar",
+	"--non-interactive",
+	"--attestations",
+}
+
+// shellMetacharacters are rejected in extra_args. Arguments are passed to twine without a
+// shell, but they usually originate from CI templating where these point at injection.
+const shellMetacharacters = ";&|`$<>()\\'\"\n\r\x00"
+
+// validateExtraArgs validates the extra_args option. Only long options are accepted; a
+// non-option argument must be the value of the option before it, so extra_args cannot add
+// files to the upload.
+func validateExtraArgs(cfg Config) error {
+	if len(cfg.ExtraArgs) == 0 {
+		return nil
+	}
+	if usesNativeUploader(cfg) {
+		return fmt.Errorf("extra_args are passed to twine and cannot be combined with options that use the built-in uploader")
+	}
+	valueAllowed := false
+	for _, arg := range cfg.ExtraArgs {
+		if strings.ContainsAny(arg, shellMetacharacters) {
+			return fmt.Errorf("extra_args entry %q contains a shell metacharacter", arg)
+		}
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			name, _, hasValue := strings.Cut(arg, "=")
+			if name == "--" {
+				return fmt.Errorf("extra_args must not end option parsing with --")
+			}
+			for _, reserved := range reservedTwineFlags {
+				if strings.HasPrefix(reserved, name) {
+					return fmt.Errorf("extra_args must not set %s, which is configured by the plugin", reserved)
+				}
+			}
+			valueAllowed = !hasValue && !containsString(twineSwitches, name)
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("extra_args entry %q: use the long form of twine options", arg)
+		default:
+			if !valueAllowed {
+				return fmt.Errorf("extra_args entry %q is not an option or the value of one", arg)
+			}
+			valueAllowed = false
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateExtraArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"none", nil, ""},
+		{"flag", []string{"--verbose"}, ""},
+		{"flag with value", []string{"--comment", "release build", "--sign"}, ""},
+		{"inline value", []string{"--identity=releases@example.com"}, ""},
+		{"password", []string{"--password", "secret"}, "--password"},
+		{"abbreviated username", []string{"--user=admin"}, "--username"},
+		{"repository url override", []string{"--repository-url=https://evil.example.com/"}, "--repository-url"},
+		{"short option", []string{"-u", "admin"}, "long form"},
+		{"extra file", []string{"--verbose", "../secrets.tar.gz"}, "not an option"},
+		{"shell metacharacter", []string{"--comment", "$(cat ~/.pypirc)"}, "metacharacter"},
+		{"end of options", []string{"--", "file.whl"}, "--"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExtraArgs(Config{ExtraArgs: tt.args})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateExtraArgsNativeUploader(t *testing.T) {
+	cfg := Config{AuthScheme: authSchemeBearer, ExtraArgs: []string{"--verbose"}}
+	if err := validateExtraArgs(cfg); err == nil {
+		t.Error("expected extra_args to be rejected with the built-in uploader")
+	}
+}
+
+func TestBuildTwineArgsExtraArgs(t *testing.T) {
+	p := &PyPIPlugin{}
+	args := p.buildTwineArgs(Config{
+		Repository: "https://upload.pypi.org/legacy/",
+		Username:   "__token__",
+		Password:   "token",
+		DistPath:   "dist/*",
+		ExtraArgs:  []string{"--comment", "nightly"},
+	})
+	got := strings.Join(args, " ")
+	if !strings.HasSuffix(got, "--comment nightly dist/*") {
+		t.Errorf("expected the extra args before the files, got %q", got)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// repositoryCredentialKeys are the credential options a repositories entry does not inherit
+// from the top level, so a token for one index is never sent to another.
+var repositoryCredentialKeys = []string{"username", "password", "token", "token_command", "trusted_publishing"}
+
+// isFanOutConfig reports whether the config publishes the release to several repositories.
+func isFanOutConfig(raw map[string]any) bool {
+	_, ok := raw["repositories"]
+	return ok
+}
+
+// batchListKey returns the option listing the configs of a batch: repositories for a fan-out,
+// packages for a release train.
+func batchListKey(raw map[string]any) string {
+	if isFanOutConfig(raw) {
+		return "repositories"
+	}
+	return "packages"
+}
+
+// batchTargets returns the configs a batch publishes: the packages of a release train, or the
+// release once per repository of a fan-out.
+func batchTargets(raw map[string]any) ([]batchPackage, error) {
+	if isFanOutConfig(raw) {
+		return repositoryTargets(raw)
+	}
+	return batchPackages(raw)
+}
+
+// repositoryTargets returns the configs of a fan-out, each repositories entry merged over the
+// top-level options except the credentials. Entries are named after their repository URL.
+func repositoryTargets(raw map[string]any) ([]batchPackage, error) {
+	if isBatchConfig(raw) {
+		return nil, fmt.Errorf("repositories cannot be combined with packages; set the repository of each package instead")
+	}
+	targets, err := mergeBatchItems(raw, "repositories", "repository", repositoryCredentialKeys)
+	if err != nil {
+		return nil, err
+	}
+	for i, t := range targets {
+		if url, _ := t.Config["repository"].(string); url == "" {
+			return nil, fmt.Errorf("repositories[%d]: repository is required", i)
+		}
+	}
+	return targets, nil
+}
+
+// fanOutResponse reports the batch results of a fan-out per repository.
+func fanOutResponse(resp *plugin.ExecuteResponse, dryRun bool) {
+	results, _ := resp.Outputs["packages"].([]batchPackageResult)
+	delete(resp.Outputs, "packages")
+	resp.Outputs["repositories"] = results
+	if failed, ok := resp.Outputs["failed_packages"]; ok {
+		delete(resp.Outputs, "failed_packages")
+		resp.Outputs["failed_repositories"] = failed
+		resp.Error = fmt.Sprintf("%d of %d repositories failed: %v", len(failed.([]string)), len(results), failed)
+	}
+
+	published, _ := resp.Outputs["published"].(int)
+	verb := "Published"
+	if dryRun {
+		verb = "Would publish"
+	}
+	resp.Message = fmt.Sprintf("%s to %d of %d repositories", verb, published, len(results))
+}
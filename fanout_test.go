@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestRepositoryTargets(t *testing.T) {
+	raw := map[string]any{
+		"username":      "__token__",
+		"password":      "pypi-token",
+		"dist_path":     "dist/*",
+		"skip_existing": false,
+		"repositories": []any{
+			map[string]any{"repository": "https://upload.pypi.org/legacy/", "username": "__token__", "password": "pypi-token"},
+			map[string]any{"name": "internal", "repository": "http://localhost:8080/", "username": "ci", "skip_existing": true},
+		},
+	}
+	targets, err := repositoryTargets(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(targets) != 2 || targets[0].Name != "https://upload.pypi.org/legacy/" || targets[1].Name != "internal" {
+		t.Fatalf("unexpected targets %+v", targets)
+	}
+	internal := targets[1].Config
+	if internal["dist_path"] != "dist/*" || internal["skip_existing"] != true || internal["username"] != "ci" {
+		t.Errorf("entries must be merged over the top-level options: %v", internal)
+	}
+	if _, ok := internal["password"]; ok {
+		t.Error("credentials must not be inherited from the top level")
+	}
+
+	for _, bad := range []map[string]any{
+		{"repositories": []any{}},
+		{"repositories": []any{map[string]any{"username": "ci"}}},
+		{"repositories": []any{map[string]any{"repository": "http://localhost:8080/"}, map[string]any{"repository": "http://localhost:8080/"}}},
+		{"repositories": []any{map[string]any{"repository": "http://localhost:8080/"}}, "packages": []any{map[string]any{"dist_path": "dist/*"}}},
+	} {
+		if _, err := repositoryTargets(bad); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestExecuteFanOut(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.tar.gz")
+	var mu sync.Mutex
+	uploads := map[string]string{}
+	executor := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			joined := strings.Join(args, " ")
+			if strings.Contains(joined, "localhost:9090") {
+				return []byte("HTTPError: 403 Forbidden"), errors.New("exit status 1")
+			}
+			mu.Lock()
+			uploads[joined] = ""
+			mu.Unlock()
+			return []byte("uploaded"), nil
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: executor}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"mirror_consistency_check": checkOff,
+			"repositories": []any{
+				map[string]any{"name": "public", "repository": "http://localhost:8080/", "username": "__token__", "password": "pypi-token"},
+				map[string]any{"name": "internal", "repository": "http://127.0.0.1:8081/", "username": "ci", "password": "internal-pass", "skip_existing": true},
+				map[string]any{"name": "broken", "repository": "http://localhost:9090/", "username": "ci", "password": "other-pass"},
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || resp.Message != "Published to 2 of 3 repositories" || !strings.Contains(resp.Error, "1 of 3 repositories failed: [broken]") {
+		t.Errorf("unexpected response %q / %q", resp.Message, resp.Error)
+	}
+	results, _ := resp.Outputs["repositories"].([]batchPackageResult)
+	if len(results) != 3 || !results[0].Success || !results[1].Success || results[2].Success {
+		t.Fatalf("unexpected per-repository results %+v", resp.Outputs["repositories"])
+	}
+	if _, ok := resp.Outputs["packages"]; ok {
+		t.Error("expected fan-out results under repositories only")
+	}
+
+	for _, call := range executor.RunCalls {
+		args := strings.Join(call.Args, " ")
+		env := strings.Join(call.Env, " ")
+		switch {
+		case strings.Contains(args, "localhost:8080"):
+			if !strings.Contains(env, "TWINE_PASSWORD=pypi-token") || strings.Contains(args, "--skip-existing") {
+				t.Errorf("unexpected public upload %s with %s", args, env)
+			}
+		case strings.Contains(args, "127.0.0.1:8081"):
+			if !strings.Contains(env, "TWINE_PASSWORD=internal-pass") || !strings.Contains(args, "--skip-existing") {
+				t.Errorf("unexpected internal upload %s with %s", args, env)
+			}
+		}
+	}
+	if len(uploads) != 2 {
+		t.Errorf("expected one upload per healthy repository, got %v", uploads)
+	}
+}
+
+func TestValidateFanOut(t *testing.T) {
+	p := &PyPIPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{
+		"repositories": []any{
+			map[string]any{"repository": "http://localhost:8080/", "username": "ci", "password": "secret"},
+			map[string]any{"repository": "http://localhost:8081/", "username": "ci", "password": "secret", "max_output_bytes": -1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, e := range resp.Errors {
+		found = found || strings.HasPrefix(e.Field, "repositories[1].")
+	}
+	if resp.Valid || !found {
+		t.Errorf("expected an error for repositories[1], got %+v", resp.Errors)
+	}
+}
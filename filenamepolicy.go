@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// Rules of the filename_policy option, reported with each violation.
+const (
+	filenameRulePattern   = "pattern"
+	filenameRuleProject   = "project"
+	filenameRuleVersion   = "version"
+	filenameRuleExtension = "extension"
+)
+
+// FilenamePolicy holds the rules every distribution file name must satisfy, catching artifacts
+// misnamed by custom build scripts before they reach the index.
+type FilenamePolicy struct {
+	// Patterns are regular expressions of which each file name must match one. {project} and
+	// {version} expand to the escaped wheel-normalized project name and PEP 440 release version.
+	Patterns []string
+	// RequireProject requires names to start with the project name normalized as in wheel file
+	// names (lowercase, with runs of -_. replaced by _)
+	RequireProject bool
+	// RequireVersion requires the version in the name to be exactly the release version in its
+	// PEP 440 normal form, so that 1.0 does not pass for 1.0.0
+	RequireVersion bool
+	// Extensions lists the allowed file types, such as .whl and .tar.gz
+	Extensions []string
+}
+
+// filenameViolation is a distribution file name breaking a filename_policy rule, reported in
+// outputs.
+type filenameViolation struct {
+	File   string `json:"file"`
+	Rule   string `json:"rule"`
+	Detail string `json:"detail"`
+}
+
+// wheelProjectName normalizes a project name as wheel and sdist file names spell it (PEP 491,
+// PEP 625).
+func wheelProjectName(name string) string {
+	return strings.ToLower(projectNameSeparators.ReplaceAllString(name, "_"))
+}
+
+// expandFilenamePattern replaces the {project} and {version} placeholders of a pattern.
+func expandFilenamePattern(pattern, project, version string) string {
+	return strings.NewReplacer(
+		"{project}", regexp.QuoteMeta(wheelProjectName(project)),
+		"{version}", regexp.QuoteMeta(normalizeVersion(version)),
+	).Replace(pattern)
+}
+
+// checkFilenamePolicy returns the files whose names break the policy. The project is read
+// from the distribution metadata; stripLocal compares versions without their local segment,
+// as they are stripped before the upload.
+func checkFilenamePolicy(policy *FilenamePolicy, version string, files []string, stripLocal bool) []filenameViolation {
+	if policy == nil {
+		return nil
+	}
+	project, _ := distProjectVersion(files)
+	patterns := make([]*regexp.Regexp, 0, len(policy.Patterns))
+	for _, p := range policy.Patterns {
+		// Validation compiled the patterns with placeholder values, so they compile here too
+		if re, err := regexp.Compile(expandFilenamePattern(p, project, version)); err == nil {
+			patterns = append(patterns, re)
+		}
+	}
+
+	var violations []filenameViolation
+	for _, f := range files {
+		base := filepath.Base(f)
+		report := func(rule, format string, args ...any) {
+			violations = append(violations, filenameViolation{File: filepath.ToSlash(f), Rule: rule, Detail: fmt.Sprintf(format, args...)})
+		}
+
+		if len(patterns) > 0 && !matchesAny(patterns, base) {
+			report(filenameRulePattern, "matches none of %s", strings.Join(policy.Patterns, ", "))
+		}
+		if policy.RequireProject {
+			switch {
+			case project == "":
+				report(filenameRuleProject, "no distribution has readable metadata naming the project")
+			case !strings.HasPrefix(base, wheelProjectName(project)+"-"):
+				report(filenameRuleProject, "does not start with %s-", wheelProjectName(project))
+			}
+		}
+		if policy.RequireVersion && version != "" {
+			named, ok := distFilenameVersion(f)
+			compared := named
+			if stripLocal {
+				compared = stripLocalVersion(named)
+			}
+			if want := normalizeVersion(version); !ok || compared != want {
+				report(filenameRuleVersion, "names version %q instead of exactly %s", named, want)
+			}
+		}
+		if len(policy.Extensions) > 0 && !hasAnySuffix(base, policy.Extensions) {
+			report(filenameRuleExtension, "is not one of %s", strings.Join(policy.Extensions, ", "))
+		}
+	}
+	return violations
+}
+
+// matchesAny reports whether s matches one of the patterns.
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnySuffix reports whether s ends with one of the suffixes.
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// filenamePolicyError describes the violations of a release.
+func filenamePolicyError(violations []filenameViolation) string {
+	parts := make([]string, 0, len(violations))
+	for _, v := range violations {
+		parts = append(parts, fmt.Sprintf("%s %s", v.File, v.Detail))
+	}
+	return fmt.Sprintf("filename_policy: %d distribution file name(s) break the policy: %s; fix the build script's output names",
+		len(violations), strings.Join(parts, "; "))
+}
+
+// parseFilenamePolicy parses the filename_policy config object.
+func parseFilenamePolicy(raw any) *FilenamePolicy {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil
+	}
+	parser := helpers.NewConfigParser(m)
+	return &FilenamePolicy{
+		Patterns:       parser.GetStringSlice("patterns", nil),
+		RequireProject: parser.GetBool("require_project", false),
+		RequireVersion: parser.GetBool("require_version", false),
+		Extensions:     parser.GetStringSlice("extensions", nil),
+	}
+}
+
+// validateFilenamePolicy validates the filename_policy rules.
+func validateFilenamePolicy(policy *FilenamePolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if len(policy.Patterns) == 0 && !policy.RequireProject && !policy.RequireVersion && len(policy.Extensions) == 0 {
+		return fmt.Errorf("filename_policy needs patterns, require_project, require_version or extensions")
+	}
+	for i, p := range policy.Patterns {
+		if _, err := regexp.Compile(expandFilenamePattern(p, "project", "1.0")); err != nil {
+			return fmt.Errorf("filename_policy.patterns[%d]: %w", i, err)
+		}
+	}
+	for _, ext := range policy.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("filename_policy.extensions must start with a dot, got %q", ext)
+		}
+	}
+	return nil
+}
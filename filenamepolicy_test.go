@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestCheckFilenamePolicy(t *testing.T) {
+	writeDistFiles(t, "My.Pkg-1.2.0rc1.tar.gz", "my_pkg-1.2-py3-none-any.whl", "my_pkg-1.2.0rc1-cp312-cp312-linux_x86_64.egg")
+	writeTestWheel(t, filepath.Join("dist", "my_pkg-1.2.0rc1-py3-none-any.whl"), map[string]string{
+		"my_pkg-1.2.0rc1.dist-info/METADATA": "Metadata-Version: 2.1\nName: My.Pkg\nVersion: 1.2.0rc1\n",
+	})
+	files, err := expandDistGlob("dist/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := &FilenamePolicy{RequireProject: true, RequireVersion: true, Extensions: []string{".whl", ".tar.gz"}}
+	got := fmt.Sprint(checkFilenamePolicy(policy, "1.2.0-rc.1", files, false))
+	want := "[{dist/My.Pkg-1.2.0rc1.tar.gz project does not start with my_pkg-} " +
+		"{dist/my_pkg-1.2-py3-none-any.whl version names version \"1.2\" instead of exactly 1.2.0rc1} " +
+		"{dist/my_pkg-1.2.0rc1-cp312-cp312-linux_x86_64.egg version names version \"\" instead of exactly 1.2.0rc1} " +
+		"{dist/my_pkg-1.2.0rc1-cp312-cp312-linux_x86_64.egg extension is not one of .whl, .tar.gz}]"
+	if got != want {
+		t.Errorf("checkFilenamePolicy() = %s, want %s", got, want)
+	}
+
+	policy = &FilenamePolicy{Patterns: []string{`^{project}-{version}-py3-none-any\.whl$`, `^{project}-{version}\.tar\.gz$`}}
+	violations := checkFilenamePolicy(policy, "1.2.0rc1", files, false)
+	if len(violations) != 3 || violations[0].Rule != filenameRulePattern || violations[0].File != "dist/My.Pkg-1.2.0rc1.tar.gz" {
+		t.Errorf("unexpected pattern violations %v", violations)
+	}
+	if got := checkFilenamePolicy(nil, "1.2.0rc1", files, false); got != nil {
+		t.Errorf("expected no check without a policy, got %v", got)
+	}
+}
+
+func TestExecuteFilenamePolicy(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.2.0.tar.gz", "mypkg_build-1.2.0.tar.gz")
+	uploaded := false
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			uploaded = true
+			return []byte("ok"), nil
+		},
+	}}
+	config := map[string]any{
+		"username":        "__token__",
+		"password":        "pypi-token",
+		"repository":      "http://localhost:8080/",
+		"filename_policy": map[string]any{"patterns": []any{`^mypkg-{version}\.tar\.gz$`}},
+	}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "v1.2.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || uploaded || !strings.Contains(resp.Error, "dist/mypkg_build-1.2.0.tar.gz matches none of") {
+		t.Fatalf("expected the misnamed sdist to fail the publish, got %+v", resp)
+	}
+	if violations, _ := resp.Outputs["filename_violations"].([]filenameViolation); len(violations) != 1 {
+		t.Errorf("unexpected filename_violations %v", resp.Outputs["filename_violations"])
+	}
+
+	config["dist_path"] = "dist/mypkg-*"
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "v1.2.0"},
+	})
+	if err != nil || !resp.Success || !uploaded {
+		t.Errorf("expected conforming distributions to upload, got %v %+v", err, resp)
+	}
+}
+
+func TestValidateFilenamePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *FilenamePolicy
+		wantErr bool
+	}{
+		{"disabled", nil, false},
+		{"rules", &FilenamePolicy{RequireProject: true, Extensions: []string{".whl"}}, false},
+		{"pattern", &FilenamePolicy{Patterns: []string{`^{project}-{version}-.*\.whl$`}}, false},
+		{"empty", &FilenamePolicy{}, true},
+		{"invalid pattern", &FilenamePolicy{Patterns: []string{`^{project}-(`}}, true},
+		{"extension without dot", &FilenamePolicy{Extensions: []string{"whl"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateFilenamePolicy(tt.policy); (err != nil) != tt.wantErr {
+				t.Errorf("validateFilenamePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
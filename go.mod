@@ -2,7 +2,11 @@ module github.com/relicta-tech/plugin-pypi
 
 go 1.22.7
 
-require github.com/relicta-tech/relicta-plugin-sdk v1.0.0
+require (
+	github.com/relicta-tech/relicta-plugin-sdk v1.0.0
+	google.golang.org/grpc v1.68.0
+	google.golang.org/protobuf v1.35.2
+)
 
 require (
 	github.com/fatih/color v1.7.0 // indirect
@@ -17,6 +21,4 @@ require (
 	golang.org/x/sys v0.25.0 // indirect
 	golang.org/x/text v0.18.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240903143218-8af14fe29dc1 // indirect
-	google.golang.org/grpc v1.68.0 // indirect
-	google.golang.org/protobuf v1.35.2 // indirect
 )
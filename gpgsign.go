@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// defaultGPGProgram signs distributions, as twine's --sign-with defaults to it.
+const defaultGPGProgram = "gpg"
+
+// gpgSignFlags are the twine options of the sign options, which extra_args must not repeat.
+var gpgSignFlags = []string{"--sign", "--sign-with", "--identity"}
+
+// gpgSignature is the detached GPG signature of an uploaded file, reported in outputs.
+type gpgSignature struct {
+	File      string `json:"file"`
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"`
+	Identity  string `json:"identity,omitempty"`
+}
+
+// signatureFilePath returns the ASCII-armored detached signature of a distribution, where twine
+// writes and looks for it.
+func signatureFilePath(file string) string {
+	return file + ".asc"
+}
+
+// signsNatively reports whether the plugin runs gpg_program itself. twine signs the files it
+// uploads with --sign, so only the built-in uploader needs the signatures beforehand.
+func signsNatively(cfg Config) bool {
+	return cfg.Sign && usesNativeUploader(cfg) && cfg.InjectFailure == ""
+}
+
+// gpgSignArgs returns the gpg_program arguments signing file as twine does, replacing the
+// signature of an earlier run.
+func gpgSignArgs(cfg Config, file string) []string {
+	args := []string{"--detach-sign", "--armor", "--yes"}
+	if cfg.SignIdentity != "" {
+		args = append(args, "--local-user", cfg.SignIdentity)
+	}
+	return append(args, "--output", signatureFilePath(file), file)
+}
+
+// gpgSignDistFiles signs each file with gpg_program for the built-in uploader, which sends the
+// <file>.asc signatures as gpg_signature. A failed signing uploads nothing.
+func (p *PyPIPlugin) gpgSignDistFiles(ctx context.Context, cfg Config, files []string) ([]gpgSignature, []plugin.Artifact, error) {
+	signatures := make([]gpgSignature, 0, len(files))
+	artifacts := make([]plugin.Artifact, 0, len(files))
+	for _, f := range files {
+		output, err := p.getExecutor().Run(ctx, cfg.GPGProgram, gpgSignArgs(cfg, f)...)
+		if err != nil {
+			detail, _ := truncateOutput(strings.TrimSpace(string(output)), defaultMaxErrorBodyBytes)
+			return nil, nil, fmt.Errorf("failed to sign %s with %s: %w: %s", filepath.Base(f), cfg.GPGProgram, err, detail)
+		}
+		sigPath := signatureFilePath(f)
+		_, digest, _, err := fileDigests(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, sigDigest, size, err := fileDigests(sigPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s wrote no signature for %s: %w", cfg.GPGProgram, filepath.Base(f), err)
+		}
+		if size == 0 {
+			return nil, nil, fmt.Errorf("%s wrote an empty signature for %s", cfg.GPGProgram, filepath.Base(f))
+		}
+		signatures = append(signatures, gpgSignature{
+			File:      filepath.Base(f),
+			SHA256:    digest,
+			Signature: filepath.ToSlash(sigPath),
+			Identity:  cfg.SignIdentity,
+		})
+		artifacts = append(artifacts, plugin.Artifact{
+			Name:     filepath.Base(sigPath),
+			Path:     sigPath,
+			Type:     "file",
+			Size:     size,
+			Checksum: "sha256:" + sigDigest,
+		})
+	}
+	return signatures, artifacts, nil
+}
+
+// validateGPGSignConfig validates the sign, sign_identity and gpg_program options.
+func validateGPGSignConfig(cfg Config) error {
+	if !cfg.Sign {
+		if cfg.SignIdentity != "" {
+			return fmt.Errorf("sign_identity requires sign")
+		}
+		return nil
+	}
+	if isPyPIRepository(cfg.Repository) {
+		return fmt.Errorf("sign is not supported by PyPI and TestPyPI, which no longer accept PGP signatures")
+	}
+	if len(cfg.CustomCommand) > 0 {
+		return fmt.Errorf("signatures cannot be uploaded by custom_command")
+	}
+	if cfg.GPGProgram == "" {
+		return fmt.Errorf("gpg_program must not be empty")
+	}
+	if strings.HasPrefix(cfg.SignIdentity, "-") {
+		return fmt.Errorf("sign_identity must be a key ID, fingerprint or user ID, not an option")
+	}
+	for _, arg := range cfg.ExtraArgs {
+		name, _, _ := strings.Cut(arg, "=")
+		for _, flag := range gpgSignFlags {
+			if strings.HasPrefix(arg, "--") && strings.HasPrefix(flag, name) {
+				return fmt.Errorf("extra_args must not set %s with sign; use sign_identity and gpg_program", flag)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// gpgExecutor writes an armored signature to the --output of each gpg invocation.
+func gpgExecutor(t *testing.T) *MockCommandExecutor {
+	return &MockCommandExecutor{RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		for i, arg := range args {
+			if arg == "--output" {
+				if err := os.WriteFile(args[i+1], []byte("-----BEGIN PGP SIGNATURE-----\nc2ln\n-----END PGP SIGNATURE-----\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		return nil, nil
+	}}
+}
+
+func TestExecuteGPGSignNative(t *testing.T) {
+	writeDistFiles(t)
+	wheel := filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl")
+	writeTestWheel(t, wheel, map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	// A signature left by an earlier run is not a distribution
+	if err := os.WriteFile(signatureFilePath(wheel), []byte("stale"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var signatures []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("invalid upload: %v", err)
+		}
+		f, header, err := r.FormFile("gpg_signature")
+		if err != nil {
+			t.Errorf("upload of %s has no gpg_signature: %v", r.FormValue("name"), err)
+			return
+		}
+		defer func() { _ = f.Close() }()
+		signatures = append(signatures, header.Filename)
+	}))
+	defer server.Close()
+
+	executor := gpgExecutor(t)
+	p := &PyPIPlugin{cmdExecutor: executor, httpClient: server.Client()}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":       "__token__",
+			"password":       "pypi-token",
+			"repository":     server.URL,
+			"upload_backend": "native",
+			"sign":           true,
+			"sign_identity":  "releases@example.com",
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+
+	want := "--detach-sign --armor --yes --local-user releases@example.com --output " + signatureFilePath(wheel) + " " + wheel
+	if len(executor.RunCalls) != 1 || executor.RunCalls[0].Name != "gpg" || strings.Join(executor.RunCalls[0].Args, " ") != want {
+		t.Errorf("unexpected signing commands %+v", executor.RunCalls)
+	}
+	if len(signatures) != 1 || signatures[0] != "mypkg-1.0.0-py3-none-any.whl.asc" {
+		t.Errorf("unexpected uploaded signatures %v", signatures)
+	}
+	if sigs, _ := resp.Outputs["gpg_signatures"].([]gpgSignature); len(sigs) != 1 || sigs[0].Identity != "releases@example.com" {
+		t.Errorf("unexpected gpg_signatures %v", resp.Outputs["gpg_signatures"])
+	}
+	if len(resp.Artifacts) != 1 || resp.Artifacts[0].Name != "mypkg-1.0.0-py3-none-any.whl.asc" {
+		t.Errorf("unexpected artifacts %+v", resp.Artifacts)
+	}
+}
+
+func TestGPGSignDistFilesFailure(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{ReturnOut: []byte("gpg: no default secret key"), ReturnError: errors.New("exit status 2")}}
+	cfg := Config{Sign: true, GPGProgram: defaultGPGProgram}
+	if _, _, err := p.gpgSignDistFiles(context.Background(), cfg, []string{filepath.Join("dist", "mypkg-1.0.0.tar.gz")}); err == nil || !strings.Contains(err.Error(), "no default secret key") {
+		t.Errorf("expected the gpg failure, got %v", err)
+	}
+}
+
+func TestGPGSignConfig(t *testing.T) {
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{"sign": true, "sign_identity": " ABCD1234 ", "gpg_program": "gpg2", "repository": "https://pypi.example.com/legacy/"})
+	if !cfg.Sign || cfg.SignIdentity != "ABCD1234" || cfg.GPGProgram != "gpg2" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if err := validateGPGSignConfig(cfg); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if args := strings.Join(p.buildTwineArgsForFiles(cfg, []string{"dist/*"}), " "); !strings.Contains(args, "--sign --sign-with gpg2 --identity ABCD1234 dist/*") {
+		t.Errorf("unexpected twine arguments %s", args)
+	}
+	if cfg := p.parseConfig(map[string]any{}); cfg.GPGProgram != "gpg" || strings.Contains(strings.Join(p.buildTwineArgs(cfg), " "), "--sign") {
+		t.Errorf("expected no signing by default, got %+v", cfg)
+	}
+
+	base := Config{Sign: true, GPGProgram: defaultGPGProgram, Repository: "https://pypi.example.com/legacy/"}
+	for name, mutate := range map[string]func(*Config){
+		"pypi":             func(c *Config) { c.Repository = "https://upload.pypi.org/legacy/" },
+		"custom command":   func(c *Config) { c.CustomCommand = []string{"upload"} },
+		"empty program":    func(c *Config) { c.GPGProgram = "" },
+		"option identity":  func(c *Config) { c.SignIdentity = "--homedir=/tmp" },
+		"extra args":       func(c *Config) { c.ExtraArgs = []string{"--identity", "other"} },
+		"identity no sign": func(c *Config) { c.Sign, c.SignIdentity = false, "ABCD1234" },
+	} {
+		bad := base
+		mutate(&bad)
+		if err := validateGPGSignConfig(bad); err == nil {
+			t.Errorf("%s: expected %+v to be rejected", name, bad)
+		}
+	}
+}
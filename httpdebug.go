@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sensitiveHeaders carry credentials or session state and are redacted in HTTP debug events.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	artifactoryAPIKeyHeader,
+}
+
+// sensitiveHeaderWords mark custom headers that likely carry credentials, such as
+// X-Api-Key or Private-Token. Authentication challenges such as Proxy-Authenticate are kept,
+// as they name the schemes a proxy accepts.
+var sensitiveHeaderWords = []string{"token", "secret", "password", "key", "session"}
+
+// debugClient returns a copy of client whose requests, responses and proxy CONNECT exchanges
+// are logged as http events for the package configured by cfg. It returns client unchanged
+// without a log.
+func (l *publishLog) debugClient(client *http.Client, cfg Config) *http.Client {
+	if l == nil {
+		return client
+	}
+	inner := client.Transport
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	// Corporate proxies often reject the tunnel rather than the upload, which the transport
+	// only reports as a dial error
+	if base, ok := inner.(*http.Transport); ok {
+		transport := base.Clone()
+		transport.OnProxyConnectResponse = func(_ context.Context, proxyURL *url.URL, connect *http.Request, resp *http.Response) error {
+			l.event(cfg, "http_proxy_connect", map[string]any{
+				"proxy":            redactURL(proxyURL),
+				"target":           connect.Host,
+				"request_headers":  l.redactHeaders(connect.Header, cfg),
+				"status":           statusLine(resp),
+				"response_headers": l.redactHeaders(resp.Header, cfg),
+			})
+			return nil
+		}
+		inner = transport
+	}
+
+	debug := *client
+	debug.Transport = &debugTransport{inner: inner, log: l, cfg: cfg}
+	return &debug
+}
+
+// debugTransport logs the sanitized status lines and headers of every exchange.
+type debugTransport struct {
+	inner http.RoundTripper
+	log   *publishLog
+	cfg   Config
+}
+
+// RoundTrip sends the request and logs the exchange. Bodies are not logged, as uploads carry
+// the distribution and error bodies are quoted in upload errors.
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.inner.RoundTrip(req)
+	fields := map[string]any{
+		"request":         fmt.Sprintf("%s %s %s", req.Method, redactURL(req.URL), req.Proto),
+		"request_headers": t.log.redactHeaders(req.Header, t.cfg),
+		"duration_ms":     time.Since(start).Milliseconds(),
+	}
+	if req.ContentLength > 0 {
+		fields["request_bytes"] = req.ContentLength
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	} else {
+		fields["status"] = statusLine(resp)
+		fields["response_headers"] = t.log.redactHeaders(resp.Header, t.cfg)
+	}
+	t.log.event(t.cfg, "http", fields)
+	return resp, err
+}
+
+// redactHeaders returns the headers with credentials replaced. The scheme of authorization
+// headers is kept, as proxies often reject one scheme and accept another.
+func (l *publishLog) redactHeaders(header http.Header, cfg Config) map[string]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if isSensitiveHeader(name, cfg) {
+			scheme, _, ok := strings.Cut(value, " ")
+			value = redactedValue
+			if ok && strings.HasSuffix(strings.ToLower(name), "authorization") {
+				value = scheme + " " + redactedValue
+			}
+		}
+		out[name] = l.redact(value)
+	}
+	return out
+}
+
+// isSensitiveHeader reports whether a header carries credentials.
+func isSensitiveHeader(name string, cfg Config) bool {
+	if cfg.AuthHeader != "" && strings.EqualFold(name, cfg.AuthHeader) {
+		return true
+	}
+	for _, h := range sensitiveHeaders {
+		if strings.EqualFold(name, h) {
+			return true
+		}
+	}
+	lower := strings.ToLower(name)
+	for _, word := range sensitiveHeaderWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL returns the URL with its password, as url.URL.Redacted does, and query values
+// replaced.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := *u
+	if redacted.RawQuery != "" {
+		query := redacted.Query()
+		keys := make([]string, 0, len(query))
+		for k := range query {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = url.QueryEscape(k) + "=" + redactedValue
+		}
+		redacted.RawQuery = strings.Join(parts, "&")
+	}
+	return redacted.Redacted()
+}
+
+// statusLine returns the status line of a response, such as HTTP/1.1 407 Proxy
+// Authentication Required.
+func statusLine(resp *http.Response) string {
+	return resp.Proto + " " + resp.Status
+}
+
+// validateDebugHTTPConfig validates the debug_http option.
+func validateDebugHTTPConfig(cfg Config) error {
+	if cfg.DebugHTTP && cfg.LogFile == "" {
+		return fmt.Errorf("debug_http writes to the publish log; set log_file")
+	}
+	return nil
+}
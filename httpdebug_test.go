@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// readLogEvents returns the events of a publish log with the given kind.
+func readLogEvents(t *testing.T, path, kind string) []map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path) // #nosec G304 -- test file
+	if err != nil {
+		t.Fatalf("log file not written: %v", err)
+	}
+	var events []map[string]any
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		var event map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid log line %q: %v", scanner.Text(), err)
+		}
+		if event["event"] == kind {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestExecuteDebugHTTP(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "cookie-value"})
+		w.Header().Set("Via", "1.1 corporate-proxy")
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{}, httpClient: server.Client()}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"password":    "secret-upload-token",
+			"repository":  server.URL + "/upload/?api_key=query-secret",
+			"auth_scheme": "bearer",
+			"log_file":    "publish.log",
+			"debug_http":  true,
+		},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the upload to succeed, got %+v, %v", resp, err)
+	}
+
+	data, _ := os.ReadFile("publish.log")
+	for _, secret := range []string{"secret-upload-token", "cookie-value"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("the log must not contain %s", secret)
+		}
+	}
+	events := readLogEvents(t, "publish.log", "http")
+	if len(events) != 1 {
+		t.Fatalf("expected one http event, got %v", events)
+	}
+	event := events[0]
+	if event["request"] != "POST "+server.URL+"/upload/?api_key=*** HTTP/1.1" || event["status"] != "HTTP/1.1 200 OK" {
+		t.Errorf("unexpected request and status lines %v %v", event["request"], event["status"])
+	}
+	if headers, _ := event["request_headers"].(map[string]any); headers["Authorization"] != "Bearer ***" {
+		t.Errorf("expected the scheme of the redacted token, got %v", event["request_headers"])
+	}
+	if headers, _ := event["response_headers"].(map[string]any); headers["Set-Cookie"] != redactedValue || headers["Via"] != "1.1 corporate-proxy" {
+		t.Errorf("unexpected response headers %v", event["response_headers"])
+	}
+}
+
+func TestDebugClientProxyConnect(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Proxy-Authenticate", "NTLM")
+		w.WriteHeader(http.StatusProxyAuthRequired)
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("corp", "proxy-password")
+
+	writeDistFiles(t)
+	cfg := Config{LogFile: "publish.log", Repository: "https://upload.pypi.org/legacy/"}
+	log := newPublishLog(cfg)
+	client := log.debugClient(&http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}, cfg)
+	if _, err := client.Get("https://upload.pypi.org/legacy/"); err == nil {
+		t.Fatal("expected the proxy to reject the tunnel")
+	}
+
+	events := readLogEvents(t, cfg.LogFile, "http_proxy_connect")
+	if len(events) != 1 {
+		t.Fatalf("expected one CONNECT event, got %v", events)
+	}
+	event := events[0]
+	if event["status"] != "HTTP/1.1 407 Proxy Authentication Required" || event["target"] != "upload.pypi.org:443" {
+		t.Errorf("unexpected CONNECT event %v", event)
+	}
+	if strings.Contains(event["proxy"].(string), "proxy-password") {
+		t.Errorf("the proxy password must be redacted, got %v", event["proxy"])
+	}
+	if headers, _ := event["request_headers"].(map[string]any); headers["Proxy-Authorization"] != "Basic ***" {
+		t.Errorf("unexpected CONNECT headers %v", event["request_headers"])
+	}
+	if headers, _ := event["response_headers"].(map[string]any); headers["Proxy-Authenticate"] != "NTLM" {
+		t.Errorf("expected the proxy challenge, got %v", event["response_headers"])
+	}
+	if events := readLogEvents(t, cfg.LogFile, "http"); len(events) != 1 || events[0]["error"] == nil {
+		t.Errorf("expected the failed request, got %v", events)
+	}
+}
+
+func TestIsSensitiveHeader(t *testing.T) {
+	cfg := Config{AuthHeader: "X-Upload"}
+	for name, want := range map[string]bool{
+		"Authorization":      true,
+		"X-Upload":           true,
+		"Private-Token":      true,
+		"X-Api-Key":          true,
+		"Proxy-Authenticate": false,
+		"Content-Type":       false,
+	} {
+		if got := isSensitiveHeader(name, cfg); got != want {
+			t.Errorf("isSensitiveHeader(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if err := validateDebugHTTPConfig(Config{DebugHTTP: true}); err == nil {
+		t.Error("expected debug_http without log_file to be rejected")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// wheelImportNames returns the top-level importable module and package names of a wheel.
+// top_level.txt is used when the build backend wrote one; otherwise the names are derived
+// from the wheel's file layout.
+func wheelImportNames(wheelPath string) ([]string, error) {
+	zr, err := zip.OpenReader(wheelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wheel: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	names := map[string]bool{}
+	var topLevel []string
+	for _, f := range zr.File {
+		first, rest, nested := strings.Cut(f.Name, "/")
+
+		if strings.HasSuffix(first, ".dist-info") {
+			if rest == "top_level.txt" {
+				data, err := readZipMember(f, maxMetadataSize)
+				if err != nil {
+					return nil, err
+				}
+				topLevel = strings.Fields(string(data))
+			}
+			continue
+		}
+		if strings.HasSuffix(first, ".data") {
+			// purelib/platlib files install into site-packages like top-level entries
+			scheme, member, ok := strings.Cut(rest, "/")
+			if !ok || (scheme != "purelib" && scheme != "platlib") {
+				continue
+			}
+			first, _, nested = strings.Cut(member, "/")
+		}
+
+		if name, ok := importName(first, nested); ok {
+			names[name] = true
+		}
+	}
+
+	if len(topLevel) > 0 {
+		names = map[string]bool{}
+		for _, name := range topLevel {
+			// Entries may name subpackages (e.g. "google/protobuf"); only the import root matters
+			root, _, _ := strings.Cut(name, "/")
+			names[root] = true
+		}
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// importName returns the import name for a top-level wheel entry, if it is importable.
+// Directories are packages (regular or namespace); files must be modules or extensions.
+func importName(entry string, isDir bool) (string, bool) {
+	if entry == "" || entry == "__pycache__" || strings.HasSuffix(entry, ".libs") {
+		return "", false
+	}
+
+	name := entry
+	if !isDir {
+		ext := path.Ext(entry)
+		switch {
+		case ext == ".py" || ext == ".pyc":
+			name = strings.TrimSuffix(entry, ext)
+		case ext == ".so" || ext == ".pyd":
+			// Extension modules carry an ABI tag: _speedups.cpython-311-x86_64-linux-gnu.so
+			name, _, _ = strings.Cut(entry, ".")
+		default:
+			return "", false
+		}
+	}
+
+	if !isPythonIdentifier(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// isPythonIdentifier reports whether s is a valid ASCII Python identifier.
+func isPythonIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestWheelImportNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		members map[string]string
+		want    []string
+	}{
+		{
+			name: "layout",
+			members: map[string]string{
+				"mypkg/__init__.py":     "",
+				"mypkg/sub/__init__.py": "",
+				"nspkg/plugin.py":       "",
+				"helper.py":             "",
+				"_speedups.cpython-311-x86_64-linux-gnu.so": "",
+				"mypkg.libs/libz.so.1":                      "",
+				"README.md":                                 "",
+				"my_pkg-1.0.dist-info/METADATA":             "Name: my-pkg\nVersion: 1.0\n",
+				"my_pkg-1.0.data/purelib/extra_mod.py":      "",
+				"my_pkg-1.0.data/scripts/tool":              "",
+				"__pycache__/helper.cpython-311.pyc":        "",
+				"not-importable/__init__.py":                "",
+			},
+			want: []string{"_speedups", "extra_mod", "helper", "mypkg", "nspkg"},
+		},
+		{
+			name: "top_level.txt",
+			members: map[string]string{
+				"google/protobuf/__init__.py":          "",
+				"protobuf-4.0.dist-info/top_level.txt": "google\ngoogle/protobuf\n",
+			},
+			want: []string{"google"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wheel := filepath.Join(t.TempDir(), "my_pkg-1.0-py3-none-any.whl")
+			writeTestWheel(t, wheel, tt.members)
+
+			got, err := wheelImportNames(wheel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wheelImportNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteReportsImportNames(t *testing.T) {
+	distPath := writeDistFiles(t, "my_pkg-1.0.tar.gz")
+	writeTestWheel(t, filepath.Join("dist", "my_pkg-1.0-py3-none-any.whl"), map[string]string{
+		"mypkg/__init__.py": "",
+	})
+	writeTestWheel(t, filepath.Join("dist", "my_pkg-1.0-cp311-cp311-manylinux_2_17_x86_64.whl"), map[string]string{
+		"mypkg/__init__.py":     "",
+		"_mypkg_native.abi3.so": "",
+	})
+
+	p := &PyPIPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":   "__token__",
+			"password":   "pypi-token",
+			"repository": "http://localhost:8080/",
+			"dist_path":  distPath,
+		},
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"_mypkg_native", "mypkg"}
+	if got := resp.Outputs["import_names"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("import_names = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Index polling defaults.
+const (
+	// maxIndexPageSize bounds how much of a simple index page is read.
+	maxIndexPageSize              = 32 << 20 // 32 MiB
+	defaultDependencyWaitTimeout  = 10 * time.Minute
+	defaultDependencyPollInterval = 10 * time.Second
+)
+
+// knownIndexURLs maps upload endpoints to the simple index serving their files.
+var knownIndexURLs = map[string]string{
+	"https://upload.pypi.org/legacy/": "https://pypi.org/simple/",
+	"https://test.pypi.org/legacy/":   "https://test.pypi.org/simple/",
+}
+
+// defaultIndexURL returns the simple index for a well-known upload repository, or "".
+func defaultIndexURL(repository string) string {
+	if !strings.HasSuffix(repository, "/") {
+		repository += "/"
+	}
+	return knownIndexURLs[repository]
+}
+
+// releasePageURL returns the release page of the project on PyPI or TestPyPI, or "" for other
+// repositories.
+func releasePageURL(repository, project, version string) string {
+	if project == "" || !isPyPIRepository(repository) {
+		return ""
+	}
+	host := "pypi.org"
+	if u, err := url.Parse(repository); err == nil && strings.EqualFold(u.Hostname(), "test.pypi.org") {
+		host = "test.pypi.org"
+	}
+	return fmt.Sprintf("https://%s/project/%s/%s/", host, normalizeProjectName(project), version)
+}
+
+// projectPageURL returns the simple index page of a project (PEP 503).
+func projectPageURL(indexURL, project string) (string, error) {
+	base, err := url.Parse(indexURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid index URL: %w", err)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	return base.JoinPath(normalizeProjectName(project) + "/").String(), nil
+}
+
+// indexHasFiles reports whether the project page of the index lists every file name.
+// A project that does not exist yet is reported as missing files, not as an error.
+func (p *PyPIPlugin) indexHasFiles(ctx context.Context, indexURL, project string, files []string) (bool, error) {
+	listed, err := p.indexFiles(ctx, indexURL, project)
+	if err != nil {
+		return false, err
+	}
+	for _, f := range files {
+		if _, ok := listed[filepath.Base(f)]; !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// indexFileDigests returns the SHA-256 digests the index reports for the project's files,
+// keyed by file name. Both the JSON (PEP 691) and HTML (PEP 503) simple APIs are understood.
+func (p *PyPIPlugin) indexFileDigests(ctx context.Context, indexURL, project string) (map[string]string, error) {
+	files, err := p.indexFiles(ctx, indexURL, project)
+	if err != nil {
+		return nil, err
+	}
+	for name, digest := range files {
+		if digest == "" {
+			delete(files, name)
+		}
+	}
+	return files, nil
+}
+
+// indexFiles returns the project's files listed by the index, mapped to their SHA-256 digest
+// or "" when the index reports none. Indexes serving only the JSON (PEP 691) or only the HTML
+// (PEP 503) simple API are both understood.
+func (p *PyPIPlugin) indexFiles(ctx context.Context, indexURL, project string) (map[string]string, error) {
+	page, contentType, err := p.fetchProjectPage(ctx, indexURL, project)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	if page == nil {
+		return files, nil
+	}
+
+	listing, err := parseSimplePage(page, contentType)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range listing {
+		files[f.Filename] = f.SHA256
+	}
+	return files, nil
+}
+
+// fetchProjectPage returns the project page of the index and its content type. A project
+// that does not exist yet yields a nil page and no error.
+func (p *PyPIPlugin) fetchProjectPage(ctx context.Context, indexURL, project string) ([]byte, string, error) {
+	pageURL, err := projectPageURL(indexURL, project)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create index request: %w", err)
+	}
+	req.Header.Set("Accept", simpleAcceptHeader)
+	// Bypass CDN caches that would hide freshly uploaded files
+	req.Header.Set("Cache-Control", "max-age=0")
+
+	resp, err := p.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("index request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("index request for %s failed: %s", project, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIndexPageSize))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read index page: %w", err)
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// waitForIndexFiles polls the index until it lists every file, the timeout elapses,
+// or the context is cancelled. Transient index errors are retried until the timeout.
+func (p *PyPIPlugin) waitForIndexFiles(ctx context.Context, indexURL, project string, files []string, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for {
+		ok, err := p.indexHasFiles(ctx, indexURL, project, files)
+		if ok {
+			return nil
+		}
+		lastErr = err
+
+		if time.Now().Add(interval).After(deadline) {
+			if lastErr != nil {
+				return fmt.Errorf("%s not available on %s after %s: %w", project, indexURL, timeout, lastErr)
+			}
+			return fmt.Errorf("%s not available on %s after %s", project, indexURL, timeout)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
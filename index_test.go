@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDefaultIndexURL(t *testing.T) {
+	tests := map[string]string{
+		"https://upload.pypi.org/legacy/": "https://pypi.org/simple/",
+		"https://test.pypi.org/legacy":    "https://test.pypi.org/simple/",
+		"https://pypi.example.com/":       "",
+	}
+	for repository, want := range tests {
+		if got := defaultIndexURL(repository); got != want {
+			t.Errorf("defaultIndexURL(%q) = %q, want %q", repository, got, want)
+		}
+	}
+}
+
+func TestProjectPageURL(t *testing.T) {
+	got, err := projectPageURL("https://pypi.example.com/simple", "My_Package")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "https://pypi.example.com/simple/my-package/" {
+		t.Errorf("unexpected page URL: %s", got)
+	}
+}
+
+func TestIndexHasFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/simple/core/" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<a href="core-1.0.tar.gz">core-1.0.tar.gz</a>`))
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	ctx := context.Background()
+	tests := []struct {
+		project string
+		files   []string
+		want    bool
+	}{
+		{"core", []string{"core-1.0.tar.gz"}, true},
+		{"core", []string{"core-1.0.tar.gz", "core-1.0-py3-none-any.whl"}, false},
+		{"missing", []string{"missing-1.0.tar.gz"}, false},
+	}
+	for _, tt := range tests {
+		got, err := p.indexHasFiles(ctx, server.URL+"/simple/", tt.project, tt.files)
+		if err != nil || got != tt.want {
+			t.Errorf("indexHasFiles(%s, %v) = %v, %v; want %v", tt.project, tt.files, got, err, tt.want)
+		}
+	}
+}
+
+func TestWaitForIndexFiles(t *testing.T) {
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&polls, 1) < 3 {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<a href="core-1.0.tar.gz">core-1.0.tar.gz</a>`))
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	err := p.waitForIndexFiles(context.Background(), server.URL, "core", []string{"core-1.0.tar.gz"}, time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if polls != 3 {
+		t.Errorf("expected 3 polls, got %d", polls)
+	}
+
+	err = p.waitForIndexFiles(context.Background(), server.URL, "core", []string{"core-2.0.tar.gz"}, 5*time.Millisecond, time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestIndexFileDigests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html/core/":
+			_, _ = w.Write([]byte(`<a href="../../files/core-1.0.tar.gz#sha256=ABC123">core-1.0.tar.gz</a>` +
+				`<a href="core-1.0-py3-none-any.whl#md5=ffff">core-1.0-py3-none-any.whl</a>`))
+		case "/json/core/":
+			w.Header().Set("Content-Type", simpleJSONContentType)
+			_, _ = w.Write([]byte(`{"files": [{"filename": "core-1.0.tar.gz", "hashes": {"sha256": "abc123"}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	for _, index := range []string{"/html/", "/json/"} {
+		digests, err := p.indexFileDigests(context.Background(), server.URL+index, "core")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", index, err)
+		}
+		if len(digests) != 1 || digests["core-1.0.tar.gz"] != "abc123" {
+			t.Errorf("%s: unexpected digests: %v", index, digests)
+		}
+	}
+
+	digests, err := p.indexFileDigests(context.Background(), server.URL+"/missing/", "core")
+	if err != nil || len(digests) != 0 {
+		t.Errorf("expected no digests for a missing project, got %v, %v", digests, err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxIntegrationResponseSize caps the responses read from integration APIs.
+const maxIntegrationResponseSize = 1 << 20
+
+// sendJSON sends payload as JSON to an integration API and decodes the response into result
+// when it is not nil. A nil payload sends no body. Errors are prefixed with service.
+func (p *PyPIPlugin) sendJSON(ctx context.Context, service, method, target string, headers map[string]string, payload, result any) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", service, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.getHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", service, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxIntegrationResponseSize))
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", service, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := truncateOutput(strings.TrimSpace(string(respBody)), defaultMaxErrorBodyBytes)
+		return fmt.Errorf("%s request failed: %s: %s", service, resp.Status, detail)
+	}
+	if result == nil || len(bytes.TrimSpace(respBody)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("invalid %s response: %w", service, err)
+	}
+	return nil
+}
+
+// postGraphQL runs a GraphQL query and decodes its data into result. GraphQL APIs report errors
+// in the body of a 200 response, which are returned as errors.
+func (p *PyPIPlugin) postGraphQL(ctx context.Context, service, target string, headers map[string]string, query string, variables map[string]any, result any) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	payload := map[string]any{"query": query, "variables": variables}
+	if err := p.sendJSON(ctx, service, http.MethodPost, target, headers, payload, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("%s request failed: %s", service, resp.Errors[0].Message)
+	}
+	if result == nil || len(resp.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Data, result); err != nil {
+		return fmt.Errorf("invalid %s response: %w", service, err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// interruptedUpload is the error of an upload stopped by Ctrl-C. remaining are the files not
+// known to be uploaded, including the one in flight when the upload was aborted.
+type interruptedUpload struct {
+	uploaded  int
+	remaining []string
+	aborted   bool
+}
+
+// Error implements error.
+func (e *interruptedUpload) Error() string {
+	how := "interrupted"
+	if e.aborted {
+		how = "aborted"
+	}
+	return fmt.Sprintf("upload %s after %d of %d file(s)", how, e.uploaded, e.uploaded+len(e.remaining))
+}
+
+// runningInCI reports whether the plugin runs in a CI job, where nobody is at the terminal.
+func runningInCI() bool {
+	ci := os.Getenv("CI")
+	return ci != "" && ci != "false"
+}
+
+// isInteractiveSession reports whether a maintainer runs the release from a terminal, who can
+// press Ctrl-C during the upload.
+func isInteractiveSession() bool {
+	if runningInCI() {
+		return false
+	}
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// The null device is a character device too; go test and service managers attach it
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	return true
+}
+
+// interruptGuard turns Ctrl-C during an interactive upload into a clean stop: the first
+// interrupt lets the file in flight finish and skips the rest, a second one cancels the upload.
+type interruptGuard struct {
+	mu          sync.Mutex
+	interrupted bool
+	aborted     bool
+	signals     chan os.Signal
+	done        chan struct{}
+}
+
+// watchInterrupts starts handling Ctrl-C for an upload that cancel aborts. It returns nil
+// outside interactive sessions, where the release tool decides how to stop.
+func (p *PyPIPlugin) watchInterrupts(cancel context.CancelFunc) *interruptGuard {
+	signals := p.interrupts
+	if signals == nil {
+		if !isInteractiveSession() {
+			return nil
+		}
+		signals = make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt)
+	}
+
+	g := &interruptGuard{signals: signals, done: make(chan struct{})}
+	out := p.getPromptOutput()
+	go func() {
+		for {
+			select {
+			case <-g.done:
+				return
+			case <-signals:
+			}
+			g.mu.Lock()
+			first := !g.interrupted
+			g.interrupted = true
+			g.aborted = !first
+			g.mu.Unlock()
+			if first {
+				_, _ = fmt.Fprintln(out, "Interrupted: finishing the file being uploaded, then stopping. Press Ctrl-C again to abort it.")
+				continue
+			}
+			_, _ = fmt.Fprintln(out, "Aborting the upload.")
+			cancel()
+		}
+	}()
+	return g
+}
+
+// stop ends the handling of Ctrl-C.
+func (g *interruptGuard) stop() {
+	if g == nil {
+		return
+	}
+	if g.signals != nil {
+		signal.Stop(g.signals)
+	}
+	close(g.done)
+}
+
+// state reports whether Ctrl-C was pressed once or twice. A nil guard was never interrupted.
+func (g *interruptGuard) state() (interrupted, aborted bool) {
+	if g == nil {
+		return false, false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.interrupted, g.aborted
+}
+
+// uploadEach uploads files one at a time with upload, so an interrupt can stop between files.
+// With continueOnError a failed file does not stop the upload: the remaining files are
+// uploaded and the failures returned together as a *partialFailure. The runs of the files are
+// merged into one, with the failed files in failed. A nil guard uploads without handling
+// interrupts.
+func (g *interruptGuard) uploadEach(ctx context.Context, files []string, continueOnError bool, upload func(files []string) (uploadRun, error)) (run uploadRun, err error) {
+	var output strings.Builder
+	var errs []error
+	defer func() {
+		run.output = output.String()
+		if err == nil && len(run.failed) > 0 {
+			err = &partialFailure{failed: run.failed, errs: errs, total: len(files)}
+		}
+	}()
+
+	for i, file := range files {
+		if interrupted, _ := g.state(); interrupted {
+			return run, &interruptedUpload{uploaded: i - len(run.failed), remaining: append(append([]string{}, run.failed...), files[i:]...)}
+		}
+		fileRun, fileErr := upload([]string{file})
+		output.WriteString(fileRun.output)
+		run.merge(fileRun)
+		if fileErr == nil {
+			continue
+		}
+		if _, aborted := g.state(); aborted {
+			return run, &interruptedUpload{uploaded: i - len(run.failed), remaining: append(append([]string{}, run.failed...), files[i:]...), aborted: true}
+		}
+		if !continueOnError {
+			return run, fileErr
+		}
+		run.failed = append(run.failed, file)
+		errs = append(errs, fileErr)
+		// Nothing more can be uploaded once the upload is cancelled or out of time
+		if ctx.Err() != nil {
+			return run, fileErr
+		}
+	}
+	return run, nil
+}
+
+// merge adds the timings, notices, token refreshes and credential groups of another run. The
+// output is left to the caller.
+func (r *uploadRun) merge(other uploadRun) {
+	r.tokenRefreshes += other.tokenRefreshes
+	r.notices = append(r.notices, other.notices...)
+	r.timings = append(r.timings, other.timings...)
+	for _, group := range other.groups {
+		merged := false
+		for i := range r.groups {
+			if r.groups[i].override == group.override {
+				r.groups[i].files = append(r.groups[i].files, group.files...)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			r.groups = append(r.groups, uploadGroup{override: group.override, files: append([]string{}, group.files...)})
+		}
+	}
+}
+
+// resumeInstructions tells the maintainer how to upload the files an interrupt left out:
+// from the queue entry saved for them, or by running the release again with skip_existing.
+func resumeInstructions(interrupted *interruptedUpload, entry string) string {
+	names := make([]string, len(interrupted.remaining))
+	for i, f := range interrupted.remaining {
+		names[i] = filepath.Base(f)
+	}
+	if entry != "" {
+		return fmt.Sprintf("%d file(s) not uploaded (%s) were saved in %s; upload them with resume_queued: true", len(names), strings.Join(names, ", "), entry)
+	}
+	return fmt.Sprintf("%d file(s) not uploaded (%s); run the release again with skip_existing: true to upload them", len(names), strings.Join(names, ", "))
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// interruptTest is a plugin whose twine uploads press Ctrl-C presses times during the first
+// file, waiting until the plugin reported each interrupt.
+func interruptTest(t *testing.T, presses int) (*PyPIPlugin, *MockCommandExecutor, *syncWriter) {
+	t.Helper()
+	prompts := &syncWriter{w: &bytes.Buffer{}}
+	printed := func() string {
+		prompts.mu.Lock()
+		defer prompts.mu.Unlock()
+		return prompts.w.(*bytes.Buffer).String()
+	}
+	p := &PyPIPlugin{promptOutput: prompts, interrupts: make(chan os.Signal, 1)}
+	calls := 0
+	executor := &MockCommandExecutor{RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls++
+		if calls == 1 {
+			for _, want := range []string{"Interrupted:", "Aborting"}[:presses] {
+				p.interrupts <- os.Interrupt
+				for deadline := time.Now().Add(5 * time.Second); !strings.Contains(printed(), want); time.Sleep(time.Millisecond) {
+					if time.Now().After(deadline) {
+						t.Fatalf("interrupt %q not reported", want)
+					}
+				}
+			}
+		}
+		if presses > 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []byte("Uploading " + args[len(args)-1] + "\n"), nil
+	}}
+	p.cmdExecutor = executor
+	return p, executor, prompts
+}
+
+func interruptConfig(extra map[string]any) map[string]any {
+	cfg := map[string]any{
+		"username":   "__token__",
+		"password":   "pypi-token",
+		"repository": "http://localhost:8080/",
+	}
+	for k, v := range extra {
+		cfg[k] = v
+	}
+	return cfg
+}
+
+func TestExecuteInterruptFinishesInFlightFile(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz", "mypkg-1.0.0-py3-none-any.whl")
+	p, executor, prompts := interruptTest(t, 1)
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:   plugin.HookPostPublish,
+		Config: interruptConfig(nil),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(executor.RunCalls) != 1 {
+		t.Errorf("expected the upload to stop after the file in flight, got %d twine runs", len(executor.RunCalls))
+	}
+	if resp.Success || !strings.Contains(resp.Error, "upload interrupted after 1 of 2 file(s)") || !strings.Contains(resp.Error, "skip_existing: true") {
+		t.Errorf("expected an interrupted upload with resume instructions, got %q", resp.Error)
+	}
+	if remaining, _ := resp.Outputs["remaining_files"].([]string); len(remaining) != 1 || resp.Outputs["interrupted"] != true {
+		t.Errorf("unexpected outputs %v", resp.Outputs)
+	}
+	if !strings.Contains(resp.Error, "Output: Uploading dist/") {
+		t.Errorf("expected the output of the uploaded file, got %q", resp.Error)
+	}
+	if out := prompts.w.(*bytes.Buffer).String(); !strings.Contains(out, "1 file(s) not uploaded") {
+		t.Errorf("expected resume instructions at the terminal, got %q", out)
+	}
+}
+
+func TestExecuteInterruptQueuesRemainingFiles(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz", "mypkg-1.0.0-py3-none-any.whl")
+	p, _, _ := interruptTest(t, 1)
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  interruptConfig(map[string]any{"queue_dir": "queue"}),
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(resp.Error, "resume_queued: true") || resp.Outputs["queue_entry"] == nil {
+		t.Fatalf("expected the remaining files to be queued, got %q %v", resp.Error, resp.Outputs)
+	}
+	entries, err := readQueue("queue")
+	if err != nil || len(entries) != 1 || len(entries[0].manifest.Files) != 1 {
+		t.Fatalf("expected one queue entry with the remaining file, got %+v %v", entries, err)
+	}
+}
+
+func TestExecuteInterruptTwiceAborts(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz", "mypkg-1.0.0-py3-none-any.whl")
+	p, _, _ := interruptTest(t, 2)
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:   plugin.HookPostPublish,
+		Config: interruptConfig(nil),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(resp.Error, "upload aborted after 0 of 2 file(s)") {
+		t.Errorf("expected an aborted upload, got %q", resp.Error)
+	}
+	// The aborted file may have reached the index, so it is left to resume
+	if remaining, _ := resp.Outputs["remaining_files"].([]string); len(remaining) != 2 {
+		t.Errorf("expected both files to remain, got %v", resp.Outputs["remaining_files"])
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Issue tracker providers.
+const (
+	trackerJira   = "jira"
+	trackerLinear = "linear"
+)
+
+// issueTrackerProviders lists the supported issue_trackers providers.
+var issueTrackerProviders = []string{trackerJira, trackerLinear}
+
+// Issue tracker defaults.
+const (
+	// defaultLinearEndpoint is the Linear GraphQL API
+	defaultLinearEndpoint = "https://api.linear.app/graphql"
+	// defaultReleasedStatus is the workflow status issues are moved to
+	defaultReleasedStatus = "Released"
+)
+
+// Issue keys such as ENG-123 are shared by Jira and Linear. A closing keyword may be followed
+// by several keys ("fixes ENG-1, ENG-2 and ENG-3").
+var (
+	issueKeyPattern       = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-[0-9]+\b`)
+	issueProjectPattern   = regexp.MustCompile(`^[A-Z][A-Z0-9]+$`)
+	issueReferencePattern = regexp.MustCompile(`\b(?i:fix(?:e[sd])?|close[sd]?|resolve[sd]?)\b:?\s+([A-Z][A-Z0-9]+-[0-9]+(?:\s*(?:,|&|and)\s*[A-Z][A-Z0-9]+-[0-9]+)*)`)
+)
+
+// Linear GraphQL operations.
+const (
+	linearIssueQuery = `query($id: String!) {
+  issue(id: $id) { id state { name } team { states { nodes { id name } } } }
+}`
+	linearIssueUpdateMutation = `mutation($id: String!, $stateId: String!) {
+  issueUpdate(id: $id, input: {stateId: $stateId}) { success }
+}`
+	linearCommentMutation = `mutation($issueId: String!, $body: String!) {
+  commentCreate(input: {issueId: $issueId, body: $body}) { success }
+}`
+)
+
+// IssueTracker moves the issues that released commits fix or close to a released status and
+// comments with the release after a successful publish.
+type IssueTracker struct {
+	// Provider is jira or linear.
+	Provider string
+	// Endpoint is the Jira site URL or the Linear GraphQL API.
+	Endpoint string
+	// Email is the Jira Cloud account of the API token; without it the key is sent as a bearer
+	// personal access token (Jira Data Center).
+	Email string
+	// APIKey authenticates the requests.
+	APIKey string
+	// APIKeyEnv names an environment variable holding the API key, used when APIKey is empty.
+	APIKeyEnv string
+	// Status is the workflow status issues are moved to (defaults to Released).
+	Status string
+	// Comment adds a comment with the release to each issue (defaults to true).
+	Comment bool
+	// Projects limits the tracker to issue keys of these projects or teams (e.g. ENG).
+	Projects []string
+}
+
+// resolvedAPIKey returns the configured API key or the value of APIKeyEnv.
+func (t IssueTracker) resolvedAPIKey() string {
+	if t.APIKey != "" {
+		return t.APIKey
+	}
+	if t.APIKeyEnv != "" {
+		return os.Getenv(t.APIKeyEnv)
+	}
+	return ""
+}
+
+// handles reports whether issue belongs to one of the tracker's projects.
+func (t IssueTracker) handles(issue string) bool {
+	if len(t.Projects) == 0 {
+		return true
+	}
+	project, _, _ := strings.Cut(issue, "-")
+	return containsString(t.Projects, project)
+}
+
+// issueTransitionResult is the outcome for one issue, reported in outputs.
+type issueTransitionResult struct {
+	Provider  string `json:"provider"`
+	Issue     string `json:"issue"`
+	Status    string `json:"status"`
+	Commented bool   `json:"commented"`
+	Error     string `json:"error,omitempty"`
+}
+
+// referencedIssues returns the issue keys that released commits fix, close or resolve, in
+// order of first reference.
+func referencedIssues(changes *plugin.CategorizedChanges) []string {
+	if changes == nil {
+		return nil
+	}
+	var issues []string
+	for _, group := range [][]plugin.ConventionalCommit{
+		changes.Breaking, changes.Features, changes.Fixes, changes.Performance,
+		changes.Refactor, changes.Docs, changes.Other,
+	} {
+		for _, c := range group {
+			for _, ref := range issueReferencePattern.FindAllStringSubmatch(c.Description+"\n"+c.Body, -1) {
+				for _, key := range issueKeyPattern.FindAllString(ref[1], -1) {
+					if !containsString(issues, key) {
+						issues = append(issues, key)
+					}
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// transitionIssues moves the referenced issues of each tracker to its released status and
+// comments with the release. Failures are reported per issue and never fail the publish.
+func (p *PyPIPlugin) transitionIssues(ctx context.Context, cfg Config, issues []string, event releaseEvent) []issueTransitionResult {
+	results := []issueTransitionResult{}
+	for _, tracker := range cfg.IssueTrackers {
+		for _, issue := range issues {
+			if !tracker.handles(issue) {
+				continue
+			}
+			result := issueTransitionResult{Provider: tracker.Provider, Issue: issue}
+			var err error
+			switch tracker.Provider {
+			case trackerJira:
+				err = p.transitionJiraIssue(ctx, tracker, issue, event, &result)
+			case trackerLinear:
+				err = p.transitionLinearIssue(ctx, tracker, issue, event, &result)
+			default:
+				err = fmt.Errorf("unsupported issue tracker %q", tracker.Provider)
+			}
+			if err != nil {
+				// A failed comment keeps the status of a completed transition
+				if result.Status == "" {
+					result.Status = "failed"
+				}
+				result.Error = err.Error()
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// transitionJiraIssue applies the Jira transition leading to the released status and comments
+// on the issue.
+func (p *PyPIPlugin) transitionJiraIssue(ctx context.Context, t IssueTracker, issue string, event releaseEvent, result *issueTransitionResult) error {
+	base := strings.TrimSuffix(t.Endpoint, "/") + "/rest/api/2/issue/" + issue
+	headers := map[string]string{"Authorization": "Bearer " + t.resolvedAPIKey()}
+	if t.Email != "" {
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(t.Email+":"+t.resolvedAPIKey()))
+	}
+
+	var current struct {
+		Fields struct {
+			Status struct {
+				Name string `json:"name"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	if err := p.sendJSON(ctx, trackerJira, http.MethodGet, base+"?fields=status", headers, nil, &current); err != nil {
+		return err
+	}
+	if strings.EqualFold(current.Fields.Status.Name, t.Status) {
+		result.Status = "unchanged"
+	} else {
+		var available struct {
+			Transitions []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+				To   struct {
+					Name string `json:"name"`
+				} `json:"to"`
+			} `json:"transitions"`
+		}
+		if err := p.sendJSON(ctx, trackerJira, http.MethodGet, base+"/transitions", headers, nil, &available); err != nil {
+			return err
+		}
+		id := ""
+		for _, tr := range available.Transitions {
+			if strings.EqualFold(tr.To.Name, t.Status) || strings.EqualFold(tr.Name, t.Status) {
+				id = tr.ID
+				break
+			}
+		}
+		if id == "" {
+			return fmt.Errorf("no transition from %s to %s", current.Fields.Status.Name, t.Status)
+		}
+		payload := map[string]any{"transition": map[string]string{"id": id}}
+		if err := p.sendJSON(ctx, trackerJira, http.MethodPost, base+"/transitions", headers, payload, nil); err != nil {
+			return err
+		}
+		result.Status = "transitioned"
+	}
+
+	if t.Comment {
+		if err := p.sendJSON(ctx, trackerJira, http.MethodPost, base+"/comment", headers, map[string]string{"body": event.text()}, nil); err != nil {
+			return err
+		}
+		result.Commented = true
+	}
+	return nil
+}
+
+// transitionLinearIssue moves a Linear issue to the team's released workflow state and
+// comments on it.
+func (p *PyPIPlugin) transitionLinearIssue(ctx context.Context, t IssueTracker, issue string, event releaseEvent, result *issueTransitionResult) error {
+	endpoint := t.Endpoint
+	if endpoint == "" {
+		endpoint = defaultLinearEndpoint
+	}
+	// Linear API keys are sent without a scheme
+	headers := map[string]string{"Authorization": t.resolvedAPIKey()}
+
+	var found struct {
+		Issue *struct {
+			ID    string `json:"id"`
+			State struct {
+				Name string `json:"name"`
+			} `json:"state"`
+			Team struct {
+				States struct {
+					Nodes []struct {
+						ID   string `json:"id"`
+						Name string `json:"name"`
+					} `json:"nodes"`
+				} `json:"states"`
+			} `json:"team"`
+		} `json:"issue"`
+	}
+	if err := p.postGraphQL(ctx, trackerLinear, endpoint, headers, linearIssueQuery, map[string]any{"id": issue}, &found); err != nil {
+		return err
+	}
+	if found.Issue == nil {
+		return fmt.Errorf("issue %s not found", issue)
+	}
+
+	if strings.EqualFold(found.Issue.State.Name, t.Status) {
+		result.Status = "unchanged"
+	} else {
+		stateID := ""
+		for _, s := range found.Issue.Team.States.Nodes {
+			if strings.EqualFold(s.Name, t.Status) {
+				stateID = s.ID
+				break
+			}
+		}
+		if stateID == "" {
+			return fmt.Errorf("team of %s has no %s state", issue, t.Status)
+		}
+		vars := map[string]any{"id": found.Issue.ID, "stateId": stateID}
+		if err := p.postGraphQL(ctx, trackerLinear, endpoint, headers, linearIssueUpdateMutation, vars, nil); err != nil {
+			return err
+		}
+		result.Status = "transitioned"
+	}
+
+	if t.Comment {
+		vars := map[string]any{"issueId": found.Issue.ID, "body": event.text()}
+		if err := p.postGraphQL(ctx, trackerLinear, endpoint, headers, linearCommentMutation, vars, nil); err != nil {
+			return err
+		}
+		result.Commented = true
+	}
+	return nil
+}
+
+// parseIssueTrackers parses the issue_trackers config list.
+func parseIssueTrackers(raw any) []IssueTracker {
+	items, ok := raw.([]any)
+	if !ok {
+		return nil
+	}
+
+	trackers := make([]IssueTracker, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		t := IssueTracker{Status: defaultReleasedStatus, Comment: true}
+		t.Provider, _ = m["provider"].(string)
+		t.Endpoint, _ = m["endpoint"].(string)
+		t.Email, _ = m["email"].(string)
+		t.APIKey, _ = m["api_key"].(string)
+		t.APIKeyEnv, _ = m["api_key_env"].(string)
+		if v, ok := m["status"].(string); ok && v != "" {
+			t.Status = v
+		}
+		if v, ok := m["comment"].(bool); ok {
+			t.Comment = v
+		}
+		if projects, ok := m["projects"].([]any); ok {
+			for _, project := range projects {
+				if s, ok := project.(string); ok {
+					t.Projects = append(t.Projects, s)
+				}
+			}
+		}
+		trackers = append(trackers, t)
+	}
+	return trackers
+}
+
+// validateIssueTrackers validates the issue_trackers entries.
+func validateIssueTrackers(trackers []IssueTracker) error {
+	for i, t := range trackers {
+		if !containsString(issueTrackerProviders, t.Provider) {
+			return fmt.Errorf("issue_trackers[%d]: provider must be one of: %s", i, strings.Join(issueTrackerProviders, ", "))
+		}
+		if t.Endpoint == "" && t.Provider == trackerJira {
+			return fmt.Errorf("issue_trackers[%d]: endpoint is required for jira", i)
+		}
+		if t.Endpoint != "" {
+			if err := validateRepositoryURL(t.Endpoint); err != nil {
+				return fmt.Errorf("issue_trackers[%d]: invalid endpoint: %w", i, err)
+			}
+		}
+		if t.APIKey == "" && t.APIKeyEnv == "" {
+			return fmt.Errorf("issue_trackers[%d]: api_key or api_key_env is required", i)
+		}
+		for _, project := range t.Projects {
+			if !issueProjectPattern.MatchString(project) {
+				return fmt.Errorf("issue_trackers[%d]: invalid project key %q", i, project)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestReferencedIssues(t *testing.T) {
+	changes := &plugin.CategorizedChanges{
+		Fixes: []plugin.ConventionalCommit{
+			{Description: "handle empty wheels, fixes ENG-12"},
+			{Description: "retry uploads", Body: "Closes: ENG-14, OPS-3 and ENG-15\nRelated to ENG-99"},
+		},
+		Features: []plugin.ConventionalCommit{
+			{Description: "add markers (resolves ENG-12)"},
+			{Description: "mention ENG-20 without a keyword"},
+			{Description: "prefix fixes", Body: "prefixes ENG-21"},
+		},
+	}
+	want := []string{"ENG-12", "ENG-14", "OPS-3", "ENG-15"}
+	if got := referencedIssues(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("referencedIssues = %v, want %v", got, want)
+	}
+	if got := referencedIssues(nil); got != nil {
+		t.Errorf("expected no issues without changes, got %v", got)
+	}
+}
+
+func TestTransitionJiraIssue(t *testing.T) {
+	var transitioned, comment string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "ci@example.com" || pass != "jira-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/issue/ENG-12":
+			_, _ = w.Write([]byte(`{"fields": {"status": {"name": "Done"}}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/issue/ENG-13":
+			_, _ = w.Write([]byte(`{"fields": {"status": {"name": "Released"}}}`))
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/transitions"):
+			_, _ = w.Write([]byte(`{"transitions": [{"id": "21", "name": "Reopen", "to": {"name": "Open"}}, {"id": "31", "name": "Ship", "to": {"name": "Released"}}]}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/transitions"):
+			var body struct {
+				Transition struct {
+					ID string `json:"id"`
+				} `json:"transition"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			transitioned = body.Transition.ID
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/comment"):
+			var body struct {
+				Body string `json:"body"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			comment = body.Body
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	cfg := Config{IssueTrackers: []IssueTracker{{
+		Provider: trackerJira, Endpoint: server.URL, Email: "ci@example.com", APIKey: "jira-token",
+		Status: defaultReleasedStatus, Comment: true, Projects: []string{"ENG"},
+	}}}
+	event := releaseEvent{Project: "mypkg", Version: "1.0.0", Repository: "https://upload.pypi.org/legacy/", Link: "https://pypi.org/project/mypkg/1.0.0/"}
+
+	results := p.transitionIssues(context.Background(), cfg, []string{"ENG-12", "ENG-13", "OPS-3", "ENG-404"}, event)
+	want := []issueTransitionResult{
+		{Provider: trackerJira, Issue: "ENG-12", Status: "transitioned", Commented: true},
+		{Provider: trackerJira, Issue: "ENG-13", Status: "unchanged", Commented: true},
+		{Provider: trackerJira, Issue: "ENG-404", Status: "failed", Error: "jira request failed: 404 Not Found: 404 page not found"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("transitionIssues = %+v, want %+v", results, want)
+	}
+	if transitioned != "31" {
+		t.Errorf("expected the transition to Released, got %q", transitioned)
+	}
+	if !strings.Contains(comment, "Released mypkg 1.0.0") || !strings.Contains(comment, "https://pypi.org/project/mypkg/1.0.0/") {
+		t.Errorf("unexpected comment %q", comment)
+	}
+}
+
+func TestTransitionLinearIssue(t *testing.T) {
+	var operations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_api_key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case strings.Contains(req.Query, "issueUpdate"):
+			operations = append(operations, "update:"+req.Variables["stateId"].(string))
+			_, _ = w.Write([]byte(`{"data": {"issueUpdate": {"success": true}}}`))
+		case strings.Contains(req.Query, "commentCreate"):
+			operations = append(operations, "comment")
+			_, _ = w.Write([]byte(`{"data": {"commentCreate": {"success": true}}}`))
+		case req.Variables["id"] == "ENG-12":
+			_, _ = w.Write([]byte(`{"data": {"issue": {"id": "uuid-12", "state": {"name": "Done"}, "team": {"states": {"nodes": [{"id": "s1", "name": "Done"}, {"id": "s2", "name": "Released"}]}}}}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data": null, "errors": [{"message": "Entity not found: Issue"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	cfg := Config{IssueTrackers: []IssueTracker{{Provider: trackerLinear, Endpoint: server.URL, APIKey: "lin_api_key", Status: defaultReleasedStatus, Comment: true}}}
+	results := p.transitionIssues(context.Background(), cfg, []string{"ENG-12", "ENG-404"}, releaseEvent{Version: "1.0.0"})
+
+	if len(results) != 2 || results[0].Status != "transitioned" || !results[0].Commented {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if results[1].Status != "failed" || !strings.Contains(results[1].Error, "Entity not found") {
+		t.Errorf("unexpected result for a missing issue %+v", results[1])
+	}
+	if !reflect.DeepEqual(operations, []string{"update:s2", "comment"}) {
+		t.Errorf("unexpected operations %v", operations)
+	}
+}
+
+func TestValidateIssueTrackers(t *testing.T) {
+	tests := []struct {
+		name     string
+		trackers []IssueTracker
+		wantErr  bool
+	}{
+		{"linear default endpoint", []IssueTracker{{Provider: trackerLinear, APIKeyEnv: "LINEAR_API_KEY"}}, false},
+		{"jira", []IssueTracker{{Provider: trackerJira, Endpoint: "http://localhost:8080", APIKey: "key", Projects: []string{"ENG"}}}, false},
+		{"unknown provider", []IssueTracker{{Provider: "github", APIKey: "key"}}, true},
+		{"jira without endpoint", []IssueTracker{{Provider: trackerJira, APIKey: "key"}}, true},
+		{"missing api key", []IssueTracker{{Provider: trackerLinear}}, true},
+		{"invalid project", []IssueTracker{{Provider: trackerLinear, APIKey: "key", Projects: []string{"eng"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateIssueTrackers(tt.trackers); (err != nil) != tt.wantErr {
+				t.Errorf("validateIssueTrackers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseIssueTrackers(t *testing.T) {
+	trackers := parseIssueTrackers([]any{
+		map[string]any{"provider": "linear", "api_key_env": "LINEAR_API_KEY"},
+		map[string]any{"provider": "jira", "status": "Shipped", "comment": false, "projects": []any{"ENG", "OPS"}},
+	})
+	want := []IssueTracker{
+		{Provider: trackerLinear, APIKeyEnv: "LINEAR_API_KEY", Status: defaultReleasedStatus, Comment: true},
+		{Provider: trackerJira, Status: "Shipped", Projects: []string{"ENG", "OPS"}},
+	}
+	if !reflect.DeepEqual(trackers, want) {
+		t.Errorf("parseIssueTrackers = %+v, want %+v", trackers, want)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// stepSummaryEnv names the Markdown file GitHub Actions renders as the job summary.
+const stepSummaryEnv = "GITHUB_STEP_SUMMARY"
+
+// writeJobSummary appends a Markdown record of the publish to the GitHub Actions job summary:
+// a heading with the outcome, a table of the files with their sizes, digests, links and
+// statuses, and the error of a failed publish. It does nothing outside GitHub Actions, when
+// job_summary is disabled, or for hooks without distribution files.
+func writeJobSummary(cfg Config, resp *plugin.ExecuteResponse, outcome uploadOutcome, version string, dryRun bool) error {
+	path := os.Getenv(stepSummaryEnv)
+	if !cfg.JobSummary || path == "" || len(outcome.files) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) // #nosec G304 -- file provided by the GitHub Actions runner
+	if err != nil {
+		return fmt.Errorf("failed to open the job summary: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	_, err = f.WriteString(jobSummary(cfg, resp, outcome, version, dryRun))
+	return err
+}
+
+// jobSummary renders the Markdown job summary of a publish.
+func jobSummary(cfg Config, resp *plugin.ExecuteResponse, outcome uploadOutcome, version string, dryRun bool) string {
+	project, metaVersion := distProjectVersion(outcome.files)
+	if project == "" {
+		project = strings.SplitN(filepath.Base(outcome.files[0]), "-", 2)[0]
+	}
+	if version == "" {
+		version = metaVersion
+	}
+	release := strings.TrimSpace(project + " " + version)
+	if page := releasePageURL(cfg.Repository, project, version); page != "" {
+		release = fmt.Sprintf("[%s](%s)", release, page)
+	}
+	repository := repositoryName(cfg.Repository)
+
+	var b strings.Builder
+	switch {
+	case dryRun:
+		fmt.Fprintf(&b, "### %s would be published to %s (dry run)\n\n", release, repository)
+	case resp.Success:
+		fmt.Fprintf(&b, "### %s published to %s\n\n", release, repository)
+	default:
+		fmt.Fprintf(&b, "### %s failed to publish to %s\n\n", release, repository)
+	}
+
+	b.WriteString("| File | Size | SHA-256 | Status |\n|------|-----:|---------|--------|\n")
+	for _, file := range fileOutputs(cfg, outcome) {
+		name := "`" + file.Name + "`"
+		if file.URL != "" {
+			name = fmt.Sprintf("[%s](%s)", name, file.URL)
+		}
+		fmt.Fprintf(&b, "| %s | %s | `%s` | %s |\n", name, formatBytes(file.Size), file.SHA256, file.Status)
+	}
+
+	if !resp.Success && resp.Error != "" {
+		// A fence longer than any backtick run of the error keeps it in the code block
+		fence := "```"
+		for strings.Contains(resp.Error, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&b, "\n<details><summary>Error</summary>\n\n%s\n%s\n%s\n\n</details>\n", fence, resp.Error, fence)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
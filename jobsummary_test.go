@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestExecuteWritesJobSummary(t *testing.T) {
+	writeDistFiles(t)
+	wheel := filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl")
+	writeTestWheel(t, wheel, map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	_, digest, _, _ := fileDigests(wheel)
+	summary := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv(stepSummaryEnv, summary)
+	if err := os.WriteFile(summary, []byte("## Build\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{ReturnOut: []byte("Uploading mypkg-1.0.0-py3-none-any.whl\n")}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"username": "__token__", "password": "pypi-token", "repository": "http://localhost:8080/", "skip_existing_check": true},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the upload to succeed, got %+v, %v", resp, err)
+	}
+
+	data, _ := os.ReadFile(summary)
+	text := string(data)
+	for _, want := range []string{
+		"## Build\n",
+		"### mypkg 1.0.0 published to ",
+		"| `mypkg-1.0.0-py3-none-any.whl` | ",
+		"| `" + digest + "` | uploaded |",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("job summary lacks %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestJobSummaryFailure(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	cfg := Config{Repository: "https://packages.example.com/upload/", JobSummary: true}
+	outcome := uploadOutcome{files: []string{filepath.Join("dist", "mypkg-1.0.0.tar.gz")}, attempted: true, failed: true, output: "Uploading mypkg-1.0.0.tar.gz"}
+	resp := &plugin.ExecuteResponse{Error: "twine upload failed: 403 Forbidden\n```quoted```"}
+
+	text := jobSummary(cfg, resp, outcome, "1.0.0", false)
+	for _, want := range []string{
+		"### mypkg 1.0.0 failed to publish to packages.example.com",
+		"| `mypkg-1.0.0.tar.gz` | 18 B | ",
+		"| failed |",
+		"````\ntwine upload failed: 403 Forbidden\n```quoted```\n````",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("job summary lacks %q:\n%s", want, text)
+		}
+	}
+
+	// PyPI releases link to their release page
+	cfg.Repository = "https://upload.pypi.org/legacy/"
+	if text := jobSummary(cfg, &plugin.ExecuteResponse{Success: true}, outcome, "1.0.0", true); !strings.HasPrefix(text, "### [mypkg 1.0.0](https://pypi.org/project/mypkg/1.0.0/) would be published to ") {
+		t.Errorf("unexpected dry run heading:\n%s", text)
+	}
+
+	// Outside GitHub Actions or when disabled nothing is written
+	summary := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv(stepSummaryEnv, summary)
+	cfg.JobSummary = false
+	if err := writeJobSummary(cfg, resp, outcome, "1.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(summary); !os.IsNotExist(err) {
+		t.Errorf("expected no job summary with job_summary disabled, got %v", err)
+	}
+}
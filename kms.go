@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Cloud KMS providers of manifest_kms_key_id, told apart by the form of the key ID.
+const (
+	kmsProviderAWS   = "aws"
+	kmsProviderGCP   = "gcp"
+	kmsProviderAzure = "azure"
+)
+
+// Cloud KMS defaults.
+const (
+	// defaultKMSAlgorithm is the KMS signing algorithm of ECC_NIST_P256 keys.
+	defaultKMSAlgorithm = "ECDSA_SHA_256"
+	// defaultGCPKMSEndpoint is the Cloud KMS API of Google Cloud
+	defaultGCPKMSEndpoint = "https://cloudkms.googleapis.com/"
+	// defaultGCPMetadataHost serves the access token of the service account of Google Cloud runners
+	defaultGCPMetadataHost = "metadata.google.internal"
+	// gcpMetadataTimeout bounds the metadata server request, which hangs off Google Cloud
+	gcpMetadataTimeout = 5 * time.Second
+	// defaultAzureAuthorityHost is the Microsoft Entra ID token endpoint host
+	defaultAzureAuthorityHost = "https://login.microsoftonline.com/"
+	azureKeyVaultScope        = "https://vault.azure.net/.default"
+	azureKeyVaultAPIVersion   = "7.4"
+	// azureTokenExchangeAudience is the audience of OIDC tokens federated with Entra ID
+	azureTokenExchangeAudience = "api://AzureADTokenExchange"
+	azureClientAssertionType   = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// kmsAlgorithms lists the supported manifest_kms_algorithm values.
+var kmsAlgorithms = []string{
+	"ECDSA_SHA_256", "ECDSA_SHA_384", "ECDSA_SHA_512",
+	"RSASSA_PKCS1_V1_5_SHA_256", "RSASSA_PKCS1_V1_5_SHA_384", "RSASSA_PKCS1_V1_5_SHA_512",
+	"RSASSA_PSS_SHA_256", "RSASSA_PSS_SHA_384", "RSASSA_PSS_SHA_512",
+}
+
+// azureKeyVaultAlgorithms maps manifest_kms_algorithm values to Key Vault (JWA) algorithms.
+var azureKeyVaultAlgorithms = map[string]string{
+	"ECDSA_SHA_256":             "ES256",
+	"ECDSA_SHA_384":             "ES384",
+	"ECDSA_SHA_512":             "ES512",
+	"RSASSA_PKCS1_V1_5_SHA_256": "RS256",
+	"RSASSA_PKCS1_V1_5_SHA_384": "RS384",
+	"RSASSA_PKCS1_V1_5_SHA_512": "RS512",
+	"RSASSA_PSS_SHA_256":        "PS256",
+	"RSASSA_PSS_SHA_384":        "PS384",
+	"RSASSA_PSS_SHA_512":        "PS512",
+}
+
+// gcpKeyVersionPattern matches the resource name of a Cloud KMS key version.
+var gcpKeyVersionPattern = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+/cryptoKeyVersions/[^/]+$`)
+
+// kmsAlgorithmHash returns the digest a KMS signing algorithm signs.
+func kmsAlgorithmHash(algorithm string) crypto.Hash {
+	switch {
+	case strings.HasSuffix(algorithm, "_384"):
+		return crypto.SHA384
+	case strings.HasSuffix(algorithm, "_512"):
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+// kmsProvider returns the cloud of a KMS key ID: Cloud KMS key version names start with
+// projects/, Key Vault keys are https URLs, and anything else is an AWS KMS key ID, alias or ARN.
+func kmsProvider(keyID string) string {
+	switch {
+	case strings.HasPrefix(keyID, "projects/"):
+		return kmsProviderGCP
+	case strings.HasPrefix(keyID, "https://"):
+		return kmsProviderAzure
+	default:
+		return kmsProviderAWS
+	}
+}
+
+// kmsRegion returns the region of the KMS key: the region of a key ARN, or aws_region.
+func kmsRegion(cfg Config) string {
+	if parts := strings.Split(cfg.ManifestKMSKeyID, ":"); len(parts) > 4 && parts[0] == "arn" && parts[3] != "" {
+		return parts[3]
+	}
+	return cfg.AWSRegion
+}
+
+// kmsEndpoint returns the KMS API endpoint of the key.
+func kmsEndpoint(cfg Config) string {
+	if cfg.ManifestKMSEndpoint != "" {
+		return cfg.ManifestKMSEndpoint
+	}
+	if kmsProvider(cfg.ManifestKMSKeyID) == kmsProviderGCP {
+		return defaultGCPKMSEndpoint
+	}
+	return "https://kms." + kmsRegion(cfg) + ".amazonaws.com/"
+}
+
+// signKMS signs data with the KMS key of manifest_kms_key_id, sending only its digest. It
+// returns the signature and the name of the key that signed.
+func (p *PyPIPlugin) signKMS(ctx context.Context, cfg Config, data []byte) ([]byte, string, error) {
+	h := kmsAlgorithmHash(cfg.ManifestKMSAlgorithm).New()
+	h.Write(data)
+	digest := h.Sum(nil)
+	switch kmsProvider(cfg.ManifestKMSKeyID) {
+	case kmsProviderGCP:
+		return p.signGCPKMS(ctx, cfg, digest)
+	case kmsProviderAzure:
+		return p.signAzureKeyVault(ctx, cfg, digest)
+	default:
+		return p.signAWSKMS(ctx, cfg, digest)
+	}
+}
+
+// signAWSKMS signs a digest with an AWS KMS key and the runner's AWS credentials.
+func (p *PyPIPlugin) signAWSKMS(ctx context.Context, cfg Config, digest []byte) ([]byte, string, error) {
+	body, err := json.Marshal(map[string]any{
+		"KeyId":            cfg.ManifestKMSKeyID,
+		"Message":          digest,
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": cfg.ManifestKMSAlgorithm,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	respBody, err := p.callAWSKMS(ctx, kmsEndpoint(cfg), kmsRegion(cfg), "TrentService.Sign", body)
+	if err != nil {
+		return nil, "", err
+	}
+	var result struct {
+		KeyID     string `json:"KeyId"`
+		Signature []byte `json:"Signature"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil || len(result.Signature) == 0 {
+		return nil, "", fmt.Errorf("invalid KMS response: %s", strings.TrimSpace(string(respBody)))
+	}
+	if result.KeyID == "" {
+		result.KeyID = cfg.ManifestKMSKeyID
+	}
+	return result.Signature, result.KeyID, nil
+}
+
+// callAWSKMS calls the AWS KMS action target at endpoint with the runner's AWS credentials and
+// returns the response body.
+func (p *PyPIPlugin) callAWSKMS(ctx context.Context, endpoint, region, target string, body []byte) ([]byte, error) {
+	creds, err := loadAWSCredentials()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create KMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	payloadHash := sha256.Sum256(body)
+	signSigV4(req, hex.EncodeToString(payloadHash[:]), creds, region, "kms", time.Now())
+
+	resp, err := p.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("KMS request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxIntegrationResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("KMS request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := truncateOutput(strings.TrimSpace(string(respBody)), defaultMaxErrorBodyBytes)
+		return nil, fmt.Errorf("KMS request failed: %s: %s", resp.Status, detail)
+	}
+	return respBody, nil
+}
+
+// signGCPKMS signs a digest with a Cloud KMS key version. The key version determines the
+// algorithm; manifest_kms_algorithm only selects the digest, which must match it.
+func (p *PyPIPlugin) signGCPKMS(ctx context.Context, cfg Config, digest []byte) ([]byte, string, error) {
+	token, err := p.gcpAccessToken(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	endpoint := kmsEndpoint(cfg)
+	if !strings.HasSuffix(endpoint, "/") {
+		endpoint += "/"
+	}
+	// The digest field is named after the hash, such as sha256
+	field := strings.ToLower(strings.ReplaceAll(kmsAlgorithmHash(cfg.ManifestKMSAlgorithm).String(), "-", ""))
+	payload := map[string]any{"digest": map[string][]byte{field: digest}}
+	var result struct {
+		Name      string `json:"name"`
+		Signature []byte `json:"signature"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + token}
+	if err := p.sendJSON(ctx, "cloud kms", http.MethodPost, endpoint+"v1/"+cfg.ManifestKMSKeyID+":asymmetricSign", headers, payload, &result); err != nil {
+		return nil, "", err
+	}
+	if len(result.Signature) == 0 {
+		return nil, "", fmt.Errorf("cloud kms returned no signature")
+	}
+	if result.Name == "" {
+		result.Name = cfg.ManifestKMSKeyID
+	}
+	return result.Signature, result.Name, nil
+}
+
+// gcpAccessToken returns a Google Cloud access token from GOOGLE_OAUTH_ACCESS_TOKEN or
+// CLOUDSDK_AUTH_ACCESS_TOKEN, falling back to the service account of a Google Cloud runner.
+func (p *PyPIPlugin) gcpAccessToken(ctx context.Context) (string, error) {
+	for _, env := range []string{"GOOGLE_OAUTH_ACCESS_TOKEN", "CLOUDSDK_AUTH_ACCESS_TOKEN"} {
+		if token := strings.TrimSpace(os.Getenv(env)); token != "" {
+			return token, nil
+		}
+	}
+	token, _, err := p.gcpMetadataToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("no Google Cloud credentials found (set GOOGLE_OAUTH_ACCESS_TOKEN or run with a service account on Google Cloud): %w", err)
+	}
+	return token, nil
+}
+
+// gcpMetadataToken returns the access token of the service account of a Google Cloud runner
+// and how long it remains valid.
+func (p *PyPIPlugin) gcpMetadataToken(ctx context.Context) (string, time.Duration, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = defaultGCPMetadataHost
+	}
+	ctx, cancel := context.WithTimeout(ctx, gcpMetadataTimeout)
+	defer cancel()
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	target := "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token"
+	headers := map[string]string{"Metadata-Flavor": "Google"}
+	if err := p.sendJSON(ctx, "gcp metadata", http.MethodGet, target, headers, nil, &token); err != nil {
+		return "", 0, err
+	}
+	if token.AccessToken == "" {
+		return "", 0, fmt.Errorf("gcp metadata request returned no token")
+	}
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, nil
+}
+
+// signAzureKeyVault signs a digest with an Azure Key Vault key. ECDSA signatures are returned
+// in the ASN.1 form the other providers and openssl use.
+func (p *PyPIPlugin) signAzureKeyVault(ctx context.Context, cfg Config, digest []byte) ([]byte, string, error) {
+	token, _, err := p.azureAccessToken(ctx, cfg, azureKeyVaultScope)
+	if err != nil {
+		return nil, "", err
+	}
+	alg := azureKeyVaultAlgorithms[cfg.ManifestKMSAlgorithm]
+	payload := map[string]string{"alg": alg, "value": base64.RawURLEncoding.EncodeToString(digest)}
+	var result struct {
+		KeyID string `json:"kid"`
+		Value string `json:"value"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + token}
+	target := strings.TrimSuffix(cfg.ManifestKMSKeyID, "/") + "/sign?api-version=" + azureKeyVaultAPIVersion
+	if err := p.sendJSON(ctx, "key vault", http.MethodPost, target, headers, payload, &result); err != nil {
+		return nil, "", err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(result.Value, "="))
+	if err != nil || len(sig) == 0 {
+		return nil, "", fmt.Errorf("invalid key vault response: no signature")
+	}
+	if strings.HasPrefix(alg, "ES") {
+		half := len(sig) / 2
+		if sig, err = asn1.Marshal(struct{ R, S *big.Int }{new(big.Int).SetBytes(sig[:half]), new(big.Int).SetBytes(sig[half:])}); err != nil {
+			return nil, "", err
+		}
+	}
+	if result.KeyID == "" {
+		result.KeyID = cfg.ManifestKMSKeyID
+	}
+	return sig, result.KeyID, nil
+}
+
+// azureAccessToken returns an access token of scope for the AZURE_CLIENT_ID application of
+// AZURE_TENANT_ID, and how long it remains valid. The application authenticates with
+// AZURE_CLIENT_SECRET, the workload identity token of AZURE_FEDERATED_TOKEN_FILE, or else the
+// ambient OIDC token of the CI run.
+func (p *PyPIPlugin) azureAccessToken(ctx context.Context, cfg Config, scope string) (string, time.Duration, error) {
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+		"client_id":  {os.Getenv("AZURE_CLIENT_ID")},
+		"scope":      {scope},
+	}
+	if secret := os.Getenv("AZURE_CLIENT_SECRET"); secret != "" {
+		form.Set("client_secret", secret)
+	} else {
+		var assertion string
+		if path := os.Getenv("AZURE_FEDERATED_TOKEN_FILE"); path != "" {
+			data, err := os.ReadFile(path) // #nosec G304 -- the workload identity token file
+			if err != nil {
+				return "", 0, fmt.Errorf("failed to read AZURE_FEDERATED_TOKEN_FILE: %w", err)
+			}
+			assertion = strings.TrimSpace(string(data))
+		} else {
+			var err error
+			if assertion, _, err = p.ambientOIDCToken(ctx, cfg, azureTokenExchangeAudience); err != nil {
+				return "", 0, fmt.Errorf("no Azure credentials found (set AZURE_CLIENT_SECRET or AZURE_FEDERATED_TOKEN_FILE): %w", err)
+			}
+		}
+		form.Set("client_assertion_type", azureClientAssertionType)
+		form.Set("client_assertion", assertion)
+	}
+
+	authority := os.Getenv("AZURE_AUTHORITY_HOST")
+	if authority == "" {
+		authority = defaultAzureAuthorityHost
+	}
+	if !strings.HasSuffix(authority, "/") {
+		authority += "/"
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := p.postOAuthForm(ctx, authority+url.PathEscape(os.Getenv("AZURE_TENANT_ID"))+"/oauth2/v2.0/token", form, &token); err != nil {
+		return "", 0, fmt.Errorf("azure token request failed: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", 0, fmt.Errorf("azure token request returned no token")
+	}
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, nil
+}
+
+// validateKMSConfig validates manifest_kms_key_id and the options of its provider.
+func validateKMSConfig(cfg Config) error {
+	if cfg.ManifestKMSKeyID == "" {
+		if cfg.ManifestKMSEndpoint != "" {
+			return fmt.Errorf("manifest_kms_endpoint requires manifest_kms_key_id")
+		}
+		return nil
+	}
+	if !containsString(kmsAlgorithms, cfg.ManifestKMSAlgorithm) {
+		return fmt.Errorf("manifest_kms_algorithm must be one of: %s", strings.Join(kmsAlgorithms, ", "))
+	}
+	if cfg.ManifestKMSEndpoint != "" {
+		u, err := url.Parse(cfg.ManifestKMSEndpoint)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("manifest_kms_endpoint must be an http(s) URL")
+		}
+	}
+
+	switch kmsProvider(cfg.ManifestKMSKeyID) {
+	case kmsProviderGCP:
+		if !gcpKeyVersionPattern.MatchString(cfg.ManifestKMSKeyID) {
+			return fmt.Errorf("manifest_kms_key_id must name a Cloud KMS key version (projects/.../cryptoKeys/<key>/cryptoKeyVersions/<version>)")
+		}
+		return nil
+	case kmsProviderAzure:
+		u, err := url.Parse(cfg.ManifestKMSKeyID)
+		if err != nil || u.Host == "" || len(strings.Split(strings.Trim(u.Path, "/"), "/")) != 3 || !strings.HasPrefix(u.Path, "/keys/") {
+			return fmt.Errorf("manifest_kms_key_id must be a Key Vault key URL with a version (https://<vault>.vault.azure.net/keys/<name>/<version>)")
+		}
+		if cfg.ManifestKMSEndpoint != "" {
+			return fmt.Errorf("manifest_kms_endpoint cannot be combined with a Key Vault key")
+		}
+		if os.Getenv("AZURE_TENANT_ID") == "" || os.Getenv("AZURE_CLIENT_ID") == "" {
+			return fmt.Errorf("a Key Vault manifest_kms_key_id requires AZURE_TENANT_ID and AZURE_CLIENT_ID")
+		}
+		return nil
+	default:
+		if kmsRegion(cfg) == "" {
+			return fmt.Errorf("manifest_kms_key_id requires a key ARN or aws_region (or set AWS_REGION)")
+		}
+		_, err := loadAWSCredentials()
+		return err
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSignKMS(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	data := []byte(`{"project": "mypkg"}`)
+	arn := "arn:aws:kms:eu-west-1:111122223333:key/1234abcd"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "TrentService.Sign" || !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request") {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		var req struct {
+			KeyID            string `json:"KeyId"`
+			Message          []byte `json:"Message"`
+			MessageType      string `json:"MessageType"`
+			SigningAlgorithm string `json:"SigningAlgorithm"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		digest := sha256.Sum256(data)
+		if req.KeyID != "alias/release" && req.KeyID != arn || req.MessageType != "DIGEST" || string(req.Message) != string(digest[:]) || req.SigningAlgorithm != "ECDSA_SHA_256" {
+			t.Errorf("unexpected request %s", body)
+		}
+		if req.KeyID == "alias/release" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"NotFoundException","message":"Alias is not found."}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"KeyId": arn, "Signature": []byte("signature"), "SigningAlgorithm": req.SigningAlgorithm})
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{}
+	cfg := Config{ManifestKMSKeyID: arn, ManifestKMSAlgorithm: defaultKMSAlgorithm, ManifestKMSEndpoint: server.URL}
+	raw, signature, err := p.signManifest(context.Background(), cfg, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "signature" || signature.KeyID != arn || signature.Algorithm != "ECDSA_SHA_256" || signature.Signature != base64.StdEncoding.EncodeToString(raw) {
+		t.Errorf("unexpected signature %+v", signature)
+	}
+
+	cfg.ManifestKMSKeyID, cfg.AWSRegion = "alias/release", "eu-west-1"
+	if _, _, err := p.signManifest(context.Background(), cfg, data); err == nil || !strings.Contains(err.Error(), "Alias is not found") {
+		t.Errorf("expected the KMS error, got %v", err)
+	}
+}
+
+func TestKMSProvider(t *testing.T) {
+	tests := map[string]string{
+		"arn:aws:kms:eu-west-1:111122223333:key/1234abcd": kmsProviderAWS,
+		"alias/release": kmsProviderAWS,
+		"projects/acme/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1": kmsProviderGCP,
+		"https://acme.vault.azure.net/keys/manifest/0123456789abcdef":                kmsProviderAzure,
+	}
+	for keyID, want := range tests {
+		if got := kmsProvider(keyID); got != want {
+			t.Errorf("kmsProvider(%q) = %q, want %q", keyID, got, want)
+		}
+	}
+}
+
+func TestSignGCPKMS(t *testing.T) {
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "ya29.token")
+	data := []byte(`{"project": "mypkg"}`)
+	name := "projects/acme/locations/global/keyRings/release/cryptoKeys/manifest/cryptoKeyVersions/1"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/"+name+":asymmetricSign" || r.Header.Get("Authorization") != "Bearer ya29.token" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		var req struct {
+			Digest struct {
+				SHA384 []byte `json:"sha384"`
+			} `json:"digest"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		digest := sha512.Sum384(data)
+		if string(req.Digest.SHA384) != string(digest[:]) {
+			t.Errorf("unexpected request %s", body)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": name, "signature": []byte("signature")})
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{}
+	cfg := Config{ManifestKMSKeyID: name, ManifestKMSAlgorithm: "ECDSA_SHA_384", ManifestKMSEndpoint: server.URL}
+	raw, signature, err := p.signManifest(context.Background(), cfg, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "signature" || signature.KeyID != name || signature.Algorithm != "ECDSA_SHA_384" {
+		t.Errorf("unexpected signature %+v", signature)
+	}
+}
+
+func TestSignAzureKeyVault(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	data := []byte(`{"project": "mypkg"}`)
+	digest := sha256.Sum256(data)
+
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tenant/oauth2/v2.0/token":
+			_ = r.ParseForm()
+			if r.Form.Get("client_id") != "client" || r.Form.Get("client_secret") != "secret" || r.Form.Get("scope") != azureKeyVaultScope {
+				t.Errorf("unexpected token request %v", r.Form)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "vault-token", "expires_in": 3600})
+		case "/keys/manifest/v1/sign":
+			var req struct {
+				Alg   string `json:"alg"`
+				Value string `json:"value"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if r.Header.Get("Authorization") != "Bearer vault-token" || r.URL.Query().Get("api-version") != azureKeyVaultAPIVersion ||
+				req.Alg != "ES256" || req.Value != base64.RawURLEncoding.EncodeToString(digest[:]) {
+				t.Errorf("unexpected sign request %v %+v", r.Header, req)
+			}
+			rInt, sInt, _ := ecdsa.Sign(rand.Reader, key, digest[:])
+			sig := make([]byte, 64)
+			rInt.FillBytes(sig[:32])
+			sInt.FillBytes(sig[32:])
+			_ = json.NewEncoder(w).Encode(map[string]string{"kid": server.URL + "/keys/manifest/v1", "value": base64.RawURLEncoding.EncodeToString(sig)})
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("AZURE_AUTHORITY_HOST", server.URL)
+	t.Setenv("AZURE_TENANT_ID", "tenant")
+	t.Setenv("AZURE_CLIENT_ID", "client")
+	t.Setenv("AZURE_CLIENT_SECRET", "secret")
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	cfg := Config{ManifestKMSKeyID: server.URL + "/keys/manifest/v1", ManifestKMSAlgorithm: defaultKMSAlgorithm}
+	raw, signature, err := p.signManifest(context.Background(), cfg, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature.KeyID != cfg.ManifestKMSKeyID {
+		t.Errorf("unexpected key ID %q", signature.KeyID)
+	}
+	// The raw signature is converted to the ASN.1 form openssl verifies
+	if !ecdsa.VerifyASN1(&key.PublicKey, digest[:], raw) {
+		t.Error("expected an ASN.1 signature verifying with the key")
+	}
+
+	t.Setenv("AZURE_CLIENT_SECRET", "")
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", "")
+	t.Setenv(githubTokenRequestURLEnv, "")
+	if _, _, err := p.signManifest(context.Background(), cfg, data); err == nil || !strings.Contains(err.Error(), "no Azure credentials") {
+		t.Errorf("expected a credentials error, got %v", err)
+	}
+}
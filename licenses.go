@@ -0,0 +1,253 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxLicenseFileSize bounds how much of a bundled license file is read for identification.
+const maxLicenseFileSize = 256 << 10 // 256 KiB
+
+// licenseUnknown marks components whose license could not be identified.
+const licenseUnknown = "unknown"
+
+// licenseFilePattern matches the file names conventionally used for license texts.
+var licenseFilePattern = regexp.MustCompile(`(?i)^(licen[cs]e|copying|notice)([._-].*)?$`)
+
+// sharedLibraryPattern matches native libraries vendored into a wheel (e.g. by auditwheel or delocate).
+var sharedLibraryPattern = regexp.MustCompile(`\.(so(\.[0-9]+)*|dylib|dll|pyd)$`)
+
+// licenseSignatures identifies a license from distinctive phrases of its text, most specific first.
+var licenseSignatures = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license"}},
+	{"LGPL-2.0", []string{"gnu library general public license"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"PSF-2.0", []string{"python software foundation license"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"Zlib", []string{"this software is provided 'as-is', without any express or implied"}},
+}
+
+// classifierLicenses maps trove license classifiers to SPDX identifiers.
+var classifierLicenses = map[string]string{
+	"License :: OSI Approved :: MIT License":                                             "MIT",
+	"License :: OSI Approved :: Apache Software License":                                 "Apache-2.0",
+	"License :: OSI Approved :: BSD License":                                             "BSD-3-Clause",
+	"License :: OSI Approved :: ISC License (ISCL)":                                      "ISC",
+	"License :: OSI Approved :: Mozilla Public License 2.0 (MPL 2.0)":                    "MPL-2.0",
+	"License :: OSI Approved :: Python Software Foundation License":                      "PSF-2.0",
+	"License :: OSI Approved :: GNU General Public License v2 (GPLv2)":                   "GPL-2.0",
+	"License :: OSI Approved :: GNU General Public License v3 (GPLv3)":                   "GPL-3.0",
+	"License :: OSI Approved :: GNU Lesser General Public License v2 (LGPLv2)":           "LGPL-2.0",
+	"License :: OSI Approved :: GNU Lesser General Public License v3 (LGPLv3)":           "LGPL-3.0",
+	"License :: OSI Approved :: GNU Affero General Public License v3":                    "AGPL-3.0",
+	"License :: OSI Approved :: The Unlicense (Unlicense)":                               "Unlicense",
+	"License :: OSI Approved :: zlib/libpng License":                                     "Zlib",
+	"License :: OSI Approved :: GNU Lesser General Public License v2 or later (LGPLv2+)": "LGPL-2.0",
+}
+
+// bundledComponent is a vendored piece of a wheel and the license it was identified under.
+type bundledComponent struct {
+	Dist      string `json:"dist"`
+	Component string `json:"component"`
+	License   string `json:"license"`
+	Source    string `json:"source"`
+}
+
+// scanWheelLicenses identifies the licenses of code and libraries bundled into a wheel.
+// The wheel's own top-level metadata is not a bundled component and is skipped.
+func scanWheelLicenses(wheelPath string) ([]bundledComponent, error) {
+	zr, err := zip.OpenReader(wheelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wheel: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	dist := filepath.Base(wheelPath)
+	licensed := map[string]bool{}
+	var components []bundledComponent
+	var libraries []string
+
+	for _, f := range zr.File {
+		dir, name := path.Split(f.Name)
+		dir = strings.TrimSuffix(dir, "/")
+		topLevelMeta := !strings.Contains(dir, "/") && strings.HasSuffix(dir, ".dist-info")
+		ownLicenses := strings.Count(dir, "/") == 1 && strings.HasSuffix(path.Dir(dir), ".dist-info")
+		if topLevelMeta || ownLicenses {
+			continue
+		}
+
+		switch {
+		case name == "METADATA" && strings.HasSuffix(dir, ".dist-info"):
+			data, err := readZipMember(f, maxMetadataSize)
+			if err != nil {
+				return nil, err
+			}
+			license := licenseUnknown
+			if meta, err := parseMetadata(data); err == nil {
+				license = metadataLicense(meta)
+			}
+			components = append(components, bundledComponent{Dist: dist, Component: dir, License: license, Source: f.Name})
+		case licenseFilePattern.MatchString(name):
+			data, err := readZipMember(f, maxLicenseFileSize)
+			if err != nil {
+				return nil, err
+			}
+			components = append(components, bundledComponent{Dist: dist, Component: dir, License: identifyLicense(string(data)), Source: f.Name})
+			licensed[dir] = true
+		case sharedLibraryPattern.MatchString(name) && strings.HasSuffix(strings.SplitN(dir, "/", 2)[0], ".libs"):
+			libraries = append(libraries, f.Name)
+		}
+	}
+
+	// Vendored native libraries carry no license text unless one was shipped next to them
+	for _, lib := range libraries {
+		if !licensed[path.Dir(lib)] {
+			components = append(components, bundledComponent{Dist: dist, Component: lib, License: licenseUnknown, Source: lib})
+		}
+	}
+
+	sort.SliceStable(components, func(i, j int) bool { return components[i].Component < components[j].Component })
+	return components, nil
+}
+
+// readZipMember reads at most limit bytes of a zip member.
+func readZipMember(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	defer func() { _ = rc.Close() }()
+	return io.ReadAll(io.LimitReader(rc, limit))
+}
+
+// metadataLicense returns the license declared in core metadata, preferring License-Expression,
+// then a recognizable License field, then trove classifiers, then the raw short License field.
+func metadataLicense(meta *packageMetadata) string {
+	if meta.LicenseExpression != "" {
+		return meta.LicenseExpression
+	}
+	if id := identifyLicense(meta.License); id != licenseUnknown {
+		return id
+	}
+
+	var ids []string
+	for _, c := range meta.Classifiers {
+		if id, ok := classifierLicenses[c]; ok {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		return strings.Join(ids, " OR ")
+	}
+
+	if license := strings.TrimSpace(meta.License); license != "" && !strings.Contains(license, "\n") && len(license) <= 64 {
+		return license
+	}
+	return licenseUnknown
+}
+
+// identifyLicense identifies a license from its text or short name.
+func identifyLicense(text string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, sig := range licenseSignatures {
+		matched := true
+		for _, phrase := range sig.phrases {
+			if !strings.Contains(normalized, phrase) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return sig.id
+		}
+	}
+
+	// Short license fields often carry the SPDX identifier or a common alias
+	switch normalized {
+	case "mit", "mit license":
+		return "MIT"
+	case "apache-2.0", "apache 2.0", "apache license 2.0", "apache software license":
+		return "Apache-2.0"
+	case "bsd", "bsd license", "bsd-3-clause", "new bsd", "3-clause bsd":
+		return "BSD-3-Clause"
+	case "bsd-2-clause", "simplified bsd", "2-clause bsd":
+		return "BSD-2-Clause"
+	case "isc", "isc license":
+		return "ISC"
+	}
+	return licenseUnknown
+}
+
+// licenseAllowed reports whether a license expression satisfies the allowlist.
+// At least one "OR" alternative must have all of its "AND" terms allowed.
+func licenseAllowed(expression string, allowlist []string) bool {
+	allowed := make(map[string]bool, len(allowlist))
+	for _, id := range allowlist {
+		allowed[strings.ToLower(id)] = true
+	}
+
+	expression = strings.NewReplacer("(", " ", ")", " ").Replace(expression)
+	for _, alternative := range splitLicenseOperator(expression, "or") {
+		ok := true
+		for _, term := range splitLicenseOperator(alternative, "and") {
+			term = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(term)), "+")
+			term = strings.TrimSuffix(term, "-or-later")
+			term = strings.TrimSuffix(term, "-only")
+			if !allowed[term] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLicenseOperator splits an SPDX expression on a case-insensitive operator word.
+func splitLicenseOperator(expression, operator string) []string {
+	var parts []string
+	var current []string
+	for _, word := range strings.Fields(expression) {
+		if strings.EqualFold(word, operator) {
+			parts = append(parts, strings.Join(current, " "))
+			current = nil
+			continue
+		}
+		current = append(current, word)
+	}
+	return append(parts, strings.Join(current, " "))
+}
+
+// validateLicenseConfig validates the license check options.
+func validateLicenseConfig(cfg Config) error {
+	if cfg.LicenseCheck == "" || cfg.LicenseCheck == checkOff {
+		return nil
+	}
+	if !containsString(checkModes, cfg.LicenseCheck) {
+		return fmt.Errorf("license_check must be one of: %s", strings.Join(checkModes, ", "))
+	}
+	if len(cfg.LicenseAllowlist) == 0 {
+		return fmt.Errorf("license_allowlist is required when license_check is enabled")
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+const (
+	testMITText = `MIT License
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal`
+
+	testGPLText = `GNU GENERAL PUBLIC LICENSE
+Version 3, 29 June 2007`
+)
+
+// writeVendoringWheel writes a wheel bundling a vendored MIT project, a GPL-licensed
+// directory, and an auditwheel-style native library without license text.
+func writeVendoringWheel(t *testing.T, path string) {
+	t.Helper()
+	writeTestWheel(t, path, map[string]string{
+		"app/__init__.py":                                "",
+		"app-1.0.0.dist-info/METADATA":                   "Name: app\nVersion: 1.0.0\nLicense: Proprietary\n",
+		"app-1.0.0.dist-info/licenses/LICENSE":           "All rights reserved.",
+		"app/_vendor/six-1.16.0.dist-info/METADATA":      "Name: six\nVersion: 1.16.0\nClassifier: License :: OSI Approved :: MIT License\n",
+		"app/_vendor/readline/COPYING":                   testGPLText,
+		"app/_vendor/readline/__init__.py":               "",
+		"app/_vendor/idna/LICENSE.md":                    strings.ReplaceAll(testMITText, "\n", "\n  "),
+		"app.libs/libgfortran-2e0d59d6.so.5.0.0":         "\x7fELF",
+		"app/_native.cpython-311-x86_64-linux-gnu.so":    "\x7fELF",
+		"app/_vendor/readline/libreadline.so":            "\x7fELF",
+		"app-1.0.0.dist-info/RECORD":                     "",
+		"app/_vendor/six-1.16.0.dist-info/LICENSE.other": testMITText,
+	})
+}
+
+func TestScanWheelLicenses(t *testing.T) {
+	wheel := filepath.Join(t.TempDir(), "app-1.0.0-cp311-cp311-manylinux_2_17_x86_64.whl")
+	writeVendoringWheel(t, wheel)
+
+	components, err := scanWheelLicenses(wheel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, c := range components {
+		if c.Source != "app/_vendor/six-1.16.0.dist-info/LICENSE.other" {
+			got[c.Component] = c.License
+		}
+	}
+	want := map[string]string{
+		"app/_vendor/six-1.16.0.dist-info":       "MIT",
+		"app/_vendor/readline":                   "GPL-3.0",
+		"app/_vendor/idna":                       "MIT",
+		"app.libs/libgfortran-2e0d59d6.so.5.0.0": licenseUnknown,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d components, got %v", len(want), got)
+	}
+	for component, license := range want {
+		if got[component] != license {
+			t.Errorf("%s: license = %q, want %q", component, got[component], license)
+		}
+	}
+}
+
+func TestIdentifyLicense(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{testMITText, "MIT"},
+		{testGPLText, "GPL-3.0"},
+		{"GNU LESSER GENERAL PUBLIC LICENSE\nVersion 2.1, February 1999", "LGPL-2.1"},
+		{"Apache License\nVersion 2.0, January 2004", "Apache-2.0"},
+		{"Redistribution and use in source and binary forms ... Neither the name of", "BSD-3-Clause"},
+		{"BSD License", "BSD-3-Clause"},
+		{"MIT", "MIT"},
+		{"Some custom terms", licenseUnknown},
+		{"", licenseUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := identifyLicense(tt.text); got != tt.want {
+			t.Errorf("identifyLicense(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMetadataLicense(t *testing.T) {
+	tests := []struct {
+		name string
+		meta packageMetadata
+		want string
+	}{
+		{"expression", packageMetadata{LicenseExpression: "MIT OR Apache-2.0", License: "GPL"}, "MIT OR Apache-2.0"},
+		{"license field", packageMetadata{License: "Apache 2.0"}, "Apache-2.0"},
+		{"classifier", packageMetadata{License: "UNKNOWN", Classifiers: []string{"License :: OSI Approved :: BSD License"}}, "BSD-3-Clause"},
+		{"raw short field", packageMetadata{License: "Proprietary"}, "Proprietary"},
+		{"nothing", packageMetadata{}, licenseUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := metadataLicense(&tt.meta); got != tt.want {
+				t.Errorf("metadataLicense() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLicenseAllowed(t *testing.T) {
+	allowlist := []string{"MIT", "apache-2.0", "BSD-3-Clause"}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{"MIT", true},
+		{"Apache-2.0", true},
+		{"GPL-3.0", false},
+		{"GPL-3.0-or-later OR MIT", true},
+		{"MIT AND GPL-2.0", false},
+		{"(MIT AND BSD-3-Clause) OR GPL-2.0", true},
+		{licenseUnknown, false},
+	}
+
+	for _, tt := range tests {
+		if got := licenseAllowed(tt.expression, allowlist); got != tt.want {
+			t.Errorf("licenseAllowed(%q) = %v, want %v", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteLicenseCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       map[string]any
+		wantSuccess  bool
+		wantWarnings bool
+	}{
+		{
+			name:        "fail on GPL and unknown",
+			config:      map[string]any{"license_check": "fail", "license_allowlist": []any{"MIT"}},
+			wantSuccess: false,
+		},
+		{
+			name:         "warn mode uploads",
+			config:       map[string]any{"license_check": "warn", "license_allowlist": []any{"MIT"}},
+			wantSuccess:  true,
+			wantWarnings: true,
+		},
+		{
+			name:        "allowed with unknown accepted",
+			config:      map[string]any{"license_check": "fail", "license_allowlist": []any{"MIT", "GPL-3.0"}, "license_allow_unknown": true},
+			wantSuccess: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distPath := writeDistFiles(t)
+			writeVendoringWheel(t, filepath.Join("dist", "app-1.0.0-cp311-cp311-manylinux_2_17_x86_64.whl"))
+
+			config := map[string]any{
+				"username":   "__token__",
+				"password":   "pypi-token",
+				"repository": "http://localhost:8080/",
+				"dist_path":  distPath,
+			}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+
+			p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					return []byte("ok"), nil
+				},
+			}}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:   plugin.HookPostPublish,
+				Config: config,
+				DryRun: true,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Success != tt.wantSuccess {
+				t.Fatalf("success = %v, want %v (error: %s)", resp.Success, tt.wantSuccess, resp.Error)
+			}
+			if !tt.wantSuccess && !strings.Contains(resp.Error, "app/_vendor/readline (GPL-3.0)") {
+				t.Errorf("expected GPL component in error, got: %s", resp.Error)
+			}
+			if _, ok := resp.Outputs["warnings"]; ok != tt.wantWarnings {
+				t.Errorf("warnings present = %v, want %v", ok, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestValidateLicenseConfig(t *testing.T) {
+	p := &PyPIPlugin{}
+	if err := validateLicenseConfig(p.parseConfig(map[string]any{})); err != nil {
+		t.Errorf("unexpected error for disabled check: %v", err)
+	}
+	if err := validateLicenseConfig(p.parseConfig(map[string]any{"license_check": "warn"})); err == nil {
+		t.Error("expected error without allowlist")
+	}
+	if err := validateLicenseConfig(p.parseConfig(map[string]any{"license_check": "audit", "license_allowlist": []any{"MIT"}})); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Policies of the local_version option for versions with a local segment (1.0.0+deadbeef).
+const (
+	localVersionAllow    = "allow"
+	localVersionStrip    = "strip"
+	localVersionFail     = "fail"
+	localVersionRedirect = "redirect"
+)
+
+// localVersionPolicies lists the supported local_version values.
+var localVersionPolicies = []string{localVersionAllow, localVersionStrip, localVersionFail, localVersionRedirect}
+
+// metadataVersionPattern matches the Version header of core metadata.
+var metadataVersionPattern = regexp.MustCompile(`(?m)^Version:[ \t]*(\S+)[ \t]*(\r?)$`)
+
+// localVersionPolicy returns the policy for versions with a local segment: the configured one,
+// or fail for PyPI and TestPyPI, which reject local versions, and allow for other indexes.
+func localVersionPolicy(cfg Config) string {
+	if cfg.LocalVersion != "" {
+		return cfg.LocalVersion
+	}
+	if isPyPIRepository(cfg.Repository) {
+		return localVersionFail
+	}
+	return localVersionAllow
+}
+
+// stripLocalVersion returns version without its local segment.
+func stripLocalVersion(version string) string {
+	public, _, _ := strings.Cut(version, "+")
+	return public
+}
+
+// localVersions returns the local versions of the release and of the distributions, sorted.
+func localVersions(version string, files []string) []string {
+	var versions []string
+	if strings.Contains(version, "+") {
+		versions = append(versions, version)
+	}
+	for _, f := range files {
+		if meta, err := readDistMetadata(f); err == nil && strings.Contains(meta.Version, "+") && !containsString(versions, meta.Version) {
+			versions = append(versions, meta.Version)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// redirectedConfig returns cfg uploading to repository. The index, role and JSON APIs that
+// defaulted from the configured repository follow it.
+func redirectedConfig(cfg Config, repository string) Config {
+	if cfg.IndexURL == defaultIndexURL(cfg.Repository) {
+		cfg.IndexURL = defaultIndexURL(repository)
+	}
+	if cfg.MaintainerAPIURL == defaultRoleAPI(cfg.Repository) {
+		cfg.MaintainerAPIURL = defaultRoleAPI(repository)
+	}
+	if cfg.JSONAPIURL == defaultJSONAPI(cfg.Repository) {
+		cfg.JSONAPIURL = defaultJSONAPI(repository)
+	}
+	cfg.Repository = repository
+	return cfg
+}
+
+// stripLocalVersionFiles writes copies of the distributions with a local version to dir,
+// without the local segment in their file names, metadata and wheel RECORD. It returns the
+// files to upload in the order of files, and the copies keyed by original file.
+func stripLocalVersionFiles(files []string, dir string) ([]string, map[string]string, error) {
+	stripped := map[string]string{}
+	upload := make([]string, 0, len(files))
+	for _, f := range files {
+		meta, err := readDistMetadata(f)
+		if err != nil || !strings.Contains(meta.Version, "+") {
+			upload = append(upload, f)
+			continue
+		}
+		base := filepath.Base(f)
+		named := versionFromFilename(meta.Name, base)
+		_, local, ok := strings.Cut(named, "+")
+		if !ok {
+			return nil, nil, fmt.Errorf("file name of %s does not carry the local version %s of its metadata", f, meta.Version)
+		}
+		r := localSegmentRemover("+" + local)
+		target := filepath.Join(dir, r(base))
+		switch {
+		case strings.HasSuffix(base, ".whl"):
+			err = rewriteWheel(f, target, r, stripLocalVersion(meta.Version))
+		case strings.HasSuffix(base, ".tar.gz"):
+			err = rewriteSdist(f, target, r, stripLocalVersion(meta.Version))
+		default:
+			err = fmt.Errorf("unsupported distribution format")
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to strip the local version of %s: %w", f, err)
+		}
+		stripped[f] = target
+		upload = append(upload, target)
+	}
+	return upload, stripped, nil
+}
+
+// localSegmentRemover returns a function removing segment from the first component of an
+// archive member name, such as the dist-info directory or the sdist root.
+func localSegmentRemover(segment string) func(string) string {
+	return firstComponentReplacer(segment, "")
+}
+
+// firstComponentReplacer returns a function replacing old with new once in the first component
+// of an archive member name or in a file name.
+func firstComponentReplacer(old, new string) func(string) string {
+	return func(name string) string {
+		first, rest, nested := strings.Cut(name, "/")
+		first = strings.Replace(first, old, new, 1)
+		if nested {
+			return first + "/" + rest
+		}
+		return first
+	}
+}
+
+// setMetadataVersion replaces the Version header of core metadata.
+func setMetadataVersion(data []byte, version string) []byte {
+	header, body, ok := bytes.Cut(data, []byte("\n\n"))
+	header = metadataVersionPattern.ReplaceAll(header, []byte("Version: "+version+"$2"))
+	if ok {
+		return append(append(header, "\n\n"...), body...)
+	}
+	return header
+}
+
+// rewriteWheel copies the wheel src to dst with member names renamed by rename, the Version of
+// METADATA set to version, and RECORD updated to match.
+func rewriteWheel(src, dst string, rename func(string) string, version string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = zr.Close() }()
+
+	members := map[string][]byte{}
+	var record string
+	for _, f := range zr.File {
+		dir, file := path.Split(f.Name)
+		if strings.Count(f.Name, "/") != 1 || !strings.HasSuffix(dir, ".dist-info/") || (file != "METADATA" && file != "RECORD") {
+			continue
+		}
+		data, err := readZipMember(f, maxMetadataSize)
+		if err != nil {
+			return err
+		}
+		if file == "METADATA" {
+			data = setMetadataVersion(data, version)
+		} else {
+			record = f.Name
+		}
+		members[f.Name] = data
+	}
+	if record != "" {
+		members[record], err = rewriteRecord(members[record], rename, members)
+		if err != nil {
+			return err
+		}
+	}
+
+	out, err := os.Create(dst) // #nosec G304 -- dst is in the plugin's temporary directory
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+	zw := zip.NewWriter(out)
+	for _, f := range zr.File {
+		header := f.FileHeader
+		header.Name = rename(f.Name)
+		if data, ok := members[f.Name]; ok {
+			w, err := zw.CreateHeader(&header)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			continue
+		}
+		raw, err := f.OpenRaw()
+		if err != nil {
+			return err
+		}
+		w, err := zw.CreateRaw(&header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, raw); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// rewriteRecord renames the paths of a wheel RECORD and updates the digest and size of the
+// rewritten members.
+func rewriteRecord(record []byte, rename func(string) string, rewritten map[string][]byte) ([]byte, error) {
+	rows, err := csv.NewReader(bytes.NewReader(record)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECORD: %w", err)
+	}
+	var out bytes.Buffer
+	w := csv.NewWriter(&out)
+	for _, row := range rows {
+		if len(row) != 3 {
+			return nil, fmt.Errorf("invalid RECORD row %q", strings.Join(row, ","))
+		}
+		if data, ok := rewritten[row[0]]; ok && row[1] != "" {
+			sum := sha256.Sum256(data)
+			row[1] = "sha256=" + base64.RawURLEncoding.EncodeToString(sum[:])
+			row[2] = strconv.Itoa(len(data))
+		}
+		row[0] = rename(row[0])
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return out.Bytes(), w.Error()
+}
+
+// rewriteSdist copies the sdist src to dst with member names renamed by rename and the Version
+// of every PKG-INFO set to version.
+func rewriteSdist(src, dst string, rename func(string) string, version string) error {
+	in, err := os.Open(src) // #nosec G304 -- dist files come from the validated dist path
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = gz.Close() }()
+
+	out, err := os.Create(dst) // #nosec G304 -- dst is in the plugin's temporary directory
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+	gw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gw)
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(hdr.Name, "./")
+		hdr.Name = rename(name)
+		delete(hdr.PAXRecords, "path")
+		var content io.Reader = tr
+		if hdr.Typeflag == tar.TypeReg && path.Base(name) == "PKG-INFO" {
+			data, err := io.ReadAll(io.LimitReader(tr, maxMetadataSize))
+			if err != nil {
+				return err
+			}
+			data = setMetadataVersion(data, version)
+			hdr.Size = int64(len(data))
+			content = bytes.NewReader(data)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, content); err != nil { // #nosec G110 -- sizes come from the sdist's own headers
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// validateLocalVersionConfig validates the local_version options.
+func validateLocalVersionConfig(cfg Config) error {
+	if cfg.LocalVersion != "" && !containsString(localVersionPolicies, cfg.LocalVersion) {
+		return fmt.Errorf("local_version must be one of: %s", strings.Join(localVersionPolicies, ", "))
+	}
+	if cfg.LocalVersion != localVersionRedirect {
+		if cfg.LocalVersionRepository != "" {
+			return fmt.Errorf("local_version_repository requires local_version: redirect")
+		}
+		return nil
+	}
+	if cfg.LocalVersionRepository == "" {
+		return fmt.Errorf("local_version redirect requires local_version_repository")
+	}
+	if isPyPIRepository(cfg.LocalVersionRepository) {
+		return fmt.Errorf("local_version_repository must be a private index; PyPI rejects local versions")
+	}
+	if err := validateRepositoryURL(cfg.LocalVersionRepository); err != nil {
+		return fmt.Errorf("invalid local_version_repository: %w", err)
+	}
+	return nil
+}
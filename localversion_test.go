@@ -0,0 +1,194 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestLocalVersionPolicy(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{Repository: "https://upload.pypi.org/legacy/"}, localVersionFail},
+		{Config{Repository: "https://test.pypi.org/legacy/"}, localVersionFail},
+		{Config{Repository: "https://pypi.example.com/"}, localVersionAllow},
+		{Config{Repository: "https://upload.pypi.org/legacy/", LocalVersion: localVersionStrip}, localVersionStrip},
+	}
+	for _, tt := range tests {
+		if got := localVersionPolicy(tt.cfg); got != tt.want {
+			t.Errorf("localVersionPolicy(%+v) = %s, want %s", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestSetMetadataVersion(t *testing.T) {
+	data := "Metadata-Version: 2.1\r\nName: mypkg\r\nVersion: 1.0.0+deadbeef\r\n\r\nVersion: in the description\n"
+	want := "Metadata-Version: 2.1\r\nName: mypkg\r\nVersion: 1.0.0\r\n\r\nVersion: in the description\n"
+	if got := string(setMetadataVersion([]byte(data), "1.0.0")); got != want {
+		t.Errorf("setMetadataVersion = %q, want %q", got, want)
+	}
+}
+
+func TestStripLocalVersionFiles(t *testing.T) {
+	writeDistFiles(t)
+	metadata := "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0+g1234.dirty\n"
+	wheel := filepath.Join("dist", "mypkg-1.0.0+g1234.dirty-py3-none-any.whl")
+	writeTestWheel(t, wheel, map[string]string{
+		"mypkg/__init__.py":                              "",
+		"mypkg-1.0.0+g1234.dirty.dist-info/METADATA":     metadata,
+		"mypkg-1.0.0+g1234.dirty.dist-info/WHEEL":        "Wheel-Version: 1.0\n",
+		"mypkg-1.0.0+g1234.dirty.dist-info/RECORD":       "mypkg/__init__.py,sha256=47DEQpj8HBSa-_TImW-5JCeuQeRkm5NMpJWZG3hSuFU,0\nmypkg-1.0.0+g1234.dirty.dist-info/METADATA,sha256=old,10\nmypkg-1.0.0+g1234.dirty.dist-info/RECORD,,\n",
+		"mypkg-1.0.0+g1234.dirty.dist-info/entry_points": "",
+	})
+	sdist := filepath.Join("dist", "mypkg-1.0.0+g1234.dirty.tar.gz")
+	writeTestSdist(t, sdist, map[string]string{
+		"mypkg-1.0.0+g1234.dirty/PKG-INFO":                metadata,
+		"mypkg-1.0.0+g1234.dirty/mypkg.egg-info/PKG-INFO": metadata,
+		"mypkg-1.0.0+g1234.dirty/setup.py":                "",
+	})
+	plain := filepath.Join("dist", "other-1.0.0-py3-none-any.whl")
+	writeTestWheel(t, plain, map[string]string{
+		"other-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: other\nVersion: 1.0.0\n",
+	})
+
+	dir := t.TempDir()
+	upload, stripped, err := stripLocalVersionFiles([]string{wheel, sdist, plain}, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantWheel := filepath.Join(dir, "mypkg-1.0.0-py3-none-any.whl")
+	wantSdist := filepath.Join(dir, "mypkg-1.0.0.tar.gz")
+	if fmt.Sprint(upload) != fmt.Sprint([]string{wantWheel, wantSdist, plain}) || len(stripped) != 2 {
+		t.Fatalf("unexpected files %v %v", upload, stripped)
+	}
+
+	for _, f := range []string{wantWheel, wantSdist} {
+		meta, err := readDistMetadata(f)
+		if err != nil || meta.Version != "1.0.0" || meta.Name != "mypkg" {
+			t.Errorf("%s: unexpected metadata %+v %v", f, meta, err)
+		}
+	}
+	egg, err := readSdistFile(wantSdist, func(name string) bool { return name == "mypkg-1.0.0/mypkg.egg-info/PKG-INFO" })
+	if err != nil || !strings.Contains(string(egg), "Version: 1.0.0\n") {
+		t.Errorf("unexpected egg-info PKG-INFO %q %v", egg, err)
+	}
+
+	zr, err := zip.OpenReader(wantWheel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = zr.Close() }()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if strings.Contains(strings.Join(names, " "), "+g1234") {
+		t.Errorf("local segment left in %v", names)
+	}
+	record, err := readWheelFile(wantWheel, func(name string) bool { return name == "mypkg-1.0.0.dist-info/RECORD" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMetadata := strings.Replace(metadata, "+g1234.dirty", "", 1)
+	sum := sha256.Sum256([]byte(newMetadata))
+	wantRow := fmt.Sprintf("mypkg-1.0.0.dist-info/METADATA,sha256=%s,%d", base64.RawURLEncoding.EncodeToString(sum[:]), len(newMetadata))
+	if !strings.Contains(string(record), wantRow) || !strings.Contains(string(record), "mypkg/__init__.py,sha256=47DEQ") || !strings.Contains(string(record), "mypkg-1.0.0.dist-info/RECORD,,") {
+		t.Errorf("unexpected RECORD:\n%s", record)
+	}
+}
+
+func TestExecuteLocalVersionPolicy(t *testing.T) {
+	newTest := func(t *testing.T) (*PyPIPlugin, *[]string) {
+		writeDistFiles(t)
+		writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0+deadbeef-py3-none-any.whl"), map[string]string{
+			"mypkg-1.0.0+deadbeef.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0+deadbeef\n",
+		})
+		var calls []string
+		return &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+			RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				calls = append(calls, strings.Join(args, " "))
+				return nil, nil
+			},
+		}}, &calls
+	}
+	config := func(extra map[string]any) map[string]any {
+		c := map[string]any{"username": "__token__", "password": "pypi-token"}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	t.Run("fail on PyPI by default", func(t *testing.T) {
+		p, calls := newTest(t)
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config(nil)})
+		if err != nil || resp.Success || !strings.Contains(resp.Error, "local versions are not accepted by https://upload.pypi.org/legacy/: 1.0.0+deadbeef") || len(*calls) != 0 {
+			t.Fatalf("expected the local version to be refused, got %v %+v", err, resp)
+		}
+	})
+
+	t.Run("strip", func(t *testing.T) {
+		p, calls := newTest(t)
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  config(map[string]any{"local_version": "strip"}),
+			Context: plugin.ReleaseContext{Version: "v1.0.0+deadbeef"},
+		})
+		if err != nil || !resp.Success || resp.Outputs["version"] != "1.0.0" {
+			t.Fatalf("expected the stripped version to be uploaded, got %v %+v", err, resp)
+		}
+		if len(*calls) != 1 || !strings.HasSuffix((*calls)[0], "mypkg-1.0.0-py3-none-any.whl") {
+			t.Errorf("unexpected uploads %v", *calls)
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		p, calls := newTest(t)
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:   plugin.HookPostPublish,
+			Config: config(map[string]any{"local_version": "redirect", "local_version_repository": server.URL + "/private/"}),
+		})
+		if err != nil || !resp.Success || resp.Outputs["repository"] != server.URL+"/private/" {
+			t.Fatalf("expected the upload to be redirected, got %v %+v", err, resp)
+		}
+		if len(*calls) != 1 || !strings.Contains((*calls)[0], "--repository-url "+server.URL+"/private/") {
+			t.Errorf("unexpected uploads %v", *calls)
+		}
+	})
+}
+
+func TestValidateLocalVersionConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"default", Config{}, false},
+		{"strip", Config{LocalVersion: localVersionStrip}, false},
+		{"redirect", Config{LocalVersion: localVersionRedirect, LocalVersionRepository: "http://localhost:8080/private/"}, false},
+		{"redirect without repository", Config{LocalVersion: localVersionRedirect}, true},
+		{"redirect to PyPI", Config{LocalVersion: localVersionRedirect, LocalVersionRepository: "https://upload.pypi.org/legacy/"}, true},
+		{"repository without redirect", Config{LocalVersion: localVersionFail, LocalVersionRepository: "https://pypi.example.com/"}, true},
+		{"unknown", Config{LocalVersion: "ignore"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateLocalVersionConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateLocalVersionConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// redactedValue replaces secrets in the publish log.
+const redactedValue = "***"
+
+// secretFlags are command line flags whose value is a secret.
+var secretFlags = []string{"-p", "--password"}
+
+// publishLog writes a structured log of a publish to the log_file as JSON lines: every
+// command and upload attempt with redacted arguments and timings, and the start and end of
+// each package. A nil log discards events.
+type publishLog struct {
+	path string
+
+	mu      sync.Mutex
+	secrets []string
+	err     error
+}
+
+// newPublishLog creates (or truncates) the configured log file, or returns nil when no log
+// file is configured.
+func newPublishLog(cfg Config) *publishLog {
+	// Invalid paths are reported by validation before anything is published
+	if cfg.LogFile == "" || validateLogFileConfig(cfg) != nil {
+		return nil
+	}
+	l := &publishLog{path: cfg.LogFile}
+	if err := os.MkdirAll(filepath.Dir(cfg.LogFile), 0o750); err != nil {
+		l.err = fmt.Errorf("failed to create log directory: %w", err)
+		return l
+	}
+	f, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) // #nosec G304 -- path configured by the user
+	if err != nil {
+		l.err = fmt.Errorf("failed to create log file: %w", err)
+		return l
+	}
+	l.err = f.Close()
+	return l
+}
+
+// addSecrets registers the credentials of cfg so they are redacted from events.
+func (l *publishLog) addSecrets(cfg Config) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, s := range configSecrets(cfg) {
+		if !containsString(l.secrets, s) {
+			l.secrets = append(l.secrets, s)
+		}
+	}
+}
+
+// redact replaces the registered secrets in s.
+func (l *publishLog) redact(s string) string {
+	for _, secret := range l.secrets {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	return s
+}
+
+// redactArgs returns args with the values of secret flags and registered secrets replaced.
+func (l *publishLog) redactArgs(args []string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if i > 0 && containsString(secretFlags, args[i-1]) {
+			redacted[i] = redactedValue
+			continue
+		}
+		redacted[i] = l.redact(arg)
+	}
+	return redacted
+}
+
+// event appends an event for the package configured by cfg to the log.
+func (l *publishLog) event(cfg Config, kind string, fields map[string]any) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+
+	entry := map[string]any{
+		"time":       time.Now().UTC().Format(time.RFC3339Nano),
+		"event":      kind,
+		"repository": cfg.Repository,
+		"dist_path":  cfg.DistPath,
+	}
+	for k, v := range fields {
+		if s, ok := v.(string); ok {
+			v = l.redact(s)
+		}
+		entry[k] = v
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		l.err = err
+		return
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_WRONLY, 0o600) // #nosec G304 -- path configured by the user
+	if err != nil {
+		l.err = fmt.Errorf("failed to write log file: %w", err)
+		return
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		l.err = fmt.Errorf("failed to write log file: %w", err)
+	}
+}
+
+// annotate reports the log file, or why it could not be written, in the hook response.
+func (l *publishLog) annotate(resp *plugin.ExecuteResponse) {
+	if l == nil {
+		return
+	}
+	if resp.Outputs == nil {
+		resp.Outputs = map[string]any{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		resp.Outputs["log_file_error"] = l.err.Error()
+		return
+	}
+	resp.Outputs["log_file"] = l.path
+}
+
+// wrap returns an executor whose commands are logged for the package configured by cfg.
+func (l *publishLog) wrap(executor CommandExecutor, cfg Config) CommandExecutor {
+	if l == nil {
+		return executor
+	}
+	return &loggingExecutor{inner: executor, log: l, cfg: cfg}
+}
+
+// loggingExecutor logs every command it runs with its redacted arguments, duration, and output.
+type loggingExecutor struct {
+	inner CommandExecutor
+	log   *publishLog
+	cfg   Config
+}
+
+// Run runs the command and logs the attempt.
+func (e *loggingExecutor) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return e.RunWithEnv(ctx, nil, name, args...)
+}
+
+// RunWithEnv runs the command with additional environment variables and logs the attempt. Only
+// the names of the variables are logged, as they carry credentials.
+func (e *loggingExecutor) RunWithEnv(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	return e.logged(env, name, args, func() ([]byte, error) {
+		return e.inner.RunWithEnv(ctx, env, name, args...)
+	})
+}
+
+// Output runs the command for its standard output and logs the attempt.
+func (e *loggingExecutor) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return e.logged(nil, name, args, func() ([]byte, error) {
+		return commandOutput(ctx, e.inner, name, args...)
+	})
+}
+
+// logged runs the command with run and logs the attempt.
+func (e *loggingExecutor) logged(env []string, name string, args []string, run func() ([]byte, error)) ([]byte, error) {
+	start := time.Now()
+	out, err := run()
+
+	fields := map[string]any{
+		"command":     name,
+		"args":        e.log.redactArgs(args),
+		"duration_ms": time.Since(start).Milliseconds(),
+		"output":      string(out),
+	}
+	if len(env) > 0 {
+		names := make([]string, len(env))
+		for i, kv := range env {
+			names[i], _, _ = strings.Cut(kv, "=")
+		}
+		fields["env"] = names
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	e.log.event(e.cfg, "command", fields)
+	return out, err
+}
+
+// validateLogFileConfig validates the log_file option like other paths written by the plugin.
+func validateLogFileConfig(cfg Config) error {
+	if cfg.LogFile == "" {
+		return nil
+	}
+	if err := validateDistPath(cfg.LogFile); err != nil {
+		return fmt.Errorf("invalid log_file: %w", err)
+	}
+	if strings.Contains(cfg.LogFile, "*") {
+		return fmt.Errorf("invalid log_file: must not contain wildcards")
+	}
+	if matched, _ := filepath.Match(toSlashPath(cfg.DistPath), toSlashPath(cfg.LogFile)); matched {
+		return fmt.Errorf("log_file must not match dist_path, or the log would be uploaded")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestExecuteWritesLogFile(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("Uploading mypkg-1.0.0.tar.gz\nHTTPError: 400 Bad Request"), errors.New("exit status 1")
+		},
+	}}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Context: plugin.ReleaseContext{Version: "v1.0.0"},
+		Config: map[string]any{
+			"username":   "__token__",
+			"password":   "pypi-secret-token",
+			"repository": "http://localhost:8080/",
+			"log_file":   "publish.log",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || resp.Outputs["log_file"] != "publish.log" {
+		t.Fatalf("expected a failed publish reporting the log file, got %+v", resp.Outputs)
+	}
+
+	data, err := os.ReadFile("publish.log")
+	if err != nil {
+		t.Fatalf("log file not written: %v", err)
+	}
+	if strings.Contains(string(data), "pypi-secret-token") {
+		t.Error("the password must be redacted from the log")
+	}
+
+	var events []map[string]any
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		var event map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid log line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	var kinds []string
+	for _, e := range events {
+		kinds = append(kinds, e["event"].(string))
+	}
+	if strings.Join(kinds, ",") != "publish_start,command,publish_end" {
+		t.Fatalf("unexpected events %v", kinds)
+	}
+
+	command := events[1]
+	if command["command"] != "twine" || command["error"] != "exit status 1" || !strings.Contains(command["output"].(string), "HTTPError: 400") {
+		t.Errorf("unexpected command event %+v", command)
+	}
+	if env, _ := command["env"].([]any); len(env) != 2 || env[0] != "TWINE_USERNAME" || env[1] != "TWINE_PASSWORD" {
+		t.Errorf("expected only the names of the credential variables, got %v", command["env"])
+	}
+	if _, ok := command["duration_ms"]; !ok {
+		t.Error("expected command timings")
+	}
+	if end := events[2]; end["success"] != false || end["version"] != nil {
+		t.Errorf("unexpected end event %+v", end)
+	}
+}
+
+func TestValidateLogFileConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"unset", Config{}, false},
+		{"relative", Config{LogFile: "logs/publish.jsonl", DistPath: "dist/*"}, false},
+		{"absolute", Config{LogFile: "/var/log/publish.jsonl", DistPath: "dist/*"}, true},
+		{"traversal", Config{LogFile: "../publish.jsonl", DistPath: "dist/*"}, true},
+		{"uploaded", Config{LogFile: "dist/publish.log", DistPath: "dist/*"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateLogFileConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateLogFileConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
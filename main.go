@@ -2,9 +2,21 @@
 package main
 
 import (
+	"os"
+
 	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
 )
 
 func main() {
+	// Helper commands for preparing encrypted config values
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "encrypt-value":
+			os.Exit(runEncryptValue(os.Stdin, os.Stdout, os.Stderr))
+		case "generate-config-key":
+			os.Exit(runGenerateConfigKey(os.Stdout, os.Stderr))
+		}
+	}
+
 	plugin.Serve(&PyPIPlugin{})
 }
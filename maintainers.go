@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// knownRoleAPIs maps upload endpoints to the XML-RPC API reporting their project roles.
+var knownRoleAPIs = map[string]string{
+	"https://upload.pypi.org/legacy/": "https://pypi.org/pypi",
+	"https://test.pypi.org/legacy/":   "https://test.pypi.org/pypi",
+}
+
+// projectRole is an account with a role (Owner or Maintainer) on a project.
+type projectRole struct {
+	Role string `json:"role"`
+	User string `json:"user"`
+}
+
+// xmlrpcValue is an XML-RPC value; untyped values are strings.
+type xmlrpcValue struct {
+	String string `xml:"string"`
+	Text   string `xml:",chardata"`
+	Array  *struct {
+		Values []xmlrpcValue `xml:"data>value"`
+	} `xml:"array"`
+}
+
+// text returns the string content of the value.
+func (v xmlrpcValue) text() string {
+	if v.String != "" {
+		return v.String
+	}
+	return strings.TrimSpace(v.Text)
+}
+
+// xmlrpcResponse is an XML-RPC method response with a single result or a fault.
+type xmlrpcResponse struct {
+	Result xmlrpcValue `xml:"params>param>value"`
+	Fault  *struct {
+		Members []struct {
+			Name  string      `xml:"name"`
+			Value xmlrpcValue `xml:"value"`
+		} `xml:"value>struct>member"`
+	} `xml:"fault"`
+}
+
+// defaultRoleAPI returns the role API of a well-known upload repository, or "".
+func defaultRoleAPI(repository string) string {
+	if !strings.HasSuffix(repository, "/") {
+		repository += "/"
+	}
+	return knownRoleAPIs[repository]
+}
+
+// maintainerAccount returns the account expected among the maintainers: the configured one,
+// or the username unless it is the API token placeholder.
+func maintainerAccount(cfg Config) string {
+	if cfg.MaintainerAccount != "" {
+		return cfg.MaintainerAccount
+	}
+	if cfg.Username != "__token__" {
+		return cfg.Username
+	}
+	return ""
+}
+
+// fetchProjectRoles returns the accounts with a role on project through the package_roles
+// XML-RPC method. A project that does not exist yet has no roles.
+func (p *PyPIPlugin) fetchProjectRoles(ctx context.Context, apiURL, project string) ([]projectRole, error) {
+	var call bytes.Buffer
+	call.WriteString(`<?xml version="1.0"?><methodCall><methodName>package_roles</methodName><params><param><value><string>`)
+	if err := xml.EscapeText(&call, []byte(project)); err != nil {
+		return nil, err
+	}
+	call.WriteString(`</string></value></param></params></methodCall>`)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, &call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/xml")
+
+	resp, err := p.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("role request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("role request failed: %s", resp.Status)
+	}
+
+	var parsed xmlrpcResponse
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("invalid role response: %w", err)
+	}
+	if parsed.Fault != nil {
+		for _, m := range parsed.Fault.Members {
+			if m.Name == "faultString" {
+				return nil, fmt.Errorf("role request failed: %s", m.Value.text())
+			}
+		}
+		return nil, fmt.Errorf("role request failed")
+	}
+
+	roles := []projectRole{}
+	if parsed.Result.Array == nil {
+		return roles, nil
+	}
+	for _, v := range parsed.Result.Array.Values {
+		if v.Array == nil || len(v.Array.Values) != 2 {
+			continue
+		}
+		roles = append(roles, projectRole{Role: v.Array.Values[0].text(), User: v.Array.Values[1].text()})
+	}
+	return roles, nil
+}
+
+// maintainerFindings compares the project roles with the publishing account and the expected
+// maintainers.
+func maintainerFindings(cfg Config, project string, roles []projectRole) []string {
+	var findings []string
+	users := map[string]bool{}
+	for _, r := range roles {
+		users[strings.ToLower(r.User)] = true
+	}
+	if account := maintainerAccount(cfg); account != "" && !users[strings.ToLower(account)] {
+		findings = append(findings, fmt.Sprintf("publishing account %s is not a maintainer of %s", account, project))
+	}
+	if len(cfg.ExpectedMaintainers) > 0 {
+		expected := map[string]bool{}
+		for _, m := range cfg.ExpectedMaintainers {
+			expected[strings.ToLower(m)] = true
+		}
+		var unexpected []string
+		for _, r := range roles {
+			if !expected[strings.ToLower(r.User)] && !containsString(unexpected, r.User) {
+				unexpected = append(unexpected, r.User)
+			}
+		}
+		if len(unexpected) > 0 {
+			findings = append(findings, fmt.Sprintf("unexpected maintainers of %s: %s", project, strings.Join(unexpected, ", ")))
+		}
+	}
+	return findings
+}
+
+// preflightMaintainers checks the project's maintainers on the index, as an early signal of a
+// compromised account or a hijacked project. A project that does not exist yet is skipped.
+func (p *PyPIPlugin) preflightMaintainers(ctx context.Context, cfg Config, result *preflightResult) *plugin.ExecuteResponse {
+	project := ""
+	for _, f := range result.files {
+		if meta, err := readDistMetadata(f); err == nil {
+			project = meta.Name
+			break
+		}
+	}
+	if project == "" {
+		result.warn("maintainer check skipped: no distribution with readable metadata")
+		return nil
+	}
+
+	roles, err := p.fetchProjectRoles(ctx, cfg.MaintainerAPIURL, project)
+	if err != nil {
+		if cfg.MaintainerCheck == checkFail {
+			return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("maintainer check failed: %v", err)}
+		}
+		result.warn("maintainer check skipped: %v", err)
+		return nil
+	}
+	result.outputs["maintainers"] = roles
+	if len(roles) == 0 {
+		return nil
+	}
+
+	findings := maintainerFindings(cfg, project, roles)
+	if len(findings) == 0 {
+		return nil
+	}
+	msg := strings.Join(findings, "; ")
+	if cfg.MaintainerCheck == checkFail {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   "maintainer check failed: " + msg,
+			Outputs: map[string]any{"maintainers": roles},
+		}
+	}
+	result.warn("%s", msg)
+	return nil
+}
+
+// validateMaintainerConfig validates the maintainer check options.
+func validateMaintainerConfig(cfg Config) error {
+	if cfg.MaintainerCheck == "" || cfg.MaintainerCheck == checkOff {
+		return nil
+	}
+	if !containsString(checkModes, cfg.MaintainerCheck) {
+		return fmt.Errorf("maintainer_check must be one of: %s", strings.Join(checkModes, ", "))
+	}
+	if cfg.MaintainerAPIURL == "" {
+		return fmt.Errorf("maintainer_api_url is required for repositories other than PyPI and TestPyPI")
+	}
+	if cfg.MaintainerAPIURL != defaultRoleAPI(cfg.Repository) {
+		if err := validateRepositoryURL(cfg.MaintainerAPIURL); err != nil {
+			return fmt.Errorf("invalid maintainer_api_url: %w", err)
+		}
+	}
+	if maintainerAccount(cfg) == "" && len(cfg.ExpectedMaintainers) == 0 {
+		return fmt.Errorf("maintainer_check requires maintainer_account or expected_maintainers when publishing with an API token")
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+const packageRolesResponse = `<?xml version='1.0'?>
+<methodResponse>
+<params>
+<param>
+<value><array><data>
+<value><array><data>
+<value><string>Owner</string></value>
+<value><string>alice</string></value>
+</data></array></value>
+<value><array><data>
+<value>Maintainer</value>
+<value>mallory</value>
+</data></array></value>
+</data></array></value>
+</param>
+</params>
+</methodResponse>`
+
+const packageRolesFault = `<?xml version='1.0'?>
+<methodResponse>
+<fault>
+<value><struct>
+<member><name>faultCode</name><value><int>-32500</int></value></member>
+<member><name>faultString</name><value><string>RuntimeError: rate limited</string></value></member>
+</struct></value>
+</fault>
+</methodResponse>`
+
+func TestFetchProjectRoles(t *testing.T) {
+	var call string
+	body := packageRolesResponse
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		call = string(data)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	p := &PyPIPlugin{httpClient: server.Client()}
+
+	roles, err := p.fetchProjectRoles(context.Background(), server.URL, "my<pkg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []projectRole{{Role: "Owner", User: "alice"}, {Role: "Maintainer", User: "mallory"}}
+	if !reflect.DeepEqual(roles, want) {
+		t.Errorf("roles = %+v, want %+v", roles, want)
+	}
+	if !strings.Contains(call, "<methodName>package_roles</methodName>") || !strings.Contains(call, "my&lt;pkg") {
+		t.Errorf("unexpected call %s", call)
+	}
+
+	body = packageRolesFault
+	if _, err := p.fetchProjectRoles(context.Background(), server.URL, "mypkg"); err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("expected the fault to be reported, got %v", err)
+	}
+}
+
+func TestMaintainerFindings(t *testing.T) {
+	roles := []projectRole{{Role: "Owner", User: "alice"}, {Role: "Maintainer", User: "mallory"}}
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{"account is a maintainer", Config{Username: "Alice"}, nil},
+		{"token without account", Config{Username: "__token__"}, nil},
+		{"account is not a maintainer", Config{Username: "__token__", MaintainerAccount: "bob"}, []string{"publishing account bob is not a maintainer of mypkg"}},
+		{"unexpected maintainer", Config{Username: "alice", ExpectedMaintainers: []string{"alice"}}, []string{"unexpected maintainers of mypkg: mallory"}},
+		{"all expected", Config{ExpectedMaintainers: []string{"alice", "Mallory"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maintainerFindings(tt.cfg, "mypkg", roles); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("maintainerFindings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteMaintainerCheck(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(packageRolesResponse))
+	}))
+	defer server.Close()
+
+	uploads := 0
+	p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			uploads++
+			return nil, nil
+		},
+	}}
+	config := map[string]any{
+		"username":             "__token__",
+		"password":             "pypi-token",
+		"repository":           "http://localhost:8080/",
+		"maintainer_check":     "fail",
+		"maintainer_api_url":   server.URL,
+		"expected_maintainers": []any{"alice"},
+	}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "unexpected maintainers of mypkg: mallory") || uploads != 0 {
+		t.Fatalf("expected the maintainer check to block the publish, got %v %+v", err, resp)
+	}
+
+	config["maintainer_check"] = "warn"
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || !resp.Success || uploads != 1 {
+		t.Fatalf("expected the publish to proceed with a warning, got %v %+v", err, resp)
+	}
+	if warnings, _ := resp.Outputs["warnings"].([]string); len(warnings) != 1 || !strings.Contains(warnings[0], "mallory") {
+		t.Errorf("unexpected warnings %v", resp.Outputs["warnings"])
+	}
+}
+
+func TestValidateMaintainerConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"off", Config{MaintainerCheck: checkOff}, false},
+		{"pypi account", Config{MaintainerCheck: checkWarn, Repository: "https://upload.pypi.org/legacy/", MaintainerAPIURL: "https://pypi.org/pypi", Username: "alice"}, false},
+		{"token with expected maintainers", Config{MaintainerCheck: checkFail, Repository: "https://upload.pypi.org/legacy/", MaintainerAPIURL: "https://pypi.org/pypi", Username: "__token__", ExpectedMaintainers: []string{"alice"}}, false},
+		{"token without account", Config{MaintainerCheck: checkFail, Repository: "https://upload.pypi.org/legacy/", MaintainerAPIURL: "https://pypi.org/pypi", Username: "__token__"}, true},
+		{"other repository without api", Config{MaintainerCheck: checkWarn, Repository: "http://localhost:8080/", Username: "alice"}, true},
+		{"invalid mode", Config{MaintainerCheck: "strict"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMaintainerConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateMaintainerConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// manifestFile is an uploaded distribution listed in the publish manifest.
+type manifestFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// manifestPipeline identifies the CI run that published, as reported by the CI environment.
+type manifestPipeline struct {
+	Provider   string `json:"provider"`
+	Repository string `json:"repository,omitempty"`
+	Workflow   string `json:"workflow,omitempty"`
+	RunID      string `json:"run_id,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Commit     string `json:"commit,omitempty"`
+}
+
+// publishManifest records what a publish uploaded and which pipeline uploaded it. It is signed
+// so consumers can verify the origin of a set of artifacts.
+type publishManifest struct {
+	Project     string            `json:"project"`
+	Version     string            `json:"version"`
+	Repository  string            `json:"repository"`
+	Channel     string            `json:"channel"`
+	Files       []manifestFile    `json:"files"`
+	Pipeline    *manifestPipeline `json:"pipeline,omitempty"`
+	PluginBuild string            `json:"plugin_build"`
+	PublishedAt string            `json:"published_at"`
+}
+
+// manifestSignature is the signature of the publish manifest, reported in outputs.
+type manifestSignature struct {
+	Algorithm string `json:"algorithm"`
+	// KeyID is the SHA256 fingerprint of the public key, or the name of a cloud KMS key
+	KeyID string `json:"key_id"`
+	// Signature is the base64 signature of the exact bytes of the publish_manifest output
+	Signature      string `json:"signature"`
+	ManifestSHA256 string `json:"manifest_sha256"`
+	ManifestPath   string `json:"manifest_path,omitempty"`
+	SignaturePath  string `json:"signature_path,omitempty"`
+}
+
+// ciPipelineEnvs are the variables identifying a run of the supported CI providers, detected by
+// the first variable.
+var ciPipelineEnvs = []struct {
+	provider, detect, repository, workflow, runID, url, commit string
+}{
+	{"github-actions", "GITHUB_ACTIONS", "GITHUB_REPOSITORY", "GITHUB_WORKFLOW_REF", "GITHUB_RUN_ID", "", "GITHUB_SHA"},
+	{"gitlab-ci", "GITLAB_CI", "CI_PROJECT_PATH", "CI_JOB_NAME", "CI_PIPELINE_ID", "CI_PIPELINE_URL", "CI_COMMIT_SHA"},
+	{"jenkins", "JENKINS_URL", "GIT_URL", "JOB_NAME", "BUILD_NUMBER", "BUILD_URL", "GIT_COMMIT"},
+	{"circleci", "CIRCLECI", "CIRCLE_REPOSITORY_URL", "CIRCLE_JOB", "CIRCLE_WORKFLOW_ID", "CIRCLE_BUILD_URL", "CIRCLE_SHA1"},
+}
+
+// currentPipeline returns the CI run the plugin is running in, or nil outside CI.
+func currentPipeline() *manifestPipeline {
+	for _, e := range ciPipelineEnvs {
+		if os.Getenv(e.detect) == "" {
+			continue
+		}
+		pipeline := &manifestPipeline{
+			Provider:   e.provider,
+			Repository: os.Getenv(e.repository),
+			Workflow:   os.Getenv(e.workflow),
+			RunID:      os.Getenv(e.runID),
+			Commit:     os.Getenv(e.commit),
+		}
+		if e.url != "" {
+			pipeline.URL = os.Getenv(e.url)
+		} else if server := os.Getenv("GITHUB_SERVER_URL"); server != "" && pipeline.Repository != "" && pipeline.RunID != "" {
+			pipeline.URL = fmt.Sprintf("%s/%s/actions/runs/%s", server, pipeline.Repository, pipeline.RunID)
+		}
+		return pipeline
+	}
+	return nil
+}
+
+// usesManifest reports whether a publish manifest is produced.
+func usesManifest(cfg Config) bool {
+	return cfg.ManifestSigningKey != "" || cfg.ManifestKMSKeyID != "" || cfg.ManifestPath != ""
+}
+
+// buildPublishManifest returns the encoded manifest of uploading files as version.
+func buildPublishManifest(cfg Config, version string, files []string) ([]byte, error) {
+	project, _ := distProjectVersion(files)
+	manifest := publishManifest{
+		Project:     project,
+		Version:     version,
+		Repository:  cfg.Repository,
+		Channel:     releaseChannel(version),
+		Files:       make([]manifestFile, 0, len(files)),
+		Pipeline:    currentPipeline(),
+		PluginBuild: currentBuild().String(),
+		PublishedAt: formatTimestamp(time.Now()),
+	}
+	for _, f := range files {
+		_, digest, size, err := fileDigests(f)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, manifestFile{Name: filepath.Base(f), SHA256: digest, Size: size})
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// manifestKey is a local private key signing manifests.
+type manifestKey struct {
+	signer    crypto.Signer
+	algorithm string
+	// hash is the digest signed, or zero for Ed25519, which signs the message itself
+	hash  crypto.Hash
+	keyID string
+}
+
+// loadManifestKey parses the manifest_signing_key option: an unencrypted PEM private key, or
+// the path of one. Ed25519, ECDSA and RSA keys are supported.
+func loadManifestKey(value string) (*manifestKey, error) {
+	data := []byte(value)
+	if !strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		var err error
+		if data, err = os.ReadFile(value); err != nil { // #nosec G304 -- the configured key file
+			return nil, fmt.Errorf("failed to read manifest_signing_key: %w", err)
+		}
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("manifest_signing_key is not a PEM private key")
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] != "" {
+		return nil, fmt.Errorf("manifest_signing_key must not be encrypted; encrypt the config value with enc: instead")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("manifest_signing_key has unsupported PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest_signing_key: %w", err)
+	}
+
+	k := &manifestKey{}
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		k.signer, k.algorithm = key, "ed25519"
+	case *ecdsa.PrivateKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			k.hash = crypto.SHA256
+		case 384:
+			k.hash = crypto.SHA384
+		default:
+			k.hash = crypto.SHA512
+		}
+		k.signer, k.algorithm = key, "ecdsa-"+strings.ToLower(strings.ReplaceAll(k.hash.String(), "-", ""))
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("manifest_signing_key RSA keys must have at least 2048 bits")
+		}
+		k.signer, k.algorithm, k.hash = key, "rsa-pkcs1v15-sha256", crypto.SHA256
+	default:
+		return nil, fmt.Errorf("manifest_signing_key has an unsupported key type %T", key)
+	}
+	der, err := x509.MarshalPKIXPublicKey(k.signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest_signing_key: %w", err)
+	}
+	fingerprint := sha256.Sum256(der)
+	k.keyID = "sha256:" + hex.EncodeToString(fingerprint[:])
+	return k, nil
+}
+
+// sign signs data with the key.
+func (k *manifestKey) sign(data []byte) ([]byte, error) {
+	if k.hash == 0 {
+		return k.signer.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	h := k.hash.New()
+	h.Write(data)
+	return k.signer.Sign(rand.Reader, h.Sum(nil), k.hash)
+}
+
+// signManifest signs the encoded manifest with the configured key.
+func (p *PyPIPlugin) signManifest(ctx context.Context, cfg Config, data []byte) ([]byte, *manifestSignature, error) {
+	digest := sha256.Sum256(data)
+	signature := &manifestSignature{ManifestSHA256: hex.EncodeToString(digest[:])}
+	var sig []byte
+	if cfg.ManifestKMSKeyID != "" {
+		var err error
+		if sig, signature.KeyID, err = p.signKMS(ctx, cfg, data); err != nil {
+			return nil, nil, err
+		}
+		signature.Algorithm = cfg.ManifestKMSAlgorithm
+	} else {
+		key, err := loadManifestKey(cfg.ManifestSigningKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sig, err = key.sign(data); err != nil {
+			return nil, nil, fmt.Errorf("failed to sign the manifest: %w", err)
+		}
+		signature.Algorithm, signature.KeyID = key.algorithm, key.keyID
+	}
+	signature.Signature = base64.StdEncoding.EncodeToString(sig)
+	return sig, signature, nil
+}
+
+// recordPublishManifest builds the manifest of the uploaded files, signs it when a key is
+// configured and writes both to manifest_path. The manifest is reported in the
+// publish_manifest output as the exact bytes that were signed.
+func (p *PyPIPlugin) recordPublishManifest(ctx context.Context, cfg Config, version string, files []string, result *preflightResult) error {
+	data, err := buildPublishManifest(cfg, version, files)
+	if err != nil {
+		return err
+	}
+	var sig []byte
+	var signature *manifestSignature
+	if cfg.ManifestSigningKey != "" || cfg.ManifestKMSKeyID != "" {
+		if sig, signature, err = p.signManifest(ctx, cfg, data); err != nil {
+			return err
+		}
+	}
+
+	if cfg.ManifestPath != "" {
+		if err := os.WriteFile(cfg.ManifestPath, data, 0o644); err != nil { // #nosec G306 -- the manifest is public
+			return fmt.Errorf("failed to write the manifest: %w", err)
+		}
+		digest := sha256.Sum256(data)
+		result.artifacts = append(result.artifacts, plugin.Artifact{
+			Name:     "publish-manifest",
+			Path:     cfg.ManifestPath,
+			Type:     "file",
+			Size:     int64(len(data)),
+			Checksum: "sha256:" + hex.EncodeToString(digest[:]),
+		})
+		if signature != nil {
+			// The raw signature, as openssl dgst -verify and pkeyutl -verify expect
+			sigPath := cfg.ManifestPath + ".sig"
+			if err := os.WriteFile(sigPath, sig, 0o644); err != nil { // #nosec G306 -- signatures are public
+				return fmt.Errorf("failed to write the manifest signature: %w", err)
+			}
+			signature.ManifestPath, signature.SignaturePath = cfg.ManifestPath, sigPath
+			sigDigest := sha256.Sum256(sig)
+			result.artifacts = append(result.artifacts, plugin.Artifact{
+				Name:     "publish-manifest-signature",
+				Path:     sigPath,
+				Type:     "file",
+				Size:     int64(len(sig)),
+				Checksum: "sha256:" + hex.EncodeToString(sigDigest[:]),
+			})
+		}
+	}
+
+	result.outputs["publish_manifest"] = string(data)
+	if signature != nil {
+		result.outputs["manifest_signature"] = signature
+	}
+	return nil
+}
+
+// validateManifestConfig validates the publish manifest options.
+func validateManifestConfig(cfg Config) error {
+	if cfg.ManifestSigningKey != "" && cfg.ManifestKMSKeyID != "" {
+		return fmt.Errorf("manifest_signing_key and manifest_kms_key_id cannot be combined")
+	}
+	if cfg.ManifestSigningKey != "" {
+		_, err := loadManifestKey(cfg.ManifestSigningKey)
+		return err
+	}
+	return validateKMSConfig(cfg)
+}
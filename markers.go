@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Release marker providers.
+const (
+	markerGrafana  = "grafana"
+	markerDatadog  = "datadog"
+	markerNewRelic = "newrelic"
+)
+
+// markerProviders lists the supported release_markers providers.
+var markerProviders = []string{markerGrafana, markerDatadog, markerNewRelic}
+
+// defaultMarkerEndpoints are the API endpoints of providers with a public service. Grafana is
+// self-hosted or per-stack and has no default.
+var defaultMarkerEndpoints = map[string]string{
+	markerDatadog:  "https://api.datadoghq.com",
+	markerNewRelic: "https://api.newrelic.com/graphql",
+}
+
+// newRelicDeploymentMutation records a deployment with New Relic change tracking.
+const newRelicDeploymentMutation = `mutation($deployment: ChangeTrackingDeploymentInput!) {
+  changeTrackingCreateDeployment(deployment: $deployment) { deploymentId }
+}`
+
+// ReleaseMarker creates an annotation in an observability backend after a successful publish,
+// so dashboards show when a version shipped.
+type ReleaseMarker struct {
+	// Provider is grafana, datadog or newrelic.
+	Provider string
+	// Endpoint is the API base URL (Grafana instance, Datadog site) or the NerdGraph URL.
+	Endpoint string
+	// APIKey authenticates the request.
+	APIKey string
+	// APIKeyEnv names an environment variable holding the API key, used when APIKey is empty.
+	APIKeyEnv string
+	// Tags are added to the marker.
+	Tags []string
+	// DashboardUID limits a Grafana annotation to one dashboard.
+	DashboardUID string
+	// EntityGUID is the New Relic entity the deployment is recorded on.
+	EntityGUID string
+}
+
+// resolvedAPIKey returns the configured API key or the value of APIKeyEnv.
+func (m ReleaseMarker) resolvedAPIKey() string {
+	if m.APIKey != "" {
+		return m.APIKey
+	}
+	if m.APIKeyEnv != "" {
+		return os.Getenv(m.APIKeyEnv)
+	}
+	return ""
+}
+
+// endpoint returns the configured endpoint or the provider's default, without a trailing slash.
+func (m ReleaseMarker) endpoint() string {
+	if m.Endpoint != "" {
+		return strings.TrimSuffix(m.Endpoint, "/")
+	}
+	return defaultMarkerEndpoints[m.Provider]
+}
+
+// releaseEvent describes the published release for release markers.
+type releaseEvent struct {
+	Project    string
+	Version    string
+	Repository string
+	Link       string
+	Time       time.Time
+}
+
+// title is the one-line description of the release.
+func (e releaseEvent) title() string {
+	if e.Project == "" {
+		return "Released version " + e.Version
+	}
+	return fmt.Sprintf("Released %s %s", e.Project, e.Version)
+}
+
+// text is the marker body, with the project page when there is one.
+func (e releaseEvent) text() string {
+	text := fmt.Sprintf("%s to %s", e.title(), e.Repository)
+	if e.Link != "" {
+		text += "\n" + e.Link
+	}
+	return text
+}
+
+// tags returns the marker tags of the release, as key:value pairs when keyed.
+func (e releaseEvent) tags(keyed bool) []string {
+	tags := []string{"release"}
+	if keyed {
+		if e.Project != "" {
+			tags = append(tags, "project:"+e.Project)
+		}
+		return append(tags, "version:"+e.Version)
+	}
+	if e.Project != "" {
+		tags = append(tags, e.Project)
+	}
+	return append(tags, e.Version)
+}
+
+// releaseMarkerResult is the outcome of one release marker, reported in outputs.
+type releaseMarkerResult struct {
+	Provider string `json:"provider"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// newReleaseEvent describes a release of files. The project name is read from the first
+// distribution with readable metadata.
+func newReleaseEvent(cfg Config, version string, files []string) releaseEvent {
+	event := releaseEvent{Version: version, Repository: cfg.Repository, Time: time.Now()}
+	for _, f := range files {
+		if meta, err := readDistMetadata(f); err == nil {
+			event.Project = meta.Name
+			break
+		}
+	}
+	event.Link = releasePageURL(cfg.Repository, event.Project, version)
+	return event
+}
+
+// createReleaseMarkers creates the configured release markers. Failures are reported per
+// marker and never fail the publish, as the release has already shipped.
+func (p *PyPIPlugin) createReleaseMarkers(ctx context.Context, cfg Config, event releaseEvent) []releaseMarkerResult {
+	results := make([]releaseMarkerResult, 0, len(cfg.ReleaseMarkers))
+	for _, m := range cfg.ReleaseMarkers {
+		result := releaseMarkerResult{Provider: m.Provider, Status: "created"}
+		if err := p.createReleaseMarker(ctx, m, event); err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// createReleaseMarker sends one release marker to its provider.
+func (p *PyPIPlugin) createReleaseMarker(ctx context.Context, m ReleaseMarker, event releaseEvent) error {
+	key := m.resolvedAPIKey()
+	switch m.Provider {
+	case markerGrafana:
+		annotation := map[string]any{
+			"time": event.Time.UnixMilli(),
+			"tags": append(event.tags(false), m.Tags...),
+			"text": event.text(),
+		}
+		if m.DashboardUID != "" {
+			annotation["dashboardUID"] = m.DashboardUID
+		}
+		return p.sendJSON(ctx, m.Provider, http.MethodPost, m.endpoint()+"/api/annotations",
+			map[string]string{"Authorization": "Bearer " + key}, annotation, nil)
+	case markerDatadog:
+		return p.sendJSON(ctx, m.Provider, http.MethodPost, m.endpoint()+"/api/v1/events",
+			map[string]string{"DD-API-KEY": key}, map[string]any{
+				"title":         event.title(),
+				"text":          event.text(),
+				"date_happened": event.Time.Unix(),
+				"alert_type":    "info",
+				"tags":          append(event.tags(true), m.Tags...),
+			}, nil)
+	case markerNewRelic:
+		deployment := map[string]any{
+			"entityGuid":  m.EntityGUID,
+			"version":     event.Version,
+			"description": event.text(),
+			"timestamp":   event.Time.UnixMilli(),
+		}
+		if event.Link != "" {
+			deployment["deepLink"] = event.Link
+		}
+		return p.postGraphQL(ctx, m.Provider, m.endpoint(), map[string]string{"API-Key": key},
+			newRelicDeploymentMutation, map[string]any{"deployment": deployment}, nil)
+	default:
+		return fmt.Errorf("unsupported release marker provider %q", m.Provider)
+	}
+}
+
+// parseReleaseMarkers parses the release_markers config list.
+func parseReleaseMarkers(raw any) []ReleaseMarker {
+	items, ok := raw.([]any)
+	if !ok {
+		return nil
+	}
+
+	markers := make([]ReleaseMarker, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		marker := ReleaseMarker{}
+		marker.Provider, _ = m["provider"].(string)
+		marker.Endpoint, _ = m["endpoint"].(string)
+		marker.APIKey, _ = m["api_key"].(string)
+		marker.APIKeyEnv, _ = m["api_key_env"].(string)
+		marker.DashboardUID, _ = m["dashboard_uid"].(string)
+		marker.EntityGUID, _ = m["entity_guid"].(string)
+		if tags, ok := m["tags"].([]any); ok {
+			for _, t := range tags {
+				if s, ok := t.(string); ok {
+					marker.Tags = append(marker.Tags, s)
+				}
+			}
+		}
+		markers = append(markers, marker)
+	}
+	return markers
+}
+
+// validateReleaseMarkers validates the release_markers entries.
+func validateReleaseMarkers(markers []ReleaseMarker) error {
+	for i, m := range markers {
+		if !containsString(markerProviders, m.Provider) {
+			return fmt.Errorf("release_markers[%d]: provider must be one of: %s", i, strings.Join(markerProviders, ", "))
+		}
+		if m.Endpoint == "" && m.Provider == markerGrafana {
+			return fmt.Errorf("release_markers[%d]: endpoint is required for grafana", i)
+		}
+		if m.Endpoint != "" {
+			if err := validateRepositoryURL(m.Endpoint); err != nil {
+				return fmt.Errorf("release_markers[%d]: invalid endpoint: %w", i, err)
+			}
+		}
+		if m.APIKey == "" && m.APIKeyEnv == "" {
+			return fmt.Errorf("release_markers[%d]: api_key or api_key_env is required", i)
+		}
+		if m.Provider == markerNewRelic && m.EntityGUID == "" {
+			return fmt.Errorf("release_markers[%d]: entity_guid is required for newrelic", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestCreateReleaseMarker(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization") + r.Header.Get("DD-API-KEY") + r.Header.Get("API-Key")
+		gotBody = nil
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	}))
+	defer server.Close()
+	p := &PyPIPlugin{httpClient: server.Client()}
+	event := releaseEvent{Project: "mypkg", Version: "1.0.0", Repository: "https://upload.pypi.org/legacy/", Time: time.Unix(1700000000, 0)}
+
+	tests := []struct {
+		marker   ReleaseMarker
+		wantPath string
+		wantAuth string
+		wantKey  string
+	}{
+		{ReleaseMarker{Provider: markerGrafana, Endpoint: server.URL + "/", APIKey: "glsa-key", DashboardUID: "abc"}, "/api/annotations", "Bearer glsa-key", "dashboardUID"},
+		{ReleaseMarker{Provider: markerDatadog, Endpoint: server.URL, APIKey: "dd-key"}, "/api/v1/events", "dd-key", "date_happened"},
+		{ReleaseMarker{Provider: markerNewRelic, Endpoint: server.URL + "/graphql", APIKey: "NRAK-key", EntityGUID: "MXxBUE18"}, "/graphql", "NRAK-key", "variables"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.marker.Provider, func(t *testing.T) {
+			if err := p.createReleaseMarker(context.Background(), tt.marker, event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotPath != tt.wantPath || gotAuth != tt.wantAuth {
+				t.Errorf("got request to %s with key %q", gotPath, gotAuth)
+			}
+			if _, ok := gotBody[tt.wantKey]; !ok {
+				t.Errorf("expected %s in body %v", tt.wantKey, gotBody)
+			}
+		})
+	}
+}
+
+func TestCreateReleaseMarkerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/graphql" {
+			_, _ = w.Write([]byte(`{"errors": [{"message": "entity not found"}]}`))
+			return
+		}
+		http.Error(w, "invalid API key", http.StatusForbidden)
+	}))
+	defer server.Close()
+	p := &PyPIPlugin{httpClient: server.Client()}
+
+	results := p.createReleaseMarkers(context.Background(), Config{ReleaseMarkers: []ReleaseMarker{
+		{Provider: markerDatadog, Endpoint: server.URL, APIKey: "bad"},
+		{Provider: markerNewRelic, Endpoint: server.URL + "/graphql", APIKey: "key", EntityGUID: "missing"},
+	}}, releaseEvent{Version: "1.0.0"})
+	if len(results) != 2 {
+		t.Fatalf("expected two results, got %+v", results)
+	}
+	if results[0].Status != "failed" || !strings.Contains(results[0].Error, "403 Forbidden: invalid API key") {
+		t.Errorf("unexpected datadog result %+v", results[0])
+	}
+	if results[1].Status != "failed" || !strings.Contains(results[1].Error, "entity not found") {
+		t.Errorf("unexpected newrelic result %+v", results[1])
+	}
+}
+
+func TestExecuteReleaseMarkers(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	var text string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		text = body.Text
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("Uploading mypkg-1.0.0-py3-none-any.whl"), nil
+		},
+	}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Context: plugin.ReleaseContext{Version: "v1.0.0"},
+		Config: map[string]any{
+			"username":   "__token__",
+			"password":   "pypi-token",
+			"repository": "http://localhost:8080/",
+			"release_markers": []any{
+				map[string]any{"provider": "grafana", "endpoint": server.URL, "api_key": "glsa-key"},
+			},
+		},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+	markers, _ := resp.Outputs["release_markers"].([]releaseMarkerResult)
+	if len(markers) != 1 || markers[0].Status != "created" {
+		t.Errorf("unexpected release markers %+v", resp.Outputs["release_markers"])
+	}
+	if text != "Released mypkg 1.0.0 to http://localhost:8080/" {
+		t.Errorf("unexpected marker text %q", text)
+	}
+}
+
+func TestReleasePageURL(t *testing.T) {
+	if got := releasePageURL("https://upload.pypi.org/legacy/", "My_Pkg", "1.0.0"); got != "https://pypi.org/project/my-pkg/1.0.0/" {
+		t.Errorf("releasePageURL = %q", got)
+	}
+	if got := releasePageURL("https://test.pypi.org/legacy/", "mypkg", "1.0.0"); got != "https://test.pypi.org/project/mypkg/1.0.0/" {
+		t.Errorf("releasePageURL = %q", got)
+	}
+	if got := releasePageURL("https://pypi.example.com/", "mypkg", "1.0.0"); got != "" {
+		t.Errorf("expected no page for other repositories, got %q", got)
+	}
+}
+
+func TestValidateReleaseMarkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		markers []ReleaseMarker
+		wantErr bool
+	}{
+		{"datadog default endpoint", []ReleaseMarker{{Provider: markerDatadog, APIKeyEnv: "DD_API_KEY"}}, false},
+		{"grafana", []ReleaseMarker{{Provider: markerGrafana, Endpoint: "http://localhost:3000", APIKey: "key"}}, false},
+		{"unknown provider", []ReleaseMarker{{Provider: "splunk", APIKey: "key"}}, true},
+		{"grafana without endpoint", []ReleaseMarker{{Provider: markerGrafana, APIKey: "key"}}, true},
+		{"missing api key", []ReleaseMarker{{Provider: markerDatadog}}, true},
+		{"newrelic without entity", []ReleaseMarker{{Provider: markerNewRelic, APIKey: "key"}}, true},
+		{"plain http endpoint", []ReleaseMarker{{Provider: markerDatadog, Endpoint: "http://dd.example.com", APIKey: "key"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateReleaseMarkers(tt.markers); (err != nil) != tt.wantErr {
+				t.Errorf("validateReleaseMarkers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
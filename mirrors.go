@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// mirrorFile compares the digests of one distribution file across targets.
+type mirrorFile struct {
+	File        string `json:"file"`
+	LocalSHA256 string `json:"local_sha256"`
+	// Targets maps each target index to the digest it serves, or "" when the file is missing
+	Targets map[string]string `json:"targets"`
+}
+
+// mirrorReport is the consistency report of one project version published to several targets.
+type mirrorReport struct {
+	Project    string       `json:"project"`
+	Version    string       `json:"version"`
+	Targets    []string     `json:"targets"`
+	Consistent bool         `json:"consistent"`
+	Files      []mirrorFile `json:"files"`
+	// Inconsistent lists the targets serving different bytes than the local files or none at all
+	Inconsistent []string `json:"inconsistent,omitempty"`
+}
+
+// mirrorGroup collects the packages that published the same project version.
+type mirrorGroup struct {
+	project, version string
+	local            map[string]string
+	targets          []string
+	// configs holds the config of the first package publishing to each target
+	configs map[string]Config
+}
+
+// sortedKeys returns the keys of m in lexical order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mirrorConsistencyMode returns the mirror_consistency_check mode of a batch.
+func mirrorConsistencyMode(raw map[string]any) (string, error) {
+	mode, ok := raw["mirror_consistency_check"].(string)
+	if !ok || mode == "" {
+		return checkWarn, nil
+	}
+	if !containsString(checkModes, mode) {
+		return "", fmt.Errorf("mirror_consistency_check must be one of: %s", strings.Join(checkModes, ", "))
+	}
+	return mode, nil
+}
+
+// groupMirrorTargets groups the published packages by project version and returns the groups
+// that were fanned out to more than one index, sorted by project and version.
+func groupMirrorTargets(configs []Config, results []batchPackageResult) []*mirrorGroup {
+	groups := map[string]*mirrorGroup{}
+	for i, cfg := range configs {
+		if !results[i].Success || cfg.IndexURL == "" {
+			continue
+		}
+		files, _ := expandDistGlob(cfg.DistPath)
+		for _, f := range files {
+			meta, err := readDistMetadata(f)
+			if err != nil {
+				continue
+			}
+			_, digest, _, err := fileDigests(f)
+			if err != nil {
+				continue
+			}
+
+			key := normalizeProjectName(meta.Name) + "==" + meta.Version
+			g, ok := groups[key]
+			if !ok {
+				g = &mirrorGroup{project: meta.Name, version: meta.Version, local: map[string]string{}, configs: map[string]Config{}}
+				groups[key] = g
+			}
+			g.local[filepath.Base(f)] = digest
+			if _, ok := g.configs[cfg.IndexURL]; !ok {
+				g.configs[cfg.IndexURL] = cfg
+				g.targets = append(g.targets, cfg.IndexURL)
+			}
+		}
+	}
+
+	var fannedOut []*mirrorGroup
+	for _, g := range groups {
+		if len(g.targets) > 1 {
+			sort.Strings(g.targets)
+			fannedOut = append(fannedOut, g)
+		}
+	}
+	sort.Slice(fannedOut, func(a, b int) bool {
+		if fannedOut[a].project != fannedOut[b].project {
+			return fannedOut[a].project < fannedOut[b].project
+		}
+		return fannedOut[a].version < fannedOut[b].version
+	})
+	return fannedOut
+}
+
+// checkMirrorConsistency compares the digests every target serves for the fanned-out project
+// versions with the local files. Targets are polled until they list the files, so index lag is
+// not reported as a missing file.
+func (p *PyPIPlugin) checkMirrorConsistency(ctx context.Context, configs []Config, results []batchPackageResult) []mirrorReport {
+	var reports []mirrorReport
+	for _, g := range groupMirrorTargets(configs, results) {
+		names := sortedKeys(g.local)
+		served := make(map[string]map[string]string, len(g.targets))
+		for _, target := range g.targets {
+			cfg := g.configs[target]
+			_ = p.waitForIndexFiles(ctx, target, g.project, names, cfg.DependencyWaitTimeout, cfg.DependencyPollInterval)
+			digests, err := p.indexFileDigests(ctx, target, g.project)
+			if err != nil {
+				digests = map[string]string{}
+			}
+			served[target] = digests
+		}
+
+		report := mirrorReport{Project: g.project, Version: g.version, Targets: g.targets, Consistent: true}
+		flagged := map[string]bool{}
+		for _, name := range names {
+			file := mirrorFile{File: name, LocalSHA256: g.local[name], Targets: map[string]string{}}
+			for _, target := range g.targets {
+				digest := served[target][name]
+				file.Targets[target] = digest
+				if digest != g.local[name] {
+					flagged[target] = true
+				}
+			}
+			report.Files = append(report.Files, file)
+		}
+		for _, target := range g.targets {
+			if flagged[target] {
+				report.Inconsistent = append(report.Inconsistent, target)
+				report.Consistent = false
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// applyMirrorConsistency adds the mirror consistency report to a batch response. In fail
+// mode an inconsistent mirror fails the batch; otherwise it is reported as a warning.
+func applyMirrorConsistency(resp *plugin.ExecuteResponse, reports []mirrorReport, mode string) {
+	if len(reports) == 0 {
+		return
+	}
+	resp.Outputs["mirror_consistency"] = reports
+
+	var problems []string
+	for _, r := range reports {
+		if !r.Consistent {
+			problems = append(problems, fmt.Sprintf("%s %s differs on %s", r.Project, r.Version, strings.Join(r.Inconsistent, ", ")))
+		}
+	}
+	if len(problems) == 0 {
+		return
+	}
+
+	if mode == checkFail {
+		resp.Success = false
+		msg := "mirror consistency check failed: " + strings.Join(problems, "; ")
+		if resp.Error != "" {
+			msg = resp.Error + "; " + msg
+		}
+		resp.Error = msg
+		return
+	}
+	warnings, _ := resp.Outputs["warnings"].([]string)
+	for _, problem := range problems {
+		warnings = append(warnings, "mirror inconsistency: "+problem)
+	}
+	resp.Outputs["warnings"] = warnings
+}
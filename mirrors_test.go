@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// newTestMirror serves a simple index listing file with the given digest.
+func newTestMirror(t *testing.T, file, digest string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/simple/core/" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<a href="/files/%s#sha256=%s">%s</a>`, file, digest, file)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExecuteBatchMirrorConsistency(t *testing.T) {
+	writeDistFiles(t)
+	wheel := filepath.Join("dist", "core-1.0-py3-none-any.whl")
+	writeTestWheel(t, wheel, map[string]string{
+		"core-1.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: core\nVersion: 1.0\n",
+	})
+	data, err := os.ReadFile(wheel)
+	if err != nil {
+		t.Fatalf("failed to read wheel: %v", err)
+	}
+
+	primary := newTestMirror(t, filepath.Base(wheel), sha256Hex(string(data)))
+	stale := newTestMirror(t, filepath.Base(wheel), sha256Hex("rebuilt in between"))
+
+	for _, mode := range []string{checkWarn, checkFail} {
+		t.Run(mode, func(t *testing.T) {
+			p := &PyPIPlugin{
+				httpClient: primary.Client(),
+				cmdExecutor: &MockCommandExecutor{
+					RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+						return []byte("ok"), nil
+					},
+				},
+			}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"username":                 "__token__",
+					"password":                 "pypi-token",
+					"dist_path":                "dist/*",
+					"mirror_consistency_check": mode,
+					"packages": []any{
+						map[string]any{"name": "primary", "repository": "http://localhost:8080/", "index_url": primary.URL + "/simple/"},
+						map[string]any{"name": "mirror", "repository": "http://localhost:8081/", "index_url": stale.URL + "/simple/"},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			reports, _ := resp.Outputs["mirror_consistency"].([]mirrorReport)
+			if len(reports) != 1 || reports[0].Consistent || len(reports[0].Targets) != 2 {
+				t.Fatalf("unexpected report: %+v", reports)
+			}
+			if got := reports[0].Inconsistent; len(got) != 1 || got[0] != stale.URL+"/simple/" {
+				t.Errorf("expected only the stale mirror to be flagged, got %v", got)
+			}
+
+			if mode == checkFail {
+				if resp.Success || !strings.Contains(resp.Error, "mirror consistency check failed") {
+					t.Errorf("expected failure, got success=%v error=%q", resp.Success, resp.Error)
+				}
+				return
+			}
+			warnings, _ := resp.Outputs["warnings"].([]string)
+			if !resp.Success || len(warnings) != 1 {
+				t.Errorf("expected success with a warning, got success=%v warnings=%v", resp.Success, warnings)
+			}
+		})
+	}
+}
+
+func TestGroupMirrorTargetsSkipsSingleTarget(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "core-1.0-py3-none-any.whl"), map[string]string{
+		"core-1.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: core\nVersion: 1.0\n",
+	})
+
+	cfg := (&PyPIPlugin{}).parseConfig(map[string]any{"index_url": "https://pypi.example.com/simple/"})
+	groups := groupMirrorTargets([]Config{cfg, cfg}, []batchPackageResult{{Success: true}, {Success: true}})
+	if len(groups) != 0 {
+		t.Errorf("expected no fan-out for a single target, got %d groups", len(groups))
+	}
+}
+
+func TestMirrorConsistencyMode(t *testing.T) {
+	if mode, err := mirrorConsistencyMode(map[string]any{}); err != nil || mode != checkWarn {
+		t.Errorf("expected warn by default, got %q, %v", mode, err)
+	}
+	if _, err := mirrorConsistencyMode(map[string]any{"mirror_consistency_check": "strict"}); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"crypto/md5" // #nosec G501 -- the legacy upload API still expects an MD5 digest field
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// distribution describes a single distribution file to upload via the legacy upload API.
+type distribution struct {
+	// Path is the local path of the distribution file.
+	Path string
+	// Name is the project name.
+	Name string
+	// Version is the project version.
+	Version string
+	// Filetype is the legacy API file type ("sdist" or "bdist_wheel").
+	Filetype string
+	// PyVersion is the Python tag ("source" for sdists).
+	PyVersion string
+	// MetadataVersion is the core metadata version of the distribution.
+	MetadataVersion string
+	// Metadata is the core metadata sent with the upload, or nil to send only the fields above.
+	Metadata *packageMetadata
+	// SignaturePath is an ASCII-armored detached GPG signature sent as gpg_signature, or "".
+	SignaturePath string
+	// AttestationPath is a PEP 740 attestation sent in the attestations field, or "".
+	AttestationPath string
+}
+
+// Authentication schemes accepted by the auth_scheme option.
+const (
+	authSchemeBasic  = "basic"
+	authSchemeBearer = "bearer"
+	// authSchemeNone sends the password as the raw header value, for API key headers
+	authSchemeNone = "none"
+)
+
+// authSchemes lists the supported auth_scheme values.
+var authSchemes = []string{authSchemeBasic, authSchemeBearer, authSchemeNone, authSchemeSigV4}
+
+// Upload backends accepted by the upload_backend option. auto uploads with twine unless the
+// authentication or connection options need the native uploader.
+const (
+	uploadBackendAuto   = "auto"
+	uploadBackendTwine  = "twine"
+	uploadBackendNative = "native"
+)
+
+// uploadBackends lists the supported upload_backend values.
+var uploadBackends = []string{uploadBackendAuto, uploadBackendTwine, uploadBackendNative}
+
+// defaultAuthHeader is the header carrying credentials unless auth_header names another.
+const defaultAuthHeader = "Authorization"
+
+// usesBasicAuth reports whether credentials are sent as username and password.
+func usesBasicAuth(cfg Config) bool {
+	return cfg.AuthScheme == "" || cfg.AuthScheme == authSchemeBasic
+}
+
+// usesNativeAuth reports whether the credentials must be sent in a way twine does not support
+// (anything but basic auth in the Authorization header, or no credentials besides a SPIFFE
+// identity), so uploads use the native uploader.
+func usesNativeAuth(cfg Config) bool {
+	return !usesBasicAuth(cfg) ||
+		(cfg.AuthHeader != "" && !strings.EqualFold(cfg.AuthHeader, defaultAuthHeader)) ||
+		(cfg.SpiffeWorkloadAPI && cfg.Password == "")
+}
+
+// headerNamePattern matches valid HTTP header field names (RFC 9110 tokens).
+var headerNamePattern = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// validateAuthConfig validates the auth_scheme and auth_header options.
+func validateAuthConfig(cfg Config) error {
+	if cfg.AuthScheme != "" && !containsString(authSchemes, cfg.AuthScheme) {
+		return fmt.Errorf("auth_scheme must be one of: %s", strings.Join(authSchemes, ", "))
+	}
+	if cfg.AuthHeader != "" && !headerNamePattern.MatchString(cfg.AuthHeader) {
+		return fmt.Errorf("auth_header %q is not a valid header name", cfg.AuthHeader)
+	}
+	return nil
+}
+
+// validateUploadBackend validates the upload_backend option.
+func validateUploadBackend(cfg Config) error {
+	if cfg.UploadBackend != "" && !containsString(uploadBackends, cfg.UploadBackend) {
+		return fmt.Errorf("upload_backend must be one of: %s", strings.Join(uploadBackends, ", "))
+	}
+	if cfg.UploadBackend == uploadBackendTwine && requiresNativeUploader(cfg) {
+		return fmt.Errorf("upload_backend twine cannot be combined with auth or connection options of the built-in uploader")
+	}
+	return nil
+}
+
+// nativeUploader uploads distributions using the PyPI legacy upload API directly.
+type nativeUploader struct {
+	client     *http.Client
+	repository string
+	username   string
+	password   string
+	authScheme string
+	authHeader string
+	awsRegion  string
+	awsService string
+	// maxErrorBody caps the response body quoted in upload errors
+	maxErrorBody int
+	// notices collects the deprecation and brownout notices of the index responses
+	notices []indexNotice
+}
+
+// newNativeUploader creates an uploader for the configured repository and credentials.
+func newNativeUploader(client *http.Client, cfg Config) *nativeUploader {
+	return &nativeUploader{
+		client:     client,
+		repository: uploadRequestURL(cfg.Repository),
+		username:   cfg.Username,
+		password:   cfg.Password,
+		authScheme: cfg.AuthScheme,
+		authHeader: cfg.AuthHeader,
+		awsRegion:  cfg.AWSRegion,
+		awsService: cfg.AWSService,
+
+		maxErrorBody: cfg.MaxErrorBodyBytes,
+	}
+}
+
+// setAuth adds the credentials to req according to the configured scheme and header.
+func (u *nativeUploader) setAuth(req *http.Request) {
+	if u.authScheme == authSchemeSigV4 || (u.username == "" && u.password == "") {
+		// SigV4 requests are signed once the body is hashed; without credentials the client
+		// certificate alone authenticates
+		return
+	}
+	header := u.authHeader
+	if header == "" {
+		header = defaultAuthHeader
+	}
+	switch u.authScheme {
+	case authSchemeBearer:
+		req.Header.Set(header, "Bearer "+u.password)
+	case authSchemeNone:
+		req.Header.Set(header, u.password)
+	default:
+		creds := base64.StdEncoding.EncodeToString([]byte(u.username + ":" + u.password))
+		req.Header.Set(header, "Basic "+creds)
+	}
+}
+
+// upload sends a distribution to the repository and returns the number of bytes uploaded.
+func (u *nativeUploader) upload(ctx context.Context, dist distribution) (int64, error) {
+	md5Digest, sha256Digest, size, err := fileDigests(dist.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	body, contentType := multipartUploadBody(dist, md5Digest, sha256Digest)
+
+	// SigV4 signs the payload hash, so the streamed body is spooled to disk first
+	var payloadHash string
+	var bodySize int64
+	if u.authScheme == authSchemeSigV4 {
+		spooled, hash, n, cleanup, err := spoolBody(body)
+		if err != nil {
+			return 0, err
+		}
+		defer cleanup()
+		body, payloadHash, bodySize = spooled, hash, n
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.repository, body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	u.setAuth(req)
+	if u.authScheme == authSchemeSigV4 {
+		creds, err := loadAWSCredentials()
+		if err != nil {
+			return 0, err
+		}
+		req.ContentLength = bodySize
+		signSigV4(req, payloadHash, creds, u.awsRegion, u.awsService, time.Now())
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("upload request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	limit := u.maxErrorBody
+	if limit <= 0 {
+		limit = defaultMaxErrorBodyBytes
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, int64(limit)))
+	_, _ = io.Copy(io.Discard, resp.Body)
+	u.notices = append(u.notices, noticesFromResponse(resp, string(msg))...)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Worded like twine's errors so upload failures are classified the same way
+		return 0, fmt.Errorf("upload of %s rejected: HTTPError: %s: %s", filepath.Base(dist.Path), resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return size, nil
+}
+
+// distributionForFile describes a built wheel or sdist for the legacy upload API.
+func distributionForFile(path string) (distribution, error) {
+	meta, err := readDistMetadata(path)
+	if err != nil {
+		return distribution{}, fmt.Errorf("failed to read metadata of %s: %w", filepath.Base(path), err)
+	}
+
+	dist := distribution{
+		Path:            path,
+		Name:            meta.Name,
+		Version:         meta.Version,
+		Filetype:        "sdist",
+		PyVersion:       "source",
+		MetadataVersion: meta.MetadataVersion,
+		Metadata:        meta,
+	}
+	if strings.HasSuffix(path, ".whl") {
+		// {name}-{version}(-{build})?-{python tag}-{abi tag}-{platform tag}.whl
+		parts := strings.Split(strings.TrimSuffix(filepath.Base(path), ".whl"), "-")
+		if len(parts) < 5 {
+			return distribution{}, fmt.Errorf("invalid wheel file name: %s", filepath.Base(path))
+		}
+		dist.Filetype = "bdist_wheel"
+		dist.PyVersion = parts[len(parts)-3]
+	}
+	return dist, nil
+}
+
+// multipartUploadBody streams the legacy upload form for dist without buffering the file in memory.
+func multipartUploadBody(dist distribution, md5Digest, sha256Digest string) (io.Reader, string) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		fields := [][2]string{
+			{":action", "file_upload"},
+			{"protocol_version", "1"},
+			{"metadata_version", dist.MetadataVersion},
+			{"name", dist.Name},
+			{"version", dist.Version},
+			{"filetype", dist.Filetype},
+			{"pyversion", dist.PyVersion},
+			{"md5_digest", md5Digest},
+			{"sha256_digest", sha256Digest},
+		}
+		fields = append(fields, metadataFields(dist.Metadata)...)
+		for _, f := range fields {
+			if err := mw.WriteField(f[0], f[1]); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		if dist.AttestationPath != "" {
+			attestations, err := attestationsField(dist.AttestationPath)
+			if err == nil {
+				err = mw.WriteField("attestations", attestations)
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+
+		part, err := mw.CreateFormFile("content", filepath.Base(dist.Path))
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		f, err := os.Open(dist.Path)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		defer func() { _ = f.Close() }()
+
+		if _, err := io.Copy(part, f); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		if dist.SignaturePath != "" {
+			sig, err := os.ReadFile(dist.SignaturePath)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			part, err := mw.CreateFormFile("gpg_signature", filepath.Base(dist.SignaturePath))
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := part.Write(sig); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+
+		pw.CloseWithError(mw.Close())
+	}()
+
+	return pr, mw.FormDataContentType()
+}
+
+// metadataFields returns the core metadata fields of the upload form, as twine sends them: one
+// field per value of multiple-use fields, and none for fields the metadata leaves out.
+func metadataFields(meta *packageMetadata) [][2]string {
+	if meta == nil {
+		return nil
+	}
+	var fields [][2]string
+	for _, f := range []struct {
+		name   string
+		values []string
+	}{
+		{"summary", []string{meta.Summary}},
+		{"home_page", []string{meta.HomePage}},
+		{"download_url", []string{meta.DownloadURL}},
+		{"author", []string{meta.Author}},
+		{"author_email", []string{meta.AuthorEmail}},
+		{"maintainer", []string{meta.Maintainer}},
+		{"maintainer_email", []string{meta.MaintainerEmail}},
+		{"license", []string{meta.License}},
+		{"license_expression", []string{meta.LicenseExpression}},
+		{"license_file", meta.LicenseFiles},
+		{"description", []string{meta.Description}},
+		{"description_content_type", []string{meta.DescriptionContentType}},
+		{"keywords", []string{meta.Keywords}},
+		{"platform", meta.Platforms},
+		{"supported_platform", meta.SupportedPlatforms},
+		{"classifiers", meta.Classifiers},
+		{"project_urls", meta.ProjectURLs},
+		{"requires_python", []string{meta.RequiresPython}},
+		{"requires_dist", meta.RequiresDist},
+		{"requires_external", meta.RequiresExternal},
+		{"provides_dist", meta.ProvidesDist},
+		{"obsoletes_dist", meta.ObsoletesDist},
+		{"provides_extra", meta.ProvidesExtra},
+		{"dynamic", meta.Dynamic},
+	} {
+		for _, v := range f.values {
+			if v != "" {
+				fields = append(fields, [2]string{f.name, v})
+			}
+		}
+	}
+	return fields
+}
+
+// fileDigests returns the hex MD5 and SHA256 digests and the size of a file.
+func fileDigests(path string) (string, string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to open distribution: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	md5Hash := md5.New() // #nosec G401 -- required by the legacy upload API, not used for security
+	sha256Hash := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), f)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to read distribution: %w", err)
+	}
+
+	return hex.EncodeToString(md5Hash.Sum(nil)), hex.EncodeToString(sha256Hash.Sum(nil)), size, nil
+}
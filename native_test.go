@@ -0,0 +1,441 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestNativeUploaderUpload(t *testing.T) {
+	content := []byte("fake wheel content")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mypkg-1.0.0-py3-none-any.whl")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("failed to write dist: %v", err)
+	}
+	sum := sha256.Sum256(content)
+	expectedSHA := hex.EncodeToString(sum[:])
+
+	var gotFields map[string]string
+	var gotContent []byte
+	var gotUser, gotPass string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotPass, _ = r.BasicAuth()
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("failed to parse multipart form: %v", err)
+			return
+		}
+		gotFields = map[string]string{}
+		for k, v := range r.MultipartForm.Value {
+			gotFields[k] = v[0]
+		}
+		f, _, err := r.FormFile("content")
+		if err != nil {
+			t.Errorf("missing content: %v", err)
+			return
+		}
+		gotContent, _ = io.ReadAll(f)
+	}))
+	defer server.Close()
+
+	u := newNativeUploader(server.Client(), Config{
+		Repository: server.URL,
+		Username:   "__token__",
+		Password:   "pypi-secret",
+	})
+
+	n, err := u.upload(context.Background(), distribution{
+		Path:            path,
+		Name:            "mypkg",
+		Version:         "1.0.0",
+		Filetype:        "bdist_wheel",
+		PyVersion:       "py3",
+		MetadataVersion: "2.1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n != int64(len(content)) {
+		t.Errorf("expected %d bytes uploaded, got %d", len(content), n)
+	}
+	if gotUser != "__token__" || gotPass != "pypi-secret" {
+		t.Errorf("unexpected basic auth: %s/%s", gotUser, gotPass)
+	}
+	if string(gotContent) != string(content) {
+		t.Errorf("unexpected uploaded content: %q", gotContent)
+	}
+
+	expectedFields := map[string]string{
+		":action":          "file_upload",
+		"protocol_version": "1",
+		"name":             "mypkg",
+		"version":          "1.0.0",
+		"filetype":         "bdist_wheel",
+		"pyversion":        "py3",
+		"metadata_version": "2.1",
+		"sha256_digest":    expectedSHA,
+	}
+	for k, v := range expectedFields {
+		if gotFields[k] != v {
+			t.Errorf("field %s: expected '%s', got '%s'", k, v, gotFields[k])
+		}
+	}
+	if gotFields["md5_digest"] == "" {
+		t.Error("expected md5_digest field")
+	}
+}
+
+func TestNativeUploaderSendsCoreMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mypkg-1.0.0-py3-none-any.whl")
+	writeTestWheel(t, path, map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": `Metadata-Version: 2.1
+Name: mypkg
+Version: 1.0.0
+Summary: A package
+Author: Jane Doe
+Author-email: jane@example.com
+License: MIT
+Requires-Python: >=3.9
+Requires-Dist: requests>=2
+Requires-Dist: click; extra == "cli"
+Provides-Extra: cli
+Classifier: Programming Language :: Python :: 3
+Classifier: License :: OSI Approved :: MIT License
+Project-URL: Homepage, https://example.com
+Project-URL: Source, https://example.com/src
+Description-Content-Type: text/markdown
+
+# mypkg
+`,
+	})
+
+	var got map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("failed to parse multipart form: %v", err)
+			return
+		}
+		got = r.MultipartForm.Value
+	}))
+	defer server.Close()
+
+	dist, err := distributionForFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newNativeUploader(server.Client(), Config{Repository: server.URL, Username: "__token__", Password: "pypi-secret"})
+	if _, err := u.upload(context.Background(), dist); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for field, want := range map[string][]string{
+		"summary":                  {"A package"},
+		"author":                   {"Jane Doe"},
+		"author_email":             {"jane@example.com"},
+		"license":                  {"MIT"},
+		"requires_python":          {">=3.9"},
+		"requires_dist":            {"requests>=2", `click; extra == "cli"`},
+		"provides_extra":           {"cli"},
+		"classifiers":              {"Programming Language :: Python :: 3", "License :: OSI Approved :: MIT License"},
+		"project_urls":             {"Homepage, https://example.com", "Source, https://example.com/src"},
+		"description":              {"# mypkg"},
+		"description_content_type": {"text/markdown"},
+	} {
+		if !reflect.DeepEqual(got[field], want) {
+			t.Errorf("field %s: expected %q, got %q", field, want, got[field])
+		}
+	}
+	if _, ok := got["maintainer"]; ok {
+		t.Error("expected no field for metadata the package leaves out")
+	}
+}
+
+func TestNativeUploaderRejected(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mypkg-1.0.0.tar.gz")
+	if err := os.WriteFile(path, []byte("sdist"), 0o600); err != nil {
+		t.Fatalf("failed to write dist: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "File already exists", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	u := newNativeUploader(server.Client(), Config{Repository: server.URL})
+	_, err := u.upload(context.Background(), distribution{Path: path, Name: "mypkg", Version: "1.0.0", Filetype: "sdist", PyVersion: "source"})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "File already exists") {
+		t.Errorf("expected status and body in error, got '%s'", err.Error())
+	}
+}
+
+func TestNativeUploaderMissingFile(t *testing.T) {
+	u := newNativeUploader(http.DefaultClient, Config{Repository: "http://localhost:1/"})
+	_, err := u.upload(context.Background(), distribution{Path: filepath.Join(t.TempDir(), "missing.whl")})
+	if err == nil || !strings.Contains(err.Error(), "failed to open distribution") {
+		t.Errorf("expected open error, got %v", err)
+	}
+}
+
+func TestNativeUploaderAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        Config
+		header     string
+		wantHeader string
+	}{
+		{"basic", Config{Username: "user", Password: "pass"}, "Authorization", "Basic dXNlcjpwYXNz"},
+		{"bearer", Config{Password: "tok", AuthScheme: authSchemeBearer}, "Authorization", "Bearer tok"},
+		{"api key header", Config{Password: "key", AuthScheme: authSchemeNone, AuthHeader: "X-Api-Key"}, "X-Api-Key", "key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://localhost/", nil)
+			newNativeUploader(http.DefaultClient, tt.cfg).setAuth(req)
+			if got := req.Header.Get(tt.header); got != tt.wantHeader {
+				t.Errorf("%s = %q, want %q", tt.header, got, tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestDistributionForFile(t *testing.T) {
+	writeDistFiles(t)
+	metadata := "Metadata-Version: 2.3\nName: mypkg\nVersion: 1.0.0\n"
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-cp312-cp312-manylinux_2_17_x86_64.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": metadata,
+	})
+	writeTestSdist(t, filepath.Join("dist", "mypkg-1.0.0.tar.gz"), map[string]string{
+		"mypkg-1.0.0/PKG-INFO": metadata,
+	})
+
+	wheel, err := distributionForFile(filepath.Join("dist", "mypkg-1.0.0-cp312-cp312-manylinux_2_17_x86_64.whl"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if wheel.Filetype != "bdist_wheel" || wheel.PyVersion != "cp312" || wheel.MetadataVersion != "2.3" || wheel.Name != "mypkg" {
+		t.Errorf("unexpected wheel distribution: %+v", wheel)
+	}
+
+	sdist, err := distributionForFile(filepath.Join("dist", "mypkg-1.0.0.tar.gz"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sdist.Filetype != "sdist" || sdist.PyVersion != "source" || sdist.Version != "1.0.0" {
+		t.Errorf("unexpected sdist distribution: %+v", sdist)
+	}
+}
+
+func TestExecuteBearerAuthUsesNativeUploader(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+
+	var gotAuth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = append(gotAuth, r.Header.Get("Authorization"))
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+
+	mockExecutor := &MockCommandExecutor{}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor, httpClient: server.Client()}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"password":    "private-token",
+			"auth_scheme": "bearer",
+			"repository":  server.URL,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got: %s", resp.Error)
+	}
+	if len(mockExecutor.RunCalls) != 0 {
+		t.Errorf("twine must not run for bearer auth, got %d calls", len(mockExecutor.RunCalls))
+	}
+	if len(gotAuth) != 1 || gotAuth[0] != "Bearer private-token" {
+		t.Errorf("unexpected Authorization headers: %v", gotAuth)
+	}
+	if !strings.Contains(resp.Outputs["output"].(string), "Uploading mypkg-1.0.0-py3-none-any.whl") {
+		t.Errorf("unexpected output: %v", resp.Outputs["output"])
+	}
+}
+
+func TestExecuteBearerAuthRefreshesRejectedToken(t *testing.T) {
+	for _, concurrency := range []int{1, 2} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			writeDistFiles(t)
+			for _, platform := range []string{"linux_x86_64", "macosx_11_0_arm64"} {
+				writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-cp312-cp312-"+platform+".whl"), map[string]string{
+					"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+					"mypkg/_platform.txt":            platform,
+				})
+			}
+
+			// The first token is revoked before the second file is uploaded
+			var mu sync.Mutex
+			var accepted []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				mu.Lock()
+				defer mu.Unlock()
+				if auth := r.Header.Get("Authorization"); auth != "Bearer token-2" && (auth != "Bearer token-1" || len(accepted) > 0) {
+					http.Error(w, "Invalid or non-existent authentication information.", http.StatusForbidden)
+					return
+				}
+				accepted = append(accepted, r.Header.Get("Authorization"))
+			}))
+			defer server.Close()
+
+			var tokens int
+			executor := &MockCommandExecutor{RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				tokens++
+				return []byte(fmt.Sprintf("token-%d", tokens)), nil
+			}}
+			p := &PyPIPlugin{cmdExecutor: executor, httpClient: server.Client()}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"auth_scheme":    "bearer",
+					"token_command":  []any{"get-token"},
+					"repository":     server.URL,
+					"upload_backend": "native",
+					"concurrency":    concurrency,
+				},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected success, got %v %+v", err, resp)
+			}
+			if len(accepted) != 2 || tokens != 2 {
+				t.Errorf("expected a retry with a refreshed token, got %d token fetches and uploads %v", tokens, accepted)
+			}
+			if resp.Outputs["token_refreshes"] != 1 {
+				t.Errorf("expected token_refreshes 1, got %v", resp.Outputs["token_refreshes"])
+			}
+		})
+	}
+}
+
+func TestExecuteNativeUploadBackend(t *testing.T) {
+	writeDistFiles(t)
+	wheel := filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl")
+	writeTestWheel(t, wheel, map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	_, digest, _, err := fileDigests(wheel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := http.StatusOK
+	var fields []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "__token__" || pass != "pypi-token" {
+			t.Errorf("unexpected credentials %q %q", user, pass)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("invalid upload: %v", err)
+		}
+		fields = append(fields, map[string]string{
+			":action":       r.FormValue(":action"),
+			"name":          r.FormValue("name"),
+			"version":       r.FormValue("version"),
+			"sha256_digest": r.FormValue("sha256_digest"),
+		})
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	mockExecutor := &MockCommandExecutor{}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor, httpClient: server.Client()}
+	config := map[string]any{
+		"username":       "__token__",
+		"password":       "pypi-token",
+		"repository":     server.URL,
+		"upload_backend": "native",
+	}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "v1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+	if len(mockExecutor.RunCalls) != 0 {
+		t.Errorf("twine must not run with upload_backend native, got %d calls", len(mockExecutor.RunCalls))
+	}
+	want := map[string]string{":action": "file_upload", "name": "mypkg", "version": "1.0.0", "sha256_digest": digest}
+	if len(fields) != 1 || !reflect.DeepEqual(fields[0], want) {
+		t.Errorf("upload fields = %v, want %v", fields, want)
+	}
+	for _, key := range []string{"repository", "dist_path", "version", "output", "plugin_build", "upload_timings"} {
+		if _, ok := resp.Outputs[key]; !ok {
+			t.Errorf("missing output %s", key)
+		}
+	}
+
+	status = http.StatusBadRequest
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "native upload failed") {
+		t.Fatalf("expected the native upload to fail, got %v %+v", err, resp)
+	}
+}
+
+func TestValidateUploadBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"auto", Config{UploadBackend: uploadBackendAuto, AuthScheme: authSchemeBearer}, false},
+		{"native", Config{UploadBackend: uploadBackendNative}, false},
+		{"twine", Config{UploadBackend: uploadBackendTwine}, false},
+		{"twine with bearer auth", Config{UploadBackend: uploadBackendTwine, AuthScheme: authSchemeBearer}, true},
+		{"twine with unix socket", Config{UploadBackend: uploadBackendTwine, Repository: "http+unix://%2Frun%2Fpypi.sock/legacy/"}, true},
+		{"unknown", Config{UploadBackend: "poetry"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateUploadBackend(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateUploadBackend() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAuthConfig(t *testing.T) {
+	p := &PyPIPlugin{}
+	for _, raw := range []map[string]any{
+		{"auth_scheme": "digest"},
+		{"auth_header": "X Api Key"},
+	} {
+		if err := validateAuthConfig(p.parseConfig(raw)); err == nil {
+			t.Errorf("expected error for %v", raw)
+		}
+	}
+	if err := validateAuthConfig(p.parseConfig(map[string]any{"auth_scheme": "none", "auth_header": "X-JFrog-Art-Api"})); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
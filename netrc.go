@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// netrcEntry is a machine (or default) entry of a netrc file.
+type netrcEntry struct {
+	Machine  string
+	Login    string
+	Password string
+}
+
+// netrcPath returns the netrc file consulted for credentials: $NETRC, or ~/.netrc
+// (~/_netrc when only that exists, as on Windows), as curl and requests do.
+func netrcPath() (string, error) {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	p := filepath.Join(home, ".netrc")
+	if _, err := os.Stat(p); err != nil {
+		if alt := filepath.Join(home, "_netrc"); fileExists(alt) {
+			return alt, nil
+		}
+	}
+	return p, nil
+}
+
+// fileExists reports whether path names an existing file.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// parseNetrc parses netrc entries. Macro definitions are skipped; the "default" entry is
+// returned with an empty Machine.
+func parseNetrc(data string) []netrcEntry {
+	var entries []netrcEntry
+	var current *netrcEntry
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	inMacro := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			// A macro definition ends at the first empty line
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			next := func() string {
+				if i+1 < len(fields) {
+					i++
+					return fields[i]
+				}
+				return ""
+			}
+			switch fields[i] {
+			case "machine":
+				entries = append(entries, netrcEntry{Machine: next()})
+				current = &entries[len(entries)-1]
+			case "default":
+				entries = append(entries, netrcEntry{})
+				current = &entries[len(entries)-1]
+			case "login":
+				if v := next(); current != nil {
+					current.Login = v
+				}
+			case "password":
+				if v := next(); current != nil {
+					current.Password = v
+				}
+			case "account":
+				next()
+			case "macdef":
+				inMacro = true
+				i = len(fields)
+			}
+		}
+	}
+	return entries
+}
+
+// netrcCredentials returns the login and password of the netrc entry for the repository host,
+// falling back to the default entry. ok is false when the file or a matching entry is missing.
+func netrcCredentials(repository string) (login, password string, ok bool) {
+	u, err := url.Parse(repository)
+	if err != nil || u.Hostname() == "" {
+		return "", "", false
+	}
+	path, err := netrcPath()
+	if err != nil {
+		return "", "", false
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- user-controlled credential file, read only when opted in
+	if err != nil {
+		return "", "", false
+	}
+
+	var fallback *netrcEntry
+	entries := parseNetrc(string(data))
+	for i, e := range entries {
+		switch {
+		case e.Machine == "" && fallback == nil:
+			fallback = &entries[i]
+		case strings.EqualFold(e.Machine, u.Hostname()):
+			return e.Login, e.Password, true
+		}
+	}
+	if fallback != nil {
+		return fallback.Login, fallback.Password, true
+	}
+	return "", "", false
+}
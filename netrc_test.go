@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testNetrc = `# CI credentials
+machine upload.pypi.org login __token__ password pypi-token
+machine packages.example.com
+  login deploy
+  account ignored
+  password s3cret
+
+macdef init
+  cd /pub
+  machine not-a-machine
+
+default login anonymous password guest
+`
+
+func TestParseNetrc(t *testing.T) {
+	entries := parseNetrc(testNetrc)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+	if entries[1] != (netrcEntry{Machine: "packages.example.com", Login: "deploy", Password: "s3cret"}) {
+		t.Errorf("unexpected multi-line entry: %+v", entries[1])
+	}
+	if entries[2] != (netrcEntry{Login: "anonymous", Password: "guest"}) {
+		t.Errorf("unexpected default entry: %+v", entries[2])
+	}
+}
+
+func TestParseConfigNetrc(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netrc")
+	if err := os.WriteFile(path, []byte(testNetrc), 0o600); err != nil {
+		t.Fatalf("failed to write netrc: %v", err)
+	}
+	t.Setenv("NETRC", path)
+	t.Setenv("PYPI_USERNAME", "")
+	t.Setenv("PYPI_PASSWORD", "")
+
+	p := &PyPIPlugin{}
+	tests := []struct {
+		name         string
+		raw          map[string]any
+		wantUsername string
+		wantPassword string
+	}{
+		{"disabled", map[string]any{}, "", ""},
+		{"pypi", map[string]any{"use_netrc": true}, "__token__", "pypi-token"},
+		{"host match", map[string]any{"use_netrc": true, "repository": "https://PACKAGES.example.com/upload/"}, "deploy", "s3cret"},
+		{"default entry", map[string]any{"use_netrc": true, "repository": "https://other.example.com/"}, "anonymous", "guest"},
+		{"config wins", map[string]any{"use_netrc": true, "password": "explicit"}, "__token__", "explicit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := p.parseConfig(tt.raw)
+			if cfg.Username != tt.wantUsername || cfg.Password != tt.wantPassword {
+				t.Errorf("credentials = %q/%q, want %q/%q", cfg.Username, cfg.Password, tt.wantUsername, tt.wantPassword)
+			}
+		})
+	}
+}
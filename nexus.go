@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Nexus staging statuses reported in outputs, following the Maven staging lifecycle.
+const (
+	stagingOpen     = "open"
+	stagingClosed   = "closed"
+	stagingReleased = "released"
+	stagingDropped  = "dropped"
+)
+
+// nexusRepositoryNamePattern matches Nexus repository names.
+var nexusRepositoryNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// nexusStaging is a release staged in a Nexus Repository Pro hosted repository. A tag plays
+// the part of a Maven staging repository: it is created before the upload, associated with the
+// uploaded components when the staging is closed, and moved to the destination on release.
+type nexusStaging struct {
+	NexusURL    string `json:"nexus_url"`
+	Repository  string `json:"staging_repository"`
+	Destination string `json:"destination"`
+	Tag         string `json:"tag"`
+	Project     string `json:"project,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+
+	headers map[string]string
+}
+
+// nexusComponent is a component of the Nexus search API.
+type nexusComponent struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Assets  []struct {
+		Path     string `json:"path"`
+		Checksum struct {
+			SHA256 string `json:"sha256"`
+		} `json:"checksum"`
+	} `json:"assets"`
+}
+
+// newNexusStaging describes a staging of the configured repository, which must be a Nexus
+// hosted repository URL (.../repository/<name>/). The Nexus URL defaults to the part of the
+// repository URL before /repository/.
+func newNexusStaging(cfg Config, tag string) (*nexusStaging, error) {
+	u, err := url.Parse(cfg.Repository)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository URL: %w", err)
+	}
+	prefix, rest, ok := strings.Cut(u.Path, "/repository/")
+	name, _, _ := strings.Cut(rest, "/")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("nexus staging requires a Nexus repository URL ending in /repository/<name>/")
+	}
+
+	nexusURL := cfg.NexusURL
+	if nexusURL == "" {
+		nexusURL = u.Scheme + "://" + u.Host + prefix
+	}
+	return &nexusStaging{
+		NexusURL:    strings.TrimSuffix(nexusURL, "/"),
+		Repository:  name,
+		Destination: cfg.NexusStagingDestination,
+		Tag:         tag,
+		Status:      stagingOpen,
+		headers: map[string]string{
+			"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(cfg.Username+":"+cfg.Password)),
+		},
+	}, nil
+}
+
+// nexusStagingTag returns a unique tag for a staging of project and version.
+func nexusStagingTag(project, version string, now time.Time) string {
+	return fmt.Sprintf("pypi-%s-%s-%s", normalizeProjectName(project), versionSlug(version), now.UTC().Format("20060102T150405Z"))
+}
+
+// api returns the URL of a Nexus REST endpoint.
+func (s *nexusStaging) api(endpoint string, query url.Values) string {
+	target := s.NexusURL + "/service/rest/v1/" + endpoint
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	return target
+}
+
+// distProjectVersion returns the project and version of the first distribution with readable
+// metadata, or empty strings.
+func distProjectVersion(files []string) (string, string) {
+	for _, f := range files {
+		if meta, err := readDistMetadata(f); err == nil {
+			return meta.Name, meta.Version
+		}
+	}
+	return "", ""
+}
+
+// openNexusStaging creates the staging tag for the project and version of files before they
+// are uploaded.
+func (p *PyPIPlugin) openNexusStaging(ctx context.Context, cfg Config, files []string) (*nexusStaging, error) {
+	project, version := distProjectVersion(files)
+	if project == "" {
+		return nil, fmt.Errorf("nexus staging: no distribution with readable metadata")
+	}
+
+	s, err := newNexusStaging(cfg, nexusStagingTag(project, version, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	s.Project, s.Version = project, version
+	tag := map[string]any{
+		"name":       s.Tag,
+		"attributes": map[string]string{"project": project, "version": version, "staging_repository": s.Repository},
+	}
+	if err := p.sendJSON(ctx, "nexus", http.MethodPost, s.api("tags", nil), s.headers, tag, nil); err != nil {
+		return nil, fmt.Errorf("failed to create staging tag: %w", err)
+	}
+	return s, nil
+}
+
+// closeNexusStaging associates the uploaded components with the staging tag and verifies that
+// the staging repository serves every file with its local SHA-256.
+func (p *PyPIPlugin) closeNexusStaging(ctx context.Context, s *nexusStaging, files []string) error {
+	query := url.Values{
+		"repository": {s.Repository},
+		"format":     {"pypi"},
+		"name":       {normalizeProjectName(s.Project)},
+		"version":    {s.Version},
+	}
+	if err := p.sendJSON(ctx, "nexus", http.MethodPost, s.api("tags/associate/"+url.PathEscape(s.Tag), query), s.headers, nil, nil); err != nil {
+		return fmt.Errorf("failed to tag staged components: %w", err)
+	}
+
+	components, err := p.searchNexus(ctx, s, url.Values{"repository": {s.Repository}, "tag": {s.Tag}})
+	if err != nil {
+		return err
+	}
+	staged := map[string]string{}
+	for _, c := range components {
+		for _, a := range c.Assets {
+			staged[path.Base(a.Path)] = a.Checksum.SHA256
+		}
+	}
+	for _, f := range files {
+		_, digest, _, err := fileDigests(f)
+		if err != nil {
+			return err
+		}
+		name := path.Base(toSlashPath(f))
+		switch got, ok := staged[name]; {
+		case !ok:
+			return fmt.Errorf("staging verification failed: %s is not in %s", name, s.Repository)
+		case !strings.EqualFold(got, digest):
+			return fmt.Errorf("staging verification failed: %s has SHA256 %s in %s, expected %s", name, got, s.Repository, digest)
+		}
+	}
+	s.Status = stagingClosed
+	return nil
+}
+
+// releaseNexusStaging moves the tagged components to the destination repository.
+func (p *PyPIPlugin) releaseNexusStaging(ctx context.Context, s *nexusStaging) error {
+	query := url.Values{"tag": {s.Tag}}
+	if err := p.sendJSON(ctx, "nexus", http.MethodPost, s.api("staging/move/"+url.PathEscape(s.Destination), query), s.headers, nil, nil); err != nil {
+		return fmt.Errorf("failed to release staging tag %s: %w", s.Tag, err)
+	}
+	s.Status = stagingReleased
+	return nil
+}
+
+// dropNexusStaging deletes the staged components of the project version and the staging tag,
+// so a failed staging leaves nothing to be released by mistake.
+func (p *PyPIPlugin) dropNexusStaging(ctx context.Context, s *nexusStaging) error {
+	components, err := p.searchNexus(ctx, s, url.Values{
+		"repository": {s.Repository},
+		"format":     {"pypi"},
+		"name":       {normalizeProjectName(s.Project)},
+		"version":    {s.Version},
+	})
+	if err != nil {
+		return err
+	}
+	for _, c := range components {
+		if err := p.sendJSON(ctx, "nexus", http.MethodDelete, s.api("components/"+url.PathEscape(c.ID), nil), s.headers, nil, nil); err != nil {
+			return fmt.Errorf("failed to drop staged component %s: %w", c.ID, err)
+		}
+	}
+	if err := p.sendJSON(ctx, "nexus", http.MethodDelete, s.api("tags/"+url.PathEscape(s.Tag), nil), s.headers, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete staging tag: %w", err)
+	}
+	s.Status = stagingDropped
+	return nil
+}
+
+// searchNexus returns the components matching query, following continuation tokens.
+func (p *PyPIPlugin) searchNexus(ctx context.Context, s *nexusStaging, query url.Values) ([]nexusComponent, error) {
+	var components []nexusComponent
+	for {
+		var page struct {
+			Items             []nexusComponent `json:"items"`
+			ContinuationToken string           `json:"continuationToken"`
+		}
+		if err := p.sendJSON(ctx, "nexus", http.MethodGet, s.api("search", query), s.headers, nil, &page); err != nil {
+			return nil, fmt.Errorf("failed to search staged components: %w", err)
+		}
+		components = append(components, page.Items...)
+		if page.ContinuationToken == "" {
+			return components, nil
+		}
+		query.Set("continuationToken", page.ContinuationToken)
+	}
+}
+
+// releaseStagedTag releases a staging closed by an earlier run with nexus_staging_release set to
+// false, without uploading anything.
+func (p *PyPIPlugin) releaseStagedTag(ctx context.Context, cfg Config, dryRun bool) *plugin.ExecuteResponse {
+	s, err := newNexusStaging(cfg, cfg.NexusStagingReleaseTag)
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: err.Error()}
+	}
+	s.Status = stagingClosed
+	outputs := map[string]any{
+		"repository":    cfg.Repository,
+		"nexus_staging": s,
+		"plugin_build":  currentBuild().String(),
+	}
+	if dryRun {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Would release staging tag %s to %s", s.Tag, s.Destination),
+			Outputs: outputs,
+		}
+	}
+
+	components, err := p.searchNexus(ctx, s, url.Values{"repository": {s.Repository}, "tag": {s.Tag}})
+	if err == nil && len(components) == 0 {
+		err = fmt.Errorf("staging tag %s has no components in %s", s.Tag, s.Repository)
+	}
+	if err == nil {
+		s.Project, s.Version = components[0].Name, components[0].Version
+		err = p.releaseNexusStaging(ctx, s)
+	}
+	if err != nil {
+		s.Error = err.Error()
+		return &plugin.ExecuteResponse{Success: false, Error: err.Error(), Outputs: outputs}
+	}
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Released staging tag %s to %s", s.Tag, s.Destination),
+		Outputs: outputs,
+	}
+}
+
+// failStaging drops a staging whose upload or verification failed and records why.
+func (p *PyPIPlugin) failStaging(ctx context.Context, s *nexusStaging, cause error) {
+	s.Error = cause.Error()
+	if err := p.dropNexusStaging(ctx, s); err != nil {
+		s.Error += "; " + err.Error()
+	}
+}
+
+// validateNexusStagingConfig validates the Nexus staging options.
+func validateNexusStagingConfig(cfg Config) error {
+	if cfg.NexusStagingDestination == "" {
+		if cfg.NexusStagingReleaseTag != "" {
+			return fmt.Errorf("nexus_staging_release_tag requires nexus_staging_destination")
+		}
+		return nil
+	}
+	if strings.HasPrefix(cfg.Repository, unixRepositoryPrefix) {
+		return fmt.Errorf("nexus staging is not supported for unix socket repositories")
+	}
+	if !nexusRepositoryNamePattern.MatchString(cfg.NexusStagingDestination) {
+		return fmt.Errorf("invalid nexus_staging_destination %q", cfg.NexusStagingDestination)
+	}
+	s, err := newNexusStaging(cfg, cfg.NexusStagingReleaseTag)
+	if err != nil {
+		return err
+	}
+	if s.Repository == cfg.NexusStagingDestination {
+		return fmt.Errorf("nexus_staging_destination must differ from the staging repository")
+	}
+	if cfg.NexusURL != "" {
+		if err := validateRepositoryURL(cfg.NexusURL); err != nil {
+			return fmt.Errorf("invalid nexus_url: %w", err)
+		}
+	}
+	if cfg.NexusStagingReleaseTag != "" && !nexusRepositoryNamePattern.MatchString(cfg.NexusStagingReleaseTag) {
+		return fmt.Errorf("invalid nexus_staging_release_tag %q", cfg.NexusStagingReleaseTag)
+	}
+	return nil
+}
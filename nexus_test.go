@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// fakeNexus records the staging REST calls and serves the staged wheel with digest.
+type fakeNexus struct {
+	mu     sync.Mutex
+	calls  []string
+	digest string
+	tag    string
+}
+
+func (n *fakeNexus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if user, pass, ok := r.BasicAuth(); !ok || user != "deployer" || pass != "secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	endpoint := strings.TrimPrefix(r.URL.Path, "/service/rest/v1/")
+	n.calls = append(n.calls, r.Method+" "+endpoint)
+	switch {
+	case r.Method == http.MethodPost && endpoint == "tags":
+		var tag struct {
+			Name string `json:"name"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&tag)
+		n.tag = tag.Name
+		_, _ = w.Write([]byte(`{}`))
+	case r.Method == http.MethodGet && endpoint == "search":
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": []map[string]any{{
+			"id": "c1", "name": "mypkg", "version": "1.0.0",
+			"assets": []map[string]any{{"path": "packages/mypkg/1.0.0/mypkg-1.0.0-py3-none-any.whl", "checksum": map[string]string{"sha256": n.digest}}},
+		}}})
+	default:
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+func (n *fakeNexus) endpoints() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var endpoints []string
+	for _, c := range n.calls {
+		endpoint, _, _ := strings.Cut(c, "/"+n.tag)
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+func newNexusStagingTest(t *testing.T) (*fakeNexus, *PyPIPlugin, map[string]any) {
+	t.Helper()
+	writeDistFiles(t)
+	wheel := filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl")
+	writeTestWheel(t, wheel, map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	_, digest, _, err := fileDigests(wheel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nexus := &fakeNexus{digest: digest}
+	server := httptest.NewServer(nexus)
+	t.Cleanup(server.Close)
+	p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("Uploading mypkg-1.0.0-py3-none-any.whl"), nil
+		},
+	}}
+	config := map[string]any{
+		"username":                  "deployer",
+		"password":                  "secret",
+		"repository":                server.URL + "/repository/pypi-staging/",
+		"nexus_staging_destination": "pypi-releases",
+	}
+	return nexus, p, config
+}
+
+func TestExecuteNexusStaging(t *testing.T) {
+	nexus, p, config := newNexusStagingTest(t)
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+	staging, _ := resp.Outputs["nexus_staging"].(*nexusStaging)
+	if staging == nil || staging.Status != stagingReleased || !strings.HasPrefix(staging.Tag, "pypi-mypkg-1.0.0-") {
+		t.Fatalf("unexpected staging %+v", resp.Outputs["nexus_staging"])
+	}
+	want := []string{"POST tags", "POST tags/associate", "GET search", "POST staging/move/pypi-releases"}
+	if got := nexus.endpoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Nexus calls = %v, want %v", got, want)
+	}
+	if !strings.Contains(resp.Message, "Released package to pypi-releases") {
+		t.Errorf("unexpected message %q", resp.Message)
+	}
+}
+
+func TestExecuteNexusStagingDropsOnMismatch(t *testing.T) {
+	nexus, p, config := newNexusStagingTest(t)
+	nexus.digest = strings.Repeat("0", 64)
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "staging verification failed") {
+		t.Fatalf("expected a verification failure, got %v %+v", err, resp)
+	}
+	if staging, _ := resp.Outputs["nexus_staging"].(*nexusStaging); staging == nil || staging.Status != stagingDropped {
+		t.Errorf("expected the staging to be dropped, got %+v", resp.Outputs["nexus_staging"])
+	}
+	want := []string{"POST tags", "POST tags/associate", "GET search", "GET search", "DELETE components/c1", "DELETE tags"}
+	if got := nexus.endpoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Nexus calls = %v, want %v", got, want)
+	}
+}
+
+func TestExecuteNexusStagingManualRelease(t *testing.T) {
+	nexus, p, config := newNexusStagingTest(t)
+	config["nexus_staging_release"] = false
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+	staging, _ := resp.Outputs["nexus_staging"].(*nexusStaging)
+	if staging == nil || staging.Status != stagingClosed {
+		t.Fatalf("expected a closed staging, got %+v", resp.Outputs["nexus_staging"])
+	}
+
+	config["nexus_staging_release_tag"] = staging.Tag
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the release to succeed, got %v %+v", err, resp)
+	}
+	want := []string{"POST tags", "POST tags/associate", "GET search", "GET search", "POST staging/move/pypi-releases"}
+	if got := nexus.endpoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Nexus calls = %v, want %v", got, want)
+	}
+}
+
+func TestValidateNexusStagingConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"unset", Config{Repository: "https://upload.pypi.org/legacy/"}, false},
+		{"staging", Config{Repository: "http://localhost:8081/repository/pypi-staging/", NexusStagingDestination: "pypi-releases"}, false},
+		{"not a nexus repository", Config{Repository: "https://upload.pypi.org/legacy/", NexusStagingDestination: "pypi-releases"}, true},
+		{"same repository", Config{Repository: "http://localhost:8081/repository/pypi/", NexusStagingDestination: "pypi"}, true},
+		{"invalid destination", Config{Repository: "http://localhost:8081/repository/pypi-staging/", NexusStagingDestination: "../pypi"}, true},
+		{"release tag without destination", Config{NexusStagingReleaseTag: "pypi-mypkg-1.0.0"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateNexusStagingConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateNexusStagingConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of index notices.
+const (
+	noticeDeprecation = "deprecation"
+	noticeSunset      = "sunset"
+	noticeBrownout    = "brownout"
+	noticeWarning     = "warning"
+)
+
+// indexNotice is an advance warning from the index, such as a deprecation brownout or the
+// upcoming removal of an API, reported in the index_notices output.
+type indexNotice struct {
+	Kind    string `json:"kind"`
+	Source  string `json:"source"`
+	Message string `json:"message"`
+	Link    string `json:"link,omitempty"`
+}
+
+// noticeLinePattern matches response and output lines announcing deprecations or outages.
+var noticeLinePattern = regexp.MustCompile(`(?i)\b(brownout|deprecat\w*|sunset|will be (removed|disabled|discontinued))\b`)
+
+// pythonWarningPattern matches warnings of the local Python tooling, which are not index notices.
+var pythonWarningPattern = regexp.MustCompile(`\b[A-Z][A-Za-z]*Warning:`)
+
+// warningHeaderPattern extracts the text of an RFC 7234 Warning header value.
+var warningHeaderPattern = regexp.MustCompile(`^\d{3}\s+\S+\s+"((?:[^"\\]|\\.)*)"`)
+
+// linkRelPattern matches a Link header entry and its relation type.
+var linkRelPattern = regexp.MustCompile(`<([^>]+)>\s*;[^,]*\brel="?([a-z-]+)"?`)
+
+// noticesFromResponse returns the notices announced by the headers and body of an index
+// response: Warning, Deprecation (RFC 9745) and Sunset (RFC 8594) headers, and body lines
+// mentioning deprecations or brownouts.
+func noticesFromResponse(resp *http.Response, body string) []indexNotice {
+	var notices []indexNotice
+	links := map[string]string{}
+	for _, v := range resp.Header.Values("Link") {
+		for _, m := range linkRelPattern.FindAllStringSubmatch(v, -1) {
+			links[m[2]] = m[1]
+		}
+	}
+
+	for _, v := range resp.Header.Values("Warning") {
+		text := v
+		if m := warningHeaderPattern.FindStringSubmatch(v); m != nil {
+			text = strings.ReplaceAll(m[1], `\"`, `"`)
+		}
+		notices = append(notices, indexNotice{Kind: classifyNotice(text), Source: "Warning header", Message: text})
+	}
+	if v := resp.Header.Get("Deprecation"); v != "" {
+		msg := "the upload API is deprecated"
+		if when, ok := parseNoticeDate(v); ok {
+			msg = fmt.Sprintf("the upload API is deprecated since %s", when.UTC().Format(time.DateOnly))
+		}
+		notices = append(notices, indexNotice{Kind: noticeDeprecation, Source: "Deprecation header", Message: msg, Link: links["deprecation"]})
+	}
+	if v := resp.Header.Get("Sunset"); v != "" {
+		msg := "the upload API will be removed"
+		if when, ok := parseNoticeDate(v); ok {
+			msg = fmt.Sprintf("the upload API will be removed on %s", when.UTC().Format(time.DateOnly))
+		}
+		notices = append(notices, indexNotice{Kind: noticeSunset, Source: "Sunset header", Message: msg, Link: links["sunset"]})
+	}
+
+	return append(notices, noticesFromText(body, "response body")...)
+}
+
+// noticesFromOutput returns the index notices quoted in upload tool output.
+func noticesFromOutput(output string) []indexNotice {
+	return noticesFromText(output, "upload output")
+}
+
+// noticesFromText returns a notice for each line of text mentioning a deprecation or brownout.
+func noticesFromText(text, source string) []indexNotice {
+	var notices []indexNotice
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || !noticeLinePattern.MatchString(line) || pythonWarningPattern.MatchString(line) {
+			continue
+		}
+		notices = append(notices, indexNotice{Kind: classifyNotice(line), Source: source, Message: line})
+	}
+	return notices
+}
+
+// classifyNotice returns the kind of notice text announces.
+func classifyNotice(text string) string {
+	lower := strings.ToLower(text)
+	switch {
+	case strings.Contains(lower, "brownout"):
+		return noticeBrownout
+	case strings.Contains(lower, "sunset") || strings.Contains(lower, "will be removed"):
+		return noticeSunset
+	case strings.Contains(lower, "deprecat"):
+		return noticeDeprecation
+	default:
+		return noticeWarning
+	}
+}
+
+// parseNoticeDate parses the date of a Deprecation or Sunset header, given as an HTTP date or
+// as an @-prefixed Unix timestamp.
+func parseNoticeDate(v string) (time.Time, bool) {
+	if ts, ok := strings.CutPrefix(v, "@"); ok {
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		return time.Unix(sec, 0), err == nil
+	}
+	t, err := http.ParseTime(v)
+	return t, err == nil
+}
+
+// dedupeNotices drops repeated notices, which indexes typically send with every upload.
+func dedupeNotices(notices []indexNotice) []indexNotice {
+	seen := map[indexNotice]bool{}
+	var unique []indexNotice
+	for _, n := range notices {
+		if !seen[n] {
+			seen[n] = true
+			unique = append(unique, n)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNoticesFromResponse(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Add("Warning", `299 pypi.org "Uploads of .egg files are deprecated and will be rejected after 2026-12-01"`)
+	resp.Header.Set("Deprecation", "@1767225600")
+	resp.Header.Set("Sunset", "Wed, 01 Jul 2026 00:00:00 GMT")
+	resp.Header.Set("Link", `<https://blog.pypi.org/legacy-upload>; rel="deprecation", <https://status.python.org>; rel="sunset"`)
+
+	notices := noticesFromResponse(resp, "OK\nThis index is in a scheduled brownout of the legacy API today.\n")
+	want := []indexNotice{
+		{Kind: noticeDeprecation, Source: "Warning header", Message: "Uploads of .egg files are deprecated and will be rejected after 2026-12-01"},
+		{Kind: noticeDeprecation, Source: "Deprecation header", Message: "the upload API is deprecated since 2026-01-01", Link: "https://blog.pypi.org/legacy-upload"},
+		{Kind: noticeSunset, Source: "Sunset header", Message: "the upload API will be removed on 2026-07-01", Link: "https://status.python.org"},
+		{Kind: noticeBrownout, Source: "response body", Message: "This index is in a scheduled brownout of the legacy API today."},
+	}
+	if len(notices) != len(want) {
+		t.Fatalf("expected %d notices, got %+v", len(want), notices)
+	}
+	for i := range want {
+		if notices[i] != want[i] {
+			t.Errorf("notice %d = %+v, want %+v", i, notices[i], want[i])
+		}
+	}
+}
+
+func TestNoticesFromOutput(t *testing.T) {
+	output := `Uploading distributions to https://upload.pypi.org/legacy/
+/usr/lib/python3/site-packages/twine/cli.py:12: DeprecationWarning: pkg_resources is deprecated
+Uploading mypkg-1.0.0.tar.gz
+WARNING  Received "400: Bad Request": md5_digest will be removed from the upload API
+`
+	notices := noticesFromOutput(output)
+	if len(notices) != 1 || notices[0].Kind != noticeSunset || !strings.Contains(notices[0].Message, "md5_digest") {
+		t.Errorf("expected only the index notice, got %+v", notices)
+	}
+}
+
+func TestNativeUploadIndexNotices(t *testing.T) {
+	writeDistFiles(t)
+	for _, name := range []string{"mypkg-1.0.0-py3-none-any.whl", "mypkg-1.0.0-py2-none-any.whl"} {
+		writeTestWheel(t, filepath.Join("dist", name), map[string]string{
+			"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Sunset", "Wed, 01 Jul 2026 00:00:00 GMT")
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	cfg := p.parseConfig(map[string]any{
+		"repository":  server.URL,
+		"password":    "token",
+		"auth_scheme": "bearer",
+	})
+	run, err := p.runNativeUploads(context.Background(), cfg, newPublishSession(cfg), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if notices := dedupeNotices(run.notices); len(run.notices) != 2 || len(notices) != 1 || notices[0].Kind != noticeSunset {
+		t.Errorf("expected a sunset notice per upload, got %+v", run.notices)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Versions of the response outputs selected by the outputs_version option.
+const (
+	// outputsVersionFlat reports the repository as its URL and the files only in tool output
+	outputsVersionFlat = 1
+	// outputsVersionNested reports the repository as an object and every file with its status
+	outputsVersionNested = 2
+)
+
+// outputsVersions lists the accepted outputs_version values.
+var outputsVersions = []int{outputsVersionFlat, outputsVersionNested}
+
+// Statuses of a file in the files output.
+const (
+	fileUploaded = "uploaded"
+	// fileSkipped files were already on the index, as found by skip_existing or on_existing
+	fileSkipped = "skipped"
+	// fileStaged files are held in a Nexus staging tag that was not released
+	fileStaged = "staged"
+	fileFailed = "failed"
+	// filePending files were not uploaded: dry runs, and files after a failed one
+	filePending = "pending"
+)
+
+// repositoryOutput is the repository output of outputs_version 2.
+type repositoryOutput struct {
+	URL string `json:"url"`
+	// Name is pypi or testpypi for PyPI, and the host of the repository otherwise
+	Name string `json:"name"`
+}
+
+// fileOutput is one distribution in the files output of outputs_version 2.
+type fileOutput struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// URL is where the index lists the file: the release page on PyPI and TestPyPI, or the
+	// project page of the simple index
+	URL    string `json:"url,omitempty"`
+	Status string `json:"status"`
+}
+
+// uploadOutcome is what a publish did with its files, from which the files output is built.
+type uploadOutcome struct {
+	files []string
+	// attempted reports whether the upload ran, with output and failed describing it
+	attempted bool
+	output    string
+	failed    bool
+	staged    bool
+	// existing names the files on_existing skip found on the index, ending the publish
+	existing []string
+	// failuresNamed reports uploads that name their failed files in failedFiles, as
+	// continue_on_error and parallel uploads do
+	failuresNamed bool
+	failedFiles   []string
+}
+
+// repositoryName returns the name of the repository in outputs.
+func repositoryName(repository string) string {
+	u, err := url.Parse(repository)
+	if err != nil || u.Host == "" {
+		return repository
+	}
+	switch host := strings.ToLower(u.Hostname()); host {
+	case "upload.pypi.org", "pypi.org":
+		return "pypi"
+	case "test.pypi.org":
+		return "testpypi"
+	default:
+		return host
+	}
+}
+
+// statuses returns the status of each file by name. Upload tools print "Uploading <file>"
+// before each upload and "Skipping <file>" for files skip_existing found; after a failure,
+// the last file being uploaded failed and the files not reached are pending. Uploads that
+// name their failed files uploaded every other file they started.
+func (o uploadOutcome) statuses() map[string]string {
+	statuses := make(map[string]string, len(o.files))
+	if !o.attempted {
+		for _, f := range o.files {
+			statuses[filepath.Base(f)] = filePending
+			if containsString(o.existing, filepath.Base(f)) {
+				statuses[filepath.Base(f)] = fileSkipped
+			}
+		}
+		return statuses
+	}
+
+	done := fileUploaded
+	if o.staged {
+		done = fileStaged
+	}
+	names := make([]string, 0, len(o.files))
+	for _, f := range o.files {
+		names = append(names, filepath.Base(f))
+	}
+	// Lines such as "Uploading distributions to <url>" name no file
+	var started []string
+	skipped := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(o.output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !containsString(names, filepath.Base(fields[1])) {
+			continue
+		}
+		switch fields[0] {
+		case "Uploading":
+			started = append(started, filepath.Base(fields[1]))
+		case "Skipping":
+			skipped[filepath.Base(fields[1])] = true
+		}
+	}
+	for _, name := range names {
+		switch {
+		case skipped[name]:
+			statuses[name] = fileSkipped
+		case !o.failed:
+			statuses[name] = done
+		case o.failuresNamed && containsString(o.failedFiles, name):
+			statuses[name] = fileFailed
+		case o.failuresNamed && containsString(started, name):
+			statuses[name] = done
+		case o.failuresNamed:
+			statuses[name] = filePending
+		case len(started) == 0:
+			statuses[name] = fileFailed
+		case name == started[len(started)-1]:
+			statuses[name] = fileFailed
+		case containsString(started, name):
+			statuses[name] = done
+		default:
+			statuses[name] = filePending
+		}
+	}
+	return statuses
+}
+
+// fileOutputs returns the files output of a publish.
+func fileOutputs(cfg Config, outcome uploadOutcome) []fileOutput {
+	project, version := distProjectVersion(outcome.files)
+	listing := releasePageURL(cfg.Repository, project, version)
+	if listing != "" {
+		listing += "#files"
+	} else if cfg.IndexURL != "" && project != "" {
+		listing, _ = projectPageURL(cfg.IndexURL, project)
+	}
+
+	statuses := outcome.statuses()
+	files := make([]fileOutput, 0, len(outcome.files))
+	for _, f := range outcome.files {
+		file := fileOutput{Name: filepath.Base(f), Status: statuses[filepath.Base(f)]}
+		if _, sha256, size, err := fileDigests(f); err == nil {
+			file.SHA256, file.Size = sha256, size
+		}
+		if file.Status == fileUploaded || file.Status == fileSkipped {
+			file.URL = listing
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// nestOutputs restructures the outputs of a publish for outputs_version 2: the repository
+// becomes an object and the files are listed with their status.
+func nestOutputs(cfg Config, outputs map[string]any, outcome uploadOutcome) {
+	outputs["outputs_version"] = outputsVersionNested
+	outputs["repository"] = repositoryOutput{URL: maskURLPasswords(cfg.Repository), Name: repositoryName(cfg.Repository)}
+	if len(outcome.files) > 0 {
+		outputs["files"] = fileOutputs(cfg, outcome)
+	}
+}
+
+// validateOutputsVersion validates the outputs_version option.
+func validateOutputsVersion(cfg Config) error {
+	for _, v := range outputsVersions {
+		if cfg.OutputsVersion == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("outputs_version must be %d or %d", outputsVersionFlat, outputsVersionNested)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestUploadOutcomeStatuses(t *testing.T) {
+	files := []string{"dist/mypkg-1.0.0-py3-none-any.whl", "dist/mypkg-1.0.0.tar.gz", "dist/mypkg-1.0.0-py2-none-any.whl"}
+	tests := []struct {
+		name    string
+		outcome uploadOutcome
+		want    []string
+	}{
+		{"dry run", uploadOutcome{files: files}, []string{filePending, filePending, filePending}},
+		{"on_existing skip", uploadOutcome{files: files, existing: []string{"mypkg-1.0.0.tar.gz", "mypkg-1.0.0-py3-none-any.whl"}}, []string{fileSkipped, fileSkipped, filePending}},
+		{"uploaded", uploadOutcome{files: files, attempted: true, output: "Uploading distributions to https://upload.pypi.org/legacy/\nSkipping mypkg-1.0.0.tar.gz because it appears to already exist\n"}, []string{fileUploaded, fileSkipped, fileUploaded}},
+		{"staged", uploadOutcome{files: files, attempted: true, staged: true}, []string{fileStaged, fileStaged, fileStaged}},
+		{"failed midway", uploadOutcome{files: files, attempted: true, failed: true, output: "Uploading distributions to https://upload.pypi.org/legacy/\nUploading mypkg-1.0.0-py3-none-any.whl\n100%\nUploading mypkg-1.0.0.tar.gz\nERROR    HTTPError: 400 Bad Request"}, []string{fileUploaded, fileFailed, filePending}},
+		{"failed before uploading", uploadOutcome{files: files, attempted: true, failed: true, output: "Uploading distributions to https://upload.pypi.org/legacy/\nERROR    connection refused"}, []string{fileFailed, fileFailed, fileFailed}},
+	}
+	for _, tt := range tests {
+		statuses := tt.outcome.statuses()
+		for i, f := range files {
+			if got := statuses[filepath.Base(f)]; got != tt.want[i] {
+				t.Errorf("%s: status of %s = %q, want %q", tt.name, filepath.Base(f), got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestRepositoryName(t *testing.T) {
+	for repository, want := range map[string]string{
+		"https://upload.pypi.org/legacy/":                     "pypi",
+		"https://test.pypi.org/legacy/":                       "testpypi",
+		"https://nexus.example.com/repository/pypi-internal/": "nexus.example.com",
+		"local": "local",
+	} {
+		if got := repositoryName(repository); got != want {
+			t.Errorf("repositoryName(%q) = %q, want %q", repository, got, want)
+		}
+	}
+}
+
+func TestExecuteOutputsVersion(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0-py3-none-any.whl", "mypkg-1.0.0.tar.gz")
+	execute := func(outputsVersion int, out string, runErr error) *plugin.ExecuteResponse {
+		t.Helper()
+		p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+			RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				return []byte(out), runErr
+			},
+		}}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"username":        "__token__",
+				"password":        "pypi-token",
+				"repository":      "http://localhost:8080/",
+				"outputs_version": outputsVersion,
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp
+	}
+
+	resp := execute(outputsVersionFlat, "Uploading mypkg-1.0.0-py3-none-any.whl\nUploading mypkg-1.0.0.tar.gz\n", nil)
+	if resp.Outputs["repository"] != "http://localhost:8080/" || resp.Outputs["files"] != nil {
+		t.Errorf("expected flat outputs by default, got %v", resp.Outputs)
+	}
+
+	resp = execute(outputsVersionNested, "Uploading mypkg-1.0.0-py3-none-any.whl\nUploading mypkg-1.0.0.tar.gz\nERROR    HTTPError: 400 Bad Request", errors.New("exit status 1"))
+	if resp.Success || resp.Outputs["outputs_version"] != outputsVersionNested {
+		t.Fatalf("expected a failed publish with nested outputs, got %+v", resp)
+	}
+	if repo, _ := resp.Outputs["repository"].(repositoryOutput); repo != (repositoryOutput{URL: "http://localhost:8080/", Name: "localhost"}) {
+		t.Errorf("unexpected repository output %v", resp.Outputs["repository"])
+	}
+	files, _ := resp.Outputs["files"].([]fileOutput)
+	if len(files) != 2 {
+		t.Fatalf("expected both files, got %v", resp.Outputs["files"])
+	}
+	statuses := map[string]string{}
+	for _, f := range files {
+		if f.Size == 0 || len(f.SHA256) != 64 {
+			t.Errorf("expected the size and digest of %s, got %+v", f.Name, f)
+		}
+		statuses[f.Name] = f.Status
+	}
+	if statuses["mypkg-1.0.0-py3-none-any.whl"] != fileUploaded || statuses["mypkg-1.0.0.tar.gz"] != fileFailed {
+		t.Errorf("unexpected statuses %v", statuses)
+	}
+}
+
+func TestValidateOutputsVersion(t *testing.T) {
+	if err := validateOutputsVersion(Config{OutputsVersion: outputsVersionNested}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := validateOutputsVersion(Config{OutputsVersion: 3}); err == nil {
+		t.Error("expected outputs_version 3 to be rejected")
+	}
+}
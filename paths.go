@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// The helpers in this file keep dist path handling identical across operating systems.
+// Paths are normalized to forward slashes before validation so Windows runners can use
+// backslash-separated patterns, while absolute-path and traversal checks reject the same
+// inputs on every platform.
+
+// toSlashPath converts OS-specific path separators to forward slashes.
+// Backslashes are only treated as separators on Windows; elsewhere they are left as-is.
+func toSlashPath(p string) string {
+	return filepath.ToSlash(p)
+}
+
+// isAbsolutePath reports whether p is absolute on any supported platform.
+// Rooted paths without a drive letter and volume names are rejected even where the
+// current OS would consider them relative.
+func isAbsolutePath(p string) bool {
+	return filepath.IsAbs(p) || strings.HasPrefix(toSlashPath(p), "/") || filepath.VolumeName(p) != ""
+}
+
+// escapesWorkDir reports whether a slash-separated path escapes the working directory.
+// Glob characters are ignored so patterns like "dist/*" are checked by their fixed parts.
+func escapesWorkDir(p string) bool {
+	cleaned := strings.ReplaceAll(path.Clean(p), "*", "")
+	return strings.HasPrefix(cleaned, "..") || strings.Contains(cleaned, "/..")
+}
+
+// noDistFilesMessage explains that pattern matches no distribution, naming the first directory
+// of the pattern that does not exist, which usually means the package was not built.
+func noDistFilesMessage(pattern string) string {
+	msg := fmt.Sprintf("no distribution files match %s", pattern)
+	dir := path.Dir(toSlashPath(pattern))
+	for strings.ContainsAny(dir, "*?[") {
+		dir = path.Dir(dir)
+	}
+	if _, err := os.Stat(filepath.FromSlash(dir)); errors.Is(err, fs.ErrNotExist) {
+		msg += fmt.Sprintf(" (%s does not exist; build the package first or set dist_path)", dir)
+	}
+	return msg
+}
+
+// distDirGlob returns the dist path glob matching every file of an artifact directory.
+func distDirGlob(dir string) string {
+	return strings.TrimSuffix(toSlashPath(dir), "/") + "/*"
+}
+
+// expandDistGlob expands a slash-separated dist path pattern into the matching regular files,
+// leaving out attestations and signatures, which are uploaded with their distribution. Matches
+// are returned in lexical order using OS-native separators.
+func expandDistGlob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.FromSlash(pattern))
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern: %w", err)
+	}
+
+	files := make([]string, 0, len(matches))
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil || !info.Mode().IsRegular() || isAttestationFile(m) || isSignatureFile(m) {
+			continue
+		}
+		files = append(files, m)
+	}
+	return files, nil
+}
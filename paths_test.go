@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestIsAbsolutePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"dist/*", false},
+		{"build/dist/*.whl", false},
+		{"/etc/passwd", true},
+		{"/dist/*", true},
+	}
+
+	for _, tt := range tests {
+		if got := isAbsolutePath(tt.path); got != tt.expected {
+			t.Errorf("isAbsolutePath(%q) = %v, expected %v", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestEscapesWorkDir(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"dist/*", false},
+		{"dist/../dist/*", false},
+		{"./dist/*.whl", false},
+		{"..", true},
+		{"../dist/*", true},
+		{"dist/../../*", true},
+		{"dist/*/../../..", true},
+	}
+
+	for _, tt := range tests {
+		if got := escapesWorkDir(tt.path); got != tt.expected {
+			t.Errorf("escapesWorkDir(%q) = %v, expected %v", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestValidateDistPathSeparators(t *testing.T) {
+	err := validateDistPath(`dist\*.whl`)
+	if runtime.GOOS == "windows" {
+		if err != nil {
+			t.Errorf("expected backslash path to be accepted on windows, got %v", err)
+		}
+	} else if err == nil {
+		t.Error("expected backslash path to be rejected outside windows")
+	}
+}
+
+func TestExpandDistGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"pkg-1.0.0.tar.gz", "pkg-1.0.0-py3-none-any.whl"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0o750); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	files, err := expandDistGlob(filepath.ToSlash(dir) + "/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "pkg-1.0.0-py3-none-any.whl"),
+		filepath.Join(dir, "pkg-1.0.0.tar.gz"),
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %d files, got %d: %v", len(expected), len(files), files)
+	}
+	for i, f := range expected {
+		if files[i] != f {
+			t.Errorf("file[%d]: expected '%s', got '%s'", i, f, files[i])
+		}
+	}
+
+	if _, err := expandDistGlob("dist/[*"); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestNoDistFilesMessage(t *testing.T) {
+	writeDistFiles(t)
+	tests := map[string]string{
+		"dist/*.whl":       "no distribution files match dist/*.whl",
+		"build/dist/*":     "no distribution files match build/dist/* (build/dist does not exist; build the package first or set dist_path)",
+		"out/*/pkg-*.whl":  "no distribution files match out/*/pkg-*.whl (out does not exist; build the package first or set dist_path)",
+		"mypkg-1.0.tar.gz": "no distribution files match mypkg-1.0.tar.gz",
+	}
+	for pattern, want := range tests {
+		if got := noDistFilesMessage(pattern); got != want {
+			t.Errorf("noDistFilesMessage(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestExecuteNoDistFiles(t *testing.T) {
+	tests := []struct {
+		name          string
+		failOnNoFiles any
+		wantOK        bool
+		want          string
+	}{
+		{"default", nil, false, "no distribution files match build/*.whl (build does not exist"},
+		{"fail", true, false, "no distribution files match build/*.whl"},
+		{"skip", false, true, "no distribution files match build/*.whl (build does not exist; build the package first or set dist_path); nothing to upload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeDistFiles(t)
+			config := map[string]any{
+				"username":  "__token__",
+				"password":  "pypi-token",
+				"dist_path": "build/*.whl",
+			}
+			if tt.failOnNoFiles != nil {
+				config["fail_on_no_files"] = tt.failOnNoFiles
+			}
+			executor := &MockCommandExecutor{}
+			p := &PyPIPlugin{cmdExecutor: executor}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+			if err != nil || resp.Success != tt.wantOK || !strings.Contains(resp.Message+resp.Error, tt.want) {
+				t.Fatalf("expected success %v with %q, got %v %+v", tt.wantOK, tt.want, err, resp)
+			}
+			if len(executor.RunCalls) != 0 {
+				t.Errorf("expected twine not to run, got %+v", executor.RunCalls)
+			}
+		})
+	}
+}
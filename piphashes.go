@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// pipHashRequirements returns a requirement line per project and version of files, pinned to
+// the SHA-256 digests of their distributions as pip's hash-checking mode expects them:
+//
+//	mypkg==1.0.0 --hash=sha256:<sdist> --hash=sha256:<wheel>
+//
+// The project is read from the distribution metadata, falling back to the file name and the
+// release version.
+func pipHashRequirements(files []string, version string) ([]string, error) {
+	hashes := map[string][]string{}
+	for _, f := range files {
+		name, fileVersion := strings.SplitN(filepath.Base(f), "-", 2)[0], version
+		if meta, err := readDistMetadata(f); err == nil {
+			name, fileVersion = meta.Name, meta.Version
+		}
+		_, digest, _, err := fileDigests(f)
+		if err != nil {
+			return nil, err
+		}
+		pin := normalizeProjectName(name) + "==" + fileVersion
+		hashes[pin] = append(hashes[pin], "--hash=sha256:"+digest)
+	}
+
+	lines := make([]string, 0, len(hashes))
+	for pin, h := range hashes {
+		sort.Strings(h)
+		lines = append(lines, pin+" "+strings.Join(h, " "))
+	}
+	sort.Strings(lines)
+	return lines, nil
+}
+
+// writeHashesFile writes the requirement lines to hashes_file, replacing an earlier release's.
+func writeHashesFile(cfg Config, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(filepath.FromSlash(cfg.HashesFile)), 0o750); err != nil {
+		return fmt.Errorf("failed to create the directory of hashes_file: %w", err)
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(filepath.FromSlash(cfg.HashesFile), []byte(content), 0o600); err != nil {
+		return fmt.Errorf("failed to write hashes_file: %w", err)
+	}
+	return nil
+}
+
+// validateHashesFileConfig validates the hashes_file option.
+func validateHashesFileConfig(cfg Config) error {
+	if cfg.HashesFile == "" {
+		return nil
+	}
+	if err := validateDistPath(cfg.HashesFile); err != nil {
+		return fmt.Errorf("invalid hashes_file: %w", err)
+	}
+	if strings.Contains(cfg.HashesFile, "*") {
+		return fmt.Errorf("invalid hashes_file: must not contain wildcards")
+	}
+	if matched, _ := filepath.Match(toSlashPath(cfg.DistPath), toSlashPath(cfg.HashesFile)); matched {
+		return fmt.Errorf("hashes_file must not match dist_path, or it would be uploaded")
+	}
+	return nil
+}
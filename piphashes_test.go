@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestPipHashRequirements(t *testing.T) {
+	writeDistFiles(t, "my_pkg-1.0.0.tar.gz")
+	wheel := filepath.Join("dist", "my_pkg-1.0.0-py3-none-any.whl")
+	writeTestWheel(t, wheel, map[string]string{
+		"my_pkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: My.Pkg\nVersion: 1.0.0\n",
+	})
+	sdist := filepath.Join("dist", "my_pkg-1.0.0.tar.gz")
+	_, wheelDigest, _, _ := fileDigests(wheel)
+	_, sdistDigest, _, _ := fileDigests(sdist)
+
+	lines, err := pipHashRequirements([]string{wheel, sdist}, "1.0.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The wheel's metadata and the sdist's file name name the same project
+	hashes := []string{"--hash=sha256:" + wheelDigest, "--hash=sha256:" + sdistDigest}
+	if hashes[0] > hashes[1] {
+		hashes[0], hashes[1] = hashes[1], hashes[0]
+	}
+	if want := "my-pkg==1.0.0 " + strings.Join(hashes, " "); len(lines) != 1 || lines[0] != want {
+		t.Errorf("got %q, want %q", lines, want)
+	}
+}
+
+func TestExecuteHashesFile(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	_, digest, _, _ := fileDigests(filepath.Join("dist", "mypkg-1.0.0.tar.gz"))
+
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":    "__token__",
+			"password":    "pypi-token",
+			"repository":  "http://localhost:8080/",
+			"hashes_file": "constraints/hashes.txt",
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the upload to succeed, got %+v, %v", resp, err)
+	}
+
+	want := "mypkg==1.0.0 --hash=sha256:" + digest
+	if lines, _ := resp.Outputs["pip_hashes"].([]string); len(lines) != 1 || lines[0] != want {
+		t.Errorf("unexpected pip_hashes %v", resp.Outputs["pip_hashes"])
+	}
+	data, err := os.ReadFile(filepath.Join("constraints", "hashes.txt"))
+	if err != nil || string(data) != want+"\n" {
+		t.Errorf("unexpected hashes_file %q, %v", data, err)
+	}
+
+	for _, bad := range []string{"/tmp/hashes.txt", "dist/hashes.txt", "hashes/*.txt"} {
+		if err := validateHashesFileConfig(Config{DistPath: "dist/*", HashesFile: bad}); err == nil {
+			t.Errorf("expected hashes_file %s to be rejected", bad)
+		}
+	}
+}
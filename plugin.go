@@ -2,15 +2,20 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
 	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
@@ -20,11 +25,44 @@ import (
 var (
 	// distPathPattern validates dist path patterns - allows alphanumerics, dots, dashes, underscores, forward slashes, and glob patterns.
 	distPathPattern = regexp.MustCompile(`^[a-zA-Z0-9._/*-]+$`)
+
+	// projectNamePattern validates Python project names as defined by PEP 508.
+	projectNamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
 )
 
+// validationWarningCode marks Validate entries that are advisory and do not make the config invalid.
+const validationWarningCode = "warning"
+
+// defaultHTTPTimeout bounds direct HTTP requests made by the plugin.
+const defaultHTTPTimeout = 5 * time.Minute
+
+// processWaitDelay is how long a cancelled command may take to exit before it is killed.
+const processWaitDelay = 10 * time.Second
+
 // CommandExecutor abstracts command execution for testability.
 type CommandExecutor interface {
 	Run(ctx context.Context, name string, args ...string) ([]byte, error)
+	// RunWithEnv runs the command with env (KEY=value entries) added to the plugin's
+	// environment, for values such as credentials that must not appear in the process arguments
+	RunWithEnv(ctx context.Context, env []string, name string, args ...string) ([]byte, error)
+}
+
+// outputExecutor is implemented by executors that can return the standard output of a
+// command alone. It is not part of CommandExecutor, so executors that only implement Run and
+// RunWithEnv keep working.
+type outputExecutor interface {
+	// Output runs the command and returns its standard output only, for commands whose output
+	// is parsed, such as tokens and keys. Standard error is reported in the error instead.
+	Output(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// commandOutput runs a command whose output is parsed, reading its standard output only when
+// executor supports it and its combined output otherwise.
+func commandOutput(ctx context.Context, executor CommandExecutor, name string, args ...string) ([]byte, error) {
+	if o, ok := executor.(outputExecutor); ok {
+		return o.Output(ctx, name, args...)
+	}
+	return executor.Run(ctx, name, args...)
 }
 
 // RealCommandExecutor executes real shell commands.
@@ -32,28 +70,458 @@ type RealCommandExecutor struct{}
 
 // Run executes a command and returns combined output.
 func (e *RealCommandExecutor) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return e.RunWithEnv(ctx, nil, name, args...)
+}
+
+// RunWithEnv executes a command with additional environment variables and returns combined output.
+// On cancellation the process and its children are asked to terminate and killed if they have not
+// exited after processWaitDelay.
+func (e *RealCommandExecutor) RunWithEnv(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
 	cmd := exec.CommandContext(ctx, name, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	configureCancellation(cmd)
 	return cmd.CombinedOutput()
 }
 
+// Output executes a command and returns its standard output. Warnings CLIs print to standard
+// error would otherwise end up in the parsed value; they are reported when the command fails.
+func (e *RealCommandExecutor) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	configureCancellation(cmd)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		detail, _ := truncateOutput(strings.TrimSpace(stderr.String()), defaultMaxErrorBodyBytes)
+		err = fmt.Errorf("%w: %s", err, detail)
+	}
+	return out, err
+}
+
 // Config holds the PyPI plugin configuration.
 type Config struct {
 	// Username for PyPI authentication (can be set via PYPI_USERNAME env var)
 	Username string
 	// Password or API token for PyPI authentication (can be set via PYPI_PASSWORD env var)
 	Password string
+	// Token is an API token, used as the password with the __token__ username (can be set via
+	// PYPI_TOKEN env var)
+	Token string
+	// ClientCert is a PEM client certificate for mTLS, with the key bundled unless ClientKey is set.
+	// It is reloaded when the file changes during a publish.
+	ClientCert string
+	// ClientKey is the PEM private key of ClientCert when stored separately
+	ClientKey string
+	// TrustedPublishing exchanges the CI job's OIDC token for a short-lived API token (PyPI
+	// Trusted Publishers) instead of using static credentials
+	TrustedPublishing bool
+	// TrustedPublishingURL is the index exchanging OIDC tokens (defaults to PyPI or TestPyPI)
+	TrustedPublishingURL string
+	// OIDCTokenEnv is the variable holding an OIDC token outside GitHub Actions (defaults to PYPI_ID_TOKEN)
+	OIDCTokenEnv string
+	// Attestations uploads a PEP 740 Sigstore attestation with each distribution, generating
+	// missing ones with AttestationCommand
+	Attestations bool
+	// AttestationCommand signs the distributions given as arguments (defaults to
+	// python3 -m pypi_attestations sign)
+	AttestationCommand []string
+	// Sign uploads a detached GPG signature (<file>.asc) with each distribution, created by
+	// twine --sign or, for the built-in uploader, by GPGProgram
+	Sign bool
+	// SignIdentity is the GPG key signing the distributions (defaults to gpg's default key)
+	SignIdentity string
+	// GPGProgram is the gpg executable creating the signatures (defaults to gpg)
+	GPGProgram string
+	// DeviceAuth logs in with the OAuth 2.0 device authorization grant before the upload: the
+	// maintainer approves the printed code in a browser, for local releases without stored tokens
+	DeviceAuth bool
+	// DeviceAuthURL is the device authorization endpoint of the index's OAuth server
+	DeviceAuthURL string
+	// DeviceTokenURL is the token endpoint polled until the login is approved
+	DeviceTokenURL string
+	// DeviceClientID is the OAuth client registered for uploads
+	DeviceClientID string
+	// DeviceScope is the OAuth scope requested, if the server needs one
+	DeviceScope string
+	// DevicePollInterval is the delay between token polls unless the server asks for longer
+	DevicePollInterval time.Duration
+	// SpiffeWorkloadAPI authenticates with the workload's X.509 SVID from the SPIFFE Workload API
+	SpiffeWorkloadAPI bool
+	// SpiffeEndpointSocket is the Workload API address (defaults to SPIFFE_ENDPOINT_SOCKET)
+	SpiffeEndpointSocket string
+	// SpiffeID selects the SVID to use when the workload has several (defaults to the first)
+	SpiffeID string
+	// SpiffeServerID is the SPIFFE ID the index must present, verified with the trust bundle of
+	// the Workload API instead of the host name
+	SpiffeServerID string
+	// UseNetrc reads missing credentials from the netrc entry of the repository host
+	UseNetrc bool
+	// PypircPath is the .pypirc file read for the repository and credentials (defaults to
+	// ~/.pypirc when RepositoryName is set)
+	PypircPath string
+	// RepositoryName is the .pypirc section to read (defaults to $TWINE_REPOSITORY or pypi)
+	RepositoryName string
+	// UploadBackend selects the uploader (auto, twine, native; defaults to auto, which uses twine
+	// unless the authentication or connection options need the native uploader)
+	UploadBackend string
+	// AuthScheme is how credentials are sent (basic, bearer, none, sigv4; defaults to basic)
+	AuthScheme string
+	// AuthHeader is the header carrying credentials (defaults to Authorization)
+	AuthHeader string
+	// AWSRegion is the region sigv4 requests are signed for (defaults to AWS_REGION)
+	AWSRegion string
+	// AWSService is the service name sigv4 requests are signed for (defaults to execute-api)
+	AWSService string
 	// Repository URL (defaults to https://upload.pypi.org/legacy/)
 	Repository string
 	// DistPath is the path to distribution files (defaults to "dist/*")
 	DistPath string
+	// WindowsExtendedPaths accepts UNC paths (\\server\share\dist\*) and paths longer than 256
+	// characters as DistPath on Windows
+	WindowsExtendedPaths bool
+	// SymlinkPolicy handles symlinked distribution files: follow uploads their targets, reject
+	// fails the publish, and copy uploads copies from a temporary directory (defaults to follow)
+	SymlinkPolicy string
+	// FailOnNoFiles fails the publish when DistPath matches no files; otherwise it succeeds
+	// without uploading
+	FailOnNoFiles bool
+	// PublishBranches lists the branches, as glob patterns like release/*, releases are
+	// uploaded from; releases of other branches succeed without uploading
+	PublishBranches []string
+	// SkipPrereleases succeeds without uploading alpha, beta, rc and dev releases
+	SkipPrereleases bool
+	// OnlyIfPathsChanged uploads only releases changing a file matching one of these patterns
+	// since the previous release, with paths relative to the repository root
+	OnlyIfPathsChanged []string
+	// CheckVersionMatch fails the publish when a distribution file name names another version
+	// than the release
+	CheckVersionMatch bool
+	// FilenamePolicy holds rules every distribution file name must satisfy (nil disables)
+	FilenamePolicy *FilenamePolicy
+	// NormalizeWheels uploads copies of the wheels without build-host paths, user names and
+	// timestamps
+	NormalizeWheels bool
+	// Build builds the distributions into the directory of DistPath on the pre-publish hook
+	Build bool
+	// BuildBackend selects the build tool: build (default), poetry, hatch, flit or uv
+	BuildBackend string
+	// BuildCommand replaces the command of BuildBackend
+	BuildCommand []string
 	// SkipExisting skips upload if package version already exists
 	SkipExisting bool
+	// ContinueOnError uploads the remaining files after one fails instead of stopping; the
+	// publish still fails, reporting which files were uploaded
+	ContinueOnError bool
+	// Concurrency is the number of files the built-in uploader uploads at once (defaults to 1)
+	Concurrency int
+	// InjectFailure simulates a publish failure (timeout, http500, partial) for pipeline testing
+	InjectFailure string
+	// Benchmark uploads synthetic throwaway files to a staging index instead of publishing
+	Benchmark bool
+	// BenchmarkIterations is the number of synthetic uploads (defaults to 5)
+	BenchmarkIterations int
+	// BenchmarkSize is the payload size of each synthetic upload in bytes (defaults to 1 MiB)
+	BenchmarkSize int64
+	// BenchmarkPackage is the project name used for synthetic uploads
+	BenchmarkPackage string
+	// TokenCommand prints a short-lived upload token; files are then uploaded one at a time
+	// and the token is refreshed before it expires
+	TokenCommand []string
+	// TokenLifetime is the assumed token lifetime when the command output carries no expiry
+	TokenLifetime time.Duration
+	// TokenRefreshMargin is how long before expiry a token is refreshed (defaults to 5m)
+	TokenRefreshMargin time.Duration
+	// CredentialOverrides upload files matching a pattern with their own credentials
+	CredentialOverrides []CredentialOverride
+	// CodeArtifact uploads to an AWS CodeArtifact repository with authorization tokens obtained
+	// and refreshed with the runner's AWS credentials (nil disables)
+	CodeArtifact *CodeArtifact
+	// GCPArtifactRegistry uploads to a Google Artifact Registry repository with access tokens
+	// obtained and refreshed from Application Default Credentials
+	GCPArtifactRegistry bool
+	// GCPCredentialsFile is a service account key used instead of Application Default Credentials
+	GCPCredentialsFile string
+	// AzureArtifacts uploads to an Azure Artifacts feed with a personal access token or
+	// federated credentials (nil disables)
+	AzureArtifacts *AzureArtifacts
+	// Artifactory uploads to a JFrog Artifactory PyPI repository (nil disables)
+	Artifactory *Artifactory
+	// VulnerabilityCheck audits declared dependencies before upload (off, warn, fail; defaults to off)
+	VulnerabilityCheck string
+	// VulnerabilitySeverity is the lowest severity that is reported (defaults to critical)
+	VulnerabilitySeverity string
+	// VulnerabilitySource is the vulnerability database client (osv or pip-audit; defaults to osv)
+	VulnerabilitySource string
+	// OSVURL is the OSV query endpoint
+	OSVURL string
+	// RequirementsFile lists additional pinned dependencies to audit
+	RequirementsFile string
+	// LicenseCheck scans wheels for bundled code and libraries outside the allowlist (off, warn, fail)
+	LicenseCheck string
+	// LicenseAllowlist lists the SPDX license identifiers permitted in bundled components
+	LicenseAllowlist []string
+	// LicenseAllowUnknown accepts bundled components whose license cannot be identified
+	LicenseAllowUnknown bool
+	// SharedObjectCheck compares .so files in Linux wheels against SharedObjectAllowlist (off, warn, fail)
+	SharedObjectCheck string
+	// SharedObjectAllowlist holds SHA-256 digests or in-wheel path globs of expected native code
+	SharedObjectAllowlist []string
+	// DescriptionPreview renders the long description to an HTML artifact before publishing
+	DescriptionPreview bool
+	// DescriptionPreviewPath is where the preview page is written (defaults to description-preview.html)
+	DescriptionPreviewPath string
+	// ReadmeRendererCommand renders descriptions as PyPI does (defaults to python3 -m readme_renderer)
+	ReadmeRendererCommand []string
+	// QueueDir receives the distributions and a manifest of publishes that failed because the
+	// index was unavailable, for a later ResumeQueued run
+	QueueDir string
+	// ResumeQueued uploads the publishes queued in QueueDir instead of the current release
+	ResumeQueued bool
+	// StatusCheck checks the index status page for incidents before uploading (off, warn, fail)
+	StatusCheck string
+	// StatusURL is the Statuspage summary.json of the index (defaults to status.python.org)
+	StatusURL string
+	// StatusWait is how long the upload waits for an incident to be resolved (0 does not wait)
+	StatusWait time.Duration
+	// StatusPollInterval is the delay between status page polls while waiting
+	StatusPollInterval time.Duration
+	// MaintainerCheck compares the project maintainers on the index with the publishing account
+	// and ExpectedMaintainers before uploading (off, warn, fail)
+	MaintainerCheck string
+	// MaintainerAccount is the index account expected among the maintainers (defaults to Username
+	// unless it is __token__)
+	MaintainerAccount string
+	// ExpectedMaintainers are the accounts allowed to hold a role on the project
+	ExpectedMaintainers []string
+	// OnExisting checks whether the version is already published before uploading (off, skip,
+	// warn, fail); skip ends the publish successfully without uploading
+	OnExisting string
+	// VerifyOnly skips the upload and verifies that the release version and its files are
+	// published on the index with the SHA-256 digests of the local distributions
+	VerifyOnly bool
+	// SignaturesOnly uploads the <file>.asc signatures of dist_path files already published on
+	// the index instead of publishing, for signing stages that run after the initial publish
+	SignaturesOnly bool
+	// WaitForAvailability polls IndexURL after the upload until it lists the uploaded files
+	WaitForAvailability bool
+	// AvailabilityTimeout bounds the wait for the uploaded files to appear on the index
+	AvailabilityTimeout time.Duration
+	// AvailabilityPollInterval is the delay between index polls while waiting for availability
+	AvailabilityPollInterval time.Duration
+	// SmokeTest installs the published version from IndexURL into a throwaway virtualenv after
+	// the upload (off, warn, fail)
+	SmokeTest string
+	// SmokeTestImport imports the top-level modules of the wheel after installing it
+	SmokeTestImport bool
+	// SmokeTestPython is the interpreter creating the virtualenv
+	SmokeTestPython string
+	// Verifiers are the checks run in order after the upload to decide whether the release was
+	// published successfully
+	Verifiers []VerifierConfig
+	// VerifyCommand runs after the verifiers with the release described in RELEASE_* environment
+	// variables; a non-zero exit fails the publish
+	VerifyCommand []string
+	// Remediations map upload error output to suggested fixes, tried before the built-in rules
+	Remediations []RemediationRule
+	// YankOnRollback yanks the published version from the OnError hook when the release
+	// pipeline fails after the publish
+	YankOnRollback bool
+	// YankReason is the reason shown with the yanked version
+	YankReason string
+	// YankURL is the Warehouse web interface managing the project (defaults to https://pypi.org
+	// or https://test.pypi.org)
+	YankURL string
+	// YankWebSession yanks through the Warehouse web form with the logged-in session of
+	// YankSessionEnv, an unsupported interface; otherwise the yank is left to a maintainer
+	YankWebSession bool
+	// YankSessionEnv is the environment variable holding a logged-in Warehouse session
+	YankSessionEnv string
+	// DependencyReport compares the dependencies of the distributions with the previous release
+	// on the JSON API and reports those added, removed and changed
+	DependencyReport bool
+	// APIDiff runs APIDiffTool between the previous release and the new build and reports public
+	// symbols removed without a major version bump (off, warn, fail)
+	APIDiff string
+	// APIDiffTool selects the API diff tool: griffe (default) or abidiff
+	APIDiffTool string
+	// APIDiffCommand replaces the command of APIDiffTool
+	APIDiffCommand []string
+	// JSONAPIURL is the JSON API asked for published versions (defaults to PyPI's for PyPI and
+	// TestPyPI); other indexes are checked through IndexURL
+	JSONAPIURL string
+	// MaintainerAPIURL is the XML-RPC API reporting project roles (defaults to PyPI's for PyPI and TestPyPI)
+	MaintainerAPIURL string
+	// ReleaseMarkers are annotations created in observability backends after a successful publish
+	ReleaseMarkers []ReleaseMarker
+	// IssueTrackers move the issues fixed by released commits to a released status after a successful publish
+	IssueTrackers []IssueTracker
+	// ManifestSigningKey is a PEM private key, or the path of one, signing the publish manifest
+	ManifestSigningKey string
+	// ManifestKMSKeyID is an AWS KMS, Cloud KMS or Key Vault key signing the publish manifest
+	// instead of a local key
+	ManifestKMSKeyID string
+	// ManifestKMSAlgorithm is the KMS signing algorithm of ManifestKMSKeyID
+	ManifestKMSAlgorithm string
+	// ManifestKMSEndpoint replaces the AWS or Cloud KMS endpoint, such as a VPC endpoint
+	ManifestKMSEndpoint string
+	// ManifestPath is where the publish manifest is written, with its signature in ManifestPath.sig
+	ManifestPath string
+	// PKCS11Module is the PKCS#11 library of a hardware token signing the distribution files
+	PKCS11Module string
+	// PKCS11KeyID is the hex object ID of the signing key on the token
+	PKCS11KeyID string
+	// PKCS11KeyLabel is the label of the signing key, used instead of PKCS11KeyID
+	PKCS11KeyLabel string
+	// PKCS11TokenLabel selects the token when several are plugged in
+	PKCS11TokenLabel string
+	// PKCS11Mechanism is the pkcs11-tool signing mechanism of the key
+	PKCS11Mechanism string
+	// PKCS11PinEnv is the environment variable holding the user PIN of the token
+	PKCS11PinEnv string
+	// DistSignatureDir receives the signatures of the distribution files as <file>.sig
+	DistSignatureDir string
+	// NexusStagingDestination enables Nexus Repository Pro staging: Repository is the staging
+	// repository and verified uploads are moved to this repository on release
+	NexusStagingDestination string
+	// NexusStagingRelease releases a closed staging right away (defaults to true); false leaves
+	// it for a manual release
+	NexusStagingRelease bool
+	// NexusStagingReleaseTag releases a staging closed by an earlier run instead of uploading
+	NexusStagingReleaseTag string
+	// CanaryPercentage uploads the release as a canary rolled out to this percentage of
+	// consumers, recorded in a Nexus tag (0 disables)
+	CanaryPercentage int
+	// CanaryPromoteTag promotes the canary rollout of an earlier run, to CanaryPercentage or to
+	// stable, instead of uploading
+	CanaryPromoteTag string
+	// NexusURL is the Nexus base URL for the REST API (defaults to the part of Repository before /repository/)
+	NexusURL string
+	// IndexURL is the simple index polled for published files (defaults to the index of Repository)
+	IndexURL string
+	// ReleaseAudit compares the repository's release tags with the versions published on the
+	// index instead of uploading (off, warn, fail)
+	ReleaseAudit string
+	// AuditProject is the project audited (defaults to the name in the distribution metadata)
+	AuditProject string
+	// AuditTagPrefix is the prefix of release tags (defaults to "v")
+	AuditTagPrefix string
+	// LocalVersion is the policy for versions with a local segment (allow, strip, fail, redirect;
+	// defaults to fail for PyPI and TestPyPI, which reject them, and allow elsewhere)
+	LocalVersion string
+	// LocalVersionRepository is the private index local versions are uploaded to with redirect
+	LocalVersionRepository string
+	// VersionEpoch is the PEP 440 epoch added to the release version (1 publishes 2.0.0 as 1!2.0.0)
+	VersionEpoch int
+	// AllowBackfill permits publishing BackfillVersion outside the release flow
+	AllowBackfill bool
+	// BackfillVersion is a missed historical version published from BackfillDistPath instead of
+	// the current release
+	BackfillVersion string
+	// BackfillDistPath is the directory holding the artifacts of BackfillVersion
+	BackfillDistPath string
+	// BackfillConfirm must repeat BackfillVersion
+	BackfillConfirm string
+	// DevRelease publishes the release as version.devN to DevRepository, for nightly runs
+	DevRelease bool
+	// DevRepository is the separate index dev releases are uploaded to
+	DevRepository string
+	// DevNumber selects the N of dev versions: timestamp (default) or the CI run number
+	DevNumber string
+	// DevBuildCommand builds the dev release; without it the dist_path distributions are
+	// restamped with the dev version
+	DevBuildCommand []string
+	// DependencyWaitTimeout bounds how long a batch package waits for its dependencies to appear on the index
+	DependencyWaitTimeout time.Duration
+	// DependencyPollInterval is the delay between index polls while waiting for dependencies
+	DependencyPollInterval time.Duration
+	// CircuitBreakerThreshold is the number of consecutive server errors from a repository
+	// after which remaining uploads to it are aborted (defaults to 3; 0 disables)
+	CircuitBreakerThreshold int
+	// CircuitBreakerGlobalThreshold is the number of server errors across all repositories
+	// after which all remaining uploads are aborted (0 disables)
+	CircuitBreakerGlobalThreshold int
+	// ConnectTimeout bounds establishing a connection for native uploads
+	ConnectTimeout time.Duration
+	// TLSTimeout bounds the TLS handshake for native uploads
+	TLSTimeout time.Duration
+	// RequestTimeout bounds each native upload request (0 disables)
+	RequestTimeout time.Duration
+	// IdleTimeout aborts native uploads whose connection transferred no data for this long
+	IdleTimeout time.Duration
+	// TotalTimeout bounds the whole upload, native or twine (0 disables)
+	TotalTimeout time.Duration
+	// UploadTimeout bounds the whole hook, including builds, checks and waits (0 disables)
+	UploadTimeout time.Duration
+	// LogFile receives a structured JSON lines log of the publish with redacted commands
+	LogFile string
+	// HashesFile receives pip requirement lines pinning the published files to their SHA-256
+	// digests, for hash-checking constraints files
+	HashesFile string
+	// DebugHTTP logs the sanitized status lines and headers of native uploads and proxy CONNECT
+	// exchanges to the log file
+	DebugHTTP bool
+	// CustomCommand is an argument template run instead of twine to upload to indexes with
+	// their own CLI; {repository}, {files}, {env.NAME} and similar variables are substituted
+	CustomCommand []string
+	// ExtraArgs are additional long options appended to the twine command line
+	ExtraArgs []string
+	// WarningsAsErrors fails the publish when the upload output contains warnings
+	WarningsAsErrors bool
+	// WarningPatterns are regular expressions selecting the output lines treated as warnings
+	// (defaults to twine warnings and Python warning categories)
+	WarningPatterns []string
+	// MaxOutputBytes caps tool output captured into the response, keeping head and tail (0 disables)
+	MaxOutputBytes int
+	// StreamOutput forwards twine's output line by line to stderr while it runs
+	StreamOutput bool
+	// JobSummary appends a table of the published files to the GitHub Actions job summary
+	JobSummary bool
+	// CIAnnotations shows publishes in the UI of Buildkite, as build annotations, and of
+	// CircleCI, as test results
+	CIAnnotations bool
+	// ServiceMessages registers published files and versions with TeamCity or Jenkins (off,
+	// auto, teamcity, jenkins; defaults to off)
+	ServiceMessages string
+	// OutputsVersion selects the shape of publish outputs: 1 is flat, 2 nests the repository and
+	// lists every file with its status
+	OutputsVersion int
+	// FileSizeLimit and ProjectSizeLimit are the upload size limits of the repository in bytes,
+	// warned about when approached (0 uses the limits of PyPI and TestPyPI)
+	FileSizeLimit    int64
+	ProjectSizeLimit int64
+	// MaxErrorBodyBytes caps the index response body quoted in native upload errors
+	MaxErrorBodyBytes int
+	// IPFamily restricts upload connections to IPv4 or IPv6 (auto races both)
+	IPFamily string
+	// DNSServers resolve upload hostnames instead of the system resolver (ip or ip:port)
+	DNSServers []string
+	// StaticHosts maps lowercase hostnames to the IP addresses upload connections use
+	StaticHosts map[string]string
+	// ForceHTTP1 keeps native upload connections on HTTP/1.1
+	ForceHTTP1 bool
+	// DisableCompression stops native uploads from requesting compressed responses
+	DisableCompression bool
+	// ProxyHeaders are sent to the proxy with the CONNECT request of native uploads
+	ProxyHeaders map[string]string
+	// CredentialWarnings describes whitespace, quotes and invisible characters removed from the
+	// credentials by parseConfig
+	CredentialWarnings []string
 }
 
 // PyPIPlugin implements the Publish packages to PyPI (Python Package Index) plugin.
 type PyPIPlugin struct {
 	// cmdExecutor is used for executing shell commands. If nil, uses RealCommandExecutor.
 	cmdExecutor CommandExecutor
+	// httpClient is used for direct HTTP requests to the index. If nil, uses a default client.
+	httpClient *http.Client
+	// promptOutput receives interactive prompts such as device login codes. If nil, uses stderr.
+	promptOutput io.Writer
+	// streamWriter receives command output forwarded by stream_output. If nil, uses stderr.
+	streamWriter io.Writer
+	// interrupts delivers Ctrl-C during uploads. If nil, uses SIGINT in interactive sessions.
+	interrupts chan os.Signal
 }
 
 // getExecutor returns the command executor, defaulting to RealCommandExecutor.
@@ -64,37 +532,439 @@ func (p *PyPIPlugin) getExecutor() CommandExecutor {
 	return &RealCommandExecutor{}
 }
 
+// getHTTPClient returns the HTTP client, defaulting to a client with a conservative timeout.
+func (p *PyPIPlugin) getHTTPClient() *http.Client {
+	if p.httpClient != nil {
+		return p.httpClient
+	}
+	return &http.Client{Timeout: defaultHTTPTimeout}
+}
+
 // GetInfo returns plugin metadata.
 func (p *PyPIPlugin) GetInfo() plugin.Info {
 	return plugin.Info{
 		Name:        "pypi",
-		Version:     "2.0.0",
+		Version:     currentBuild().Version,
 		Description: "Publish packages to PyPI (Python Package Index)",
 		Author:      "Relicta Team",
 		Hooks: []plugin.Hook{
+			plugin.HookPrePublish,
 			plugin.HookPostPublish,
+			plugin.HookOnError,
 		},
 		ConfigSchema: `{
 			"type": "object",
 			"properties": {
 				"username": {"type": "string", "description": "PyPI username (or use PYPI_USERNAME env)"},
 				"password": {"type": "string", "description": "PyPI password or API token (or use PYPI_PASSWORD env)"},
+				"token": {"type": "string", "description": "PyPI API token, sent with the __token__ username (or use PYPI_TOKEN env)"},
+				"client_cert": {"type": "string", "description": "PEM client certificate for mTLS (key bundled unless client_key is set), reloaded when rotated during a publish"},
+				"client_key": {"type": "string", "description": "PEM private key of client_cert when stored in a separate file"},
+				"trusted_publishing": {"type": "boolean", "description": "Exchange the GitHub Actions or GitLab CI OIDC token for a short-lived PyPI API token (Trusted Publishing) instead of using username and password", "default": false},
+				"trusted_publishing_url": {"type": "string", "description": "Index exchanging OIDC tokens (defaults to https://pypi.org or https://test.pypi.org)"},
+				"oidc_token_env": {"type": "string", "description": "Environment variable holding the OIDC token outside GitHub Actions, such as a GitLab CI id_tokens entry", "default": "PYPI_ID_TOKEN"},
+				"attestations": {"type": "boolean", "description": "Upload a PEP 740 Sigstore attestation with each distribution, generating missing <file>.publish.attestation files (requires trusted_publishing)", "default": false},
+				"attestation_command": {"type": "array", "items": {"type": "string"}, "description": "Command signing the distributions given as arguments", "default": ["python3", "-m", "pypi_attestations", "sign"]},
+				"sign": {"type": "boolean", "description": "Upload a detached GPG signature with each distribution (not supported by PyPI)", "default": false},
+				"sign_identity": {"type": "string", "description": "GPG key ID, fingerprint or user ID signing the distributions (defaults to gpg's default key)"},
+				"gpg_program": {"type": "string", "description": "gpg executable creating the signatures", "default": "gpg"},
+				"device_auth": {"type": "boolean", "description": "Log in with the OAuth device flow before the upload, printing a code to approve in a browser (local releases only)", "default": false},
+				"device_auth_url": {"type": "string", "description": "Device authorization endpoint of the index's OAuth server"},
+				"device_token_url": {"type": "string", "description": "Token endpoint of the index's OAuth server"},
+				"device_client_id": {"type": "string", "description": "OAuth client ID registered for uploads"},
+				"device_scope": {"type": "string", "description": "OAuth scope requested for the upload token"},
+				"device_poll_interval": {"type": "string", "description": "Delay between token polls while waiting for approval, unless the server asks for longer", "default": "5s"},
+				"spiffe_workload_api": {"type": "boolean", "description": "Authenticate with the workload's X.509 SVID from the SPIFFE Workload API (mTLS)", "default": false},
+				"spiffe_endpoint_socket": {"type": "string", "description": "Workload API address such as unix:///run/spire/sockets/agent.sock (defaults to SPIFFE_ENDPOINT_SOCKET)"},
+				"spiffe_id": {"type": "string", "description": "SPIFFE ID of the SVID to use when the workload has several"},
+				"spiffe_server_id": {"type": "string", "description": "SPIFFE ID the index must present in its certificate, verified with the trust bundle of the Workload API instead of the host name"},
+				"pypirc_path": {"type": "string", "description": "Read the repository and credentials missing from the config and environment from this .pypirc file (defaults to ~/.pypirc when repository_name is set)"},
+				"repository_name": {"type": "string", "description": "Section of the .pypirc file to read, like twine --repository (defaults to $TWINE_REPOSITORY or pypi)"},
+				"use_netrc": {"type": "boolean", "description": "Read credentials missing from the config and environment from the netrc entry of the repository host ($NETRC or ~/.netrc)", "default": false},
+				"upload_backend": {"type": "string", "enum": ["auto", "twine", "native"], "description": "Uploader: twine, the built-in uploader implementing the legacy upload API (no Python needed on the runner), or auto to use twine unless the options need the built-in uploader", "default": "auto"},
+				"auth_scheme": {"type": "string", "enum": ["basic", "bearer", "none", "sigv4"], "description": "How credentials are sent: basic auth, a bearer token, the raw password as header value, or AWS SigV4 signing with the runner's AWS credentials", "default": "basic"},
+				"auth_header": {"type": "string", "description": "Header carrying the credentials for private indexes using API key headers", "default": "Authorization"},
+				"aws_region": {"type": "string", "description": "AWS region for sigv4 request signing (defaults to AWS_REGION or AWS_DEFAULT_REGION)"},
+				"aws_service": {"type": "string", "description": "AWS service name for sigv4 request signing (execute-api for API Gateway, s3 for S3)", "default": "execute-api"},
 				"repository": {"type": "string", "description": "Repository URL", "default": "https://upload.pypi.org/legacy/"},
 				"dist_path": {"type": "string", "description": "Path to distribution files", "default": "dist/*"},
-				"skip_existing": {"type": "boolean", "description": "Skip upload if version exists", "default": false}
+				"windows_extended_paths": {"type": "boolean", "description": "On Windows, accept UNC paths on network shares and paths longer than 256 characters as dist_path", "default": false},
+				"publish_branches": {"type": "array", "items": {"type": "string"}, "description": "Branches, as glob patterns like release/*, releases are uploaded from; releases of other branches are skipped"},
+				"skip_prereleases": {"type": "boolean", "description": "Skip uploading alpha, beta, rc and dev releases", "default": false},
+				"only_if_paths_changed": {"type": "array", "items": {"type": "string"}, "description": "Upload only releases changing a file matching one of these patterns (relative to the repository root, ** for any directories) since the previous release"},
+				"symlink_policy": {"type": "string", "enum": ["follow", "reject", "copy"], "description": "How symlinked distribution files, like those of Bazel output trees, are handled: upload their targets, fail the publish, or upload copies made into a temporary directory", "default": "follow"},
+				"fail_on_no_files": {"type": "boolean", "description": "Fail when dist_path matches no files; otherwise succeed without uploading", "default": true},
+				"check_version_match": {"type": "boolean", "description": "Fail when a wheel or sdist file name names another version than the release", "default": false},
+				"filename_policy": {
+					"type": "object",
+					"description": "Rules every distribution file name must satisfy before upload",
+					"properties": {
+						"patterns": {"type": "array", "items": {"type": "string"}, "description": "Regular expressions of which each file name must match one, with {project} and {version} placeholders"},
+						"require_project": {"type": "boolean", "description": "Require names to start with the wheel-normalized project name"},
+						"require_version": {"type": "boolean", "description": "Require the version in the name to be exactly the release version"},
+						"extensions": {"type": "array", "items": {"type": "string"}, "description": "Allowed file types, such as .whl and .tar.gz"}
+					}
+				},
+				"normalize_wheels": {"type": "boolean", "description": "Strip build-host paths, user names and timestamps from the wheels before uploading", "default": false},
+				"build": {"type": "boolean", "description": "Build the distributions into the directory of dist_path on the pre-publish hook", "default": false},
+				"build_backend": {"type": "string", "enum": ["build", "poetry", "hatch", "flit", "uv"], "description": "Build tool used by build", "default": "build"},
+				"build_command": {"type": "array", "items": {"type": "string"}, "description": "Command replacing the build backend, with {out_dir} and {version} variables"},
+				"skip_existing": {"type": "boolean", "description": "Skip upload if version exists", "default": false},
+				"concurrency": {"type": "integer", "description": "Number of files the built-in uploader uploads at once", "default": 1},
+				"continue_on_error": {"type": "boolean", "description": "Upload the remaining files after one fails instead of stopping (the publish still fails)", "default": false},
+				"inject_failure": {"type": "string", "enum": ["timeout", "http500", "partial"], "description": "Simulate a publish failure for pipeline testing (nothing is uploaded)"},
+				"benchmark": {"type": "boolean", "description": "Benchmark synthetic uploads to a staging index instead of publishing", "default": false},
+				"benchmark_iterations": {"type": "integer", "description": "Number of synthetic benchmark uploads", "default": 5},
+				"benchmark_size": {"type": "integer", "description": "Synthetic upload payload size in bytes", "default": 1048576},
+				"benchmark_package": {"type": "string", "description": "Project name used for synthetic uploads", "default": "relicta-pypi-benchmark"},
+				"config_key_command": {"type": "array", "items": {"type": "string"}, "description": "Command printing the base64 key for enc: values (or use RELICTA_PYPI_CONFIG_KEY / RELICTA_PYPI_CONFIG_KEY_FILE / RELICTA_PYPI_CONFIG_KEY_KMS env)"},
+				"token_command": {"type": "array", "items": {"type": "string"}, "description": "Command printing a short-lived upload token (plain, JWT, or JSON with expiry); refreshed automatically"},
+				"token_lifetime": {"type": "string", "description": "Assumed token lifetime when the command reports no expiry (e.g. 15m)"},
+				"token_refresh_margin": {"type": "string", "description": "Refresh tokens this long before they expire", "default": "5m"},
+				"credential_overrides": {
+					"type": "array",
+					"description": "Credentials for files matching a file name pattern (first match wins)",
+					"items": {
+						"type": "object",
+						"properties": {
+							"pattern": {"type": "string", "description": "File name glob, e.g. *.tar.gz"},
+							"username": {"type": "string"},
+							"password": {"type": "string"},
+							"password_env": {"type": "string", "description": "Environment variable holding the password"}
+						},
+						"required": ["pattern", "username"]
+					}
+				},
+				"codeartifact": {
+					"type": "object",
+					"description": "AWS CodeArtifact repository; the upload URL is derived and authorization tokens are obtained and refreshed with the runner's AWS credentials",
+					"properties": {
+						"domain": {"type": "string", "description": "CodeArtifact domain"},
+						"domain_owner": {"type": "string", "description": "12-digit AWS account ID owning the domain"},
+						"region": {"type": "string", "description": "Region of the domain (defaults to aws_region or AWS_REGION)"},
+						"repository": {"type": "string", "description": "Repository within the domain"},
+						"endpoint": {"type": "string", "description": "CodeArtifact API endpoint, such as a VPC endpoint"}
+					},
+					"required": ["domain", "domain_owner", "repository"]
+				},
+				"gcp_artifact_registry": {"type": "boolean", "description": "Upload to a Google Artifact Registry repository (https://<region>-python.pkg.dev/<project>/<repository>/) with access tokens from Application Default Credentials", "default": false},
+				"gcp_credentials_file": {"type": "string", "description": "Service account key used by gcp_artifact_registry instead of Application Default Credentials"},
+				"azure_artifacts": {
+					"type": "object",
+					"description": "Azure Artifacts feed; the upload URL is derived and uploads authenticate with a personal access token or federated credentials",
+					"properties": {
+						"organization": {"type": "string", "description": "Azure DevOps organization"},
+						"project": {"type": "string", "description": "Project of a project-scoped feed"},
+						"feed": {"type": "string", "description": "Feed name"},
+						"auth": {"type": "string", "enum": ["pat", "federated"], "description": "Authenticate with a personal access token or with an Entra ID token of federated credentials", "default": "pat"},
+						"pat_env": {"type": "string", "description": "Environment variable holding the personal access token", "default": "AZURE_DEVOPS_PAT"}
+					},
+					"required": ["organization", "feed"]
+				},
+				"artifactory": {
+					"type": "object",
+					"description": "JFrog Artifactory PyPI repository; the upload URL is derived and the password is sent as an access token or API key",
+					"properties": {
+						"base_url": {"type": "string", "description": "Artifactory URL, such as https://acme.jfrog.io/artifactory"},
+						"repo_key": {"type": "string", "description": "Key of a local repository, or of a virtual repository with a default deployment repository"},
+						"auth": {"type": "string", "enum": ["basic", "access_token", "api_key"], "description": "Send username and password, the password as a bearer access token, or the password in the X-JFrog-Art-Api header", "default": "basic"}
+					},
+					"required": ["base_url", "repo_key"]
+				},
+				"vulnerability_check": {"type": "string", "enum": ["off", "warn", "fail"], "description": "Check declared dependencies for known vulnerabilities before upload", "default": "off"},
+				"vulnerability_severity": {"type": "string", "enum": ["low", "moderate", "high", "critical"], "description": "Lowest severity that triggers the vulnerability check", "default": "critical"},
+				"vulnerability_source": {"type": "string", "enum": ["osv", "pip-audit"], "description": "Vulnerability database client", "default": "osv"},
+				"osv_url": {"type": "string", "description": "OSV query endpoint", "default": "https://api.osv.dev/v1/query"},
+				"requirements_file": {"type": "string", "description": "Pinned requirements file audited in addition to Requires-Dist"},
+				"license_check": {"type": "string", "enum": ["off", "warn", "fail"], "description": "Scan wheels for bundled code and libraries whose license is not allowlisted", "default": "off"},
+				"license_allowlist": {"type": "array", "items": {"type": "string"}, "description": "SPDX license identifiers permitted in bundled components (e.g. MIT, Apache-2.0)"},
+				"license_allow_unknown": {"type": "boolean", "description": "Accept bundled components whose license cannot be identified", "default": false},
+				"shared_object_check": {"type": "string", "enum": ["off", "warn", "fail"], "description": "Flag .so files in Linux wheels that are not in shared_object_allowlist", "default": "off"},
+				"shared_object_allowlist": {"type": "array", "items": {"type": "string"}, "description": "SHA-256 digests or in-wheel path globs of expected shared objects"},
+				"description_preview": {"type": "boolean", "description": "Render the long description to an HTML artifact as PyPI would", "default": false},
+				"description_preview_path": {"type": "string", "description": "Path of the rendered preview page", "default": "description-preview.html"},
+				"readme_renderer_command": {"type": "array", "items": {"type": "string"}, "description": "Command running readme_renderer", "default": ["python3", "-m", "readme_renderer"]},
+				"packages": {
+					"type": "array",
+					"description": "Batch mode: package configs published together, each merged over the top-level options",
+					"items": {
+						"type": "object",
+						"properties": {
+							"name": {"type": "string", "description": "Package name used in the aggregated report"},
+							"priority": {"type": "integer", "description": "Packages ready at the same time are published in descending priority", "default": 0},
+							"depends_on": {"type": "array", "items": {"type": "string"}, "description": "Names of packages that must be published and available on the index first"}
+						}
+					}
+				},
+				"repositories": {
+					"type": "array",
+					"description": "Publish the release to each repository, its config merged over the top-level options except the credentials",
+					"items": {
+						"type": "object",
+						"properties": {
+							"name": {"type": "string", "description": "Name used in the per-repository results (defaults to the repository URL)"},
+							"repository": {"type": "string", "description": "Repository upload URL"},
+							"username": {"type": "string", "description": "Username for this repository"},
+							"password": {"type": "string", "description": "Password or API token for this repository"},
+							"token": {"type": "string", "description": "API token for this repository"},
+							"skip_existing": {"type": "boolean", "description": "Skip upload if version exists on this repository", "default": false}
+						},
+						"required": ["repository"]
+					}
+				},
+				"batch_concurrency": {"type": "integer", "description": "Number of packages published at once in batch mode", "default": 4},
+				"mirror_consistency_check": {"type": "string", "enum": ["off", "warn", "fail"], "description": "Batch mode: verify that every index a project version was published to serves identical file digests", "default": "warn"},
+				"queue_dir": {"type": "string", "description": "Directory where publishes that fail because the index is down are queued with their files and a manifest"},
+				"resume_queued": {"type": "boolean", "description": "Upload the publishes queued in queue_dir instead of the current release", "default": false},
+				"status_check": {"type": "string", "enum": ["off", "warn", "fail"], "description": "Check the index status page for incidents in progress before publishing", "default": "off"},
+				"status_url": {"type": "string", "description": "Statuspage summary.json of the index", "default": "https://status.python.org/api/v2/summary.json"},
+				"status_wait": {"type": "string", "description": "How long to wait for an incident to be resolved before applying status_check (0 does not wait)", "default": "0"},
+				"status_poll_interval": {"type": "string", "description": "Delay between status page polls while waiting", "default": "30s"},
+				"maintainer_check": {"type": "string", "enum": ["off", "warn", "fail"], "description": "Check that the publishing account maintains the project and no unexpected maintainers appeared", "default": "off"},
+				"maintainer_account": {"type": "string", "description": "Index account expected among the maintainers (defaults to username unless it is __token__)"},
+				"expected_maintainers": {"type": "array", "items": {"type": "string"}, "description": "Accounts allowed to hold a role on the project"},
+				"on_existing": {"type": "string", "enum": ["off", "skip", "warn", "fail"], "description": "Check whether the version is already published before uploading; skip succeeds without uploading", "default": "off"},
+				"verify_only": {"type": "boolean", "description": "Skip the upload and verify that the version and dist_path files are published with matching digests", "default": false},
+				"signatures_only": {"type": "boolean", "description": "Upload the <file>.asc GPG signatures of dist_path files already published on the index instead of publishing (not supported by PyPI)", "default": false},
+				"wait_for_availability": {"type": "boolean", "description": "After the upload, poll index_url until it lists the uploaded files, so later steps can install the new version", "default": false},
+				"availability_timeout": {"type": "string", "description": "How long to wait for the uploaded files to appear on the index", "default": "5m"},
+				"availability_poll_interval": {"type": "string", "description": "Delay between index polls while waiting for availability", "default": "5s"},
+				"smoke_test": {"type": "string", "enum": ["off", "warn", "fail"], "description": "After the upload, pip install the published version from index_url into a throwaway virtualenv", "default": "off"},
+				"smoke_test_import": {"type": "boolean", "description": "Import the top-level modules of the wheel after the smoke test install", "default": true},
+				"smoke_test_python": {"type": "string", "description": "Python interpreter creating the smoke test virtualenv", "default": "python3"},
+				"verifiers": {
+					"type": "array",
+					"description": "Checks run in order after the upload that define a successful publish",
+					"items": {
+						"type": "object",
+						"properties": {
+							"type": {"type": "string", "enum": ["availability", "pip_install", "digest", "command"]},
+							"name": {"type": "string", "description": "Label of the verifier in outputs (defaults to type)"},
+							"on_failure": {"type": "string", "enum": ["warn", "fail"], "default": "fail"},
+							"command": {"type": "array", "items": {"type": "string"}, "description": "Command of a command verifier, with {project}, {version}, {repository}, {index_url} and {dist_path} variables"}
+						},
+						"required": ["type"]
+					}
+				},
+				"verify_command": {"type": "array", "items": {"type": "string"}, "description": "Command run after the upload with RELEASE_PROJECT, RELEASE_VERSION, RELEASE_URL and other RELEASE_* environment variables; a non-zero exit fails the publish"},
+				"remediations": {
+					"type": "array",
+					"description": "Suggested fixes put in front of upload errors whose output matches pattern, tried before the built-in rules",
+					"items": {
+						"type": "object",
+						"properties": {
+							"name": {"type": "string", "description": "Label of the rule in the remediation output"},
+							"pattern": {"type": "string", "description": "Regular expression matched against the upload error and output"},
+							"suggestion": {"type": "string", "description": "How to fix the failure"}
+						},
+						"required": ["pattern", "suggestion"]
+					}
+				},
+				"yank_on_rollback": {"type": "boolean", "description": "Yank the published version when the release pipeline fails after the publish", "default": false},
+				"yank_reason": {"type": "string", "description": "Reason shown with the yanked version", "default": "Rolled back: the release pipeline failed after publishing"},
+				"yank_url": {"type": "string", "description": "Warehouse web interface managing the project (defaults to https://pypi.org or https://test.pypi.org)"},
+				"yank_web_session": {"type": "boolean", "description": "UNSUPPORTED: yank through the Warehouse web form with the logged-in browser session of yank_session_env, which grants full account access and breaks when the page markup changes; otherwise the yank output gives manual instructions", "default": false},
+				"yank_session_env": {"type": "string", "description": "Environment variable holding a logged-in Warehouse session for yank_web_session, as API tokens cannot yank", "default": "PYPI_SESSION"},
+				"dependency_report": {"type": "boolean", "description": "Report the dependencies added, removed and changed since the previous release", "default": false},
+				"api_diff": {"type": "string", "enum": ["off", "warn", "fail"], "description": "Compare the public API with the previous release and report symbols removed without a major version bump", "default": "off"},
+				"api_diff_tool": {"type": "string", "enum": ["griffe", "abidiff"], "description": "API diff tool used by api_diff", "default": "griffe"},
+				"api_diff_command": {"type": "array", "items": {"type": "string"}, "description": "Command replacing the API diff tool's, with {project}, {package}, {version} and {previous_version} variables"},
+				"json_api_url": {"type": "string", "description": "JSON API asked for published versions (defaults to https://pypi.org/pypi or https://test.pypi.org/pypi)"},
+				"maintainer_api_url": {"type": "string", "description": "XML-RPC API reporting project roles (defaults to PyPI's for PyPI and TestPyPI)"},
+				"release_markers": {
+					"type": "array",
+					"description": "Release markers created in observability backends after a successful publish",
+					"items": {
+						"type": "object",
+						"properties": {
+							"provider": {"type": "string", "enum": ["grafana", "datadog", "newrelic"]},
+							"endpoint": {"type": "string", "description": "API base URL (required for grafana; defaults to api.datadoghq.com or the New Relic NerdGraph API)"},
+							"api_key": {"type": "string"},
+							"api_key_env": {"type": "string", "description": "Environment variable holding the API key"},
+							"tags": {"type": "array", "items": {"type": "string"}, "description": "Additional marker tags"},
+							"dashboard_uid": {"type": "string", "description": "Grafana dashboard the annotation is limited to"},
+							"entity_guid": {"type": "string", "description": "New Relic entity the deployment is recorded on (required for newrelic)"}
+						},
+						"required": ["provider"]
+					}
+				},
+				"nexus_staging_destination": {"type": "string", "description": "Nexus Repository Pro staging: repository verified uploads are moved to on release (the repository is the staging repository)"},
+				"nexus_staging_release": {"type": "boolean", "description": "Release a closed staging right away; false leaves it for a manual release", "default": true},
+				"nexus_staging_release_tag": {"type": "string", "description": "Release a staging closed by an earlier run instead of uploading"},
+				"canary_percentage": {"type": "integer", "description": "Upload the release to a Nexus repository as a canary rolled out to this percentage of consumers, recorded in a rollout tag (0 disables)", "default": 0},
+				"canary_promote_tag": {"type": "string", "description": "Promote the canary rollout tag of an earlier run to canary_percentage, or to stable when unset, without uploading"},
+				"nexus_url": {"type": "string", "description": "Nexus base URL for the REST API (defaults to the part of the repository URL before /repository/)"},
+				"issue_trackers": {
+					"type": "array",
+					"description": "Issue trackers whose issues fixed or closed by released commits are moved to a released status after a successful publish",
+					"items": {
+						"type": "object",
+						"properties": {
+							"provider": {"type": "string", "enum": ["jira", "linear"]},
+							"endpoint": {"type": "string", "description": "Jira site URL (required for jira) or Linear GraphQL API"},
+							"email": {"type": "string", "description": "Jira Cloud account of the API token; omit to send a bearer personal access token"},
+							"api_key": {"type": "string"},
+							"api_key_env": {"type": "string", "description": "Environment variable holding the API key"},
+							"status": {"type": "string", "description": "Workflow status issues are moved to", "default": "Released"},
+							"comment": {"type": "boolean", "description": "Comment on each issue with the release", "default": true},
+							"projects": {"type": "array", "items": {"type": "string"}, "description": "Jira project or Linear team keys handled by this tracker (default all)"}
+						},
+						"required": ["provider"]
+					}
+				},
+				"manifest_signing_key": {"type": "string", "description": "PEM private key (Ed25519, ECDSA or RSA), or the path of one, signing the publish manifest"},
+				"manifest_kms_key_id": {"type": "string", "description": "AWS KMS key ID or ARN, Cloud KMS key version name, or Azure Key Vault key URL signing the publish manifest instead of manifest_signing_key"},
+				"manifest_kms_algorithm": {"type": "string", "enum": ["ECDSA_SHA_256", "ECDSA_SHA_384", "ECDSA_SHA_512", "RSASSA_PKCS1_V1_5_SHA_256", "RSASSA_PKCS1_V1_5_SHA_384", "RSASSA_PKCS1_V1_5_SHA_512", "RSASSA_PSS_SHA_256", "RSASSA_PSS_SHA_384", "RSASSA_PSS_SHA_512"], "description": "KMS signing algorithm of manifest_kms_key_id", "default": "ECDSA_SHA_256"},
+				"manifest_kms_endpoint": {"type": "string", "description": "AWS or Cloud KMS endpoint replacing the default one, such as a VPC endpoint"},
+				"manifest_path": {"type": "string", "description": "File the publish manifest is written to, with its signature in <manifest_path>.sig"},
+				"pkcs11_module": {"type": "string", "description": "PKCS#11 library of a hardware token (PIV smart card, YubiKey) signing the distribution files with pkcs11-tool"},
+				"pkcs11_key_id": {"type": "string", "description": "Hex object ID of the signing key on the token, such as 02 for PIV slot 9c"},
+				"pkcs11_key_label": {"type": "string", "description": "Label of the signing key on the token, instead of pkcs11_key_id"},
+				"pkcs11_token_label": {"type": "string", "description": "Label of the token to use when several are plugged in"},
+				"pkcs11_mechanism": {"type": "string", "enum": ["ECDSA-SHA256", "ECDSA-SHA384", "ECDSA-SHA512", "SHA256-RSA-PKCS", "SHA384-RSA-PKCS", "SHA512-RSA-PKCS", "SHA256-RSA-PKCS-PSS", "SHA384-RSA-PKCS-PSS", "SHA512-RSA-PKCS-PSS"], "description": "Signing mechanism of the token key", "default": "ECDSA-SHA256"},
+				"pkcs11_pin_env": {"type": "string", "description": "Environment variable holding the user PIN of the token", "default": "PKCS11_PIN"},
+				"dist_signature_dir": {"type": "string", "description": "Directory receiving the <file>.sig signatures of the distribution files", "default": "signatures"},
+				"index_url": {"type": "string", "description": "Simple index polled for published files (defaults to the index of the repository for PyPI and TestPyPI)"},
+				"release_audit": {"type": "string", "enum": ["off", "warn", "fail"], "description": "Compare git release tags with the versions published on the index instead of publishing", "default": "off"},
+				"audit_project": {"type": "string", "description": "Project audited by release_audit (defaults to the name in the distribution metadata)"},
+				"audit_tag_prefix": {"type": "string", "description": "Prefix of the release tags compared by release_audit", "default": "v"},
+				"local_version": {"type": "string", "enum": ["allow", "strip", "fail", "redirect"], "description": "Policy for versions with a local segment such as +deadbeef (defaults to fail for PyPI and TestPyPI, which reject them, and allow elsewhere)"},
+				"local_version_repository": {"type": "string", "description": "Private index receiving local versions with local_version redirect"},
+				"version_epoch": {"type": "integer", "description": "PEP 440 epoch added to the release version (1 publishes 2.0.0 as 1!2.0.0)", "default": 0},
+				"allow_backfill": {"type": "boolean", "description": "Allow publishing backfill_version outside the release flow", "default": false},
+				"dev_release": {"type": "boolean", "description": "Publish the release as version.devN to dev_repository instead, for nightly runs", "default": false},
+				"dev_repository": {"type": "string", "description": "Separate index dev releases are uploaded to"},
+				"dev_number": {"type": "string", "enum": ["timestamp", "run"], "description": "N of dev versions: the UTC timestamp or the CI run number", "default": "timestamp"},
+				"dev_build_command": {"type": "array", "items": {"type": "string"}, "description": "Command building the dev release into {out_dir} with version {version}; without it the dist_path distributions are restamped"},
+				"backfill_version": {"type": "string", "description": "Missed historical version published from backfill_dist_path instead of the current release"},
+				"backfill_dist_path": {"type": "string", "description": "Directory holding the artifacts of backfill_version"},
+				"backfill_confirm": {"type": "string", "description": "Must repeat backfill_version to confirm the backfill"},
+				"dependency_wait_timeout": {"type": "string", "description": "How long a batch package waits for its dependencies to appear on the index", "default": "10m"},
+				"dependency_poll_interval": {"type": "string", "description": "Delay between index polls while waiting for dependencies", "default": "10s"},
+				"connect_timeout": {"type": "string", "description": "Time allowed to establish a connection for native uploads", "default": "30s"},
+				"tls_timeout": {"type": "string", "description": "Time allowed for the TLS handshake of native uploads", "default": "10s"},
+				"request_timeout": {"type": "string", "description": "Time allowed for each native upload request (0 disables)", "default": "5m"},
+				"idle_timeout": {"type": "string", "description": "Abort native uploads after this long without data transferred", "default": "2m"},
+				"ip_family": {"type": "string", "enum": ["auto", "ipv4", "ipv6"], "description": "IP family for upload connections; auto races IPv6 and IPv4 (Happy Eyeballs)", "default": "auto"},
+				"dns_servers": {"type": "array", "items": {"type": "string"}, "description": "DNS servers (ip or ip:port) resolving upload hostnames, for split-horizon DNS"},
+				"static_hosts": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Hosts-file style mapping of hostnames to IP addresses for upload connections"},
+				"force_http1": {"type": "boolean", "description": "Keep upload connections on HTTP/1.1, for proxies that break multipart uploads over HTTP/2", "default": false},
+				"disable_compression": {"type": "boolean", "description": "Do not request compressed responses from the index or proxy", "default": false},
+				"proxy_headers": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Headers sent to the HTTPS proxy with the CONNECT request of upload tunnels"},
+				"log_file": {"type": "string", "description": "Path of a structured JSON lines log of every command and upload attempt, with redacted credentials and timings"},
+				"hashes_file": {"type": "string", "description": "Path receiving pip requirement lines such as mypkg==1.0.0 --hash=sha256:... for the published files"},
+				"debug_http": {"type": "boolean", "description": "Log the status lines and headers of every native upload request and proxy CONNECT to log_file, with credentials redacted", "default": false},
+				"custom_command": {"type": "array", "items": {"type": "string"}, "description": "Command template run instead of twine, e.g. [\"acme-pkg\", \"push\", \"--to\", \"{repository}\", \"{files}\"]; supports {repository}, {dist_path}, {version}, {username}, {password}, {env.NAME} and {files}"},
+				"extra_args": {"type": "array", "items": {"type": "string"}, "description": "Additional twine long options such as --attestations; options set by the plugin and shell metacharacters are rejected"},
+				"warnings_as_errors": {"type": "boolean", "description": "Fail the publish when the upload output contains warnings matching warning_patterns", "default": false},
+				"warning_patterns": {"type": "array", "items": {"type": "string"}, "description": "Regular expressions of output lines promoted to errors (defaults to twine WARNING lines and Python warnings such as InsecureRequestWarning)"},
+				"max_output_bytes": {"type": "integer", "description": "Maximum bytes of tool output captured into the response; longer output keeps its head and tail (0 disables)", "default": 65536},
+				"file_size_limit": {"type": "integer", "description": "Per-file upload limit of the repository in bytes, warned about when approached (0 uses 100 MiB on PyPI and TestPyPI)", "default": 0},
+				"project_size_limit": {"type": "integer", "description": "Project size limit of the repository in bytes, warned about when a run's uploads approach it (0 uses 10 GiB on PyPI and TestPyPI)", "default": 0},
+				"stream_output": {"type": "boolean", "description": "Forward twine's output line by line to stderr, which the release tool logs, while it runs; credentials are masked", "default": false},
+				"service_messages": {"type": "string", "enum": ["off", "auto", "teamcity", "jenkins"], "description": "Register published files and the version with TeamCity service messages or a Jenkins properties file; auto detects the CI system", "default": "off"},
+				"ci_annotations": {"type": "boolean", "description": "Under Buildkite, annotate the build with the published files and set the version as meta-data; under CircleCI, write the files as JUnit results to test-results/pypi-publish", "default": true},
+				"job_summary": {"type": "boolean", "description": "Under GitHub Actions, append a table of the published files with sizes, digests, links and statuses to the job summary", "default": true},
+				"outputs_version": {"type": "integer", "enum": [1, 2], "description": "Shape of publish outputs: 1 reports the repository as its URL; 2 reports repository as {url, name} and adds files as [{name, size, sha256, url, status}]", "default": 1},
+				"max_error_body_bytes": {"type": "integer", "description": "Maximum bytes of an index error response quoted in native upload errors", "default": 4096},
+				"total_timeout": {"type": "string", "description": "Time allowed for the whole upload, including twine (0 disables)"},
+				"upload_timeout": {"type": "string", "description": "Time allowed for the whole hook, including builds, checks, waits and uploads; running commands and their child processes are terminated when it expires (0 disables)"},
+				"circuit_breaker_threshold": {"type": "integer", "description": "Consecutive server errors from a repository after which remaining uploads to it are aborted (0 disables)", "default": 3},
+				"circuit_breaker_global_threshold": {"type": "integer", "description": "Server errors across all repositories after which all remaining uploads are aborted (0 disables)", "default": 0}
 			},
 			"required": []
 		}`,
 	}
 }
 
-// Execute runs the plugin for a given hook.
+// Execute runs the plugin for a given hook, within upload_timeout when it is set.
 func (p *PyPIPlugin) Execute(ctx context.Context, req plugin.ExecuteRequest) (*plugin.ExecuteResponse, error) {
-	cfg := p.parseConfig(req.Config)
+	timeout, _ := durationOption(req.Config, "upload_timeout", 0)
+	if timeout <= 0 {
+		return p.executeHook(ctx, req)
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	resp, err := p.executeHook(hookCtx, req)
+	if resp != nil && !resp.Success && errors.Is(hookCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		resp.Error = fmt.Sprintf("upload_timeout of %s exceeded: %s", timeout, resp.Error)
+	}
+	return resp, err
+}
 
+// executeHook runs the plugin for a given hook.
+func (p *PyPIPlugin) executeHook(ctx context.Context, req plugin.ExecuteRequest) (*plugin.ExecuteResponse, error) {
 	switch req.Hook {
+	case plugin.HookPrePublish:
+		cfg, err := p.loadConfig(ctx, req.Config)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		if !cfg.Build || isBatchConfig(req.Config) {
+			return &plugin.ExecuteResponse{
+				Success: true,
+				Message: fmt.Sprintf("Hook %s not handled", req.Hook),
+			}, nil
+		}
+		if err := validateBuildConfig(cfg); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("configuration validation failed: %v", err),
+			}, nil
+		}
+		return p.buildPackage(ctx, cfg, req.Context, req.DryRun), nil
 	case plugin.HookPostPublish:
-		return p.uploadPackage(ctx, cfg, req.Context, req.DryRun)
+		if isBatchConfig(req.Config) || isFanOutConfig(req.Config) {
+			return p.runBatch(ctx, req), nil
+		}
+		cfg, err := p.loadConfig(ctx, req.Config)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		session := newPublishSession(cfg)
+		if cfg.ResumeQueued {
+			resp := p.resumeQueued(ctx, cfg, req.DryRun, session)
+			session.log.annotate(resp)
+			return resp, nil
+		}
+		resp, err := p.uploadPackage(ctx, cfg, req.Context, req.DryRun, session)
+		if resp != nil {
+			session.log.annotate(resp)
+		}
+		return resp, err
+	case plugin.HookOnError:
+		if isBatchConfig(req.Config) || isFanOutConfig(req.Config) {
+			return &plugin.ExecuteResponse{
+				Success: true,
+				Message: fmt.Sprintf("Hook %s not handled", req.Hook),
+			}, nil
+		}
+		cfg, err := p.loadConfig(ctx, req.Config)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		if !cfg.YankOnRollback {
+			return &plugin.ExecuteResponse{
+				Success: true,
+				Message: fmt.Sprintf("Hook %s not handled", req.Hook),
+			}, nil
+		}
+		if err := validateYankConfig(cfg); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("configuration validation failed: %v", err),
+			}, nil
+		}
+		return p.rollbackRelease(ctx, cfg, req.Context.Version, req.DryRun), nil
 	default:
 		return &plugin.ExecuteResponse{
 			Success: true,
@@ -103,8 +973,55 @@ func (p *PyPIPlugin) Execute(ctx context.Context, req plugin.ExecuteRequest) (*p
 	}
 }
 
-// uploadPackage executes twine upload with the configured options.
-func (p *PyPIPlugin) uploadPackage(ctx context.Context, cfg Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+// uploadPackage executes twine upload with the configured options. The session is shared by
+// all packages of a batch.
+func (p *PyPIPlugin) uploadPackage(ctx context.Context, cfg Config, releaseCtx plugin.ReleaseContext, dryRun bool, session *publishSession) (resp *plugin.ExecuteResponse, err error) {
+	start := time.Now()
+	session.log.addSecrets(cfg)
+	session.log.event(cfg, "publish_start", map[string]any{"version": releaseCtx.Version, "dry_run": dryRun})
+	var outcome uploadOutcome
+	defer func() {
+		end := time.Now()
+		fields := map[string]any{"duration_ms": end.Sub(start).Milliseconds()}
+		if resp != nil {
+			if resp.Outputs == nil {
+				resp.Outputs = map[string]any{}
+			}
+			addTimingOutputs(resp.Outputs, start, end)
+			// Tool output and errors can echo credentials, such as a repository URL with a
+			// password; cfg holds the latest minted or device login token here
+			maskResponse(cfg, resp)
+			version, _ := resp.Outputs["version"].(string)
+			if version == "" {
+				version = releaseCtx.Version
+			}
+			if err := writeJobSummary(cfg, resp, outcome, version, dryRun); err != nil {
+				resp.Outputs["job_summary_error"] = err.Error()
+				fields["job_summary_error"] = err.Error()
+			}
+			if err := p.annotateCI(ctx, cfg, resp, outcome, version, dryRun); err != nil {
+				resp.Outputs["ci_annotations_error"] = err.Error()
+				fields["ci_annotations_error"] = err.Error()
+			}
+			if resp.Success && !dryRun {
+				if err := p.emitServiceMessages(cfg, outcome, version); err != nil {
+					resp.Outputs["service_messages_error"] = err.Error()
+					fields["service_messages_error"] = err.Error()
+				}
+			}
+			if cfg.OutputsVersion == outputsVersionNested {
+				nestOutputs(cfg, resp.Outputs, outcome)
+			} else if len(outcome.files) > 1 {
+				resp.Outputs["file_results"] = fileOutputs(cfg, outcome)
+			}
+			fields["success"] = resp.Success
+			fields["message"] = resp.Message
+			fields["error"] = resp.Error
+			fields["output"] = resp.Outputs["output"]
+		}
+		session.log.event(cfg, "publish_end", fields)
+	}()
+
 	// Validate configuration
 	if err := p.validateConfig(cfg); err != nil {
 		return &plugin.ExecuteResponse{
@@ -113,97 +1030,973 @@ func (p *PyPIPlugin) uploadPackage(ctx context.Context, cfg Config, releaseCtx p
 		}, nil
 	}
 
-	version := strings.TrimPrefix(releaseCtx.Version, "v")
+	if cfg.Benchmark {
+		return p.runBenchmark(ctx, cfg, dryRun)
+	}
+	if cfg.NexusStagingReleaseTag != "" {
+		return p.releaseStagedTag(ctx, cfg, dryRun), nil
+	}
+	if cfg.CanaryPromoteTag != "" {
+		return p.promoteCanary(ctx, cfg, dryRun), nil
+	}
+	if auditsReleases(cfg) {
+		return p.auditReleases(ctx, cfg), nil
+	}
+	if cfg.VerifyOnly {
+		return p.verifyRelease(ctx, cfg, releaseCtx.Version), nil
+	}
+	if cfg.SignaturesOnly {
+		return p.uploadSignaturesOnly(ctx, cfg, releaseCtx.Version, dryRun), nil
+	}
+
+	version, err := releaseVersion(cfg, releaseCtx.Version)
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: err.Error()}, nil
+	}
+	if cfg.BackfillVersion != "" {
+		version = cfg.BackfillVersion
+	} else {
+		// A backfill names its version explicitly, so only releases are subject to the conditions
+		reason, err := p.publishSkipReason(ctx, cfg, releaseCtx, version)
+		if err != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: err.Error()}, nil
+		}
+		if reason != "" {
+			return &plugin.ExecuteResponse{
+				Success: true,
+				Message: "Skipped publishing: " + reason,
+				Outputs: map[string]any{"repository": cfg.Repository, "dist_path": cfg.DistPath, "version": version, "skipped": true, "skip_reason": reason, "plugin_build": currentBuild().String()},
+			}, nil
+		}
+	}
 
-	if dryRun {
+	// A dev release uploads version.devN to the dev index, from distributions built or restamped
+	// in a temporary directory, leaving the stable release untouched
+	var dev *devRelease
+	if cfg.DevRelease {
+		if dev, err = p.prepareDevRelease(ctx, cfg, version, dryRun); err != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("dev release failed: %v", err)}, nil
+		}
+		if dev.dir != "" {
+			defer func() { _ = os.RemoveAll(dev.dir) }()
+			cfg.DistPath = distDirGlob(dev.dir)
+		}
+		cfg = redirectedConfig(cfg, dev.Repository)
+		version = dev.Version
+	}
+
+	// PyPI rejects local versions (1.0.0+deadbeef) with an opaque 400, so the local_version
+	// policy applies before any check or upload
+	var localVersion map[string]any
+	distFiles, err := expandDistGlob(cfg.DistPath)
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("invalid dist path: %v", err)}, nil
+	}
+	if len(distFiles) == 0 {
+		msg := noDistFilesMessage(cfg.DistPath)
+		if cfg.FailOnNoFiles {
+			return &plugin.ExecuteResponse{Success: false, Error: msg}, nil
+		}
 		return &plugin.ExecuteResponse{
 			Success: true,
-			Message: fmt.Sprintf("Would upload package to %s", cfg.Repository),
-			Outputs: map[string]any{
-				"repository":    cfg.Repository,
-				"dist_path":     cfg.DistPath,
-				"skip_existing": cfg.SkipExisting,
-				"version":       version,
-			},
+			Message: msg + "; nothing to upload",
+			Outputs: map[string]any{"repository": cfg.Repository, "dist_path": cfg.DistPath, "skipped": true, "plugin_build": currentBuild().String()},
+		}, nil
+	}
+	// Build systems like Bazel produce symlink forests; how their links are uploaded is chosen
+	// explicitly instead of depending on twine and the operating system
+	symlinks, err := findDistSymlinks(cfg.DistPath)
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("invalid dist path: %v", err)}, nil
+	}
+	if len(symlinks) > 0 {
+		switch cfg.SymlinkPolicy {
+		case symlinkReject:
+			return &plugin.ExecuteResponse{Success: false, Error: rejectDistSymlinks(symlinks).Error(), Outputs: map[string]any{"symlinks": symlinks}}, nil
+		case symlinkCopy:
+			dir, err := os.MkdirTemp("", "relicta-pypi-copied-")
+			if err != nil {
+				return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("failed to create a directory for copied distributions: %v", err)}, nil
+			}
+			defer func() { _ = os.RemoveAll(dir) }()
+			if err := copyDistFiles(cfg.DistPath, dir); err != nil {
+				return &plugin.ExecuteResponse{Success: false, Error: err.Error()}, nil
+			}
+			cfg.DistPath = distDirGlob(dir)
+			if distFiles, err = expandDistGlob(cfg.DistPath); err != nil {
+				return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("invalid dist path: %v", err)}, nil
+			}
+		}
+	}
+	if local := localVersions(version, distFiles); len(local) > 0 {
+		policy := localVersionPolicy(cfg)
+		switch policy {
+		case localVersionFail:
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("local versions are not accepted by %s: %s; set local_version to strip or redirect", cfg.Repository, strings.Join(local, ", ")),
+			}, nil
+		case localVersionRedirect:
+			cfg = redirectedConfig(cfg, cfg.LocalVersionRepository)
+		case localVersionStrip:
+			version = stripLocalVersion(version)
+		}
+		localVersion = map[string]any{"policy": policy, "versions": local, "repository": cfg.Repository}
+	}
+
+	// Stale files of an earlier build would otherwise be published along with the release.
+	// Backfills select their files by version, and dev releases stamp their own.
+	if cfg.CheckVersionMatch && cfg.BackfillVersion == "" && dev == nil {
+		if mismatches := checkVersionMatch(version, distFiles, localVersionPolicy(cfg) == localVersionStrip); len(mismatches) > 0 {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   versionMismatchError(version, mismatches),
+				Outputs: map[string]any{"version_mismatches": mismatches},
+			}, nil
+		}
+	}
+
+	// Custom build scripts can misname artifacts in ways the index only rejects, or worse
+	// accepts, at upload time
+	if cfg.FilenamePolicy != nil && cfg.BackfillVersion == "" && dev == nil {
+		if violations := checkFilenamePolicy(cfg.FilenamePolicy, version, distFiles, localVersionPolicy(cfg) == localVersionStrip); len(violations) > 0 {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   filenamePolicyError(violations),
+				Outputs: map[string]any{"filename_violations": violations},
+			}, nil
+		}
+	}
+
+	preflight, blocked := p.runPreflight(ctx, cfg)
+	if blocked != nil {
+		return blocked, nil
+	}
+	if localVersion != nil {
+		preflight.outputs["local_version"] = localVersion
+	}
+	if dev != nil {
+		preflight.outputs["dev_release"] = dev
+	}
+	if len(symlinks) > 0 {
+		preflight.outputs["symlinks"] = symlinks
+	}
+	if err := checkDistEpochs(version, preflight.files); err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("version epoch mismatch: %v", err)}, nil
+	}
+	if cfg.BackfillVersion != "" {
+		if blocked := p.checkBackfill(ctx, cfg, preflight); blocked != nil {
+			return blocked, nil
+		}
+	} else if cfg.OnExisting != checkOff {
+		// Decide from the index instead of twine's error text; a skip ends the publish here
+		if done := p.checkExisting(ctx, cfg, version, preflight); done != nil {
+			outcome.files = preflight.files
+			outcome.existing, _ = done.Outputs["existing_files"].([]string)
+			return done, nil
+		}
+	}
+
+	// Upload each distinct file once, even when globs or batch packages overlap. twine receives
+	// the expanded files rather than dist_path, so it never sees a pattern matching nothing
+	uploadFiles, duplicates := dedupeDistFiles(preflight.files, cfg.Repository, session.digests)
+	if len(duplicates) > 0 {
+		preflight.outputs["duplicate_files"] = duplicates
+		for _, d := range duplicates {
+			preflight.warn("%s has the same SHA256 as %s and is uploaded once", d.Path, d.DuplicateOf)
+		}
+	}
+
+	if localVersion != nil && localVersion["policy"] == localVersionStrip {
+		if dryRun {
+			preflight.warn("local versions %s would be stripped from the distributions", strings.Join(localVersion["versions"].([]string), ", "))
+		} else {
+			dir, err := os.MkdirTemp("", "relicta-pypi-local-")
+			if err != nil {
+				return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("failed to create a directory for stripped distributions: %v", err)}, nil
+			}
+			defer func() { _ = os.RemoveAll(dir) }()
+			upload, stripped, err := stripLocalVersionFiles(uploadFiles, dir)
+			if err != nil {
+				return &plugin.ExecuteResponse{Success: false, Error: err.Error()}, nil
+			}
+			for i, f := range preflight.files {
+				if s, ok := stripped[f]; ok {
+					preflight.files[i] = s
+				}
+			}
+			uploadFiles = upload
+			localVersion["stripped_files"] = stripped
+		}
+	}
+
+	// Wheels are normalized in dry runs too, to report what would leak; nothing is uploaded
+	if cfg.NormalizeWheels {
+		dir, err := os.MkdirTemp("", "relicta-pypi-normalized-")
+		if err != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("failed to create a directory for normalized wheels: %v", err)}, nil
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+		upload, normalized, report, err := normalizeWheelFiles(uploadFiles, dir)
+		if err != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: err.Error()}, nil
+		}
+		for i, f := range preflight.files {
+			if n, ok := normalized[f]; ok {
+				preflight.files[i] = n
+			}
+		}
+		uploadFiles = upload
+		preflight.outputs["normalized_wheels"] = report
+	}
+	outcome.files = uploadFiles
+
+	if dryRun {
+		outputs := map[string]any{
+			"repository":    cfg.Repository,
+			"dist_path":     cfg.DistPath,
+			"skip_existing": cfg.SkipExisting,
+			"version":       version,
+			"channel":       releaseChannel(version),
+			"plugin_build":  currentBuild().String(),
+		}
+		if cfg.InjectFailure != "" {
+			outputs["inject_failure"] = cfg.InjectFailure
+		}
+		if len(cfg.CustomCommand) > 0 {
+			if command, err := renderCustomCommand(cfg, version, uploadFiles, true); err == nil {
+				outputs["custom_command"] = command
+			}
+		}
+		if cfg.NexusStagingDestination != "" {
+			outputs["nexus_staging"] = map[string]any{"destination": cfg.NexusStagingDestination, "release": cfg.NexusStagingRelease}
+		}
+		if cfg.CanaryPercentage > 0 {
+			outputs["canary"] = map[string]any{"channel": rolloutCanary, "percentage": cfg.CanaryPercentage}
+		}
+		if usesTokenSigning(cfg) {
+			outputs["dist_signing"] = map[string]any{"module": cfg.PKCS11Module, "mechanism": cfg.PKCS11Mechanism, "signature_dir": cfg.DistSignatureDir}
+		}
+		if cfg.Attestations {
+			outputs["attestation_command"] = cfg.AttestationCommand
+		}
+		if cfg.Sign {
+			outputs["gpg_signing"] = map[string]any{"program": cfg.GPGProgram, "identity": cfg.SignIdentity}
+		}
+		if suggestions := classifierSuggestions(preflight.files); len(suggestions) > 0 {
+			outputs["suggested_classifiers"] = suggestions
+			for _, c := range suggestions {
+				preflight.warn("missing classifier suggested by package metadata: %s", c)
+			}
+		}
+		preflight.apply(outputs)
+		message := fmt.Sprintf("Would upload package to %s", cfg.Repository)
+		if cfg.BackfillVersion != "" {
+			message = fmt.Sprintf("Would backfill version %s to %s", version, cfg.Repository)
+		} else if dev != nil {
+			message = fmt.Sprintf("Would publish dev release %s to %s", version, cfg.Repository)
+		}
+		return &plugin.ExecuteResponse{
+			Success:   true,
+			Message:   message,
+			Outputs:   outputs,
+			Artifacts: preflight.artifacts,
 		}, nil
 	}
 
-	// Build twine command arguments
-	args := p.buildTwineArgs(cfg)
+	// Give incidents found by the status check time to be resolved
+	if len(preflight.statusIncidents) > 0 {
+		if blocked := p.awaitIndexStatus(ctx, cfg, preflight); blocked != nil {
+			return blocked, nil
+		}
+	}
+
+	// Files are signed on the hardware token before the upload, so a missing token or a wrong
+	// PIN publishes nothing unsigned
+	if usesTokenSigning(cfg) && len(uploadFiles) > 0 {
+		signatures, artifacts, signErr := p.signDistFiles(ctx, cfg, uploadFiles)
+		if signErr != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: signErr.Error()}, nil
+		}
+		preflight.outputs["dist_signatures"] = signatures
+		preflight.artifacts = append(preflight.artifacts, artifacts...)
+	}
+
+	// The built-in uploader sends signatures made beforehand; twine signs as it uploads
+	if signsNatively(cfg) && len(uploadFiles) > 0 {
+		signatures, artifacts, signErr := p.gpgSignDistFiles(ctx, cfg, uploadFiles)
+		if signErr != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: signErr.Error()}, nil
+		}
+		preflight.outputs["gpg_signatures"] = signatures
+		preflight.artifacts = append(preflight.artifacts, artifacts...)
+	}
+
+	// Attestations are generated before the upload token is minted, so a failed signing
+	// publishes nothing without provenance
+	if cfg.Attestations && len(uploadFiles) > 0 {
+		attestations, artifacts, attestErr := p.attestDistFiles(ctx, cfg, uploadFiles)
+		if attestErr != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: attestErr.Error()}, nil
+		}
+		preflight.outputs["attestations"] = attestations
+		preflight.artifacts = append(preflight.artifacts, artifacts...)
+	}
 
-	// Execute twine upload
+	// Device login asks the maintainer to approve the upload right before it
+	if cfg.DeviceAuth {
+		token, authorization, authErr := p.authorizeDevice(ctx, cfg)
+		if authErr != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: authErr.Error()}, nil
+		}
+		if cfg.Username == "" && usesBasicAuth(cfg) {
+			cfg.Username = defaultTokenUsername
+		}
+		cfg.Password = token
+		session.log.addSecrets(cfg)
+		preflight.outputs["device_auth"] = authorization
+	}
+
+	// Trusted Publishing mints the upload token right before the upload, as it expires quickly
+	if cfg.TrustedPublishing {
+		token, minted, mintErr := p.mintTrustedPublishingToken(ctx, cfg)
+		if mintErr != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: mintErr.Error()}, nil
+		}
+		cfg.Username, cfg.Password = defaultTokenUsername, token
+		session.log.addSecrets(cfg)
+		preflight.outputs["trusted_publishing"] = minted
+	}
+
+	// Execute twine upload, simulating a failure instead when one is injected
 	executor := p.getExecutor()
-	output, err := executor.Run(ctx, "twine", args...)
-	if err != nil {
+	if cfg.InjectFailure != "" {
+		executor = newFaultInjectingExecutor(cfg)
+	}
+	if len(uploadFiles) == 0 {
+		outputs := map[string]any{
+			"repository":   cfg.Repository,
+			"dist_path":    cfg.DistPath,
+			"version":      version,
+			"channel":      releaseChannel(version),
+			"plugin_build": currentBuild().String(),
+		}
+		preflight.apply(outputs)
 		return &plugin.ExecuteResponse{
+			Success:   true,
+			Message:   "All distribution files were already uploaded in this run",
+			Outputs:   outputs,
+			Artifacts: preflight.artifacts,
+		}, nil
+	}
+
+	// Nexus staging opens a staging tag before the upload, like a Maven staging repository
+	var staging *nexusStaging
+	if cfg.NexusStagingDestination != "" {
+		var openErr error
+		if staging, openErr = p.openNexusStaging(ctx, cfg, preflight.files); openErr != nil {
+			return &plugin.ExecuteResponse{Success: false, Error: openErr.Error()}, nil
+		}
+	}
+
+	// The total timeout bounds the whole upload, including twine processes
+	uploadCtx, cancel := withTotalTimeout(ctx, cfg)
+	defer cancel()
+
+	var run uploadRun
+	var upload func(files []string) (uploadRun, error)
+	tool, native := "twine", false
+	switch {
+	case len(cfg.CustomCommand) > 0:
+		tool = filepath.Base(cfg.CustomCommand[0])
+		run, err = p.runCustomCommand(uploadCtx, cfg, session.breaker.wrap(session.log.wrap(executor, cfg), cfg.Repository), version, uploadFiles)
+	case usesNativeUploader(cfg) && cfg.InjectFailure == "":
+		// upload_backend native avoids twine on the runner, and twine only sends basic auth over
+		// default connections, so other schemes, headers and connection options need it too
+		tool, native = "native", true
+		upload = func(files []string) (uploadRun, error) {
+			return p.runNativeUploads(uploadCtx, cfg, session, files)
+		}
+	default:
+		upload = func(files []string) (uploadRun, error) {
+			return p.runTwineUploads(uploadCtx, cfg, session.breaker.wrap(session.log.wrap(executor, cfg), cfg.Repository), files)
+		}
+	}
+	if upload != nil {
+		// At a terminal, Ctrl-C stops between files instead of killing an upload halfway, and
+		// continue_on_error goes on after a failed file; both upload one file at a time, except
+		// parallel uploads, which stop starting files themselves
+		interrupt := p.watchInterrupts(cancel)
+		switch {
+		case interrupt != nil && native && cfg.Concurrency > 1 && len(uploadFiles) > 1:
+			session.interrupt = interrupt
+			run, err = upload(uploadFiles)
+		case interrupt != nil || cfg.ContinueOnError:
+			run, err = interrupt.uploadEach(uploadCtx, uploadFiles, cfg.ContinueOnError, upload)
+		default:
+			run, err = upload(uploadFiles)
+		}
+		interrupt.stop()
+	}
+	outcome.attempted, outcome.output, outcome.failed = true, run.output, err != nil
+	outcome.failuresNamed = len(run.failed) > 0 || cfg.ContinueOnError && upload != nil
+	for _, f := range run.failed {
+		outcome.failedFiles = append(outcome.failedFiles, filepath.Base(f))
+	}
+	outcome.staged = staging != nil
+
+	// Count what reached the index, including the files before a failed one, against the
+	// credentials and the size limits of the repository
+	var uploaded []string
+	statuses := outcome.statuses()
+	for _, f := range uploadFiles {
+		if s := statuses[filepath.Base(f)]; s == fileUploaded || s == fileStaged {
+			uploaded = append(uploaded, f)
+		}
+	}
+	project, _ := distProjectVersion(uploadFiles)
+	for _, w := range session.volume.record(cfg, project, uploaded) {
+		preflight.warn("%s", w)
+	}
+
+	// Deprecation and brownout notices of the index come from the responses of native uploads
+	// and from the output of upload tools
+	notices := run.notices
+	if !native {
+		notices = append(notices, noticesFromOutput(run.output)...)
+	}
+	notices = dedupeNotices(notices)
+
+	if err != nil {
+		if errors.Is(uploadCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			err = fmt.Errorf("total_timeout of %s exceeded: %w", cfg.TotalTimeout, err)
+		}
+		resp := &plugin.ExecuteResponse{
 			Success: false,
-			Error:   fmt.Sprintf("twine upload failed: %v\nOutput: %s", err, string(output)),
+			Error:   fmt.Sprintf("%s upload failed: %v\nOutput: %s", tool, err, limitOutput(cfg, run.output)),
+			Outputs: map[string]any{},
+		}
+		if errors.Is(err, errRepositoryUnhealthy) {
+			resp.Error = err.Error()
+		}
+		if fix := suggestRemediation(cfg, err.Error()+"\n"+run.output); fix != nil {
+			resp.Error = withRemediation(fix, resp.Error)
+			resp.Outputs["remediation"] = fix
+			if fix.Rule == remediationTwoFactor {
+				resp.Outputs["auth_failure"] = "two_factor_required"
+			}
+		}
+		if session.breaker.allow(cfg.Repository) != nil {
+			resp.Outputs["repository_status"] = "unhealthy"
+		}
+		if len(uploaded) > 0 {
+			resp.Outputs["upload_volume"] = session.volume.snapshot()
+		}
+		if cfg.InjectFailure != "" {
+			resp.Outputs["injected_failure"] = cfg.InjectFailure
+		}
+		if len(notices) > 0 {
+			resp.Outputs["index_notices"] = notices
+		}
+		if len(run.timings) > 0 {
+			resp.Outputs["upload_timings"] = run.timings
+		}
+		if staging != nil {
+			p.failStaging(ctx, staging, err)
+			resp.Outputs["nexus_staging"] = staging
+		}
+
+		// Record what an interrupt left out and how to upload it
+		var interrupted *interruptedUpload
+		if errors.As(err, &interrupted) {
+			var entry string
+			if cfg.QueueDir != "" {
+				var qerr error
+				if entry, qerr = queueUpload(cfg, version, interrupted.remaining, err); qerr != nil {
+					resp.Error += fmt.Sprintf("\nfailed to queue the remaining files: %v", qerr)
+				}
+			}
+			instructions := resumeInstructions(interrupted, entry)
+			_, _ = fmt.Fprintln(p.getPromptOutput(), instructions)
+			session.log.event(cfg, "interrupted", map[string]any{"remaining": interrupted.remaining, "queue_entry": entry})
+			resp.Error += "\n" + instructions
+			resp.Outputs["interrupted"] = true
+			resp.Outputs["remaining_files"] = interrupted.remaining
+			if entry != "" {
+				resp.Outputs["queue_entry"] = entry
+			}
+			return resp, nil
+		}
+
+		// Keep the publish for a later resume_queued run while the index is down
+		if cfg.QueueDir != "" && cfg.InjectFailure == "" && isOutageFailure(err, run.output) {
+			// Files continue_on_error uploaded before or after the outage are not queued again
+			queued := uploadFiles
+			var partial *partialFailure
+			if errors.As(err, &partial) {
+				queued = partial.failed
+			}
+			entry, qerr := queueUpload(cfg, version, queued, err)
+			if qerr != nil {
+				resp.Error += fmt.Sprintf("\nfailed to queue the upload: %v", qerr)
+				return resp, nil
+			}
+			outputs := map[string]any{
+				"repository":   cfg.Repository,
+				"dist_path":    cfg.DistPath,
+				"version":      version,
+				"channel":      releaseChannel(version),
+				"queued":       true,
+				"queue_entry":  entry,
+				"plugin_build": currentBuild().String(),
+			}
+			for k, v := range resp.Outputs {
+				outputs[k] = v
+			}
+			preflight.warn("upload failed because the index is unavailable (%v); queued for a resume_queued run", err)
+			preflight.apply(outputs)
+			return &plugin.ExecuteResponse{
+				Success:   true,
+				Message:   fmt.Sprintf("%s is unavailable; queued %d file(s) in %s for a resume_queued run", cfg.Repository, len(queued), entry),
+				Outputs:   outputs,
+				Artifacts: preflight.artifacts,
+			}, nil
+		}
+		return resp, nil
+	}
+
+	output, truncated := truncateOutput(run.output, cfg.MaxOutputBytes)
+	outputs := map[string]any{
+		"repository":   cfg.Repository,
+		"dist_path":    cfg.DistPath,
+		"version":      version,
+		"channel":      releaseChannel(version),
+		"output":       output,
+		"plugin_build": currentBuild().String(),
+	}
+	if truncated {
+		outputs["output_truncated"] = true
+	}
+	if len(run.timings) > 0 {
+		outputs["upload_timings"] = run.timings
+	}
+	if len(notices) > 0 {
+		outputs["index_notices"] = notices
+		for _, n := range notices {
+			preflight.warn("index %s notice: %s", n.Kind, n.Message)
+		}
+	}
+
+	// Hash-pinned constraints files can be updated from the digests of the published files
+	if requirements, hashErr := pipHashRequirements(uploadFiles, version); hashErr != nil {
+		preflight.warn("failed to compute pip hashes: %v", hashErr)
+	} else {
+		outputs["pip_hashes"] = requirements
+		if cfg.HashesFile != "" {
+			if err := writeHashesFile(cfg, requirements); err != nil {
+				preflight.warn("%v", err)
+			} else {
+				outputs["hashes_file"] = cfg.HashesFile
+			}
+		}
+	}
+
+	// Organization policy may treat upload warnings as failures. The files are already
+	// uploaded, so the release is reported as failed for follow-up rather than retried.
+	if staging != nil {
+		outputs["nexus_staging"] = staging
+	}
+	if warnings := promotedWarnings(cfg, run.output); len(warnings) > 0 {
+		outputs["promoted_warnings"] = warnings
+		preflight.apply(outputs)
+		return &plugin.ExecuteResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("upload reported %d warning(s) treated as errors by warnings_as_errors: %s", len(warnings), strings.Join(warnings, "; ")),
+			Outputs:   outputs,
+			Artifacts: preflight.artifacts,
 		}, nil
 	}
+	if usesTokenSource(cfg) {
+		outputs["token_refreshes"] = run.tokenRefreshes
+	}
+	if len(cfg.CredentialOverrides) > 0 {
+		outputs["upload_groups"] = uploadGroupOutputs(run.groups)
+	}
+	outputs["upload_volume"] = session.volume.snapshot()
+
+	message := fmt.Sprintf("Successfully uploaded package to %s", cfg.Repository)
+	if cfg.BackfillVersion != "" {
+		message = fmt.Sprintf("Backfilled version %s to %s", version, cfg.Repository)
+	} else if dev != nil {
+		message = fmt.Sprintf("Published dev release %s to %s", version, cfg.Repository)
+	}
+	if staging != nil {
+		if err := p.closeNexusStaging(ctx, staging, preflight.files); err != nil {
+			p.failStaging(ctx, staging, err)
+			// Dropping the staging loses every file, whatever the upload output says
+			outcome.output, outcome.failed = "", true
+			preflight.apply(outputs)
+			return &plugin.ExecuteResponse{
+				Success:   false,
+				Error:     fmt.Sprintf("nexus staging failed, staged files were dropped: %v", err),
+				Outputs:   outputs,
+				Artifacts: preflight.artifacts,
+			}, nil
+		}
+		if !cfg.NexusStagingRelease {
+			message = fmt.Sprintf("Staged package in %s as %s; release it with nexus_staging_release_tag", staging.Repository, staging.Tag)
+		} else if err := p.releaseNexusStaging(ctx, staging); err != nil {
+			// The closed staging stays in place for a retry with nexus_staging_release_tag
+			staging.Error = err.Error()
+			preflight.apply(outputs)
+			return &plugin.ExecuteResponse{
+				Success:   false,
+				Error:     err.Error(),
+				Outputs:   outputs,
+				Artifacts: preflight.artifacts,
+			}, nil
+		} else {
+			outcome.staged = false
+			message = fmt.Sprintf("Released package to %s through staging tag %s", staging.Destination, staging.Tag)
+		}
+	}
+
+	// A canary records its rollout for the organization's installers; a later run promotes it
+	if cfg.CanaryPercentage > 0 {
+		rollout, err := p.tagCanary(ctx, cfg, preflight.files)
+		if err != nil {
+			preflight.apply(outputs)
+			return &plugin.ExecuteResponse{
+				Success:   false,
+				Error:     fmt.Sprintf("uploaded, but the canary rollout was not recorded: %v", err),
+				Outputs:   outputs,
+				Artifacts: preflight.artifacts,
+			}, nil
+		}
+		outputs["canary"] = rollout
+		message = fmt.Sprintf("Uploaded %s %s to %s as a canary for %d%% of consumers", rollout.Project, rollout.Version, cfg.Repository, rollout.Percentage)
+	}
+
+	// Later release steps install the new version, so the publish ends once the index lists
+	// it, and the smoke test installs it like its first consumer. Files held in a closed
+	// staging are not on the index yet.
+	if (cfg.WaitForAvailability || runsSmokeTest(cfg)) && (staging == nil || staging.Status == stagingReleased) {
+		availability, err := p.awaitAvailability(ctx, cfg, uploadFiles)
+		outputs["availability"] = availability
+		if err != nil && cfg.WaitForAvailability {
+			preflight.apply(outputs)
+			return &plugin.ExecuteResponse{
+				Success:   false,
+				Error:     fmt.Sprintf("uploaded, but the release is not available yet: %v", err),
+				Outputs:   outputs,
+				Artifacts: preflight.artifacts,
+			}, nil
+		}
+		if runsSmokeTest(cfg) {
+			smoke := p.runSmokeTest(ctx, cfg, uploadFiles, err)
+			outputs["smoke_test"] = smoke
+			if smoke.Status != "passed" {
+				msg := fmt.Sprintf("smoke test of %s %s failed at the %s step: %s", smoke.Project, smoke.Version, smoke.Step, smoke.Error)
+				if cfg.SmokeTest == checkFail {
+					preflight.apply(outputs)
+					return &plugin.ExecuteResponse{
+						Success:   false,
+						Error:     "uploaded, but the " + msg,
+						Outputs:   outputs,
+						Artifacts: preflight.artifacts,
+					}, nil
+				}
+				preflight.warn("%s", msg)
+			}
+		}
+	}
+
+	// The verifiers define a successful publish, which files held in a closed staging are not yet
+	if len(configuredVerifiers(cfg)) > 0 && (staging == nil || staging.Status == stagingReleased) {
+		verifications, warnings, err := p.runVerifiers(ctx, cfg, uploadFiles)
+		outputs["verifications"] = verifications
+		for _, w := range warnings {
+			preflight.warn("%s", w)
+		}
+		if err != nil {
+			preflight.apply(outputs)
+			return &plugin.ExecuteResponse{
+				Success:   false,
+				Error:     fmt.Sprintf("uploaded, but %v", err),
+				Outputs:   outputs,
+				Artifacts: preflight.artifacts,
+			}, nil
+		}
+	}
+
+	// The signed manifest lets consumers verify which pipeline uploaded the files
+	if usesManifest(cfg) {
+		if err := p.recordPublishManifest(ctx, cfg, version, uploadFiles, preflight); err != nil {
+			preflight.apply(outputs)
+			return &plugin.ExecuteResponse{
+				Success:   false,
+				Error:     fmt.Sprintf("uploaded, but the publish manifest was not recorded: %v", err),
+				Outputs:   outputs,
+				Artifacts: preflight.artifacts,
+			}, nil
+		}
+	}
+
+	// Markers and issue transitions announce a release, which a closed staging is not yet and
+	// a backfilled historical version or a dev release is not at all
+	released := (staging == nil || staging.Status == stagingReleased) && cfg.BackfillVersion == "" && !cfg.DevRelease
+	if released && (len(cfg.ReleaseMarkers) > 0 || len(cfg.IssueTrackers) > 0) {
+		event := newReleaseEvent(cfg, version, preflight.files)
+		if len(cfg.ReleaseMarkers) > 0 {
+			markers := p.createReleaseMarkers(ctx, cfg, event)
+			outputs["release_markers"] = markers
+			for _, m := range markers {
+				if m.Error != "" {
+					preflight.warn("release marker not created: %s", m.Error)
+				}
+			}
+		}
+		if len(cfg.IssueTrackers) > 0 {
+			transitions := p.transitionIssues(ctx, cfg, referencedIssues(releaseCtx.Changes), event)
+			outputs["issue_transitions"] = transitions
+			for _, t := range transitions {
+				if t.Error != "" {
+					preflight.warn("issue %s not updated in %s: %s", t.Issue, t.Provider, t.Error)
+				}
+			}
+		}
+	}
+	preflight.apply(outputs)
 
 	return &plugin.ExecuteResponse{
-		Success: true,
-		Message: fmt.Sprintf("Successfully uploaded package to %s", cfg.Repository),
-		Outputs: map[string]any{
-			"repository": cfg.Repository,
-			"dist_path":  cfg.DistPath,
-			"version":    version,
-			"output":     string(output),
-		},
+		Success:   true,
+		Message:   message,
+		Outputs:   outputs,
+		Artifacts: preflight.artifacts,
 	}, nil
 }
 
 // buildTwineArgs constructs the command line arguments for twine upload.
 func (p *PyPIPlugin) buildTwineArgs(cfg Config) []string {
+	return p.buildTwineArgsForFiles(cfg, []string{cfg.DistPath})
+}
+
+// twineEnv returns the environment passing the credentials to twine. Unlike -u and -p, the
+// environment of a process is not visible to other users in ps or process auditing.
+func twineEnv(cfg Config) []string {
+	return []string{"TWINE_USERNAME=" + cfg.Username, "TWINE_PASSWORD=" + cfg.Password}
+}
+
+// buildTwineArgsForFiles constructs twine upload arguments for an explicit list of files or patterns.
+func (p *PyPIPlugin) buildTwineArgsForFiles(cfg Config, files []string) []string {
 	args := []string{"upload"}
 
 	// Repository URL
 	args = append(args, "--repository-url", cfg.Repository)
 
-	// Username and password
-	args = append(args, "-u", cfg.Username)
-	args = append(args, "-p", cfg.Password)
-
 	// Skip existing if enabled
 	if cfg.SkipExisting {
 		args = append(args, "--skip-existing")
 	}
 
-	// Distribution path
-	args = append(args, cfg.DistPath)
+	// Client certificate for mTLS
+	if cfg.ClientCert != "" {
+		args = append(args, "--client-cert", cfg.ClientCert)
+	}
+
+	// PEP 740 attestations next to the files
+	if cfg.Attestations && !containsString(cfg.ExtraArgs, "--attestations") {
+		args = append(args, "--attestations")
+	}
+
+	// Detached GPG signatures made by twine
+	if cfg.Sign {
+		args = append(args, "--sign", "--sign-with", cfg.GPGProgram)
+		if cfg.SignIdentity != "" {
+			args = append(args, "--identity", cfg.SignIdentity)
+		}
+	}
+
+	// Validated additional options
+	args = append(args, cfg.ExtraArgs...)
+
+	// Distribution files
+	args = append(args, files...)
 
 	return args
 }
 
-// validateConfig performs security validation on the configuration.
+// configCheck is a validation of the configuration whose failure Validate reports under field.
+type configCheck struct {
+	field string
+	check func(cfg Config) error
+}
+
+// configChecks lists the validations of the configuration, in the order validateConfig runs
+// them. Checks of options sharing a validation function pass it only their own option, so
+// that Validate reports the failure under the right field.
+func configChecks() []configCheck {
+	return []configCheck{
+		{"repository", validateRepositoryOption},
+		{"dist_path", validateDistPathOption},
+		{"username", validateUsername},
+		{"password", validatePassword},
+		{"credentials", validateCredentialEncoding},
+		{"token", validateTokenConfig},
+		{"codeartifact", validateCodeArtifactConfig},
+		{"gcp_artifact_registry", validateArtifactRegistryConfig},
+		{"azure_artifacts", validateAzureArtifactsConfig},
+		{"artifactory", validateArtifactoryConfig},
+		{"inject_failure", func(cfg Config) error { return validateInjectFailure(cfg.InjectFailure) }},
+		{"auth_scheme", func(cfg Config) error { return validateAuthConfig(Config{AuthScheme: cfg.AuthScheme}) }},
+		{"auth_header", func(cfg Config) error { return validateAuthConfig(Config{AuthHeader: cfg.AuthHeader}) }},
+		{"upload_backend", validateUploadBackend},
+		{"client_cert", validateClientCertConfig},
+		{"spiffe_workload_api", validateSpiffeConfig},
+		{"trusted_publishing", validateTrustedPublishingConfig},
+		{"attestations", validateAttestationConfig},
+		{"sign", validateGPGSignConfig},
+		{"device_auth", validateDeviceAuthConfig},
+		{"auth_scheme", validateSigV4Config},
+		{"request_timeout", validateTimeoutConfig},
+		{"upload_timeout", validateUploadTimeout},
+		{"ip_family", validateIPFamily},
+		{"max_output_bytes", validateOutputLimits},
+		{"log_file", validateLogFileConfig},
+		{"hashes_file", validateHashesFileConfig},
+		{"debug_http", validateDebugHTTPConfig},
+		{"extra_args", validateExtraArgs},
+		{"custom_command", validateCustomCommand},
+		{"warning_patterns", validateWarningConfig},
+		{"dns_servers", func(cfg Config) error { return validateResolverConfig(Config{DNSServers: cfg.DNSServers}) }},
+		{"static_hosts", func(cfg Config) error { return validateResolverConfig(Config{StaticHosts: cfg.StaticHosts}) }},
+		{"proxy_headers", validateProxyHeaders},
+		{"benchmark", validateBenchmarkConfig},
+		{"credential_overrides", func(cfg Config) error { return validateCredentialOverrides(cfg.CredentialOverrides) }},
+		{"vulnerability_check", validateVulnerabilityConfig},
+		{"license_check", validateLicenseConfig},
+		{"shared_object_allowlist", validateSharedObjectConfig},
+		{"description_preview_path", validateDescriptionPreviewConfig},
+		{"status_url", validateStatusConfig},
+		{"queue_dir", validateQueueConfig},
+		{"maintainer_check", validateMaintainerConfig},
+		{"on_existing", validateExistingConfig},
+		{"verify_only", validateVerifyOnlyConfig},
+		{"signatures_only", validateSignaturesOnlyConfig},
+		{"wait_for_availability", validateAvailabilityConfig},
+		{"filename_policy", func(cfg Config) error { return validateFilenamePolicy(cfg.FilenamePolicy) }},
+		{"smoke_test", validateSmokeTestConfig},
+		{"verifiers", validateVerifiers},
+		{"remediations", func(cfg Config) error { return validateRemediations(cfg.Remediations) }},
+		{"outputs_version", validateOutputsVersion},
+		{"continue_on_error", validateContinueOnError},
+		{"concurrency", validateConcurrency},
+		{"service_messages", validateServiceMessages},
+		{"publish_branches", validatePublishBranches},
+		{"only_if_paths_changed", validateOnlyIfPathsChanged},
+		{"symlink_policy", validateSymlinkPolicy},
+		{"file_size_limit", validateSizeLimits},
+		{"pypirc_path", validatePypircConfig},
+		{"yank_on_rollback", validateYankConfig},
+		{"dependency_report", validateDependencyReportConfig},
+		{"api_diff", validateAPIDiffConfig},
+		{"normalize_wheels", validateNormalizeWheelsConfig},
+		{"release_markers", func(cfg Config) error { return validateReleaseMarkers(cfg.ReleaseMarkers) }},
+		{"issue_trackers", func(cfg Config) error { return validateIssueTrackers(cfg.IssueTrackers) }},
+		{"manifest_signing_key", validateManifestConfig},
+		{"pkcs11_module", validateTokenSigningConfig},
+		{"nexus_staging_destination", validateNexusStagingConfig},
+		{"canary_percentage", validateCanaryConfig},
+		{"release_audit", validateAuditConfig},
+		{"backfill_version", validateBackfillConfig},
+		{"dev_release", validateDevReleaseConfig},
+		{"build", validateBuildConfig},
+		{"version_epoch", validateVersionEpoch},
+		{"local_version", validateLocalVersionConfig},
+		{"index_url", validateIndexURL},
+		{"circuit_breaker", validateCircuitBreakerConfig},
+	}
+}
+
+// validateConfig performs security validation on the configuration, stopping at the first
+// failed check.
 func (p *PyPIPlugin) validateConfig(cfg Config) error {
-	// Validate repository URL
-	if err := validateRepositoryURL(cfg.Repository); err != nil {
+	for _, c := range configChecks() {
+		if err := c.check(cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRepositoryOption validates the repository URL, resolving it as upload connections do.
+func validateRepositoryOption(cfg Config) error {
+	if err := validateRepositoryURLWith(cfg.Repository, uploadLookupIP(cfg)); err != nil {
 		return fmt.Errorf("invalid repository URL: %w", err)
 	}
+	return nil
+}
 
-	// Validate dist path
-	if err := validateDistPath(cfg.DistPath); err != nil {
+// validateDistPathOption validates the dist_path option.
+func validateDistPathOption(cfg Config) error {
+	if err := validateDistPathFor(cfg); err != nil {
 		return fmt.Errorf("invalid dist path: %w", err)
 	}
+	return nil
+}
+
+// requiresStaticCredentials reports whether the upload needs a configured password. A token
+// command, CodeArtifact, Artifact Registry or federated Azure Artifacts credentials supply it at
+// upload time, a SPIFFE workload identity, Trusted Publishing or a device login replaces it, a
+// custom command authenticates on its own, and release audits and verifications only read the
+// index.
+func requiresStaticCredentials(cfg Config) bool {
+	return !usesTokenSource(cfg) && len(cfg.CustomCommand) == 0 && !cfg.SpiffeWorkloadAPI && cfg.AuthScheme != authSchemeSigV4 && !auditsReleases(cfg) && !cfg.VerifyOnly && !cfg.TrustedPublishing && !cfg.DeviceAuth
+}
 
-	// Validate credentials are present
-	if cfg.Username == "" {
-		return fmt.Errorf("username is required")
+// validateUsername checks that static credentials have a username. Only basic auth sends one.
+func validateUsername(cfg Config) error {
+	if requiresStaticCredentials(cfg) && usesBasicAuth(cfg) && cfg.Username == "" {
+		return fmt.Errorf("username is required (set via config, PYPI_USERNAME env var, .pypirc, or use_netrc)")
 	}
-	if cfg.Password == "" {
-		return fmt.Errorf("password is required")
+	return nil
+}
+
+// validatePassword checks that static credentials have a password.
+func validatePassword(cfg Config) error {
+	if requiresStaticCredentials(cfg) && cfg.Password == "" {
+		return fmt.Errorf("password is required (set via config, token, PYPI_PASSWORD or PYPI_TOKEN env var, .pypirc, or use_netrc)")
 	}
+	return nil
+}
 
+// validateIndexURL validates the index_url option.
+func validateIndexURL(cfg Config) error {
+	if cfg.IndexURL == "" {
+		return nil
+	}
+	if err := validateRepositoryURL(cfg.IndexURL); err != nil {
+		return fmt.Errorf("invalid index_url: %w", err)
+	}
 	return nil
 }
 
 // validateRepositoryURL validates that a repository URL is safe (SSRF protection).
 func validateRepositoryURL(rawURL string) error {
+	return validateRepositoryURLWith(rawURL, net.LookupIP)
+}
+
+// validateRepositoryURLWith validates a URL, resolving its hostname with lookup.
+func validateRepositoryURLWith(rawURL string, lookup func(host string) ([]net.IP, error)) error {
 	if rawURL == "" {
 		return fmt.Errorf("repository URL cannot be empty")
 	}
 
+	// Unix sockets are local and reached without the network
+	if strings.HasPrefix(rawURL, unixRepositoryPrefix) {
+		return validateUnixRepository(rawURL)
+	}
+
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
 		return fmt.Errorf("invalid URL: %w", err)
@@ -230,7 +2023,7 @@ func validateRepositoryURL(rawURL string) error {
 	}
 
 	// Resolve hostname to check for private IPs
-	ips, err := net.LookupIP(host)
+	ips, err := lookup(host)
 	if err != nil {
 		return fmt.Errorf("failed to resolve hostname: %w", err)
 	}
@@ -292,22 +2085,21 @@ func validateDistPath(path string) error {
 		return fmt.Errorf("dist path too long (max 256 characters)")
 	}
 
+	// Normalize OS-specific separators so the checks below behave the same on every platform
+	normalized := toSlashPath(path)
+
 	// Check for valid characters
-	if !distPathPattern.MatchString(path) {
+	if !distPathPattern.MatchString(normalized) {
 		return fmt.Errorf("dist path contains invalid characters")
 	}
 
-	// Clean the path for traversal check
-	cleaned := filepath.Clean(path)
-
 	// Check for path traversal attempts (excluding glob patterns)
-	pathWithoutGlob := strings.ReplaceAll(cleaned, "*", "")
-	if strings.HasPrefix(pathWithoutGlob, "..") || strings.Contains(pathWithoutGlob, string(filepath.Separator)+"..") {
+	if escapesWorkDir(normalized) {
 		return fmt.Errorf("path traversal detected: cannot use '..' to escape working directory")
 	}
 
 	// Check for absolute paths (potential escape from working directory)
-	if filepath.IsAbs(path) {
+	if isAbsolutePath(path) {
 		return fmt.Errorf("absolute paths are not allowed")
 	}
 
@@ -315,40 +2107,139 @@ func validateDistPath(path string) error {
 }
 
 // Validate validates the plugin configuration.
-func (p *PyPIPlugin) Validate(_ context.Context, config map[string]any) (*plugin.ValidateResponse, error) {
+func (p *PyPIPlugin) Validate(ctx context.Context, config map[string]any) (*plugin.ValidateResponse, error) {
 	vb := helpers.NewValidationBuilder()
-	cfg := p.parseConfig(config)
 
-	// Username and password are required (can come from env vars)
-	if cfg.Username == "" {
-		vb.AddError("username", "username is required (set via config or PYPI_USERNAME env var)")
+	// Encrypted values must be decryptable before anything else can be checked
+	config, err := p.decryptConfig(ctx, config)
+	if err != nil {
+		vb.AddError("config", err.Error())
+		return vb.Build(), nil
 	}
-	if cfg.Password == "" {
-		vb.AddError("password", "password is required (set via config or PYPI_PASSWORD env var)")
+	if isBatchConfig(config) || isFanOutConfig(config) {
+		return p.validateBatch(ctx, config)
 	}
+	cfg := p.parseConfig(config)
 
-	// Validate repository URL
-	if cfg.Repository != "" {
-		if err := validateRepositoryURL(cfg.Repository); err != nil {
-			vb.AddError("repository", err.Error())
+	// Validate duration options
+	for _, key := range []string{"token_lifetime", "token_refresh_margin", "dependency_wait_timeout", "dependency_poll_interval", "status_wait", "status_poll_interval",
+		"availability_timeout", "availability_poll_interval", "device_poll_interval", "connect_timeout", "tls_timeout", "request_timeout", "idle_timeout", "total_timeout", "upload_timeout"} {
+		d, err := durationOption(config, key, time.Second)
+		if err != nil {
+			vb.AddError(key, err.Error())
+		} else if d == 0 && containsString(positiveTimeoutKeys, key) {
+			vb.AddError(key, key+" must be positive so a stalled connection cannot hang the upload")
 		}
 	}
 
-	// Validate dist path
-	if cfg.DistPath != "" {
-		if err := validateDistPath(cfg.DistPath); err != nil {
-			vb.AddError("dist_path", err.Error())
+	// The checks of validateConfig, reporting every failure rather than the first
+	for _, c := range configChecks() {
+		if err := c.check(cfg); err != nil {
+			vb.AddError(c.field, err.Error())
 		}
 	}
 
-	return vb.Build(), nil
+	resp := vb.Build()
+
+	for _, w := range cfg.CredentialWarnings {
+		addValidationWarning(resp, "credentials", w)
+	}
+	if cfg.YankOnRollback && cfg.YankWebSession {
+		addValidationWarning(resp, "yank_web_session", "yank_web_session drives the unsupported Warehouse web form with a logged-in account session; a change of the page breaks the rollback yank")
+	}
+	if len(cfg.WarningPatterns) > 0 && !cfg.WarningsAsErrors {
+		addValidationWarning(resp, "warning_patterns", "warning_patterns has no effect unless warnings_as_errors is enabled")
+	}
+
+	// Suggest missing classifiers when the distributions have already been built
+	if files, err := expandDistGlob(cfg.DistPath); err == nil && validateDistPathFor(cfg) == nil {
+		for _, c := range classifierSuggestions(files) {
+			addValidationWarning(resp, "classifiers", "missing classifier suggested by package metadata: "+c)
+		}
+	}
+
+	return resp, nil
+}
+
+// addValidationWarning appends an advisory entry that leaves the response valid.
+func addValidationWarning(resp *plugin.ValidateResponse, field, message string) {
+	resp.Errors = append(resp.Errors, plugin.ValidationError{
+		Field:   field,
+		Message: message,
+		Code:    validationWarningCode,
+	})
+}
+
+// loadConfig decrypts any encrypted values in the raw config and parses it.
+func (p *PyPIPlugin) loadConfig(ctx context.Context, raw map[string]any) (Config, error) {
+	decrypted, err := p.decryptConfig(ctx, raw)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to decrypt config: %w", err)
+	}
+	return p.parseConfig(decrypted), nil
 }
 
 // parseConfig parses the raw config map into a Config struct.
 func (p *PyPIPlugin) parseConfig(raw map[string]any) Config {
 	cfg := Config{
-		Repository: "https://upload.pypi.org/legacy/",
-		DistPath:   "dist/*",
+		Repository:               "https://upload.pypi.org/legacy/",
+		UploadBackend:            uploadBackendAuto,
+		AuthScheme:               authSchemeBasic,
+		AuthHeader:               defaultAuthHeader,
+		AWSService:               defaultAWSService,
+		DistPath:                 "dist/*",
+		FailOnNoFiles:            true,
+		BenchmarkIterations:      defaultBenchmarkIterations,
+		BenchmarkSize:            defaultBenchmarkSize,
+		BenchmarkPackage:         defaultBenchmarkPackage,
+		TokenRefreshMargin:       defaultTokenRefreshMargin,
+		OIDCTokenEnv:             defaultOIDCTokenEnv,
+		VulnerabilityCheck:       checkOff,
+		VulnerabilitySeverity:    "critical",
+		VulnerabilitySource:      vulnSourceOSV,
+		OSVURL:                   defaultOSVURL,
+		LicenseCheck:             checkOff,
+		SharedObjectCheck:        checkOff,
+		DescriptionPreviewPath:   defaultDescriptionPreviewPath,
+		StatusCheck:              checkOff,
+		OnExisting:               checkOff,
+		APIDiff:                  checkOff,
+		APIDiffTool:              apiDiffGriffe,
+		ManifestKMSAlgorithm:     defaultKMSAlgorithm,
+		PKCS11Mechanism:          defaultPKCS11Mechanism,
+		PKCS11PinEnv:             defaultPKCS11PinEnv,
+		DistSignatureDir:         defaultDistSignatureDir,
+		GPGProgram:               defaultGPGProgram,
+		MaintainerCheck:          checkOff,
+		ReleaseAudit:             checkOff,
+		AuditTagPrefix:           defaultAuditTagPrefix,
+		DevNumber:                devNumberTimestamp,
+		BuildBackend:             buildBackendBuild,
+		StatusURL:                defaultStatusURL,
+		StatusPollInterval:       defaultStatusPollInterval,
+		DevicePollInterval:       defaultDevicePollInterval,
+		DependencyWaitTimeout:    defaultDependencyWaitTimeout,
+		DependencyPollInterval:   defaultDependencyPollInterval,
+		AvailabilityTimeout:      defaultAvailabilityTimeout,
+		AvailabilityPollInterval: defaultAvailabilityPollInterval,
+		SmokeTest:                checkOff,
+		SmokeTestPython:          defaultSmokeTestPython,
+		YankReason:               defaultYankReason,
+		YankSessionEnv:           defaultYankSessionEnv,
+		CircuitBreakerThreshold:  defaultCircuitBreakerThreshold,
+		ConnectTimeout:           defaultConnectTimeout,
+		TLSTimeout:               defaultTLSTimeout,
+		RequestTimeout:           defaultHTTPTimeout,
+		IdleTimeout:              defaultIdleTimeout,
+		IPFamily:                 ipFamilyAuto,
+		MaxOutputBytes:           defaultMaxOutputBytes,
+		MaxErrorBodyBytes:        defaultMaxErrorBodyBytes,
+		OutputsVersion:           outputsVersionFlat,
+		JobSummary:               true,
+		CIAnnotations:            true,
+		ServiceMessages:          serviceMessagesOff,
+		Concurrency:              defaultConcurrency,
+		SymlinkPolicy:            symlinkFollow,
 	}
 
 	if v, ok := raw["username"].(string); ok && v != "" {
@@ -363,17 +2254,540 @@ func (p *PyPIPlugin) parseConfig(raw map[string]any) Config {
 		cfg.Password = v
 	}
 
+	// An API token implies the __token__ username and takes the place of the password, unless
+	// they are configured explicitly (which validation rejects)
+	configUsername, _ := raw["username"].(string)
+	configPassword, _ := raw["password"].(string)
+	if v, ok := raw["token"].(string); ok && v != "" {
+		cfg.Token = v
+	} else if v := os.Getenv("PYPI_TOKEN"); v != "" && configPassword == "" {
+		cfg.Token = v
+	}
+	if cfg.Token != "" {
+		if configUsername == "" {
+			cfg.Username = defaultTokenUsername
+		}
+		if configPassword == "" {
+			cfg.Password = cfg.Token
+		}
+	}
+
+	if v, ok := raw["client_cert"].(string); ok {
+		cfg.ClientCert = v
+	}
+	if v, ok := raw["client_key"].(string); ok {
+		cfg.ClientKey = v
+	}
+	if v, ok := raw["trusted_publishing"].(bool); ok {
+		cfg.TrustedPublishing = v
+	}
+	if v, ok := raw["oidc_token_env"].(string); ok {
+		cfg.OIDCTokenEnv = v
+	}
+	if v, ok := raw["attestations"].(bool); ok {
+		cfg.Attestations = v
+	}
+	if v, ok := raw["sign"].(bool); ok {
+		cfg.Sign = v
+	}
+	if v, ok := raw["sign_identity"].(string); ok {
+		cfg.SignIdentity = strings.TrimSpace(v)
+	}
+	if v, ok := raw["gpg_program"].(string); ok {
+		cfg.GPGProgram = strings.TrimSpace(v)
+	}
+	if v, ok := raw["device_auth"].(bool); ok {
+		cfg.DeviceAuth = v
+	}
+	if v, ok := raw["device_auth_url"].(string); ok {
+		cfg.DeviceAuthURL = v
+	}
+	if v, ok := raw["device_token_url"].(string); ok {
+		cfg.DeviceTokenURL = v
+	}
+	if v, ok := raw["device_client_id"].(string); ok {
+		cfg.DeviceClientID = v
+	}
+	if v, ok := raw["device_scope"].(string); ok {
+		cfg.DeviceScope = v
+	}
+	cfg.DevicePollInterval, _ = durationOption(raw, "device_poll_interval", cfg.DevicePollInterval)
+	if v, ok := raw["spiffe_workload_api"].(bool); ok {
+		cfg.SpiffeWorkloadAPI = v
+	}
+	cfg.SpiffeEndpointSocket = defaultSpiffeSocket()
+	if v, ok := raw["spiffe_endpoint_socket"].(string); ok && v != "" {
+		cfg.SpiffeEndpointSocket = v
+	}
+	if v, ok := raw["spiffe_id"].(string); ok {
+		cfg.SpiffeID = v
+	}
+	if v, ok := raw["spiffe_server_id"].(string); ok {
+		cfg.SpiffeServerID = v
+	}
+
+	if v, ok := raw["upload_backend"].(string); ok && v != "" {
+		cfg.UploadBackend = strings.ToLower(v)
+	}
+	// An artifactory block sends the password as its auth asks, unless auth_scheme and
+	// auth_header are configured (which validation rejects)
+	cfg.Artifactory = parseArtifactory(raw["artifactory"])
+	if cfg.Artifactory != nil {
+		cfg.AuthScheme, cfg.AuthHeader = cfg.Artifactory.authScheme()
+	}
+	if v, ok := raw["auth_scheme"].(string); ok && v != "" {
+		cfg.AuthScheme = strings.ToLower(v)
+	}
+	if v, ok := raw["auth_header"].(string); ok && v != "" {
+		cfg.AuthHeader = v
+	}
+	cfg.AWSRegion = defaultAWSRegion()
+	if v, ok := raw["aws_region"].(string); ok && v != "" {
+		cfg.AWSRegion = v
+	}
+	if v, ok := raw["aws_service"].(string); ok && v != "" {
+		cfg.AWSService = v
+	}
+
+	if v, ok := raw["pypirc_path"].(string); ok {
+		cfg.PypircPath = v
+	}
+	if v, ok := raw["repository_name"].(string); ok {
+		cfg.RepositoryName = v
+	}
+	var pypirc *pypircSection
+	if usesPypirc(cfg) {
+		if section, err := loadPypircSection(cfg); err == nil {
+			pypirc = &section
+		}
+	}
+
+	cfg.CodeArtifact = parseCodeArtifact(raw["codeartifact"])
+	cfg.AzureArtifacts = parseAzureArtifacts(raw["azure_artifacts"])
+	if cfg.CodeArtifact != nil && cfg.CodeArtifact.Region == "" {
+		cfg.CodeArtifact.Region = cfg.AWSRegion
+	}
 	if v, ok := raw["repository"].(string); ok && v != "" {
 		cfg.Repository = v
+	} else if cfg.CodeArtifact != nil {
+		cfg.Repository = cfg.CodeArtifact.repositoryURL()
+	} else if cfg.AzureArtifacts != nil {
+		cfg.Repository = cfg.AzureArtifacts.repositoryURL()
+	} else if cfg.Artifactory != nil {
+		cfg.Repository = cfg.Artifactory.repositoryURL()
+	} else if pypirc != nil {
+		cfg.Repository = pypirc.Repository
+	}
+	// A personal access token of an Azure Artifacts feed replaces the configured credentials
+	if cfg.AzureArtifacts != nil && cfg.AzureArtifacts.Auth == azureArtifactsAuthPAT {
+		if pat := strings.TrimSpace(os.Getenv(cfg.AzureArtifacts.PATEnv)); pat != "" {
+			cfg.Username, cfg.Password = azureArtifactsUsername, pat
+		}
+	}
+	if v, ok := raw["gcp_artifact_registry"].(bool); ok {
+		cfg.GCPArtifactRegistry = v
+	}
+	if v, ok := raw["gcp_credentials_file"].(string); ok {
+		cfg.GCPCredentialsFile = v
+	}
+
+	// Like twine, .pypirc credentials come after the config and environment
+	if pypirc != nil {
+		applyPypircCredentials(&cfg, *pypirc)
+	}
+
+	// Netrc entries fill in credentials missing from the config and environment
+	if v, ok := raw["use_netrc"].(bool); ok {
+		cfg.UseNetrc = v
+	}
+	if cfg.UseNetrc && (cfg.Username == "" || cfg.Password == "") {
+		if login, password, ok := netrcCredentials(cfg.Repository); ok {
+			if cfg.Username == "" {
+				cfg.Username = login
+			}
+			if cfg.Password == "" {
+				cfg.Password = password
+			}
+		}
 	}
 
 	if v, ok := raw["dist_path"].(string); ok && v != "" {
 		cfg.DistPath = v
 	}
+	if v, ok := raw["windows_extended_paths"].(bool); ok {
+		cfg.WindowsExtendedPaths = v
+	}
+	if v, ok := raw["symlink_policy"].(string); ok && v != "" {
+		cfg.SymlinkPolicy = v
+	}
+	if v, ok := raw["fail_on_no_files"].(bool); ok {
+		cfg.FailOnNoFiles = v
+	}
+	if v, ok := raw["check_version_match"].(bool); ok {
+		cfg.CheckVersionMatch = v
+	}
+	cfg.FilenamePolicy = parseFilenamePolicy(raw["filename_policy"])
+	if v, ok := raw["normalize_wheels"].(bool); ok {
+		cfg.NormalizeWheels = v
+	}
+	if v, ok := raw["build"].(bool); ok {
+		cfg.Build = v
+	}
+	if v, ok := raw["build_backend"].(string); ok && v != "" {
+		cfg.BuildBackend = v
+	}
 
 	if v, ok := raw["skip_existing"].(bool); ok {
 		cfg.SkipExisting = v
 	}
+	if v, ok := raw["continue_on_error"].(bool); ok {
+		cfg.ContinueOnError = v
+	}
+
+	if v, ok := raw["inject_failure"].(string); ok {
+		cfg.InjectFailure = v
+	}
+
+	parser := helpers.NewConfigParser(raw)
+	cfg.Concurrency = parser.GetInt("concurrency", cfg.Concurrency)
+	cfg.PublishBranches = parser.GetStringSlice("publish_branches", nil)
+	cfg.SkipPrereleases = parser.GetBool("skip_prereleases", false)
+	cfg.OnlyIfPathsChanged = parser.GetStringSlice("only_if_paths_changed", nil)
+	cfg.Benchmark = parser.GetBool("benchmark", false)
+	cfg.BenchmarkIterations = parser.GetInt("benchmark_iterations", cfg.BenchmarkIterations)
+	cfg.BenchmarkSize = int64(parser.GetInt("benchmark_size", int(cfg.BenchmarkSize)))
+	if v, ok := raw["benchmark_package"].(string); ok && v != "" {
+		cfg.BenchmarkPackage = v
+	}
+
+	cfg.TokenCommand = parser.GetStringSlice("token_command", nil)
+	cfg.TokenLifetime, _ = durationOption(raw, "token_lifetime", 0)
+	cfg.TokenRefreshMargin, _ = durationOption(raw, "token_refresh_margin", cfg.TokenRefreshMargin)
+	cfg.CredentialOverrides = parseCredentialOverrides(raw["credential_overrides"])
+
+	if v, ok := raw["vulnerability_check"].(string); ok && v != "" {
+		cfg.VulnerabilityCheck = v
+	}
+	if v, ok := raw["vulnerability_severity"].(string); ok && v != "" {
+		cfg.VulnerabilitySeverity = strings.ToLower(v)
+	}
+	if v, ok := raw["vulnerability_source"].(string); ok && v != "" {
+		cfg.VulnerabilitySource = v
+	}
+	if v, ok := raw["osv_url"].(string); ok && v != "" {
+		cfg.OSVURL = v
+	}
+	if v, ok := raw["requirements_file"].(string); ok {
+		cfg.RequirementsFile = v
+	}
+
+	if v, ok := raw["license_check"].(string); ok && v != "" {
+		cfg.LicenseCheck = v
+	}
+	cfg.LicenseAllowlist = parser.GetStringSlice("license_allowlist", nil)
+	cfg.LicenseAllowUnknown = parser.GetBool("license_allow_unknown", false)
+
+	if v, ok := raw["shared_object_check"].(string); ok && v != "" {
+		cfg.SharedObjectCheck = v
+	}
+	cfg.SharedObjectAllowlist = parser.GetStringSlice("shared_object_allowlist", nil)
+
+	cfg.DescriptionPreview = parser.GetBool("description_preview", false)
+	if v, ok := raw["description_preview_path"].(string); ok && v != "" {
+		cfg.DescriptionPreviewPath = v
+	}
+	cfg.ReadmeRendererCommand = parser.GetStringSlice("readme_renderer_command", defaultReadmeRendererCommand)
+	cfg.AttestationCommand = parser.GetStringSlice("attestation_command", defaultAttestationCommand)
+
+	if v, ok := raw["queue_dir"].(string); ok {
+		cfg.QueueDir = v
+	}
+	cfg.ResumeQueued = parser.GetBool("resume_queued", false)
+
+	if v, ok := raw["status_check"].(string); ok && v != "" {
+		cfg.StatusCheck = v
+	}
+	if v, ok := raw["status_url"].(string); ok && v != "" {
+		cfg.StatusURL = v
+	}
+	cfg.StatusWait, _ = durationOption(raw, "status_wait", 0)
+	cfg.StatusPollInterval, _ = durationOption(raw, "status_poll_interval", cfg.StatusPollInterval)
+	cfg.ReleaseMarkers = parseReleaseMarkers(raw["release_markers"])
+	cfg.IssueTrackers = parseIssueTrackers(raw["issue_trackers"])
+	if v, ok := raw["manifest_signing_key"].(string); ok {
+		cfg.ManifestSigningKey = v
+	}
+	if v, ok := raw["manifest_kms_key_id"].(string); ok {
+		cfg.ManifestKMSKeyID = v
+	}
+	if v, ok := raw["manifest_kms_algorithm"].(string); ok && v != "" {
+		cfg.ManifestKMSAlgorithm = v
+	}
+	if v, ok := raw["manifest_kms_endpoint"].(string); ok {
+		cfg.ManifestKMSEndpoint = v
+	}
+	if v, ok := raw["manifest_path"].(string); ok {
+		cfg.ManifestPath = v
+	}
+	if v, ok := raw["pkcs11_module"].(string); ok {
+		cfg.PKCS11Module = v
+	}
+	if v, ok := raw["pkcs11_key_id"].(string); ok {
+		cfg.PKCS11KeyID = v
+	}
+	if v, ok := raw["pkcs11_key_label"].(string); ok {
+		cfg.PKCS11KeyLabel = v
+	}
+	if v, ok := raw["pkcs11_token_label"].(string); ok {
+		cfg.PKCS11TokenLabel = v
+	}
+	if v, ok := raw["pkcs11_mechanism"].(string); ok && v != "" {
+		cfg.PKCS11Mechanism = v
+	}
+	if v, ok := raw["pkcs11_pin_env"].(string); ok && v != "" {
+		cfg.PKCS11PinEnv = v
+	}
+	if v, ok := raw["dist_signature_dir"].(string); ok && v != "" {
+		cfg.DistSignatureDir = v
+	}
+
+	if v, ok := raw["nexus_staging_destination"].(string); ok {
+		cfg.NexusStagingDestination = v
+	}
+	cfg.NexusStagingRelease = parser.GetBool("nexus_staging_release", true)
+	if v, ok := raw["nexus_staging_release_tag"].(string); ok {
+		cfg.NexusStagingReleaseTag = v
+	}
+	if v, ok := raw["nexus_url"].(string); ok {
+		cfg.NexusURL = v
+	}
+	cfg.CanaryPercentage = parser.GetInt("canary_percentage", 0)
+	if v, ok := raw["canary_promote_tag"].(string); ok {
+		cfg.CanaryPromoteTag = v
+	}
+
+	if v, ok := raw["index_url"].(string); ok && v != "" {
+		cfg.IndexURL = v
+	} else {
+		cfg.IndexURL = defaultIndexURL(cfg.Repository)
+	}
+	if v, ok := raw["trusted_publishing_url"].(string); ok && v != "" {
+		cfg.TrustedPublishingURL = v
+	} else {
+		cfg.TrustedPublishingURL = defaultTrustedPublishingURL(cfg.Repository)
+	}
+
+	if v, ok := raw["maintainer_check"].(string); ok && v != "" {
+		cfg.MaintainerCheck = v
+	}
+	if v, ok := raw["maintainer_account"].(string); ok {
+		cfg.MaintainerAccount = v
+	}
+	cfg.ExpectedMaintainers = parser.GetStringSlice("expected_maintainers", nil)
+	if v, ok := raw["on_existing"].(string); ok && v != "" {
+		cfg.OnExisting = v
+	}
+	if v, ok := raw["verify_only"].(bool); ok {
+		cfg.VerifyOnly = v
+	}
+	if v, ok := raw["signatures_only"].(bool); ok {
+		cfg.SignaturesOnly = v
+	}
+	if v, ok := raw["wait_for_availability"].(bool); ok {
+		cfg.WaitForAvailability = v
+	}
+	cfg.AvailabilityTimeout, _ = durationOption(raw, "availability_timeout", cfg.AvailabilityTimeout)
+	cfg.AvailabilityPollInterval, _ = durationOption(raw, "availability_poll_interval", cfg.AvailabilityPollInterval)
+	if v, ok := raw["smoke_test"].(string); ok && v != "" {
+		cfg.SmokeTest = v
+	}
+	cfg.SmokeTestImport = parser.GetBool("smoke_test_import", true)
+	if v, ok := raw["smoke_test_python"].(string); ok && v != "" {
+		cfg.SmokeTestPython = v
+	}
+	cfg.Verifiers = parseVerifiers(raw["verifiers"])
+	cfg.VerifyCommand = parser.GetStringSlice("verify_command", nil)
+	cfg.Remediations = parseRemediations(raw["remediations"])
+	if v, ok := raw["yank_on_rollback"].(bool); ok {
+		cfg.YankOnRollback = v
+	}
+	if v, ok := raw["yank_reason"].(string); ok && v != "" {
+		cfg.YankReason = v
+	}
+	if v, ok := raw["yank_url"].(string); ok && v != "" {
+		cfg.YankURL = v
+	} else {
+		cfg.YankURL = defaultWebURL(cfg.Repository)
+	}
+	if v, ok := raw["yank_web_session"].(bool); ok {
+		cfg.YankWebSession = v
+	}
+	if v, ok := raw["yank_session_env"].(string); ok && v != "" {
+		cfg.YankSessionEnv = v
+	}
+	if v, ok := raw["dependency_report"].(bool); ok {
+		cfg.DependencyReport = v
+	}
+	if v, ok := raw["api_diff"].(string); ok && v != "" {
+		cfg.APIDiff = v
+	}
+	if v, ok := raw["api_diff_tool"].(string); ok && v != "" {
+		cfg.APIDiffTool = v
+	}
+	if v, ok := raw["json_api_url"].(string); ok && v != "" {
+		cfg.JSONAPIURL = v
+	} else {
+		cfg.JSONAPIURL = defaultJSONAPI(cfg.Repository)
+	}
+	if v, ok := raw["maintainer_api_url"].(string); ok && v != "" {
+		cfg.MaintainerAPIURL = v
+	} else {
+		cfg.MaintainerAPIURL = defaultRoleAPI(cfg.Repository)
+	}
+
+	if v, ok := raw["release_audit"].(string); ok && v != "" {
+		cfg.ReleaseAudit = v
+	}
+	if v, ok := raw["audit_project"].(string); ok {
+		cfg.AuditProject = v
+	}
+	if v, ok := raw["audit_tag_prefix"].(string); ok {
+		cfg.AuditTagPrefix = v
+	}
+
+	cfg.VersionEpoch = parser.GetInt("version_epoch", 0)
+	if v, ok := raw["local_version"].(string); ok && v != "" {
+		cfg.LocalVersion = strings.ToLower(v)
+	}
+	if v, ok := raw["local_version_repository"].(string); ok {
+		cfg.LocalVersionRepository = v
+	}
+
+	// A backfill publishes the artifacts of backfill_dist_path instead of dist_path
+	cfg.AllowBackfill = parser.GetBool("allow_backfill", false)
+	if v, ok := raw["backfill_version"].(string); ok {
+		cfg.BackfillVersion = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	}
+	if v, ok := raw["backfill_confirm"].(string); ok {
+		cfg.BackfillConfirm = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	}
+	if v, ok := raw["backfill_dist_path"].(string); ok {
+		cfg.BackfillDistPath = v
+	}
+	if cfg.BackfillVersion != "" && cfg.BackfillDistPath != "" {
+		cfg.DistPath = distDirGlob(cfg.BackfillDistPath)
+	}
+
+	cfg.DevRelease = parser.GetBool("dev_release", false)
+	if v, ok := raw["dev_repository"].(string); ok {
+		cfg.DevRepository = v
+	}
+	if v, ok := raw["dev_number"].(string); ok && v != "" {
+		cfg.DevNumber = v
+	}
+	cfg.DevBuildCommand = parser.GetStringSlice("dev_build_command", nil)
+	cfg.BuildCommand = parser.GetStringSlice("build_command", nil)
+	cfg.APIDiffCommand = parser.GetStringSlice("api_diff_command", nil)
+
+	cfg.DependencyWaitTimeout, _ = durationOption(raw, "dependency_wait_timeout", cfg.DependencyWaitTimeout)
+	cfg.DependencyPollInterval, _ = durationOption(raw, "dependency_poll_interval", cfg.DependencyPollInterval)
+	cfg.ConnectTimeout, _ = durationOption(raw, "connect_timeout", cfg.ConnectTimeout)
+	cfg.TLSTimeout, _ = durationOption(raw, "tls_timeout", cfg.TLSTimeout)
+	cfg.RequestTimeout, _ = durationOption(raw, "request_timeout", cfg.RequestTimeout)
+	cfg.IdleTimeout, _ = durationOption(raw, "idle_timeout", cfg.IdleTimeout)
+	cfg.TotalTimeout, _ = durationOption(raw, "total_timeout", 0)
+	cfg.UploadTimeout, _ = durationOption(raw, "upload_timeout", 0)
+	if v, ok := raw["ip_family"].(string); ok && v != "" {
+		cfg.IPFamily = strings.ToLower(v)
+	}
+	for _, server := range parser.GetStringSlice("dns_servers", nil) {
+		if normalized, err := normalizeDNSServer(server); err == nil {
+			server = normalized
+		}
+		cfg.DNSServers = append(cfg.DNSServers, server)
+	}
+	if hosts, ok := raw["static_hosts"].(map[string]any); ok {
+		cfg.StaticHosts = make(map[string]string, len(hosts))
+		for host, ip := range hosts {
+			s, _ := ip.(string)
+			cfg.StaticHosts[strings.ToLower(host)] = s
+		}
+	}
+	if v, ok := raw["force_http1"].(bool); ok {
+		cfg.ForceHTTP1 = v
+	}
+	if v, ok := raw["disable_compression"].(bool); ok {
+		cfg.DisableCompression = v
+	}
+	if headers, ok := raw["proxy_headers"].(map[string]any); ok {
+		cfg.ProxyHeaders = make(map[string]string, len(headers))
+		for name, value := range headers {
+			s, _ := value.(string)
+			cfg.ProxyHeaders[name] = s
+		}
+	}
+
+	cfg.CircuitBreakerThreshold = parser.GetInt("circuit_breaker_threshold", cfg.CircuitBreakerThreshold)
+	cfg.CircuitBreakerGlobalThreshold = parser.GetInt("circuit_breaker_global_threshold", 0)
+	cfg.LogFile, _ = raw["log_file"].(string)
+	cfg.HashesFile, _ = raw["hashes_file"].(string)
+	if v, ok := raw["debug_http"].(bool); ok {
+		cfg.DebugHTTP = v
+	}
+	cfg.CustomCommand = parser.GetStringSlice("custom_command", nil)
+	cfg.ExtraArgs = parser.GetStringSlice("extra_args", nil)
+	cfg.WarningsAsErrors = parser.GetBool("warnings_as_errors", false)
+	cfg.WarningPatterns = parser.GetStringSlice("warning_patterns", nil)
+	cfg.MaxOutputBytes = parser.GetInt("max_output_bytes", cfg.MaxOutputBytes)
+	cfg.OutputsVersion = parser.GetInt("outputs_version", cfg.OutputsVersion)
+	if v, ok := raw["stream_output"].(bool); ok {
+		cfg.StreamOutput = v
+	}
+	if v, ok := raw["job_summary"].(bool); ok {
+		cfg.JobSummary = v
+	}
+	if v, ok := raw["ci_annotations"].(bool); ok {
+		cfg.CIAnnotations = v
+	}
+	if v, ok := raw["service_messages"].(string); ok && v != "" {
+		cfg.ServiceMessages = strings.ToLower(v)
+	}
+	cfg.FileSizeLimit = int64(parser.GetInt("file_size_limit", int(cfg.FileSizeLimit)))
+	cfg.ProjectSizeLimit = int64(parser.GetInt("project_size_limit", int(cfg.ProjectSizeLimit)))
+	cfg.MaxErrorBodyBytes = parser.GetInt("max_error_body_bytes", cfg.MaxErrorBodyBytes)
+
+	cleanCredentials(&cfg)
 
 	return cfg
 }
+
+// durationOption reads a duration from raw[key], given either as a Go duration string
+// ("90s", "15m") or as a number of seconds. defaultVal is returned when the key is absent
+// or invalid.
+func durationOption(raw map[string]any, key string, defaultVal time.Duration) (time.Duration, error) {
+	switch v := raw[key].(type) {
+	case nil:
+		return defaultVal, nil
+	case string:
+		if v == "" {
+			return defaultVal, nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return defaultVal, fmt.Errorf("%s must be a non-negative duration such as \"30s\" or \"5m\"", key)
+		}
+		return d, nil
+	case float64:
+		if v < 0 {
+			return defaultVal, fmt.Errorf("%s must not be negative", key)
+		}
+		return time.Duration(v * float64(time.Second)), nil
+	case int:
+		if v < 0 {
+			return defaultVal, fmt.Errorf("%s must not be negative", key)
+		}
+		return time.Duration(v) * time.Second, nil
+	default:
+		return defaultVal, fmt.Errorf("%s must be a duration string or a number of seconds", key)
+	}
+}
@@ -6,35 +6,116 @@ import (
 	"errors"
 	"net"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
 )
 
+// TestMain keeps tests run by GitHub Actions, Buildkite and CircleCI from writing job
+// summaries and annotations.
+func TestMain(m *testing.M) {
+	for _, env := range []string{stepSummaryEnv, "BUILDKITE", "CIRCLECI"} {
+		_ = os.Unsetenv(env)
+	}
+	os.Exit(m.Run())
+}
+
 // MockCommandExecutor is a mock implementation of CommandExecutor for testing.
 type MockCommandExecutor struct {
 	RunFunc     func(ctx context.Context, name string, args ...string) ([]byte, error)
 	RunCalls    []MockRunCall
 	ReturnError error
 	ReturnOut   []byte
+	// ReturnStderr is appended to ReturnOut by Run and RunWithEnv, and left out by Output
+	ReturnStderr []byte
+
+	mu sync.Mutex
 }
 
-// MockRunCall records a call to Run.
+// MockRunCall records a call to Run or RunWithEnv.
 type MockRunCall struct {
 	Name string
 	Args []string
+	Env  []string
 }
 
 // Run implements CommandExecutor.
 func (m *MockCommandExecutor) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return m.RunWithEnv(ctx, nil, name, args...)
+}
+
+// RunWithEnv implements CommandExecutor.
+func (m *MockCommandExecutor) RunWithEnv(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	m.mu.Lock()
+	m.RunCalls = append(m.RunCalls, MockRunCall{Name: name, Args: args, Env: env})
+	m.mu.Unlock()
+	if m.RunFunc != nil {
+		return m.RunFunc(ctx, name, args...)
+	}
+	if len(m.ReturnStderr) > 0 {
+		return append(append([]byte{}, m.ReturnOut...), m.ReturnStderr...), m.ReturnError
+	}
+	return m.ReturnOut, m.ReturnError
+}
+
+// Output implements outputExecutor.
+func (m *MockCommandExecutor) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	m.mu.Lock()
 	m.RunCalls = append(m.RunCalls, MockRunCall{Name: name, Args: args})
+	m.mu.Unlock()
 	if m.RunFunc != nil {
 		return m.RunFunc(ctx, name, args...)
 	}
 	return m.ReturnOut, m.ReturnError
 }
 
+// runOnlyExecutor implements CommandExecutor without Output, like executors written before it.
+type runOnlyExecutor struct{ mock *MockCommandExecutor }
+
+// Run implements CommandExecutor.
+func (e runOnlyExecutor) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return e.mock.Run(ctx, name, args...)
+}
+
+// RunWithEnv implements CommandExecutor.
+func (e runOnlyExecutor) RunWithEnv(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	return e.mock.RunWithEnv(ctx, env, name, args...)
+}
+
+func TestCommandOutput(t *testing.T) {
+	mock := &MockCommandExecutor{ReturnOut: []byte("key\n"), ReturnStderr: []byte("warning\n")}
+	if out, _ := commandOutput(context.Background(), mock, "vault"); string(out) != "key\n" {
+		t.Errorf("expected stdout only, got %q", out)
+	}
+	legacy := runOnlyExecutor{mock: mock}
+	if out, _ := commandOutput(context.Background(), legacy, "vault"); string(out) != "key\nwarning\n" {
+		t.Errorf("expected the combined output of Run, got %q", out)
+	}
+}
+
+// writeTestDists changes to a temporary directory holding a distribution for each dist_path
+// of the Execute tests.
+func writeTestDists(t *testing.T) {
+	t.Helper()
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	for _, dir := range []string{"build/dist", "output"} {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "mypkg-1.0.0.tar.gz"), []byte("sdist"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestWheel(t, filepath.Join("build", "dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+}
+
 func TestGetInfo(t *testing.T) {
 	p := &PyPIPlugin{}
 	info := p.GetInfo()
@@ -229,6 +310,45 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateReportsConfigChecks(t *testing.T) {
+	p := &PyPIPlugin{}
+	tests := map[string]map[string]any{
+		"inject_failure": {"inject_failure": "bogus"},
+		"auth_header":    {"auth_scheme": "none", "auth_header": "X Bad Header"},
+		"ip_family":      {"ip_family": "ipv5"},
+		"benchmark":      {"benchmark": true, "benchmark_package": "not a project!"},
+		"license_check":  {"license_check": "fail"},
+		"version_epoch":  {"version_epoch": -1},
+		"index_url":      {"index_url": "ftp://localhost/simple/"},
+		"dist_path":      {"dist_path": "../dist/*"},
+		"username":       {"username": ""},
+		"upload_backend": {"upload_backend": "bogus"},
+	}
+	for field, extra := range tests {
+		t.Run(field, func(t *testing.T) {
+			config := map[string]any{"username": "__token__", "password": "pypi-token", "repository": "http://localhost:8080/"}
+			for k, v := range extra {
+				config[k] = v
+			}
+			// Execute and Validate run the same checks; Validate names the failed option
+			if err := p.validateConfig(p.parseConfig(config)); err == nil {
+				t.Errorf("expected validateConfig to reject %v", extra)
+			}
+			resp, err := p.Validate(context.Background(), config)
+			if err != nil || resp.Valid {
+				t.Fatalf("expected %v to be invalid, got %v %+v", extra, err, resp)
+			}
+			found := false
+			for _, e := range resp.Errors {
+				found = found || e.Field == field
+			}
+			if !found {
+				t.Errorf("expected an error for %s, got %+v", field, resp.Errors)
+			}
+		})
+	}
+}
+
 func TestParseConfig(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -430,6 +550,7 @@ func TestExecuteDryRun(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			writeTestDists(t)
 			p := &PyPIPlugin{}
 			ctx := context.Background()
 
@@ -550,7 +671,7 @@ func TestExecuteActualRun(t *testing.T) {
 			},
 			mockOutput:     []byte("Uploading distributions to https://upload.pypi.org/legacy/\nUploading mypackage-1.0.0.tar.gz\n"),
 			mockError:      nil,
-			expectedArgs:   []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "-u", "testuser", "-p", "testpass", "dist/*"},
+			expectedArgs:   []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "dist/mypkg-1.0.0.tar.gz"},
 			expectSuccess:  true,
 			expectContains: "Successfully uploaded",
 		},
@@ -566,7 +687,7 @@ func TestExecuteActualRun(t *testing.T) {
 			},
 			mockOutput:     []byte("Uploading distributions..."),
 			mockError:      nil,
-			expectedArgs:   []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "-u", "testuser", "-p", "testpass", "--skip-existing", "dist/*"},
+			expectedArgs:   []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "--skip-existing", "dist/mypkg-1.0.0.tar.gz"},
 			expectSuccess:  true,
 			expectContains: "Successfully uploaded",
 		},
@@ -582,7 +703,7 @@ func TestExecuteActualRun(t *testing.T) {
 			},
 			mockOutput:     []byte("Uploading distributions..."),
 			mockError:      nil,
-			expectedArgs:   []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "-u", "testuser", "-p", "testpass", "build/dist/*.whl"},
+			expectedArgs:   []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "build/dist/mypkg-1.0.0-py3-none-any.whl"},
 			expectSuccess:  true,
 			expectContains: "Successfully uploaded",
 		},
@@ -598,7 +719,7 @@ func TestExecuteActualRun(t *testing.T) {
 			},
 			mockOutput:     []byte("Uploading distributions..."),
 			mockError:      nil,
-			expectedArgs:   []string{"upload", "--repository-url", "https://test.pypi.org/legacy/", "-u", "testuser", "-p", "testpass", "dist/*"},
+			expectedArgs:   []string{"upload", "--repository-url", "https://test.pypi.org/legacy/", "dist/mypkg-1.0.0.tar.gz"},
 			expectSuccess:  true,
 			expectContains: "Successfully uploaded",
 		},
@@ -613,7 +734,7 @@ func TestExecuteActualRun(t *testing.T) {
 			},
 			mockOutput:     []byte("HTTPError: 400 Bad Request"),
 			mockError:      errors.New("exit status 1"),
-			expectedArgs:   []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "-u", "testuser", "-p", "testpass", "dist/*"},
+			expectedArgs:   []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "dist/mypkg-1.0.0.tar.gz"},
 			expectSuccess:  false,
 			expectContains: "twine upload failed",
 		},
@@ -631,7 +752,7 @@ func TestExecuteActualRun(t *testing.T) {
 			},
 			mockOutput:     []byte("Success!"),
 			mockError:      nil,
-			expectedArgs:   []string{"upload", "--repository-url", "http://localhost:9999/", "-u", "testuser", "-p", "testpass", "--skip-existing", "output/*.tar.gz"},
+			expectedArgs:   []string{"upload", "--repository-url", "http://localhost:9999/", "--skip-existing", "output/mypkg-1.0.0.tar.gz"},
 			expectSuccess:  true,
 			expectContains: "Successfully uploaded",
 		},
@@ -639,6 +760,7 @@ func TestExecuteActualRun(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			writeTestDists(t)
 			mockExecutor := &MockCommandExecutor{
 				ReturnOut:   tt.mockOutput,
 				ReturnError: tt.mockError,
@@ -681,12 +803,16 @@ func TestExecuteActualRun(t *testing.T) {
 			if call.Name != "twine" {
 				t.Errorf("expected command 'twine', got '%s'", call.Name)
 			}
+			wantEnv := []string{"TWINE_USERNAME=" + tt.config["username"].(string), "TWINE_PASSWORD=" + tt.config["password"].(string)}
+			if !reflect.DeepEqual(call.Env, wantEnv) {
+				t.Errorf("expected credentials in the environment %v, got %v", wantEnv, call.Env)
+			}
 
 			if len(call.Args) != len(tt.expectedArgs) {
 				t.Errorf("expected %d args, got %d: %v", len(tt.expectedArgs), len(call.Args), call.Args)
 			} else {
 				for i, expected := range tt.expectedArgs {
-					if call.Args[i] != expected {
+					if filepath.ToSlash(call.Args[i]) != expected {
 						t.Errorf("arg[%d]: expected '%s', got '%s'", i, expected, call.Args[i])
 					}
 				}
@@ -798,7 +924,7 @@ func TestBuildTwineArgs(t *testing.T) {
 				Password:   "pass",
 				DistPath:   "dist/*",
 			},
-			expectedArgs: []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "-u", "user", "-p", "pass", "dist/*"},
+			expectedArgs: []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "dist/*"},
 		},
 		{
 			name: "with skip existing",
@@ -809,7 +935,7 @@ func TestBuildTwineArgs(t *testing.T) {
 				DistPath:     "dist/*",
 				SkipExisting: true,
 			},
-			expectedArgs: []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "-u", "user", "-p", "pass", "--skip-existing", "dist/*"},
+			expectedArgs: []string{"upload", "--repository-url", "https://upload.pypi.org/legacy/", "--skip-existing", "dist/*"},
 		},
 		{
 			name: "custom repository and dist path",
@@ -819,7 +945,7 @@ func TestBuildTwineArgs(t *testing.T) {
 				Password:   "testpass",
 				DistPath:   "build/output/*.whl",
 			},
-			expectedArgs: []string{"upload", "--repository-url", "https://test.pypi.org/legacy/", "-u", "testuser", "-p", "testpass", "build/output/*.whl"},
+			expectedArgs: []string{"upload", "--repository-url", "https://test.pypi.org/legacy/", "build/output/*.whl"},
 		},
 	}
 
@@ -1074,3 +1200,32 @@ func TestGetExecutor(t *testing.T) {
 		}
 	})
 }
+
+func TestDurationOption(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      map[string]any
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"missing uses default", map[string]any{}, time.Minute, false},
+		{"duration string", map[string]any{"d": "90s"}, 90 * time.Second, false},
+		{"seconds as float", map[string]any{"d": float64(30)}, 30 * time.Second, false},
+		{"seconds as int", map[string]any{"d": 5}, 5 * time.Second, false},
+		{"invalid string", map[string]any{"d": "soon"}, time.Minute, true},
+		{"negative", map[string]any{"d": "-1s"}, time.Minute, true},
+		{"wrong type", map[string]any{"d": true}, time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := durationOption(tt.raw, "d", time.Minute)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// preflightResult collects outputs and warnings from checks that run before the upload.
+type preflightResult struct {
+	files     []string
+	outputs   map[string]any
+	warnings  []string
+	artifacts []plugin.Artifact
+	// statusIncidents are the index incidents the upload waits for with status_wait
+	statusIncidents []statusIncident
+}
+
+// warn records a non-blocking problem.
+func (r *preflightResult) warn(format string, args ...any) {
+	r.warnings = append(r.warnings, fmt.Sprintf(format, args...))
+}
+
+// apply copies the collected check outputs and warnings into response outputs.
+func (r *preflightResult) apply(outputs map[string]any) {
+	for k, v := range r.outputs {
+		outputs[k] = v
+	}
+	if len(r.warnings) > 0 {
+		outputs["warnings"] = r.warnings
+	}
+}
+
+// runPreflight runs the enabled pre-upload checks, in dry runs too.
+// A non-nil response means a check blocked the publish.
+func (p *PyPIPlugin) runPreflight(ctx context.Context, cfg Config) (*preflightResult, *plugin.ExecuteResponse) {
+	result := &preflightResult{outputs: map[string]any{}}
+	result.files, _ = expandDistGlob(cfg.DistPath)
+	for _, w := range cfg.CredentialWarnings {
+		result.warn("%s", w)
+	}
+
+	if cfg.StatusCheck != "" && cfg.StatusCheck != checkOff {
+		if resp := p.preflightStatus(ctx, cfg, result); resp != nil {
+			return nil, resp
+		}
+	}
+
+	if cfg.MaintainerCheck != "" && cfg.MaintainerCheck != checkOff {
+		if resp := p.preflightMaintainers(ctx, cfg, result); resp != nil {
+			return nil, resp
+		}
+	}
+
+	if cfg.VulnerabilityCheck != "" && cfg.VulnerabilityCheck != checkOff {
+		if resp := p.preflightVulnerabilities(ctx, cfg, result); resp != nil {
+			return nil, resp
+		}
+	}
+
+	if cfg.LicenseCheck != "" && cfg.LicenseCheck != checkOff {
+		if resp := p.preflightLicenses(cfg, result); resp != nil {
+			return nil, resp
+		}
+	}
+
+	if resp := p.preflightSharedObjects(cfg, result); resp != nil {
+		return nil, resp
+	}
+
+	reportImportNames(result)
+	reportEntryPoints(result)
+
+	if cfg.DependencyReport {
+		p.reportDependencyChanges(ctx, cfg, result)
+	}
+
+	if cfg.APIDiff != "" && cfg.APIDiff != checkOff {
+		if resp := p.preflightAPIDiff(ctx, cfg, result); resp != nil {
+			return nil, resp
+		}
+	}
+
+	if cfg.DescriptionPreview {
+		p.previewDescription(ctx, cfg, result)
+	}
+
+	return result, nil
+}
+
+// preflightVulnerabilities runs the dependency vulnerability check.
+func (p *PyPIPlugin) preflightVulnerabilities(ctx context.Context, cfg Config, result *preflightResult) *plugin.ExecuteResponse {
+	findings, unchecked, err := p.auditDependencies(ctx, cfg, result.files)
+	if err != nil {
+		// An unavailable vulnerability source only blocks the publish in fail mode
+		if cfg.VulnerabilityCheck == checkFail {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("vulnerability check failed: %v", err),
+			}
+		}
+		result.warn("vulnerability check skipped: %v", err)
+		return nil
+	}
+
+	result.outputs["vulnerabilities"] = findings
+	if len(unchecked) > 0 {
+		result.outputs["vulnerabilities_unchecked"] = unchecked
+		result.warn("%d dependencies are not pinned and were not checked for vulnerabilities: %s",
+			len(unchecked), strings.Join(unchecked, ", "))
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("%d known vulnerabilities at or above %s severity: %s",
+		len(findings), cfg.VulnerabilitySeverity, formatFindings(findings))
+	if cfg.VulnerabilityCheck == checkFail {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   "vulnerability check failed: " + msg,
+			Outputs: map[string]any{"vulnerabilities": findings},
+		}
+	}
+	result.warn("%s", msg)
+	return nil
+}
+
+// preflightLicenses scans wheels for bundled components outside the license allowlist.
+func (p *PyPIPlugin) preflightLicenses(cfg Config, result *preflightResult) *plugin.ExecuteResponse {
+	var bundled []bundledComponent
+	var violations []string
+	for _, file := range result.files {
+		if !strings.HasSuffix(file, ".whl") {
+			continue
+		}
+		components, err := scanWheelLicenses(file)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("license check failed: %v", err),
+			}
+		}
+		for _, c := range components {
+			bundled = append(bundled, c)
+			if c.License == licenseUnknown && cfg.LicenseAllowUnknown {
+				continue
+			}
+			if !licenseAllowed(c.License, cfg.LicenseAllowlist) {
+				violations = append(violations, fmt.Sprintf("%s: %s (%s)", c.Dist, c.Component, c.License))
+			}
+		}
+	}
+
+	if bundled == nil {
+		bundled = []bundledComponent{}
+	}
+	result.outputs["bundled_licenses"] = bundled
+	if len(violations) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("%d bundled components violate the license policy: %s", len(violations), strings.Join(violations, "; "))
+	if cfg.LicenseCheck == checkFail {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   "license check failed: " + msg,
+			Outputs: map[string]any{"license_violations": violations},
+		}
+	}
+	result.outputs["license_violations"] = violations
+	result.warn("%s", msg)
+	return nil
+}
+
+// preflightSharedObjects reports the shared objects shipped in Linux wheels and, when enabled,
+// flags those not covered by the allowlist.
+func (p *PyPIPlugin) preflightSharedObjects(cfg Config, result *preflightResult) *plugin.ExecuteResponse {
+	checking := cfg.SharedObjectCheck != "" && cfg.SharedObjectCheck != checkOff
+
+	var objects []sharedObject
+	var unexpected []string
+	scanned := false
+	for _, file := range result.files {
+		if !isLinuxBinaryWheel(filepath.Base(file)) {
+			continue
+		}
+		scanned = true
+		listed, err := listSharedObjects(file)
+		if err != nil {
+			if cfg.SharedObjectCheck == checkFail {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("shared object check failed: %v", err),
+				}
+			}
+			result.warn("could not list shared objects: %v", err)
+			continue
+		}
+		for _, obj := range listed {
+			objects = append(objects, obj)
+			if checking && !sharedObjectAllowed(obj, cfg.SharedObjectAllowlist) {
+				unexpected = append(unexpected, fmt.Sprintf("%s: %s (sha256:%s)", obj.Dist, obj.Path, obj.SHA256))
+			}
+		}
+	}
+
+	if !scanned {
+		return nil
+	}
+	if objects == nil {
+		objects = []sharedObject{}
+	}
+	result.outputs["shared_objects"] = objects
+	if len(unexpected) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("%d unexpected shared objects: %s", len(unexpected), strings.Join(unexpected, "; "))
+	if cfg.SharedObjectCheck == checkFail {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   "shared object check failed: " + msg,
+			Outputs: map[string]any{"unexpected_shared_objects": unexpected},
+		}
+	}
+	result.outputs["unexpected_shared_objects"] = unexpected
+	result.warn("%s", msg)
+	return nil
+}
+
+// reportImportNames adds the top-level import names provided by the wheels to the outputs.
+func reportImportNames(result *preflightResult) {
+	seen := map[string]bool{}
+	names := []string{}
+	wheels := false
+	for _, file := range result.files {
+		if !strings.HasSuffix(file, ".whl") {
+			continue
+		}
+		wheels = true
+		wheelNames, err := wheelImportNames(file)
+		if err != nil {
+			result.warn("could not determine import names of %s: %v", filepath.Base(file), err)
+			continue
+		}
+		for _, name := range wheelNames {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	if wheels {
+		sort.Strings(names)
+		result.outputs["import_names"] = names
+	}
+}
+
+// reportEntryPoints adds the console scripts, GUI scripts and plugin entry points declared by
+// the wheels to the outputs.
+func reportEntryPoints(result *preflightResult) {
+	merged := map[string][]entryPoint{}
+	wheels := false
+	for _, file := range result.files {
+		if !strings.HasSuffix(file, ".whl") {
+			continue
+		}
+		wheels = true
+		groups, err := wheelEntryPoints(file)
+		if err != nil {
+			result.warn("could not read entry points of %s: %v", filepath.Base(file), err)
+			continue
+		}
+		mergeEntryPoints(merged, groups)
+	}
+	if !wheels {
+		return
+	}
+
+	result.outputs["console_scripts"] = nonNilEntryPoints(merged[consoleScriptsGroup])
+	result.outputs["gui_scripts"] = nonNilEntryPoints(merged[guiScriptsGroup])
+	delete(merged, consoleScriptsGroup)
+	delete(merged, guiScriptsGroup)
+	result.outputs["entry_points"] = merged
+}
+
+// nonNilEntryPoints returns points, or an empty slice so outputs serialize as [] rather than null.
+func nonNilEntryPoints(points []entryPoint) []entryPoint {
+	if points == nil {
+		return []entryPoint{}
+	}
+	return points
+}
+
+// previewDescription renders the long description to an HTML artifact for release review.
+// Rendering problems are reported as warnings.
+func (p *PyPIPlugin) previewDescription(ctx context.Context, cfg Config, result *preflightResult) {
+	insights, err := inspectDists(result.files)
+	if err != nil {
+		result.warn("description preview skipped: %v", err)
+		return
+	}
+
+	page, err := p.renderDescriptionPreview(ctx, cfg, insights.meta, cfg.DescriptionPreviewPath)
+	if err != nil {
+		result.warn("description preview failed: %v", err)
+		return
+	}
+
+	result.outputs["description_preview"] = cfg.DescriptionPreviewPath
+	result.artifacts = append(result.artifacts, plugin.Artifact{
+		Name:     "description-preview",
+		Path:     cfg.DescriptionPreviewPath,
+		Type:     "file",
+		Size:     int64(len(page)),
+		Checksum: fmt.Sprintf("sha256:%x", sha256.Sum256(page)),
+	})
+}
+
+// formatFindings renders findings as "package==version (ID, severity)" entries.
+func formatFindings(findings []vulnerabilityFinding) string {
+	parts := make([]string, 0, len(findings))
+	for _, f := range findings {
+		parts = append(parts, fmt.Sprintf("%s==%s (%s, %s)", f.Package, f.Version, f.ID, f.Severity))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Description preview defaults.
+const (
+	defaultDescriptionPreviewPath = "description-preview.html"
+	// previewPageTemplate wraps the rendered description in a standalone page.
+	previewPageTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>%s %s</title>
+</head>
+<body>
+<h1>%s %s</h1>
+<p>%s</p>
+<hr>
+%s
+</body>
+</html>
+`
+)
+
+// defaultReadmeRendererCommand runs readme_renderer, the library PyPI uses to render project pages.
+var defaultReadmeRendererCommand = []string{"python3", "-m", "readme_renderer"}
+
+// descriptionFormat maps a Description-Content-Type to a readme_renderer format.
+// PyPI renders descriptions without a content type as reStructuredText.
+func descriptionFormat(contentType string) (string, error) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(strings.ToLower(mediaType)) {
+	case "", "text/x-rst":
+		return "rst", nil
+	case "text/markdown":
+		return "md", nil
+	case "text/plain":
+		return "txt", nil
+	default:
+		return "", fmt.Errorf("unsupported Description-Content-Type %q", contentType)
+	}
+}
+
+// renderDescriptionPreview renders the long description as PyPI would and writes a standalone
+// HTML page to outPath. It returns the written page.
+func (p *PyPIPlugin) renderDescriptionPreview(ctx context.Context, cfg Config, meta *packageMetadata, outPath string) ([]byte, error) {
+	format, err := descriptionFormat(meta.DescriptionContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := os.CreateTemp("", "relicta-pypi-description-*."+format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create description file: %w", err)
+	}
+	defer func() { _ = os.Remove(src.Name()) }()
+	if _, err := src.WriteString(meta.Description); err != nil {
+		_ = src.Close()
+		return nil, fmt.Errorf("failed to write description file: %w", err)
+	}
+	if err := src.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write description file: %w", err)
+	}
+
+	// Render to a file so diagnostics printed by the renderer do not end up in the page
+	fragment := src.Name() + ".html"
+	defer func() { _ = os.Remove(fragment) }()
+
+	command := cfg.ReadmeRendererCommand
+	args := append(append([]string{}, command[1:]...), "-f", format, "-o", fragment, src.Name())
+	if output, err := p.getExecutor().Run(ctx, command[0], args...); err != nil {
+		return nil, fmt.Errorf("description failed to render: %v\nOutput: %s", err, limitOutput(cfg, string(output)))
+	}
+	rendered, err := os.ReadFile(fragment) // #nosec G304 -- temp file created above
+	if err != nil {
+		return nil, fmt.Errorf("renderer produced no output: %w", err)
+	}
+
+	page := []byte(fmt.Sprintf(previewPageTemplate,
+		html.EscapeString(meta.Name), html.EscapeString(meta.Version),
+		html.EscapeString(meta.Name), html.EscapeString(meta.Version),
+		html.EscapeString(meta.Summary), string(rendered)))
+
+	if dir := filepath.Dir(outPath); dir != "." {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create preview directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(outPath, page, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write preview: %w", err)
+	}
+	return page, nil
+}
+
+// validateDescriptionPreviewConfig validates the description preview options.
+func validateDescriptionPreviewConfig(cfg Config) error {
+	if !cfg.DescriptionPreview {
+		return nil
+	}
+	if err := validateDistPath(cfg.DescriptionPreviewPath); err != nil {
+		return fmt.Errorf("invalid description_preview_path: %w", err)
+	}
+	if strings.Contains(cfg.DescriptionPreviewPath, "*") {
+		return fmt.Errorf("invalid description_preview_path: must not contain wildcards")
+	}
+	if matched, _ := filepath.Match(toSlashPath(cfg.DistPath), toSlashPath(cfg.DescriptionPreviewPath)); matched {
+		return fmt.Errorf("description_preview_path must not match dist_path, or the preview would be uploaded")
+	}
+	if len(cfg.ReadmeRendererCommand) == 0 {
+		return fmt.Errorf("readme_renderer_command must not be empty")
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// fakeReadmeRenderer writes a fragment to the -o path, recording the requested format.
+func fakeReadmeRenderer(format *string) *MockCommandExecutor {
+	return &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			var out string
+			for i, arg := range args {
+				switch arg {
+				case "-f":
+					*format = args[i+1]
+				case "-o":
+					out = args[i+1]
+				}
+			}
+			src, err := os.ReadFile(args[len(args)-1])
+			if err != nil {
+				return nil, err
+			}
+			return []byte("warning: noise"), os.WriteFile(out, []byte("<p>"+string(src)+"</p>"), 0o600)
+		},
+	}
+}
+
+func TestDescriptionFormat(t *testing.T) {
+	tests := map[string]string{
+		"":           "rst",
+		"text/x-rst": "rst",
+		"text/markdown; charset=UTF-8; variant=GFM": "md",
+		"text/plain": "txt",
+	}
+	for contentType, want := range tests {
+		if got, err := descriptionFormat(contentType); err != nil || got != want {
+			t.Errorf("descriptionFormat(%q) = %q, %v; want %q", contentType, got, err, want)
+		}
+	}
+	if _, err := descriptionFormat("text/html"); err == nil {
+		t.Error("expected error for unsupported content type")
+	}
+}
+
+func TestExecuteDescriptionPreview(t *testing.T) {
+	distPath := writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "app-1.0-py3-none-any.whl"), map[string]string{
+		"app-1.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: app\nVersion: 1.0\nSummary: <b>App</b>\n" +
+			"Description-Content-Type: text/markdown\n\n# Hello\n",
+	})
+
+	var format string
+	p := &PyPIPlugin{cmdExecutor: fakeReadmeRenderer(&format)}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":                 "__token__",
+			"password":                 "pypi-token",
+			"repository":               "http://localhost:8080/",
+			"dist_path":                distPath,
+			"description_preview":      true,
+			"description_preview_path": "review/page.html",
+		},
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got error: %s", resp.Error)
+	}
+
+	if format != "md" {
+		t.Errorf("expected markdown rendering, got %q", format)
+	}
+	page, err := os.ReadFile(filepath.Join("review", "page.html"))
+	if err != nil {
+		t.Fatalf("preview not written: %v", err)
+	}
+	if !strings.Contains(string(page), "<p># Hello</p>") || !strings.Contains(string(page), "&lt;b&gt;App&lt;/b&gt;") {
+		t.Errorf("unexpected page content:\n%s", page)
+	}
+	if strings.Contains(string(page), "noise") {
+		t.Error("renderer diagnostics must not end up in the page")
+	}
+	if len(resp.Artifacts) != 1 || resp.Artifacts[0].Path != "review/page.html" || resp.Artifacts[0].Size != int64(len(page)) {
+		t.Errorf("unexpected artifacts: %+v", resp.Artifacts)
+	}
+	if !strings.HasPrefix(resp.Artifacts[0].Checksum, "sha256:") {
+		t.Errorf("expected sha256 checksum, got %q", resp.Artifacts[0].Checksum)
+	}
+}
+
+func TestExecuteDescriptionPreviewRenderFailure(t *testing.T) {
+	distPath := writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "app-1.0-py3-none-any.whl"), map[string]string{
+		"app-1.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: app\nVersion: 1.0\n\nBroken `rst\n",
+	})
+
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("<string>:1: (WARNING/2) Inline interpreted text start-string without end-string."), errors.New("exit status 1")
+		},
+	}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":            "__token__",
+			"password":            "pypi-token",
+			"repository":          "http://localhost:8080/",
+			"dist_path":           distPath,
+			"description_preview": true,
+		},
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	warnings, _ := resp.Outputs["warnings"].([]string)
+	if !resp.Success || len(warnings) != 1 || !strings.Contains(warnings[0], "start-string without end-string") {
+		t.Errorf("expected render warning, got success=%v warnings=%v", resp.Success, warnings)
+	}
+	if len(resp.Artifacts) != 0 {
+		t.Errorf("expected no artifacts, got %+v", resp.Artifacts)
+	}
+}
+
+func TestValidateDescriptionPreviewConfig(t *testing.T) {
+	p := &PyPIPlugin{}
+	tests := []struct {
+		name    string
+		raw     map[string]any
+		wantErr bool
+	}{
+		{"disabled", map[string]any{"description_preview_path": "/abs.html"}, false},
+		{"defaults", map[string]any{"description_preview": true}, false},
+		{"absolute", map[string]any{"description_preview": true, "description_preview_path": "/tmp/page.html"}, true},
+		{"uploaded with dist", map[string]any{"description_preview": true, "description_preview_path": "dist/page.html"}, true},
+		{"wildcard", map[string]any{"description_preview": true, "description_preview_path": "out/*.html"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDescriptionPreviewConfig(p.parseConfig(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDescriptionPreviewConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// configureCancellation runs cmd in its own process group and makes cancellation stop the
+// whole group: it is asked to exit with SIGTERM, and whatever is still running after
+// processWaitDelay is killed. twine runs python helpers such as keyring backends as child
+// processes, which would otherwise outlive a cancelled upload on the runner.
+func configureCancellation(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		time.AfterFunc(processWaitDelay, func() { _ = signalProcessGroup(pgid, syscall.SIGKILL) })
+		return signalProcessGroup(pgid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = processWaitDelay
+}
+
+// signalProcessGroup sends sig to every process of the group. A group without processes left
+// reports os.ErrProcessDone.
+func signalProcessGroup(pgid int, sig syscall.Signal) error {
+	if err := syscall.Kill(-pgid, sig); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+	return nil
+}
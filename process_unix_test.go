@@ -0,0 +1,112 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// processRunning reports whether a process exists and has not exited. Orphans reparented to a
+// PID 1 that does not reap them stay as zombies, which count as exited.
+func processRunning(t *testing.T, pid int) bool {
+	t.Helper()
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if os.IsNotExist(err) {
+		return false
+	}
+	if err != nil {
+		t.Skipf("cannot inspect processes: %v", err)
+	}
+	// The state follows the parenthesized command name
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestRealCommandExecutorCancellation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := (&RealCommandExecutor{}).Run(ctx, "sleep", "10")
+	if err == nil {
+		t.Fatal("expected error from cancelled command")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected cancelled command to terminate promptly, took %s", elapsed)
+	}
+}
+
+func TestRealCommandExecutorCancellationStopsChildren(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("requires /proc")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The shell waits on a child, like twine on a keyring helper; both must be stopped
+	child := make(chan int, 1)
+	_, err := (&RealCommandExecutor{}).RunStreaming(ctx, nil, func(line string) {
+		if pid, err := strconv.Atoi(line); err == nil {
+			child <- pid
+			cancel()
+		}
+	}, 0, "sh", "-c", "sleep 30 & echo $!; wait")
+	if err == nil {
+		t.Fatal("expected error from cancelled command")
+	}
+
+	pid := <-child
+	deadline := time.Now().Add(5 * time.Second)
+	for processRunning(t, pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("child process %d still running after cancellation", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRealCommandExecutorRunWithEnv(t *testing.T) {
+	t.Setenv("PYPI_INHERITED", "inherited")
+	out, err := (&RealCommandExecutor{}).RunWithEnv(context.Background(), []string{"TWINE_PASSWORD=secret"}, "sh", "-c", `echo "$PYPI_INHERITED $TWINE_PASSWORD"`)
+	if err != nil || string(out) != "inherited secret\n" {
+		t.Errorf("expected the inherited and added variables, got %q %v", out, err)
+	}
+}
+
+func TestRealCommandExecutorRunStreaming(t *testing.T) {
+	var lines []string
+	script := `printf 'Uploading a.whl\n'; printf 'progress 50%%\rprogress 100%%\n'; printf 'HTTPError: 400\n' >&2; printf 'tail'`
+	out, err := (&RealCommandExecutor{}).RunStreaming(context.Background(), nil, func(line string) { lines = append(lines, line) }, 0, "sh", "-c", script)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"Uploading a.whl", "progress 50%", "progress 100%", "HTTPError: 400", "tail"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	if string(out) != "Uploading a.whl\nprogress 50%\rprogress 100%\nHTTPError: 400\ntail" {
+		t.Errorf("expected the whole combined output, got %q", out)
+	}
+
+	out, err = (&RealCommandExecutor{}).RunStreaming(context.Background(), nil, nil, 100, "sh", "-c", `for i in $(seq 1000); do echo "line $i"; done; exit 3`)
+	if err == nil || len(out) > 200 || !strings.Contains(string(out), "bytes truncated") || !strings.HasSuffix(string(out), "line 1000\n") {
+		t.Errorf("expected the head and tail of the output and the exit status, got %q %v", out, err)
+	}
+}
+
+func TestRealCommandExecutorOutput(t *testing.T) {
+	e := &RealCommandExecutor{}
+	out, err := e.Output(context.Background(), "sh", "-c", "echo 'WARNING: deprecated' >&2; echo token")
+	if err != nil || string(out) != "token\n" {
+		t.Errorf("expected stdout only, got %q %v", out, err)
+	}
+	if _, err := e.Output(context.Background(), "sh", "-c", "echo 'token expired' >&2; exit 3"); err == nil || !strings.Contains(err.Error(), "exit status 3: token expired") {
+		t.Errorf("expected stderr in the error, got %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// configureCancellation makes cancellation kill cmd and its child processes. Windows has no
+// SIGTERM, so the tree is ended with taskkill, falling back to killing the process itself.
+func configureCancellation(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		// #nosec G204 -- fixed command, the argument is a process ID
+		if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err == nil {
+			return nil
+		}
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = processWaitDelay
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultPypircRepositoryName is the .pypirc section twine uses without --repository.
+const defaultPypircRepositoryName = "pypi"
+
+// pypircDefaultRepositories are the upload URLs twine assumes for the pypi and testpypi
+// sections when they set no repository.
+var pypircDefaultRepositories = map[string]string{
+	"pypi":     "https://upload.pypi.org/legacy/",
+	"testpypi": "https://test.pypi.org/legacy/",
+}
+
+// pypircSection is the repository and credentials of a .pypirc section.
+type pypircSection struct {
+	Repository string
+	Username   string
+	Password   string
+	ClientCert string
+}
+
+// usesPypirc reports whether credentials are read from a .pypirc file.
+func usesPypirc(cfg Config) bool {
+	return cfg.PypircPath != "" || cfg.RepositoryName != ""
+}
+
+// pypircRepositoryName returns the .pypirc section to read: repository_name, $TWINE_REPOSITORY
+// or pypi, as twine picks it.
+func pypircRepositoryName(cfg Config) string {
+	if cfg.RepositoryName != "" {
+		return cfg.RepositoryName
+	}
+	if v := os.Getenv("TWINE_REPOSITORY"); v != "" {
+		return v
+	}
+	return defaultPypircRepositoryName
+}
+
+// pypircFilePath returns the .pypirc file to read: pypirc_path, or ~/.pypirc like twine.
+func pypircFilePath(cfg Config) (string, error) {
+	path := cfg.PypircPath
+	if path == "" {
+		path = "~/.pypirc"
+	}
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// parsePypirc parses the sections of an INI file the way Python's RawConfigParser does: keys
+// are case-insensitive, values are not interpolated, and indented lines continue the previous
+// value.
+func parsePypirc(data string) map[string]map[string]string {
+	sections := map[string]map[string]string{}
+	var current map[string]string
+	var key string
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";"):
+			continue
+		case line[0] == ' ' || line[0] == '\t':
+			if current != nil && key != "" {
+				current[key] = strings.TrimSpace(current[key] + "\n" + trimmed)
+			}
+			continue
+		case strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]"):
+			name := strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if sections[name] == nil {
+				sections[name] = map[string]string{}
+			}
+			current, key = sections[name], ""
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		sep := strings.IndexAny(trimmed, "=:")
+		if sep < 0 {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(trimmed[:sep]))
+		current[key] = strings.TrimSpace(trimmed[sep+1:])
+	}
+	return sections
+}
+
+// readPypircSection returns the named section of a .pypirc file as twine resolves it: the
+// section must be listed in [distutils] index-servers (pypi and testpypi when absent), pypi
+// and testpypi default to the PyPI upload URLs, and a missing username falls back to
+// [server-login].
+func readPypircSection(path, name string) (pypircSection, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- user-controlled credential file, read only when opted in
+	if err != nil {
+		return pypircSection{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	sections := parsePypirc(string(data))
+
+	servers := []string{"pypi", "testpypi"}
+	if v, ok := sections["distutils"]["index-servers"]; ok {
+		servers = strings.Fields(v)
+	}
+	if !containsString(servers, name) {
+		return pypircSection{}, fmt.Errorf("%s has no repository %q in [distutils] index-servers", path, name)
+	}
+
+	values := sections[name]
+	section := pypircSection{
+		Repository: values["repository"],
+		Username:   values["username"],
+		Password:   values["password"],
+		ClientCert: values["client_cert"],
+	}
+	if section.Repository == "" {
+		section.Repository = pypircDefaultRepositories[name]
+	}
+	if section.Repository == "" {
+		return pypircSection{}, fmt.Errorf("%s section [%s] sets no repository", path, name)
+	}
+	if section.Username == "" {
+		section.Username = sections["server-login"]["username"]
+	}
+	return section, nil
+}
+
+// loadPypircSection reads the .pypirc section configured by cfg.
+func loadPypircSection(cfg Config) (pypircSection, error) {
+	path, err := pypircFilePath(cfg)
+	if err != nil {
+		return pypircSection{}, err
+	}
+	return readPypircSection(path, pypircRepositoryName(cfg))
+}
+
+// sameRepository reports whether two repository URLs differ at most in a trailing slash.
+func sameRepository(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+// applyPypircCredentials fills credentials missing from the config and environment from the
+// .pypirc section. They are only used for the section's own repository, so an explicit
+// repository never receives the credentials of another index.
+func applyPypircCredentials(cfg *Config, section pypircSection) {
+	if !sameRepository(section.Repository, cfg.Repository) {
+		return
+	}
+	if cfg.Username == "" {
+		cfg.Username = section.Username
+	}
+	if cfg.Password == "" {
+		cfg.Password = section.Password
+	}
+	if cfg.ClientCert == "" {
+		cfg.ClientCert = section.ClientCert
+	}
+}
+
+// validatePypircConfig validates pypirc_path and repository_name.
+func validatePypircConfig(cfg Config) error {
+	if !usesPypirc(cfg) {
+		return nil
+	}
+	section, err := loadPypircSection(cfg)
+	if err != nil {
+		return err
+	}
+	if !sameRepository(section.Repository, cfg.Repository) && cfg.RepositoryName != "" {
+		return fmt.Errorf("repository_name %s uploads to %s, but repository is %s; remove one of them", cfg.RepositoryName, section.Repository, cfg.Repository)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPypirc = `[distutils]
+index-servers =
+    pypi
+    internal
+
+; shared login
+[server-login]
+username: deploy
+
+[pypi]
+username = __token__
+password = pypi-AgEIcHlwaS5vcmc=
+
+[internal]
+repository = https://packages.example.com/upload/
+password = s3cret:with=separators
+client_cert = /etc/ssl/ci.pem
+
+[testpypi]
+username = __token__
+password = test-token
+`
+
+func writeTestPypirc(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".pypirc")
+	if err := os.WriteFile(path, []byte(testPypirc), 0o600); err != nil {
+		t.Fatalf("failed to write .pypirc: %v", err)
+	}
+	return path
+}
+
+func TestReadPypircSection(t *testing.T) {
+	path := writeTestPypirc(t)
+
+	section, err := readPypircSection(path, "pypi")
+	if err != nil || section != (pypircSection{Repository: "https://upload.pypi.org/legacy/", Username: "__token__", Password: "pypi-AgEIcHlwaS5vcmc="}) {
+		t.Errorf("unexpected pypi section %+v, %v", section, err)
+	}
+	section, err = readPypircSection(path, "internal")
+	want := pypircSection{Repository: "https://packages.example.com/upload/", Username: "deploy", Password: "s3cret:with=separators", ClientCert: "/etc/ssl/ci.pem"}
+	if err != nil || section != want {
+		t.Errorf("unexpected internal section %+v, %v", section, err)
+	}
+
+	// Like twine, sections missing from index-servers are not repositories
+	if _, err := readPypircSection(path, "testpypi"); err == nil || !strings.Contains(err.Error(), "index-servers") {
+		t.Errorf("expected testpypi to need an index-servers entry, got %v", err)
+	}
+	if _, err := readPypircSection(filepath.Join(t.TempDir(), "missing"), "pypi"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestParseConfigPypirc(t *testing.T) {
+	path := writeTestPypirc(t)
+	t.Setenv("PYPI_USERNAME", "")
+	t.Setenv("PYPI_PASSWORD", "")
+	t.Setenv("PYPI_TOKEN", "")
+	t.Setenv("TWINE_REPOSITORY", "")
+
+	p := &PyPIPlugin{}
+	tests := []struct {
+		name           string
+		raw            map[string]any
+		env            string
+		wantRepository string
+		wantUsername   string
+		wantPassword   string
+	}{
+		{"disabled", map[string]any{}, "", "https://upload.pypi.org/legacy/", "", ""},
+		{"default section", map[string]any{"pypirc_path": path}, "", "https://upload.pypi.org/legacy/", "__token__", "pypi-AgEIcHlwaS5vcmc="},
+		{"named section", map[string]any{"pypirc_path": path, "repository_name": "internal"}, "", "https://packages.example.com/upload/", "deploy", "s3cret:with=separators"},
+		{"TWINE_REPOSITORY", map[string]any{"pypirc_path": path}, "internal", "https://packages.example.com/upload/", "deploy", "s3cret:with=separators"},
+		{"config wins", map[string]any{"pypirc_path": path, "repository_name": "internal", "password": "explicit"}, "", "https://packages.example.com/upload/", "deploy", "explicit"},
+		{"other repository", map[string]any{"pypirc_path": path, "repository": "https://other.example.com/"}, "", "https://other.example.com/", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TWINE_REPOSITORY", tt.env)
+			cfg := p.parseConfig(tt.raw)
+			if cfg.Repository != tt.wantRepository || cfg.Username != tt.wantUsername || cfg.Password != tt.wantPassword {
+				t.Errorf("got %s %q/%q, want %s %q/%q", cfg.Repository, cfg.Username, cfg.Password, tt.wantRepository, tt.wantUsername, tt.wantPassword)
+			}
+		})
+	}
+}
+
+func TestValidatePypircConfig(t *testing.T) {
+	path := writeTestPypirc(t)
+	t.Setenv("TWINE_REPOSITORY", "")
+
+	if err := validatePypircConfig(Config{}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := validatePypircConfig(Config{PypircPath: path, RepositoryName: "internal", Repository: "https://packages.example.com/upload"}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := validatePypircConfig(Config{PypircPath: path, RepositoryName: "internal", Repository: "https://other.example.com/"}); err == nil {
+		t.Error("expected a conflicting repository to be rejected")
+	}
+	if err := validatePypircConfig(Config{PypircPath: path, RepositoryName: "staging"}); err == nil {
+		t.Error("expected an unknown repository name to be rejected")
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Queue entry layout: the manifest next to a directory holding the distribution files.
+const (
+	queueManifestName = "manifest.json"
+	queueFilesDir     = "dist"
+)
+
+// connectionErrorPattern matches twine and native upload errors of an unreachable index.
+var connectionErrorPattern = regexp.MustCompile(`(?i)connection (refused|reset|aborted)|ConnectionError|Max retries exceeded|no such host|i/o timeout|upload request failed`)
+
+// queuedUpload is the manifest of a publish queued while the index was down. It records what
+// to upload but never credentials, which the resuming run supplies.
+type queuedUpload struct {
+	Version    string       `json:"version"`
+	Repository string       `json:"repository"`
+	DistPath   string       `json:"dist_path"`
+	Files      []queuedFile `json:"files"`
+	QueuedAt   time.Time    `json:"queued_at"`
+	Reason     string       `json:"reason"`
+}
+
+// queuedFile is a distribution copied into a queue entry.
+type queuedFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// isOutageFailure reports whether an upload failed because the index was unavailable, as
+// opposed to rejecting the files or the credentials.
+func isOutageFailure(err error, output string) bool {
+	if errors.Is(err, errRepositoryUnhealthy) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	text := err.Error() + "\n" + output
+	return isServerError(text) || connectionErrorPattern.MatchString(text)
+}
+
+// queueUpload copies files into a new entry of cfg.QueueDir with a manifest, so a later
+// resume_queued run can upload them. It returns the entry directory.
+func queueUpload(cfg Config, version string, files []string, reason error) (string, error) {
+	if len(files) == 0 {
+		return "", fmt.Errorf("no distribution files match %s", cfg.DistPath)
+	}
+	if err := os.MkdirAll(filepath.FromSlash(cfg.QueueDir), 0o750); err != nil {
+		return "", fmt.Errorf("failed to create queue directory: %w", err)
+	}
+	prefix := time.Now().UTC().Format("20060102T150405Z") + "-" + versionSlug(version) + "-"
+	entry, err := os.MkdirTemp(filepath.FromSlash(cfg.QueueDir), prefix)
+	if err == nil {
+		err = os.Mkdir(filepath.Join(entry, queueFilesDir), 0o750)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create queue entry: %w", err)
+	}
+
+	reasonText, _ := truncateOutput(reason.Error(), defaultMaxErrorBodyBytes)
+	manifest := queuedUpload{
+		Version:    version,
+		Repository: cfg.Repository,
+		DistPath:   cfg.DistPath,
+		QueuedAt:   time.Now().UTC(),
+		Reason:     reasonText,
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		digest, err := copyFileWithDigest(file, filepath.Join(entry, queueFilesDir, name))
+		if err != nil {
+			_ = os.RemoveAll(entry)
+			return "", fmt.Errorf("failed to queue %s: %w", name, err)
+		}
+		manifest.Files = append(manifest.Files, queuedFile{Name: name, SHA256: digest})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(entry, queueManifestName), append(data, '\n'), 0o600)
+	}
+	if err != nil {
+		_ = os.RemoveAll(entry)
+		return "", fmt.Errorf("failed to write queue manifest: %w", err)
+	}
+	return toSlashPath(entry), nil
+}
+
+// copyFileWithDigest copies src to dst and returns the hex SHA-256 of the content.
+func copyFileWithDigest(src, dst string) (string, error) {
+	in, err := os.Open(src) // #nosec G304 -- distribution file matched by the validated dist_path
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600) // #nosec G304 -- inside the queue entry
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, hash), in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return hex.EncodeToString(hash.Sum(nil)), err
+}
+
+// queueEntry is a queued upload found in the queue directory.
+type queueEntry struct {
+	dir      string
+	manifest queuedUpload
+}
+
+// readQueue returns the entries of the queue directory, oldest first.
+func readQueue(queueDir string) ([]queueEntry, error) {
+	dirs, err := os.ReadDir(filepath.FromSlash(queueDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue directory: %w", err)
+	}
+
+	var entries []queueEntry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		dir := path.Join(toSlashPath(queueDir), d.Name())
+		data, err := os.ReadFile(filepath.Join(filepath.FromSlash(dir), queueManifestName)) // #nosec G304 -- inside the configured queue directory
+		if err != nil {
+			continue
+		}
+		var manifest queuedUpload
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("invalid queue manifest in %s: %w", dir, err)
+		}
+		entries = append(entries, queueEntry{dir: dir, manifest: manifest})
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].manifest.QueuedAt.Before(entries[b].manifest.QueuedAt)
+	})
+	return entries, nil
+}
+
+// verify checks that the queued files are unchanged since they were queued.
+func (e queueEntry) verify() error {
+	for _, f := range e.manifest.Files {
+		_, digest, _, err := fileDigests(filepath.Join(filepath.FromSlash(e.dir), queueFilesDir, f.Name))
+		if err != nil {
+			return err
+		}
+		if digest != f.SHA256 {
+			return fmt.Errorf("%s changed since it was queued", f.Name)
+		}
+	}
+	return nil
+}
+
+// resumeQueued uploads the queued publishes for the configured repository with the
+// credentials of the current run. Files uploaded before the outage are skipped. Entries are
+// removed once uploaded and stay queued when the upload fails again.
+func (p *PyPIPlugin) resumeQueued(ctx context.Context, cfg Config, dryRun bool, session *publishSession) *plugin.ExecuteResponse {
+	entries, err := readQueue(cfg.QueueDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{Success: false, Error: err.Error()}
+	}
+
+	resumed := []map[string]any{}
+	var failed []map[string]any
+	var errs []string
+	for _, entry := range entries {
+		if entry.manifest.Repository != cfg.Repository {
+			continue
+		}
+		report := map[string]any{"entry": entry.dir, "version": entry.manifest.Version, "channel": releaseChannel(entry.manifest.Version), "files": len(entry.manifest.Files)}
+		if dryRun {
+			resumed = append(resumed, report)
+			continue
+		}
+		if err := entry.verify(); err != nil {
+			report["error"] = err.Error()
+			failed = append(failed, report)
+			errs = append(errs, fmt.Sprintf("%s: %v", entry.dir, err))
+			continue
+		}
+
+		entryCfg := cfg
+		entryCfg.DistPath = path.Join(entry.dir, queueFilesDir, "*")
+		entryCfg.SkipExisting = true
+		entryCfg.ResumeQueued = false
+		// A failed resume keeps the entry instead of queueing it again
+		entryCfg.QueueDir = ""
+		resp, err := p.uploadPackage(ctx, entryCfg, plugin.ReleaseContext{Version: entry.manifest.Version}, false, session)
+		if err == nil && resp.Success {
+			if err := os.RemoveAll(filepath.FromSlash(entry.dir)); err != nil {
+				report["cleanup_error"] = err.Error()
+			}
+			resumed = append(resumed, report)
+			continue
+		}
+		if err == nil {
+			err = errors.New(resp.Error)
+		}
+		report["error"] = err.Error()
+		failed = append(failed, report)
+		errs = append(errs, fmt.Sprintf("%s: %v", entry.dir, err))
+	}
+
+	outputs := map[string]any{"queue_dir": cfg.QueueDir, "resumed": resumed}
+	if len(failed) > 0 {
+		outputs["still_queued"] = failed
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("%d queued upload(s) could not be completed: %s", len(failed), strings.Join(errs, "; ")),
+			Outputs: outputs,
+		}
+	}
+	message := fmt.Sprintf("Resumed %d queued upload(s) to %s", len(resumed), cfg.Repository)
+	if dryRun {
+		message = fmt.Sprintf("Would resume %d queued upload(s) to %s", len(resumed), cfg.Repository)
+	}
+	return &plugin.ExecuteResponse{Success: true, Message: message, Outputs: outputs}
+}
+
+// validateQueueConfig validates the queue_dir and resume_queued options.
+func validateQueueConfig(cfg Config) error {
+	if cfg.QueueDir == "" {
+		if cfg.ResumeQueued {
+			return fmt.Errorf("resume_queued requires queue_dir")
+		}
+		return nil
+	}
+	if err := validateDistPath(cfg.QueueDir); err != nil {
+		return fmt.Errorf("invalid queue_dir: %w", err)
+	}
+	if strings.Contains(cfg.QueueDir, "*") {
+		return fmt.Errorf("invalid queue_dir: must not contain wildcards")
+	}
+	if matched, _ := filepath.Match(toSlashPath(cfg.DistPath), toSlashPath(cfg.QueueDir)); matched {
+		return fmt.Errorf("queue_dir must not match dist_path")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestIsOutageFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		output string
+		want   bool
+	}{
+		{"server error", errors.New("exit status 1"), "ERROR    HTTPError: 503 Service Unavailable from https://upload.pypi.org/legacy/", true},
+		{"connection refused", errors.New("exit status 1"), "requests.exceptions.ConnectionError: Max retries exceeded", true},
+		{"unhealthy repository", errRepositoryUnhealthy, "", true},
+		{"rejected file", errors.New("exit status 1"), "ERROR    HTTPError: 400 Bad Request from https://upload.pypi.org/legacy/", false},
+		{"bad credentials", errors.New("exit status 1"), "ERROR    HTTPError: 403 Forbidden", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isOutageFailure(tt.err, tt.output); got != tt.want {
+				t.Errorf("isOutageFailure = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueAndResume(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz", "mypkg-1.0.0-py3-none-any.whl")
+
+	down := true
+	var lastArgs []string
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			lastArgs = args
+			if down {
+				return []byte("ERROR    HTTPError: 503 Service Unavailable from http://localhost:8080/"), errors.New("exit status 1")
+			}
+			return []byte("Uploading distributions"), nil
+		},
+	}}
+	config := map[string]any{
+		"username":   "__token__",
+		"password":   "pypi-token",
+		"repository": "http://localhost:8080/",
+		"queue_dir":  "queue",
+	}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "v1.0.0"},
+	})
+	if err != nil || !resp.Success || resp.Outputs["queued"] != true {
+		t.Fatalf("expected the upload to be queued, got %v %+v", err, resp)
+	}
+	entry, _ := resp.Outputs["queue_entry"].(string)
+	manifest, err := os.ReadFile(filepath.Join(entry, queueManifestName))
+	if err != nil {
+		t.Fatalf("expected a manifest: %v", err)
+	}
+	if strings.Contains(string(manifest), "pypi-token") || !strings.Contains(string(manifest), `"version": "1.0.0"`) {
+		t.Errorf("unexpected manifest %s", manifest)
+	}
+
+	// A resume while the index is still down keeps the entry
+	config["resume_queued"] = true
+	resp, _ = p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if resp.Success {
+		t.Error("expected the resume to fail while the index is down")
+	}
+	if _, err := os.Stat(entry); err != nil {
+		t.Errorf("expected the entry to stay queued: %v", err)
+	}
+
+	down = false
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the resume to succeed, got %v %+v", err, resp)
+	}
+	if resumed, _ := resp.Outputs["resumed"].([]map[string]any); len(resumed) != 1 {
+		t.Errorf("unexpected resumed entries %v", resp.Outputs["resumed"])
+	}
+	args := strings.Join(lastArgs, " ")
+	if !strings.Contains(args, "--skip-existing") || !strings.Contains(args, filepath.Join(entry, "dist", "mypkg-1.0.0.tar.gz")) {
+		t.Errorf("unexpected resume upload arguments %q", args)
+	}
+	if _, err := os.Stat(entry); !os.IsNotExist(err) {
+		t.Errorf("expected the entry to be removed, got %v", err)
+	}
+}
+
+func TestValidateQueueConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"unset", Config{}, false},
+		{"queue dir", Config{QueueDir: ".relicta/pypi-queue", DistPath: "dist/*"}, false},
+		{"resume without queue dir", Config{ResumeQueued: true}, true},
+		{"absolute", Config{QueueDir: "/var/queue"}, true},
+		{"inside dist path", Config{QueueDir: "dist/queue", DistPath: "dist/*"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateQueueConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateQueueConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
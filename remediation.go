@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// remediationTwoFactor names the built-in rule for password uploads to accounts with
+// two-factor authentication.
+const remediationTwoFactor = "two_factor_required"
+
+// RemediationRule maps upload error output matching Pattern to a suggested fix, which is put in
+// front of the upload error.
+type RemediationRule struct {
+	// Name labels the rule in the remediation output (defaults to "custom")
+	Name string
+	// Pattern is a regular expression matched against the upload error and tool output
+	Pattern string
+	// Suggestion tells the maintainer how to fix the failure
+	Suggestion string
+}
+
+// remediation is the suggested fix of a failed upload, reported in outputs.
+type remediation struct {
+	Rule       string `json:"rule"`
+	Suggestion string `json:"suggestion"`
+}
+
+// builtinRemediation is a built-in rule.
+type builtinRemediation struct {
+	name       string
+	match      func(output string) bool
+	suggestion string
+}
+
+// builtinRemediations recognize the rejections of PyPI and Warehouse-compatible indexes whose
+// cause is not obvious from the HTTP status. They are tried after the remediations option.
+var builtinRemediations = []builtinRemediation{
+	{remediationTwoFactor, isTwoFactorFailure, twoFactorAdvice},
+	{"readme_render", regexp.MustCompile(`(?i)description failed to render`).MatchString,
+		"The README used as the long description does not render; PyPI rejects reStructuredText with directives or markup it cannot render. Run twine check on the distributions to see the error, or declare long_description_content_type = text/markdown for a Markdown README"},
+	{"file_exists", regexp.MustCompile(`(?i)file already exists`).MatchString,
+		"The version is already on the index and its files cannot be replaced; bump the version, or set skip_existing to skip files that are already uploaded"},
+	{"filename_reused", regexp.MustCompile(`(?i)filename has already been used`).MatchString,
+		"PyPI never accepts a file name twice, even after the file or release was deleted; publish a new version, such as a post release"},
+	{"not_project_owner", regexp.MustCompile(`(?i)isn't allowed to upload to project`).MatchString,
+		"The credentials are not allowed to publish this project; ask an owner to add the account as a maintainer, or use an API token scoped to the project"},
+	{"invalid_token", regexp.MustCompile(`(?i)invalid or non-existent authentication information`).MatchString,
+		"The index rejected the credentials; check that the API token is complete, has not been revoked and is scoped to this project, and that the username is __token__"},
+	{"name_too_similar", regexp.MustCompile(`(?i)too similar to an existing project`).MatchString,
+		"PyPI rejects new project names too similar to existing ones; choose a different name"},
+	{"invalid_classifier", regexp.MustCompile(`(?i)invalid value for classifiers|is not a valid classifier`).MatchString,
+		"A trove classifier is not recognized; correct or remove it using the list at https://pypi.org/classifiers/"},
+	{"local_version", regexp.MustCompile(`(?i)PEP 440 local versions`).MatchString,
+		"The index does not accept local versions such as 1.0.0+abc; set local_version to strip or redirect"},
+	{"invalid_version", regexp.MustCompile(`(?i)invalid value for version`).MatchString,
+		"The version is not a valid PEP 440 version; check the version your build backend stamps into the metadata"},
+	{"metadata_version", regexp.MustCompile(`(?i)invalid value for metadata-version|metadata is missing required fields|unknown distribution format`).MatchString,
+		"twine cannot read the metadata of the distributions, which happens when it is older than the build backend; upgrade twine and pkginfo"},
+	{"platform_tag", regexp.MustCompile(`(?i)unsupported platform tag`).MatchString,
+		"PyPI only accepts portable Linux wheels; build manylinux or musllinux wheels, for example with cibuildwheel, or repair them with auditwheel"},
+	{"file_too_large", regexp.MustCompile(`(?i)file too large|413 Request Entity Too Large|413 Payload Too Large`).MatchString,
+		"The distribution exceeds the upload size limit of the project (100 MB on PyPI); shrink it or request a higher limit from the index"},
+	{"project_too_large", regexp.MustCompile(`(?i)project size too large`).MatchString,
+		"The project exceeds its total size limit (10 GB on PyPI); delete old releases or request a higher limit from the index"},
+}
+
+// parseRemediations parses the remediations option.
+func parseRemediations(raw any) []RemediationRule {
+	items, ok := raw.([]any)
+	if !ok {
+		return nil
+	}
+
+	rules := make([]RemediationRule, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		var r RemediationRule
+		r.Name, _ = m["name"].(string)
+		r.Pattern, _ = m["pattern"].(string)
+		r.Suggestion, _ = m["suggestion"].(string)
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// validateRemediations validates the remediations option.
+func validateRemediations(rules []RemediationRule) error {
+	for i, r := range rules {
+		if r.Pattern == "" || strings.TrimSpace(r.Suggestion) == "" {
+			return fmt.Errorf("remediations[%d] requires pattern and suggestion", i)
+		}
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("remediations[%d].pattern is invalid: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// suggestRemediation returns the fix of the first rule matching the upload failure, trying the
+// configured rules, then those of the configured index type, then the built-in ones, or nil
+// when none matches.
+func suggestRemediation(cfg Config, text string) *remediation {
+	for _, r := range cfg.Remediations {
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil || !pattern.MatchString(text) {
+			continue
+		}
+		name := r.Name
+		if name == "" {
+			name = "custom"
+		}
+		return &remediation{Rule: name, Suggestion: strings.TrimSpace(r.Suggestion)}
+	}
+	builtins := builtinRemediations
+	if cfg.Artifactory != nil {
+		builtins = append(append([]builtinRemediation{}, artifactoryRemediations...), builtins...)
+	}
+	for _, r := range builtins {
+		if r.match(text) {
+			return &remediation{Rule: r.name, Suggestion: r.suggestion}
+		}
+	}
+	return nil
+}
+
+// withRemediation puts the suggested fix in front of an upload error.
+func withRemediation(fix *remediation, errText string) string {
+	return strings.TrimSuffix(fix.Suggestion, ".") + ".\n" + errText
+}
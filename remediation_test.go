@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestSuggestRemediation(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"HTTPError: 400 Bad Request from https://upload.pypi.org/legacy/\nThe description failed to render for 'text/x-rst'. See https://pypi.org/help/#description-content-type for more information.", "readme_render"},
+		{"HTTPError: 400 Bad Request from https://upload.pypi.org/legacy/\nFile already exists ('mypkg-1.0.0.tar.gz', with blake2_256 hash '0123').", "file_exists"},
+		{"HTTPError: 400 Bad Request from https://upload.pypi.org/legacy/\nThis filename has already been used, use a different version.", "filename_reused"},
+		{"HTTPError: 403 Forbidden from https://upload.pypi.org/legacy/\nThe user 'release-bot' isn't allowed to upload to project 'mypkg'.", "not_project_owner"},
+		{"HTTPError: 403 Forbidden from https://upload.pypi.org/legacy/\nInvalid or non-existent authentication information.", "invalid_token"},
+		{"HTTPError: 400 Bad Request\nInvalid value for classifiers. Error: Classifier 'Framework :: Djangoo' is not a valid classifier.", "invalid_classifier"},
+		{"HTTPError: 400 Bad Request\nCan't use PEP 440 local versions.", "local_version"},
+		{"HTTPError: 400 Bad Request\n'1.0.0-beta.x' is an invalid value for Version.", "invalid_version"},
+		{"InvalidDistribution: Metadata is missing required fields: Name, Version.", "metadata_version"},
+		{"HTTPError: 400 Bad Request\nBinary wheel 'mypkg-1.0.0-cp312-cp312-linux_x86_64.whl' has an unsupported platform tag 'linux_x86_64'.", "platform_tag"},
+		{"HTTPError: 400 Bad Request\nFile too large. Limit for project 'mypkg' is 100 MB.", "file_too_large"},
+		{"User release-bot has two factor auth enabled, an API Token or Trusted Publisher must be used to upload in place of password.", remediationTwoFactor},
+		{"HTTPError: 500 Internal Server Error", ""},
+	}
+	for _, tt := range tests {
+		var got string
+		if fix := suggestRemediation(Config{}, tt.output); fix != nil {
+			got = fix.Rule
+		}
+		if got != tt.want {
+			t.Errorf("suggestRemediation(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+
+	// Configured rules come first, so teams can replace the built-in advice
+	rules := []RemediationRule{
+		{Pattern: `File already exists`, Suggestion: "Versions are cut by the release train; re-run the train instead."},
+		{Name: "proxy", Pattern: `(?i)407 Proxy Authentication Required`, Suggestion: "Renew the proxy credentials in HTTPS_PROXY"},
+	}
+	if fix := suggestRemediation(Config{Remediations: rules}, "HTTPError: 400 File already exists"); fix == nil || fix.Rule != "custom" || !strings.HasPrefix(fix.Suggestion, "Versions are cut") {
+		t.Errorf("expected the configured rule to win, got %+v", fix)
+	}
+	if fix := suggestRemediation(Config{Remediations: rules}, "HTTPError: 407 Proxy Authentication Required"); fix == nil || fix.Rule != "proxy" {
+		t.Errorf("expected the proxy rule, got %+v", fix)
+	}
+	if got := withRemediation(&remediation{Suggestion: "Re-run the train."}, "twine upload failed"); got != "Re-run the train.\ntwine upload failed" {
+		t.Errorf("withRemediation() = %q", got)
+	}
+}
+
+func TestExecuteRemediation(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("Uploading mypkg-1.0.0.tar.gz\nERROR    HTTPError: 400 Bad Request from http://localhost:8080/\n         The description failed to render in the default format of reStructuredText."), errors.New("exit status 1")
+		},
+	}}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":   "__token__",
+			"password":   "pypi-token",
+			"repository": "http://localhost:8080/",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.HasPrefix(resp.Error, "The README used as the long description does not render") || !strings.Contains(resp.Error, "twine upload failed") {
+		t.Errorf("expected the README advice in front of the error, got %q", resp.Error)
+	}
+	if fix, _ := resp.Outputs["remediation"].(*remediation); fix == nil || fix.Rule != "readme_render" {
+		t.Errorf("unexpected remediation output %v", resp.Outputs["remediation"])
+	}
+	if _, ok := resp.Outputs["auth_failure"]; ok {
+		t.Errorf("expected no auth_failure output, got %v", resp.Outputs)
+	}
+}
+
+func TestValidateRemediations(t *testing.T) {
+	if err := validateRemediations([]RemediationRule{{Pattern: `HTTPError: 418`, Suggestion: "Ask the teapot."}}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := validateRemediations([]RemediationRule{{Pattern: `HTTPError: 418`}}); err == nil {
+		t.Error("expected a rule without a suggestion to be rejected")
+	}
+	if err := validateRemediations([]RemediationRule{{Pattern: `(unclosed`, Suggestion: "x"}}); err == nil || !strings.Contains(err.Error(), "remediations[0].pattern is invalid") {
+		t.Errorf("expected the invalid pattern to be rejected, got %v", err)
+	}
+}
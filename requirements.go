@@ -0,0 +1,61 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// requirementPattern splits a PEP 508 requirement into name, extras, specifier and marker.
+var requirementPattern = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)\s*(\[[^\]]*\])?\s*\(?([^;()]*)\)?\s*(?:;\s*(.*))?$`)
+
+// requirement is a parsed dependency specification.
+type requirement struct {
+	Name      string
+	Extras    string
+	Specifier string
+	Marker    string
+}
+
+// parseRequirement parses a Requires-Dist or requirements-file line.
+// It returns false for lines that are not plain named requirements (URLs, options, comments).
+func parseRequirement(line string) (requirement, bool) {
+	line = strings.TrimSpace(line)
+	if i := strings.Index(line, " #"); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") || strings.Contains(line, "@") {
+		return requirement{}, false
+	}
+
+	m := requirementPattern.FindStringSubmatch(line)
+	if m == nil {
+		return requirement{}, false
+	}
+
+	return requirement{
+		Name:      m[1],
+		Extras:    m[2],
+		Specifier: strings.ReplaceAll(strings.TrimSpace(m[3]), " ", ""),
+		Marker:    strings.TrimSpace(m[4]),
+	}, true
+}
+
+// pinnedVersion returns the exact version of an "==" requirement, if it pins one.
+func (r requirement) pinnedVersion() (string, bool) {
+	if !strings.HasPrefix(r.Specifier, "==") || strings.HasPrefix(r.Specifier, "===") {
+		return "", false
+	}
+	v := strings.TrimPrefix(r.Specifier, "==")
+	if v == "" || strings.ContainsAny(v, ",*") {
+		return "", false
+	}
+	return v, true
+}
+
+// normalizeProjectName normalizes a project name per PEP 503.
+func normalizeProjectName(name string) string {
+	return strings.ToLower(projectNameSeparators.ReplaceAllString(name, "-"))
+}
+
+// projectNameSeparators matches runs of characters that PEP 503 treats as equivalent.
+var projectNameSeparators = regexp.MustCompile(`[-_.]+`)
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestParseRequirement(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   requirement
+		wantOK bool
+	}{
+		{"requests==2.31.0", requirement{Name: "requests", Specifier: "==2.31.0"}, true},
+		{"requests (==2.19.0)", requirement{Name: "requests", Specifier: "==2.19.0"}, true},
+		{"click >= 8.0, < 9 ; python_version >= \"3.8\"", requirement{Name: "click", Specifier: ">=8.0,<9", Marker: "python_version >= \"3.8\""}, true},
+		{"uvicorn[standard]==0.23.0  # server", requirement{Name: "uvicorn", Extras: "[standard]", Specifier: "==0.23.0"}, true},
+		{"six", requirement{Name: "six"}, true},
+		{"# comment", requirement{}, false},
+		{"-r other.txt", requirement{}, false},
+		{"pkg @ https://example.com/pkg.whl", requirement{}, false},
+		{"", requirement{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := parseRequirement(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinnedVersion(t *testing.T) {
+	tests := []struct {
+		specifier string
+		want      string
+		wantOK    bool
+	}{
+		{"==1.0", "1.0", true},
+		{"==1.*", "", false},
+		{"===1.0", "", false},
+		{">=1.0", "", false},
+		{"==1.0,!=1.0.1", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := requirement{Specifier: tt.specifier}.pinnedVersion()
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("pinnedVersion(%q) = %q, %v; want %q, %v", tt.specifier, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNormalizeProjectName(t *testing.T) {
+	for _, name := range []string{"Friendly-Bard", "friendly.bard", "FRIENDLY__bard", "friendly-._bard"} {
+		if got := normalizeProjectName(name); got != "friendly-bard" {
+			t.Errorf("normalizeProjectName(%q) = %q", name, got)
+		}
+	}
+}
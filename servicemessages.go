@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Values of the service_messages option.
+const (
+	serviceMessagesOff  = "off"
+	serviceMessagesAuto = "auto"
+	// serviceMessagesTeamCity prints ##teamcity[...] service messages to the build log
+	serviceMessagesTeamCity = "teamcity"
+	// serviceMessagesJenkins writes a properties file that pipelines load with readProperties
+	// and freestyle jobs with EnvInject
+	serviceMessagesJenkins = "jenkins"
+)
+
+// serviceMessageFormats lists the accepted service_messages values.
+var serviceMessageFormats = []string{serviceMessagesOff, serviceMessagesAuto, serviceMessagesTeamCity, serviceMessagesJenkins}
+
+// jenkinsPropertiesFile receives the published release for Jenkins.
+const jenkinsPropertiesFile = "pypi-publish.properties"
+
+// serviceMessageFormat returns the format of the service messages for cfg, detecting the CI
+// system for auto by the variables TeamCity and Jenkins set in builds. It returns off when
+// nothing is to be emitted.
+func serviceMessageFormat(cfg Config) string {
+	if cfg.ServiceMessages != serviceMessagesAuto {
+		return cfg.ServiceMessages
+	}
+	switch {
+	case os.Getenv("TEAMCITY_VERSION") != "":
+		return serviceMessagesTeamCity
+	case os.Getenv("JENKINS_URL") != "":
+		return serviceMessagesJenkins
+	default:
+		return serviceMessagesOff
+	}
+}
+
+// teamCityEscaper escapes the values of TeamCity service messages.
+var teamCityEscaper = strings.NewReplacer(
+	"|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]",
+	"\u0085", "|x", "\u2028", "|l", "\u2029", "|p",
+)
+
+// teamCityMessages returns the service messages registering the published files as build
+// artifacts and the release as build parameters and a build tag.
+func teamCityMessages(files []string, project, version, repository string) []string {
+	var messages []string
+	for _, f := range files {
+		messages = append(messages, fmt.Sprintf("##teamcity[publishArtifacts '%s']", teamCityEscaper.Replace(filepath.ToSlash(f))))
+	}
+	for _, param := range [][2]string{{"pypi.project", project}, {"pypi.version", version}, {"pypi.repository", repository}} {
+		messages = append(messages, fmt.Sprintf("##teamcity[setParameter name='%s' value='%s']", param[0], teamCityEscaper.Replace(param[1])))
+	}
+	return append(messages, fmt.Sprintf("##teamcity[addBuildTag '%s']", teamCityEscaper.Replace(project+"=="+version)))
+}
+
+// jenkinsEscaper escapes the values of a Java properties file.
+var jenkinsEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+
+// jenkinsProperties returns the properties file of a published release. PYPI_FILES lists the
+// files separated by commas, as archiveArtifacts takes them.
+func jenkinsProperties(files []string, project, version, repository string) string {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = filepath.ToSlash(f)
+	}
+	var b strings.Builder
+	for _, prop := range [][2]string{
+		{"PYPI_PROJECT", project},
+		{"PYPI_VERSION", version},
+		{"PYPI_REPOSITORY", repository},
+		{"PYPI_FILES", strings.Join(paths, ",")},
+	} {
+		fmt.Fprintf(&b, "%s=%s\n", prop[0], jenkinsEscaper.Replace(prop[1]))
+	}
+	return b.String()
+}
+
+// emitServiceMessages registers a successful publish with TeamCity or Jenkins. Only the files
+// the index holds afterwards, uploaded or skipped as existing, are registered.
+func (p *PyPIPlugin) emitServiceMessages(cfg Config, outcome uploadOutcome, version string) error {
+	format := serviceMessageFormat(cfg)
+	if format == serviceMessagesOff || len(outcome.files) == 0 {
+		return nil
+	}
+	statuses := outcome.statuses()
+	var files []string
+	for _, f := range outcome.files {
+		if s := statuses[filepath.Base(f)]; s == fileUploaded || s == fileSkipped {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	project, metaVersion := distProjectVersion(files)
+	if project == "" {
+		project = strings.SplitN(filepath.Base(files[0]), "-", 2)[0]
+	}
+	if version == "" {
+		version = metaVersion
+	}
+	repository := maskURLPasswords(cfg.Repository)
+
+	if format == serviceMessagesJenkins {
+		if err := os.WriteFile(jenkinsPropertiesFile, []byte(jenkinsProperties(files, project, version, repository)), 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", jenkinsPropertiesFile, err)
+		}
+		return nil
+	}
+	w := p.getStreamWriter()
+	for _, message := range teamCityMessages(files, project, version, repository) {
+		if _, err := fmt.Fprintln(w, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateServiceMessages validates the service_messages option.
+func validateServiceMessages(cfg Config) error {
+	if !containsString(serviceMessageFormats, cfg.ServiceMessages) {
+		return fmt.Errorf("service_messages must be one of: %s", strings.Join(serviceMessageFormats, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sha256HexPattern matches a hex-encoded SHA-256 digest.
+var sha256HexPattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// sharedObject is a native library shipped inside a Linux wheel.
+type sharedObject struct {
+	Dist   string `json:"dist"`
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   uint64 `json:"size"`
+}
+
+// isLinuxBinaryWheel reports whether a wheel file name carries a manylinux or musllinux platform tag.
+func isLinuxBinaryWheel(name string) bool {
+	return strings.HasSuffix(name, ".whl") && (strings.Contains(name, "manylinux") || strings.Contains(name, "musllinux") ||
+		strings.Contains(name, "-linux_"))
+}
+
+// listSharedObjects returns every .so file in a wheel with its SHA-256 digest.
+func listSharedObjects(wheelPath string) ([]sharedObject, error) {
+	zr, err := zip.OpenReader(wheelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wheel: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	var objects []sharedObject
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !isSharedObjectName(path.Base(f.Name)) {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, rc) // #nosec G110 -- size is bounded by the wheel being published
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", f.Name, err)
+		}
+
+		objects = append(objects, sharedObject{
+			Dist:   filepath.Base(wheelPath),
+			Path:   f.Name,
+			SHA256: hex.EncodeToString(h.Sum(nil)),
+			Size:   f.UncompressedSize64,
+		})
+	}
+	return objects, nil
+}
+
+// isSharedObjectName reports whether a file name is an ELF shared object (foo.so, libfoo.so.1.2).
+func isSharedObjectName(name string) bool {
+	return strings.HasSuffix(name, ".so") || strings.Contains(name, ".so.")
+}
+
+// sharedObjectAllowed reports whether an allowlist entry matches the object.
+// Entries that look like SHA-256 digests match the content; any other entry is a glob
+// matched against the path inside the wheel.
+func sharedObjectAllowed(obj sharedObject, allowlist []string) bool {
+	for _, entry := range allowlist {
+		if sha256HexPattern.MatchString(entry) {
+			if strings.EqualFold(entry, obj.SHA256) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(entry, obj.Path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSharedObjectConfig validates the shared object check options.
+func validateSharedObjectConfig(cfg Config) error {
+	if cfg.SharedObjectCheck == "" || cfg.SharedObjectCheck == checkOff {
+		return nil
+	}
+	if !containsString(checkModes, cfg.SharedObjectCheck) {
+		return fmt.Errorf("shared_object_check must be one of: %s", strings.Join(checkModes, ", "))
+	}
+	for i, entry := range cfg.SharedObjectAllowlist {
+		if sha256HexPattern.MatchString(entry) {
+			continue
+		}
+		if _, err := path.Match(entry, ""); err != nil {
+			return fmt.Errorf("shared_object_allowlist[%d]: invalid pattern: %w", i, err)
+		}
+	}
+	return nil
+}
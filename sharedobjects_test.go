@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestListSharedObjects(t *testing.T) {
+	wheel := filepath.Join(t.TempDir(), "app-1.0.0-cp311-cp311-manylinux_2_17_x86_64.whl")
+	writeTestWheel(t, wheel, map[string]string{
+		"app/__init__.py": "",
+		"app/_native.cpython-311-x86_64-linux-gnu.so": "native",
+		"app.libs/libz-a147dcb0.so.1.2.13":            "zlib",
+		"app-1.0.0.dist-info/METADATA":                "Name: app\nVersion: 1.0.0\n",
+	})
+
+	objects, err := listSharedObjects(wheel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 shared objects, got %+v", objects)
+	}
+	for _, obj := range objects {
+		switch obj.Path {
+		case "app/_native.cpython-311-x86_64-linux-gnu.so":
+			if obj.SHA256 != sha256Hex("native") || obj.Size != 6 {
+				t.Errorf("unexpected digest or size: %+v", obj)
+			}
+		case "app.libs/libz-a147dcb0.so.1.2.13":
+			if obj.SHA256 != sha256Hex("zlib") {
+				t.Errorf("unexpected digest: %+v", obj)
+			}
+		default:
+			t.Errorf("unexpected object: %s", obj.Path)
+		}
+		if obj.Dist != filepath.Base(wheel) {
+			t.Errorf("unexpected dist: %s", obj.Dist)
+		}
+	}
+}
+
+func TestIsLinuxBinaryWheel(t *testing.T) {
+	tests := map[string]bool{
+		"app-1.0-cp311-cp311-manylinux_2_17_x86_64.manylinux2014_x86_64.whl": true,
+		"app-1.0-cp311-cp311-musllinux_1_1_aarch64.whl":                      true,
+		"app-1.0-cp311-cp311-linux_x86_64.whl":                               true,
+		"app-1.0-py3-none-any.whl":                                           false,
+		"app-1.0-cp311-cp311-macosx_11_0_arm64.whl":                          false,
+		"app-1.0.tar.gz": false,
+	}
+	for name, want := range tests {
+		if got := isLinuxBinaryWheel(name); got != want {
+			t.Errorf("isLinuxBinaryWheel(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestSharedObjectAllowed(t *testing.T) {
+	obj := sharedObject{Path: "app.libs/libz-a147dcb0.so.1.2.13", SHA256: sha256Hex("zlib")}
+	tests := []struct {
+		name      string
+		allowlist []string
+		want      bool
+	}{
+		{"digest", []string{strings.ToUpper(sha256Hex("zlib"))}, true},
+		{"other digest", []string{sha256Hex("other")}, false},
+		{"glob", []string{"app.libs/libz-*.so.*"}, true},
+		{"non-matching glob", []string{"app/*.so"}, false},
+		{"empty", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sharedObjectAllowed(obj, tt.allowlist); got != tt.want {
+				t.Errorf("sharedObjectAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteSharedObjectCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]any
+		wantSuccess bool
+	}{
+		{"report only", map[string]any{}, true},
+		{"all allowed", map[string]any{"shared_object_check": "fail", "shared_object_allowlist": []any{"app/*.so", sha256Hex("injected")}}, true},
+		{"unexpected blocks", map[string]any{"shared_object_check": "fail", "shared_object_allowlist": []any{"app/*.so"}}, false},
+		{"unexpected warns", map[string]any{"shared_object_check": "warn", "shared_object_allowlist": []any{"app/*.so"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distPath := writeDistFiles(t)
+			writeTestWheel(t, filepath.Join("dist", "app-1.0.0-cp311-cp311-manylinux_2_17_x86_64.whl"), map[string]string{
+				"app/_native.so":             "native",
+				"app.libs/libevil.so.1":      "injected",
+				"app-1.0.0.dist-info/RECORD": "",
+			})
+
+			config := map[string]any{
+				"username":   "__token__",
+				"password":   "pypi-token",
+				"repository": "http://localhost:8080/",
+				"dist_path":  distPath,
+			}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+
+			p := &PyPIPlugin{}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:   plugin.HookPostPublish,
+				Config: config,
+				DryRun: true,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Success != tt.wantSuccess {
+				t.Fatalf("success = %v, want %v (error: %s)", resp.Success, tt.wantSuccess, resp.Error)
+			}
+			if !tt.wantSuccess {
+				if !strings.Contains(resp.Error, "app.libs/libevil.so.1 (sha256:"+sha256Hex("injected")+")") {
+					t.Errorf("expected unexpected object in error, got: %s", resp.Error)
+				}
+				return
+			}
+			objects, ok := resp.Outputs["shared_objects"].([]sharedObject)
+			if !ok || len(objects) != 2 {
+				t.Errorf("expected shared_objects output with 2 entries, got %v", resp.Outputs["shared_objects"])
+			}
+		})
+	}
+}
+
+func TestValidateSharedObjectConfig(t *testing.T) {
+	p := &PyPIPlugin{}
+	if err := validateSharedObjectConfig(p.parseConfig(map[string]any{"shared_object_check": "warn", "shared_object_allowlist": []any{"app/[.so"}})); err == nil {
+		t.Error("expected error for invalid glob")
+	}
+	if err := validateSharedObjectConfig(p.parseConfig(map[string]any{"shared_object_check": "strict"})); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+	if err := validateSharedObjectConfig(p.parseConfig(map[string]any{"shared_object_check": "fail", "shared_object_allowlist": []any{sha256Hex("x")}})); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Results of uploading the signature of a published distribution.
+const (
+	signatureUploaded = "uploaded"
+	signatureRejected = "rejected"
+	// signaturePending is a signature a dry run would upload
+	signaturePending = "pending"
+)
+
+// pgpSignatureHeader starts an ASCII-armored detached signature.
+var pgpSignatureHeader = []byte("-----BEGIN PGP SIGNATURE-----")
+
+// uploadedSignature is the signature upload of one published distribution, reported in outputs.
+type uploadedSignature struct {
+	File      string `json:"file"`
+	Signature string `json:"signature"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// isSignatureFile reports whether a dist_path match is a detached signature rather than a
+// distribution.
+func isSignatureFile(path string) bool {
+	return strings.HasSuffix(path, ".asc")
+}
+
+// uploadSignaturesOnly attaches the <file>.asc signatures of distributions that are already
+// published, for signing stages that run after the initial publish. The legacy upload API
+// only takes a signature with its file, so each file is submitted again with gpg_signature;
+// the file must be published with the same SHA-256, so the index receives no new content.
+func (p *PyPIPlugin) uploadSignaturesOnly(ctx context.Context, cfg Config, releaseCtxVersion string, dryRun bool) *plugin.ExecuteResponse {
+	fail := func(format string, args ...any) *plugin.ExecuteResponse {
+		return &plugin.ExecuteResponse{Success: false, Error: "signature upload failed: " + fmt.Sprintf(format, args...)}
+	}
+	version, err := releaseVersion(cfg, releaseCtxVersion)
+	if err != nil {
+		return fail("%v", err)
+	}
+	files, err := expandDistGlob(cfg.DistPath)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if len(files) == 0 {
+		return fail("no distributions in %s", cfg.DistPath)
+	}
+	project, distVersion := distProjectVersion(files)
+	if project == "" {
+		return fail("no distribution with readable metadata in %s", cfg.DistPath)
+	}
+	if version == "" {
+		version = distVersion
+	}
+
+	// Every signature is checked before anything is sent, so a partial signing stage uploads nothing
+	dists := make([]distribution, 0, len(files))
+	for _, f := range files {
+		sigPath := signatureFilePath(f)
+		sig, err := os.ReadFile(sigPath) // #nosec G304 -- the signature of a dist_path file
+		if err != nil {
+			return fail("%s has no signature %s", filepath.Base(f), filepath.Base(sigPath))
+		}
+		if !bytes.HasPrefix(bytes.TrimSpace(sig), pgpSignatureHeader) {
+			return fail("%s is not an ASCII-armored PGP signature", filepath.Base(sigPath))
+		}
+		dist, err := distributionForFile(f)
+		if err != nil {
+			return fail("%v", err)
+		}
+		dist.SignaturePath = sigPath
+		dists = append(dists, dist)
+	}
+
+	published, err := p.publishedFiles(ctx, cfg, project, version)
+	if err != nil {
+		return fail("cannot read %s %s from the index: %v", project, version, err)
+	}
+	for _, f := range files {
+		name := filepath.Base(f)
+		indexDigest, ok := published[name]
+		if !ok {
+			return fail("%s is not published on %s; signatures_only only signs published files", name, cfg.Repository)
+		}
+		if _, digest, _, err := fileDigests(f); err != nil {
+			return fail("%v", err)
+		} else if indexDigest != "" && indexDigest != digest {
+			return fail("%s is published with a different SHA256 than the signed file", name)
+		}
+	}
+
+	outputs := map[string]any{
+		"project":      project,
+		"version":      version,
+		"repository":   cfg.Repository,
+		"plugin_build": currentBuild().String(),
+	}
+	results := make([]uploadedSignature, 0, len(dists))
+	if dryRun {
+		for _, dist := range dists {
+			results = append(results, uploadedSignature{File: filepath.Base(dist.Path), Signature: filepath.Base(dist.SignaturePath), Status: signaturePending})
+		}
+		outputs["signatures"] = results
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Would upload %d signature(s) of %s %s to %s", len(dists), project, version, cfg.Repository),
+			Outputs: outputs,
+		}
+	}
+
+	uploader := newNativeUploader(p.uploadHTTPClient(cfg), cfg)
+	var rejected []string
+	for _, dist := range dists {
+		result := uploadedSignature{File: filepath.Base(dist.Path), Signature: filepath.Base(dist.SignaturePath), Status: signatureUploaded}
+		if _, err := uploader.upload(ctx, dist); err != nil {
+			result.Status, result.Error = signatureRejected, err.Error()
+			rejected = append(rejected, result.Signature)
+		}
+		results = append(results, result)
+	}
+	outputs["signatures"] = results
+	if len(rejected) > 0 {
+		resp := fail("%s rejected %d signature(s): %s; the index may not accept signatures for files already uploaded",
+			cfg.Repository, len(rejected), strings.Join(rejected, ", "))
+		resp.Outputs = outputs
+		return resp
+	}
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Uploaded %d signature(s) of %s %s to %s", len(dists), project, version, cfg.Repository),
+		Outputs: outputs,
+	}
+}
+
+// validateSignaturesOnlyConfig validates the signatures_only option.
+func validateSignaturesOnlyConfig(cfg Config) error {
+	if !cfg.SignaturesOnly {
+		return nil
+	}
+	if isPyPIRepository(cfg.Repository) {
+		return fmt.Errorf("signatures_only is not supported by PyPI and TestPyPI, which no longer accept PGP signatures")
+	}
+	if cfg.JSONAPIURL == "" && cfg.IndexURL == "" {
+		return fmt.Errorf("signatures_only needs json_api_url or index_url to find the published files")
+	}
+	if cfg.VerifyOnly || cfg.Benchmark || cfg.BackfillVersion != "" || cfg.DevRelease || auditsReleases(cfg) || len(cfg.CustomCommand) > 0 || cfg.NexusStagingDestination != "" {
+		return fmt.Errorf("signatures_only cannot be combined with verify_only, benchmark, backfill_version, dev_release, release_audit, custom_command or nexus_staging_destination")
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+const testPGPSignature = "-----BEGIN PGP SIGNATURE-----\n\niQEzBAABCAAdFiEE\n-----END PGP SIGNATURE-----\n"
+
+// writeSignedDists writes the distributions of writeReleaseDists with their .asc signatures.
+func writeSignedDists(t *testing.T) map[string]string {
+	t.Helper()
+	digests := writeReleaseDists(t)
+	for name := range digests {
+		if err := os.WriteFile(filepath.Join("dist", name+".asc"), []byte(testPGPSignature), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return digests
+}
+
+func TestExecuteSignaturesOnly(t *testing.T) {
+	digests := writeSignedDists(t)
+	var mu sync.Mutex
+	var uploads []string
+	reject := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pypi/mypkg/1.0.0/json":
+			var urls []string
+			for name, digest := range digests {
+				urls = append(urls, fmt.Sprintf(`{"filename": %q, "digests": {"sha256": %q}}`, name, digest))
+			}
+			_, _ = fmt.Fprintf(w, `{"urls": [%s]}`, strings.Join(urls, ","))
+		case "/legacy/":
+			content, header, err := r.FormFile("content")
+			if err != nil {
+				t.Errorf("expected the file content, got %v", err)
+				return
+			}
+			_ = content.Close()
+			sig, sigHeader, err := r.FormFile("gpg_signature")
+			if err != nil {
+				t.Errorf("expected a gpg_signature, got %v", err)
+				return
+			}
+			data, _ := io.ReadAll(sig)
+			_ = sig.Close()
+			if string(data) != testPGPSignature || sigHeader.Filename != header.Filename+".asc" {
+				t.Errorf("unexpected signature %s %q", sigHeader.Filename, data)
+			}
+			mu.Lock()
+			uploads = append(uploads, header.Filename)
+			mu.Unlock()
+			if header.Filename == reject {
+				http.Error(w, "File already exists.", http.StatusBadRequest)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	execute := func(dryRun bool) *plugin.ExecuteResponse {
+		t.Helper()
+		p := &PyPIPlugin{}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:   plugin.HookPostPublish,
+			DryRun: dryRun,
+			Config: map[string]any{
+				"username":        "ci",
+				"password":        "secret",
+				"repository":      server.URL + "/legacy/",
+				"json_api_url":    server.URL + "/pypi/",
+				"signatures_only": true,
+			},
+			Context: plugin.ReleaseContext{Version: "v1.0.0"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := execute(true)
+	if !resp.Success || len(uploads) != 0 || !strings.Contains(resp.Message, "Would upload 2 signature(s)") {
+		t.Errorf("expected a dry run to upload nothing, got %+v %v", resp, uploads)
+	}
+
+	resp = execute(false)
+	if !resp.Success || len(uploads) != 2 {
+		t.Fatalf("expected both signatures to be uploaded, got %+v %v", resp, uploads)
+	}
+	if results, _ := resp.Outputs["signatures"].([]uploadedSignature); len(results) != 2 || results[0].Status != signatureUploaded {
+		t.Errorf("unexpected signatures %+v", resp.Outputs["signatures"])
+	}
+
+	uploads, reject = nil, "mypkg-1.0.0.tar.gz"
+	resp = execute(false)
+	if resp.Success || !strings.Contains(resp.Error, "rejected 1 signature(s): mypkg-1.0.0.tar.gz.asc") {
+		t.Errorf("expected the rejected signature to fail the run, got %+v", resp)
+	}
+
+	// A file changed since the publish cannot carry the signature of the published one
+	digests["mypkg-1.0.0.tar.gz"] = strings.Repeat("0", 64)
+	uploads = nil
+	resp = execute(false)
+	if resp.Success || len(uploads) != 0 || !strings.Contains(resp.Error, "published with a different SHA256") {
+		t.Errorf("expected the mismatched file to fail the run before any upload, got %+v %v", resp, uploads)
+	}
+
+	if err := os.Remove(filepath.Join("dist", "mypkg-1.0.0.tar.gz.asc")); err != nil {
+		t.Fatal(err)
+	}
+	resp = execute(false)
+	if resp.Success || !strings.Contains(resp.Error, "mypkg-1.0.0.tar.gz has no signature") {
+		t.Errorf("expected the missing signature to fail the run, got %+v", resp)
+	}
+}
+
+func TestValidateSignaturesOnlyConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"disabled", Config{Repository: "https://upload.pypi.org/legacy/"}, false},
+		{"private index", Config{SignaturesOnly: true, Repository: "https://pypi.example.com/legacy/", IndexURL: "https://pypi.example.com/simple/"}, false},
+		{"pypi", Config{SignaturesOnly: true, Repository: "https://upload.pypi.org/legacy/", IndexURL: "https://pypi.org/simple/"}, true},
+		{"no index", Config{SignaturesOnly: true, Repository: "https://pypi.example.com/legacy/"}, true},
+		{"verify only", Config{SignaturesOnly: true, VerifyOnly: true, Repository: "https://pypi.example.com/legacy/", IndexURL: "https://pypi.example.com/simple/"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSignaturesOnlyConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateSignaturesOnlyConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
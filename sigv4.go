@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWS Signature Version 4 constants.
+const (
+	// authSchemeSigV4 signs upload requests with the runner's AWS credentials
+	authSchemeSigV4 = "sigv4"
+	// defaultAWSService is the signing name of API Gateway, the usual front of such indexes
+	defaultAWSService = "execute-api"
+	sigV4Algorithm    = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat   = "20060102T150405Z"
+)
+
+// errNoAWSCredentials is returned when neither the environment nor the shared credentials
+// file provides AWS credentials.
+var errNoAWSCredentials = errors.New("no AWS credentials found (set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY or configure a profile in the shared credentials file)")
+
+// awsCredentials are the AWS access keys requests are signed with.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// loadAWSCredentials returns the runner's AWS credentials from the standard environment
+// variables, falling back to the AWS_PROFILE (or default) profile of the shared credentials
+// file. Credentials are loaded per request so refreshed session credentials are picked up.
+func loadAWSCredentials() (awsCredentials, error) {
+	creds := awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID != "" && creds.SecretAccessKey != "" {
+		return creds, nil
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return awsCredentials{}, errNoAWSCredentials
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return awsCredentials{}, errNoAWSCredentials
+	}
+	defer func() { _ = f.Close() }()
+	creds = parseAWSCredentialsFile(f, profile)
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return awsCredentials{}, errNoAWSCredentials
+	}
+	return creds, nil
+}
+
+// parseAWSCredentialsFile returns the keys of a profile of an INI-style shared credentials file.
+func parseAWSCredentialsFile(r io.Reader, profile string) awsCredentials {
+	var creds awsCredentials
+	inProfile := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inProfile = strings.TrimSpace(line[1:len(line)-1]) == profile
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || !inProfile {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = value
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = value
+		case "aws_session_token":
+			creds.SessionToken = value
+		}
+	}
+	return creds
+}
+
+// defaultAWSRegion returns the region configured for the AWS SDKs in the environment.
+func defaultAWSRegion() string {
+	if v := os.Getenv("AWS_REGION"); v != "" {
+		return v
+	}
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// signSigV4 adds the AWS Signature Version 4 headers to req. payloadHash is the hex SHA256
+// of the request body, which the caller computes because upload bodies are streamed.
+func signSigV4(req *http.Request, payloadHash string, creds awsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format(sigV4TimeFormat)
+	scope := strings.Join([]string{amzDate[:8], region, service, "aws4_request"}, "/")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	if service == "s3" {
+		// S3 rejects requests without the payload hash header
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers, signedHeaders := canonicalSigV4Headers(req)
+	canonical := strings.Join([]string{
+		req.Method,
+		canonicalSigV4Path(req, service),
+		canonicalSigV4Query(req),
+		headers,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), amzDate[:8])
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalSigV4Headers returns the canonical headers block and the signed header list. The
+// host, content type and x-amz-* headers are signed.
+func canonicalSigV4Headers(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values := map[string]string{"host": host}
+	for name, v := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			values[lower] = strings.Join(strings.Fields(strings.Join(v, ",")), " ")
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + values[name] + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// canonicalSigV4Path returns the canonical URI. Services other than S3 expect the already
+// escaped path to be escaped a second time.
+func canonicalSigV4Path(req *http.Request, service string) string {
+	path := req.URL.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	if service == "s3" {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = sigV4Escape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalSigV4Query returns the query parameters sorted by name and value.
+func canonicalSigV4Query(req *http.Request) string {
+	var pairs [][2]string
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			pairs = append(pairs, [2]string{sigV4Escape(name), sigV4Escape(v)})
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a][0] != pairs[b][0] {
+			return pairs[a][0] < pairs[b][0]
+		}
+		return pairs[a][1] < pairs[b][1]
+	})
+	encoded := make([]string, len(pairs))
+	for i, pair := range pairs {
+		encoded[i] = pair[0] + "=" + pair[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// sigV4Escape percent-encodes every byte except the RFC 3986 unreserved characters.
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// spoolBody copies body to a temporary file while hashing it, so a streamed upload can be
+// signed without buffering the distribution in memory. The returned cleanup removes the file.
+func spoolBody(body io.Reader) (*os.File, string, int64, func(), error) {
+	f, err := os.CreateTemp("", "relicta-pypi-upload-*")
+	if err != nil {
+		return nil, "", 0, nil, fmt.Errorf("failed to create upload spool file: %w", err)
+	}
+	cleanup := func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hash), body)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, "", 0, nil, fmt.Errorf("failed to prepare upload body: %w", err)
+	}
+	return f, hex.EncodeToString(hash.Sum(nil)), size, cleanup, nil
+}
+
+// validateSigV4Config validates the options of the sigv4 auth scheme.
+func validateSigV4Config(cfg Config) error {
+	if cfg.AuthScheme != authSchemeSigV4 {
+		return nil
+	}
+	if cfg.AWSRegion == "" {
+		return fmt.Errorf("aws_region is required for sigv4 (or set AWS_REGION)")
+	}
+	if _, err := loadAWSCredentials(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testAWSCredentials are the example credentials of the AWS SigV4 test suite.
+var testAWSCredentials = awsCredentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func TestSignSigV4(t *testing.T) {
+	// get-vanilla from the AWS Signature Version 4 test suite
+	req := httptest.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	req.Host = "example.amazonaws.com"
+	emptyHash := sha256.Sum256(nil)
+	signSigV4(req, hex.EncodeToString(emptyHash[:]), testAWSCredentials, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %q", got)
+	}
+}
+
+func TestCanonicalSigV4Request(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "https://api.example.com/prod/simple%20index/?b=2&a=1&a=0", nil)
+	if got := canonicalSigV4Path(req, defaultAWSService); got != "/prod/simple%2520index/" {
+		t.Errorf("expected a doubly escaped path, got %q", got)
+	}
+	if got := canonicalSigV4Path(req, "s3"); got != "/prod/simple%20index/" {
+		t.Errorf("expected S3 paths to be escaped once, got %q", got)
+	}
+	if got := canonicalSigV4Query(req); got != "a=0&a=1&b=2" {
+		t.Errorf("unexpected canonical query %q", got)
+	}
+}
+
+func TestParseAWSCredentialsFile(t *testing.T) {
+	data := `[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = default-secret
+
+# release credentials
+[publisher]
+aws_access_key_id=AKIDPUBLISHER
+aws_secret_access_key=publisher-secret
+aws_session_token=session
+`
+	creds := parseAWSCredentialsFile(strings.NewReader(data), "publisher")
+	if creds != (awsCredentials{AccessKeyID: "AKIDPUBLISHER", SecretAccessKey: "publisher-secret", SessionToken: "session"}) {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+	if creds := parseAWSCredentialsFile(strings.NewReader(data), "default"); creds.AccessKeyID != "AKIDDEFAULT" || creds.SessionToken != "" {
+		t.Errorf("unexpected default credentials %+v", creds)
+	}
+}
+
+func TestLoadAWSCredentialsSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(path, []byte("[ci]\naws_access_key_id = AKIDCI\naws_secret_access_key = ci-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	t.Setenv("AWS_PROFILE", "ci")
+
+	creds, err := loadAWSCredentials()
+	if err != nil || creds.AccessKeyID != "AKIDCI" {
+		t.Errorf("expected the profile's credentials, got %+v, %v", creds, err)
+	}
+
+	t.Setenv("AWS_PROFILE", "missing")
+	if _, err := loadAWSCredentials(); err == nil {
+		t.Error("expected an error without credentials")
+	}
+}
+
+func TestNativeUploadSigV4(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	t.Setenv("AWS_ACCESS_KEY_ID", testAWSCredentials.AccessKeyID)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", testAWSCredentials.SecretAccessKey)
+	t.Setenv("AWS_SESSION_TOKEN", "session-token")
+
+	var verified bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.ContentLength != int64(len(body)) {
+			t.Errorf("expected a known content length, got %d for %d bytes", r.ContentLength, len(body))
+		}
+		if r.Header.Get("X-Amz-Security-Token") != "session-token" {
+			t.Error("expected the session token header")
+		}
+
+		// Sign the received request again and compare
+		date, err := time.Parse(sigV4TimeFormat, r.Header.Get("X-Amz-Date"))
+		if err != nil {
+			t.Errorf("invalid X-Amz-Date: %v", err)
+		}
+		check, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+		check.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+		bodyHash := sha256.Sum256(body)
+		signSigV4(check, hex.EncodeToString(bodyHash[:]), awsCredentials{
+			AccessKeyID:     testAWSCredentials.AccessKeyID,
+			SecretAccessKey: testAWSCredentials.SecretAccessKey,
+			SessionToken:    "session-token",
+		}, "eu-west-1", defaultAWSService, date)
+		if got, want := r.Header.Get("Authorization"), check.Header.Get("Authorization"); got != want {
+			t.Errorf("signature mismatch:\n got %s\nwant %s", got, want)
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token") {
+			t.Errorf("unexpected signed headers: %s", r.Header.Get("Authorization"))
+		}
+		verified = true
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	cfg := p.parseConfig(map[string]any{
+		"repository":  server.URL + "/prod/upload/",
+		"auth_scheme": "sigv4",
+		"aws_region":  "eu-west-1",
+	})
+	if err := p.validateConfig(cfg); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	if _, err := p.runNativeUploads(context.Background(), cfg, newPublishSession(cfg), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !verified {
+		t.Error("expected a signed upload request")
+	}
+}
+
+func TestValidateSigV4Config(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", testAWSCredentials.AccessKeyID)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", testAWSCredentials.SecretAccessKey)
+
+	if err := validateSigV4Config(Config{AuthScheme: authSchemeBasic}); err != nil {
+		t.Errorf("unexpected error for basic auth: %v", err)
+	}
+	if err := validateSigV4Config(Config{AuthScheme: authSchemeSigV4, AWSRegion: "us-east-1"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateSigV4Config(Config{AuthScheme: authSchemeSigV4}); err == nil || !strings.Contains(err.Error(), "aws_region") {
+		t.Errorf("expected a missing region error, got %v", err)
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "missing"))
+	if err := validateSigV4Config(Config{AuthScheme: authSchemeSigV4, AWSRegion: "us-east-1"}); err == nil {
+		t.Error("expected an error without AWS credentials")
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Content types of the simple repository API. PEP 691 serves the same listing as JSON or as
+// HTML, and "latest" aliases the newest version of either.
+const (
+	simpleJSONContentType       = "application/vnd.pypi.simple.v1+json"
+	simpleJSONLatestContentType = "application/vnd.pypi.simple.latest+json"
+	simpleHTMLContentType       = "application/vnd.pypi.simple.v1+html"
+)
+
+// simpleAcceptHeader prefers the JSON API and falls back to the HTML API of PEP 691, then to the
+// plain HTML pages of PEP 503 indexes that predate it.
+const simpleAcceptHeader = simpleJSONContentType + ", " + simpleHTMLContentType + ";q=0.2, text/html;q=0.01"
+
+// simpleAnchorPattern matches the anchors of an HTML simple index page and captures their href,
+// quoted either way.
+var simpleAnchorPattern = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// simpleFile is a file listed on the project page of a simple index.
+type simpleFile struct {
+	Filename string
+	// SHA256 is the lowercase hex digest reported by the index, or "" when it reports none
+	SHA256 string
+}
+
+// parseSimplePage parses a project page of the simple API, as JSON (PEP 691) or HTML (PEP 503)
+// depending on its content type. Indexes that label JSON with a generic content type are
+// recognized by the body.
+func parseSimplePage(body []byte, contentType string) ([]simpleFile, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case simpleJSONContentType, simpleJSONLatestContentType:
+		return parseSimpleJSON(body)
+	case simpleHTMLContentType, "text/html":
+		return parseSimpleHTML(body), nil
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return parseSimpleJSON(body)
+	}
+	return parseSimpleHTML(body), nil
+}
+
+// parseSimpleJSON parses a JSON project page (PEP 691). Pages of an unknown major API version
+// are rejected, as PEP 629 asks of clients.
+func parseSimpleJSON(body []byte) ([]simpleFile, error) {
+	var page struct {
+		Meta struct {
+			APIVersion string `json:"api-version"`
+		} `json:"meta"`
+		Files []struct {
+			Filename string            `json:"filename"`
+			Hashes   map[string]string `json:"hashes"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("failed to parse index page: %w", err)
+	}
+	if major, _, _ := strings.Cut(page.Meta.APIVersion, "."); major != "" && major != "1" {
+		return nil, fmt.Errorf("unsupported simple API version %s", page.Meta.APIVersion)
+	}
+	files := make([]simpleFile, 0, len(page.Files))
+	for _, f := range page.Files {
+		files = append(files, simpleFile{Filename: f.Filename, SHA256: strings.ToLower(f.Hashes["sha256"])})
+	}
+	return files, nil
+}
+
+// parseSimpleHTML parses an HTML project page (PEP 503). File names are taken from the link
+// path, and digests from a #sha256= fragment.
+func parseSimpleHTML(body []byte) []simpleFile {
+	var files []simpleFile
+	for _, m := range simpleAnchorPattern.FindAllSubmatch(body, -1) {
+		href := string(m[1]) + string(m[2])
+		link, err := url.Parse(html.UnescapeString(href))
+		if err != nil || link.Path == "" {
+			continue
+		}
+		file := simpleFile{Filename: path.Base(link.Path)}
+		if algo, value, ok := strings.Cut(link.Fragment, "="); ok && algo == "sha256" {
+			file.SHA256 = strings.ToLower(value)
+		}
+		files = append(files, file)
+	}
+	return files
+}
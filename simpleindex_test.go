@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseSimplePage(t *testing.T) {
+	jsonPage := `{"meta": {"api-version": "1.1"}, "files": [{"filename": "core-1.0.tar.gz", "hashes": {"sha256": "ABC123"}}]}`
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+		wantErr     bool
+	}{
+		{"json", simpleJSONContentType, jsonPage, "[{core-1.0.tar.gz abc123}]", false},
+		{"json latest", simpleJSONLatestContentType + "; charset=utf-8", jsonPage, "[{core-1.0.tar.gz abc123}]", false},
+		{"json mislabelled", "application/json", jsonPage, "[{core-1.0.tar.gz abc123}]", false},
+		{"json unknown major version", simpleJSONContentType, `{"meta": {"api-version": "2.0"}, "files": []}`, "", true},
+		{"json malformed", simpleJSONContentType, `{"files": [`, "", true},
+		{"html", "text/html", `<a href="https://files.example.com/core-1.0.tar.gz#sha256=ABC123">core-1.0.tar.gz</a>`, "[{core-1.0.tar.gz abc123}]", false},
+		{"html v1", simpleHTMLContentType, `<a data-requires-python="&gt;=3.8" href='../../core-1.0-py3-none-any.whl#md5=ff'>x</a>`, "[{core-1.0-py3-none-any.whl }]", false},
+		{"html escaped", "", `<A HREF="core-1.0.tar.gz?sig=1&amp;exp=2#sha256=abc">x</A>`, "[{core-1.0.tar.gz abc}]", false},
+		{"html without links", "text/html", `<html><body><h1>Links for core</h1></body></html>`, "[]", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := parseSimplePage([]byte(tt.body), tt.contentType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSimplePage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := fmt.Sprint(files); !tt.wantErr && got != tt.want {
+				t.Errorf("parseSimplePage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexHasFilesJSONOnly(t *testing.T) {
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", simpleJSONContentType)
+		_, _ = w.Write([]byte(`{"meta": {"api-version": "1.0"}, "name": "core", "files": [{"filename": "core-1.0.tar.gz", "url": "https://files.example.com/core-1.0.tar.gz", "hashes": {}}]}`))
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	ok, err := p.indexHasFiles(context.Background(), server.URL+"/simple/", "core", []string{"core-1.0.tar.gz"})
+	if err != nil || !ok {
+		t.Errorf("expected the file on a JSON-only index, got %v, %v", ok, err)
+	}
+	if !strings.HasPrefix(accept, simpleJSONContentType) {
+		t.Errorf("expected the JSON API to be preferred, got Accept %q", accept)
+	}
+	// A file name contained in a listed one is not listed itself
+	ok, err = p.indexHasFiles(context.Background(), server.URL+"/simple/", "core", []string{"re-1.0.tar.gz"})
+	if err != nil || ok {
+		t.Errorf("expected a partial file name not to match, got %v, %v", ok, err)
+	}
+}
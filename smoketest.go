@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// defaultSmokeTestPython creates the throwaway virtualenv of the smoke test.
+const defaultSmokeTestPython = "python3"
+
+// Steps of the smoke test, reported with a failure.
+const (
+	smokeStepAvailability = "availability"
+	smokeStepVenv         = "venv"
+	smokeStepInstall      = "install"
+	smokeStepImport       = "import"
+)
+
+// smokeTestReport is the result of installing the published release, reported in outputs.
+type smokeTestReport struct {
+	// Status is passed or failed
+	Status   string   `json:"status"`
+	Project  string   `json:"project"`
+	Version  string   `json:"version"`
+	IndexURL string   `json:"index_url"`
+	Imports  []string `json:"imports,omitempty"`
+	// Step is the step that failed: availability, venv, install or import
+	Step       string `json:"step,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Output     string `json:"output,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// runsSmokeTest reports whether the published release is installed after the upload.
+func runsSmokeTest(cfg Config) bool {
+	return cfg.SmokeTest != "" && cfg.SmokeTest != checkOff
+}
+
+// venvPython returns the interpreter of a virtualenv.
+func venvPython(dir string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(dir, "Scripts", "python.exe")
+	}
+	return filepath.Join(dir, "bin", "python")
+}
+
+// smokeTestIndexURL returns the index pip installs from. A private index on the host of the
+// repository gets the upload credentials, as pip would otherwise prompt for them.
+func smokeTestIndexURL(cfg Config) string {
+	u, err := url.Parse(cfg.IndexURL)
+	if err != nil || isPyPIRepository(cfg.Repository) || !usesBasicAuth(cfg) || cfg.Username == "" {
+		return cfg.IndexURL
+	}
+	if repo, err := url.Parse(cfg.Repository); err != nil || !strings.EqualFold(repo.Host, u.Host) {
+		return cfg.IndexURL
+	}
+	u.User = url.UserPassword(cfg.Username, cfg.Password)
+	return u.String()
+}
+
+// runSmokeTest installs the published version into a throwaway virtualenv from the index and
+// imports its top-level modules, the way the first consumer of the release will. availErr is
+// the result of waiting for the files to be listed on the index.
+func (p *PyPIPlugin) runSmokeTest(ctx context.Context, cfg Config, files []string, availErr error) *smokeTestReport {
+	start := time.Now()
+	project, version := distProjectVersion(files)
+	report := &smokeTestReport{Status: "passed", Project: project, Version: version, IndexURL: cfg.IndexURL}
+	fail := func(step string, err error, output []byte) *smokeTestReport {
+		report.Status, report.Step, report.Error = "failed", step, err.Error()
+		report.Output, _ = truncateOutput(strings.TrimSpace(string(output)), cfg.MaxOutputBytes)
+		report.DurationMs = time.Since(start).Milliseconds()
+		return report
+	}
+	if project == "" {
+		return fail(smokeStepInstall, fmt.Errorf("no distribution has readable metadata naming the project"), nil)
+	}
+	if availErr != nil {
+		return fail(smokeStepAvailability, availErr, nil)
+	}
+	if cfg.SmokeTestImport {
+		for _, f := range files {
+			if strings.HasSuffix(f, ".whl") {
+				if names, err := wheelImportNames(f); err == nil {
+					report.Imports = names
+					break
+				}
+			}
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "relicta-pypi-smoke-")
+	if err != nil {
+		return fail(smokeStepVenv, err, nil)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	executor := p.getExecutor()
+	if output, err := executor.Run(ctx, cfg.SmokeTestPython, "-m", "venv", dir); err != nil {
+		return fail(smokeStepVenv, err, output)
+	}
+
+	// The index URL may carry credentials, so it is passed in the environment
+	python := venvPython(dir)
+	env := []string{"PIP_INDEX_URL=" + smokeTestIndexURL(cfg), "PIP_DISABLE_PIP_VERSION_CHECK=1", "PIP_NO_INPUT=1"}
+	if output, err := executor.RunWithEnv(ctx, env, python, "-m", "pip", "install", "--no-cache-dir", project+"=="+version); err != nil {
+		return fail(smokeStepInstall, err, output)
+	}
+	if len(report.Imports) > 0 {
+		if output, err := executor.Run(ctx, python, "-c", "import "+strings.Join(report.Imports, ", ")); err != nil {
+			return fail(smokeStepImport, err, output)
+		}
+	}
+	report.DurationMs = time.Since(start).Milliseconds()
+	return report
+}
+
+// validateSmokeTestConfig validates the smoke_test options.
+func validateSmokeTestConfig(cfg Config) error {
+	if !runsSmokeTest(cfg) {
+		return nil
+	}
+	if !containsString(checkModes, cfg.SmokeTest) {
+		return fmt.Errorf("smoke_test must be one of: %s", strings.Join(checkModes, ", "))
+	}
+	if cfg.IndexURL == "" {
+		return fmt.Errorf("smoke_test needs index_url for repositories other than PyPI and TestPyPI")
+	}
+	if cfg.SmokeTestPython == "" {
+		return fmt.Errorf("smoke_test_python must not be empty")
+	}
+	if cfg.AvailabilityTimeout <= 0 {
+		return fmt.Errorf("availability_timeout must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// SPIFFE Workload API constants.
+const (
+	// spiffeSocketEnv is the standard variable naming the Workload API endpoint
+	spiffeSocketEnv = "SPIFFE_ENDPOINT_SOCKET"
+	// fetchX509SVIDMethod streams the X.509 SVIDs of the calling workload
+	fetchX509SVIDMethod = "/SpiffeWorkloadAPI/FetchX509SVID"
+	// spiffeRefreshMargin is how long before expiry an SVID is fetched again
+	spiffeRefreshMargin = 5 * time.Minute
+	// spiffeFetchTimeout bounds a single Workload API call
+	spiffeFetchTimeout = 30 * time.Second
+)
+
+// x509SVID is an X.509 SPIFFE Verifiable Identity Document issued to this workload.
+type x509SVID struct {
+	ID          string
+	Certificate tls.Certificate
+	// Bundle holds the CA certificates of the workload's trust domain, which issue the SVIDs of
+	// the servers in it
+	Bundle []*x509.Certificate
+}
+
+// rawCodec passes protobuf messages through as bytes, so the Workload API can be called
+// without generated stubs.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+// workloadAPITarget converts a SPIFFE endpoint address (unix:///path or tcp://host:port)
+// into a gRPC target.
+func workloadAPITarget(socket string) (string, error) {
+	switch {
+	case strings.HasPrefix(socket, "unix:"):
+		return socket, nil
+	case strings.HasPrefix(socket, "tcp://"):
+		return "passthrough:///" + strings.TrimPrefix(socket, "tcp://"), nil
+	default:
+		return "", fmt.Errorf("SPIFFE endpoint %q must start with unix: or tcp://", socket)
+	}
+}
+
+// fetchX509SVID fetches the workload's X.509 SVIDs from the Workload API and returns the one
+// with the given SPIFFE ID, or the default (first) SVID when id is empty.
+func fetchX509SVID(ctx context.Context, socket, id string) (*x509SVID, error) {
+	target, err := workloadAPITarget(socket)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the SPIFFE Workload API: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithTimeout(ctx, spiffeFetchTimeout)
+	defer cancel()
+	// The Workload API rejects calls without this header to prevent SSRF
+	ctx = metadata.AppendToOutgoingContext(ctx, "workload.spiffe.io", "true")
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, fetchX509SVIDMethod, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch X.509 SVID: %w", err)
+	}
+	request := []byte{} // X509SVIDRequest has no fields
+	if err := stream.SendMsg(&request); err != nil {
+		return nil, fmt.Errorf("failed to fetch X.509 SVID: %w", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, fmt.Errorf("failed to fetch X.509 SVID: %w", err)
+	}
+	var response []byte
+	if err := stream.RecvMsg(&response); err != nil {
+		return nil, fmt.Errorf("failed to fetch X.509 SVID: %w", err)
+	}
+
+	svids, err := parseX509SVIDResponse(response)
+	if err != nil {
+		return nil, err
+	}
+	for _, svid := range svids {
+		if id == "" || svid.ID == id {
+			return svid, nil
+		}
+	}
+	if id != "" {
+		return nil, fmt.Errorf("workload has no SVID for %s", id)
+	}
+	return nil, errors.New("workload API returned no SVIDs")
+}
+
+// parseX509SVIDResponse decodes the SVIDs of an X509SVIDResponse message:
+//
+//	message X509SVIDResponse { repeated X509SVID svids = 1; ... }
+//	message X509SVID { string spiffe_id = 1; bytes x509_svid = 2; bytes x509_svid_key = 3; bytes bundle = 4; ... }
+func parseX509SVIDResponse(data []byte) ([]*x509SVID, error) {
+	var svids []*x509SVID
+	err := walkProtoBytes(data, func(num protowire.Number, value []byte) error {
+		if num != 1 {
+			return nil
+		}
+		var id string
+		var certDER, keyDER, bundleDER []byte
+		err := walkProtoBytes(value, func(num protowire.Number, value []byte) error {
+			switch num {
+			case 1:
+				id = string(value)
+			case 2:
+				certDER = value
+			case 3:
+				keyDER = value
+			case 4:
+				bundleDER = value
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		svid, err := newX509SVID(id, certDER, keyDER)
+		if err != nil {
+			return err
+		}
+		if len(bundleDER) > 0 {
+			if svid.Bundle, err = x509.ParseCertificates(bundleDER); err != nil {
+				return fmt.Errorf("invalid trust bundle for %s: %w", id, err)
+			}
+		}
+		svids = append(svids, svid)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid X.509 SVID response: %w", err)
+	}
+	return svids, nil
+}
+
+// walkProtoBytes calls fn for every length-delimited field of a protobuf message and skips
+// the others.
+func walkProtoBytes(data []byte, fn func(protowire.Number, []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+		value, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if err := fn(num, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newX509SVID builds a TLS certificate from the DER certificate chain and PKCS#8 key of an SVID.
+func newX509SVID(id string, certDER, keyDER []byte) (*x509SVID, error) {
+	certs, err := x509.ParseCertificates(certDER)
+	if err != nil || len(certs) == 0 {
+		return nil, fmt.Errorf("invalid certificate for %s: %v", id, err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyDER)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key for %s: %w", id, err)
+	}
+
+	cert := tls.Certificate{PrivateKey: key, Leaf: certs[0]}
+	for _, c := range certs {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	return &x509SVID{ID: id, Certificate: cert}, nil
+}
+
+// spiffeSource serves the workload's X.509 SVID as client certificate, fetching a new one
+// from the Workload API shortly before the current one expires, and verifies servers with the
+// trust bundle that comes with it.
+type spiffeSource struct {
+	socket string
+	id     string
+	// serverID is the SPIFFE ID the server must present, if set
+	serverID string
+	now      func() time.Time
+
+	mu   sync.Mutex
+	svid *x509SVID
+}
+
+// newSpiffeSource creates a client certificate source backed by the Workload API.
+func newSpiffeSource(cfg Config) *spiffeSource {
+	return &spiffeSource{socket: cfg.SpiffeEndpointSocket, id: cfg.SpiffeID, serverID: cfg.SpiffeServerID, now: time.Now}
+}
+
+// current returns a current SVID.
+func (s *spiffeSource) current() (*x509SVID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.svid != nil && s.now().Add(spiffeRefreshMargin).Before(s.svid.Certificate.Leaf.NotAfter) {
+		return s.svid, nil
+	}
+
+	svid, err := fetchX509SVID(context.Background(), s.socket, s.id)
+	if err != nil {
+		return nil, err
+	}
+	s.svid = svid
+	return s.svid, nil
+}
+
+// certificate returns the certificate of a current SVID.
+func (s *spiffeSource) certificate() (*tls.Certificate, error) {
+	svid, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return &svid.Certificate, nil
+}
+
+// configureServerTrust adds the trust bundle to the roots of config. Servers are verified by
+// host name as usual, so the bundle is read when the client is built; should the Workload API
+// fail, so does the handshake, when the server asks for the client certificate. With
+// spiffe_server_id, the server is verified by SPIFFE ID instead.
+func (s *spiffeSource) configureServerTrust(config *tls.Config) {
+	if s.serverID == "" {
+		if svid, err := s.current(); err == nil && len(svid.Bundle) > 0 {
+			config.RootCAs = trustPool(config.RootCAs, svid.Bundle)
+		}
+		return
+	}
+	// The default verification is replaced, not skipped: VerifyConnection checks the chain
+	config.InsecureSkipVerify = true // #nosec G402 -- verified by VerifyConnection
+	config.VerifyConnection = s.verifyServerID
+}
+
+// verifyServerID verifies that the server presents an SVID for spiffe_server_id, issued by the
+// current trust bundle. SVIDs name workloads by URI, so the host name is not checked.
+func (s *spiffeSource) verifyServerID(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	svid, err := s.current()
+	if err != nil {
+		return err
+	}
+	if len(svid.Bundle) == 0 {
+		return fmt.Errorf("the Workload API returned no trust bundle to verify %s with", s.serverID)
+	}
+	leaf := cs.PeerCertificates[0]
+	opts := x509.VerifyOptions{Roots: trustPool(x509.NewCertPool(), svid.Bundle), Intermediates: x509.NewCertPool()}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return fmt.Errorf("server certificate is not issued by the SPIFFE trust bundle: %w", err)
+	}
+	presented := "no SPIFFE ID"
+	for i, uri := range leaf.URIs {
+		if uri.String() == s.serverID {
+			return nil
+		}
+		if i == 0 {
+			presented = uri.String()
+		}
+	}
+	return fmt.Errorf("server presented %s, expected SPIFFE ID %s", presented, s.serverID)
+}
+
+// trustPool returns a copy of base, or of the system roots if nil, with certs added.
+func trustPool(base *x509.CertPool, certs []*x509.Certificate) *x509.CertPool {
+	var pool *x509.CertPool
+	if base != nil {
+		pool = base.Clone()
+	} else if system, err := x509.SystemCertPool(); err == nil {
+		pool = system
+	} else {
+		pool = x509.NewCertPool()
+	}
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return pool
+}
+
+// defaultSpiffeSocket returns the Workload API endpoint from the environment.
+func defaultSpiffeSocket() string {
+	return os.Getenv(spiffeSocketEnv)
+}
+
+// validateSpiffeConfig validates the SPIFFE workload identity options.
+func validateSpiffeConfig(cfg Config) error {
+	if !cfg.SpiffeWorkloadAPI {
+		if cfg.SpiffeServerID != "" {
+			return fmt.Errorf("spiffe_server_id requires spiffe_workload_api")
+		}
+		return nil
+	}
+	if cfg.ClientCert != "" {
+		return fmt.Errorf("spiffe_workload_api and client_cert are mutually exclusive")
+	}
+	if cfg.SpiffeEndpointSocket == "" {
+		return fmt.Errorf("spiffe_endpoint_socket is required when %s is not set", spiffeSocketEnv)
+	}
+	if _, err := workloadAPITarget(cfg.SpiffeEndpointSocket); err != nil {
+		return err
+	}
+	if cfg.SpiffeID != "" && !strings.HasPrefix(cfg.SpiffeID, "spiffe://") {
+		return fmt.Errorf("spiffe_id must be a spiffe:// URI")
+	}
+	if cfg.SpiffeServerID != "" && !strings.HasPrefix(cfg.SpiffeServerID, "spiffe://") {
+		return fmt.Errorf("spiffe_server_id must be a spiffe:// URI")
+	}
+	return nil
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// encodeTestSVID returns an X509SVID message for a new self-signed certificate with the
+// given SPIFFE ID and the DER trust bundle, if any.
+func encodeTestSVID(t *testing.T, id string, bundle []byte) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	uri, _ := url.Parse(id)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		URIs:         []*url.URL{uri},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	var svid []byte
+	svid = protowire.AppendTag(svid, 1, protowire.BytesType)
+	svid = protowire.AppendString(svid, id)
+	svid = protowire.AppendTag(svid, 2, protowire.BytesType)
+	svid = protowire.AppendBytes(svid, certDER)
+	svid = protowire.AppendTag(svid, 3, protowire.BytesType)
+	svid = protowire.AppendBytes(svid, keyDER)
+	if bundle != nil {
+		svid = protowire.AppendTag(svid, 4, protowire.BytesType)
+		svid = protowire.AppendBytes(svid, bundle)
+	}
+	return svid
+}
+
+// startTestWorkloadAPI serves a Workload API returning SVIDs for ids on a unix socket and
+// returns its address and a counter of FetchX509SVID calls.
+func startTestWorkloadAPI(t *testing.T, ids ...string) (string, *int) {
+	t.Helper()
+	return startTestWorkloadAPIWithBundle(t, nil, ids...)
+}
+
+// startTestWorkloadAPIWithBundle is startTestWorkloadAPI returning the DER trust bundle with
+// every SVID.
+func startTestWorkloadAPIWithBundle(t *testing.T, bundle []byte, ids ...string) (string, *int) {
+	t.Helper()
+	var response []byte
+	// Fields other than svids must be skipped
+	response = protowire.AppendTag(response, 4, protowire.VarintType)
+	response = protowire.AppendVarint(response, 1)
+	for _, id := range ids {
+		response = protowire.AppendTag(response, 1, protowire.BytesType)
+		response = protowire.AppendBytes(response, encodeTestSVID(t, id, bundle))
+	}
+
+	// Unix socket paths are limited in length, so avoid the long test temp dir
+	dir, err := os.MkdirTemp("", "spiffe")
+	if err != nil {
+		t.Fatalf("failed to create socket dir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "agent.sock")
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	calls := 0
+	server := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		if method != fetchX509SVIDMethod {
+			return errors.New("unexpected method " + method)
+		}
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		if v := md.Get("workload.spiffe.io"); len(v) != 1 || v[0] != "true" {
+			return errors.New("missing security header")
+		}
+		var request []byte
+		if err := stream.RecvMsg(&request); err != nil {
+			return err
+		}
+		calls++
+		return stream.SendMsg(&response)
+	}))
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+	return "unix://" + socket, &calls
+}
+
+func TestFetchX509SVID(t *testing.T) {
+	socket, _ := startTestWorkloadAPI(t, "spiffe://example.org/ci", "spiffe://example.org/publisher")
+
+	svid, err := fetchX509SVID(context.Background(), socket, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if svid.ID != "spiffe://example.org/ci" {
+		t.Errorf("expected the default SVID, got %s", svid.ID)
+	}
+
+	svid, err = fetchX509SVID(context.Background(), socket, "spiffe://example.org/publisher")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if svid.ID != "spiffe://example.org/publisher" || svid.Certificate.Leaf.URIs[0].String() != svid.ID {
+		t.Errorf("unexpected SVID %s", svid.ID)
+	}
+
+	if _, err := fetchX509SVID(context.Background(), socket, "spiffe://example.org/other"); err == nil {
+		t.Error("expected an error for an unknown SPIFFE ID")
+	}
+}
+
+func TestSpiffeSourceRefreshesBeforeExpiry(t *testing.T) {
+	socket, calls := startTestWorkloadAPI(t, "spiffe://example.org/ci")
+	now := time.Now()
+	s := newSpiffeSource(Config{SpiffeEndpointSocket: socket})
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.certificate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if *calls != 1 {
+		t.Errorf("expected the SVID to be cached, got %d fetches", *calls)
+	}
+
+	now = now.Add(time.Hour - spiffeRefreshMargin)
+	if _, err := s.certificate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("expected a new SVID near expiry, got %d fetches", *calls)
+	}
+}
+
+func TestNativeUploadSpiffe(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	socket, _ := startTestWorkloadAPI(t, "spiffe://example.org/ci", "spiffe://example.org/publisher")
+
+	var identity string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity = r.TLS.PeerCertificates[0].URIs[0].String()
+		if r.Header.Get("Authorization") != "" {
+			t.Error("no credentials must be sent with a workload identity")
+		}
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	cfg := p.parseConfig(map[string]any{
+		"repository":             server.URL,
+		"spiffe_workload_api":    true,
+		"spiffe_endpoint_socket": socket,
+		"spiffe_id":              "spiffe://example.org/publisher",
+	})
+	if err := p.validateConfig(cfg); err != nil {
+		t.Fatalf("workload identity must replace credentials: %v", err)
+	}
+	if !usesNativeAuth(cfg) {
+		t.Fatal("expected the native uploader without credentials")
+	}
+	if _, err := p.runNativeUploads(context.Background(), cfg, newPublishSession(cfg), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity != "spiffe://example.org/publisher" {
+		t.Errorf("expected the selected SVID to be presented, got %q", identity)
+	}
+}
+
+// newTestTrustDomainCA returns a CA of a trust domain and a TLS server certificate it issued
+// with the given SPIFFE ID and IP address.
+func newTestTrustDomainCA(t *testing.T, serverID string, ip net.IP) (*x509.Certificate, tls.Certificate) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, _ := url.Parse(serverID)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		URIs:         []*url.URL{uri},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestNativeUploadSpiffeTrustBundle(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+
+	for _, tc := range []struct {
+		name     string
+		serverID string
+		ip       net.IP
+		wantErr  string
+	}{
+		{name: "server id", serverID: "spiffe://example.org/pypi"},
+		{name: "host name", ip: net.IPv4(127, 0, 0, 1)},
+		{name: "other server id", serverID: "spiffe://example.org/other", wantErr: "server presented spiffe://example.org/pypi, expected SPIFFE ID spiffe://example.org/other"},
+		{name: "host name mismatch", wantErr: "127.0.0.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SVIDs of servers carry a URI SAN and usually no DNS name or IP address
+			ca, serverCert := newTestTrustDomainCA(t, "spiffe://example.org/pypi", tc.ip)
+			socket, _ := startTestWorkloadAPIWithBundle(t, ca.Raw, "spiffe://example.org/publisher")
+
+			uploads := 0
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				uploads++
+				_, _ = io.Copy(io.Discard, r.Body)
+			}))
+			server.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}, ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+			server.StartTLS()
+			defer server.Close()
+
+			// The server is trusted through the bundle alone, not the system roots
+			p := &PyPIPlugin{}
+			cfg := p.parseConfig(map[string]any{
+				"repository":             server.URL,
+				"spiffe_workload_api":    true,
+				"spiffe_endpoint_socket": socket,
+				"spiffe_server_id":       tc.serverID,
+			})
+			if err := p.validateConfig(cfg); err != nil {
+				t.Fatal(err)
+			}
+			_, err := p.runNativeUploads(context.Background(), cfg, newPublishSession(cfg), nil)
+			if tc.wantErr == "" {
+				if err != nil || uploads != 1 {
+					t.Errorf("expected the upload to succeed, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) || uploads != 0 {
+				t.Errorf("expected the server to be rejected with %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpiffeConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"disabled", Config{}, false},
+		{"unix socket", Config{SpiffeWorkloadAPI: true, SpiffeEndpointSocket: "unix:///run/spire/agent.sock"}, false},
+		{"tcp endpoint", Config{SpiffeWorkloadAPI: true, SpiffeEndpointSocket: "tcp://127.0.0.1:8081", SpiffeID: "spiffe://example.org/ci"}, false},
+		{"missing socket", Config{SpiffeWorkloadAPI: true}, true},
+		{"bad socket", Config{SpiffeWorkloadAPI: true, SpiffeEndpointSocket: "/run/spire/agent.sock"}, true},
+		{"bad id", Config{SpiffeWorkloadAPI: true, SpiffeEndpointSocket: "unix:///run/spire/agent.sock", SpiffeID: "example.org/ci"}, true},
+		{"with client cert", Config{SpiffeWorkloadAPI: true, SpiffeEndpointSocket: "unix:///run/spire/agent.sock", ClientCert: "tls.crt"}, true},
+		{"server id", Config{SpiffeWorkloadAPI: true, SpiffeEndpointSocket: "unix:///run/spire/agent.sock", SpiffeServerID: "spiffe://example.org/pypi"}, false},
+		{"bad server id", Config{SpiffeWorkloadAPI: true, SpiffeEndpointSocket: "unix:///run/spire/agent.sock", SpiffeServerID: "pypi"}, true},
+		{"server id without workload api", Config{SpiffeServerID: "spiffe://example.org/pypi"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSpiffeConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateSpiffeConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Index status page defaults.
+const (
+	// defaultStatusURL is the Statuspage summary of status.python.org, which covers PyPI
+	defaultStatusURL = "https://status.python.org/api/v2/summary.json"
+	// defaultStatusPollInterval is the delay between status polls while waiting for an incident
+	defaultStatusPollInterval = 30 * time.Second
+	// maxStatusPageSize caps the status summary read from the status page
+	maxStatusPageSize = 1 << 20
+)
+
+// statusIncident is an unresolved incident or maintenance reported by the status page.
+type statusIncident struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Impact string `json:"impact"`
+	Link   string `json:"link,omitempty"`
+}
+
+// statusSummary is the part of a Statuspage summary.json the check reads.
+type statusSummary struct {
+	Status struct {
+		Indicator   string `json:"indicator"`
+		Description string `json:"description"`
+	} `json:"status"`
+	Incidents []struct {
+		Name      string `json:"name"`
+		Status    string `json:"status"`
+		Impact    string `json:"impact"`
+		Shortlink string `json:"shortlink"`
+	} `json:"incidents"`
+	ScheduledMaintenances []struct {
+		Name      string `json:"name"`
+		Status    string `json:"status"`
+		Shortlink string `json:"shortlink"`
+	} `json:"scheduled_maintenances"`
+}
+
+// fetchIndexStatus returns the incidents in progress according to the status page. A degraded
+// overall indicator without a listed incident is reported as an incident of its own.
+func (p *PyPIPlugin) fetchIndexStatus(ctx context.Context, statusURL string) ([]statusIncident, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create status request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("status request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status request failed: %s", resp.Status)
+	}
+
+	var summary statusSummary
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxStatusPageSize)).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("invalid status page response: %w", err)
+	}
+
+	var incidents []statusIncident
+	for _, i := range summary.Incidents {
+		if i.Status == "resolved" || i.Status == "postmortem" || i.Impact == "none" {
+			continue
+		}
+		incidents = append(incidents, statusIncident{Name: i.Name, Status: i.Status, Impact: i.Impact, Link: i.Shortlink})
+	}
+	for _, m := range summary.ScheduledMaintenances {
+		if m.Status == "in_progress" || m.Status == "verifying" {
+			incidents = append(incidents, statusIncident{Name: m.Name, Status: m.Status, Impact: "maintenance", Link: m.Shortlink})
+		}
+	}
+	if len(incidents) == 0 && (summary.Status.Indicator == "major" || summary.Status.Indicator == "critical") {
+		incidents = append(incidents, statusIncident{Name: summary.Status.Description, Status: "degraded", Impact: summary.Status.Indicator})
+	}
+	return incidents, nil
+}
+
+// statusIncidentMessage describes the incidents for the hook response.
+func statusIncidentMessage(cfg Config, incidents []statusIncident) string {
+	descriptions := make([]string, len(incidents))
+	for i, incident := range incidents {
+		descriptions[i] = fmt.Sprintf("%s (%s)", incident.Name, incident.Status)
+		if incident.Link != "" {
+			descriptions[i] += " " + incident.Link
+		}
+	}
+	subject := "PyPI"
+	if cfg.StatusURL != defaultStatusURL {
+		subject = "Index"
+	}
+	return fmt.Sprintf("%s incident in progress: %s", subject, strings.Join(descriptions, "; "))
+}
+
+// preflightStatus checks the index status page. An incident blocks the publish in fail mode
+// unless status_wait lets the upload wait for it to be resolved. An unreachable status page
+// never blocks, as it is often affected by the same outage.
+func (p *PyPIPlugin) preflightStatus(ctx context.Context, cfg Config, result *preflightResult) *plugin.ExecuteResponse {
+	incidents, err := p.fetchIndexStatus(ctx, cfg.StatusURL)
+	if err != nil {
+		result.warn("status check skipped: %v", err)
+		return nil
+	}
+	result.outputs["index_incidents"] = nonNilIncidents(incidents)
+	if len(incidents) == 0 {
+		return nil
+	}
+
+	msg := statusIncidentMessage(cfg, incidents)
+	if cfg.StatusWait > 0 {
+		result.statusIncidents = incidents
+		result.warn("%s; waiting up to %s for it to be resolved before uploading", msg, cfg.StatusWait)
+		return nil
+	}
+	if cfg.StatusCheck == checkFail {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   msg + "; aborting the publish to avoid failures during a known outage",
+			Outputs: map[string]any{"index_incidents": incidents},
+		}
+	}
+	result.warn("%s", msg)
+	return nil
+}
+
+// awaitIndexStatus polls the status page until the incidents found by the preflight check are
+// resolved or StatusWait elapses. It returns a failure response when the incidents persist in
+// fail mode; in warn mode the upload proceeds with a warning.
+func (p *PyPIPlugin) awaitIndexStatus(ctx context.Context, cfg Config, result *preflightResult) *plugin.ExecuteResponse {
+	deadline := time.Now().Add(cfg.StatusWait)
+	incidents := result.statusIncidents
+	for remaining := time.Until(deadline); remaining > 0; remaining = time.Until(deadline) {
+		timer := time.NewTimer(min(cfg.StatusPollInterval, remaining))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &plugin.ExecuteResponse{Success: false, Error: fmt.Sprintf("waiting for the index status: %v", ctx.Err())}
+		case <-timer.C:
+		}
+
+		current, err := p.fetchIndexStatus(ctx, cfg.StatusURL)
+		if err != nil {
+			continue
+		}
+		if len(current) == 0 {
+			result.outputs["index_incidents"] = []statusIncident{}
+			return nil
+		}
+		incidents = current
+	}
+
+	result.outputs["index_incidents"] = incidents
+	msg := fmt.Sprintf("%s after waiting %s", statusIncidentMessage(cfg, incidents), cfg.StatusWait)
+	if cfg.StatusCheck == checkFail {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   msg + "; aborting the publish to avoid failures during a known outage",
+			Outputs: map[string]any{"index_incidents": incidents},
+		}
+	}
+	result.warn("%s; uploading anyway", msg)
+	return nil
+}
+
+// nonNilIncidents returns incidents, or an empty list so outputs serialize as [].
+func nonNilIncidents(incidents []statusIncident) []statusIncident {
+	if incidents == nil {
+		return []statusIncident{}
+	}
+	return incidents
+}
+
+// validateStatusConfig validates the status page check options.
+func validateStatusConfig(cfg Config) error {
+	if cfg.StatusCheck == "" || cfg.StatusCheck == checkOff {
+		return nil
+	}
+	if !containsString(checkModes, cfg.StatusCheck) {
+		return fmt.Errorf("status_check must be one of: %s", strings.Join(checkModes, ", "))
+	}
+	if cfg.StatusURL != defaultStatusURL {
+		if err := validateRepositoryURL(cfg.StatusURL); err != nil {
+			return fmt.Errorf("invalid status_url: %w", err)
+		}
+	}
+	if cfg.StatusWait > 0 && cfg.StatusPollInterval <= 0 {
+		return fmt.Errorf("status_poll_interval must be positive when status_wait is set")
+	}
+	return nil
+}
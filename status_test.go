@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+const statusIncidentSummary = `{
+	"status": {"indicator": "major", "description": "Partial System Outage"},
+	"incidents": [
+		{"name": "Elevated upload errors", "status": "investigating", "impact": "major", "shortlink": "https://stspg.io/abc"},
+		{"name": "CDN purge delays", "status": "resolved", "impact": "minor"}
+	],
+	"scheduled_maintenances": []
+}`
+
+const statusOperationalSummary = `{
+	"status": {"indicator": "none", "description": "All Systems Operational"},
+	"incidents": [],
+	"scheduled_maintenances": [{"name": "Database upgrade", "status": "scheduled"}]
+}`
+
+func TestFetchIndexStatus(t *testing.T) {
+	summary := statusIncidentSummary
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(summary))
+	}))
+	defer server.Close()
+	p := &PyPIPlugin{httpClient: server.Client()}
+
+	incidents, err := p.fetchIndexStatus(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(incidents) != 1 || incidents[0] != (statusIncident{Name: "Elevated upload errors", Status: "investigating", Impact: "major", Link: "https://stspg.io/abc"}) {
+		t.Errorf("unexpected incidents %+v", incidents)
+	}
+
+	summary = statusOperationalSummary
+	if incidents, err := p.fetchIndexStatus(context.Background(), server.URL); err != nil || len(incidents) != 0 {
+		t.Errorf("expected no incidents, got %+v, %v", incidents, err)
+	}
+}
+
+func TestExecuteStatusCheck(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The incident is resolved after the first poll
+		if polls.Add(1) == 1 {
+			_, _ = w.Write([]byte(statusIncidentSummary))
+			return
+		}
+		_, _ = w.Write([]byte(statusOperationalSummary))
+	}))
+	defer server.Close()
+
+	var uploads int
+	p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			uploads++
+			return []byte("Uploading mypkg-1.0.0.tar.gz"), nil
+		},
+	}}
+	config := map[string]any{
+		"username":     "__token__",
+		"password":     "pypi-token",
+		"repository":   "http://localhost:8080/",
+		"status_check": "fail",
+		"status_url":   server.URL,
+	}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "Index incident in progress: Elevated upload errors (investigating)") || uploads != 0 {
+		t.Errorf("expected the incident to abort the publish, got success=%v error=%q", resp.Success, resp.Error)
+	}
+
+	polls.Store(0)
+	config["status_wait"] = "5s"
+	config["status_poll_interval"] = "10ms"
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || !resp.Success || uploads != 1 {
+		t.Fatalf("expected the upload after the incident was resolved, got %v %+v", err, resp)
+	}
+	if incidents, _ := resp.Outputs["index_incidents"].([]statusIncident); len(incidents) != 0 {
+		t.Errorf("expected no remaining incidents, got %v", resp.Outputs["index_incidents"])
+	}
+}
+
+func TestPreflightStatusUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	p := &PyPIPlugin{httpClient: server.Client()}
+	result := &preflightResult{outputs: map[string]any{}}
+	if blocked := p.preflightStatus(context.Background(), Config{StatusCheck: checkFail, StatusURL: server.URL}, result); blocked != nil {
+		t.Errorf("expected an unreachable status page not to block, got %+v", blocked)
+	}
+	if len(result.warnings) != 1 || !strings.Contains(result.warnings[0], "status check skipped") {
+		t.Errorf("unexpected warnings %q", result.warnings)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxStreamLineBytes bounds a forwarded line; longer output without a line break, such as a
+// progress bar, is forwarded in pieces.
+const maxStreamLineBytes = 64 << 10
+
+// StreamingCommandExecutor is a CommandExecutor that can hand out the output of a command
+// while it runs, keeping only a bounded capture in memory.
+type StreamingCommandExecutor interface {
+	CommandExecutor
+	// RunStreaming runs the command like RunWithEnv, passing each line of the combined stdout
+	// and stderr to onLine (when not nil) as it is written. The returned output keeps its head
+	// and tail within limit bytes, as truncateOutput does; a limit of zero keeps it whole.
+	RunStreaming(ctx context.Context, env []string, onLine func(line string), limit int, name string, args ...string) ([]byte, error)
+}
+
+// runStreaming runs the command with the streaming variant of executor. Executors without one
+// run the command whole, after which its lines are passed to onLine and its output truncated.
+func runStreaming(ctx context.Context, executor CommandExecutor, env []string, onLine func(string), limit int, name string, args ...string) ([]byte, error) {
+	if streaming, ok := executor.(StreamingCommandExecutor); ok {
+		return streaming.RunStreaming(ctx, env, onLine, limit, name, args...)
+	}
+	out, err := executor.RunWithEnv(ctx, env, name, args...)
+	if onLine != nil && len(out) > 0 {
+		lines := newLineWriter(onLine)
+		_, _ = lines.Write(out)
+		lines.flush()
+	}
+	truncated, _ := truncateOutput(string(out), limit)
+	return []byte(truncated), err
+}
+
+// RunStreaming implements StreamingCommandExecutor.
+func (e *RealCommandExecutor) RunStreaming(ctx context.Context, env []string, onLine func(string), limit int, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	configureCancellation(cmd)
+
+	capture := newOutputCapture(limit)
+	var lines *lineWriter
+	var w io.Writer = capture
+	if onLine != nil {
+		lines = newLineWriter(onLine)
+		w = io.MultiWriter(capture, lines)
+	}
+	// A single writer serializes the writes of stdout and stderr, as CombinedOutput does
+	out := &syncWriter{w: w}
+	cmd.Stdout, cmd.Stderr = out, out
+	err := cmd.Run()
+	if lines != nil {
+		lines.flush()
+	}
+	return []byte(capture.String()), err
+}
+
+// RunStreaming checks the circuit like RunWithEnv.
+func (e *breakerExecutor) RunStreaming(ctx context.Context, env []string, onLine func(string), limit int, name string, args ...string) ([]byte, error) {
+	if err := e.breaker.allow(e.repository); err != nil {
+		return nil, err
+	}
+	out, err := runStreaming(ctx, e.inner, env, onLine, limit, name, args...)
+	e.breaker.record(e.repository, string(out), err)
+	return out, err
+}
+
+// RunStreaming logs the command like RunWithEnv, with the captured output.
+func (e *loggingExecutor) RunStreaming(ctx context.Context, env []string, onLine func(string), limit int, name string, args ...string) ([]byte, error) {
+	return e.logged(env, name, args, func() ([]byte, error) {
+		return runStreaming(ctx, e.inner, env, onLine, limit, name, args...)
+	})
+}
+
+// syncWriter serializes writes to w.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write implements io.Writer.
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// lineWriter splits written output into lines at line feeds and carriage returns, which
+// progress bars use to redraw a line.
+type lineWriter struct {
+	onLine  func(string)
+	pending []byte
+}
+
+// newLineWriter creates a writer passing complete lines to onLine.
+func newLineWriter(onLine func(string)) *lineWriter {
+	return &lineWriter{onLine: onLine}
+}
+
+// Write implements io.Writer.
+func (l *lineWriter) Write(p []byte) (int, error) {
+	for _, c := range p {
+		if c == '\n' || c == '\r' {
+			l.emit()
+			continue
+		}
+		l.pending = append(l.pending, c)
+		if len(l.pending) >= maxStreamLineBytes {
+			l.emit()
+		}
+	}
+	return len(p), nil
+}
+
+// flush passes on the last line when the output does not end with a line break.
+func (l *lineWriter) flush() {
+	l.emit()
+}
+
+// emit passes the pending line to onLine, skipping empty lines.
+func (l *lineWriter) emit() {
+	if len(l.pending) > 0 {
+		l.onLine(string(l.pending))
+	}
+	l.pending = l.pending[:0]
+}
+
+// outputCapture keeps the head and tail of command output within a limit, so verbose
+// commands do not hold all of their output in memory. A limit of zero or less keeps it whole.
+type outputCapture struct {
+	limit int
+	head  []byte
+	// tail is a ring of the latest output, starting at next once it is full
+	tail  []byte
+	next  int
+	full  bool
+	total int
+	whole bytes.Buffer
+}
+
+// newOutputCapture creates a capture keeping about limit bytes.
+func newOutputCapture(limit int) *outputCapture {
+	if limit <= 0 {
+		return &outputCapture{}
+	}
+	return &outputCapture{limit: limit, tail: make([]byte, 0, limit-limit/2)}
+}
+
+// Write implements io.Writer.
+func (c *outputCapture) Write(p []byte) (int, error) {
+	c.total += len(p)
+	if c.limit <= 0 {
+		return c.whole.Write(p)
+	}
+	rest := p
+	if room := c.limit/2 - len(c.head); room > 0 {
+		n := min(room, len(rest))
+		c.head = append(c.head, rest[:n]...)
+		rest = rest[n:]
+	}
+	for len(rest) > 0 {
+		if !c.full {
+			n := min(cap(c.tail)-len(c.tail), len(rest))
+			c.tail = append(c.tail, rest[:n]...)
+			rest = rest[n:]
+			c.full = len(c.tail) == cap(c.tail)
+			continue
+		}
+		n := copy(c.tail[c.next:], rest)
+		c.next = (c.next + n) % len(c.tail)
+		rest = rest[n:]
+	}
+	return len(p), nil
+}
+
+// String returns the captured output, with the dropped middle replaced by the marker of
+// truncateOutput.
+func (c *outputCapture) String() string {
+	if c.limit <= 0 {
+		return c.whole.String()
+	}
+	tail := append(append([]byte{}, c.tail[c.next:]...), c.tail[:c.next]...)
+	if c.total <= c.limit {
+		return string(c.head) + string(tail)
+	}
+
+	// Do not split multi-byte characters
+	head := c.head
+	for end := len(head); end > 0; end-- {
+		if utf8.RuneStart(head[end-1]) {
+			if !utf8.FullRune(head[end-1:]) {
+				head = head[:end-1]
+			}
+			break
+		}
+	}
+	for len(tail) > 0 && !utf8.RuneStart(tail[0]) {
+		tail = tail[1:]
+	}
+	return fmt.Sprintf("%s\n... [%d bytes truncated] ...\n%s", head, c.total-len(head)-len(tail), tail)
+}
+
+// streamOutput returns the line callback forwarding the output of upload commands, with
+// credentials masked, when stream_output is enabled.
+func (p *PyPIPlugin) streamOutput(cfg Config, prefix string) func(string) {
+	if !cfg.StreamOutput {
+		return nil
+	}
+	w := p.getStreamWriter()
+	return func(line string) {
+		_, _ = fmt.Fprintln(w, prefix+strings.TrimRight(maskSecrets(cfg, line), " \t"))
+	}
+}
+
+// getStreamWriter returns where streamed command output is written, defaulting to stderr,
+// which the release tool records in its log.
+func (p *PyPIPlugin) getStreamWriter() io.Writer {
+	if p.streamWriter != nil {
+		return p.streamWriter
+	}
+	return os.Stderr
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestOutputCapture(t *testing.T) {
+	output := strings.Repeat("héllo wörld\n", 50)
+	for _, limit := range []int{0, 1, 7, 64, 101, len(output), len(output) + 1} {
+		for _, chunk := range []int{1, 5, 64, len(output)} {
+			capture := newOutputCapture(limit)
+			for rest := output; len(rest) > 0; {
+				n := min(chunk, len(rest))
+				_, _ = capture.Write([]byte(rest[:n]))
+				rest = rest[n:]
+			}
+			want, _ := truncateOutput(output, limit)
+			if got := capture.String(); got != want {
+				t.Errorf("limit %d, chunk %d: capture = %q, want %q", limit, chunk, got, want)
+			}
+		}
+	}
+
+	// Captured output within the limit is not truncated again
+	captured, _ := truncateOutput(output, 100)
+	if got, truncated := truncateOutput(captured, 100); got != captured || !truncated {
+		t.Errorf("expected captured output to be kept, got %q", got)
+	}
+}
+
+func TestRunStreamingFallback(t *testing.T) {
+	executor := &MockCommandExecutor{ReturnOut: []byte("Uploading a.whl\nUploading b.whl\nERROR    HTTPError: 400 Bad Request\n")}
+	var lines []string
+	out, err := runStreaming(context.Background(), executor, nil, func(line string) { lines = append(lines, line) }, 40, "twine", "upload")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 3 || lines[2] != "ERROR    HTTPError: 400 Bad Request" {
+		t.Errorf("expected the lines of the output, got %q", lines)
+	}
+	if want, _ := truncateOutput(string(executor.ReturnOut), 40); string(out) != want {
+		t.Errorf("expected the truncated output, got %q", out)
+	}
+}
+
+func TestExecuteStreamOutput(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	var streamed bytes.Buffer
+	p := &PyPIPlugin{
+		cmdExecutor:  &MockCommandExecutor{ReturnOut: []byte("Uploading distributions to http://localhost:8080/\nUploading mypkg-1.0.0.tar.gz\nsent with pypi-secret-token\n")},
+		streamWriter: &streamed,
+	}
+
+	for _, stream := range []bool{false, true} {
+		streamed.Reset()
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"username":      "__token__",
+				"password":      "pypi-secret-token",
+				"repository":    "http://localhost:8080/",
+				"stream_output": stream,
+			},
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("expected the upload to succeed, got %+v, %v", resp, err)
+		}
+		if !stream {
+			if streamed.Len() != 0 {
+				t.Errorf("expected no streamed output by default, got %q", streamed.String())
+			}
+			continue
+		}
+		want := "twine: Uploading distributions to http://localhost:8080/\ntwine: Uploading mypkg-1.0.0.tar.gz\ntwine: sent with ***\n"
+		if streamed.String() != want {
+			t.Errorf("streamed %q, want %q", streamed.String(), want)
+		}
+	}
+}
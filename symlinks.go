@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Values of the symlink_policy option.
+const (
+	// symlinkFollow uploads the targets of symlinked files under the names of the links
+	symlinkFollow = "follow"
+	// symlinkReject fails the publish when dist_path matches a symlink
+	symlinkReject = "reject"
+	// symlinkCopy copies the distributions into a temporary directory and uploads the copies
+	symlinkCopy = "copy"
+)
+
+// symlinkPolicies lists the accepted symlink_policy values.
+var symlinkPolicies = []string{symlinkFollow, symlinkReject, symlinkCopy}
+
+// distSymlink is a symlinked distribution file, reported in outputs.
+type distSymlink struct {
+	Path   string `json:"path"`
+	Target string `json:"target"`
+}
+
+// findDistSymlinks returns the files matching the dist path pattern that are symlinks, with
+// their resolved targets. A link whose target does not exist fails, whatever the policy:
+// expanding dist_path would otherwise leave the distribution out without a word.
+func findDistSymlinks(pattern string) ([]distSymlink, error) {
+	matches, err := filepath.Glob(filepath.FromSlash(pattern))
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern: %w", err)
+	}
+	var links []distSymlink
+	for _, m := range matches {
+		info, err := os.Lstat(m)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		target, err := filepath.EvalSymlinks(m)
+		if err != nil {
+			return nil, fmt.Errorf("%s is a symlink to a missing file: %w", toSlashPath(m), err)
+		}
+		if info, err := os.Stat(target); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		links = append(links, distSymlink{Path: toSlashPath(m), Target: toSlashPath(target)})
+	}
+	return links, nil
+}
+
+// rejectDistSymlinks returns the error of symlink_policy reject for links.
+func rejectDistSymlinks(links []distSymlink) error {
+	paths := make([]string, len(links))
+	for i, l := range links {
+		paths[i] = l.Path
+	}
+	return fmt.Errorf("symlink_policy is reject, and dist_path matches symlinks: %s", strings.Join(paths, ", "))
+}
+
+// copyDistFiles copies the distributions matching the dist path pattern into dir as regular
+// files, keeping the names of links rather than those of their targets. Signatures and
+// attestations next to a distribution are copied with it, so they are uploaded with the copy.
+func copyDistFiles(pattern, dir string) error {
+	files, err := expandDistGlob(pattern)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		for _, src := range []string{f, signatureFilePath(f), f + attestationSuffix} {
+			if src != f {
+				if _, err := os.Stat(src); err != nil {
+					continue
+				}
+			}
+			dst := filepath.Join(dir, filepath.Base(src))
+			if _, err := copyFileWithDigest(src, dst); err != nil {
+				return fmt.Errorf("failed to copy %s: %w", toSlashPath(src), err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateSymlinkPolicy validates the symlink_policy option.
+func validateSymlinkPolicy(cfg Config) error {
+	if !containsString(symlinkPolicies, cfg.SymlinkPolicy) {
+		return fmt.Errorf("symlink_policy must be one of: %s", strings.Join(symlinkPolicies, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// writeSymlinkedWheel writes a wheel into bazel-out and links it into dist, as Bazel does.
+func writeSymlinkedWheel(t *testing.T) string {
+	t.Helper()
+	writeDistFiles(t)
+	target := filepath.Join("bazel-out", "mypkg-1.0.0-py3-none-any.whl")
+	if err := os.MkdirAll("bazel-out", 0o750); err != nil {
+		t.Fatal(err)
+	}
+	writeTestWheel(t, target, map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	link := filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl")
+	if err := os.Symlink(filepath.Join("..", target), link); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+	return link
+}
+
+func TestExecuteSymlinkPolicyCopy(t *testing.T) {
+	writeSymlinkedWheel(t)
+
+	var uploaded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("invalid upload: %v", err)
+			return
+		}
+		_, header, err := r.FormFile("content")
+		if err != nil {
+			t.Errorf("upload has no content: %v", err)
+			return
+		}
+		uploaded = append(uploaded, header.Filename)
+	}))
+	defer server.Close()
+
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{}, httpClient: server.Client()}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":       "__token__",
+			"password":       "pypi-token",
+			"repository":     server.URL,
+			"upload_backend": "native",
+			"symlink_policy": "copy",
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+	if len(uploaded) != 1 || uploaded[0] != "mypkg-1.0.0-py3-none-any.whl" {
+		t.Errorf("unexpected uploads %v", uploaded)
+	}
+	links, _ := resp.Outputs["symlinks"].([]distSymlink)
+	if len(links) != 1 || links[0].Path != "dist/mypkg-1.0.0-py3-none-any.whl" || !strings.HasSuffix(links[0].Target, "bazel-out/mypkg-1.0.0-py3-none-any.whl") {
+		t.Errorf("unexpected symlinks output %v", resp.Outputs["symlinks"])
+	}
+}
+
+func TestExecuteSymlinkPolicyReject(t *testing.T) {
+	writeSymlinkedWheel(t)
+	executor := &MockCommandExecutor{}
+	p := &PyPIPlugin{cmdExecutor: executor}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":       "__token__",
+			"password":       "pypi-token",
+			"repository":     "http://localhost:8080/",
+			"symlink_policy": "reject",
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "dist/mypkg-1.0.0-py3-none-any.whl") {
+		t.Fatalf("expected the symlink to be rejected, got %v %+v", err, resp)
+	}
+	if len(executor.RunCalls) != 0 {
+		t.Errorf("expected no upload, got %+v", executor.RunCalls)
+	}
+}
+
+func TestFindDistSymlinksDangling(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	if err := os.Symlink("missing.whl", filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+	if _, err := findDistSymlinks("dist/*"); err == nil || !strings.Contains(err.Error(), "missing file") {
+		t.Errorf("expected the dangling symlink to fail, got %v", err)
+	}
+}
+
+func TestCopyDistFilesCompanions(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz", "mypkg-1.0.0.tar.gz.asc", "mypkg-1.0.0.tar.gz"+attestationSuffix)
+	dir := t.TempDir()
+	if err := copyDistFiles("dist/*.tar.gz", dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"mypkg-1.0.0.tar.gz", "mypkg-1.0.0.tar.gz.asc", "mypkg-1.0.0.tar.gz" + attestationSuffix} {
+		if info, err := os.Lstat(filepath.Join(dir, name)); err != nil || !info.Mode().IsRegular() {
+			t.Errorf("expected a regular copy of %s, got %v", name, err)
+		}
+	}
+}
+
+func TestSymlinkPolicyConfig(t *testing.T) {
+	p := &PyPIPlugin{}
+	if cfg := p.parseConfig(map[string]any{}); cfg.SymlinkPolicy != symlinkFollow {
+		t.Errorf("expected follow by default, got %q", cfg.SymlinkPolicy)
+	}
+	if err := validateSymlinkPolicy(Config{SymlinkPolicy: "resolve"}); err == nil {
+		t.Error("expected an unknown policy to be rejected")
+	}
+}
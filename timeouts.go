@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Upload timeout defaults.
+const (
+	defaultConnectTimeout = 30 * time.Second
+	defaultTLSTimeout     = 10 * time.Second
+	defaultIdleTimeout    = 2 * time.Minute
+)
+
+// positiveTimeoutKeys are the timeouts that cannot be disabled, so a stalled connection
+// never hangs an upload.
+var positiveTimeoutKeys = []string{"connect_timeout", "tls_timeout", "idle_timeout"}
+
+// clientWithTimeouts returns a copy of client applying the upload timeouts of cfg: dialing is
+// bounded by ConnectTimeout, the TLS handshake by TLSTimeout, each request by RequestTimeout,
+// and a connection is closed once no data moved in either direction for IdleTimeout. Zero
+// connect, TLS and idle timeouts keep the transport's behavior.
+func clientWithTimeouts(client *http.Client, cfg Config) *http.Client {
+	base, ok := client.Transport.(*http.Transport)
+	if !ok || base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+
+	transport.DialContext = uploadDialContext(cfg)
+	if cfg.TLSTimeout > 0 {
+		transport.TLSHandshakeTimeout = cfg.TLSTimeout
+	}
+
+	withTimeouts := *client
+	withTimeouts.Transport = transport
+	withTimeouts.Timeout = cfg.RequestTimeout
+	return &withTimeouts
+}
+
+// idleTimeoutConn fails reads and writes once the connection has been idle for timeout. Any
+// progress pushes the deadline of both directions, so the response wait during a long body
+// upload does not time out while bytes are still being sent.
+type idleTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *idleTimeoutConn) Write(b []byte) (int, error) {
+	if err := c.Conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
+
+// withTotalTimeout bounds the upload phase, including twine processes, by TotalTimeout.
+func withTotalTimeout(ctx context.Context, cfg Config) (context.Context, context.CancelFunc) {
+	if cfg.TotalTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, cfg.TotalTimeout)
+}
+
+// validateTimeoutConfig validates the relation between the upload timeouts.
+func validateTimeoutConfig(cfg Config) error {
+	if cfg.TotalTimeout > 0 && cfg.RequestTimeout > cfg.TotalTimeout {
+		return fmt.Errorf("request_timeout (%s) must not exceed total_timeout (%s)", cfg.RequestTimeout, cfg.TotalTimeout)
+	}
+	return nil
+}
+
+// validateUploadTimeout validates that upload_timeout leaves room for total_timeout.
+func validateUploadTimeout(cfg Config) error {
+	if cfg.UploadTimeout > 0 && cfg.TotalTimeout > cfg.UploadTimeout {
+		return fmt.Errorf("total_timeout (%s) must not exceed upload_timeout (%s)", cfg.TotalTimeout, cfg.UploadTimeout)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestNativeUploadIdleTimeout(t *testing.T) {
+	writeDistFiles(t)
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"), map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+
+	// The index accepts the upload but never answers
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p := &PyPIPlugin{httpClient: server.Client()}
+	cfg := p.parseConfig(map[string]any{
+		"repository":      server.URL,
+		"password":        "token",
+		"auth_scheme":     "bearer",
+		"request_timeout": "0",
+		"idle_timeout":    "200ms",
+	})
+	start := time.Now()
+	_, err := p.runNativeUploads(context.Background(), cfg, newPublishSession(cfg), nil)
+	if err == nil {
+		t.Fatal("expected the idle connection to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("idle timeout took %s", elapsed)
+	}
+}
+
+func TestExecuteTotalTimeout(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			// A hanging twine process is stopped by the total timeout
+			<-ctx.Done()
+			return []byte("Uploading mypkg-1.0.0.tar.gz"), ctx.Err()
+		},
+	}}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":        "__token__",
+			"password":        "pypi-token",
+			"repository":      "http://localhost:8080/",
+			"request_timeout": "10ms",
+			"total_timeout":   "50ms",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "total_timeout of 50ms exceeded") {
+		t.Errorf("expected a total timeout failure, got success=%v error=%q", resp.Success, resp.Error)
+	}
+}
+
+func TestExecuteUploadTimeout(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}}
+
+	start := time.Now()
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":       "__token__",
+			"password":       "pypi-token",
+			"repository":     "http://localhost:8080/",
+			"upload_timeout": "50ms",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.HasPrefix(resp.Error, "upload_timeout of 50ms exceeded: ") {
+		t.Errorf("expected an upload timeout failure, got success=%v error=%q", resp.Success, resp.Error)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the hook to stop at the deadline, took %s", elapsed)
+	}
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	p := &PyPIPlugin{}
+	tests := []struct {
+		name      string
+		config    map[string]any
+		wantField string
+	}{
+		{"defaults", map[string]any{}, ""},
+		{"tuned", map[string]any{"connect_timeout": "5s", "request_timeout": "20m", "total_timeout": "1h", "idle_timeout": 300}, ""},
+		{"disabled request timeout", map[string]any{"request_timeout": "0s"}, ""},
+		{"disabled idle timeout", map[string]any{"idle_timeout": "0s"}, "idle_timeout"},
+		{"invalid", map[string]any{"tls_timeout": "soon"}, "tls_timeout"},
+		{"request longer than total", map[string]any{"request_timeout": "10m", "total_timeout": "5m"}, "request_timeout"},
+		{"total longer than upload", map[string]any{"total_timeout": "1h", "upload_timeout": "30m"}, "upload_timeout"},
+		{"invalid upload timeout", map[string]any{"upload_timeout": "-1m"}, "upload_timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["username"] = "user"
+			tt.config["password"] = "pass"
+			resp, err := p.Validate(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var fields []string
+			for _, e := range resp.Errors {
+				if e.Code != validationWarningCode {
+					fields = append(fields, e.Field)
+				}
+			}
+			if tt.wantField == "" && len(fields) > 0 {
+				t.Errorf("unexpected errors: %+v", resp.Errors)
+			}
+			if tt.wantField != "" && !containsString(fields, tt.wantField) {
+				t.Errorf("expected an error for %s, got %+v", tt.wantField, resp.Errors)
+			}
+		})
+	}
+}
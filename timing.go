@@ -0,0 +1,44 @@
+package main
+
+import "time"
+
+// timestampFormat is the RFC 3339 format of timestamps in outputs, with millisecond precision
+// and an explicit offset.
+const timestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// formatTimestamp formats t in UTC for outputs.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
+
+// uploadTiming is when an upload of files started and finished. The native uploader sends
+// one file per upload; a twine invocation may upload several files, which share its timing.
+// Files are the paths or the dist_path pattern passed to the upload.
+type uploadTiming struct {
+	Files      []string `json:"files"`
+	StartedAt  string   `json:"started_at"`
+	FinishedAt string   `json:"finished_at"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+// recordTiming records an upload of files that started at start and finished now.
+func (r *uploadRun) recordTiming(files []string, start time.Time) {
+	end := time.Now()
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = toSlashPath(f)
+	}
+	r.timings = append(r.timings, uploadTiming{
+		Files:      names,
+		StartedAt:  formatTimestamp(start),
+		FinishedAt: formatTimestamp(end),
+		DurationMs: end.Sub(start).Milliseconds(),
+	})
+}
+
+// addTimingOutputs adds the overall started_at, finished_at and duration_ms outputs.
+func addTimingOutputs(outputs map[string]any, start, end time.Time) {
+	outputs["started_at"] = formatTimestamp(start)
+	outputs["finished_at"] = formatTimestamp(end)
+	outputs["duration_ms"] = end.Sub(start).Milliseconds()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestFormatTimestamp(t *testing.T) {
+	at := time.Date(2024, 3, 1, 14, 5, 9, 123456789, time.FixedZone("CET", 3600))
+	if got := formatTimestamp(at); got != "2024-03-01T13:05:09.123Z" {
+		t.Errorf("formatTimestamp = %q", got)
+	}
+	if _, err := time.Parse(time.RFC3339, formatTimestamp(at)); err != nil {
+		t.Errorf("expected an RFC 3339 timestamp: %v", err)
+	}
+}
+
+func TestExecuteTimingOutputs(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz", "mypkg-1.0.0-py3-none-any.whl")
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("Uploading distributions"), nil
+		},
+	}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":   "__token__",
+			"password":   "pypi-token",
+			"repository": "http://localhost:8080/",
+		},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+
+	started, err := time.Parse(time.RFC3339, resp.Outputs["started_at"].(string))
+	if err != nil {
+		t.Fatalf("invalid started_at: %v", err)
+	}
+	finished, err := time.Parse(time.RFC3339, resp.Outputs["finished_at"].(string))
+	if err != nil {
+		t.Fatalf("invalid finished_at: %v", err)
+	}
+	if finished.Before(started) {
+		t.Errorf("finished_at %v before started_at %v", finished, started)
+	}
+	if _, ok := resp.Outputs["duration_ms"].(int64); !ok {
+		t.Errorf("expected duration_ms, got %v", resp.Outputs["duration_ms"])
+	}
+
+	timings, _ := resp.Outputs["upload_timings"].([]uploadTiming)
+	if len(timings) != 1 || len(timings[0].Files) != 2 || timings[0].Files[0] != filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl") {
+		t.Errorf("unexpected upload timings %+v", timings)
+	}
+}
+
+func TestExecuteTimingOutputsOnFailure(t *testing.T) {
+	p := &PyPIPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:   plugin.HookPostPublish,
+		Config: map[string]any{"repository": "ftp://example.com"},
+	})
+	if err != nil || resp.Success {
+		t.Fatalf("expected a validation failure, got %v %+v", err, resp)
+	}
+	if resp.Outputs["started_at"] == nil || resp.Outputs["finished_at"] == nil {
+		t.Errorf("expected timing outputs on failure, got %v", resp.Outputs)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Hardware token signing defaults.
+const (
+	// defaultPKCS11Mechanism is the signing mechanism of the ECC P-256 keys of PIV tokens
+	defaultPKCS11Mechanism = "ECDSA-SHA256"
+	// defaultPKCS11PinEnv holds the user PIN of the token
+	defaultPKCS11PinEnv = "PKCS11_PIN"
+	// defaultDistSignatureDir receives the signatures, outside dist/ so that a later dist/*
+	// upload does not pick them up
+	defaultDistSignatureDir = "signatures"
+)
+
+// pkcs11Mechanisms lists the supported pkcs11_mechanism values, named as pkcs11-tool names them.
+var pkcs11Mechanisms = []string{
+	"ECDSA-SHA256", "ECDSA-SHA384", "ECDSA-SHA512",
+	"SHA256-RSA-PKCS", "SHA384-RSA-PKCS", "SHA512-RSA-PKCS",
+	"SHA256-RSA-PKCS-PSS", "SHA384-RSA-PKCS-PSS", "SHA512-RSA-PKCS-PSS",
+}
+
+// distSignature is the hardware token signature of an uploaded file, reported in outputs.
+type distSignature struct {
+	File string `json:"file"`
+	// SHA256 is the digest of the signed file, which is the uploaded file
+	SHA256        string `json:"sha256"`
+	SignaturePath string `json:"signature_path"`
+	Mechanism     string `json:"mechanism"`
+	// Key is the object ID or label of the key on the token
+	Key string `json:"key"`
+}
+
+// usesTokenSigning reports whether distribution files are signed with a hardware token.
+func usesTokenSigning(cfg Config) bool {
+	return cfg.PKCS11Module != ""
+}
+
+// pkcs11SignArgs returns the pkcs11-tool arguments signing file into sigPath. The PIN is read
+// by pkcs11-tool from the environment rather than passed in the process arguments.
+func pkcs11SignArgs(cfg Config, file, sigPath string) []string {
+	args := []string{"--module", cfg.PKCS11Module}
+	if cfg.PKCS11TokenLabel != "" {
+		args = append(args, "--token-label", cfg.PKCS11TokenLabel)
+	}
+	args = append(args, "--login")
+	if os.Getenv(cfg.PKCS11PinEnv) != "" {
+		args = append(args, "--pin", "env:"+cfg.PKCS11PinEnv)
+	}
+	if cfg.PKCS11KeyID != "" {
+		args = append(args, "--id", cfg.PKCS11KeyID)
+	} else {
+		args = append(args, "--label", cfg.PKCS11KeyLabel)
+	}
+	args = append(args, "--sign", "--mechanism", cfg.PKCS11Mechanism)
+	// ECDSA signatures are written in the ASN.1 form openssl verifies instead of raw r||s
+	if strings.HasPrefix(cfg.PKCS11Mechanism, "ECDSA-") {
+		args = append(args, "--signature-format", "openssl")
+	}
+	return append(args, "--input-file", file, "--output-file", sigPath)
+}
+
+// signDistFiles signs each file with the key on the hardware token, writing the signatures to
+// dist_signature_dir as <file>.sig. Tokens that require a touch wait for it on each file.
+func (p *PyPIPlugin) signDistFiles(ctx context.Context, cfg Config, files []string) ([]distSignature, []plugin.Artifact, error) {
+	if err := os.MkdirAll(cfg.DistSignatureDir, 0o750); err != nil {
+		return nil, nil, fmt.Errorf("failed to create dist_signature_dir: %w", err)
+	}
+	key := cfg.PKCS11KeyID
+	if key == "" {
+		key = cfg.PKCS11KeyLabel
+	}
+
+	signatures := make([]distSignature, 0, len(files))
+	artifacts := make([]plugin.Artifact, 0, len(files))
+	for _, f := range files {
+		sigPath := filepath.Join(cfg.DistSignatureDir, filepath.Base(f)+".sig")
+		output, err := p.getExecutor().Run(ctx, "pkcs11-tool", pkcs11SignArgs(cfg, f, sigPath)...)
+		if err != nil {
+			detail, _ := truncateOutput(strings.TrimSpace(string(output)), defaultMaxErrorBodyBytes)
+			return nil, nil, fmt.Errorf("failed to sign %s with the hardware token: %w: %s", filepath.Base(f), err, detail)
+		}
+		_, digest, _, err := fileDigests(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, sigDigest, size, err := fileDigests(sigPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("pkcs11-tool wrote no signature for %s: %w", filepath.Base(f), err)
+		}
+		if size == 0 {
+			return nil, nil, fmt.Errorf("pkcs11-tool wrote an empty signature for %s", filepath.Base(f))
+		}
+		signatures = append(signatures, distSignature{
+			File:          filepath.Base(f),
+			SHA256:        digest,
+			SignaturePath: filepath.ToSlash(sigPath),
+			Mechanism:     cfg.PKCS11Mechanism,
+			Key:           key,
+		})
+		artifacts = append(artifacts, plugin.Artifact{
+			Name:     filepath.Base(sigPath),
+			Path:     sigPath,
+			Type:     "file",
+			Size:     size,
+			Checksum: "sha256:" + sigDigest,
+		})
+	}
+	return signatures, artifacts, nil
+}
+
+// validateTokenSigningConfig validates the pkcs11_* options.
+func validateTokenSigningConfig(cfg Config) error {
+	if cfg.PKCS11Module == "" {
+		if cfg.PKCS11KeyID != "" || cfg.PKCS11KeyLabel != "" || cfg.PKCS11TokenLabel != "" {
+			return fmt.Errorf("pkcs11_key_id, pkcs11_key_label and pkcs11_token_label require pkcs11_module")
+		}
+		return nil
+	}
+	if info, err := os.Stat(cfg.PKCS11Module); err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("pkcs11_module %s is not a PKCS#11 library on this machine", cfg.PKCS11Module)
+	}
+	if (cfg.PKCS11KeyID == "") == (cfg.PKCS11KeyLabel == "") {
+		return fmt.Errorf("pkcs11_module requires exactly one of pkcs11_key_id and pkcs11_key_label")
+	}
+	if cfg.PKCS11KeyID != "" {
+		if _, err := hex.DecodeString(cfg.PKCS11KeyID); err != nil {
+			return fmt.Errorf("pkcs11_key_id must be the hex object ID of the key, such as 02 for PIV slot 9c")
+		}
+	}
+	if !containsString(pkcs11Mechanisms, cfg.PKCS11Mechanism) {
+		return fmt.Errorf("pkcs11_mechanism must be one of: %s", strings.Join(pkcs11Mechanisms, ", "))
+	}
+	if cfg.DistSignatureDir == "" {
+		return fmt.Errorf("dist_signature_dir must not be empty")
+	}
+	if matched, _ := filepath.Match(filepath.FromSlash(cfg.DistPath), filepath.Join(cfg.DistSignatureDir, "x.sig")); matched {
+		return fmt.Errorf("dist_signature_dir %s is matched by dist_path; the signatures would be uploaded by the next publish", cfg.DistSignatureDir)
+	}
+	return nil
+}
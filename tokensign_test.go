@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestPKCS11SignArgs(t *testing.T) {
+	t.Setenv("YUBIKEY_PIN", "123456")
+	cfg := Config{
+		PKCS11Module:     "/usr/lib/libykcs11.so",
+		PKCS11KeyID:      "02",
+		PKCS11TokenLabel: "YubiKey PIV #1234",
+		PKCS11Mechanism:  "ECDSA-SHA256",
+		PKCS11PinEnv:     "YUBIKEY_PIN",
+	}
+	want := []string{
+		"--module", "/usr/lib/libykcs11.so", "--token-label", "YubiKey PIV #1234", "--login", "--pin", "env:YUBIKEY_PIN",
+		"--id", "02", "--sign", "--mechanism", "ECDSA-SHA256", "--signature-format", "openssl",
+		"--input-file", "dist/mypkg-1.0.0.tar.gz", "--output-file", "signatures/mypkg-1.0.0.tar.gz.sig",
+	}
+	if got := pkcs11SignArgs(cfg, "dist/mypkg-1.0.0.tar.gz", "signatures/mypkg-1.0.0.tar.gz.sig"); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected args %v", got)
+	}
+
+	// Without a PIN in the environment, pin pad readers authenticate on the reader
+	cfg.PKCS11KeyID, cfg.PKCS11KeyLabel, cfg.PKCS11TokenLabel, cfg.PKCS11Mechanism, cfg.PKCS11PinEnv = "", "release", "", "SHA256-RSA-PKCS", "UNSET_PIN"
+	got := strings.Join(pkcs11SignArgs(cfg, "a.whl", "a.whl.sig"), " ")
+	if got != "--module /usr/lib/libykcs11.so --login --label release --sign --mechanism SHA256-RSA-PKCS --input-file a.whl --output-file a.whl.sig" {
+		t.Errorf("unexpected args %s", got)
+	}
+}
+
+func TestExecuteSignsDistFiles(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz", "mypkg-1.0.0-py3-none-any.whl")
+	if err := os.WriteFile("libykcs11.so", []byte("module"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var signed, uploaded []string
+	executor := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name == "pkcs11-tool" {
+				signed = append(signed, args[len(args)-3])
+				return nil, os.WriteFile(args[len(args)-1], []byte("signature"), 0o600)
+			}
+			if len(signed) != 2 {
+				t.Errorf("expected the files to be signed before the upload, signed %v", signed)
+			}
+			uploaded = append(uploaded, args[len(args)-2:]...)
+			return []byte("ok"), nil
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: executor}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":      "__token__",
+			"password":      "pypi-token",
+			"repository":    "http://localhost:8080/",
+			"pkcs11_module": "libykcs11.so",
+			"pkcs11_key_id": "02",
+		},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+	signatures, _ := resp.Outputs["dist_signatures"].([]distSignature)
+	if len(signatures) != 2 || signatures[1].SignaturePath != "signatures/mypkg-1.0.0.tar.gz.sig" || signatures[1].Key != "02" || signatures[1].Mechanism != defaultPKCS11Mechanism {
+		t.Errorf("unexpected dist_signatures %+v", resp.Outputs["dist_signatures"])
+	}
+	if len(resp.Artifacts) != 2 || resp.Artifacts[0].Name != "mypkg-1.0.0-py3-none-any.whl.sig" {
+		t.Errorf("unexpected artifacts %+v", resp.Artifacts)
+	}
+	for _, f := range uploaded {
+		if strings.HasSuffix(f, ".sig") {
+			t.Errorf("expected only distributions to be uploaded, got %v", uploaded)
+		}
+	}
+
+	// A token that is not plugged in fails the publish before anything is uploaded
+	signed, uploaded = nil, nil
+	executor.RunFunc = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "pkcs11-tool" {
+			return []byte("error: No slot with a token was found"), errors.New("exit status 1")
+		}
+		uploaded = append(uploaded, args[len(args)-1])
+		return []byte("ok"), nil
+	}
+	resp, _ = p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":      "__token__",
+			"password":      "pypi-token",
+			"repository":    "http://localhost:8080/",
+			"pkcs11_module": "libykcs11.so",
+			"pkcs11_key_id": "02",
+		},
+	})
+	if resp.Success || !strings.Contains(resp.Error, "No slot with a token was found") || len(uploaded) != 0 {
+		t.Errorf("expected the signing failure to stop the upload, got %+v uploaded %v", resp, uploaded)
+	}
+}
+
+func TestValidateTokenSigningConfig(t *testing.T) {
+	module := filepath.Join(t.TempDir(), "opensc-pkcs11.so")
+	if err := os.WriteFile(module, []byte("module"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	valid := Config{DistPath: "dist/*", PKCS11Module: module, PKCS11KeyID: "02", PKCS11Mechanism: defaultPKCS11Mechanism, DistSignatureDir: defaultDistSignatureDir}
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"valid", func(c *Config) {}, false},
+		{"disabled", func(c *Config) { c.PKCS11Module, c.PKCS11KeyID = "", "" }, false},
+		{"key without module", func(c *Config) { c.PKCS11Module = "" }, true},
+		{"missing module", func(c *Config) { c.PKCS11Module = filepath.Join(filepath.Dir(module), "missing.so") }, true},
+		{"label", func(c *Config) { c.PKCS11KeyID, c.PKCS11KeyLabel = "", "release" }, false},
+		{"id and label", func(c *Config) { c.PKCS11KeyLabel = "release" }, true},
+		{"no key", func(c *Config) { c.PKCS11KeyID = "" }, true},
+		{"key id not hex", func(c *Config) { c.PKCS11KeyID = "slot 9c" }, true},
+		{"mechanism", func(c *Config) { c.PKCS11Mechanism = "EDDSA" }, true},
+		{"signatures in dist", func(c *Config) { c.DistSignatureDir = "dist" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			if err := validateTokenSigningConfig(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateTokenSigningConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
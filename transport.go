@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// usesTransportOptions reports whether cfg changes how the native uploader speaks HTTP.
+func usesTransportOptions(cfg Config) bool {
+	return cfg.ForceHTTP1 || cfg.DisableCompression || len(cfg.ProxyHeaders) > 0
+}
+
+// clientWithTransportOptions returns a copy of client applying the HTTP options of cfg:
+// ForceHTTP1 keeps connections on HTTP/1.1, DisableCompression stops the transport from asking
+// for gzip responses, and ProxyHeaders are sent with the CONNECT request of proxy tunnels.
+// client is returned unchanged when no option is set.
+func clientWithTransportOptions(client *http.Client, cfg Config) *http.Client {
+	if !usesTransportOptions(cfg) {
+		return client
+	}
+	base, ok := client.Transport.(*http.Transport)
+	if !ok || base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+
+	if cfg.ForceHTTP1 {
+		// Some corporate proxies break multipart bodies over HTTP/2; a non-nil empty map
+		// disables the HTTP/2 upgrade during the TLS handshake
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		if transport.TLSClientConfig != nil {
+			transport.TLSClientConfig.NextProtos = nil
+		}
+	}
+	if cfg.DisableCompression {
+		transport.DisableCompression = true
+	}
+	if len(cfg.ProxyHeaders) > 0 {
+		header := transport.ProxyConnectHeader.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		for name, value := range cfg.ProxyHeaders {
+			header.Set(name, value)
+		}
+		transport.ProxyConnectHeader = header
+	}
+
+	withOptions := *client
+	withOptions.Transport = transport
+	return &withOptions
+}
+
+// proxyHeaderSecrets returns the values of proxy headers that carry credentials, such as
+// Proxy-Authorization, so they are masked like passwords.
+func proxyHeaderSecrets(cfg Config) []string {
+	var secrets []string
+	for name, value := range cfg.ProxyHeaders {
+		if isSensitiveHeader(name, cfg) {
+			secrets = append(secrets, value)
+			// Authorization values are logged with their scheme, so mask the credentials alone
+			if _, credentials, ok := strings.Cut(value, " "); ok {
+				secrets = append(secrets, strings.TrimSpace(credentials))
+			}
+		}
+	}
+	return secrets
+}
+
+// validateProxyHeaders validates the proxy_headers option.
+func validateProxyHeaders(cfg Config) error {
+	names := make([]string, 0, len(cfg.ProxyHeaders))
+	for name := range cfg.ProxyHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !isHeaderName(name) {
+			return fmt.Errorf("proxy header %q is not a valid header name", name)
+		}
+		if strings.ContainsAny(cfg.ProxyHeaders[name], "\r\n\x00") {
+			return fmt.Errorf("proxy header %s must not contain line breaks", name)
+		}
+	}
+	return nil
+}
+
+// isHeaderName reports whether name is an HTTP token (RFC 9110, section 5.1).
+func isHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
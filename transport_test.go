@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestClientWithTransportOptionsForceHTTP1(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	for _, tt := range []struct {
+		force bool
+		want  string
+	}{{false, "HTTP/2.0"}, {true, "HTTP/1.1"}} {
+		p := &PyPIPlugin{httpClient: server.Client()}
+		resp, err := p.uploadHTTPClient(Config{ForceHTTP1: tt.force}).Get(server.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.Proto != tt.want {
+			t.Errorf("force_http1=%v: got %s, want %s", tt.force, resp.Proto, tt.want)
+		}
+	}
+}
+
+func TestClientWithTransportOptionsCompression(t *testing.T) {
+	var acceptEncoding []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = append(acceptEncoding, r.Header.Get("Accept-Encoding"))
+	}))
+	defer server.Close()
+
+	for _, disable := range []bool{false, true} {
+		client := clientWithTransportOptions(server.Client(), Config{DisableCompression: disable})
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+	if len(acceptEncoding) != 2 || acceptEncoding[0] != "gzip" || acceptEncoding[1] != "" {
+		t.Errorf("expected gzip to be requested only with compression enabled, got %q", acceptEncoding)
+	}
+}
+
+func TestClientWithTransportOptionsProxyHeaders(t *testing.T) {
+	var connect http.Header
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connect = r.Header.Clone()
+		w.WriteHeader(http.StatusProxyAuthRequired)
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	base := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	client := clientWithTransportOptions(base, Config{ProxyHeaders: map[string]string{
+		"Proxy-Authorization": "Bearer proxy-token",
+		"X-Forwarded-Client":  "release",
+	}})
+	if _, err := client.Get("https://upload.pypi.org/legacy/"); err == nil {
+		t.Fatal("expected the proxy to reject the tunnel")
+	}
+	if connect.Get("Proxy-Authorization") != "Bearer proxy-token" || connect.Get("X-Forwarded-Client") != "release" {
+		t.Errorf("expected the proxy headers on the CONNECT request, got %v", connect)
+	}
+	if base.Transport.(*http.Transport).ProxyConnectHeader != nil {
+		t.Error("the original transport must not be modified")
+	}
+	if same := clientWithTransportOptions(base, Config{}); same != base {
+		t.Error("expected the client unchanged without transport options")
+	}
+}
+
+func TestTransportOptionsConfig(t *testing.T) {
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"force_http1":         true,
+		"disable_compression": true,
+		"proxy_headers":       map[string]any{"Proxy-Authorization": "Basic c2VjcmV0"},
+	})
+	if !cfg.ForceHTTP1 || !cfg.DisableCompression || cfg.ProxyHeaders["Proxy-Authorization"] != "Basic c2VjcmV0" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	// twine only speaks HTTP/1.1, but cannot set proxy headers or stop asking for gzip
+	if requiresNativeUploader(Config{ForceHTTP1: true}) || !requiresNativeUploader(cfg) {
+		t.Error("expected only compression and proxy headers to require the native uploader")
+	}
+	if secrets := configSecrets(cfg); !containsString(secrets, "c2VjcmV0") {
+		t.Errorf("expected the proxy credentials to be masked, got %v", secrets)
+	}
+
+	for name, wantErr := range map[string]bool{
+		"X-Proxy-Client": false,
+		"Bad Header":     true,
+		"":               true,
+	} {
+		err := validateProxyHeaders(Config{ProxyHeaders: map[string]string{name: "value"}})
+		if (err != nil) != wantErr {
+			t.Errorf("validateProxyHeaders(%q) = %v, want error %v", name, err, wantErr)
+		}
+	}
+	if err := validateProxyHeaders(Config{ProxyHeaders: map[string]string{"X-Proxy": "a\r\nHost: evil"}}); err == nil || !strings.Contains(err.Error(), "line breaks") {
+		t.Errorf("expected a header value with line breaks to be rejected, got %v", err)
+	}
+}
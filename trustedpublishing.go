@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Environment of the ambient OIDC tokens used for Trusted Publishing.
+const (
+	// githubTokenRequestURLEnv and githubTokenRequestTokenEnv are set by GitHub Actions for jobs
+	// with the id-token: write permission
+	githubTokenRequestURLEnv   = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	githubTokenRequestTokenEnv = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+	// defaultOIDCTokenEnv is the variable of the GitLab CI id_tokens entry for PyPI
+	defaultOIDCTokenEnv = "PYPI_ID_TOKEN"
+)
+
+// trustedPublishingTokenLifetime is how long PyPI accepts a minted upload token.
+const trustedPublishingTokenLifetime = 15 * time.Minute
+
+// knownTrustedPublishingURLs maps upload endpoints to the index exchanging OIDC tokens.
+var knownTrustedPublishingURLs = map[string]string{
+	"https://upload.pypi.org/legacy/": "https://pypi.org",
+	"https://test.pypi.org/legacy/":   "https://test.pypi.org",
+}
+
+// trustedPublishing describes the upload token minted for a publish, reported in outputs.
+type trustedPublishing struct {
+	Provider  string `json:"provider"`
+	Index     string `json:"index"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// defaultTrustedPublishingURL returns the token exchange index of a well-known upload
+// repository, or "".
+func defaultTrustedPublishingURL(repository string) string {
+	if !strings.HasSuffix(repository, "/") {
+		repository += "/"
+	}
+	return knownTrustedPublishingURLs[repository]
+}
+
+// ambientOIDCToken returns an OIDC token for audience from the CI environment and the name of
+// the provider that issued it. GitHub Actions tokens are requested for the audience; GitLab CI
+// and other providers expose a token minted for it in cfg.OIDCTokenEnv.
+func (p *PyPIPlugin) ambientOIDCToken(ctx context.Context, cfg Config, audience string) (string, string, error) {
+	if requestURL := os.Getenv(githubTokenRequestURLEnv); requestURL != "" {
+		requestToken := os.Getenv(githubTokenRequestTokenEnv)
+		if requestToken == "" {
+			return "", "", fmt.Errorf("%s is not set; grant the job the id-token: write permission", githubTokenRequestTokenEnv)
+		}
+		u, err := url.Parse(requestURL)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid %s: %w", githubTokenRequestURLEnv, err)
+		}
+		query := u.Query()
+		query.Set("audience", audience)
+		u.RawQuery = query.Encode()
+		var token struct {
+			Value string `json:"value"`
+		}
+		headers := map[string]string{"Authorization": "Bearer " + requestToken}
+		if err := p.sendJSON(ctx, "github oidc", http.MethodGet, u.String(), headers, nil, &token); err != nil {
+			return "", "", err
+		}
+		if token.Value == "" {
+			return "", "", fmt.Errorf("github oidc request returned no token")
+		}
+		return token.Value, "github", nil
+	}
+	if token := strings.TrimSpace(os.Getenv(cfg.OIDCTokenEnv)); token != "" {
+		if os.Getenv("GITLAB_CI") != "" {
+			return token, "gitlab", nil
+		}
+		return token, "environment", nil
+	}
+	return "", "", fmt.Errorf("no ambient OIDC token: run in GitHub Actions with the id-token: write permission, or in GitLab CI with an id_tokens entry named %s", cfg.OIDCTokenEnv)
+}
+
+// mintTrustedPublishingToken exchanges the ambient OIDC token for a short-lived upload token of
+// the index. The index looks up the Trusted Publisher matching the token's claims.
+func (p *PyPIPlugin) mintTrustedPublishingToken(ctx context.Context, cfg Config) (string, *trustedPublishing, error) {
+	index := strings.TrimSuffix(cfg.TrustedPublishingURL, "/")
+	var audience struct {
+		Audience string `json:"audience"`
+	}
+	if err := p.sendJSON(ctx, "trusted publishing", http.MethodGet, index+"/_/oidc/audience", nil, nil, &audience); err != nil {
+		return "", nil, fmt.Errorf("failed to get the OIDC audience: %w", err)
+	}
+	if audience.Audience == "" {
+		return "", nil, fmt.Errorf("failed to get the OIDC audience: %s reported none", index)
+	}
+
+	oidcToken, provider, err := p.ambientOIDCToken(ctx, cfg, audience.Audience)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var minted struct {
+		Success bool   `json:"success"`
+		Token   string `json:"token"`
+		Message string `json:"message"`
+		Errors  []struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"errors"`
+	}
+	err = p.sendJSON(ctx, "trusted publishing", http.MethodPost, index+"/_/oidc/mint-token", nil, map[string]string{"token": oidcToken}, &minted)
+	if err == nil && minted.Token == "" {
+		var reasons []string
+		for _, e := range minted.Errors {
+			reasons = append(reasons, e.Code+": "+e.Description)
+		}
+		err = fmt.Errorf("%s returned no token: %s", index, strings.Join(reasons, "; "))
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to mint an upload token (is a Trusted Publisher configured for this %s workflow?): %w", provider, err)
+	}
+	return minted.Token, &trustedPublishing{
+		Provider:  provider,
+		Index:     index,
+		ExpiresAt: formatTimestamp(time.Now().Add(trustedPublishingTokenLifetime)),
+	}, nil
+}
+
+// validateTrustedPublishingConfig validates the trusted_publishing options.
+func validateTrustedPublishingConfig(cfg Config) error {
+	if !cfg.TrustedPublishing {
+		return nil
+	}
+	switch {
+	case cfg.TrustedPublishingURL == "":
+		return fmt.Errorf("trusted_publishing_url is required for repositories other than PyPI and TestPyPI")
+	case len(cfg.TokenCommand) > 0:
+		return fmt.Errorf("trusted_publishing cannot be combined with token_command")
+	case len(cfg.CredentialOverrides) > 0:
+		return fmt.Errorf("trusted_publishing cannot be combined with credential_overrides")
+	case cfg.SpiffeWorkloadAPI || !usesBasicAuth(cfg):
+		return fmt.Errorf("trusted_publishing uploads with an API token and cannot be combined with auth_scheme or spiffe_workload_api")
+	case cfg.OIDCTokenEnv == "":
+		return fmt.Errorf("oidc_token_env cannot be empty")
+	}
+	if cfg.TrustedPublishingURL != defaultTrustedPublishingURL(cfg.Repository) {
+		if err := validateRepositoryURL(cfg.TrustedPublishingURL); err != nil {
+			return fmt.Errorf("invalid trusted_publishing_url: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// fakeTrustedPublishing serves the GitHub OIDC token endpoint and the PyPI token exchange.
+func fakeTrustedPublishing(t *testing.T, publisher bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/github-token":
+			if r.Header.Get("Authorization") != "Bearer request-token" || r.URL.Query().Get("audience") != "pypi" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"value": "github-oidc-token"}`))
+		case "/_/oidc/audience":
+			_, _ = w.Write([]byte(`{"audience": "pypi"}`))
+		case "/_/oidc/mint-token":
+			var req struct {
+				Token string `json:"token"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if !publisher || (req.Token != "github-oidc-token" && req.Token != "gitlab-oidc-token") {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_, _ = w.Write([]byte(`{"message": "Token request failed", "errors": [{"code": "invalid-publisher", "description": "valid token, but no corresponding publisher"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"success": true, "token": "pypi-minted-token"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExecuteTrustedPublishing(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		provider string
+	}{
+		{"github actions", map[string]string{githubTokenRequestURLEnv: "/github-token?api-version=2.0", githubTokenRequestTokenEnv: "request-token"}, "github"},
+		{"gitlab ci", map[string]string{"GITLAB_CI": "true", defaultOIDCTokenEnv: "gitlab-oidc-token"}, "gitlab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeTrustedPublishing(t, true)
+			t.Setenv(githubTokenRequestURLEnv, "")
+			t.Setenv(defaultOIDCTokenEnv, "")
+			for k, v := range tt.env {
+				if strings.HasPrefix(v, "/") {
+					v = server.URL + v
+				}
+				t.Setenv(k, v)
+			}
+			writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+
+			executor := &MockCommandExecutor{}
+			p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: executor}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"repository":             "http://localhost:8080/legacy/",
+					"trusted_publishing":     true,
+					"trusted_publishing_url": server.URL,
+				},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected success, got %v %+v", err, resp)
+			}
+			if env := strings.Join(executor.RunCalls[0].Env, " "); env != "TWINE_USERNAME=__token__ TWINE_PASSWORD=pypi-minted-token" {
+				t.Errorf("expected the minted token to be used, got %v", env)
+			}
+			if minted, _ := resp.Outputs["trusted_publishing"].(*trustedPublishing); minted == nil || minted.Provider != tt.provider {
+				t.Errorf("unexpected trusted_publishing output %+v", resp.Outputs["trusted_publishing"])
+			}
+		})
+	}
+}
+
+func TestExecuteTrustedPublishingFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		publisher bool
+		env       map[string]string
+		want      string
+	}{
+		{"no publisher", false, map[string]string{defaultOIDCTokenEnv: "gitlab-oidc-token"}, "invalid-publisher"},
+		{"no ambient token", true, nil, "no ambient OIDC token"},
+		{"missing id-token permission", true, map[string]string{githubTokenRequestURLEnv: "/github-token"}, "id-token: write"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeTrustedPublishing(t, tt.publisher)
+			t.Setenv(githubTokenRequestURLEnv, "")
+			t.Setenv(githubTokenRequestTokenEnv, "")
+			t.Setenv(defaultOIDCTokenEnv, "")
+			for k, v := range tt.env {
+				if strings.HasPrefix(v, "/") {
+					v = server.URL + v
+				}
+				t.Setenv(k, v)
+			}
+			writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+			uploads := 0
+			p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, a ...string) ([]byte, error) {
+					uploads++
+					return nil, nil
+				},
+			}}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"repository":             "http://localhost:8080/legacy/",
+					"trusted_publishing":     true,
+					"trusted_publishing_url": server.URL,
+				},
+			})
+			if err != nil || resp.Success || !strings.Contains(resp.Error, tt.want) || uploads != 0 {
+				t.Fatalf("expected %q, got %v %+v", tt.want, err, resp)
+			}
+		})
+	}
+}
+
+func TestValidateTrustedPublishingWithoutCredentials(t *testing.T) {
+	t.Setenv("PYPI_USERNAME", "")
+	t.Setenv("PYPI_PASSWORD", "")
+	p := &PyPIPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{"trusted_publishing": true})
+	if err != nil || !resp.Valid {
+		t.Fatalf("expected trusted publishing to need no credentials, got %v %+v", err, resp)
+	}
+	resp, err = p.Validate(context.Background(), map[string]any{})
+	if err != nil || resp.Valid {
+		t.Fatalf("expected credentials to be required without trusted publishing, got %v %+v", err, resp)
+	}
+}
+
+func TestValidateTrustedPublishingConfig(t *testing.T) {
+	pypi := Config{TrustedPublishing: true, Repository: "https://upload.pypi.org/legacy/", TrustedPublishingURL: "https://pypi.org", OIDCTokenEnv: defaultOIDCTokenEnv}
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr bool
+	}{
+		{"pypi", func(cfg *Config) {}, false},
+		{"disabled", func(cfg *Config) { *cfg = Config{} }, false},
+		{"other index without url", func(cfg *Config) { cfg.Repository, cfg.TrustedPublishingURL = "https://pypi.example.com/", "" }, true},
+		{"token command", func(cfg *Config) { cfg.TokenCommand = []string{"vault"} }, true},
+		{"bearer auth", func(cfg *Config) { cfg.AuthScheme = authSchemeBearer }, true},
+		{"empty token env", func(cfg *Config) { cfg.OIDCTokenEnv = "" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := pypi
+			tt.modify(&cfg)
+			if err := validateTrustedPublishingConfig(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateTrustedPublishingConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseConfigTrustedPublishingURL(t *testing.T) {
+	tests := []struct {
+		repository string
+		want       string
+	}{
+		{"", "https://pypi.org"},
+		{"https://test.pypi.org/legacy/", "https://test.pypi.org"},
+		{"https://pypi.example.com/simple/", ""},
+	}
+	for _, tt := range tests {
+		p := &PyPIPlugin{}
+		raw := map[string]any{"trusted_publishing": true}
+		if tt.repository != "" {
+			raw["repository"] = tt.repository
+		}
+		cfg := p.parseConfig(raw)
+		if cfg.TrustedPublishingURL != tt.want {
+			t.Errorf("repository %q: expected trusted_publishing_url %q, got %q", tt.repository, tt.want, cfg.TrustedPublishingURL)
+		}
+	}
+
+	// A private index needs its own token exchange
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{"trusted_publishing": true, "repository": "https://pypi.example.com/simple/"})
+	if err := validateTrustedPublishingConfig(cfg); err == nil || !strings.Contains(err.Error(), "trusted_publishing_url is required") {
+		t.Errorf("expected trusted_publishing_url to be required, got %v", err)
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// uploadRun summarizes the twine invocations of a publish.
+type uploadRun struct {
+	output         string
+	tokenRefreshes int
+	groups         []uploadGroup
+	// notices are the index notices of native upload responses
+	notices []indexNotice
+	// timings are the start and finish of each upload
+	timings []uploadTiming
+	// failed are the files that failed when continue_on_error went on after them or parallel
+	// uploads had several in flight
+	failed []string
+}
+
+// uploadGroup is a set of distribution files uploaded with the same credentials.
+type uploadGroup struct {
+	// override is the matching credential override, or nil for the default credentials.
+	override *CredentialOverride
+	files    []string
+}
+
+// label names the credentials used by the group in outputs.
+func (g uploadGroup) label() string {
+	if g.override == nil {
+		return "default"
+	}
+	return "override:" + g.override.Pattern
+}
+
+// publishSession holds the state shared by the packages published in one hook call.
+type publishSession struct {
+	breaker *circuitBreaker
+	digests *uploadedDigests
+	volume  *uploadVolume
+	log     *publishLog
+	// interrupt handles Ctrl-C during parallel uploads at a terminal, nil otherwise.
+	interrupt *interruptGuard
+}
+
+// newPublishSession creates the session state for a hook call configured by cfg.
+func newPublishSession(cfg Config) *publishSession {
+	log := newPublishLog(cfg)
+	log.addSecrets(cfg)
+	return &publishSession{
+		breaker: newCircuitBreaker(cfg),
+		digests: newUploadedDigests(),
+		volume:  newUploadVolume(),
+		log:     log,
+	}
+}
+
+// runTwineUploads uploads the configured distributions with twine. files, when non-nil,
+// replaces the files matched by cfg.DistPath.
+// Static credentials upload everything in a single invocation. Credential overrides split the
+// files into groups uploaded with their own credentials. With a token command the files using
+// the default credentials, and with a client certificate all files, are uploaded one at a time
+// so short-lived tokens and certificates can be refreshed between files.
+func (p *PyPIPlugin) runTwineUploads(ctx context.Context, cfg Config, executor CommandExecutor, files []string) (run uploadRun, err error) {
+	if !usesTokenSource(cfg) && len(cfg.CredentialOverrides) == 0 && !usesClientCert(cfg) {
+		if files == nil {
+			files = []string{cfg.DistPath}
+		}
+		start := time.Now()
+		output, err := p.runTwine(ctx, cfg, executor, files)
+		run = uploadRun{output: string(output)}
+		run.recordTiming(files, start)
+		return run, err
+	}
+
+	if files == nil {
+		files, err = expandDistGlob(cfg.DistPath)
+		if err != nil {
+			return uploadRun{}, err
+		}
+	}
+	if len(files) == 0 {
+		return uploadRun{}, fmt.Errorf("no distribution files match %s", cfg.DistPath)
+	}
+
+	creds := p.uploadCredentials(cfg)
+
+	run.groups = groupByCredentials(cfg.CredentialOverrides, files)
+	var output strings.Builder
+	defer func() {
+		run.output = output.String()
+		if creds != nil {
+			run.tokenRefreshes = creds.refreshCount()
+		}
+	}()
+
+	for _, group := range run.groups {
+		groupCfg := cfg
+		if group.override != nil {
+			groupCfg.Username = group.override.Username
+			groupCfg.Password = group.override.resolvedPassword()
+		}
+		tokens := group.override == nil && creds != nil
+
+		if !tokens && !usesClientCert(cfg) {
+			start := time.Now()
+			out, err := p.runTwine(ctx, groupCfg, executor, group.files)
+			run.recordTiming(group.files, start)
+			output.Write(out)
+			if err != nil {
+				return run, fmt.Errorf("%s: %w", group.label(), err)
+			}
+			continue
+		}
+
+		for _, file := range group.files {
+			var out []byte
+			var err error
+			start := time.Now()
+			if tokens {
+				out, err = p.uploadFileWithToken(ctx, cfg, executor, creds, file)
+			} else {
+				out, err = p.runTwine(ctx, groupCfg, executor, []string{file})
+			}
+			run.recordTiming([]string{file}, start)
+			output.Write(out)
+			if err != nil {
+				return run, fmt.Errorf("%s: %w", filepath.Base(file), err)
+			}
+		}
+	}
+
+	return run, nil
+}
+
+// usesTokenSource reports whether uploads authenticate with short-lived tokens that are
+// refreshed between files.
+func usesTokenSource(cfg Config) bool {
+	return len(cfg.TokenCommand) > 0 || cfg.CodeArtifact != nil || cfg.GCPArtifactRegistry || usesAzureArtifactsFederation(cfg)
+}
+
+// uploadCredentials returns the refreshing token credentials of the uploads, or nil when the
+// configured credentials are used as they are.
+func (p *PyPIPlugin) uploadCredentials(cfg Config) *refreshingCredentials {
+	switch {
+	case len(cfg.TokenCommand) > 0:
+		return newRefreshingCredentials(&commandTokenSource{
+			executor: p.getExecutor(),
+			command:  cfg.TokenCommand,
+			username: tokenUsername(cfg),
+			lifetime: cfg.TokenLifetime,
+			now:      time.Now,
+		}, cfg.TokenRefreshMargin)
+	case cfg.CodeArtifact != nil:
+		return newRefreshingCredentials(&codeArtifactTokenSource{
+			client:   p.getHTTPClient(),
+			settings: cfg.CodeArtifact,
+			now:      time.Now,
+		}, cfg.TokenRefreshMargin)
+	case cfg.GCPArtifactRegistry:
+		return newRefreshingCredentials(&gcpTokenSource{
+			p:               p,
+			credentialsFile: cfg.GCPCredentialsFile,
+			now:             time.Now,
+		}, cfg.TokenRefreshMargin)
+	case usesAzureArtifactsFederation(cfg):
+		return newRefreshingCredentials(&azureDevOpsTokenSource{p: p, cfg: cfg, now: time.Now}, cfg.TokenRefreshMargin)
+	}
+	return nil
+}
+
+// runTwine runs one twine upload of files, materializing the client certificate for it.
+func (p *PyPIPlugin) runTwine(ctx context.Context, cfg Config, executor CommandExecutor, files []string) ([]byte, error) {
+	if usesClientCert(cfg) {
+		certFile, cleanup, err := twineClientCert(cfg)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		cfg.ClientCert, cfg.ClientKey, cfg.SpiffeWorkloadAPI = certFile, "", false
+	}
+	// Verbose uploads of many files print a lot; only the head and tail are kept in memory
+	return runStreaming(ctx, executor, twineEnv(cfg), p.streamOutput(cfg, "twine: "), cfg.MaxOutputBytes, "twine", p.buildTwineArgsForFiles(cfg, files)...)
+}
+
+// runNativeUploads uploads the distributions with the native uploader, one at a time unless
+// concurrency is set, for authentication twine cannot send. Credential overrides and token commands apply per file as
+// with twine, and uploads go through the session's circuit breaker.
+func (p *PyPIPlugin) runNativeUploads(ctx context.Context, cfg Config, session *publishSession, files []string) (run uploadRun, err error) {
+	if files == nil {
+		files, err = expandDistGlob(cfg.DistPath)
+		if err != nil {
+			return uploadRun{}, err
+		}
+	}
+	if len(files) == 0 {
+		return uploadRun{}, fmt.Errorf("no distribution files match %s", cfg.DistPath)
+	}
+
+	creds := p.uploadCredentials(cfg)
+
+	run.groups = groupByCredentials(cfg.CredentialOverrides, files)
+	var output strings.Builder
+	defer func() {
+		run.output = output.String()
+		if creds != nil {
+			run.tokenRefreshes = creds.refreshCount()
+		}
+	}()
+
+	client := p.uploadHTTPClient(cfg)
+	if cfg.DebugHTTP {
+		client = session.log.debugClient(client, cfg)
+	}
+
+	fmt.Fprintf(&output, "Uploading distributions to %s\n", cfg.Repository)
+	var uploads []nativeUpload
+	for _, group := range run.groups {
+		for _, file := range group.files {
+			uploads = append(uploads, nativeUpload{file: file, override: group.override})
+		}
+	}
+	if cfg.Concurrency > 1 && len(uploads) > 1 {
+		err = p.runNativeUploadsParallel(ctx, cfg, session, creds, client, uploads, &run, &output)
+		return run, err
+	}
+
+	for _, upload := range uploads {
+		name := filepath.Base(upload.file)
+		start := time.Now()
+		notices, err := p.uploadNative(ctx, cfg, session, creds, client, upload)
+		run.recordTiming([]string{upload.file}, start)
+		run.notices = append(run.notices, notices...)
+		if err != nil {
+			if cfg.SkipExisting && isAlreadyExists(err.Error()) {
+				fmt.Fprintf(&output, "Skipping %s because it appears to already exist\n", name)
+				continue
+			}
+			fmt.Fprintf(&output, "Uploading %s\nERROR    %v\n", name, err)
+			return run, fmt.Errorf("%s: %w", name, err)
+		}
+		fmt.Fprintf(&output, "Uploading %s\n", name)
+	}
+	return run, nil
+}
+
+// nativeUpload is one file of runNativeUploads with the credential override matching it.
+type nativeUpload struct {
+	file     string
+	override *CredentialOverride
+}
+
+// uploadNative uploads one file with the credentials of its override or the token command.
+func (p *PyPIPlugin) uploadNative(ctx context.Context, cfg Config, session *publishSession, creds *refreshingCredentials, client *http.Client, upload nativeUpload) ([]indexNotice, error) {
+	switch {
+	case upload.override != nil:
+		cfg.Username = upload.override.Username
+		cfg.Password = upload.override.resolvedPassword()
+	case creds != nil:
+		return p.uploadNativeWithToken(ctx, cfg, session, creds, client, upload.file)
+	}
+	return p.uploadFileNative(ctx, cfg, client, session, upload.file)
+}
+
+// uploadNativeWithToken uploads one file with the built-in uploader, retrying once with a fresh
+// token if the index rejects the current one, as uploadFileWithToken does for twine.
+func (p *PyPIPlugin) uploadNativeWithToken(ctx context.Context, cfg Config, session *publishSession, creds *refreshingCredentials, client *http.Client, file string) ([]indexNotice, error) {
+	var notices []indexNotice
+	for attempt := 0; attempt < 2; attempt++ {
+		cred, err := creds.get(ctx)
+		if err != nil {
+			return notices, err
+		}
+		cfg.Username = cred.Username
+		cfg.Password = cred.Password
+
+		notices, err = p.uploadFileNative(ctx, cfg, client, session, file)
+		if err == nil || !isAuthFailure(err.Error()) {
+			return notices, err
+		}
+		creds.invalidate()
+	}
+	return notices, fmt.Errorf("credentials rejected after token refresh")
+}
+
+// uploadFileNative uploads one file through the circuit breaker and logs the attempt. It
+// returns the notices of the index response.
+func (p *PyPIPlugin) uploadFileNative(ctx context.Context, cfg Config, client *http.Client, session *publishSession, file string) ([]indexNotice, error) {
+	breaker := session.breaker
+	if err := breaker.allow(cfg.Repository); err != nil {
+		return nil, err
+	}
+	dist, err := distributionForFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Attestations {
+		dist.AttestationPath = attestationPath(file)
+	}
+	if cfg.Sign {
+		dist.SignaturePath = signatureFilePath(file)
+	}
+
+	start := time.Now()
+	uploader := newNativeUploader(client, cfg)
+	size, err := uploader.upload(ctx, dist)
+	fields := map[string]any{
+		"file":        filepath.Base(file),
+		"bytes":       size,
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	session.log.addSecrets(cfg)
+	if err != nil {
+		fields["error"] = err.Error()
+		session.log.event(cfg, "upload", fields)
+		breaker.record(cfg.Repository, err.Error(), err)
+		return uploader.notices, err
+	}
+	session.log.event(cfg, "upload", fields)
+	breaker.record(cfg.Repository, "", nil)
+	return uploader.notices, nil
+}
+
+// isAlreadyExists reports whether an upload error means the file is already on the index,
+// matching the responses twine's --skip-existing recognizes.
+func isAlreadyExists(msg string) bool {
+	return strings.Contains(msg, "409 Conflict") || strings.Contains(msg, "already exists")
+}
+
+// uploadFileWithToken uploads a single file, retrying once with a fresh token if the index
+// rejects the current one.
+func (p *PyPIPlugin) uploadFileWithToken(ctx context.Context, cfg Config, executor CommandExecutor, creds *refreshingCredentials, file string) ([]byte, error) {
+	var out []byte
+	for attempt := 0; attempt < 2; attempt++ {
+		cred, err := creds.get(ctx)
+		if err != nil {
+			return out, err
+		}
+
+		fileCfg := cfg
+		fileCfg.Username = cred.Username
+		fileCfg.Password = cred.Password
+
+		out, err = p.runTwine(ctx, fileCfg, executor, []string{file})
+		if err == nil || !isAuthFailure(string(out)) {
+			return out, err
+		}
+		creds.invalidate()
+	}
+	return out, fmt.Errorf("credentials rejected after token refresh")
+}
+
+// groupByCredentials assigns each file to the first credential override whose pattern matches
+// its base name, keeping the remaining files in a default group. Groups keep config order,
+// with the default group first.
+func groupByCredentials(overrides []CredentialOverride, files []string) []uploadGroup {
+	defaultGroup := uploadGroup{}
+	overrideGroups := make([]uploadGroup, len(overrides))
+	for i := range overrides {
+		overrideGroups[i].override = &overrides[i]
+	}
+
+	for _, file := range files {
+		idx := matchCredentialOverride(overrides, filepath.Base(file))
+		if idx < 0 {
+			defaultGroup.files = append(defaultGroup.files, file)
+		} else {
+			overrideGroups[idx].files = append(overrideGroups[idx].files, file)
+		}
+	}
+
+	var groups []uploadGroup
+	if len(defaultGroup.files) > 0 {
+		groups = append(groups, defaultGroup)
+	}
+	for _, g := range overrideGroups {
+		if len(g.files) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// uploadGroupOutputs describes which credentials each file was uploaded with.
+func uploadGroupOutputs(groups []uploadGroup) []map[string]any {
+	out := make([]map[string]any, 0, len(groups))
+	for _, g := range groups {
+		names := make([]string, len(g.files))
+		for i, f := range g.files {
+			names[i] = filepath.Base(f)
+		}
+		out = append(out, map[string]any{
+			"credentials": g.label(),
+			"files":       names,
+		})
+	}
+	return out
+}
+
+// tokenUsername returns the username paired with command-issued tokens.
+func tokenUsername(cfg Config) string {
+	if cfg.Username != "" {
+		return cfg.Username
+	}
+	return defaultTokenUsername
+}
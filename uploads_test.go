@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// writeDistFiles switches to a temp working directory, creates the named files in its dist
+// directory, and returns the relative "dist/*" glob. The working directory is restored on cleanup.
+func writeDistFiles(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	if err := os.Mkdir("dist", 0o750); err != nil {
+		t.Fatalf("failed to create dist: %v", err)
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join("dist", name), []byte(name), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return "dist/*"
+}
+
+// writeReleaseDists switches to a temp working directory like writeDistFiles, writes a wheel
+// and an sdist of mypkg 1.0.0 to its dist directory, and returns their SHA-256 digests by file
+// name.
+func writeReleaseDists(t *testing.T) map[string]string {
+	t.Helper()
+	writeDistFiles(t)
+	wheel := filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl")
+	writeTestWheel(t, wheel, map[string]string{
+		"mypkg-1.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	sdist := filepath.Join("dist", "mypkg-1.0.0.tar.gz")
+	writeTestSdist(t, sdist, map[string]string{
+		"mypkg-1.0.0/PKG-INFO": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\n",
+	})
+	digests := map[string]string{}
+	for _, f := range []string{wheel, sdist} {
+		_, digest, _, err := fileDigests(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		digests[filepath.Base(f)] = digest
+	}
+	return digests
+}
+
+func TestRunTwineUploadsWithTokenCommand(t *testing.T) {
+	distPath := writeDistFiles(t, "pkg-1.0.0.tar.gz", "pkg-1.0.0-py3-none-any.whl")
+
+	tokenCalls := 0
+	var twineCalls [][]string
+	mockExecutor := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name == "get-token" {
+				tokenCalls++
+				return []byte("short-lived-token"), nil
+			}
+			twineCalls = append(twineCalls, args)
+			return []byte("Uploading " + args[len(args)-1] + "\n"), nil
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor}
+
+	cfg := p.parseConfig(map[string]any{
+		"repository":    "http://localhost:8080/",
+		"dist_path":     distPath,
+		"token_command": []any{"get-token"},
+	})
+
+	run, err := p.runTwineUploads(context.Background(), cfg, mockExecutor, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(twineCalls) != 2 {
+		t.Fatalf("expected one twine call per file, got %d", len(twineCalls))
+	}
+	if tokenCalls != 1 {
+		t.Errorf("expected token to be fetched once, got %d", tokenCalls)
+	}
+	for _, call := range mockExecutor.RunCalls {
+		if call.Name != "twine" {
+			continue
+		}
+		if env := strings.Join(call.Env, " "); env != "TWINE_USERNAME=__token__ TWINE_PASSWORD=short-lived-token" {
+			t.Errorf("expected token credentials in the environment, got: %s", env)
+		}
+	}
+	if !strings.Contains(run.output, "pkg-1.0.0.tar.gz") || !strings.Contains(run.output, "py3-none-any.whl") {
+		t.Errorf("expected combined output for both files, got: %s", run.output)
+	}
+}
+
+func TestRunTwineUploadsRefreshesRejectedToken(t *testing.T) {
+	distPath := writeDistFiles(t, "pkg-1.0.0.tar.gz")
+
+	tokenCalls := 0
+	twineCalls := 0
+	mockExecutor := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name == "get-token" {
+				tokenCalls++
+				return []byte("token"), nil
+			}
+			twineCalls++
+			if twineCalls == 1 {
+				return []byte("HTTPError: 401 Unauthorized"), errors.New("exit status 1")
+			}
+			return []byte("ok"), nil
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor}
+
+	cfg := p.parseConfig(map[string]any{
+		"repository":    "http://localhost:8080/",
+		"dist_path":     distPath,
+		"token_command": []any{"get-token"},
+	})
+
+	run, err := p.runTwineUploads(context.Background(), cfg, mockExecutor, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tokenCalls != 2 || twineCalls != 2 {
+		t.Errorf("expected retry with refreshed token, got %d token calls and %d twine calls", tokenCalls, twineCalls)
+	}
+	if run.tokenRefreshes != 1 {
+		t.Errorf("expected 1 token refresh, got %d", run.tokenRefreshes)
+	}
+}
+
+func TestRunTwineUploadsNoFiles(t *testing.T) {
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"dist_path":     filepath.ToSlash(t.TempDir()) + "/*",
+		"token_command": []any{"get-token"},
+	})
+
+	_, err := p.runTwineUploads(context.Background(), cfg, &MockCommandExecutor{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "no distribution files") {
+		t.Errorf("expected no files error, got %v", err)
+	}
+}
+
+func TestValidateTokenCommandWithoutCredentials(t *testing.T) {
+	t.Setenv("PYPI_USERNAME", "")
+	t.Setenv("PYPI_PASSWORD", "")
+	p := &PyPIPlugin{}
+
+	resp, err := p.Validate(context.Background(), map[string]any{
+		"repository":     "http://localhost:8080/",
+		"token_command":  []any{"gcloud", "auth", "print-access-token"},
+		"token_lifetime": "1h",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Valid {
+		t.Errorf("expected valid config, got errors: %v", resp.Errors)
+	}
+}
+
+func TestExecuteReportsTokenRefreshes(t *testing.T) {
+	distPath := writeDistFiles(t, "pkg-1.0.0.tar.gz")
+	mockExecutor := &MockCommandExecutor{ReturnOut: []byte("token")}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"repository":    "http://localhost:8080/",
+			"dist_path":     distPath,
+			"token_command": []any{"get-token"},
+		},
+		Context: plugin.ReleaseContext{Version: "v1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got error: %s", resp.Error)
+	}
+	if resp.Outputs["token_refreshes"] != 0 {
+		t.Errorf("expected token_refreshes output 0, got %v", resp.Outputs["token_refreshes"])
+	}
+}
+
+func TestGroupByCredentials(t *testing.T) {
+	overrides := []CredentialOverride{
+		{Pattern: "*.tar.gz", Username: "__token__", Password: "sdist-token"},
+		{Pattern: "*-manylinux*.whl", Username: "__token__", Password: "native-token"},
+	}
+	files := []string{
+		"dist/pkg-1.0.0-cp312-cp312-manylinux_2_17_x86_64.whl",
+		"dist/pkg-1.0.0-py3-none-any.whl",
+		"dist/pkg-1.0.0.tar.gz",
+	}
+
+	groups := groupByCredentials(overrides, files)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d: %+v", len(groups), groups)
+	}
+
+	expected := []struct {
+		label string
+		files []string
+	}{
+		{"default", []string{"dist/pkg-1.0.0-py3-none-any.whl"}},
+		{"override:*.tar.gz", []string{"dist/pkg-1.0.0.tar.gz"}},
+		{"override:*-manylinux*.whl", []string{"dist/pkg-1.0.0-cp312-cp312-manylinux_2_17_x86_64.whl"}},
+	}
+	for i, want := range expected {
+		if groups[i].label() != want.label {
+			t.Errorf("group[%d]: expected label '%s', got '%s'", i, want.label, groups[i].label())
+		}
+		if strings.Join(groups[i].files, ",") != strings.Join(want.files, ",") {
+			t.Errorf("group[%d]: expected files %v, got %v", i, want.files, groups[i].files)
+		}
+	}
+}
+
+func TestExecuteWithCredentialOverrides(t *testing.T) {
+	distPath := writeDistFiles(t, "pkg-1.0.0.tar.gz", "pkg-1.0.0-py3-none-any.whl")
+	t.Setenv("WHEEL_TOKEN", "wheel-secret")
+
+	mockExecutor := &MockCommandExecutor{ReturnOut: []byte("ok\n")}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":   "__token__",
+			"password":   "default-secret",
+			"repository": "http://localhost:8080/",
+			"dist_path":  distPath,
+			"credential_overrides": []any{
+				map[string]any{"pattern": "*.whl", "username": "__token__", "password_env": "WHEEL_TOKEN"},
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "v1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got error: %s", resp.Error)
+	}
+
+	if len(mockExecutor.RunCalls) != 2 {
+		t.Fatalf("expected 2 twine calls, got %d", len(mockExecutor.RunCalls))
+	}
+	first, second := mockExecutor.RunCalls[0], mockExecutor.RunCalls[1]
+	if !containsString(first.Env, "TWINE_PASSWORD=default-secret") || !strings.HasSuffix(strings.Join(first.Args, " "), "pkg-1.0.0.tar.gz") {
+		t.Errorf("expected sdist with default credentials, got: %v %v", first.Env, first.Args)
+	}
+	if !containsString(second.Env, "TWINE_PASSWORD=wheel-secret") || !strings.HasSuffix(strings.Join(second.Args, " "), "py3-none-any.whl") {
+		t.Errorf("expected wheel with override credentials, got: %v %v", second.Env, second.Args)
+	}
+
+	groups, ok := resp.Outputs["upload_groups"].([]map[string]any)
+	if !ok || len(groups) != 2 {
+		t.Fatalf("expected 2 upload groups in outputs, got %v", resp.Outputs["upload_groups"])
+	}
+	if groups[1]["credentials"] != "override:*.whl" {
+		t.Errorf("unexpected group label: %v", groups[1]["credentials"])
+	}
+}
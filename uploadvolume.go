@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Upload limits of PyPI and TestPyPI. Projects can request higher limits, which are configured
+// with file_size_limit and project_size_limit.
+const (
+	pypiFileSizeLimit    = 100 << 20
+	pypiProjectSizeLimit = 10 << 30
+)
+
+// uploadVolumeWarnRatio is the share of a size limit from which uploads are warned about.
+const uploadVolumeWarnRatio = 0.8
+
+// credentialVolume is the volume uploaded with one credential to one repository in a run.
+type credentialVolume struct {
+	Repository string `json:"repository"`
+	// Credential is the username and a short SHA-256 fingerprint of the password, so tokens
+	// can be told apart without revealing them
+	Credential string `json:"credential"`
+	Files      int    `json:"files"`
+	Bytes      int64  `json:"bytes"`
+}
+
+// uploadVolume accumulates the bytes uploaded per credential and per project in a run, shared
+// by all packages of a batch, which may upload concurrently.
+type uploadVolume struct {
+	mu          sync.Mutex
+	credentials map[[2]string]*credentialVolume
+	projects    map[[2]string]int64
+}
+
+// newUploadVolume creates an empty volume tracker.
+func newUploadVolume() *uploadVolume {
+	return &uploadVolume{
+		credentials: make(map[[2]string]*credentialVolume),
+		projects:    make(map[[2]string]int64),
+	}
+}
+
+// record adds the uploaded files of project to the volume of their credentials. It returns
+// warnings for files, and for the volume of the project in this run, approaching the size
+// limits of the repository.
+func (v *uploadVolume) record(cfg Config, project string, files []string) []string {
+	fileLimit, projectLimit := sizeLimits(cfg)
+	groups := groupByCredentials(cfg.CredentialOverrides, files)
+
+	var warnings []string
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var added int64
+	for _, g := range groups {
+		id := credentialID(cfg, g.override)
+		key := [2]string{cfg.Repository, id}
+		volume, ok := v.credentials[key]
+		if !ok {
+			volume = &credentialVolume{Repository: cfg.Repository, Credential: id}
+			v.credentials[key] = volume
+		}
+		for _, f := range g.files {
+			_, _, size, err := fileDigests(f)
+			if err != nil {
+				continue
+			}
+			volume.Files++
+			volume.Bytes += size
+			added += size
+			if nearLimit(size, fileLimit) {
+				warnings = append(warnings, fmt.Sprintf("%s is %s, close to the per-file upload limit of %s on %s", filepath.Base(f), formatBytes(size), formatBytes(fileLimit), cfg.Repository))
+			}
+		}
+	}
+	if project == "" {
+		return warnings
+	}
+	key := [2]string{cfg.Repository, normalizeProjectName(project)}
+	v.projects[key] += added
+	if total := v.projects[key]; nearLimit(total, projectLimit) {
+		warnings = append(warnings, fmt.Sprintf("%s of %s uploaded in this run, close to the project size limit of %s on %s", formatBytes(total), project, formatBytes(projectLimit), cfg.Repository))
+	}
+	return warnings
+}
+
+// snapshot returns the volume per credential, ordered by repository and credential.
+func (v *uploadVolume) snapshot() []credentialVolume {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	out := make([]credentialVolume, 0, len(v.credentials))
+	for _, volume := range v.credentials {
+		out = append(out, *volume)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Repository != out[j].Repository {
+			return out[i].Repository < out[j].Repository
+		}
+		return out[i].Credential < out[j].Credential
+	})
+	return out
+}
+
+// sizeLimits returns the per-file and per-project size limits of the repository, or 0 when
+// they are unknown.
+func sizeLimits(cfg Config) (file, project int64) {
+	file, project = cfg.FileSizeLimit, cfg.ProjectSizeLimit
+	if isPyPIRepository(cfg.Repository) {
+		if file == 0 {
+			file = pypiFileSizeLimit
+		}
+		if project == 0 {
+			project = pypiProjectSizeLimit
+		}
+	}
+	return file, project
+}
+
+// nearLimit reports whether size reaches uploadVolumeWarnRatio of a known limit.
+func nearLimit(size, limit int64) bool {
+	return limit > 0 && float64(size) >= float64(limit)*uploadVolumeWarnRatio
+}
+
+// credentialID identifies the credentials of an upload group in volume outputs. Tokens issued
+// by a token source rotate during the run and are counted together.
+func credentialID(cfg Config, override *CredentialOverride) string {
+	username, password := cfg.Username, cfg.Password
+	switch {
+	case override != nil:
+		username, password = override.Username, override.resolvedPassword()
+	case usesTokenSource(cfg):
+		return tokenUsername(cfg) + " (issued tokens)"
+	}
+	if password == "" {
+		return username
+	}
+	sum := sha256.Sum256([]byte(password))
+	return username + " sha256:" + hex.EncodeToString(sum[:4])
+}
+
+// formatBytes formats a size with a binary unit, such as 95.0 MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// validateSizeLimits validates file_size_limit and project_size_limit.
+func validateSizeLimits(cfg Config) error {
+	if cfg.FileSizeLimit < 0 || cfg.ProjectSizeLimit < 0 {
+		return fmt.Errorf("file_size_limit and project_size_limit must not be negative")
+	}
+	return nil
+}
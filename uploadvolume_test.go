@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestUploadVolumeRecord(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	wheel := filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl")
+	if err := os.WriteFile(wheel, make([]byte, 900), 0o600); err != nil {
+		t.Fatalf("failed to write wheel: %v", err)
+	}
+	sdist := filepath.Join("dist", "mypkg-1.0.0.tar.gz")
+
+	cfg := Config{
+		Repository:          "https://nexus.example.com/repository/pypi/",
+		Username:            "ci",
+		Password:            "pypi-secret",
+		FileSizeLimit:       1000,
+		ProjectSizeLimit:    20000,
+		CredentialOverrides: []CredentialOverride{{Pattern: "*.whl", Username: "__token__", Password: "wheel-token"}},
+	}
+
+	volume := newUploadVolume()
+	var wg sync.WaitGroup
+	warnings := make([][]string, 20)
+	for i := range warnings {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			warnings[i] = volume.record(cfg, "mypkg", []string{sdist, wheel})
+		}(i)
+	}
+	wg.Wait()
+
+	got := volume.snapshot()
+	if len(got) != 2 {
+		t.Fatalf("expected one volume per credential, got %+v", got)
+	}
+	for _, v := range got {
+		if strings.Contains(v.Credential, "secret") || strings.Contains(v.Credential, "wheel-token") {
+			t.Errorf("the credential must not reveal the password, got %q", v.Credential)
+		}
+	}
+	if got[0].Credential != credentialID(cfg, &cfg.CredentialOverrides[0]) || got[0].Files != 20 || got[0].Bytes != 20*900 {
+		t.Errorf("unexpected wheel volume %+v", got[0])
+	}
+	if got[1].Credential != credentialID(cfg, nil) || got[1].Files != 20 || got[1].Bytes != int64(20*len("mypkg-1.0.0.tar.gz")) {
+		t.Errorf("unexpected sdist volume %+v", got[1])
+	}
+
+	// Every record warns about the wheel; the project volume crosses 80% in the last records
+	var fileWarnings, projectWarnings int
+	for _, ws := range warnings {
+		for _, w := range ws {
+			switch {
+			case strings.HasPrefix(w, "mypkg-1.0.0-py3-none-any.whl is 900 B, close to the per-file upload limit"):
+				fileWarnings++
+			case strings.Contains(w, "close to the project size limit of 19.5 KiB"):
+				projectWarnings++
+			default:
+				t.Errorf("unexpected warning %q", w)
+			}
+		}
+	}
+	if fileWarnings != 20 || projectWarnings == 0 || projectWarnings == 20 {
+		t.Errorf("unexpected warnings: %d file, %d project", fileWarnings, projectWarnings)
+	}
+
+	if warnings := newUploadVolume().record(Config{Repository: "https://nexus.example.com/repository/pypi/"}, "mypkg", []string{wheel}); len(warnings) != 0 {
+		t.Errorf("expected no warnings without known limits, got %v", warnings)
+	}
+}
+
+func TestSizeLimits(t *testing.T) {
+	if file, project := sizeLimits(Config{Repository: "https://upload.pypi.org/legacy/"}); file != pypiFileSizeLimit || project != pypiProjectSizeLimit {
+		t.Errorf("expected the PyPI limits, got %d %d", file, project)
+	}
+	if file, _ := sizeLimits(Config{Repository: "https://upload.pypi.org/legacy/", FileSizeLimit: 200 << 20}); file != 200<<20 {
+		t.Errorf("expected the configured limit, got %d", file)
+	}
+	if file, project := sizeLimits(Config{Repository: "http://localhost:8080/"}); file != 0 || project != 0 {
+		t.Errorf("expected no limits, got %d %d", file, project)
+	}
+	for n, want := range map[int64]string{512: "512 B", 95 << 20: "95.0 MiB", 10 << 30: "10.0 GiB"} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestExecuteBatchUploadVolume(t *testing.T) {
+	packages := writeBatchDists(t, "core", "cli", "extras")
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("Uploading " + filepath.Base(args[len(args)-1])), nil
+		},
+	}}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":          "__token__",
+			"password":          "pypi-token",
+			"repository":        "http://localhost:8080/",
+			"batch_concurrency": 3,
+			"packages":          packages,
+		},
+		Context: plugin.ReleaseContext{Version: "1.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	volume, _ := resp.Outputs["upload_volume"].([]credentialVolume)
+	if !resp.Success || len(volume) != 1 {
+		t.Fatalf("expected the packages to share one credential volume, got %+v", resp)
+	}
+	want := credentialVolume{Repository: "http://localhost:8080/", Credential: credentialID(Config{Username: "__token__", Password: "pypi-token"}, nil), Files: 3, Bytes: int64(len("core") + len("cli") + len("extras"))}
+	if volume[0] != want {
+		t.Errorf("upload_volume = %+v, want %+v", volume[0], want)
+	}
+	if !strings.HasPrefix(volume[0].Credential, "__token__ sha256:") {
+		t.Errorf("unexpected credential %q", volume[0].Credential)
+	}
+}
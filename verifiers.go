@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// Verifier types of the verifiers option.
+const (
+	verifierAvailability = "availability"
+	verifierPipInstall   = "pip_install"
+	verifierDigest       = "digest"
+	verifierCommand      = "command"
+)
+
+// verifyCommandName labels the verifier of the verify_command option.
+const verifyCommandName = "verify_command"
+
+// verifierTypes lists the accepted verifier types.
+var verifierTypes = []string{verifierAvailability, verifierPipInstall, verifierDigest, verifierCommand}
+
+// verifierFailureModes lists the accepted on_failure values of a verifier.
+var verifierFailureModes = []string{checkWarn, checkFail}
+
+// VerifierConfig configures one check of the verifiers list, run in order after the upload.
+type VerifierConfig struct {
+	// Type is availability, pip_install, digest or command
+	Type string
+	// Name labels the verifier in outputs and errors (defaults to Type)
+	Name string
+	// OnFailure is warn or fail (default fail)
+	OnFailure string
+	// Command is the command of a command verifier; {project}, {version}, {repository},
+	// {index_url} and {dist_path} expand within its arguments
+	Command []string
+}
+
+// label returns the name of the verifier in outputs and errors.
+func (v VerifierConfig) label() string {
+	if v.Name != "" {
+		return v.Name
+	}
+	return v.Type
+}
+
+// publishedRelease is the release a Verifier checks.
+type publishedRelease struct {
+	Project string
+	Version string
+	// Files are the uploaded distributions
+	Files []string
+}
+
+// Verifier checks one aspect of a release after it is uploaded, so that teams can compose
+// their own definition of a successful publish. The returned report is included in the
+// verifications output even when the check fails.
+type Verifier interface {
+	Verify(ctx context.Context, release publishedRelease) (report any, err error)
+}
+
+// verificationResult is the outcome of one verifier, reported in outputs.
+type verificationResult struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Status is passed or failed
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	Report     any    `json:"report,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// availabilityVerifier waits until the index lists every uploaded file.
+type availabilityVerifier struct {
+	p   *PyPIPlugin
+	cfg Config
+}
+
+// Verify implements Verifier.
+func (v *availabilityVerifier) Verify(ctx context.Context, release publishedRelease) (any, error) {
+	return v.p.awaitAvailability(ctx, v.cfg, release.Files)
+}
+
+// pipInstallVerifier installs the release into a throwaway virtualenv like the smoke test.
+type pipInstallVerifier struct {
+	p   *PyPIPlugin
+	cfg Config
+}
+
+// Verify implements Verifier.
+func (v *pipInstallVerifier) Verify(ctx context.Context, release publishedRelease) (any, error) {
+	report := v.p.runSmokeTest(ctx, v.cfg, release.Files, nil)
+	if report.Status != "passed" {
+		return report, fmt.Errorf("install of %s %s failed at the %s step: %s", report.Project, report.Version, report.Step, report.Error)
+	}
+	return report, nil
+}
+
+// digestVerifier compares the SHA-256 of the uploaded files with the digests on the index.
+type digestVerifier struct {
+	p   *PyPIPlugin
+	cfg Config
+}
+
+// Verify implements Verifier.
+func (v *digestVerifier) Verify(ctx context.Context, release publishedRelease) (any, error) {
+	published, err := v.p.publishedFiles(ctx, v.cfg, release.Project, release.Version)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s %s from the index: %w", release.Project, release.Version, err)
+	}
+	comparison, err := compareDigests(release.Files, published)
+	if err != nil {
+		return nil, err
+	}
+	if problems := comparison.problems(); len(problems) > 0 {
+		return comparison, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return comparison, nil
+}
+
+// commandVerifier runs a team's own check, which passes when the command exits with 0. The
+// release is described to it in RELEASE_* environment variables.
+type commandVerifier struct {
+	p       *PyPIPlugin
+	cfg     Config
+	command []string
+}
+
+// commandVerifierReport is the run of a command verifier.
+type commandVerifierReport struct {
+	Command []string `json:"command"`
+	Output  string   `json:"output,omitempty"`
+}
+
+// Verify implements Verifier.
+func (v *commandVerifier) Verify(ctx context.Context, release publishedRelease) (any, error) {
+	argv, err := renderCommandTemplate("verifiers.command", v.command, verifierCommandVars(v.cfg, release))
+	if err != nil {
+		return nil, err
+	}
+	output, runErr := v.p.getExecutor().RunWithEnv(ctx, releaseEnv(v.cfg, release), argv[0], argv[1:]...)
+	report := &commandVerifierReport{Command: argv}
+	report.Output, _ = truncateOutput(strings.TrimSpace(string(output)), v.cfg.MaxOutputBytes)
+	if runErr != nil {
+		return report, fmt.Errorf("%s failed: %w", argv[0], runErr)
+	}
+	return report, nil
+}
+
+// verifierCommandVars returns the variables of a command verifier.
+func verifierCommandVars(cfg Config, release publishedRelease) map[string]string {
+	return map[string]string{
+		"project":    release.Project,
+		"version":    release.Version,
+		"repository": cfg.Repository,
+		"index_url":  cfg.IndexURL,
+		"dist_path":  cfg.DistPath,
+	}
+}
+
+// releaseEnv returns the environment describing the release to command verifiers. URLs the
+// index does not have, such as the release page of a private index, are empty.
+func releaseEnv(cfg Config, release publishedRelease) []string {
+	var projectURL string
+	if cfg.IndexURL != "" {
+		projectURL, _ = projectPageURL(cfg.IndexURL, release.Project)
+	}
+	files := make([]string, 0, len(release.Files))
+	for _, f := range release.Files {
+		files = append(files, filepath.Base(f))
+	}
+	return []string{
+		"RELEASE_PROJECT=" + release.Project,
+		"RELEASE_VERSION=" + release.Version,
+		"RELEASE_REPOSITORY=" + cfg.Repository,
+		"RELEASE_INDEX_URL=" + cfg.IndexURL,
+		"RELEASE_PROJECT_URL=" + projectURL,
+		"RELEASE_URL=" + releasePageURL(cfg.Repository, release.Project, release.Version),
+		"RELEASE_FILES=" + strings.Join(files, " "),
+	}
+}
+
+// configuredVerifiers returns the verifiers list followed by the verifier of verify_command.
+func configuredVerifiers(cfg Config) []VerifierConfig {
+	if len(cfg.VerifyCommand) == 0 {
+		return cfg.Verifiers
+	}
+	verifiers := append([]VerifierConfig{}, cfg.Verifiers...)
+	return append(verifiers, VerifierConfig{Type: verifierCommand, Name: verifyCommandName, OnFailure: checkFail, Command: cfg.VerifyCommand})
+}
+
+// newVerifier returns the Verifier of a verifiers entry.
+func (p *PyPIPlugin) newVerifier(cfg Config, vc VerifierConfig) (Verifier, error) {
+	switch vc.Type {
+	case verifierAvailability:
+		return &availabilityVerifier{p: p, cfg: cfg}, nil
+	case verifierPipInstall:
+		return &pipInstallVerifier{p: p, cfg: cfg}, nil
+	case verifierDigest:
+		return &digestVerifier{p: p, cfg: cfg}, nil
+	case verifierCommand:
+		return &commandVerifier{p: p, cfg: cfg, command: vc.Command}, nil
+	}
+	return nil, fmt.Errorf("unknown verifier type %q", vc.Type)
+}
+
+// runVerifiers runs the configured verifiers in order against the uploaded files. All of them
+// run, so a failure does not hide the outcome of later checks; the error names the failed
+// verifiers whose on_failure is fail, and the warnings those set to warn.
+func (p *PyPIPlugin) runVerifiers(ctx context.Context, cfg Config, files []string) ([]verificationResult, []string, error) {
+	project, version := distProjectVersion(files)
+	release := publishedRelease{Project: project, Version: version, Files: files}
+	verifiers := configuredVerifiers(cfg)
+	results := make([]verificationResult, 0, len(verifiers))
+	var failed, warnings []string
+	for _, vc := range verifiers {
+		start := time.Now()
+		result := verificationResult{Name: vc.label(), Type: vc.Type, Status: "passed"}
+		verifier, err := p.newVerifier(cfg, vc)
+		if err == nil {
+			result.Report, err = verifier.Verify(ctx, release)
+		}
+		result.DurationMs = time.Since(start).Milliseconds()
+		if err != nil {
+			result.Status, result.Error = "failed", err.Error()
+			msg := fmt.Sprintf("verifier %s failed: %v", result.Name, err)
+			if vc.OnFailure == checkWarn {
+				warnings = append(warnings, msg)
+			} else {
+				failed = append(failed, msg)
+			}
+		}
+		results = append(results, result)
+	}
+	if len(failed) > 0 {
+		return results, warnings, fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+	return results, warnings, nil
+}
+
+// parseVerifiers parses the verifiers list.
+func parseVerifiers(raw any) []VerifierConfig {
+	items, ok := raw.([]any)
+	if !ok {
+		return nil
+	}
+
+	verifiers := make([]VerifierConfig, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		v := VerifierConfig{OnFailure: checkFail}
+		v.Type, _ = m["type"].(string)
+		v.Name, _ = m["name"].(string)
+		if mode, ok := m["on_failure"].(string); ok && mode != "" {
+			v.OnFailure = mode
+		}
+		v.Command = helpers.NewConfigParser(m).GetStringSlice("command", nil)
+		verifiers = append(verifiers, v)
+	}
+	return verifiers
+}
+
+// validateVerifiers validates the verifiers list and verify_command.
+func validateVerifiers(cfg Config) error {
+	if len(cfg.VerifyCommand) > 0 {
+		if _, err := renderCommandTemplate("verify_command", cfg.VerifyCommand, verifierCommandVars(cfg, publishedRelease{})); err != nil {
+			return err
+		}
+	}
+	for i, v := range cfg.Verifiers {
+		field := fmt.Sprintf("verifiers[%d]", i)
+		if !containsString(verifierTypes, v.Type) {
+			return fmt.Errorf("%s.type must be one of: %s", field, strings.Join(verifierTypes, ", "))
+		}
+		if !containsString(verifierFailureModes, v.OnFailure) {
+			return fmt.Errorf("%s.on_failure must be one of: %s", field, strings.Join(verifierFailureModes, ", "))
+		}
+		if v.Type != verifierCommand && len(v.Command) > 0 {
+			return fmt.Errorf("%s.command is only used by command verifiers", field)
+		}
+		switch v.Type {
+		case verifierAvailability, verifierPipInstall:
+			if cfg.IndexURL == "" {
+				return fmt.Errorf("%s needs index_url for repositories other than PyPI and TestPyPI", field)
+			}
+			if cfg.AvailabilityTimeout <= 0 {
+				return fmt.Errorf("availability_timeout must be positive")
+			}
+			if v.Type == verifierPipInstall && cfg.SmokeTestPython == "" {
+				return fmt.Errorf("smoke_test_python must not be empty")
+			}
+		case verifierDigest:
+			if cfg.JSONAPIURL == "" && cfg.IndexURL == "" {
+				return fmt.Errorf("%s needs json_api_url or index_url for repositories other than PyPI and TestPyPI", field)
+			}
+		case verifierCommand:
+			if len(v.Command) == 0 {
+				return fmt.Errorf("%s.command is required for command verifiers", field)
+			}
+			if _, err := renderCommandTemplate("verifiers.command", v.Command, verifierCommandVars(cfg, publishedRelease{})); err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+		}
+	}
+	return nil
+}
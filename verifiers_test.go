@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestExecuteVerifiers(t *testing.T) {
+	digests := writeReleaseDists(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, digest := range digests {
+			_, _ = fmt.Fprintf(w, `<a href="/files/%s#sha256=%s">%s</a>`, name, digest, name)
+		}
+	}))
+	defer server.Close()
+
+	execute := func(onFailure string, checkErr error) (*plugin.ExecuteResponse, *MockCommandExecutor) {
+		t.Helper()
+		executor := &MockCommandExecutor{
+			RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				if name == "./scripts/check-release.sh" {
+					return []byte("mypkg 1.0.0 is missing from the mirror"), checkErr
+				}
+				return []byte("ok"), nil
+			},
+		}
+		p := &PyPIPlugin{cmdExecutor: executor}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"username":                   "__token__",
+				"password":                   "pypi-token",
+				"repository":                 "http://localhost:8080/",
+				"index_url":                  server.URL + "/simple/",
+				"availability_poll_interval": "10ms",
+				"verifiers": []any{
+					map[string]any{"type": "availability"},
+					map[string]any{"type": "digest"},
+					map[string]any{"type": "command", "name": "mirror", "on_failure": onFailure, "command": []any{"./scripts/check-release.sh", "{project}=={version}"}},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, executor
+	}
+
+	resp, executor := execute(checkFail, nil)
+	if !resp.Success {
+		t.Fatalf("expected success, got %+v", resp)
+	}
+	results, _ := resp.Outputs["verifications"].([]verificationResult)
+	if len(results) != 3 || results[0].Status != "passed" || results[1].Status != "passed" || results[2].Name != "mirror" {
+		t.Fatalf("unexpected verifications %+v", results)
+	}
+	if comparison, _ := results[1].Report.(*digestComparison); comparison == nil || len(comparison.Files) != 2 || comparison.Files[0].Status != verifyMatch {
+		t.Errorf("unexpected digest report %+v", results[1].Report)
+	}
+	last := executor.RunCalls[len(executor.RunCalls)-1]
+	if strings.Join(last.Args, " ") != "mypkg==1.0.0" {
+		t.Errorf("expected the command variables to be expanded, got %+v", last)
+	}
+
+	resp, _ = execute(checkWarn, errors.New("exit status 1"))
+	warnings, _ := resp.Outputs["warnings"].([]string)
+	if !resp.Success || len(warnings) == 0 || !strings.Contains(strings.Join(warnings, " "), "verifier mirror failed") {
+		t.Errorf("expected a warning about the failed verifier, got %+v", resp)
+	}
+
+	resp, _ = execute(checkFail, errors.New("exit status 1"))
+	if resp.Success || !strings.Contains(resp.Error, "uploaded, but verifier mirror failed: ./scripts/check-release.sh failed") {
+		t.Errorf("expected the failed verifier to fail the publish, got %+v", resp)
+	}
+	results, _ = resp.Outputs["verifications"].([]verificationResult)
+	if report, _ := results[2].Report.(*commandVerifierReport); report == nil || !strings.Contains(report.Output, "missing from the mirror") {
+		t.Errorf("expected the command output in the report, got %+v", results[2])
+	}
+}
+
+func TestDigestVerifierMismatch(t *testing.T) {
+	digests := writeReleaseDists(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `<a href="/files/mypkg-1.0.0.tar.gz#sha256=%s">mypkg-1.0.0.tar.gz</a>`, strings.Repeat("0", 64))
+	}))
+	defer server.Close()
+
+	cfg := Config{IndexURL: server.URL + "/simple/"}
+	verifier, err := (&PyPIPlugin{}).newVerifier(cfg, VerifierConfig{Type: verifierDigest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []string{"dist/mypkg-1.0.0-py3-none-any.whl", "dist/mypkg-1.0.0.tar.gz"}
+	report, err := verifier.Verify(context.Background(), publishedRelease{Project: "mypkg", Version: "1.0.0", Files: files})
+	want := "1 file(s) not published: mypkg-1.0.0-py3-none-any.whl; 1 file(s) published with a different SHA256: mypkg-1.0.0.tar.gz"
+	if err == nil || err.Error() != want {
+		t.Errorf("Verify() error = %v, want %s", err, want)
+	}
+	if comparison, _ := report.(*digestComparison); comparison == nil || comparison.Files[1].LocalSHA256 != digests["mypkg-1.0.0.tar.gz"] {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestValidateVerifiers(t *testing.T) {
+	base := Config{IndexURL: "https://pypi.org/simple/", AvailabilityTimeout: defaultAvailabilityTimeout, SmokeTestPython: defaultSmokeTestPython}
+	tests := []struct {
+		name      string
+		verifiers []VerifierConfig
+		wantErr   bool
+	}{
+		{"none", nil, false},
+		{"all types", []VerifierConfig{
+			{Type: verifierAvailability, OnFailure: checkFail},
+			{Type: verifierPipInstall, OnFailure: checkWarn},
+			{Type: verifierDigest, OnFailure: checkFail},
+			{Type: verifierCommand, OnFailure: checkFail, Command: []string{"check", "{project}", "{version}", "{index_url}"}},
+		}, false},
+		{"unknown type", []VerifierConfig{{Type: "http", OnFailure: checkFail}}, true},
+		{"off", []VerifierConfig{{Type: verifierDigest, OnFailure: checkOff}}, true},
+		{"command without command", []VerifierConfig{{Type: verifierCommand, OnFailure: checkFail}}, true},
+		{"unknown variable", []VerifierConfig{{Type: verifierCommand, OnFailure: checkFail, Command: []string{"check", "{password}"}}}, true},
+		{"command on digest", []VerifierConfig{{Type: verifierDigest, OnFailure: checkFail, Command: []string{"check"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.Verifiers = tt.verifiers
+			if err := validateVerifiers(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateVerifiers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := validateVerifiers(Config{Verifiers: []VerifierConfig{{Type: verifierAvailability, OnFailure: checkFail}}, AvailabilityTimeout: defaultAvailabilityTimeout}); err == nil {
+		t.Error("expected an availability verifier without index_url to be rejected")
+	}
+	if err := validateVerifiers(Config{VerifyCommand: []string{"./verify.sh", "{release}"}}); err == nil || !strings.Contains(err.Error(), "unknown verify_command variable {release}") {
+		t.Errorf("expected the unknown verify_command variable to be rejected, got %v", err)
+	}
+}
+
+func TestExecuteVerifyCommand(t *testing.T) {
+	writeReleaseDists(t)
+	execute := func(checkErr error) (*plugin.ExecuteResponse, *MockCommandExecutor) {
+		t.Helper()
+		executor := &MockCommandExecutor{
+			RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				if name == "./verify.sh" {
+					return []byte("release not found in the artifact catalog"), checkErr
+				}
+				return []byte("ok"), nil
+			},
+		}
+		p := &PyPIPlugin{cmdExecutor: executor}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"username":       "__token__",
+				"password":       "pypi-token",
+				"repository":     "http://localhost:8080/",
+				"verify_command": []any{"./verify.sh", "--version", "{version}"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, executor
+	}
+
+	resp, executor := execute(nil)
+	if !resp.Success {
+		t.Fatalf("expected success, got %+v", resp)
+	}
+	call := executor.RunCalls[len(executor.RunCalls)-1]
+	if call.Name != "./verify.sh" || strings.Join(call.Args, " ") != "--version 1.0.0" {
+		t.Errorf("unexpected verify command %+v", call)
+	}
+	for _, want := range []string{"RELEASE_PROJECT=mypkg", "RELEASE_VERSION=1.0.0", "RELEASE_REPOSITORY=http://localhost:8080/", "RELEASE_FILES=mypkg-1.0.0-py3-none-any.whl mypkg-1.0.0.tar.gz"} {
+		if !containsString(call.Env, want) {
+			t.Errorf("expected %s in the environment, got %v", want, call.Env)
+		}
+	}
+	if results, _ := resp.Outputs["verifications"].([]verificationResult); len(results) != 1 || results[0].Name != verifyCommandName || results[0].Status != "passed" {
+		t.Errorf("unexpected verifications %+v", resp.Outputs["verifications"])
+	}
+
+	resp, _ = execute(errors.New("exit status 3"))
+	if resp.Success || !strings.Contains(resp.Error, "uploaded, but verifier verify_command failed: ./verify.sh failed: exit status 3") {
+		t.Errorf("expected the non-zero exit to fail the publish, got %+v", resp)
+	}
+}
+
+func TestReleaseEnv(t *testing.T) {
+	cfg := Config{Repository: "https://upload.pypi.org/legacy/", IndexURL: "https://pypi.org/simple/"}
+	env := releaseEnv(cfg, publishedRelease{Project: "My_Pkg", Version: "2.0.0", Files: []string{"dist/my_pkg-2.0.0.tar.gz"}})
+	want := []string{
+		"RELEASE_PROJECT=My_Pkg",
+		"RELEASE_VERSION=2.0.0",
+		"RELEASE_REPOSITORY=https://upload.pypi.org/legacy/",
+		"RELEASE_INDEX_URL=https://pypi.org/simple/",
+		"RELEASE_PROJECT_URL=https://pypi.org/simple/my-pkg/",
+		"RELEASE_URL=https://pypi.org/project/my-pkg/2.0.0/",
+		"RELEASE_FILES=my_pkg-2.0.0.tar.gz",
+	}
+	if strings.Join(env, "\n") != strings.Join(want, "\n") {
+		t.Errorf("releaseEnv() = %v, want %v", env, want)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Results of verifying a distribution against the index.
+const (
+	verifyMatch    = "verified"
+	verifyMissing  = "missing"
+	verifyMismatch = "mismatch"
+	// verifyNoDigest means the file is published but the index reports no SHA-256 to compare
+	verifyNoDigest = "no_digest"
+)
+
+// verifiedFile is the verification of one local distribution, reported in outputs.
+type verifiedFile struct {
+	File        string `json:"file"`
+	LocalSHA256 string `json:"local_sha256"`
+	IndexSHA256 string `json:"index_sha256,omitempty"`
+	Status      string `json:"status"`
+}
+
+// verifyRelease checks, without uploading anything, that the release version is published on
+// the index and that every dist_path distribution is among its files with the same SHA-256.
+// Files the index reports no digest for are only warned about.
+func (p *PyPIPlugin) verifyRelease(ctx context.Context, cfg Config, releaseCtxVersion string) *plugin.ExecuteResponse {
+	fail := func(format string, args ...any) *plugin.ExecuteResponse {
+		return &plugin.ExecuteResponse{Success: false, Error: "verification failed: " + fmt.Sprintf(format, args...)}
+	}
+	version, err := releaseVersion(cfg, releaseCtxVersion)
+	if err != nil {
+		return fail("%v", err)
+	}
+	files, err := expandDistGlob(cfg.DistPath)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if len(files) == 0 {
+		return fail("no distributions in %s", cfg.DistPath)
+	}
+	project, distVersion := distProjectVersion(files)
+	if project == "" {
+		return fail("no distribution with readable metadata in %s", cfg.DistPath)
+	}
+	if version == "" {
+		version = distVersion
+	}
+	for _, f := range files {
+		if meta, err := readDistMetadata(f); err == nil && normalizeVersion(meta.Version) != normalizeVersion(version) {
+			return fail("%s is version %s, not %s", f, meta.Version, version)
+		}
+	}
+
+	published, err := p.publishedFiles(ctx, cfg, project, version)
+	if err != nil {
+		return fail("cannot read %s %s from the index: %v", project, version, err)
+	}
+	outputs := map[string]any{
+		"project":      project,
+		"version":      version,
+		"repository":   cfg.Repository,
+		"plugin_build": currentBuild().String(),
+	}
+	if len(published) == 0 {
+		resp := fail("%s %s is not published on %s", project, version, cfg.Repository)
+		resp.Outputs = outputs
+		return resp
+	}
+
+	comparison, err := compareDigests(files, published)
+	if err != nil {
+		return fail("%v", err)
+	}
+	outputs["verified_files"] = comparison.Files
+	outputs["index_only_files"] = comparison.IndexOnly
+	if len(comparison.Warnings) > 0 {
+		outputs["warnings"] = comparison.Warnings
+	}
+	if problems := comparison.problems(); len(problems) > 0 {
+		resp := fail("%s %s on %s: %s", project, version, cfg.Repository, strings.Join(problems, "; "))
+		resp.Outputs = outputs
+		return resp
+	}
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Verified %d file(s) of %s %s on %s", len(files), project, version, cfg.Repository),
+		Outputs: outputs,
+	}
+}
+
+// digestComparison is the comparison of local distributions with the files of their release
+// on the index.
+type digestComparison struct {
+	Files []verifiedFile `json:"files"`
+	// IndexOnly lists published files that are not local, such as wheels built on other
+	// platforms
+	IndexOnly  []string `json:"index_only"`
+	Missing    []string `json:"missing,omitempty"`
+	Mismatched []string `json:"mismatched,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// compareDigests compares the SHA-256 of each local distribution with the digest published
+// for it. Files the index reports no digest for are only warned about.
+func compareDigests(files []string, published map[string]string) (*digestComparison, error) {
+	comparison := &digestComparison{IndexOnly: []string{}}
+	local := map[string]bool{}
+	for _, f := range files {
+		name := filepath.Base(f)
+		local[name] = true
+		_, digest, _, err := fileDigests(f)
+		if err != nil {
+			return nil, err
+		}
+		file := verifiedFile{File: name, LocalSHA256: digest, Status: verifyMatch}
+		indexDigest, ok := published[name]
+		switch {
+		case !ok:
+			file.Status = verifyMissing
+			comparison.Missing = append(comparison.Missing, name)
+		case indexDigest == "":
+			file.Status = verifyNoDigest
+			comparison.Warnings = append(comparison.Warnings, fmt.Sprintf("%s is published, but the index reports no SHA256 to verify it against", name))
+		case indexDigest != digest:
+			file.IndexSHA256, file.Status = indexDigest, verifyMismatch
+			comparison.Mismatched = append(comparison.Mismatched, name)
+		default:
+			file.IndexSHA256 = indexDigest
+		}
+		comparison.Files = append(comparison.Files, file)
+	}
+	for _, name := range sortedKeys(published) {
+		if !local[name] {
+			comparison.IndexOnly = append(comparison.IndexOnly, name)
+		}
+	}
+	return comparison, nil
+}
+
+// problems describes the missing and mismatched files.
+func (c *digestComparison) problems() []string {
+	var problems []string
+	if len(c.Missing) > 0 {
+		problems = append(problems, fmt.Sprintf("%d file(s) not published: %s", len(c.Missing), strings.Join(c.Missing, ", ")))
+	}
+	if len(c.Mismatched) > 0 {
+		problems = append(problems, fmt.Sprintf("%d file(s) published with a different SHA256: %s", len(c.Mismatched), strings.Join(c.Mismatched, ", ")))
+	}
+	return problems
+}
+
+// validateVerifyOnlyConfig validates the verify_only options.
+func validateVerifyOnlyConfig(cfg Config) error {
+	if !cfg.VerifyOnly {
+		return nil
+	}
+	if cfg.JSONAPIURL == "" && cfg.IndexURL == "" {
+		return fmt.Errorf("verify_only needs json_api_url or index_url for repositories other than PyPI and TestPyPI")
+	}
+	if err := validateJSONAPIURL(cfg); err != nil {
+		return err
+	}
+	if cfg.Benchmark || cfg.BackfillVersion != "" || cfg.DevRelease || auditsReleases(cfg) || cfg.NexusStagingReleaseTag != "" || cfg.CanaryPromoteTag != "" || cfg.ResumeQueued {
+		return fmt.Errorf("verify_only cannot be combined with benchmark, backfill_version, dev_release, release_audit, nexus_staging_release_tag, canary_promote_tag or resume_queued")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// newTestReleaseAPI serves mypkg 1.0.0 with files on the JSON API and on the simple index, which
+// reports no digests.
+func newTestReleaseAPI(t *testing.T, files map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pypi/mypkg/1.0.0/json":
+			var urls []string
+			for name, digest := range files {
+				urls = append(urls, fmt.Sprintf(`{"filename": %q, "digests": {"sha256": %q}}`, name, digest))
+			}
+			_, _ = fmt.Fprintf(w, `{"urls": [%s]}`, strings.Join(urls, ","))
+		case "/simple/mypkg/":
+			for name := range files {
+				_, _ = fmt.Fprintf(w, `<a href="%s">%s</a>`, name, name)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExecuteVerifyOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(files map[string]string)
+		version string
+		wantOK  bool
+		want    string
+	}{
+		{"verified", func(files map[string]string) { files["mypkg-1.0.0-cp312-cp312-manylinux_2_17_x86_64.whl"] = "abc" }, "v1.0.0", true, "Verified 2 file(s) of mypkg 1.0.0 on http://localhost:8080/"},
+		{"mismatch", func(files map[string]string) { files["mypkg-1.0.0.tar.gz"] = "abc" }, "v1.0.0", false, "1 file(s) published with a different SHA256: mypkg-1.0.0.tar.gz"},
+		{"missing", func(files map[string]string) { delete(files, "mypkg-1.0.0-py3-none-any.whl") }, "v1.0.0", false, "1 file(s) not published: mypkg-1.0.0-py3-none-any.whl"},
+		{"not published", func(files map[string]string) { clear(files) }, "v1.0.0", false, "mypkg 1.0.0 is not published on http://localhost:8080/"},
+		{"version mismatch", func(files map[string]string) {}, "v1.1.0", false, "is version 1.0.0, not 1.1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := writeReleaseDists(t)
+			tt.modify(files)
+			server := newTestReleaseAPI(t, files)
+			executor := &MockCommandExecutor{}
+			p := &PyPIPlugin{httpClient: server.Client(), cmdExecutor: executor}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"repository":   "http://localhost:8080/",
+					"verify_only":  true,
+					"json_api_url": server.URL + "/pypi",
+				},
+				Context: plugin.ReleaseContext{Version: tt.version},
+			})
+			if err != nil || resp.Success != tt.wantOK || !strings.Contains(resp.Message+resp.Error, tt.want) {
+				t.Fatalf("expected success %v with %q, got %v %+v", tt.wantOK, tt.want, err, resp)
+			}
+			if len(executor.RunCalls) != 0 {
+				t.Error("expected verify_only not to upload")
+			}
+		})
+	}
+}
+
+func TestExecuteVerifyOnlySimpleIndex(t *testing.T) {
+	server := newTestReleaseAPI(t, writeReleaseDists(t))
+	p := &PyPIPlugin{httpClient: server.Client()}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"repository":  "http://localhost:8080/",
+			"index_url":   server.URL + "/simple/",
+			"verify_only": true,
+		},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected files without digests to pass, got %v %+v", err, resp)
+	}
+	verified, _ := resp.Outputs["verified_files"].([]verifiedFile)
+	if len(verified) != 2 || verified[0].Status != verifyNoDigest {
+		t.Errorf("unexpected verified_files %+v", resp.Outputs["verified_files"])
+	}
+	if warnings := fmt.Sprint(resp.Outputs["warnings"]); !strings.Contains(warnings, "reports no SHA256") {
+		t.Errorf("expected a warning for unverified digests, got %s", warnings)
+	}
+}
+
+func TestValidateVerifyOnlyConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"disabled", Config{}, false},
+		{"pypi", Config{VerifyOnly: true, Repository: "https://upload.pypi.org/legacy/", JSONAPIURL: defaultJSONAPI("https://upload.pypi.org/legacy/")}, false},
+		{"simple index", Config{VerifyOnly: true, IndexURL: "http://localhost:8080/simple/"}, false},
+		{"no index", Config{VerifyOnly: true, Repository: "http://localhost:8080/"}, true},
+		{"invalid json api", Config{VerifyOnly: true, JSONAPIURL: "ftp://localhost/pypi"}, true},
+		{"dev release", Config{VerifyOnly: true, IndexURL: "http://localhost:8080/simple/", DevRelease: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateVerifyOnlyConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateVerifyOnlyConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// versionMismatch is a distribution whose file name names another version than the release,
+// reported in outputs.
+type versionMismatch struct {
+	File string `json:"file"`
+	// Version is the version in the file name, or empty when the name carries none
+	Version string `json:"version"`
+}
+
+// distFilenameVersion returns the version in the file name of a wheel or sdist, and false for
+// other files. The project name of an sdist is taken from its metadata when readable, as
+// legacy sdist names may contain dashes.
+func distFilenameVersion(f string) (string, bool) {
+	base := filepath.Base(f)
+	if strings.HasSuffix(base, ".whl") {
+		return versionFromFilename("", base), true
+	}
+	for _, ext := range []string{".tar.gz", ".zip", ".tar.bz2", ".tgz"} {
+		name, ok := strings.CutSuffix(base, ext)
+		if !ok {
+			continue
+		}
+		if meta, err := readDistMetadata(f); err == nil {
+			if version := versionFromFilename(meta.Name, base); version != "" {
+				return version, true
+			}
+		}
+		if i := strings.LastIndex(name, "-"); i > 0 {
+			return name[i+1:], true
+		}
+		return "", true
+	}
+	return "", false
+}
+
+// versionsMatch reports whether two spellings name the same PEP 440 version, including the
+// local segment.
+func versionsMatch(a, b string) bool {
+	publicA, localA, _ := strings.Cut(a, "+")
+	publicB, localB, _ := strings.Cut(b, "+")
+	local := strings.NewReplacer("-", ".", "_", ".")
+	if !strings.EqualFold(local.Replace(localA), local.Replace(localB)) {
+		return false
+	}
+	c, ok := compareVersions(publicA, publicB)
+	if !ok {
+		return normalizeVersion(publicA) == normalizeVersion(publicB)
+	}
+	return c == 0
+}
+
+// checkVersionMatch returns the distributions whose file name names another version than the
+// release, which usually are stale files left in dist_path by an earlier build. When local
+// versions are stripped before the upload, only the public versions are compared.
+func checkVersionMatch(version string, files []string, stripLocal bool) []versionMismatch {
+	if version == "" {
+		return nil
+	}
+	var mismatches []versionMismatch
+	for _, f := range files {
+		named, ok := distFilenameVersion(f)
+		if !ok {
+			continue
+		}
+		compared := named
+		if stripLocal {
+			compared = stripLocalVersion(named)
+		}
+		if named == "" || !versionsMatch(compared, version) {
+			mismatches = append(mismatches, versionMismatch{File: filepath.ToSlash(f), Version: named})
+		}
+	}
+	return mismatches
+}
+
+// versionMismatchError describes the mismatched distributions of a release.
+func versionMismatchError(version string, mismatches []versionMismatch) string {
+	parts := make([]string, 0, len(mismatches))
+	for _, m := range mismatches {
+		if m.Version == "" {
+			parts = append(parts, fmt.Sprintf("%s (no version in the file name)", m.File))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s (%s)", m.File, m.Version))
+		}
+	}
+	return fmt.Sprintf("version mismatch: releasing %s, but %d distribution(s) are another version: %s; remove stale files from dist_path or rebuild",
+		version, len(mismatches), strings.Join(parts, ", "))
+}
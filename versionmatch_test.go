@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestVersionsMatch(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.0.0", "1.0.0", true},
+		{"1.0", "1.0.0", true},
+		{"1.0.0-rc.1", "1.0.0rc1", true},
+		{"1!2.0", "1!2.0.0", true},
+		{"1.0.0+Ubuntu-1", "1.0.0+ubuntu.1", true},
+		{"1.0.0", "1.0.1", false},
+		{"1.0.0", "1.0.0rc1", false},
+		{"1.0.0+a", "1.0.0", false},
+		{"nightly", "nightly", true},
+	}
+	for _, tt := range tests {
+		if got := versionsMatch(tt.a, tt.b); got != tt.want {
+			t.Errorf("versionsMatch(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckVersionMatch(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.2.0.tar.gz", "mypkg-1.2.0-py3-none-any.whl", "mypkg-1.1.0-py3-none-any.whl", "my-legacy-pkg-1.2.0.zip", "mypkg.tar.gz", "README.txt")
+	writeTestWheel(t, filepath.Join("dist", "mypkg-1.2.0+local-py3-none-any.whl"), map[string]string{
+		"mypkg-1.2.0+local.dist-info/METADATA": "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.2.0+local\n",
+	})
+	files, err := expandDistGlob("dist/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := fmt.Sprint(checkVersionMatch("1.2.0", files, false))
+	if want := "[{dist/mypkg-1.1.0-py3-none-any.whl 1.1.0} {dist/mypkg-1.2.0+local-py3-none-any.whl 1.2.0+local} {dist/mypkg.tar.gz }]"; got != want {
+		t.Errorf("checkVersionMatch() = %s, want %s", got, want)
+	}
+	if got := fmt.Sprint(checkVersionMatch("1.2.0", files, true)); got != "[{dist/mypkg-1.1.0-py3-none-any.whl 1.1.0} {dist/mypkg.tar.gz }]" {
+		t.Errorf("expected stripped local versions to match, got %s", got)
+	}
+	if got := checkVersionMatch("", files, false); got != nil {
+		t.Errorf("expected no check without a release version, got %v", got)
+	}
+}
+
+func TestExecuteCheckVersionMatch(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.2.0.tar.gz", "mypkg-1.1.0.tar.gz")
+	uploaded := false
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			uploaded = true
+			return []byte("ok"), nil
+		},
+	}}
+	config := map[string]any{
+		"username":            "__token__",
+		"password":            "pypi-token",
+		"repository":          "http://localhost:8080/",
+		"check_version_match": true,
+	}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "v1.2.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || uploaded || !strings.Contains(resp.Error, "dist/mypkg-1.1.0.tar.gz (1.1.0)") {
+		t.Fatalf("expected the stale sdist to fail the publish, got %+v", resp)
+	}
+	if mismatches, _ := resp.Outputs["version_mismatches"].([]versionMismatch); len(mismatches) != 1 {
+		t.Errorf("unexpected version_mismatches %v", resp.Outputs["version_mismatches"])
+	}
+
+	config["dist_path"] = "dist/*1.2.0*"
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "v1.2.0"},
+	})
+	if err != nil || !resp.Success || !uploaded {
+		t.Errorf("expected matching distributions to upload, got %v %+v", err, resp)
+	}
+}
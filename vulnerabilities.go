@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Check modes shared by optional pre-upload checks.
+const (
+	checkOff  = "off"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// checkModes lists the accepted values of check mode options.
+var checkModes = []string{checkOff, checkWarn, checkFail}
+
+// Vulnerability data sources.
+const (
+	vulnSourceOSV      = "osv"
+	vulnSourcePipAudit = "pip-audit"
+)
+
+// vulnSources lists the accepted vulnerability_source values.
+var vulnSources = []string{vulnSourceOSV, vulnSourcePipAudit}
+
+// defaultOSVURL is the OSV single-package query endpoint.
+const defaultOSVURL = "https://api.osv.dev/v1/query"
+
+// severityRanks orders vulnerability severities from least to most severe.
+var severityRanks = map[string]int{
+	"unknown":  0,
+	"low":      1,
+	"moderate": 2,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// vulnerabilityFinding is a known vulnerability affecting a dependency.
+type vulnerabilityFinding struct {
+	Package  string `json:"package"`
+	Version  string `json:"version"`
+	ID       string `json:"id"`
+	Severity string `json:"severity"`
+	Summary  string `json:"summary,omitempty"`
+}
+
+// dependencySet collects the requirements to audit, deduplicated by normalized name and specifier.
+type dependencySet struct {
+	requirements []requirement
+	seen         map[string]bool
+}
+
+// add records a requirement unless an identical one was already added.
+func (d *dependencySet) add(r requirement) {
+	if d.seen == nil {
+		d.seen = map[string]bool{}
+	}
+	key := normalizeProjectName(r.Name) + r.Specifier + ";" + r.Marker
+	if d.seen[key] {
+		return
+	}
+	d.seen[key] = true
+	d.requirements = append(d.requirements, r)
+}
+
+// collectDependencies gathers Requires-Dist entries from the distributions and any pinned
+// requirements file.
+func collectDependencies(files []string, requirementsFile string) (*dependencySet, error) {
+	deps := &dependencySet{}
+
+	for _, file := range files {
+		meta, err := readDistMetadata(file)
+		if err != nil {
+			continue
+		}
+		for _, line := range meta.RequiresDist {
+			if r, ok := parseRequirement(line); ok {
+				deps.add(r)
+			}
+		}
+	}
+
+	if requirementsFile != "" {
+		data, err := os.ReadFile(requirementsFile) // #nosec G304 -- path validated like dist_path
+		if err != nil {
+			return nil, fmt.Errorf("failed to read requirements file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if r, ok := parseRequirement(strings.TrimSuffix(line, "\\")); ok {
+				deps.add(r)
+			}
+		}
+	}
+
+	return deps, nil
+}
+
+// auditDependencies checks the dependencies declared by the distributions (and the optional
+// requirements file) and returns findings at or above the configured severity, plus the
+// dependencies that could not be checked because they are not pinned.
+func (p *PyPIPlugin) auditDependencies(ctx context.Context, cfg Config, files []string) ([]vulnerabilityFinding, []string, error) {
+	deps, err := collectDependencies(files, cfg.RequirementsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(deps.requirements) == 0 {
+		return []vulnerabilityFinding{}, nil, nil
+	}
+
+	var unchecked []string
+	var all []vulnerabilityFinding
+	switch cfg.VulnerabilitySource {
+	case vulnSourcePipAudit:
+		results, err := p.runPipAudit(ctx, cfg, deps)
+		if err != nil {
+			return nil, nil, err
+		}
+		all = results
+	default:
+		for _, r := range deps.requirements {
+			version, ok := r.pinnedVersion()
+			if !ok {
+				unchecked = append(unchecked, r.Name+r.Specifier)
+				continue
+			}
+			results, err := p.queryOSV(ctx, cfg.OSVURL, r.Name, version)
+			if err != nil {
+				return nil, nil, err
+			}
+			all = append(all, results...)
+		}
+	}
+
+	threshold := severityRanks[cfg.VulnerabilitySeverity]
+	matched := []vulnerabilityFinding{}
+	for _, f := range all {
+		// pip-audit reports no severity, so its findings always count
+		if cfg.VulnerabilitySource == vulnSourcePipAudit || severityRanks[f.Severity] >= threshold {
+			matched = append(matched, f)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Package != matched[j].Package {
+			return matched[i].Package < matched[j].Package
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	return matched, unchecked, nil
+}
+
+// osvResponse is the subset of the OSV query response used by the check.
+type osvResponse struct {
+	Vulns []struct {
+		ID               string `json:"id"`
+		Summary          string `json:"summary"`
+		DatabaseSpecific struct {
+			Severity string `json:"severity"`
+		} `json:"database_specific"`
+	} `json:"vulns"`
+}
+
+// queryOSV queries OSV for vulnerabilities affecting a PyPI package version.
+func (p *PyPIPlugin) queryOSV(ctx context.Context, osvURL, name, version string) ([]vulnerabilityFinding, error) {
+	payload, err := json.Marshal(map[string]any{
+		"package": map[string]string{"name": name, "ecosystem": "PyPI"},
+		"version": version,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, osvURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OSV request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OSV query failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OSV query for %s failed: %s", name, resp.Status)
+	}
+
+	var parsed osvResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("invalid OSV response: %w", err)
+	}
+
+	findings := make([]vulnerabilityFinding, 0, len(parsed.Vulns))
+	for _, v := range parsed.Vulns {
+		severity := strings.ToLower(v.DatabaseSpecific.Severity)
+		if _, ok := severityRanks[severity]; !ok {
+			severity = "unknown"
+		}
+		findings = append(findings, vulnerabilityFinding{
+			Package:  name,
+			Version:  version,
+			ID:       v.ID,
+			Severity: severity,
+			Summary:  v.Summary,
+		})
+	}
+	return findings, nil
+}
+
+// pipAuditReport is the subset of "pip-audit -f json" output used by the check.
+type pipAuditReport struct {
+	Dependencies []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Vulns   []struct {
+			ID          string `json:"id"`
+			Description string `json:"description"`
+		} `json:"vulns"`
+	} `json:"dependencies"`
+}
+
+// runPipAudit audits the dependencies with pip-audit, which resolves unpinned requirements itself.
+func (p *PyPIPlugin) runPipAudit(ctx context.Context, cfg Config, deps *dependencySet) ([]vulnerabilityFinding, error) {
+	f, err := os.CreateTemp("", "relicta-pypi-requirements-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create requirements file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	for _, r := range deps.requirements {
+		line := r.Name + r.Extras + r.Specifier
+		if r.Marker != "" {
+			line += "; " + r.Marker
+		}
+		if _, err := fmt.Fprintln(f, line); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to write requirements file: %w", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write requirements file: %w", err)
+	}
+
+	// pip-audit exits non-zero when vulnerabilities are found, so rely on the JSON report instead
+	output, runErr := p.getExecutor().Run(ctx, "pip-audit", "-r", f.Name(), "-f", "json", "--progress-spinner", "off")
+
+	var report pipAuditReport
+	start := bytes.IndexByte(output, '{')
+	if start < 0 || json.Unmarshal(output[start:], &report) != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("pip-audit failed: %v\nOutput: %s", runErr, limitOutput(cfg, string(output)))
+		}
+		return nil, fmt.Errorf("pip-audit produced no JSON report")
+	}
+
+	var findings []vulnerabilityFinding
+	for _, dep := range report.Dependencies {
+		for _, v := range dep.Vulns {
+			findings = append(findings, vulnerabilityFinding{
+				Package:  dep.Name,
+				Version:  dep.Version,
+				ID:       v.ID,
+				Severity: "unknown",
+				Summary:  v.Description,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// validateVulnerabilityConfig validates the vulnerability check options.
+func validateVulnerabilityConfig(cfg Config) error {
+	if cfg.VulnerabilityCheck == "" || cfg.VulnerabilityCheck == checkOff {
+		return nil
+	}
+	if !containsString(checkModes, cfg.VulnerabilityCheck) {
+		return fmt.Errorf("vulnerability_check must be one of: %s", strings.Join(checkModes, ", "))
+	}
+	if !containsString(vulnSources, cfg.VulnerabilitySource) {
+		return fmt.Errorf("vulnerability_source must be one of: %s", strings.Join(vulnSources, ", "))
+	}
+	if _, ok := severityRanks[cfg.VulnerabilitySeverity]; !ok || cfg.VulnerabilitySeverity == "unknown" {
+		return fmt.Errorf("vulnerability_severity must be one of: low, moderate, high, critical")
+	}
+	if cfg.RequirementsFile != "" {
+		if err := validateDistPath(cfg.RequirementsFile); err != nil {
+			return fmt.Errorf("invalid requirements_file: %w", err)
+		}
+	}
+	if cfg.VulnerabilitySource == vulnSourceOSV && cfg.OSVURL != defaultOSVURL {
+		if err := validateRepositoryURL(cfg.OSVURL); err != nil {
+			return fmt.Errorf("invalid osv_url: %w", err)
+		}
+	}
+	return nil
+}
+
+// containsString reports whether values contains s.
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// newTestOSVServer serves OSV query responses with the given vulnerabilities per "name==version".
+func newTestOSVServer(t *testing.T, vulns map[string][]map[string]any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var query struct {
+			Package struct {
+				Name      string `json:"name"`
+				Ecosystem string `json:"ecosystem"`
+			} `json:"package"`
+			Version string `json:"version"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil || query.Package.Ecosystem != "PyPI" {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"vulns": vulns[query.Package.Name+"=="+query.Version]})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeDistWithRequirements creates a wheel declaring the given Requires-Dist entries and
+// returns the dist glob.
+func writeDistWithRequirements(t *testing.T, requires ...string) string {
+	t.Helper()
+	distPath := writeDistFiles(t)
+	metadata := "Metadata-Version: 2.1\nName: app\nVersion: 1.0.0\n"
+	for _, r := range requires {
+		metadata += "Requires-Dist: " + r + "\n"
+	}
+	writeTestWheel(t, filepath.Join("dist", "app-1.0.0-py3-none-any.whl"), map[string]string{
+		"app-1.0.0.dist-info/METADATA": metadata,
+	})
+	return distPath
+}
+
+var testOSVVulns = map[string][]map[string]any{
+	"requests==2.19.0": {
+		{"id": "GHSA-crit", "summary": "Critical issue", "database_specific": map[string]any{"severity": "CRITICAL"}},
+		{"id": "GHSA-low", "summary": "Minor issue", "database_specific": map[string]any{"severity": "LOW"}},
+	},
+}
+
+func TestAuditDependencies(t *testing.T) {
+	server := newTestOSVServer(t, testOSVVulns)
+	distPath := writeDistWithRequirements(t, "requests (==2.19.0)", "click>=8.0", "six==1.16.0")
+	files, _ := expandDistGlob(distPath)
+
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"vulnerability_check": "warn",
+		"osv_url":             server.URL,
+	})
+
+	findings, unchecked, err := p.auditDependencies(context.Background(), cfg, files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(findings) != 1 || findings[0].ID != "GHSA-crit" || findings[0].Severity != "critical" {
+		t.Errorf("expected only the critical finding, got %+v", findings)
+	}
+	if len(unchecked) != 1 || unchecked[0] != "click>=8.0" {
+		t.Errorf("expected click to be unchecked, got %v", unchecked)
+	}
+
+	cfg.VulnerabilitySeverity = "low"
+	findings, _, err = p.auditDependencies(context.Background(), cfg, files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Errorf("expected both findings at low threshold, got %+v", findings)
+	}
+}
+
+func TestAuditDependenciesPipAudit(t *testing.T) {
+	distPath := writeDistWithRequirements(t, "requests>=2.0")
+	files, _ := expandDistGlob(distPath)
+
+	var audited string
+	mockExecutor := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name != "pip-audit" || args[0] != "-r" {
+				t.Errorf("unexpected command: %s %v", name, args)
+			}
+			data, _ := os.ReadFile(args[1])
+			audited = string(data)
+			report := `{"dependencies":[{"name":"requests","version":"2.19.0","vulns":[{"id":"PYSEC-1","description":"bad"}]}]}`
+			return []byte("Found 1 known vulnerability\n" + report), context.DeadlineExceeded
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: mockExecutor}
+	cfg := p.parseConfig(map[string]any{"vulnerability_check": "fail", "vulnerability_source": "pip-audit"})
+
+	findings, _, err := p.auditDependencies(context.Background(), cfg, files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(audited, "requests>=2.0") {
+		t.Errorf("expected requirement passed to pip-audit, got %q", audited)
+	}
+	if len(findings) != 1 || findings[0].ID != "PYSEC-1" || findings[0].Version != "2.19.0" {
+		t.Errorf("unexpected findings: %+v", findings)
+	}
+}
+
+func TestExecuteVulnerabilityCheck(t *testing.T) {
+	server := newTestOSVServer(t, testOSVVulns)
+
+	tests := []struct {
+		mode        string
+		wantSuccess bool
+		wantUpload  bool
+	}{
+		{mode: "warn", wantSuccess: true, wantUpload: true},
+		{mode: "fail", wantSuccess: false, wantUpload: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			distPath := writeDistWithRequirements(t, "requests==2.19.0")
+			uploaded := false
+			p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					uploaded = true
+					return []byte("ok"), nil
+				},
+			}}
+
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"username":            "__token__",
+					"password":            "pypi-token",
+					"repository":          "http://localhost:8080/",
+					"dist_path":           distPath,
+					"vulnerability_check": tt.mode,
+					"osv_url":             server.URL,
+				},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Success != tt.wantSuccess {
+				t.Fatalf("success = %v, want %v (error: %s)", resp.Success, tt.wantSuccess, resp.Error)
+			}
+			if uploaded != tt.wantUpload {
+				t.Errorf("uploaded = %v, want %v", uploaded, tt.wantUpload)
+			}
+			if tt.wantSuccess {
+				warnings, _ := resp.Outputs["warnings"].([]string)
+				if len(warnings) != 1 || !strings.Contains(warnings[0], "GHSA-crit") {
+					t.Errorf("expected vulnerability warning, got %v", resp.Outputs["warnings"])
+				}
+			} else if !strings.Contains(resp.Error, "requests==2.19.0 (GHSA-crit, critical)") {
+				t.Errorf("expected finding in error, got: %s", resp.Error)
+			}
+		})
+	}
+}
+
+func TestExecuteVulnerabilityCheckUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	distPath := writeDistWithRequirements(t, "requests==2.19.0")
+
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"dist_path":           distPath,
+		"vulnerability_check": "warn",
+		"osv_url":             server.URL,
+	})
+	result, blocked := p.runPreflight(context.Background(), cfg)
+	if blocked != nil {
+		t.Fatalf("warn mode must not block: %s", blocked.Error)
+	}
+	if len(result.warnings) != 1 || !strings.Contains(result.warnings[0], "vulnerability check skipped") {
+		t.Errorf("expected skipped warning, got %v", result.warnings)
+	}
+
+	cfg.VulnerabilityCheck = checkFail
+	if _, blocked := p.runPreflight(context.Background(), cfg); blocked == nil {
+		t.Error("fail mode must block when the source is unavailable")
+	}
+}
+
+func TestValidateVulnerabilityConfig(t *testing.T) {
+	p := &PyPIPlugin{}
+	tests := []struct {
+		name    string
+		raw     map[string]any
+		wantErr bool
+	}{
+		{"off", map[string]any{}, false},
+		{"warn defaults", map[string]any{"vulnerability_check": "warn"}, false},
+		{"invalid mode", map[string]any{"vulnerability_check": "block"}, true},
+		{"invalid source", map[string]any{"vulnerability_check": "warn", "vulnerability_source": "safety"}, true},
+		{"invalid severity", map[string]any{"vulnerability_check": "warn", "vulnerability_severity": "severe"}, true},
+		{"requirements traversal", map[string]any{"vulnerability_check": "warn", "requirements_file": "../requirements.txt"}, true},
+		{"osv over http", map[string]any{"vulnerability_check": "warn", "osv_url": "http://osv.example.com/"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateVulnerabilityConfig(p.parseConfig(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateVulnerabilityConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultWarningPatterns match the warnings twine logs and Python warnings such as
+// InsecureRequestWarning when warnings_as_errors is set without warning_patterns.
+var defaultWarningPatterns = []string{`^WARNING\b`, `\b[A-Z][A-Za-z]*Warning:`}
+
+// warningPatterns returns the compiled patterns of output lines promoted to failures.
+func warningPatterns(cfg Config) ([]*regexp.Regexp, error) {
+	if !cfg.WarningsAsErrors {
+		return nil, nil
+	}
+	sources := cfg.WarningPatterns
+	if len(sources) == 0 {
+		sources = defaultWarningPatterns
+	}
+	patterns := make([]*regexp.Regexp, 0, len(sources))
+	for _, s := range sources {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid warning_patterns entry %q: %w", s, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// promotedWarnings returns the output lines matching the warning patterns of cfg, which fail
+// the publish even though the upload tool exited successfully.
+func promotedWarnings(cfg Config, output string) []string {
+	patterns, err := warningPatterns(cfg)
+	if err != nil || len(patterns) == 0 {
+		return nil
+	}
+	var warnings []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		for _, re := range patterns {
+			if re.MatchString(line) {
+				warnings = append(warnings, line)
+				break
+			}
+		}
+	}
+	return warnings
+}
+
+// validateWarningConfig validates the warning patterns, even while warnings_as_errors is off.
+func validateWarningConfig(cfg Config) error {
+	_, err := warningPatterns(Config{WarningsAsErrors: true, WarningPatterns: cfg.WarningPatterns})
+	return err
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+const twineOutputWithWarnings = `Uploading distributions to https://upload.pypi.org/legacy/
+/usr/lib/python3/dist-packages/urllib3/connectionpool.py:1045: InsecureRequestWarning: Unverified HTTPS request
+WARNING  Skipping mypkg-1.0.0.tar.gz because it appears to already exist
+Uploading mypkg-1.0.0.tar.gz
+`
+
+func TestPromotedWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want int
+	}{
+		{"disabled", Config{}, 0},
+		{"default patterns", Config{WarningsAsErrors: true}, 2},
+		{"selected warning", Config{WarningsAsErrors: true, WarningPatterns: []string{"InsecureRequestWarning"}}, 1},
+		{"patterns without warnings_as_errors", Config{WarningPatterns: []string{"InsecureRequestWarning"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promotedWarnings(tt.cfg, twineOutputWithWarnings); len(got) != tt.want {
+				t.Errorf("expected %d warnings, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecuteWarningsAsErrors(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	p := &PyPIPlugin{cmdExecutor: &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte(twineOutputWithWarnings), nil
+		},
+	}}
+	config := map[string]any{
+		"username":           "__token__",
+		"password":           "pypi-token",
+		"warnings_as_errors": true,
+		"warning_patterns":   []any{"InsecureRequestWarning"},
+	}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "InsecureRequestWarning") {
+		t.Errorf("expected the warning to fail the publish, got success=%v error=%q", resp.Success, resp.Error)
+	}
+	if warnings, _ := resp.Outputs["promoted_warnings"].([]string); len(warnings) != 1 {
+		t.Errorf("unexpected promoted_warnings %v", resp.Outputs["promoted_warnings"])
+	}
+
+	config["warning_patterns"] = []any{"DeprecationWarning"}
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookPostPublish, Config: config})
+	if err != nil || !resp.Success {
+		t.Errorf("expected unmatched warnings to pass, got %v %+v", err, resp)
+	}
+}
+
+func TestValidateWarningPatterns(t *testing.T) {
+	p := &PyPIPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{
+		"username":           "user",
+		"password":           "pass",
+		"warnings_as_errors": true,
+		"warning_patterns":   []any{"Insecure(Request"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Valid {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+
+	resp, _ = p.Validate(context.Background(), map[string]any{
+		"username":         "user",
+		"password":         "pass",
+		"warning_patterns": []any{"InsecureRequestWarning"},
+	})
+	if !resp.Valid || len(resp.Errors) == 0 || resp.Errors[0].Code != validationWarningCode {
+		t.Errorf("expected a warning about the unused patterns, got %+v", resp)
+	}
+}
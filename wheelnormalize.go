@@ -0,0 +1,262 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// sourceDateEpochEnv is the reproducible builds variable fixing the timestamps of normalized
+// wheels.
+const sourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// zipEpoch is the earliest timestamp a zip archive can hold, used without SOURCE_DATE_EPOCH.
+var zipEpoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// homePathPattern matches home directories of any build host, which name the user. It is only
+// applied to the dist-info metadata, as code may legitimately contain such paths.
+var homePathPattern = regexp.MustCompile(`(?:/home|/Users)/[^/\s"'<>:;,]+|[A-Za-z]:\\Users\\[^\\\s"'<>:;,]+`)
+
+// normalizedWheel reports what normalizing a wheel changed, in outputs.
+type normalizedWheel struct {
+	File string `json:"file"`
+	// SHA256 is the digest of the normalized wheel that is uploaded
+	SHA256 string `json:"sha256"`
+	// PathsStripped lists the members whose build-host paths were replaced
+	PathsStripped []string `json:"paths_stripped,omitempty"`
+	Timestamp     string   `json:"timestamp"`
+}
+
+// pathReplacement replaces an absolute build-host path.
+type pathReplacement struct {
+	from string
+	// pattern matches from as a whole path component, so /root does not match /rootfs
+	pattern *regexp.Regexp
+	to      string
+}
+
+// wheelNormalizer rewrites wheels without build-host paths, user names and timestamps.
+type wheelNormalizer struct {
+	// paths are replaced longest first, so the working directory wins over the home directory
+	// containing it
+	paths    []pathReplacement
+	modified time.Time
+}
+
+// newWheelNormalizer returns a normalizer replacing the working directory with "." and the home
+// directory with "~", and setting timestamps to SOURCE_DATE_EPOCH or the zip epoch.
+func newWheelNormalizer() (*wheelNormalizer, error) {
+	n := &wheelNormalizer{modified: zipEpoch}
+	if v := os.Getenv(sourceDateEpochEnv); v != "" {
+		seconds, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", sourceDateEpochEnv, v)
+		}
+		if t := time.Unix(seconds, 0).UTC(); t.After(zipEpoch) {
+			n.modified = t
+		}
+	}
+	if wd, err := os.Getwd(); err == nil {
+		n.add(wd, ".")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		n.add(home, "~")
+	}
+	return n, nil
+}
+
+// add replaces the absolute path dir, in native and forward-slash form, by replacement.
+func (n *wheelNormalizer) add(dir, replacement string) {
+	dir = strings.TrimRight(dir, `/\`)
+	if dir == "" || !filepath.IsAbs(dir) {
+		return
+	}
+	for _, p := range []string{dir, filepath.ToSlash(dir)} {
+		if len(n.paths) > 0 && n.paths[len(n.paths)-1].from == p {
+			continue
+		}
+		n.paths = append(n.paths, pathReplacement{from: p, pattern: regexp.MustCompile(regexp.QuoteMeta(p) + `(?:$|[^\w.-])`), to: replacement})
+	}
+	sort.SliceStable(n.paths, func(i, j int) bool { return len(n.paths[i].from) > len(n.paths[j].from) })
+}
+
+// stripPaths replaces the build-host paths of a text member, and any home directory when it
+// is metadata, reporting whether it changed.
+func (n *wheelNormalizer) stripPaths(data []byte, metadata bool) ([]byte, bool) {
+	out := data
+	for _, p := range n.paths {
+		out = p.pattern.ReplaceAllFunc(out, func(m []byte) []byte {
+			return append([]byte(p.to), m[len(p.from):]...)
+		})
+	}
+	if metadata {
+		out = homePathPattern.ReplaceAll(out, []byte("~"))
+	}
+	return out, !bytes.Equal(out, data)
+}
+
+// isTextMember reports whether data is a text file whose paths can be rewritten; binary members
+// such as compiled extensions and bytecode are copied unchanged.
+func isTextMember(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+// normalizeWheel copies the wheel src to dst with build-host paths stripped from its text
+// members, every timestamp and extra field (which may carry the owner's uid and gid) reset,
+// and RECORD updated to match. It returns the members whose paths were stripped.
+func (n *wheelNormalizer) normalizeWheel(src, dst string) ([]string, error) {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zr.Close() }()
+
+	members := map[string][]byte{}
+	var stripped []string
+	var record string
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || f.UncompressedSize64 > maxMetadataSize {
+			continue
+		}
+		dir, file := path.Split(f.Name)
+		metadata := strings.Count(f.Name, "/") == 1 && strings.HasSuffix(dir, ".dist-info/")
+		if metadata && file == "RECORD" {
+			record = f.Name
+			continue
+		}
+		data, err := readZipMember(f, maxMetadataSize)
+		if err != nil {
+			return nil, err
+		}
+		if !isTextMember(data) {
+			continue
+		}
+		if data, changed := n.stripPaths(data, metadata); changed {
+			members[f.Name] = data
+			stripped = append(stripped, f.Name)
+		}
+	}
+	if record != "" && len(members) > 0 {
+		for _, f := range zr.File {
+			if f.Name != record {
+				continue
+			}
+			data, err := readZipMember(f, maxMetadataSize)
+			if err != nil {
+				return nil, err
+			}
+			if members[record], err = rewriteRecord(data, func(name string) string { return name }, members); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	out, err := os.Create(dst) // #nosec G304 -- dst is in the plugin's temporary directory
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = out.Close() }()
+	zw := zip.NewWriter(out)
+	for _, f := range zr.File {
+		header := f.FileHeader
+		// CreateRaw does not derive the MS-DOS fields from Modified as CreateHeader does
+		header.Modified = n.modified
+		header.ModifiedDate, header.ModifiedTime = msDosTime(n.modified)
+		header.Extra, header.Comment = nil, ""
+		if data, ok := members[f.Name]; ok {
+			w, err := zw.CreateHeader(&header)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := w.Write(data); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		raw, err := f.OpenRaw()
+		if err != nil {
+			return nil, err
+		}
+		w, err := zw.CreateRaw(&header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(w, raw); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return stripped, out.Close()
+}
+
+// msDosTime returns the MS-DOS date and time fields of a zip header for t, which has a
+// resolution of two seconds.
+func msDosTime(t time.Time) (uint16, uint16) {
+	return uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9), // #nosec G115 -- t is after zipEpoch
+		uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11) // #nosec G115 -- bounded by the clock
+}
+
+// normalizeWheelFiles writes normalized copies of the wheels among files to dir. It returns the
+// files to upload in the order of files, the copies keyed by original wheel, and a report per
+// wheel.
+func normalizeWheelFiles(files []string, dir string) ([]string, map[string]string, []normalizedWheel, error) {
+	n, err := newWheelNormalizer()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	upload := make([]string, 0, len(files))
+	normalized := map[string]string{}
+	var report []normalizedWheel
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".whl") {
+			upload = append(upload, f)
+			continue
+		}
+		target := filepath.Join(dir, filepath.Base(f))
+		stripped, err := n.normalizeWheel(f, target)
+		if err == nil {
+			var digest string
+			_, digest, _, err = fileDigests(target)
+			report = append(report, normalizedWheel{
+				File:          filepath.ToSlash(f),
+				SHA256:        digest,
+				PathsStripped: stripped,
+				Timestamp:     formatTimestamp(n.modified),
+			})
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to normalize %s: %w", f, err)
+		}
+		normalized[f] = target
+		upload = append(upload, target)
+	}
+	return upload, normalized, report, nil
+}
+
+// validateNormalizeWheelsConfig validates the normalize_wheels option.
+func validateNormalizeWheelsConfig(cfg Config) error {
+	if !cfg.NormalizeWheels {
+		return nil
+	}
+	if containsString(cfg.ExtraArgs, "--attestations") {
+		return fmt.Errorf("normalize_wheels changes the wheels after they were attested; normalize them before generating --attestations")
+	}
+	if v := os.Getenv(sourceDateEpochEnv); v != "" {
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("normalize_wheels needs an integer %s, got %q", sourceDateEpochEnv, v)
+		}
+	}
+	return nil
+}
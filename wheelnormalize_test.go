@@ -0,0 +1,199 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// writeBuildHostWheel writes dist/mypkg-1.0.0-py3-none-any.whl as a build host would, with its
+// paths in the members, the build time and an Info-ZIP Unix extra field with the owner.
+func writeBuildHostWheel(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := map[string]string{
+		"mypkg/__init__.py":                   "SOURCE = " + fmt.Sprintf("%q", wd+"/src/mypkg") + "\nHOMES = '/Users/{name}'\n",
+		"mypkg/_native.so":                    "\x7fELF\x00" + wd,
+		"mypkg-1.0.0.dist-info/METADATA":      "Metadata-Version: 2.1\nName: mypkg\nVersion: 1.0.0\nDownload-URL: file:///home/alice/src/mypkg\n",
+		"mypkg-1.0.0.dist-info/WHEEL":         "Wheel-Version: 1.0\n",
+		"mypkg-1.0.0.dist-info/RECORD":        "",
+		"mypkg-1.0.0.dist-info/top_level.txt": "mypkg\n",
+	}
+	var record strings.Builder
+	for _, name := range sortedKeys(members) {
+		if !strings.HasSuffix(name, "RECORD") {
+			sum := sha256.Sum256([]byte(members[name]))
+			fmt.Fprintf(&record, "%s,sha256=%s,%d\n", name, base64.RawURLEncoding.EncodeToString(sum[:]), len(members[name]))
+		}
+	}
+	members["mypkg-1.0.0.dist-info/RECORD"] = record.String() + "mypkg-1.0.0.dist-info/RECORD,,\n"
+
+	f, err := os.Create(filepath.Join("dist", "mypkg-1.0.0-py3-none-any.whl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	zw := zip.NewWriter(f)
+	for _, name := range sortedKeys(members) {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC),
+			// Info-ZIP Unix extra field holding uid 1000 and gid 1000
+			Extra: []byte{0x78, 0x75, 0x0b, 0x00, 0x01, 0x04, 0xe8, 0x03, 0x00, 0x00, 0x04, 0xe8, 0x03, 0x00, 0x00},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(members[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNormalizeWheelFiles(t *testing.T) {
+	writeDistFiles(t, "mypkg-1.0.0.tar.gz")
+	writeBuildHostWheel(t)
+	t.Setenv(sourceDateEpochEnv, "1767225600")
+	files, err := expandDistGlob("dist/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	upload, normalized, report, err := normalizeWheelFiles(files, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wheel := filepath.Join(dir, "mypkg-1.0.0-py3-none-any.whl")
+	if fmt.Sprint(upload) != fmt.Sprint([]string{wheel, filepath.Join("dist", "mypkg-1.0.0.tar.gz")}) || normalized[files[0]] != wheel {
+		t.Fatalf("unexpected upload %v and normalized %v", upload, normalized)
+	}
+	if len(report) != 1 || fmt.Sprint(report[0].PathsStripped) != "[mypkg-1.0.0.dist-info/METADATA mypkg/__init__.py]" || report[0].Timestamp != "2026-01-01T00:00:00.000Z" {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if _, digest, _, _ := fileDigests(wheel); report[0].SHA256 != digest {
+		t.Errorf("expected the digest of the normalized wheel, got %s", report[0].SHA256)
+	}
+
+	zr, err := zip.OpenReader(wheel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = zr.Close() }()
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		if !f.Modified.Equal(time.Unix(1767225600, 0)) {
+			t.Errorf("%s: timestamp %v was not reset", f.Name, f.Modified)
+		}
+		if bytes.Contains(f.Extra, []byte{0x78, 0x75}) {
+			t.Errorf("%s: the owner extra field was kept", f.Name)
+		}
+		data, err := readZipMember(f, maxMetadataSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[f.Name] = string(data)
+	}
+	if got := contents["mypkg/__init__.py"]; got != "SOURCE = \"./src/mypkg\"\nHOMES = '/Users/{name}'\n" {
+		t.Errorf("unexpected __init__.py %q", got)
+	}
+	if !strings.Contains(contents["mypkg-1.0.0.dist-info/METADATA"], "Download-URL: file://~/src/mypkg") {
+		t.Errorf("expected the home directory to be stripped from METADATA, got %q", contents["mypkg-1.0.0.dist-info/METADATA"])
+	}
+	wd, _ := os.Getwd()
+	if !strings.Contains(contents["mypkg/_native.so"], wd) {
+		t.Error("expected binary members to be copied unchanged")
+	}
+	for _, row := range strings.Split(strings.TrimSpace(contents["mypkg-1.0.0.dist-info/RECORD"]), "\n") {
+		fields := strings.Split(row, ",")
+		if fields[1] == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte(contents[fields[0]]))
+		if fields[1] != "sha256="+base64.RawURLEncoding.EncodeToString(sum[:]) || fields[2] != fmt.Sprint(len(contents[fields[0]])) {
+			t.Errorf("RECORD row %q does not match the member", row)
+		}
+	}
+}
+
+func TestWheelNormalizerPathBoundaries(t *testing.T) {
+	n := &wheelNormalizer{modified: zipEpoch}
+	n.add("/root", "~")
+	n.add("/root/project", ".")
+	got, changed := n.stripPaths([]byte("/root/project/a.py /root/.cache /rootfs/etc /root"), false)
+	if !changed || string(got) != "./a.py ~/.cache /rootfs/etc ~" {
+		t.Errorf("stripPaths() = %q", got)
+	}
+}
+
+func TestExecuteNormalizeWheels(t *testing.T) {
+	writeDistFiles(t)
+	writeBuildHostWheel(t)
+	var uploaded string
+	executor := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			uploaded = args[len(args)-1]
+			zr, err := zip.OpenReader(uploaded)
+			if err != nil {
+				return nil, err
+			}
+			defer func() { _ = zr.Close() }()
+			if !zr.File[0].Modified.Equal(zipEpoch) {
+				t.Errorf("expected the normalized wheel to be uploaded, got timestamp %v", zr.File[0].Modified)
+			}
+			return []byte("ok"), nil
+		},
+	}
+	p := &PyPIPlugin{cmdExecutor: executor}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"username":         "__token__",
+			"password":         "pypi-token",
+			"repository":       "http://localhost:8080/",
+			"normalize_wheels": true,
+		},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v %+v", err, resp)
+	}
+	if filepath.Dir(uploaded) == "dist" {
+		t.Errorf("expected a normalized copy to be uploaded, got %s", uploaded)
+	}
+	if report, _ := resp.Outputs["normalized_wheels"].([]normalizedWheel); len(report) != 1 {
+		t.Errorf("unexpected normalized_wheels %v", resp.Outputs["normalized_wheels"])
+	}
+	if _, err := os.Stat(uploaded); !os.IsNotExist(err) {
+		t.Errorf("expected the normalized copy to be removed, got %v", err)
+	}
+}
+
+func TestValidateNormalizeWheelsConfig(t *testing.T) {
+	if err := validateNormalizeWheelsConfig(Config{NormalizeWheels: true}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := validateNormalizeWheelsConfig(Config{NormalizeWheels: true, ExtraArgs: []string{"--attestations"}}); err == nil {
+		t.Error("expected attestations to be rejected")
+	}
+	t.Setenv(sourceDateEpochEnv, "yesterday")
+	if err := validateNormalizeWheelsConfig(Config{NormalizeWheels: true}); err == nil {
+		t.Error("expected an invalid SOURCE_DATE_EPOCH to be rejected")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// maxWindowsPathLength is the longest path Windows accepts with long paths enabled.
+const maxWindowsPathLength = 32767
+
+// uncVolumePattern matches the \\server\share volume of a UNC path, with slashes for
+// separators. Share names may contain spaces and end in $ for administrative shares.
+var uncVolumePattern = regexp.MustCompile(`^//([A-Za-z0-9][A-Za-z0-9._-]*)/([A-Za-z0-9._$][A-Za-z0-9._$ -]*)(/|$)`)
+
+// validateDistPathFor validates dist_path, accepting UNC paths and long paths on Windows when
+// windows_extended_paths is enabled. On other platforms the option has no effect, so shared
+// configurations keep the default checks there.
+func validateDistPathFor(cfg Config) error {
+	if cfg.WindowsExtendedPaths && runtime.GOOS == "windows" {
+		return validateWindowsDistPath(cfg.DistPath)
+	}
+	return validateDistPath(cfg.DistPath)
+}
+
+// validateWindowsDistPath validates a Windows dist path that may be on a network share
+// (\\server\share\dist\*) and longer than the legacy MAX_PATH. Paths on a share are checked
+// like relative paths below the share: they must not climb out of it. Drive letters, rooted
+// paths and device paths such as \\?\ and \\.\ stay rejected.
+func validateWindowsDistPath(p string) error {
+	if p == "" {
+		return fmt.Errorf("dist path cannot be empty")
+	}
+	if len(p) > maxWindowsPathLength {
+		return fmt.Errorf("dist path too long (max %d characters)", maxWindowsPathLength)
+	}
+
+	normalized := strings.ReplaceAll(p, `\`, "/")
+	if strings.HasPrefix(normalized, "//?/") || strings.HasPrefix(normalized, "//./") {
+		return fmt.Errorf("device paths are not allowed; use a \\\\server\\share path")
+	}
+	rest := normalized
+	if strings.HasPrefix(normalized, "//") {
+		volume := uncVolumePattern.FindString(normalized)
+		if volume == "" {
+			return fmt.Errorf("invalid UNC path: expected \\\\server\\share\\...")
+		}
+		rest = strings.TrimPrefix(normalized, volume)
+		if rest == "" {
+			return fmt.Errorf("UNC dist path must name files below the share")
+		}
+	} else if isAbsolutePath(p) || strings.HasPrefix(normalized, "/") || (len(normalized) > 1 && normalized[1] == ':') {
+		return fmt.Errorf("absolute paths are not allowed; only \\\\server\\share paths are accepted")
+	}
+
+	if !distPathPattern.MatchString(rest) {
+		return fmt.Errorf("dist path contains invalid characters")
+	}
+	if escapesWorkDir(rest) {
+		return fmt.Errorf("path traversal detected: cannot use '..' to escape the working directory or share")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestValidateWindowsDistPath(t *testing.T) {
+	long := strings.Repeat("build-output/", 30) + "dist/*"
+	tests := []struct {
+		path  string
+		valid bool
+	}{
+		{`dist\*`, true},
+		{long, true},
+		{`\\buildserver\artifacts\mypkg\dist\*.whl`, true},
+		{`\\build-01.corp.example.com\Build Output$\dist\*`, true},
+		{`//buildserver/artifacts/dist/*`, true},
+		{`\\buildserver\artifacts`, false},
+		{`\\buildserver\artifacts\..\other\dist\*`, false},
+		{`\\buildserver`, false},
+		{`\\?\C:\dist\*`, false},
+		{`\\.\pipe\dist`, false},
+		{`C:\dist\*`, false},
+		{`\dist\*`, false},
+		{`..\dist\*`, false},
+		{`dist\*;calc`, false},
+		{"dist/" + strings.Repeat("a", maxWindowsPathLength), false},
+	}
+	for _, tt := range tests {
+		if err := validateWindowsDistPath(tt.path); (err == nil) != tt.valid {
+			t.Errorf("validateWindowsDistPath(%.60q) = %v, expected valid %v", tt.path, err, tt.valid)
+		}
+	}
+	if err := validateDistPath(long); err == nil {
+		t.Error("expected long paths to be rejected by default")
+	}
+}
+
+func TestValidateDistPathFor(t *testing.T) {
+	p := &PyPIPlugin{}
+	cfg := p.parseConfig(map[string]any{"dist_path": `\\buildserver\artifacts\dist\*`, "windows_extended_paths": true})
+	if !cfg.WindowsExtendedPaths {
+		t.Fatalf("expected windows_extended_paths to be parsed, got %+v", cfg)
+	}
+	err := validateDistPathFor(cfg)
+	if runtime.GOOS == "windows" {
+		if err != nil {
+			t.Errorf("expected the UNC path to be accepted, got %v", err)
+		}
+	} else if err == nil {
+		t.Error("expected windows_extended_paths to have no effect outside windows")
+	}
+
+	cfg.WindowsExtendedPaths = false
+	if err := validateDistPathFor(cfg); err == nil {
+		t.Error("expected UNC paths to be rejected without windows_extended_paths")
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Yank defaults.
+const (
+	defaultYankReason     = "Rolled back: the release pipeline failed after publishing"
+	defaultYankSessionEnv = "PYPI_SESSION"
+	// warehouseSessionCookie is the cookie of a logged-in Warehouse session
+	warehouseSessionCookie = "session_id"
+)
+
+// Results of a rollback yank.
+const (
+	yankYanked = "yanked"
+	// yankSkipped is a release not yanked because this pipeline did not publish it
+	yankSkipped = "skipped"
+	// yankPending is a yank a dry run would make
+	yankPending = "pending"
+	// yankManual is a release to be yanked by a maintainer, as yank_web_session is not set
+	yankManual = "manual"
+)
+
+// knownWebURLs maps upload endpoints to the web interface managing their projects.
+var knownWebURLs = map[string]string{
+	"https://upload.pypi.org/legacy/": "https://pypi.org",
+	"https://test.pypi.org/legacy/":   "https://test.pypi.org",
+}
+
+// csrfInputPattern matches the hidden CSRF input of a Warehouse form.
+var csrfInputPattern = regexp.MustCompile(`<input[^>]*\bname="csrf_token"[^>]*>`)
+
+// inputValuePattern extracts the value attribute of an input.
+var inputValuePattern = regexp.MustCompile(`\bvalue="([^"]*)"`)
+
+// yankReport is the rollback of a published release, reported in outputs.
+type yankReport struct {
+	Project string `json:"project"`
+	Version string `json:"version"`
+	Reason  string `json:"reason"`
+	// Status is yanked, skipped, pending or manual
+	Status string   `json:"status"`
+	Files  []string `json:"files,omitempty"`
+	Detail string   `json:"detail,omitempty"`
+	// ManageURL is the release page a maintainer yanks the version on
+	ManageURL string `json:"manage_url,omitempty"`
+}
+
+// defaultWebURL returns the web interface of a well-known upload repository, or "".
+func defaultWebURL(repository string) string {
+	if !strings.HasSuffix(repository, "/") {
+		repository += "/"
+	}
+	return knownWebURLs[repository]
+}
+
+// manageReleaseURL returns the Warehouse page managing project's version.
+func manageReleaseURL(webURL, project, version string) string {
+	return fmt.Sprintf("%s/manage/project/%s/release/%s/", strings.TrimSuffix(webURL, "/"),
+		url.PathEscape(normalizeProjectName(project)), url.PathEscape(version))
+}
+
+// rollbackRelease yanks the release version when the release pipeline fails after this plugin
+// published it. Only a release whose files on the index have the SHA-256 of the dist_path files
+// is yanked, so a failure caused by a version published earlier never yanks that version.
+// Without yank_web_session, the yank output tells a maintainer how to yank it instead.
+func (p *PyPIPlugin) rollbackRelease(ctx context.Context, cfg Config, releaseCtxVersion string, dryRun bool) *plugin.ExecuteResponse {
+	fail := func(format string, args ...any) *plugin.ExecuteResponse {
+		return &plugin.ExecuteResponse{Success: false, Error: "rollback yank failed: " + fmt.Sprintf(format, args...)}
+	}
+	version, err := releaseVersion(cfg, releaseCtxVersion)
+	if err != nil {
+		return fail("%v", err)
+	}
+	files, err := expandDistGlob(cfg.DistPath)
+	if err != nil {
+		return fail("%v", err)
+	}
+	project, distVersion := distProjectVersion(files)
+	if project == "" {
+		return fail("no distribution with readable metadata in %s", cfg.DistPath)
+	}
+	if version == "" {
+		version = distVersion
+	}
+
+	report := &yankReport{Project: project, Version: version, Reason: cfg.YankReason}
+	skip := func(detail string) *plugin.ExecuteResponse {
+		report.Status, report.Detail = yankSkipped, detail
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Not yanking %s %s: %s", project, version, detail),
+			Outputs: map[string]any{"yank": report},
+		}
+	}
+	published, err := p.publishedFiles(ctx, cfg, project, version)
+	if err != nil {
+		return fail("cannot read %s %s from the index: %v", project, version, err)
+	}
+	if len(published) == 0 {
+		return skip("the version is not published")
+	}
+	for _, f := range files {
+		name := filepath.Base(f)
+		indexDigest, ok := published[name]
+		if !ok {
+			continue
+		}
+		if _, digest, _, err := fileDigests(f); err == nil && indexDigest == digest {
+			report.Files = append(report.Files, name)
+		}
+	}
+	if len(report.Files) == 0 {
+		return skip("no published file has the digest of a dist_path file, so this pipeline did not publish it")
+	}
+
+	report.ManageURL = manageReleaseURL(cfg.YankURL, project, version)
+	if !cfg.YankWebSession {
+		report.Status = yankManual
+		report.Detail = fmt.Sprintf("open %s, choose Yank and give the reason %q; set yank_web_session to yank automatically", report.ManageURL, cfg.YankReason)
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("%s %s must be yanked by a maintainer: %s", project, version, report.Detail),
+			Outputs: map[string]any{"yank": report},
+		}
+	}
+	if dryRun {
+		report.Status = yankPending
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Would yank %s %s: %s", project, version, cfg.YankReason),
+			Outputs: map[string]any{"yank": report},
+		}
+	}
+	if err := p.yankVersion(ctx, cfg, project, version); err != nil {
+		resp := fail("%s %s: %v", project, version, err)
+		resp.Outputs = map[string]any{"yank": report}
+		return resp
+	}
+	report.Status = yankYanked
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Yanked %s %s: %s", project, version, cfg.YankReason),
+		Outputs: map[string]any{"yank": report},
+	}
+}
+
+// yankVersion submits the yank form of the Warehouse release management page. Warehouse accepts
+// no API token there, so the request carries the logged-in session read from YankSessionEnv,
+// and the CSRF token of the page is sent back with the form. This is not a supported interface
+// of Warehouse, hence yank_web_session.
+func (p *PyPIPlugin) yankVersion(ctx context.Context, cfg Config, project, version string) error {
+	session := os.Getenv(cfg.YankSessionEnv)
+	if session == "" {
+		return fmt.Errorf("%s holds no Warehouse session", cfg.YankSessionEnv)
+	}
+	page := manageReleaseURL(cfg.YankURL, project, version)
+	// A redirect leads to the login page when the session expired, so it is not followed
+	client := *p.getHTTPClient()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	send := func(req *http.Request) (*http.Response, []byte, error) {
+		req.AddCookie(&http.Cookie{Name: warehouseSessionCookie, Value: session})
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("yank request failed: %w", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxIndexPageSize))
+		if err != nil {
+			return nil, nil, fmt.Errorf("yank request failed: %w", err)
+		}
+		if location := resp.Header.Get("Location"); strings.Contains(location, "/account/login/") {
+			return nil, nil, fmt.Errorf("the session in %s expired or is not logged in", cfg.YankSessionEnv)
+		}
+		return resp, body, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, page, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create yank request: %w", err)
+	}
+	resp, body, err := send(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("release page %s returned %s; the account needs the Owner or Maintainer role", page, resp.Status)
+	}
+	input := csrfInputPattern.Find(body)
+	match := inputValuePattern.FindSubmatch(input)
+	if match == nil {
+		return fmt.Errorf("release page %s has no yank form", page)
+	}
+
+	form := url.Values{
+		"csrf_token":           {html.UnescapeString(string(match[1]))},
+		"confirm_yank_version": {version},
+		"yanked_reason":        {cfg.YankReason},
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, page, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create yank request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// Warehouse checks the origin of form posts over HTTPS
+	req.Header.Set("Origin", strings.TrimSuffix(cfg.YankURL, "/"))
+	req.Header.Set("Referer", page)
+	resp, body, err = send(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		detail, _ := truncateOutput(strings.TrimSpace(string(body)), defaultMaxErrorBodyBytes)
+		return fmt.Errorf("yank of %s was refused: %s: %s", page, resp.Status, detail)
+	}
+	return nil
+}
+
+// validateYankConfig validates the yank_on_rollback options.
+func validateYankConfig(cfg Config) error {
+	if !cfg.YankOnRollback {
+		return nil
+	}
+	if cfg.YankURL == "" {
+		return fmt.Errorf("yank_on_rollback needs yank_url for repositories other than PyPI and TestPyPI")
+	}
+	if cfg.YankURL != defaultWebURL(cfg.Repository) {
+		if err := validateRepositoryURL(cfg.YankURL); err != nil {
+			return fmt.Errorf("invalid yank_url: %w", err)
+		}
+	}
+	if strings.TrimSpace(cfg.YankReason) == "" {
+		return fmt.Errorf("yank_reason must not be empty")
+	}
+	if cfg.YankWebSession && cfg.YankSessionEnv == "" {
+		return fmt.Errorf("yank_session_env must not be empty")
+	}
+	if cfg.JSONAPIURL == "" && cfg.IndexURL == "" {
+		return fmt.Errorf("yank_on_rollback needs json_api_url or index_url to find the published files")
+	}
+	return nil
+}
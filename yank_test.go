@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestExecuteYankOnRollback(t *testing.T) {
+	digests := writeSignedDists(t)
+	t.Setenv("PYPI_SESSION", "session-123")
+	var posted []string
+	loggedIn := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pypi/mypkg/1.0.0/json":
+			var urls []string
+			for name, digest := range digests {
+				urls = append(urls, fmt.Sprintf(`{"filename": %q, "digests": {"sha256": %q}}`, name, digest))
+			}
+			_, _ = fmt.Fprintf(w, `{"urls": [%s]}`, strings.Join(urls, ","))
+		case "/manage/project/mypkg/release/1.0.0/":
+			if cookie, err := r.Cookie(warehouseSessionCookie); err != nil || cookie.Value != "session-123" || !loggedIn {
+				http.Redirect(w, r, "/account/login/?next="+r.URL.Path, http.StatusSeeOther)
+				return
+			}
+			if r.Method == http.MethodGet {
+				_, _ = fmt.Fprint(w, `<form method="POST"><input name="csrf_token" type="hidden" value="tok&amp;en"></form>`)
+				return
+			}
+			if r.FormValue("csrf_token") != "tok&en" || r.Header.Get("Origin") != "http://"+r.Host {
+				http.Error(w, "Bad CSRF token", http.StatusBadRequest)
+				return
+			}
+			posted = append(posted, r.FormValue("confirm_yank_version")+": "+r.FormValue("yanked_reason"))
+			http.Redirect(w, r, "/manage/project/mypkg/releases/", http.StatusSeeOther)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	execute := func(dryRun bool, version string) *plugin.ExecuteResponse {
+		t.Helper()
+		p := &PyPIPlugin{}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:   plugin.HookOnError,
+			DryRun: dryRun,
+			Config: map[string]any{
+				"username":         "__token__",
+				"password":         "pypi-token",
+				"repository":       "http://localhost:8080/",
+				"json_api_url":     server.URL + "/pypi/",
+				"yank_on_rollback": true,
+				"yank_web_session": true,
+				"yank_url":         server.URL,
+				"yank_reason":      "Broken deployment",
+			},
+			Context: plugin.ReleaseContext{Version: version},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := execute(true, "v1.0.0")
+	if !resp.Success || len(posted) != 0 || !strings.Contains(resp.Message, "Would yank mypkg 1.0.0") {
+		t.Errorf("expected a dry run to yank nothing, got %+v %v", resp, posted)
+	}
+
+	resp = execute(false, "v1.0.0")
+	if !resp.Success || strings.Join(posted, ",") != "1.0.0: Broken deployment" {
+		t.Fatalf("expected the release to be yanked, got %+v %v", resp, posted)
+	}
+	if report, _ := resp.Outputs["yank"].(*yankReport); report == nil || report.Status != yankYanked || len(report.Files) != 2 {
+		t.Errorf("unexpected yank %+v", resp.Outputs["yank"])
+	}
+
+	posted, loggedIn = nil, false
+	resp = execute(false, "v1.0.0")
+	if resp.Success || !strings.Contains(resp.Error, "the session in PYPI_SESSION expired") {
+		t.Errorf("expected the expired session to fail the rollback, got %+v", resp)
+	}
+
+	// A version this pipeline did not publish is left alone
+	resp = execute(false, "v2.0.0")
+	if !resp.Success || len(posted) != 0 || !strings.Contains(resp.Message, "the version is not published") {
+		t.Errorf("expected an unpublished version to be skipped, got %+v", resp)
+	}
+	loggedIn = true
+	for name := range digests {
+		digests[name] = strings.Repeat("0", 64)
+	}
+	resp = execute(false, "v1.0.0")
+	if report, _ := resp.Outputs["yank"].(*yankReport); !resp.Success || len(posted) != 0 || report == nil || report.Status != yankSkipped {
+		t.Errorf("expected a release published with other files to be skipped, got %+v %v", resp, posted)
+	}
+}
+
+func TestExecuteYankOnRollbackManual(t *testing.T) {
+	digests := writeReleaseDists(t)
+	server := newTestReleaseAPI(t, digests)
+
+	p := &PyPIPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookOnError,
+		Config: map[string]any{
+			"username":         "__token__",
+			"password":         "pypi-token",
+			"repository":       "http://localhost:8080/",
+			"json_api_url":     server.URL + "/pypi/",
+			"yank_on_rollback": true,
+			"yank_url":         server.URL,
+			"yank_reason":      "Broken deployment",
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without yank_web_session the session is not needed and no form is posted
+	report, _ := resp.Outputs["yank"].(*yankReport)
+	if !resp.Success || report == nil || report.Status != yankManual || report.ManageURL != server.URL+"/manage/project/mypkg/release/1.0.0/" {
+		t.Fatalf("expected manual yank instructions, got %+v %+v", resp, report)
+	}
+	if !strings.Contains(resp.Message, "must be yanked by a maintainer") || !strings.Contains(report.Detail, `"Broken deployment"`) {
+		t.Errorf("unexpected instructions %q", resp.Message)
+	}
+}
+
+func TestExecuteOnErrorWithoutYank(t *testing.T) {
+	p := &PyPIPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{Hook: plugin.HookOnError, Config: map[string]any{}})
+	if err != nil || !resp.Success || !strings.Contains(resp.Message, "not handled") {
+		t.Errorf("expected the hook to be ignored, got %v %+v", err, resp)
+	}
+}
+
+func TestValidateYankConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"disabled", Config{Repository: "https://pypi.example.com/legacy/"}, false},
+		{"pypi", Config{YankOnRollback: true, Repository: "https://upload.pypi.org/legacy/", YankURL: "https://pypi.org", YankReason: defaultYankReason, YankSessionEnv: defaultYankSessionEnv, JSONAPIURL: "https://pypi.org/pypi"}, false},
+		{"private", Config{YankOnRollback: true, Repository: "http://localhost:8080/legacy/", YankURL: "http://localhost:8080", YankReason: defaultYankReason, YankSessionEnv: defaultYankSessionEnv, IndexURL: "http://localhost:8080/simple/"}, false},
+		{"no yank url", Config{YankOnRollback: true, Repository: "http://localhost:8080/legacy/", YankReason: defaultYankReason, YankSessionEnv: defaultYankSessionEnv, IndexURL: "http://localhost:8080/simple/"}, true},
+		{"plain http", Config{YankOnRollback: true, Repository: "https://upload.pypi.org/legacy/", YankURL: "http://pypi.org", YankReason: defaultYankReason, YankSessionEnv: defaultYankSessionEnv, JSONAPIURL: "https://pypi.org/pypi"}, true},
+		{"empty reason", Config{YankOnRollback: true, Repository: "https://upload.pypi.org/legacy/", YankURL: "https://pypi.org", YankReason: " ", YankSessionEnv: defaultYankSessionEnv, JSONAPIURL: "https://pypi.org/pypi"}, true},
+		{"manual without session env", Config{YankOnRollback: true, Repository: "https://upload.pypi.org/legacy/", YankURL: "https://pypi.org", YankReason: defaultYankReason, JSONAPIURL: "https://pypi.org/pypi"}, false},
+		{"web session without session env", Config{YankOnRollback: true, YankWebSession: true, Repository: "https://upload.pypi.org/legacy/", YankURL: "https://pypi.org", YankReason: defaultYankReason, JSONAPIURL: "https://pypi.org/pypi"}, true},
+		{"no index", Config{YankOnRollback: true, Repository: "http://localhost:8080/legacy/", YankURL: "http://localhost:8080", YankReason: defaultYankReason, YankSessionEnv: defaultYankSessionEnv}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateYankConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateYankConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}